@@ -0,0 +1,134 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package agentcore
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// The auth_scheme values, how the token is sent on the service requests
+const (
+	// AuthBearer sends Authorization: Bearer <token>, the default
+	AuthBearer = "bearer"
+	// AuthHeader sends the token as is in the header named auth_name, for
+	// gateways that strip or rename the Authorization header
+	AuthHeader = "header"
+	// AuthCookie sends the token in the cookie named auth_name
+	AuthCookie = "cookie"
+)
+
+// DefaultAuthHeader and DefaultAuthCookie are the names used when auth_name
+// is not set
+const (
+	DefaultAuthHeader = "X-Auth-Token"
+	DefaultAuthCookie = "token"
+)
+
+// AuthScheme is how the agent sends its token
+type AuthScheme struct {
+	Kind string
+	Name string
+}
+
+// NewAuthScheme returns the scheme of the auth_scheme and auth_name settings,
+// an empty kind is AuthBearer
+func NewAuthScheme(kind, name string) (AuthScheme, error) {
+	kind = strings.ToLower(strings.TrimSpace(kind))
+	name = strings.TrimSpace(name)
+	switch kind {
+	case "", AuthBearer:
+		return AuthScheme{Kind: AuthBearer}, nil
+	case AuthHeader:
+		if name == "" {
+			name = DefaultAuthHeader
+		}
+	case AuthCookie:
+		if name == "" {
+			name = DefaultAuthCookie
+		}
+	default:
+		return AuthScheme{Kind: AuthBearer}, fmt.Errorf("unknown auth scheme %q, expected %s, %s or %s", kind, AuthBearer, AuthHeader, AuthCookie)
+	}
+	return AuthScheme{Kind: kind, Name: name}, nil
+}
+
+// Apply sets the token on the request
+func (this AuthScheme) Apply(req *http.Request, token string) {
+	switch this.Kind {
+	case AuthHeader:
+		req.Header.Set(this.Name, token)
+	case AuthCookie:
+		req.AddCookie(&http.Cookie{Name: this.Name, Value: token})
+	default:
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+}
+
+// The default paths of the login endpoints
+const (
+	DefaultAuthPath      = "/auth"
+	DefaultTfaVerifyPath = "/tfaVerify"
+)
+
+// AuthPaths are the paths of the login endpoints on the website, a gateway
+// may mount them elsewhere than the defaults
+type AuthPaths struct {
+	Auth      string
+	TfaVerify string
+}
+
+// DefaultAuthPaths returns the paths the server mounts the endpoints on
+func DefaultAuthPaths() AuthPaths {
+	return AuthPaths{Auth: DefaultAuthPath, TfaVerify: DefaultTfaVerifyPath}
+}
+
+// NewAuthPaths returns the paths of the auth_path and tfa_verify_path
+// settings, an empty path is the default. A path must start with /, an
+// invalid one is replaced by the default and reported in the error.
+func NewAuthPaths(auth, tfaVerify string) (AuthPaths, error) {
+	paths := DefaultAuthPaths()
+	var invalid []string
+	if auth = strings.TrimSpace(auth); auth != "" {
+		if strings.HasPrefix(auth, "/") {
+			paths.Auth = auth
+		} else {
+			invalid = append(invalid, fmt.Sprintf("auth path %q", auth))
+		}
+	}
+	if tfaVerify = strings.TrimSpace(tfaVerify); tfaVerify != "" {
+		if strings.HasPrefix(tfaVerify, "/") {
+			paths.TfaVerify = tfaVerify
+		} else {
+			invalid = append(invalid, fmt.Sprintf("tfa verify path %q", tfaVerify))
+		}
+	}
+	if len(invalid) > 0 {
+		return paths, fmt.Errorf("invalid %s, a path must start with /", strings.Join(invalid, " and "))
+	}
+	return paths, nil
+}
+
+// AuthURL returns the URL of the login endpoint on the website
+func (this AuthPaths) AuthURL(website string) string {
+	return strings.TrimSuffix(website, "/") + this.Auth
+}
+
+// TfaVerifyURL returns the URL of the two factor verification endpoint
+func (this AuthPaths) TfaVerifyURL(website string) string {
+	return strings.TrimSuffix(website, "/") + this.TfaVerify
+}
@@ -0,0 +1,72 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package agentcore
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAuthScheme(t *testing.T) {
+	scheme, err := NewAuthScheme("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest("POST", "/my-family/53/Location", nil)
+	scheme.Apply(req, "t1")
+	if req.Header.Get("Authorization") != "Bearer t1" {
+		t.Fatalf("the default must be a bearer header, got %v", req.Header)
+	}
+
+	scheme, _ = NewAuthScheme("Header", "X-Family-Token")
+	req = httptest.NewRequest("POST", "/", nil)
+	scheme.Apply(req, "t2")
+	if req.Header.Get("X-Family-Token") != "t2" || req.Header.Get("Authorization") != "" {
+		t.Fatalf("unexpected headers %v", req.Header)
+	}
+
+	scheme, _ = NewAuthScheme("cookie", "")
+	req = httptest.NewRequest("POST", "/", nil)
+	scheme.Apply(req, "t3")
+	if c, err := req.Cookie(DefaultAuthCookie); err != nil || c.Value != "t3" {
+		t.Fatalf("expected the token cookie, got %v %v", c, err)
+	}
+
+	if scheme, err = NewAuthScheme("basic", ""); err == nil || scheme.Kind != AuthBearer {
+		t.Fatal("an unknown scheme must fail and fall back to bearer")
+	}
+}
+
+func TestAuthPaths(t *testing.T) {
+	paths, err := NewAuthPaths("", "")
+	if err != nil || paths != DefaultAuthPaths() {
+		t.Fatalf("empty settings must give the defaults, got %v %v", paths, err)
+	}
+	if url := paths.AuthURL("https://host/"); url != "https://host/auth" {
+		t.Fatalf("auth url = %s", url)
+	}
+
+	paths, err = NewAuthPaths("/gw/login", "/gw/tfa")
+	if err != nil || paths.AuthURL("https://host") != "https://host/gw/login" ||
+		paths.TfaVerifyURL("https://host") != "https://host/gw/tfa" {
+		t.Fatalf("unexpected paths %v %v", paths, err)
+	}
+
+	paths, err = NewAuthPaths("login", "/gw/tfa")
+	if err == nil || paths.Auth != DefaultAuthPath || paths.TfaVerify != "/gw/tfa" {
+		t.Fatalf("a path without a leading / must fail and fall back, got %v %v", paths, err)
+	}
+}
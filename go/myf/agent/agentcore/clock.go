@@ -0,0 +1,90 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package agentcore
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ServerTimeHeader is the server clock in unix milliseconds, servers that set
+// it give a more precise offset than the one second resolution Date header
+const ServerTimeHeader = "X-Server-Time"
+
+// SkewWarningThreshold is the clock offset from the server that is logged
+const SkewWarningThreshold = 30 * time.Second
+
+// dateResolution is the offset below which the Date header is ignored, it
+// has a one second resolution and the request takes time
+const dateResolution = 2 * time.Second
+
+// Clock is the device clock corrected by its offset from the server clock,
+// location timestamps are stamped with it so a device with a wrong clock
+// still reports correct times
+type Clock struct {
+	mtx    sync.RWMutex
+	offset time.Duration
+	warned bool
+	// now is the device clock, tests replace it
+	now func() time.Time
+}
+
+func NewClock() *Clock {
+	return &Clock{now: time.Now}
+}
+
+// Observe updates the offset from the server time of a response to a request
+// sent at sent. It returns the offset and true when it has just exceeded
+// SkewWarningThreshold, so the caller warns once and not on every response.
+// A response without a server time keeps the offset.
+func (this *Clock) Observe(header http.Header, sent time.Time) (time.Duration, bool) {
+	received := this.now()
+	// The server stamped the response about half way through the round trip
+	local := sent.Add(received.Sub(sent) / 2)
+	var offset time.Duration
+	if ms, err := strconv.ParseInt(header.Get(ServerTimeHeader), 10, 64); err == nil {
+		offset = time.UnixMilli(ms).Sub(local)
+	} else if date, err := http.ParseTime(header.Get("Date")); err == nil {
+		offset = date.Add(time.Second / 2).Sub(local)
+		if offset > -dateResolution && offset < dateResolution {
+			offset = 0
+		}
+	} else {
+		return this.Offset(), false
+	}
+
+	this.mtx.Lock()
+	defer this.mtx.Unlock()
+	this.offset = offset
+	skewed := offset > SkewWarningThreshold || offset < -SkewWarningThreshold
+	warn := skewed && !this.warned
+	this.warned = skewed
+	return offset, warn
+}
+
+// Offset returns how far the server clock is ahead of the device clock
+func (this *Clock) Offset() time.Duration {
+	this.mtx.RLock()
+	defer this.mtx.RUnlock()
+	return this.offset
+}
+
+// Now returns the device time corrected to the server clock
+func (this *Clock) Now() time.Time {
+	return this.now().Add(this.Offset())
+}
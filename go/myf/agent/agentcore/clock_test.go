@@ -0,0 +1,59 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package agentcore
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestClockOffset(t *testing.T) {
+	device := time.Unix(1700000000, 0)
+	clock := NewClock()
+	clock.now = func() time.Time { return device }
+
+	// The server is 5 minutes ahead, the round trip took 200ms
+	server := device.Add(5 * time.Minute).Add(-100 * time.Millisecond)
+	header := http.Header{ServerTimeHeader: {strconv.FormatInt(server.UnixMilli(), 10)}}
+	offset, warn := clock.Observe(header, device.Add(-200*time.Millisecond))
+	if offset != 5*time.Minute || !warn {
+		t.Fatalf("expected a 5m offset with a warning, got %s %v", offset, warn)
+	}
+	if !clock.Now().Equal(device.Add(5 * time.Minute)) {
+		t.Fatalf("unexpected corrected time %s", clock.Now())
+	}
+	if _, warn = clock.Observe(header, device.Add(-200*time.Millisecond)); warn {
+		t.Fatal("a skew already warned about must not warn again")
+	}
+
+	if offset, _ = clock.Observe(http.Header{}, device); offset != 5*time.Minute {
+		t.Fatal("a response without a server time must keep the offset")
+	}
+
+	date := http.Header{"Date": {device.Add(-time.Hour).UTC().Format(http.TimeFormat)}}
+	if offset, _ = clock.Observe(date, device); offset > -59*time.Minute {
+		t.Fatalf("expected a -1h offset from the Date header, got %s", offset)
+	}
+	inSync := http.Header{"Date": {device.UTC().Format(http.TimeFormat)}}
+	if offset, _ = clock.Observe(inSync, device); offset != 0 {
+		t.Fatalf("an offset within the Date resolution must be ignored, got %s", offset)
+	}
+	if _, warn = clock.Observe(date, device); !warn {
+		t.Fatal("a skew after the clock was in sync must warn again")
+	}
+}
@@ -0,0 +1,158 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package agentcore
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Config holds the settings both agents persist. The agents embed it in
+// their own config, so the JSON names must stay compatible with the files
+// written by earlier versions.
+type Config struct {
+	// Version is ConfigVersion for a migrated config, absent before
+	Version    int    `json:"config_version,omitempty"`
+	DeviceID   string `json:"device_id"`
+	DeviceName string `json:"device_name,omitempty"`
+	// Alias names the device in the agent logs instead of its id, it is
+	// never posted, see DeviceLabel
+	Alias              string `json:"alias,omitempty"`
+	Website            string `json:"website,omitempty"`
+	EncryptedUser      string `json:"encrypted_user,omitempty"`
+	EncryptedPass      string `json:"encrypted_pass,omitempty"`
+	SkipTLSVerify      *bool  `json:"skip_tls_verify,omitempty"`
+	ServiceArea        int    `json:"service_area,omitempty"`
+	UseProtobuf        bool   `json:"use_protobuf,omitempty"`
+	DisableCompression bool   `json:"disable_compression,omitempty"`
+	// Geofences of this device, see TrustedZone
+	Geofences               []Geofence `json:"geofences,omitempty"`
+	TrustedHeartbeatSeconds int        `json:"trusted_heartbeat_seconds,omitempty"`
+	// PostOnMoveMeters posts a position only after a move of this distance,
+	// and a heartbeat every HeartbeatSeconds otherwise, see MovementFilter
+	PostOnMoveMeters float64 `json:"post_on_move_meters,omitempty"`
+	HeartbeatSeconds int     `json:"heartbeat_seconds,omitempty"`
+	// AuthScheme and AuthName select how the token is sent, see NewAuthScheme
+	AuthScheme string `json:"auth_scheme,omitempty"`
+	AuthName   string `json:"auth_name,omitempty"`
+	// AuthPath and TfaVerifyPath are the login endpoints, see NewAuthPaths
+	AuthPath      string `json:"auth_path,omitempty"`
+	TfaVerifyPath string `json:"tfa_verify_path,omitempty"`
+	// Telemetry adds the network type, charging state and OS version to the
+	// posts, off by default
+	Telemetry bool `json:"telemetry,omitempty"`
+	// StartJitterSeconds is the longest random delay before the first post,
+	// see StartJitter, 0 disables it
+	StartJitterSeconds *int `json:"start_jitter_seconds,omitempty"`
+	// StartupRetries is how many times a failed first login is retried,
+	// see StartupRetries
+	StartupRetries *int `json:"startup_retries,omitempty"`
+	// ReauthCooldownSeconds spaces the re-authentications that are not
+	// followed by an accepted post, see ReauthGate
+	ReauthCooldownSeconds int `json:"reauth_cooldown_seconds,omitempty"`
+	// HeartbeatOnly shares the presence of the device and never its
+	// position, the agent posts heartbeats only and registers the device
+	// heartbeat-only so the server drops any coordinate
+	HeartbeatOnly bool `json:"heartbeat_only,omitempty"`
+}
+
+// SetCredentials stores the encrypted user and password
+func (this *Config) SetCredentials(c *Cipher, user, pass string) error {
+	encryptedUser, err := c.Encrypt(user)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt user: %w", err)
+	}
+	encryptedPass, err := c.Encrypt(pass)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt pass: %w", err)
+	}
+	this.EncryptedUser = encryptedUser
+	this.EncryptedPass = encryptedPass
+	return nil
+}
+
+// Credentials decrypts the user and password, a value that is missing or
+// does not decrypt is returned empty so the agent asks for it again
+func (this *Config) Credentials(c *Cipher) (string, string) {
+	return DecryptOrEmpty(c, this.EncryptedUser), DecryptOrEmpty(c, this.EncryptedPass)
+}
+
+// DecryptOrEmpty decrypts a stored value, or returns "" if it is missing or
+// does not decrypt
+func DecryptOrEmpty(c *Cipher, encrypted string) string {
+	if encrypted == "" {
+		return ""
+	}
+	plaintext, err := c.Decrypt(encrypted)
+	if err != nil {
+		return ""
+	}
+	return plaintext
+}
+
+// Redacted replaces a secret in a printed config
+const Redacted = "<redacted>"
+
+// Redact returns Redacted for a set secret and "" for an unset one, so a
+// printed config tells whether the secret is configured without showing it
+func Redact(secret string) string {
+	if secret == "" {
+		return ""
+	}
+	return Redacted
+}
+
+// LoadConfig reads the JSON config file into cfg, it returns false without
+// an error when the file does not exist
+func LoadConfig(filename string, cfg interface{}) (bool, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to read config: %w", err)
+	}
+	if err := json.Unmarshal(data, cfg); err != nil {
+		return false, fmt.Errorf("failed to parse config: %w", err)
+	}
+	return true, nil
+}
+
+// SaveConfig writes cfg as JSON, readable by the user only
+func SaveConfig(filename string, cfg interface{}) error {
+	if err := os.MkdirAll(filepath.Dir(filename), 0700); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+	data, err := json.MarshalIndent(cfg, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal config: %w", err)
+	}
+	if err := os.WriteFile(filename, data, 0600); err != nil {
+		return fmt.Errorf("failed to write config: %w", err)
+	}
+	return nil
+}
+
+// DeviceLabel returns how the logs name the device, its alias, or its id
+// when it has no alias
+func DeviceLabel(alias, deviceID string) string {
+	if alias != "" {
+		return alias
+	}
+	return deviceID
+}
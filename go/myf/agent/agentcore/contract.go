@@ -0,0 +1,39 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package agentcore
+
+import (
+	"github.com/saichler/l8myfamiliy/go/myf/webapi"
+	"github.com/saichler/l8myfamiliy/go/types/l8myfamily"
+)
+
+// RegistrationBody returns the JSON body of a device registration in the
+// canonical contract, see webapi.JSONNames. reportIntervalSeconds is the
+// interval the agent announces, see ReportInterval. heartbeatOnly registers
+// a device that shares its presence only, see Config.HeartbeatOnly. The
+// agent Version is always sent.
+func RegistrationBody(deviceId, familyId, name, idempotencyKey, signingKey string, reportIntervalSeconds int32, heartbeatOnly bool) ([]byte, error) {
+	return webapi.EncodeBody(webapi.ContentTypeJSON, &l8myfamily.Device{
+		Id:                    deviceId,
+		FamilyId:              familyId,
+		Name:                  name,
+		IdempotencyKey:        idempotencyKey,
+		SigningKey:            signingKey,
+		ReportIntervalSeconds: reportIntervalSeconds,
+		HeartbeatOnly:         heartbeatOnly,
+		AgentVersion:          Version,
+	})
+}
@@ -0,0 +1,43 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package agentcore
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/saichler/l8myfamiliy/go/myf/webapi"
+	"github.com/saichler/l8myfamiliy/go/types/l8myfamily"
+)
+
+func TestRegistrationBody(t *testing.T) {
+	data, err := RegistrationBody("d1", "fam", "phone", "k1", "s1", 60, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := webapi.CheckJSONNames(data, &l8myfamily.Device{}); err != nil {
+		t.Fatalf("the registration names must be canonical, got %v", err)
+	}
+	device := &l8myfamily.Device{}
+	if err := webapi.DecodeBody(httptest.NewRequest("POST", "/my-family/53/Family", bytes.NewReader(data)), device); err != nil {
+		t.Fatal(err)
+	}
+	if device.Id != "d1" || device.FamilyId != "fam" || device.Name != "phone" || device.IdempotencyKey != "k1" || device.SigningKey != "s1" ||
+		device.ReportIntervalSeconds != 60 || !device.HeartbeatOnly || device.AgentVersion != Version {
+		t.Fatalf("the registration must round trip, got %v", device)
+	}
+}
@@ -0,0 +1,92 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package agentcore holds the code shared by the laptop and Android agents.
+package agentcore
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"io"
+)
+
+// The key salts of the agents, they are part of the key of every stored
+// credential so they must never change
+const (
+	SaltAndroid = "l8myfamily-android-agent"
+	SaltLaptop  = "l8myfamily-laptop-agent"
+)
+
+// Cipher encrypts the stored credentials with AES-256-GCM. The key is derived
+// from the device id and the agent salt, so a config decrypts only with the
+// device id it was written with.
+type Cipher struct {
+	key []byte
+}
+
+// NewCipher creates the cipher of a device
+func NewCipher(deviceID, salt string) *Cipher {
+	h := sha256.New()
+	h.Write([]byte(deviceID))
+	h.Write([]byte(salt))
+	return &Cipher{key: h.Sum(nil)}
+}
+
+// Encrypt returns the base64 of the random nonce followed by the sealed plaintext
+func (this *Cipher) Encrypt(plaintext string) (string, error) {
+	gcm, err := this.gcm()
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+	ciphertext := gcm.Seal(nonce, nonce, []byte(plaintext), nil)
+	return base64.StdEncoding.EncodeToString(ciphertext), nil
+}
+
+// Decrypt reverses Encrypt, it fails on a wrong key or a damaged ciphertext
+func (this *Cipher) Decrypt(encoded string) (string, error) {
+	ciphertext, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", err
+	}
+	gcm, err := this.gcm()
+	if err != nil {
+		return "", err
+	}
+	if len(ciphertext) < gcm.NonceSize() {
+		return "", fmt.Errorf("ciphertext too short")
+	}
+	nonce, ciphertext := ciphertext[:gcm.NonceSize()], ciphertext[gcm.NonceSize():]
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
+
+func (this *Cipher) gcm() (cipher.AEAD, error) {
+	block, err := aes.NewCipher(this.key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,110 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package agentcore
+
+import (
+	"encoding/base64"
+	"path/filepath"
+	"testing"
+)
+
+const testDeviceID = "5f0c6c1e-7d2a-4c1e-9b1e-2a4d7c9e0f11"
+
+func TestEncryptDecryptRoundTrip(t *testing.T) {
+	c := NewCipher(testDeviceID, SaltLaptop)
+	for _, plaintext := range []string{"", "alice", "pässwörd with unicode ✓", string(make([]byte, 4096))} {
+		encrypted, err := c.Encrypt(plaintext)
+		if err != nil {
+			t.Fatal(err)
+		}
+		decrypted, err := c.Decrypt(encrypted)
+		if err != nil || decrypted != plaintext {
+			t.Fatalf("round trip of %q failed: %q %v", plaintext, decrypted, err)
+		}
+	}
+	a, _ := c.Encrypt("alice")
+	b, _ := c.Encrypt("alice")
+	if a == b {
+		t.Fatal("every encryption must use a fresh nonce")
+	}
+}
+
+func TestDecryptWithWrongKeyFails(t *testing.T) {
+	encrypted, _ := NewCipher(testDeviceID, SaltLaptop).Encrypt("alice")
+	if _, err := NewCipher("another-device", SaltLaptop).Decrypt(encrypted); err == nil {
+		t.Fatal("a different device id must not decrypt")
+	}
+	if _, err := NewCipher(testDeviceID, SaltAndroid).Decrypt(encrypted); err == nil {
+		t.Fatal("a different agent salt must not decrypt")
+	}
+}
+
+func TestDecryptTruncatedCiphertextFails(t *testing.T) {
+	c := NewCipher(testDeviceID, SaltAndroid)
+	encrypted, _ := c.Encrypt("alice")
+	raw, _ := base64.StdEncoding.DecodeString(encrypted)
+	for _, n := range []int{0, 5, 12, len(raw) - 1} {
+		if _, err := c.Decrypt(base64.StdEncoding.EncodeToString(raw[:n])); err == nil {
+			t.Fatalf("a ciphertext truncated to %d bytes must fail", n)
+		}
+	}
+	if _, err := c.Decrypt("not base64!"); err == nil {
+		t.Fatal("invalid base64 must fail")
+	}
+	if DecryptOrEmpty(c, encrypted[:len(encrypted)-4]) != "" {
+		t.Fatal("a value that does not decrypt must load empty")
+	}
+}
+
+func TestLoadConfigWrittenByPreviousVersion(t *testing.T) {
+	for file, salt := range map[string]string{"android-config-v1.json": SaltAndroid, "laptop-config-v1.json": SaltLaptop} {
+		cfg := &Config{}
+		exists, err := LoadConfig(filepath.Join("testdata", file), cfg)
+		if err != nil || !exists {
+			t.Fatalf("%s: %v", file, err)
+		}
+		if cfg.DeviceID != testDeviceID || cfg.SkipTLSVerify == nil {
+			t.Fatalf("%s: unexpected config %+v", file, cfg)
+		}
+		user, pass := cfg.Credentials(NewCipher(cfg.DeviceID, salt))
+		if user != "alice" || pass != "s3cret!" {
+			t.Fatalf("%s: credentials did not decrypt, got %q %q", file, user, pass)
+		}
+	}
+}
+
+func TestConfigSaveLoad(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "sub", "agent.json")
+	if exists, err := LoadConfig(filename, &Config{}); exists || err != nil {
+		t.Fatal("a missing config must load as not existing without an error")
+	}
+	c := NewCipher(testDeviceID, SaltAndroid)
+	cfg := &Config{DeviceID: testDeviceID, Website: "https://example.com", ServiceArea: 7}
+	if err := cfg.SetCredentials(c, "bob", "hunter2"); err != nil {
+		t.Fatal(err)
+	}
+	if err := SaveConfig(filename, cfg); err != nil {
+		t.Fatal(err)
+	}
+	loaded := &Config{}
+	if _, err := LoadConfig(filename, loaded); err != nil {
+		t.Fatal(err)
+	}
+	user, pass := loaded.Credentials(c)
+	if user != "bob" || pass != "hunter2" || loaded.ServiceArea != 7 {
+		t.Fatalf("unexpected loaded config %+v", loaded)
+	}
+}
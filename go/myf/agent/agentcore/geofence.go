@@ -0,0 +1,104 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package agentcore
+
+import (
+	"sync"
+	"time"
+
+	"github.com/saichler/l8myfamiliy/go/myf/geo"
+)
+
+// DefaultTrustedHeartbeatSeconds is how often a device inside a trusted
+// geofence still posts, so the family sees it is alive
+const DefaultTrustedHeartbeatSeconds = 900
+
+// Geofence is a circular area of the device config
+type Geofence struct {
+	Name         string  `json:"name"`
+	Latitude     float64 `json:"latitude"`
+	Longitude    float64 `json:"longitude"`
+	RadiusMeters float64 `json:"radius_meters"`
+	// Trusted fences, like home, are reported by name and center only and
+	// suppress the regular posts while the device is inside
+	Trusted bool `json:"trusted,omitempty"`
+}
+
+// Contains returns whether the position is inside the fence
+func (this *Geofence) Contains(lat, lon float64) bool {
+	return geo.Distance(this.Latitude, this.Longitude, lat, lon) <= this.RadiusMeters
+}
+
+// TrustedZone decides what a device reports about its trusted geofences.
+// Positions are still sampled at the regular interval, only posting is
+// suppressed, so leaving a fence is reported on the next sample.
+type TrustedZone struct {
+	mtx       sync.Mutex
+	fences    []Geofence
+	heartbeat time.Duration
+	inside    string
+	lastPost  time.Time
+}
+
+// NewTrustedZone returns a zone of the trusted fences, fences not flagged
+// trusted are ignored. A heartbeat of 0 means DefaultTrustedHeartbeatSeconds.
+func NewTrustedZone(fences []Geofence, heartbeatSeconds int) *TrustedZone {
+	if heartbeatSeconds <= 0 {
+		heartbeatSeconds = DefaultTrustedHeartbeatSeconds
+	}
+	zone := &TrustedZone{heartbeat: time.Duration(heartbeatSeconds) * time.Second}
+	for _, fence := range fences {
+		if fence.Trusted && fence.RadiusMeters > 0 {
+			zone.fences = append(zone.fences, fence)
+		}
+	}
+	return zone
+}
+
+// Filter returns the position to report and the trusted fence name, "" when
+// the position is outside every trusted fence. Inside a fence the position
+// is the fence center and post is false, except on entering the fence and
+// once per heartbeat. Outside the fences every position is posted.
+func (this *TrustedZone) Filter(lat, lon float64, now time.Time) (float64, float64, string, bool) {
+	if this == nil {
+		return lat, lon, "", true
+	}
+	this.mtx.Lock()
+	defer this.mtx.Unlock()
+	for i := range this.fences {
+		fence := &this.fences[i]
+		if !fence.Contains(lat, lon) {
+			continue
+		}
+		post := this.inside != fence.Name || now.Sub(this.lastPost) >= this.heartbeat
+		if post {
+			this.inside = fence.Name
+			this.lastPost = now
+		}
+		return fence.Latitude, fence.Longitude, fence.Name, post
+	}
+	this.inside = ""
+	return lat, lon, "", true
+}
+
+// Silence returns the longest time the zone keeps the agent from posting,
+// the heartbeat interval, 0 without trusted fences
+func (this *TrustedZone) Silence() time.Duration {
+	if this == nil || len(this.fences) == 0 {
+		return 0
+	}
+	return this.heartbeat
+}
@@ -0,0 +1,54 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package agentcore
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTrustedZoneFilter(t *testing.T) {
+	home := Geofence{Name: "home", Latitude: 40.0, Longitude: -74.0, RadiusMeters: 200, Trusted: true}
+	school := Geofence{Name: "school", Latitude: 40.1, Longitude: -74.0, RadiusMeters: 200}
+	zone := NewTrustedZone([]Geofence{home, school}, 60)
+	start := time.Unix(1700000000, 0)
+
+	lat, lon, place, post := zone.Filter(40.0005, -74.0005, start)
+	if !post || place != "home" || lat != home.Latitude || lon != home.Longitude {
+		t.Fatalf("entering a trusted fence must post its center, got %v %v %q %v", lat, lon, place, post)
+	}
+	if _, _, _, post = zone.Filter(40.0006, -74.0004, start.Add(10*time.Second)); post {
+		t.Fatal("positions inside a trusted fence must be suppressed")
+	}
+	if _, _, place, post = zone.Filter(40.0006, -74.0004, start.Add(61*time.Second)); !post || place != "home" {
+		t.Fatal("the heartbeat must still post inside the fence")
+	}
+	lat, _, place, post = zone.Filter(40.01, -74.0, start.Add(70*time.Second))
+	if !post || place != "" || lat != 40.01 {
+		t.Fatal("leaving the fence must post the precise position at once")
+	}
+	if _, _, place, post = zone.Filter(40.1, -74.0, start.Add(80*time.Second)); !post || place != "" {
+		t.Fatal("a fence not flagged trusted must not collapse the position")
+	}
+	if _, _, _, post = zone.Filter(40.0, -74.0, start.Add(90*time.Second)); !post {
+		t.Fatal("re-entering the fence must post")
+	}
+
+	var none *TrustedZone
+	if _, _, _, post = none.Filter(1, 2, start); !post {
+		t.Fatal("no zone must post everything")
+	}
+}
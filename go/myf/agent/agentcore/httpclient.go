@@ -0,0 +1,73 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package agentcore
+
+import (
+	"crypto/tls"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// RequestTimeout bounds every request of the agents
+const RequestTimeout = 10 * time.Second
+
+// NewTransport returns a pooling transport that negotiates HTTP/2, keeps the
+// connections to the servers alive between posts and honors the TLS and
+// compression settings. With compression it sends Accept-Encoding: gzip and
+// transparently decodes gzipped responses.
+func NewTransport(skipTLSVerify, compression bool) *http.Transport {
+	dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+	return &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		DialContext:           dialer.DialContext,
+		TLSClientConfig:       &tls.Config{InsecureSkipVerify: skipTLSVerify},
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          16,
+		MaxIdleConnsPerHost:   4,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: time.Second,
+		DisableCompression:    !compression,
+	}
+}
+
+// HTTPClients keeps one client for the current TLS and compression settings,
+// so the auth, registration and location requests reuse its connections. A
+// change of the settings replaces the client and closes the idle connections
+// of the previous one.
+type HTTPClients struct {
+	mtx           sync.Mutex
+	client        *http.Client
+	skipTLSVerify bool
+	compression   bool
+}
+
+// Get returns the client of the settings
+func (this *HTTPClients) Get(skipTLSVerify, compression bool) *http.Client {
+	this.mtx.Lock()
+	defer this.mtx.Unlock()
+	if this.client != nil && this.skipTLSVerify == skipTLSVerify && this.compression == compression {
+		return this.client
+	}
+	if this.client != nil {
+		this.client.CloseIdleConnections()
+	}
+	this.client = &http.Client{Timeout: RequestTimeout, Transport: NewTransport(skipTLSVerify, compression)}
+	this.skipTLSVerify, this.compression = skipTLSVerify, compression
+	return this.client
+}
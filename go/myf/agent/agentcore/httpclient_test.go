@@ -0,0 +1,72 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package agentcore
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+func TestHTTPClientsReuse(t *testing.T) {
+	clients := &HTTPClients{}
+	client := clients.Get(false, true)
+	if clients.Get(false, true) != client {
+		t.Fatal("the same settings must reuse the client")
+	}
+	transport := client.Transport.(*http.Transport)
+	if !transport.ForceAttemptHTTP2 || transport.DisableCompression || transport.TLSClientConfig.InsecureSkipVerify {
+		t.Fatalf("unexpected transport settings %+v", transport)
+	}
+	insecure := clients.Get(true, false)
+	if insecure == client {
+		t.Fatal("changed settings must replace the client")
+	}
+	transport = insecure.Transport.(*http.Transport)
+	if !transport.TLSClientConfig.InsecureSkipVerify || !transport.DisableCompression {
+		t.Fatalf("the transport must honor the settings, got %+v", transport)
+	}
+}
+
+func TestHTTPClientsKeepConnections(t *testing.T) {
+	var conns int32
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	srv.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			atomic.AddInt32(&conns, 1)
+		}
+	}
+	srv.EnableHTTP2 = true
+	srv.StartTLS()
+	defer srv.Close()
+
+	clients := &HTTPClients{}
+	for i := 0; i < 5; i++ {
+		resp, err := clients.Get(true, true).Get(srv.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.ProtoMajor != 2 {
+			t.Fatalf("expected HTTP/2, got %s", resp.Proto)
+		}
+		resp.Body.Close()
+	}
+	if n := atomic.LoadInt32(&conns); n != 1 {
+		t.Fatalf("the requests must share one connection, got %d", n)
+	}
+}
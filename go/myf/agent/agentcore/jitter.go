@@ -0,0 +1,47 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package agentcore
+
+import (
+	"math/rand"
+	"time"
+)
+
+// DefaultStartJitterSeconds is the longest random delay before the first
+// post, so a fleet of agents started together, e.g. after a power outage,
+// does not authenticate and post at the same moment
+const DefaultStartJitterSeconds = 15
+
+// StartJitter returns the longest start delay of the configured seconds,
+// DefaultStartJitterSeconds when unset and none for 0
+func StartJitter(seconds *int) time.Duration {
+	if seconds == nil {
+		return DefaultStartJitterSeconds * time.Second
+	}
+	if *seconds <= 0 {
+		return 0
+	}
+	return time.Duration(*seconds) * time.Second
+}
+
+// RandomDelay returns a random delay between 0 and max, 0 when max is not
+// positive
+func RandomDelay(max time.Duration) time.Duration {
+	if max <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(max) + 1))
+}
@@ -0,0 +1,45 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package agentcore
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStartJitter(t *testing.T) {
+	zero, negative, minute := 0, -5, 60
+	if got := StartJitter(nil); got != DefaultStartJitterSeconds*time.Second {
+		t.Fatalf("an unset jitter must default, got %s", got)
+	}
+	if StartJitter(&zero) != 0 || StartJitter(&negative) != 0 {
+		t.Fatal("0 must disable the jitter")
+	}
+	if got := StartJitter(&minute); got != time.Minute {
+		t.Fatalf("expected a minute, got %s", got)
+	}
+}
+
+func TestRandomDelay(t *testing.T) {
+	if RandomDelay(0) != 0 || RandomDelay(-time.Second) != 0 {
+		t.Fatal("no jitter must not delay")
+	}
+	for i := 0; i < 100; i++ {
+		if delay := RandomDelay(time.Second); delay < 0 || delay > time.Second {
+			t.Fatalf("delay %s is out of range", delay)
+		}
+	}
+}
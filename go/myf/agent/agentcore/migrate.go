@@ -0,0 +1,93 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package agentcore
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/saichler/l8myfamiliy/go/myf/webapi"
+)
+
+// ConfigVersion is the version of the config written by this code.
+//
+// Configs without a version were written before the agents were unified:
+// each agent derived its key with its own salt (SaltLaptop, SaltAndroid) and
+// some saved a website that included the endpoint prefix. Migrate reads such
+// a config with the legacy key, whichever agent wrote it, and the agent then
+// saves it, re-encrypted with the unified Salt and with a bare website.
+const ConfigVersion = 2
+
+// Salt is the key salt of every config written with ConfigVersion
+const Salt = "l8myfamily-agent"
+
+// legacyPrefixes are endpoint prefixes earlier versions may have saved as
+// part of the website URL
+var legacyPrefixes = []string{"/probler", "/my-family"}
+
+// Migrate returns the cipher that decrypts the stored values of the config,
+// and true when the config is from an earlier version and must be saved to
+// complete the migration. ownSalt is the legacy salt of the calling agent,
+// it is tried first, then the salt of the other agent so a config moved
+// between platforms still loads.
+func (this *Config) Migrate(ownSalt string) (*Cipher, bool) {
+	migrated := this.migrateWebsite()
+	if this.Version >= ConfigVersion {
+		return NewCipher(this.DeviceID, Salt), migrated
+	}
+	probe := this.EncryptedUser
+	if probe == "" {
+		probe = this.EncryptedPass
+	}
+	candidates := []string{ownSalt, SaltLaptop, SaltAndroid, Salt}
+	for _, salt := range candidates {
+		c := NewCipher(this.DeviceID, salt)
+		if probe == "" {
+			return c, true
+		}
+		if _, err := c.Decrypt(probe); err == nil {
+			return c, true
+		}
+	}
+	// Nothing decrypts, the credentials are asked again and saved with the
+	// unified key
+	return NewCipher(this.DeviceID, Salt), true
+}
+
+// migrateWebsite strips a trailing slash and a legacy endpoint prefix from
+// the website, the prefix is added by ServiceURL and EndpointURL
+func (this *Config) migrateWebsite() bool {
+	website := strings.TrimSuffix(this.Website, "/")
+	for _, prefix := range legacyPrefixes {
+		website = strings.TrimSuffix(website, prefix)
+	}
+	if website == this.Website {
+		return false
+	}
+	this.Website = website
+	return true
+}
+
+// ServiceURL returns the URL of a family service in the service area,
+// e.g. https://host/my-family/53/Location
+func ServiceURL(website string, serviceArea int, service string) string {
+	return fmt.Sprintf("%s%s%d/%s", strings.TrimSuffix(website, "/"), webapi.Prefix, serviceArea, service)
+}
+
+// EndpointURL returns the URL of a raw endpoint, e.g. https://host/my-family/location
+func EndpointURL(website, path string) string {
+	return strings.TrimSuffix(website, "/") + webapi.Prefix + strings.TrimPrefix(path, "/")
+}
@@ -0,0 +1,95 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package agentcore
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestMigrateConfigOfEitherAgent(t *testing.T) {
+	// Both agents must load both legacy configs, a config may be copied
+	// from one platform to the other
+	for _, file := range []string{"android-config-v1.json", "laptop-config-v1.json"} {
+		for _, ownSalt := range []string{SaltAndroid, SaltLaptop} {
+			cfg := &Config{}
+			if _, err := LoadConfig(filepath.Join("testdata", file), cfg); err != nil {
+				t.Fatal(err)
+			}
+			c, migrated := cfg.Migrate(ownSalt)
+			if !migrated {
+				t.Fatalf("%s: a config without a version must migrate", file)
+			}
+			user, pass := cfg.Credentials(c)
+			if user != "alice" || pass != "s3cret!" {
+				t.Fatalf("%s: credentials did not decrypt, got %q %q", file, user, pass)
+			}
+		}
+	}
+}
+
+func TestMigratedConfigIsStable(t *testing.T) {
+	cfg := &Config{}
+	if _, err := LoadConfig(filepath.Join("testdata", "laptop-config-v1.json"), cfg); err != nil {
+		t.Fatal(err)
+	}
+	c, _ := cfg.Migrate(SaltLaptop)
+	user, pass := cfg.Credentials(c)
+
+	// Saved the way the agents save a migrated config
+	cfg.Version = ConfigVersion
+	if err := cfg.SetCredentials(NewCipher(cfg.DeviceID, Salt), user, pass); err != nil {
+		t.Fatal(err)
+	}
+	filename := filepath.Join(t.TempDir(), "agent.json")
+	if err := SaveConfig(filename, cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded := &Config{}
+	if _, err := LoadConfig(filename, loaded); err != nil {
+		t.Fatal(err)
+	}
+	c, migrated := loaded.Migrate(SaltAndroid)
+	if migrated || loaded.Version != ConfigVersion {
+		t.Fatalf("a current config must not migrate again, version %d", loaded.Version)
+	}
+	if user, pass := loaded.Credentials(c); user != "alice" || pass != "s3cret!" {
+		t.Fatalf("credentials did not decrypt with the unified key, got %q %q", user, pass)
+	}
+}
+
+func TestMigrateWebsite(t *testing.T) {
+	for website, expected := range map[string]string{
+		"https://www.probler.dev:9092":            "https://www.probler.dev:9092",
+		"https://www.probler.dev:9092/":           "https://www.probler.dev:9092",
+		"https://www.probler.dev:9092/probler/":   "https://www.probler.dev:9092",
+		"https://www.probler.dev:9092/my-family":  "https://www.probler.dev:9092",
+		"https://www.probler.dev:9092/my-family/": "https://www.probler.dev:9092",
+	} {
+		cfg := &Config{Version: ConfigVersion, Website: website}
+		_, migrated := cfg.Migrate(SaltLaptop)
+		if cfg.Website != expected || migrated != (website != expected) {
+			t.Fatalf("%s: got %s migrated %v", website, cfg.Website, migrated)
+		}
+	}
+	if url := ServiceURL("https://host/", 53, "Location"); url != "https://host/my-family/53/Location" {
+		t.Fatalf("unexpected service url %s", url)
+	}
+	if url := EndpointURL("https://host", "location"); url != "https://host/my-family/location" {
+		t.Fatalf("unexpected endpoint url %s", url)
+	}
+}
@@ -0,0 +1,91 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package agentcore
+
+import (
+	"sync"
+	"time"
+
+	"github.com/saichler/l8myfamiliy/go/myf/geo"
+)
+
+// DefaultHeartbeatSeconds is the heartbeat interval of a device that does
+// not move, below the default server online window of 300 seconds
+const DefaultHeartbeatSeconds = 240
+
+// Decision is what the agent sends for a sampled position
+type Decision int
+
+const (
+	// DecisionPost posts the full location
+	DecisionPost Decision = iota
+	// DecisionHeartbeat posts a heartbeat, the device id and time only, so
+	// the device stays online without a new position
+	DecisionHeartbeat
+	// DecisionSkip sends nothing
+	DecisionSkip
+)
+
+// MovementFilter posts a position only when the device moved, and a
+// heartbeat when it did not move for the heartbeat interval
+type MovementFilter struct {
+	mtx       sync.Mutex
+	minMeters float64
+	heartbeat time.Duration
+	posted    bool
+	lastLat   float64
+	lastLon   float64
+	lastSent  time.Time
+}
+
+// NewMovementFilter returns a filter that posts a position after a move of at
+// least minMeters from the last posted one, 0 posts every position. A
+// heartbeat of 0 means DefaultHeartbeatSeconds.
+func NewMovementFilter(minMeters float64, heartbeatSeconds int) *MovementFilter {
+	if heartbeatSeconds <= 0 {
+		heartbeatSeconds = DefaultHeartbeatSeconds
+	}
+	return &MovementFilter{minMeters: minMeters, heartbeat: time.Duration(heartbeatSeconds) * time.Second}
+}
+
+// Decide returns what to send for the position sampled at now, and records
+// it as sent unless the decision is DecisionSkip
+func (this *MovementFilter) Decide(lat, lon float64, now time.Time) Decision {
+	if this == nil || this.minMeters <= 0 {
+		return DecisionPost
+	}
+	this.mtx.Lock()
+	defer this.mtx.Unlock()
+	if !this.posted || geo.Distance(this.lastLat, this.lastLon, lat, lon) >= this.minMeters {
+		this.posted = true
+		this.lastLat, this.lastLon, this.lastSent = lat, lon, now
+		return DecisionPost
+	}
+	if now.Sub(this.lastSent) >= this.heartbeat {
+		this.lastSent = now
+		return DecisionHeartbeat
+	}
+	return DecisionSkip
+}
+
+// Silence returns the longest time the filter keeps the agent from posting,
+// the heartbeat interval, 0 when every position is posted
+func (this *MovementFilter) Silence() time.Duration {
+	if this == nil || this.minMeters <= 0 {
+		return 0
+	}
+	return this.heartbeat
+}
@@ -0,0 +1,48 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package agentcore
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMovementFilter(t *testing.T) {
+	filter := NewMovementFilter(50, 60)
+	start := time.Unix(1700000000, 0)
+	steps := []struct {
+		lat      float64
+		after    time.Duration
+		expected Decision
+	}{
+		{40.0, 0, DecisionPost},
+		// About 11 meters, not a move
+		{40.0001, 10 * time.Second, DecisionSkip},
+		{40.0001, 61 * time.Second, DecisionHeartbeat},
+		{40.0001, 100 * time.Second, DecisionSkip},
+		// About 110 meters from the last posted position
+		{40.001, 110 * time.Second, DecisionPost},
+		{40.001, 171 * time.Second, DecisionHeartbeat},
+	}
+	for i, step := range steps {
+		if got := filter.Decide(step.lat, -74, start.Add(step.after)); got != step.expected {
+			t.Fatalf("step %d: expected %d, got %d", i, step.expected, got)
+		}
+	}
+	if NewMovementFilter(0, 0).Decide(1, 1, start) != DecisionPost {
+		t.Fatal("a filter without a distance must post every position")
+	}
+}
@@ -0,0 +1,195 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package agentcore
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultNatsPort is the port of a nats:// url without one
+const DefaultNatsPort = "4222"
+
+// ErrNatsRejected is returned when the NATS server answers -ERR, e.g. an
+// authorization violation or a subject the user may not publish to
+var ErrNatsRejected = errors.New("nats server rejected")
+
+// NatsPublisher publishes messages with the core NATS protocol. It connects
+// on the first publish and again after a failed one. A publish waits for the
+// PONG of a PING sent after it, so it returns once the server processed it.
+type NatsPublisher struct {
+	url     *url.URL
+	timeout time.Duration
+	mtx     sync.Mutex
+	conn    net.Conn
+	reader  *bufio.Reader
+}
+
+// NewNatsPublisher returns a publisher of the nats://[user:pass@]host[:port]
+// server, a user without a password is sent as a token. Each exchange with
+// the server is bounded by timeout.
+func NewNatsPublisher(natsURL string, timeout time.Duration) (*NatsPublisher, error) {
+	u, err := url.Parse(natsURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid nats url: %w", err)
+	}
+	if u.Scheme != "nats" || u.Hostname() == "" {
+		return nil, fmt.Errorf("nats url %s is not nats://host[:port]", u.Redacted())
+	}
+	if u.Port() == "" {
+		u.Host = net.JoinHostPort(u.Hostname(), DefaultNatsPort)
+	}
+	return &NatsPublisher{url: u, timeout: timeout}, nil
+}
+
+// ValidNatsSubject reports whether subject is a subject a message can be
+// published to, dot separated tokens without wildcards or whitespace
+func ValidNatsSubject(subject string) bool {
+	if subject == "" || strings.ContainsAny(subject, " \t\r\n*>") {
+		return false
+	}
+	for _, token := range strings.Split(subject, ".") {
+		if token == "" {
+			return false
+		}
+	}
+	return true
+}
+
+// String returns the server url with the password redacted
+func (this *NatsPublisher) String() string {
+	return this.url.Redacted()
+}
+
+// Connect connects to the server unless it is connected
+func (this *NatsPublisher) Connect() error {
+	this.mtx.Lock()
+	defer this.mtx.Unlock()
+	if this.conn != nil {
+		return nil
+	}
+	return this.dial()
+}
+
+// Publish sends data to the subject
+func (this *NatsPublisher) Publish(subject string, data []byte) error {
+	if !ValidNatsSubject(subject) {
+		return fmt.Errorf("invalid nats subject %q", subject)
+	}
+	this.mtx.Lock()
+	defer this.mtx.Unlock()
+	if this.conn == nil {
+		if err := this.dial(); err != nil {
+			return err
+		}
+	}
+	err := this.exchange(fmt.Sprintf("PUB %s %d\r\n%s\r\nPING\r\n", subject, len(data), data))
+	if err != nil {
+		this.close()
+	}
+	return err
+}
+
+// Close closes the connection, the next publish connects again
+func (this *NatsPublisher) Close() {
+	this.mtx.Lock()
+	defer this.mtx.Unlock()
+	this.close()
+}
+
+func (this *NatsPublisher) close() {
+	if this.conn != nil {
+		this.conn.Close()
+		this.conn = nil
+	}
+}
+
+// dial connects, reads the server INFO and sends the CONNECT, must be called
+// with the lock held
+func (this *NatsPublisher) dial() error {
+	conn, err := net.DialTimeout("tcp", this.url.Host, this.timeout)
+	if err != nil {
+		return err
+	}
+	this.conn = conn
+	this.reader = bufio.NewReader(conn)
+	conn.SetDeadline(time.Now().Add(this.timeout))
+	line, err := this.readLine()
+	if err == nil && !strings.HasPrefix(line, "INFO ") {
+		err = fmt.Errorf("%s is not a nats server, it sent %q", this.url.Host, line)
+	}
+	if err != nil {
+		this.close()
+		return err
+	}
+	options := map[string]interface{}{"verbose": false, "pedantic": false, "lang": "go", "name": "mfagent",
+		"version": Version}
+	if user := this.url.User; user != nil {
+		if pass, ok := user.Password(); ok {
+			options["user"] = user.Username()
+			options["pass"] = pass
+		} else {
+			options["auth_token"] = user.Username()
+		}
+	}
+	data, _ := json.Marshal(options)
+	// A rejected CONNECT is answered -ERR instead of the PONG
+	if err := this.exchange("CONNECT " + string(data) + "\r\nPING\r\n"); err != nil {
+		this.close()
+		return err
+	}
+	return nil
+}
+
+// exchange writes the frame and reads up to the PONG of its PING, a PING of
+// the server is answered on the way
+func (this *NatsPublisher) exchange(frame string) error {
+	this.conn.SetDeadline(time.Now().Add(this.timeout))
+	if _, err := this.conn.Write([]byte(frame)); err != nil {
+		return err
+	}
+	for {
+		line, err := this.readLine()
+		if err != nil {
+			return err
+		}
+		switch {
+		case line == "PONG":
+			return nil
+		case line == "PING":
+			if _, err := this.conn.Write([]byte("PONG\r\n")); err != nil {
+				return err
+			}
+		case strings.HasPrefix(line, "-ERR"):
+			return fmt.Errorf("%w: %s", ErrNatsRejected, strings.TrimSpace(strings.TrimPrefix(line, "-ERR")))
+		}
+	}
+}
+
+func (this *NatsPublisher) readLine() (string, error) {
+	line, err := this.reader.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
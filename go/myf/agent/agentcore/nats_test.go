@@ -0,0 +1,58 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package agentcore
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/saichler/l8myfamiliy/go/myf/agent/agenttest"
+)
+
+func TestNatsPublisher(t *testing.T) {
+	server := agenttest.NewNatsServer(t, "agent", "secret")
+	publisher, err := NewNatsPublisher("nats://agent:secret@"+server.URL()[len("nats://"):], time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer publisher.Close()
+	if err := publisher.Publish("family.location", []byte("one\r\ntwo")); err != nil {
+		t.Fatal(err)
+	}
+	// A dropped connection is dialed again by the next publish
+	server.Disconnect()
+	publisher.Publish("family.location", []byte("lost"))
+	if err := publisher.Publish("family.location", []byte("three")); err != nil {
+		t.Fatal(err)
+	}
+	messages := server.Messages()
+	if len(messages) < 2 || string(messages[0].Data) != "one\r\ntwo" || string(messages[len(messages)-1].Data) != "three" ||
+		messages[0].Subject != "family.location" {
+		t.Fatalf("unexpected messages %v", messages)
+	}
+	if err := publisher.Publish("family.*", nil); err == nil {
+		t.Fatal("a wildcard subject must be rejected")
+	}
+
+	denied, _ := NewNatsPublisher("nats://agent:wrong@"+server.URL()[len("nats://"):], time.Second)
+	if err := denied.Publish("family.location", []byte("x")); !errors.Is(err, ErrNatsRejected) {
+		t.Fatalf("wrong credentials must be rejected, got %v", err)
+	}
+	if _, err := NewNatsPublisher("http://localhost", time.Second); err == nil {
+		t.Fatal("a url that is not nats:// must be rejected")
+	}
+}
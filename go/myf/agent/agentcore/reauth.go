@@ -0,0 +1,141 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package agentcore
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// DefaultReauthCooldownSeconds is the wait before a second re-authentication
+// that was not followed by an accepted post
+const DefaultReauthCooldownSeconds = 30
+
+// MaxReauthBackoff caps the wait between re-authentications that keep failing
+const MaxReauthBackoff = 30 * time.Minute
+
+// ReauthFailingAttempts is how many re-authentications without an accepted
+// post make the authentication persistently failing
+const ReauthFailingAttempts = 3
+
+// ErrReauthCoolingDown is returned for a re-authentication attempted within
+// the cooldown of the previous one
+var ErrReauthCoolingDown = errors.New("re-authentication is cooling down")
+
+// ReauthGate spaces the re-authentications of an agent. A server that keeps
+// answering 401, e.g. for a disabled account, would otherwise get a login
+// with every post. The first re-authentication after an accepted post is
+// never delayed, each further one waits twice as long as the one before,
+// from the cooldown up to MaxReauthBackoff.
+type ReauthGate struct {
+	mtx      sync.Mutex
+	cooldown time.Duration
+	last     time.Time
+	// attempts are the re-authentications since the last accepted post
+	attempts int
+	// accepted is when the server last accepted a post, zero before the first
+	accepted time.Time
+}
+
+// NewReauthGate returns a gate with the cooldown of the configured seconds,
+// DefaultReauthCooldownSeconds when not positive
+func NewReauthGate(seconds int) *ReauthGate {
+	if seconds <= 0 {
+		seconds = DefaultReauthCooldownSeconds
+	}
+	return &ReauthGate{cooldown: time.Duration(seconds) * time.Second}
+}
+
+// wait returns the wait after the last attempt, the mutex is held
+func (this *ReauthGate) wait() time.Duration {
+	if this.attempts == 0 {
+		return 0
+	}
+	wait := this.cooldown
+	for i := 1; i < this.attempts && wait < MaxReauthBackoff; i++ {
+		wait *= 2
+	}
+	if wait > MaxReauthBackoff {
+		wait = MaxReauthBackoff
+	}
+	// A cooldown over the cap is still waited
+	if wait < this.cooldown {
+		wait = this.cooldown
+	}
+	return wait
+}
+
+// Ready returns nil when a re-authentication may be sent at now, or
+// ErrReauthCoolingDown with the time left
+func (this *ReauthGate) Ready(now time.Time) (time.Duration, error) {
+	this.mtx.Lock()
+	defer this.mtx.Unlock()
+	if left := this.last.Add(this.wait()).Sub(now); left > 0 {
+		return left, ErrReauthCoolingDown
+	}
+	return 0, nil
+}
+
+// Attempted records a re-authentication the server answered at now, whether
+// it issued a token or rejected the credentials. One that did not reach the
+// server is not a credential problem and is not recorded.
+func (this *ReauthGate) Attempted(now time.Time) {
+	this.mtx.Lock()
+	defer this.mtx.Unlock()
+	this.last = now
+	this.attempts++
+}
+
+// Accepted records a post the server accepted at now, the next
+// re-authentication is not delayed. It returns true when the authentication
+// was failing.
+func (this *ReauthGate) Accepted(now time.Time) bool {
+	this.mtx.Lock()
+	defer this.mtx.Unlock()
+	failing := this.attempts >= ReauthFailingAttempts
+	this.attempts = 0
+	this.accepted = now
+	return failing
+}
+
+// Failing returns true when the last re-authentications were not followed
+// by an accepted post, the credentials or the account need attention
+func (this *ReauthGate) Failing() bool {
+	this.mtx.Lock()
+	defer this.mtx.Unlock()
+	return this.attempts >= ReauthFailingAttempts
+}
+
+// SinceAccepted returns the time since the server last accepted a post at
+// now, false when it accepted none yet
+func (this *ReauthGate) SinceAccepted(now time.Time) (time.Duration, bool) {
+	this.mtx.Lock()
+	defer this.mtx.Unlock()
+	if this.accepted.IsZero() {
+		return 0, false
+	}
+	return now.Sub(this.accepted), true
+}
+
+// FailingFor describes for the logs how long no post was accepted
+func (this *ReauthGate) FailingFor(now time.Time) string {
+	since, ok := this.SinceAccepted(now)
+	if !ok {
+		return "no post was accepted since the start"
+	}
+	return "no post was accepted for " + since.Round(time.Second).String()
+}
@@ -0,0 +1,99 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package agentcore
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestReauthGateBacksOff(t *testing.T) {
+	gate := NewReauthGate(10)
+	now := time.Now()
+	// The first re-authentication is never delayed, each further one
+	// waits twice as long
+	for i, wait := range []time.Duration{0, 10 * time.Second, 20 * time.Second, 40 * time.Second} {
+		if wait > 0 {
+			if left, err := gate.Ready(now.Add(wait - time.Second)); !errors.Is(err, ErrReauthCoolingDown) || left != time.Second {
+				t.Fatalf("attempt %d: expected to wait another second, got %s %v", i, left, err)
+			}
+		}
+		now = now.Add(wait)
+		if _, err := gate.Ready(now); err != nil {
+			t.Fatalf("attempt %d: %v", i, err)
+		}
+		gate.Attempted(now)
+		if failing := gate.Failing(); failing != (i+1 >= ReauthFailingAttempts) {
+			t.Fatalf("attempt %d: expected failing=%v", i, !failing)
+		}
+	}
+	if !gate.Accepted(now) || gate.Failing() {
+		t.Fatal("an accepted post must end the failing state")
+	}
+	if _, err := gate.Ready(now); err != nil {
+		t.Fatalf("the first re-authentication after an accepted post must not wait, got %v", err)
+	}
+}
+
+func TestReauthGateCapsTheBackoff(t *testing.T) {
+	gate := NewReauthGate(0)
+	if gate.cooldown != DefaultReauthCooldownSeconds*time.Second {
+		t.Fatalf("expected the default cooldown, got %s", gate.cooldown)
+	}
+	now := time.Now()
+	for i := 0; i < 20; i++ {
+		gate.Attempted(now)
+	}
+	if left, _ := gate.Ready(now); left != MaxReauthBackoff {
+		t.Fatalf("expected the backoff capped at %s, got %s", MaxReauthBackoff, left)
+	}
+	// A cooldown over the cap is still waited
+	long := NewReauthGate(3600)
+	long.Attempted(now)
+	long.Attempted(now)
+	if left, _ := long.Ready(now); left != time.Hour {
+		t.Fatalf("expected the configured cooldown, got %s", left)
+	}
+}
+
+func TestReauthGateFailingState(t *testing.T) {
+	gate := NewReauthGate(10)
+	now := time.Now()
+	if _, ok := gate.SinceAccepted(now); ok || gate.FailingFor(now) != "no post was accepted since the start" {
+		t.Fatal("a gate without an accepted post has no time since one")
+	}
+	gate.Accepted(now)
+	for i := 0; i < ReauthFailingAttempts; i++ {
+		if gate.Failing() {
+			t.Fatalf("attempt %d: the gate must not fail before %d attempts", i, ReauthFailingAttempts)
+		}
+		now = now.Add(time.Minute)
+		gate.Attempted(now)
+	}
+	if !gate.Failing() {
+		t.Fatal("the gate must fail after the attempts without an accepted post")
+	}
+	if since, ok := gate.SinceAccepted(now); !ok || since != 3*time.Minute || gate.FailingFor(now) != "no post was accepted for 3m0s" {
+		t.Fatalf("expected 3m since the accepted post, got %s %v", since, ok)
+	}
+	if !gate.Accepted(now) || gate.Failing() {
+		t.Fatal("an accepted post must end the failing state")
+	}
+	if since, _ := gate.SinceAccepted(now.Add(time.Second)); since != time.Second {
+		t.Fatalf("the accepted post must reset the time since one, got %s", since)
+	}
+}
@@ -0,0 +1,33 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package agentcore
+
+import "time"
+
+// ReportInterval returns the interval the agent announces to the server, the
+// longest time it may stay silent: the sample interval, or the heartbeat of
+// the movement filter or of the trusted zone when they suppress posts. The
+// server sizes the offline grace of the device from it.
+func ReportInterval(sample time.Duration, movement *MovementFilter, zone *TrustedZone) int32 {
+	longest := sample
+	if silence := movement.Silence(); silence > longest {
+		longest = silence
+	}
+	if silence := zone.Silence(); silence > longest {
+		longest = silence
+	}
+	return int32((longest + time.Second - 1) / time.Second)
+}
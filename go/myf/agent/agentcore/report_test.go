@@ -0,0 +1,37 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package agentcore
+
+import (
+	"testing"
+	"time"
+)
+
+func TestReportInterval(t *testing.T) {
+	if got := ReportInterval(1500*time.Millisecond, nil, nil); got != 2 {
+		t.Fatalf("the sample interval is rounded up to seconds, got %d", got)
+	}
+	if got := ReportInterval(10*time.Second, NewMovementFilter(0, 0), NewTrustedZone(nil, 0)); got != 10 {
+		t.Fatalf("filters that post every position do not count, got %d", got)
+	}
+	if got := ReportInterval(10*time.Second, NewMovementFilter(50, 120), nil); got != 120 {
+		t.Fatalf("the movement heartbeat bounds the silence, got %d", got)
+	}
+	zone := NewTrustedZone([]Geofence{{Name: "home", RadiusMeters: 100, Trusted: true}}, 0)
+	if got := ReportInterval(10*time.Second, NewMovementFilter(50, 120), zone); got != DefaultTrustedHeartbeatSeconds {
+		t.Fatalf("the trusted heartbeat bounds the silence, got %d", got)
+	}
+}
@@ -0,0 +1,63 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package agentcore
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MaxRetryAfter caps the wait a server asks for, so a wrong header does not
+// stall the agent
+const MaxRetryAfter = 2 * time.Minute
+
+// RetryAfter returns the wait the Retry-After header of a 503 or 429 response
+// asks for, in seconds or as an HTTP date, capped to max. now is the server
+// clock the date is compared with. It returns false for other responses and
+// for a missing or invalid header.
+func RetryAfter(resp *http.Response, now time.Time, max time.Duration) (time.Duration, bool) {
+	if resp.StatusCode != http.StatusServiceUnavailable && resp.StatusCode != http.StatusTooManyRequests {
+		return 0, false
+	}
+	value := strings.TrimSpace(resp.Header.Get("Retry-After"))
+	if value == "" {
+		return 0, false
+	}
+	var wait time.Duration
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		// Beyond the cap the multiplication could overflow
+		if seconds > int64(max/time.Second) {
+			return max, true
+		}
+		wait = time.Duration(seconds) * time.Second
+	} else if at, err := http.ParseTime(value); err == nil {
+		wait = at.Sub(now)
+		if wait < 0 {
+			wait = 0
+		}
+	} else {
+		return 0, false
+	}
+	if wait > max {
+		wait = max
+	}
+	return wait, true
+}
@@ -0,0 +1,56 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package agentcore
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestRetryAfter(t *testing.T) {
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	response := func(status int, retryAfter string) *http.Response {
+		resp := &http.Response{StatusCode: status, Header: make(http.Header)}
+		if retryAfter != "" {
+			resp.Header.Set("Retry-After", retryAfter)
+		}
+		return resp
+	}
+	for _, test := range []struct {
+		resp   *http.Response
+		wait   time.Duration
+		hinted bool
+	}{
+		{response(http.StatusServiceUnavailable, "7"), 7 * time.Second, true},
+		{response(http.StatusTooManyRequests, " 0 "), 0, true},
+		{response(http.StatusServiceUnavailable, now.Add(30*time.Second).Format(http.TimeFormat)), 30 * time.Second, true},
+		{response(http.StatusTooManyRequests, now.Add(-time.Minute).Format(http.TimeFormat)), 0, true},
+		{response(http.StatusServiceUnavailable, "3600"), time.Minute, true},
+		{response(http.StatusServiceUnavailable, "99999999999999"), time.Minute, true},
+		{response(http.StatusServiceUnavailable, now.Add(time.Hour).Format(http.TimeFormat)), time.Minute, true},
+		{response(http.StatusServiceUnavailable, ""), 0, false},
+		{response(http.StatusServiceUnavailable, "soon"), 0, false},
+		{response(http.StatusServiceUnavailable, "-5"), 0, false},
+		{response(http.StatusInternalServerError, "7"), 0, false},
+	} {
+		wait, hinted := RetryAfter(test.resp, now, time.Minute)
+		if wait != test.wait || hinted != test.hinted {
+			t.Fatalf("%d Retry-After %q: expected %s %v, got %s %v", test.resp.StatusCode,
+				test.resp.Header.Get("Retry-After"), test.wait, test.hinted, wait, hinted)
+		}
+	}
+}
@@ -0,0 +1,41 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package agentcore
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/saichler/l8myfamiliy/go/myf/webapi"
+)
+
+// FetchSchemaVersion returns the newest location post schema the server
+// decodes, from its health endpoint. A server that does not tell, or cannot
+// be asked, is taken to support schema 1 only.
+func FetchSchemaVersion(client *http.Client, website string) int32 {
+	resp, err := client.Get(EndpointURL(website, "health"))
+	if err != nil {
+		return 1
+	}
+	defer resp.Body.Close()
+	health := struct {
+		SchemaVersion int32 `json:"schemaVersion"`
+	}{}
+	if err := json.NewDecoder(resp.Body).Decode(&health); err != nil {
+		return 1
+	}
+	return webapi.NegotiateSchema(health.SchemaVersion)
+}
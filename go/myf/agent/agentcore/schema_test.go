@@ -0,0 +1,38 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package agentcore
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/saichler/l8myfamiliy/go/myf/agent/agenttest"
+	"github.com/saichler/l8myfamiliy/go/myf/webapi"
+)
+
+func TestFetchSchemaVersion(t *testing.T) {
+	srv := agenttest.NewServer(t, "user", "pass")
+	for reported, want := range map[int32]int32{0: 1, 1: 1, 2: 2, webapi.LocationSchemaVersion + 1: webapi.LocationSchemaVersion} {
+		srv.SchemaVersion = reported
+		if got := FetchSchemaVersion(http.DefaultClient, srv.URL); got != want {
+			t.Fatalf("server reporting %d: expected schema %d, got %d", reported, want, got)
+		}
+	}
+	srv.Close()
+	if got := FetchSchemaVersion(http.DefaultClient, srv.URL); got != 1 {
+		t.Fatalf("an unreachable server must be taken as schema 1, got %d", got)
+	}
+}
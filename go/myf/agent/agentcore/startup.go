@@ -0,0 +1,69 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package agentcore
+
+import "time"
+
+// DefaultStartupRetries is how many times the agents retry the first login
+// and registration, about five minutes with the backoff, so a device that
+// boots before its network is up still starts
+const DefaultStartupRetries = 10
+
+const (
+	startupMinBackoff = time.Second
+	startupMaxBackoff = time.Minute
+)
+
+// StartupRetries returns the retries of the configured count,
+// DefaultStartupRetries when unset, none for 0 and unlimited when negative
+func StartupRetries(retries *int) int {
+	if retries == nil {
+		return DefaultStartupRetries
+	}
+	return *retries
+}
+
+// StartupRetry retries the startup of an agent that failed transiently,
+// waiting twice as long after each failure from MinBackoff up to MaxBackoff
+type StartupRetry struct {
+	Retries    int
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+}
+
+// NewStartupRetry returns the startup retry of the configured count, see
+// StartupRetries
+func NewStartupRetry(retries *int) *StartupRetry {
+	return &StartupRetry{Retries: StartupRetries(retries), MinBackoff: startupMinBackoff, MaxBackoff: startupMaxBackoff}
+}
+
+// Run calls step until it succeeds, fails with an error permanent returns
+// true for, e.g. rejected credentials, or the retries are used up, and
+// returns its last error. retrying is told every wait before a retry.
+func (this *StartupRetry) Run(step func() error, permanent func(error) bool, retrying func(wait time.Duration, err error)) error {
+	wait := this.MinBackoff
+	for attempt := 0; ; attempt++ {
+		err := step()
+		if err == nil || permanent(err) || (this.Retries >= 0 && attempt >= this.Retries) {
+			return err
+		}
+		retrying(wait, err)
+		time.Sleep(wait)
+		if wait *= 2; wait > this.MaxBackoff {
+			wait = this.MaxBackoff
+		}
+	}
+}
@@ -0,0 +1,75 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package agentcore
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+var errTestRejected = errors.New("rejected")
+
+func TestStartupRetries(t *testing.T) {
+	zero, forever := 0, -1
+	if StartupRetries(nil) != DefaultStartupRetries || StartupRetries(&zero) != 0 || StartupRetries(&forever) != -1 {
+		t.Fatal("unexpected startup retries")
+	}
+}
+
+func TestStartupRetryRun(t *testing.T) {
+	retry := &StartupRetry{Retries: 3, MinBackoff: time.Millisecond, MaxBackoff: 2 * time.Millisecond}
+	waits := make([]time.Duration, 0)
+	retrying := func(wait time.Duration, err error) { waits = append(waits, wait) }
+	isRejected := func(err error) bool { return errors.Is(err, errTestRejected) }
+
+	// The step succeeds once the network is up
+	calls := 0
+	err := retry.Run(func() error {
+		if calls++; calls < 3 {
+			return errors.New("network is unreachable")
+		}
+		return nil
+	}, isRejected, retrying)
+	if err != nil || calls != 3 {
+		t.Fatalf("expected success on the third attempt, got %v after %d", err, calls)
+	}
+	if len(waits) != 2 || waits[0] != time.Millisecond || waits[1] != 2*time.Millisecond {
+		t.Fatalf("expected a doubling backoff, got %v", waits)
+	}
+
+	// The retries are used up, the last error is returned
+	calls = 0
+	if err := retry.Run(func() error { calls++; return errors.New("down") }, isRejected, retrying); err == nil || calls != 4 {
+		t.Fatalf("expected 1 attempt and 3 retries, got %v after %d", err, calls)
+	}
+	if waits[len(waits)-1] != 2*time.Millisecond {
+		t.Fatalf("the backoff must be capped, got %v", waits)
+	}
+
+	// A rejection is never retried
+	calls = 0
+	if err := retry.Run(func() error { calls++; return errTestRejected }, isRejected, retrying); err != errTestRejected || calls != 1 {
+		t.Fatalf("a permanent error must not be retried, got %v after %d", err, calls)
+	}
+
+	// Without retries the first failure is returned
+	retry.Retries = 0
+	calls = 0
+	if err := retry.Run(func() error { calls++; return errors.New("down") }, isRejected, retrying); err == nil || calls != 1 {
+		t.Fatalf("expected a single attempt, got %d", calls)
+	}
+}
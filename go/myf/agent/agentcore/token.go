@@ -0,0 +1,109 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package agentcore
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultTokenRenewMargin is how long before its expiry a bearer token is
+// renewed, a short lived token is renewed at three quarters of its lifetime
+const DefaultTokenRenewMargin = time.Minute
+
+// TokenExpiry returns when the bearer token expires: expiresIn seconds after
+// issued when the auth response carries it, otherwise the exp claim of a JWT.
+// It returns the zero time when the expiry is unknown.
+func TokenExpiry(token string, expiresIn int64, issued time.Time) time.Time {
+	if expiresIn > 0 {
+		return issued.Add(time.Duration(expiresIn) * time.Second)
+	}
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return time.Time{}
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return time.Time{}
+	}
+	claims := struct {
+		Exp float64 `json:"exp"`
+	}{}
+	if err := json.Unmarshal(payload, &claims); err != nil || claims.Exp <= 0 {
+		return time.Time{}
+	}
+	return time.Unix(int64(claims.Exp), 0)
+}
+
+// TokenRenewal tracks when the bearer token is due for renewal. The times
+// are server times, see Clock.
+type TokenRenewal struct {
+	mtx     sync.Mutex
+	maxAge  time.Duration
+	renewAt time.Time
+}
+
+// NewTokenRenewal returns a renewal that caches a token at most maxAge,
+// also one without a known expiry. 0 caches a token until its expiry.
+func NewTokenRenewal(maxAge time.Duration) *TokenRenewal {
+	return &TokenRenewal{maxAge: maxAge}
+}
+
+// Issued records a new token, issued at the given time with the expiresIn
+// of the auth response, 0 when it has none
+func (this *TokenRenewal) Issued(token string, expiresIn int64, issued time.Time) {
+	expiry := TokenExpiry(token, expiresIn, issued)
+	if this.maxAge > 0 && (expiry.IsZero() || expiry.After(issued.Add(this.maxAge))) {
+		expiry = issued.Add(this.maxAge)
+	}
+	this.mtx.Lock()
+	defer this.mtx.Unlock()
+	if expiry.IsZero() {
+		this.renewAt = time.Time{}
+		return
+	}
+	margin := DefaultTokenRenewMargin
+	if lifetime := expiry.Sub(issued); lifetime/4 < margin {
+		margin = lifetime / 4
+	}
+	this.renewAt = expiry.Add(-margin)
+}
+
+// Reset forgets the expiry, the token is then renewed only after the server
+// rejected it
+func (this *TokenRenewal) Reset() {
+	this.mtx.Lock()
+	defer this.mtx.Unlock()
+	this.renewAt = time.Time{}
+}
+
+// Due returns true when the token is to be renewed at now, never when its
+// expiry is unknown
+func (this *TokenRenewal) Due(now time.Time) bool {
+	this.mtx.Lock()
+	defer this.mtx.Unlock()
+	return !this.renewAt.IsZero() && !now.Before(this.renewAt)
+}
+
+// RenewAt returns when the token is due for renewal, zero when unknown
+func (this *TokenRenewal) RenewAt() time.Time {
+	this.mtx.Lock()
+	defer this.mtx.Unlock()
+	return this.renewAt
+}
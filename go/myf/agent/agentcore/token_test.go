@@ -0,0 +1,86 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package agentcore
+
+import (
+	"encoding/base64"
+	"testing"
+	"time"
+)
+
+// jwt returns an unsigned JWT with the claims
+func jwt(claims string) string {
+	return "eyJhbGciOiJIUzI1NiJ9." + base64.RawURLEncoding.EncodeToString([]byte(claims)) + ".sig"
+}
+
+func TestTokenExpiry(t *testing.T) {
+	issued := time.Unix(1000, 0)
+	if got := TokenExpiry("opaque", 300, issued); !got.Equal(time.Unix(1300, 0)) {
+		t.Fatalf("expiresIn must set the expiry, got %v", got)
+	}
+	if got := TokenExpiry(jwt(`{"sub":"alice","exp":2000}`), 0, issued); !got.Equal(time.Unix(2000, 0)) {
+		t.Fatalf("the JWT exp claim must set the expiry, got %v", got)
+	}
+	if got := TokenExpiry(jwt(`{"sub":"alice","exp":2000}`), 60, issued); !got.Equal(time.Unix(1060, 0)) {
+		t.Fatalf("expiresIn must take precedence over the claim, got %v", got)
+	}
+	for _, token := range []string{"opaque", jwt(`{"sub":"alice"}`), "a.!!!.c", jwt(`not json`)} {
+		if got := TokenExpiry(token, 0, issued); !got.IsZero() {
+			t.Fatalf("the expiry of %q must be unknown, got %v", token, got)
+		}
+	}
+}
+
+func TestTokenRenewalTiming(t *testing.T) {
+	issued := time.Unix(1000, 0)
+	renewal := NewTokenRenewal(0)
+
+	// A long lived token is renewed a minute before it expires
+	renewal.Issued("opaque", 3600, issued)
+	if renewal.Due(issued.Add(3600*time.Second - DefaultTokenRenewMargin - time.Second)) {
+		t.Fatal("the token must not be renewed before the margin")
+	}
+	if !renewal.Due(issued.Add(3600*time.Second - DefaultTokenRenewMargin)) {
+		t.Fatal("the token must be renewed at the margin")
+	}
+
+	// A short lived token is renewed at three quarters of its lifetime
+	renewal.Issued(jwt(`{"exp":1120}`), 0, issued)
+	if !renewal.RenewAt().Equal(time.Unix(1090, 0)) {
+		t.Fatalf("expected the renewal at 1090, got %v", renewal.RenewAt())
+	}
+
+	// Without a known expiry the token is renewed only on a rejection
+	renewal.Issued("opaque", 0, issued)
+	if renewal.Due(issued.Add(365 * 24 * time.Hour)) {
+		t.Fatal("a token without an expiry must not be renewed proactively")
+	}
+
+	// The max age caps the cache time, also of a token without an expiry
+	capped := NewTokenRenewal(20 * time.Minute)
+	capped.Issued("opaque", 0, issued)
+	if !capped.RenewAt().Equal(issued.Add(19 * time.Minute)) {
+		t.Fatalf("expected the renewal a minute before the max age, got %v", capped.RenewAt())
+	}
+	capped.Issued("opaque", 3600, issued)
+	if !capped.RenewAt().Equal(issued.Add(19 * time.Minute)) {
+		t.Fatalf("the max age must cap a longer expiry, got %v", capped.RenewAt())
+	}
+	capped.Reset()
+	if capped.Due(issued.Add(time.Hour)) {
+		t.Fatal("a reset renewal must not be due")
+	}
+}
@@ -0,0 +1,23 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package agentcore
+
+// Version is the agent version sent with the registration, set at build time:
+//
+//	go build -ldflags "-X github.com/saichler/l8myfamiliy/go/myf/agent/agentcore.Version=1.4.0"
+//
+// A development build reports dev, which a server with a minimum version refuses.
+var Version = "dev"
@@ -0,0 +1,157 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package agenttest
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// NatsMessage is a message published to the fake NATS server
+type NatsMessage struct {
+	Subject string
+	Data    []byte
+}
+
+// NatsServer is a fake NATS server speaking the core protocol of a
+// publisher, it records the published messages
+type NatsServer struct {
+	listener net.Listener
+	mtx      sync.Mutex
+	// user and pass, when set, are the accepted credentials
+	user     string
+	pass     string
+	conns    []net.Conn
+	connects int
+	messages []*NatsMessage
+}
+
+// NewNatsServer starts a fake NATS server that accepts user and pass, empty
+// ones accept any client. It is closed with the test.
+func NewNatsServer(t testing.TB, user, pass string) *NatsServer {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	this := &NatsServer{listener: listener, user: user, pass: pass}
+	go this.accept()
+	t.Cleanup(this.Close)
+	return this
+}
+
+// URL returns the nats:// url of the server
+func (this *NatsServer) URL() string {
+	return "nats://" + this.listener.Addr().String()
+}
+
+// Messages returns the published messages in order
+func (this *NatsServer) Messages() []*NatsMessage {
+	this.mtx.Lock()
+	defer this.mtx.Unlock()
+	return append([]*NatsMessage{}, this.messages...)
+}
+
+// Connects returns how many clients connected
+func (this *NatsServer) Connects() int {
+	this.mtx.Lock()
+	defer this.mtx.Unlock()
+	return this.connects
+}
+
+// Disconnect closes the open client connections, the server keeps listening
+func (this *NatsServer) Disconnect() {
+	this.mtx.Lock()
+	defer this.mtx.Unlock()
+	for _, conn := range this.conns {
+		conn.Close()
+	}
+	this.conns = nil
+}
+
+// Close stops the server
+func (this *NatsServer) Close() {
+	this.listener.Close()
+	this.Disconnect()
+}
+
+func (this *NatsServer) accept() {
+	for {
+		conn, err := this.listener.Accept()
+		if err != nil {
+			return
+		}
+		this.mtx.Lock()
+		this.conns = append(this.conns, conn)
+		this.mtx.Unlock()
+		go this.serve(conn)
+	}
+}
+
+func (this *NatsServer) serve(conn net.Conn) {
+	defer conn.Close()
+	fmt.Fprintf(conn, "INFO {\"server_id\":\"agenttest\",\"max_payload\":1048576,\"auth_required\":%t}\r\n", this.user != "")
+	reader := bufio.NewReader(conn)
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		switch strings.ToUpper(fields[0]) {
+		case "CONNECT":
+			options := struct {
+				User string `json:"user"`
+				Pass string `json:"pass"`
+			}{}
+			json.Unmarshal([]byte(strings.TrimSpace(line[len("CONNECT"):])), &options)
+			if options.User != this.user || options.Pass != this.pass {
+				conn.Write([]byte("-ERR 'Authorization Violation'\r\n"))
+				return
+			}
+			this.mtx.Lock()
+			this.connects++
+			this.mtx.Unlock()
+		case "PING":
+			conn.Write([]byte("PONG\r\n"))
+		case "PUB":
+			if len(fields) != 3 {
+				conn.Write([]byte("-ERR 'Unknown Protocol Operation'\r\n"))
+				return
+			}
+			size, err := strconv.Atoi(fields[2])
+			if err != nil {
+				return
+			}
+			data := make([]byte, size+2)
+			if _, err := io.ReadFull(reader, data); err != nil {
+				return
+			}
+			this.mtx.Lock()
+			this.messages = append(this.messages, &NatsMessage{Subject: fields[1], Data: data[:size]})
+			this.mtx.Unlock()
+		}
+	}
+}
@@ -0,0 +1,215 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package agenttest is a fake family server for the agent tests, it stands in
+// for /auth, /tfaVerify and the device and location endpoints and records
+// what the agents send.
+package agenttest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/saichler/l8myfamiliy/go/myf/webapi"
+	"github.com/saichler/l8myfamiliy/go/types/l8myfamily"
+)
+
+// Server is the fake family server, its fields are set before the agent
+// calls it and read with the getters
+type Server struct {
+	*httptest.Server
+	mtx sync.Mutex
+	// User and Pass are the accepted credentials
+	User string
+	Pass string
+	// TfaCode, when set, makes /auth ask for a TFA verification with it
+	TfaCode string
+	// AuthBody, when set, replaces the /auth response, e.g. a malformed one
+	AuthBody string
+	// ExpiresIn, when set, is sent as the token lifetime in seconds
+	ExpiresIn int64
+	// SchemaVersion is the location schema the health endpoint reports,
+	// zero stands for an old server that does not report it
+	SchemaVersion int32
+	// failures are the statuses answered to the next device and location
+	// posts before they are accepted
+	failures []int
+	// token is the valid bearer token, auths counts the issued tokens
+	token         string
+	auths         int
+	tfaVerifies   int
+	registrations []*l8myfamily.Device
+	locations     []*l8myfamily.Location
+}
+
+// NewServer starts a fake server that accepts user and pass, it is closed
+// with the test
+func NewServer(t testing.TB, user, pass string) *Server {
+	this := &Server{User: user, Pass: pass}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/auth", this.auth)
+	mux.HandleFunc("/tfaVerify", this.tfaVerify)
+	mux.HandleFunc(webapi.Prefix+"device", this.register)
+	mux.HandleFunc(webapi.Prefix+"location", this.location)
+	mux.HandleFunc(webapi.Prefix+"health", this.health)
+	this.Server = httptest.NewServer(mux)
+	t.Cleanup(this.Close)
+	return this
+}
+
+// Fail answers the next device and location posts with the statuses, in order
+func (this *Server) Fail(statuses ...int) {
+	this.mtx.Lock()
+	defer this.mtx.Unlock()
+	this.failures = append(this.failures, statuses...)
+}
+
+// ExpireToken invalidates the issued token, the next posts get a 401 until
+// the agent authenticates again
+func (this *Server) ExpireToken() {
+	this.mtx.Lock()
+	defer this.mtx.Unlock()
+	this.token = ""
+}
+
+// Auths returns the number of successful /auth calls
+func (this *Server) Auths() int {
+	this.mtx.Lock()
+	defer this.mtx.Unlock()
+	return this.auths
+}
+
+// TfaVerifies returns the number of /tfaVerify calls
+func (this *Server) TfaVerifies() int {
+	this.mtx.Lock()
+	defer this.mtx.Unlock()
+	return this.tfaVerifies
+}
+
+// Registrations returns the accepted device registrations
+func (this *Server) Registrations() []*l8myfamily.Device {
+	this.mtx.Lock()
+	defer this.mtx.Unlock()
+	return append([]*l8myfamily.Device(nil), this.registrations...)
+}
+
+// Locations returns the accepted locations
+func (this *Server) Locations() []*l8myfamily.Location {
+	this.mtx.Lock()
+	defer this.mtx.Unlock()
+	return append([]*l8myfamily.Location(nil), this.locations...)
+}
+
+func (this *Server) health(w http.ResponseWriter, r *http.Request) {
+	this.mtx.Lock()
+	defer this.mtx.Unlock()
+	health := map[string]interface{}{"status": "ok"}
+	if this.SchemaVersion != 0 {
+		health["schemaVersion"] = this.SchemaVersion
+	}
+	json.NewEncoder(w).Encode(health)
+}
+
+func (this *Server) auth(w http.ResponseWriter, r *http.Request) {
+	this.mtx.Lock()
+	defer this.mtx.Unlock()
+	if this.AuthBody != "" {
+		w.Write([]byte(this.AuthBody))
+		return
+	}
+	var creds map[string]string
+	if err := json.NewDecoder(r.Body).Decode(&creds); err != nil || creds["user"] != this.User || creds["pass"] != this.Pass {
+		http.Error(w, "invalid credentials", http.StatusUnauthorized)
+		return
+	}
+	token := fmt.Sprintf("token-%d", this.auths+1)
+	if this.TfaCode != "" {
+		// The token is valid once the code is verified
+		json.NewEncoder(w).Encode(map[string]interface{}{"token": token, "needTfa": true})
+		return
+	}
+	this.auths++
+	this.token = token
+	json.NewEncoder(w).Encode(map[string]interface{}{"token": token, "expiresIn": this.ExpiresIn})
+}
+
+func (this *Server) tfaVerify(w http.ResponseWriter, r *http.Request) {
+	this.mtx.Lock()
+	defer this.mtx.Unlock()
+	this.tfaVerifies++
+	var req map[string]string
+	json.NewDecoder(r.Body).Decode(&req)
+	if req["code"] != this.TfaCode {
+		json.NewEncoder(w).Encode(map[string]interface{}{"ok": false, "error": "wrong code"})
+		return
+	}
+	this.auths++
+	this.token = req["bearer"]
+	json.NewEncoder(w).Encode(map[string]interface{}{"ok": true})
+}
+
+// accept checks the token and the failures of a post, it writes the response
+// of a rejected post and returns false
+func (this *Server) accept(w http.ResponseWriter, r *http.Request) bool {
+	if this.token == "" || r.Header.Get("Authorization") != "Bearer "+this.token {
+		http.Error(w, "invalid bearer token", http.StatusUnauthorized)
+		return false
+	}
+	if len(this.failures) > 0 {
+		status := this.failures[0]
+		this.failures = this.failures[1:]
+		http.Error(w, http.StatusText(status), status)
+		return false
+	}
+	return true
+}
+
+func (this *Server) register(w http.ResponseWriter, r *http.Request) {
+	this.mtx.Lock()
+	defer this.mtx.Unlock()
+	if !this.accept(w, r) {
+		return
+	}
+	device := &l8myfamily.Device{}
+	if err := webapi.DecodeBody(r, device); err != nil || device.Id == "" {
+		http.Error(w, "invalid device", http.StatusBadRequest)
+		return
+	}
+	this.registrations = append(this.registrations, device)
+	webapi.WriteJSON(w, http.StatusOK, device)
+}
+
+func (this *Server) location(w http.ResponseWriter, r *http.Request) {
+	this.mtx.Lock()
+	defer this.mtx.Unlock()
+	if !this.accept(w, r) {
+		return
+	}
+	location := &l8myfamily.Location{}
+	if err := webapi.DecodeBody(r, location); err != nil {
+		http.Error(w, err.Error(), webapi.DecodeStatus(err))
+		return
+	}
+	if location.DeviceId == "" {
+		http.Error(w, "missing deviceId", http.StatusBadRequest)
+		return
+	}
+	this.locations = append(this.locations, location)
+	w.WriteHeader(http.StatusOK)
+}
@@ -6,8 +6,10 @@ import android.app.NotificationManager;
 import android.app.PendingIntent;
 import android.app.Service;
 import android.content.Intent;
+import android.content.IntentFilter;
 import android.content.pm.PackageManager;
 import android.location.Location;
+import android.os.BatteryManager;
 import android.os.Build;
 import android.os.Handler;
 import android.os.IBinder;
@@ -87,6 +89,7 @@ public class LocationService extends Service {
     private void postLocation(Location location) {
         double lat = location.getLatitude();
         double lon = location.getLongitude();
+        double accuracy = location.hasAccuracy() ? location.getAccuracy() : 0;
 
         executor.execute(() -> {
             // Verify agent is initialized before posting
@@ -96,7 +99,8 @@ public class LocationService extends Service {
             }
 
             try {
-                Mfagent.postLocation(lat, lon);
+                Mfagent.setBatteryLevel(batteryLevel());
+                Mfagent.postLocationWithAccuracy(lat, lon, accuracy);
                 Log.i(TAG, String.format("Posted location: lat=%.6f, lon=%.6f", lat, lon));
             } catch (Exception e) {
                 String errorMsg = e.getMessage();
@@ -108,7 +112,7 @@ public class LocationService extends Service {
                     try {
                         Mfagent.reAuthenticate();
                         // Retry posting after re-auth
-                        Mfagent.postLocation(lat, lon);
+                        Mfagent.postLocationWithAccuracy(lat, lon, accuracy);
                         Log.i(TAG, "Re-authentication successful, location posted");
                     } catch (Exception reAuthEx) {
                         Log.e(TAG, "Re-authentication failed: " + reAuthEx.getMessage());
@@ -118,6 +122,19 @@ public class LocationService extends Service {
         });
     }
 
+    private long batteryLevel() {
+        Intent battery = registerReceiver(null, new IntentFilter(Intent.ACTION_BATTERY_CHANGED));
+        if (battery == null) {
+            return 0;
+        }
+        int level = battery.getIntExtra(BatteryManager.EXTRA_LEVEL, -1);
+        int scale = battery.getIntExtra(BatteryManager.EXTRA_SCALE, -1);
+        if (level < 0 || scale <= 0) {
+            return 0;
+        }
+        return Math.round(level * 100f / scale);
+    }
+
     private void createNotificationChannel() {
         if (Build.VERSION.SDK_INT >= Build.VERSION_CODES.O) {
             NotificationChannel channel = new NotificationChannel(
@@ -195,22 +195,31 @@ public class MainActivity extends AppCompatActivity {
     }
 
     private void showTfaDialog() {
+        long lockout = Mfagent.getTfaLockoutSeconds();
+        if (lockout > 0) {
+            Toast.makeText(this, "Too many failed codes, try again in " + lockout + " seconds", Toast.LENGTH_LONG).show();
+            startButton.setEnabled(true);
+            statusText.setText("Status: TFA locked for " + lockout + "s");
+            Mfagent.clearTfaState();
+            return;
+        }
         statusText.setText("Status: TFA Required");
 
+        final long codeLength = Mfagent.getTfaCodeLength();
         final EditText tfaInput = new EditText(this);
-        tfaInput.setHint("Enter 6-digit code");
+        tfaInput.setHint("Enter " + codeLength + "-digit code");
         tfaInput.setInputType(android.text.InputType.TYPE_CLASS_NUMBER);
         tfaInput.setMaxLines(1);
 
         new AlertDialog.Builder(this)
                 .setTitle("Two-Factor Authentication")
-                .setMessage("Enter the 6-digit code from your authenticator app")
+                .setMessage("Enter the " + codeLength + "-digit code from your authenticator app")
                 .setView(tfaInput)
                 .setCancelable(false)
                 .setPositiveButton("Verify", (dialog, which) -> {
                     String code = tfaInput.getText().toString().trim();
-                    if (code.length() != 6) {
-                        Toast.makeText(this, "Please enter a 6-digit code", Toast.LENGTH_SHORT).show();
+                    if (code.length() != codeLength) {
+                        Toast.makeText(this, "Please enter a " + codeLength + "-digit code", Toast.LENGTH_SHORT).show();
                         startButton.setEnabled(true);
                         statusText.setText("Status: Stopped");
                         return;
@@ -232,8 +241,12 @@ public class MainActivity extends AppCompatActivity {
                 Mfagent.verifyTfa(code);
                 completeRegistration();
             } catch (Exception e) {
+                long lockout = Mfagent.getTfaLockoutSeconds();
+                String message = lockout > 0
+                        ? "TFA verification failed, locked for " + lockout + " seconds"
+                        : "TFA verification failed: " + e.getMessage();
                 runOnUiThread(() -> {
-                    Toast.makeText(this, "TFA verification failed: " + e.getMessage(), Toast.LENGTH_LONG).show();
+                    Toast.makeText(this, message, Toast.LENGTH_LONG).show();
                     statusText.setText("Status: Failed");
                     startButton.setEnabled(true);
                     Mfagent.clearTfaState();
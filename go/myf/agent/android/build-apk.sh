@@ -53,7 +53,10 @@ echo ""
 echo "=== Building Go library (mfagent.aar) ==="
 cd "$SCRIPT_DIR/mfagent"
 
-gomobile bind -v -target=android -androidapi 24 -o "$SCRIPT_DIR/app/libs/mfagent.aar" .
+# VERSION is reported to the server with the registration
+gomobile bind -v -target=android -androidapi 24 \
+    -ldflags "-X github.com/saichler/l8myfamiliy/go/myf/agent/agentcore.Version=${VERSION:-dev}" \
+    -o "$SCRIPT_DIR/app/libs/mfagent.aar" .
 
 if [ ! -f "$SCRIPT_DIR/app/libs/mfagent.aar" ]; then
     echo "Error: Failed to build mfagent.aar"
@@ -19,29 +19,47 @@ package mfagent
 
 import (
 	"bytes"
-	"crypto/aes"
-	"crypto/cipher"
-	"crypto/rand"
-	"crypto/sha256"
-	"crypto/tls"
-	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
-	"os"
 	"path/filepath"
 	"strings"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/saichler/l8myfamiliy/go/myf/agent/agentcore"
+	"github.com/saichler/l8myfamiliy/go/myf/webapi"
+	"github.com/saichler/l8myfamiliy/go/types/l8myfamily"
 )
 
 const DefaultEndpoint = "https://www.probler.dev:9092"
 
+// DefaultServiceArea is the service area of the family and location services
+const DefaultServiceArea = 53
+
+// sourceAndroid is the proto JSON name of the android location source,
+// sourceGPS of a fix from the GPS provider
+const (
+	sourceAndroid = "LOCATION_SOURCE_ANDROID"
+	sourceGPS     = "LOCATION_SOURCE_GPS"
+)
+
+// postAttempts is how many times a post is tried on a network error or a
+// server error before giving up
+const postAttempts = 3
+
+// retryDelay is the wait between post attempts, it grows with each attempt
+var retryDelay = time.Second
+
+// maxRetryAfter caps the wait a 503 or 429 Retry-After header asks for
+var maxRetryAfter = agentcore.MaxRetryAfter
+
 var (
 	deviceID        = ""
 	deviceName      = ""
+	alias           = ""
 	website         = DefaultEndpoint
 	user            = ""
 	pass            = ""
@@ -51,23 +69,112 @@ var (
 	skipTLSVerify   = false
 	initialized     = false
 	tfaRequired     = false
+	serviceArea     = DefaultServiceArea
+	useProtobuf     = false
+	compression     = true
+	geofences       []agentcore.Geofence
+	trustedBeat     = 0
+	trustedZone     = agentcore.NewTrustedZone(nil, 0)
+	serverClock     = agentcore.NewClock()
+	moveMeters      = 0.0
+	heartbeatSecs   = 0
+	movement        = agentcore.NewMovementFilter(0, 0)
+	authScheme      = agentcore.AuthScheme{Kind: agentcore.AuthBearer}
+	authPaths       = agentcore.DefaultAuthPaths()
+	batteryLevel    = 0
+	signingKey      = ""
+	// The telemetry reported by the app, posted when telemetryOn is set
+	telemetryOn = false
+	networkType = ""
+	charging    = false
+	osVersion   = ""
+
+	// startJitter is the longest random delay of the first post after
+	// Initialize, the regular posts before startAt are skipped
+	startJitter = agentcore.StartJitter(nil)
+	startAt     time.Time
+
+	// startupRetry retries a login of Initialize that failed transiently
+	startupRetry = agentcore.NewStartupRetry(nil)
+
+	// heartbeatOnly posts the presence of the device, never a position
+	heartbeatOnly = false
+
+	// reauthGate spaces the ReAuthenticate calls that are not followed by an
+	// accepted post, reauthCooldown is its configured seconds
+	reauthCooldown = 0
+	reauthGate     = agentcore.NewReauthGate(0)
+
+	// sampleSeconds is how often the app samples the position
+	sampleSeconds = DefaultSampleSeconds
 )
 
-// Config holds the persistent configuration
+// DefaultSampleSeconds is the sample interval of the app unless it calls
+// SetSampleIntervalSeconds
+const DefaultSampleSeconds = 10
+
+// Config holds the persistent configuration, the settings shared with the
+// laptop agent are in the embedded agentcore.Config
 type Config struct {
-	DeviceID      string `json:"device_id"`
-	DeviceName    string `json:"device_name,omitempty"`
-	Website       string `json:"website,omitempty"`
-	EncryptedUser string `json:"encrypted_user,omitempty"`
-	EncryptedPass string `json:"encrypted_pass,omitempty"`
-	SkipTLSVerify *bool  `json:"skip_tls_verify,omitempty"`
+	agentcore.Config
+	TfaCodeLength int `json:"tfa_code_length,omitempty"`
+	// EncryptedRefresh is the refresh token, encrypted like the credentials
+	EncryptedRefresh string `json:"encrypted_refresh_token,omitempty"`
+	// EncryptedSigningKey is the key the location posts are signed with
+	EncryptedSigningKey string `json:"encrypted_signing_key,omitempty"`
+	// LocalHistoryDisabled opts out of the local location history
+	LocalHistoryDisabled bool `json:"local_history_disabled,omitempty"`
+	// TokenMaxAgeSeconds renews the bearer token at most this long after it
+	// was issued, also one without a known expiry, 0 keeps it until it expires
+	TokenMaxAgeSeconds int `json:"token_max_age_seconds,omitempty"`
 }
 
-// Location represents a GPS location to post
+// Location represents a GPS location to post, the JSON names are the canonical
+// names of l8myfamily.Location, see webapi.JSONNames
 type Location struct {
-	DeviceID  string  `json:"device_id"`
+	DeviceID  string  `json:"deviceId"`
 	Latitude  float64 `json:"latitude"`
 	Longitude float64 `json:"longitude"`
+	Emergency bool    `json:"emergency,omitempty"`
+	Source    string  `json:"source,omitempty"`
+	Accuracy  float64 `json:"accuracy,omitempty"`
+	// Place is the trusted geofence the device is in, the position is then
+	// the fence center
+	Place string `json:"place,omitempty"`
+	// Geofence is the trusted geofence the device is in, the server records
+	// the entries and exits
+	Geofence string `json:"geofence,omitempty"`
+	// Timestamp is in unix milliseconds, corrected to the server clock
+	Timestamp int64 `json:"timestamp,omitempty"`
+	// Heartbeat is set on a post without a position, it keeps the device
+	// online while it does not move
+	Heartbeat bool `json:"heartbeat,omitempty"`
+	// Battery is the battery percentage, 0 when it is unknown
+	Battery int `json:"battery,omitempty"`
+	// IdempotencyKey is the same on every retry of a post, so the server
+	// applies a retried post only once
+	IdempotencyKey string `json:"idempotencyKey,omitempty"`
+	// Nonce, Signature and SignedTime authenticate the post with the device
+	// signing key, so a captured post cannot be replayed
+	Nonce      string `json:"nonce,omitempty"`
+	Signature  string `json:"signature,omitempty"`
+	SignedTime int64  `json:"signedTime,omitempty"`
+	// Telemetry is set when the telemetry setting is on
+	Telemetry *Telemetry `json:"telemetry,omitempty"`
+	// ReportIntervalSeconds is the longest time the agent stays silent, the
+	// server sizes the device offline grace from it
+	ReportIntervalSeconds int32 `json:"reportIntervalSeconds,omitempty"`
+	// SchemaVersion is the location schema negotiated with the server
+	SchemaVersion int32 `json:"schemaVersion,omitempty"`
+}
+
+// Telemetry is the device state posted with the locations, the JSON names are
+// the canonical names of l8myfamily.Telemetry
+type Telemetry struct {
+	NetworkType string `json:"networkType,omitempty"`
+	Charging    bool   `json:"charging,omitempty"`
+	Os          string `json:"os,omitempty"`
+	OsVersion   string `json:"osVersion,omitempty"`
 }
 
 // AuthResponse represents the response from the /auth endpoint
@@ -75,6 +182,11 @@ type AuthResponse struct {
 	Token    string `json:"token"`
 	NeedTfa  bool   `json:"needTfa"`
 	SetupTfa bool   `json:"setupTfa"`
+	// RefreshToken is issued by servers that support the /refresh endpoint
+	RefreshToken string `json:"refreshToken,omitempty"`
+	// ExpiresIn is the token lifetime in seconds, servers that do not send
+	// it may issue a JWT with an exp claim
+	ExpiresIn int64 `json:"expiresIn,omitempty"`
 }
 
 // TfaVerifyRequest represents the request body for TFA verification
@@ -86,8 +198,9 @@ type TfaVerifyRequest struct {
 
 // TfaVerifyResponse represents the response from the /tfaVerify endpoint
 type TfaVerifyResponse struct {
-	Ok    bool   `json:"ok"`
-	Error string `json:"error,omitempty"`
+	Ok           bool   `json:"ok"`
+	Error        string `json:"error,omitempty"`
+	RefreshToken string `json:"refreshToken,omitempty"`
 }
 
 // SetConfigDir sets the directory where config file will be stored.
@@ -163,6 +276,17 @@ func SetDeviceName(name string) {
 	deviceName = name
 }
 
+// GetAlias returns the name the agent logs use for the device
+func GetAlias() string {
+	return alias
+}
+
+// SetAlias sets a readable name of the device for the agent logs, it is
+// never posted. Call SaveConfig to persist it.
+func SetAlias(name string) {
+	alias = name
+}
+
 // GetWebsite returns the current website URL
 func GetWebsite() string {
 	return website
@@ -178,114 +302,264 @@ func SetEndpoint(url string) {
 	website = url
 }
 
-// IsInitialized returns whether the agent has been initialized
-func IsInitialized() bool {
-	return initialized
+// GetServiceArea returns the configured service area, the agent posts to the
+// raw endpoints, which do not depend on it
+func GetServiceArea() int {
+	return serviceArea
 }
 
-// NeedsConfiguration returns true if website or credentials are not set
-func NeedsConfiguration() bool {
-	return website == "" || user == "" || pass == ""
+// SetServiceArea sets the service area kept in the config
+func SetServiceArea(area int) {
+	serviceArea = area
 }
 
-func getConfigPath() string {
-	if configDir == "" {
-		return ""
-	}
-	return filepath.Join(configDir, "mfagent-config.json")
+// GetUseProtobuf returns whether locations are posted as protobuf
+func GetUseProtobuf() bool {
+	return useProtobuf
+}
+
+// SetUseProtobuf sets whether locations are posted in the compact protobuf
+// format instead of JSON, it saves data on metered connections
+func SetUseProtobuf(use bool) {
+	useProtobuf = use
+}
+
+// GetCompression returns whether gzip compression is enabled
+func GetCompression() bool {
+	return compression
 }
 
-func getEncryptionKey() []byte {
-	h := sha256.New()
-	h.Write([]byte(deviceID))
-	h.Write([]byte("l8myfamily-android-agent"))
-	return h.Sum(nil)
+// SetCompression enables or disables gzip of responses and large request bodies
+func SetCompression(enabled bool) {
+	compression = enabled
 }
 
-func encrypt(plaintext string) (string, error) {
-	key := getEncryptionKey()
-	block, err := aes.NewCipher(key)
+// SetAuthScheme selects how the token is sent: "bearer" (the default), "header"
+// or "cookie", name is the header or cookie name. Call SaveConfig to persist it.
+func SetAuthScheme(kind, name string) error {
+	scheme, err := agentcore.NewAuthScheme(kind, name)
 	if err != nil {
-		return "", err
+		return err
 	}
+	authScheme = scheme
+	return nil
+}
 
-	gcm, err := cipher.NewGCM(block)
+// SetAuthPaths sets the paths of the login and two factor verification
+// endpoints, for a gateway that mounts them elsewhere than /auth and
+// /tfaVerify. An empty path is the default, a path must start with /.
+// Call SaveConfig to persist it.
+func SetAuthPaths(auth, tfaVerify string) error {
+	paths, err := agentcore.NewAuthPaths(auth, tfaVerify)
 	if err != nil {
-		return "", err
+		return err
 	}
+	authPaths = paths
+	return nil
+}
 
-	nonce := make([]byte, gcm.NonceSize())
-	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
-		return "", err
+// SetSampleIntervalSeconds sets how often the app samples the position, it is
+// announced to the server so a device that posts rarely is not reported
+// offline. 0 means DefaultSampleSeconds.
+func SetSampleIntervalSeconds(seconds int) {
+	if seconds <= 0 {
+		seconds = DefaultSampleSeconds
 	}
+	sampleSeconds = seconds
+}
 
-	ciphertext := gcm.Seal(nonce, nonce, []byte(plaintext), nil)
-	return base64.StdEncoding.EncodeToString(ciphertext), nil
+// reportInterval returns the interval announced with the registration and
+// every post
+func reportInterval() int32 {
+	return agentcore.ReportInterval(time.Duration(sampleSeconds)*time.Second, movement, trustedZone)
 }
 
-func decrypt(encoded string) (string, error) {
-	key := getEncryptionKey()
-	ciphertext, err := base64.StdEncoding.DecodeString(encoded)
-	if err != nil {
-		return "", err
+// SetBatteryLevel sets the battery percentage sent with the following posts,
+// the server alerts the family when it drops below the family threshold.
+// 0 means unknown.
+func SetBatteryLevel(percent int) {
+	if percent < 0 || percent > 100 {
+		percent = 0
 	}
+	batteryLevel = percent
+}
 
-	block, err := aes.NewCipher(key)
-	if err != nil {
-		return "", err
-	}
+// GetTelemetryEnabled returns whether the telemetry is posted
+func GetTelemetryEnabled() bool {
+	return telemetryOn
+}
 
-	gcm, err := cipher.NewGCM(block)
-	if err != nil {
-		return "", err
+// SetStartJitterSeconds sets the longest random delay of the first post
+// after Initialize, 0 posts at once. Call SaveConfig to persist it.
+func SetStartJitterSeconds(seconds int) {
+	startJitter = agentcore.StartJitter(&seconds)
+}
+
+// SetStartupRetries sets how many times Initialize retries a login that
+// failed with a network or server error, 0 returns the first error and a
+// negative count retries until it succeeds. Call SaveConfig to persist it.
+func SetStartupRetries(retries int) {
+	startupRetry.Retries = retries
+}
+
+// GetHeartbeatOnly returns whether the device shares its presence only
+func GetHeartbeatOnly() bool {
+	return heartbeatOnly
+}
+
+// SetHeartbeatOnly makes the agent post heartbeats only, the device is shown
+// online or offline and never on the map. Call SaveConfig to persist it and
+// RegisterDevice so the server drops the stored position.
+func SetHeartbeatOnly(enabled bool) {
+	heartbeatOnly = enabled
+}
+
+// SetTelemetryEnabled adds the network type, charging state and Android
+// version to the following posts. Call SaveConfig to persist it.
+func SetTelemetryEnabled(enabled bool) {
+	telemetryOn = enabled
+}
+
+// SetNetworkType sets the network the device is on: "wifi", "cellular",
+// "ethernet" or "none", "" when it is unknown
+func SetNetworkType(kind string) {
+	networkType = kind
+}
+
+// SetCharging sets whether the device is on external power
+func SetCharging(isCharging bool) {
+	charging = isCharging
+}
+
+// SetOSVersion sets the Android version, e.g. Build.VERSION.RELEASE
+func SetOSVersion(version string) {
+	osVersion = version
+}
+
+// GetPostOnMoveMeters returns the distance the device must move before its
+// position is posted again, 0 posts every position
+func GetPostOnMoveMeters() float64 {
+	return moveMeters
+}
+
+// SetPostOnMoveMeters posts a position only after a move of meters, and a
+// heartbeat every GetHeartbeatSeconds while the device does not move. 0 posts
+// every position. Call SaveConfig to persist it.
+func SetPostOnMoveMeters(meters float64) {
+	moveMeters = meters
+	movement = agentcore.NewMovementFilter(moveMeters, heartbeatSecs)
+}
+
+// GetHeartbeatSeconds returns the heartbeat interval of a device that does
+// not move
+func GetHeartbeatSeconds() int {
+	if heartbeatSecs <= 0 {
+		return agentcore.DefaultHeartbeatSeconds
 	}
+	return heartbeatSecs
+}
+
+// SetHeartbeatSeconds sets the heartbeat interval of a device that does not
+// move, 0 means the default
+func SetHeartbeatSeconds(seconds int) {
+	heartbeatSecs = seconds
+	movement = agentcore.NewMovementFilter(moveMeters, heartbeatSecs)
+}
 
-	if len(ciphertext) < gcm.NonceSize() {
-		return "", fmt.Errorf("ciphertext too short")
+// AddTrustedGeofence adds a trusted geofence, e.g. home. Inside it the agent
+// reports only the fence name and center, and posts only on entering, on
+// leaving and every GetTrustedHeartbeatSeconds. Call SaveConfig to persist it.
+func AddTrustedGeofence(name string, latitude, longitude, radiusMeters float64) {
+	geofences = append(geofences, agentcore.Geofence{Name: name, Latitude: latitude,
+		Longitude: longitude, RadiusMeters: radiusMeters, Trusted: true})
+	trustedZone = agentcore.NewTrustedZone(geofences, trustedBeat)
+}
+
+// ClearGeofences removes all geofences of the device
+func ClearGeofences() {
+	geofences = nil
+	trustedZone = agentcore.NewTrustedZone(nil, trustedBeat)
+}
+
+// GetTrustedHeartbeatSeconds returns how often the agent posts while inside a
+// trusted geofence
+func GetTrustedHeartbeatSeconds() int {
+	if trustedBeat <= 0 {
+		return agentcore.DefaultTrustedHeartbeatSeconds
 	}
+	return trustedBeat
+}
 
-	nonce, ciphertext := ciphertext[:gcm.NonceSize()], ciphertext[gcm.NonceSize():]
-	plaintext, err := gcm.Open(nil, nonce, ciphertext, nil)
-	if err != nil {
-		return "", err
+// SetTrustedHeartbeatSeconds sets how often the agent posts while inside a
+// trusted geofence, 0 means the default
+func SetTrustedHeartbeatSeconds(seconds int) {
+	trustedBeat = seconds
+	trustedZone = agentcore.NewTrustedZone(geofences, trustedBeat)
+}
+
+// IsInitialized returns whether the agent has been initialized
+func IsInitialized() bool {
+	return initialized
+}
+
+// NeedsConfiguration returns true if website or credentials are not set
+func NeedsConfiguration() bool {
+	return website == "" || user == "" || pass == ""
+}
+
+func getConfigPath() string {
+	if configDir == "" {
+		return ""
 	}
+	return filepath.Join(configDir, "mfagent-config.json")
+}
 
-	return string(plaintext), nil
+// getCipher returns the cipher of the stored credentials, its key depends on
+// the device id
+func getCipher() *agentcore.Cipher {
+	return agentcore.NewCipher(deviceID, agentcore.Salt)
 }
 
+// httpClients reuses one HTTP/2 capable client for every request to the
+// servers
+var httpClients = &agentcore.HTTPClients{}
+
+// getHTTPClient returns the client of the current TLS and compression
+// settings
 func getHTTPClient() *http.Client {
-	if skipTLSVerify {
-		return &http.Client{
-			Timeout: 10 * time.Second,
-			Transport: &http.Transport{
-				TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
-			},
-		}
+	return httpClients.Get(skipTLSVerify, compression)
+}
+
+// compressBody gzips a request body above the gzip threshold when compression
+// is enabled, it returns the body and its Content-Encoding
+func compressBody(data []byte) ([]byte, string) {
+	if !compression || len(data) < webapi.GzipThreshold {
+		return data, ""
 	}
-	return &http.Client{Timeout: 10 * time.Second}
+	compressed, err := webapi.GzipBytes(data)
+	if err != nil {
+		return data, ""
+	}
+	return compressed, "gzip"
 }
 
 // LoadConfig loads the configuration from the config file.
-// Returns an error if the config file doesn't exist or can't be read.
+// Returns an error if the config file can't be read.
 func LoadConfig() error {
 	configPath := getConfigPath()
 	if configPath == "" {
 		return fmt.Errorf("config directory not set")
 	}
 
-	data, err := os.ReadFile(configPath)
+	var cfg Config
+	exists, err := agentcore.LoadConfig(configPath, &cfg)
 	if err != nil {
-		if os.IsNotExist(err) {
-			// Generate device ID for new installs
-			deviceID = uuid.New().String()
-			return nil
-		}
-		return fmt.Errorf("failed to read config: %w", err)
+		return err
 	}
-
-	var cfg Config
-	if err := json.Unmarshal(data, &cfg); err != nil {
-		return fmt.Errorf("failed to parse config: %w", err)
+	if !exists {
+		// Generate device ID for new installs
+		deviceID = uuid.New().String()
+		return nil
 	}
 
 	if cfg.DeviceID == "" {
@@ -294,28 +568,102 @@ func LoadConfig() error {
 		deviceID = cfg.DeviceID
 	}
 
+	// A config of an earlier version is read with its legacy key and saved
+	// below with the unified one
+	c, migrated := cfg.Migrate(agentcore.SaltAndroid)
 	deviceName = cfg.DeviceName
+	alias = cfg.Alias
 	website = cfg.Website
+	serviceArea = DefaultServiceArea
+	if cfg.ServiceArea != 0 {
+		serviceArea = cfg.ServiceArea
+	}
+	useProtobuf = cfg.UseProtobuf
+	compression = !cfg.DisableCompression
+	tfaCodeLength = DefaultTfaCodeLength
+	if cfg.TfaCodeLength != 0 {
+		SetTfaCodeLength(cfg.TfaCodeLength)
+	}
 	if cfg.SkipTLSVerify != nil {
 		skipTLSVerify = *cfg.SkipTLSVerify
 	}
-
-	if cfg.EncryptedUser != "" {
-		decrypted, err := decrypt(cfg.EncryptedUser)
-		if err == nil {
-			user = decrypted
-		}
+	geofences = cfg.Geofences
+	trustedBeat = cfg.TrustedHeartbeatSeconds
+	trustedZone = agentcore.NewTrustedZone(geofences, trustedBeat)
+	SetLocalHistoryEnabled(!cfg.LocalHistoryDisabled)
+	SetTokenMaxAgeSeconds(cfg.TokenMaxAgeSeconds)
+	moveMeters = cfg.PostOnMoveMeters
+	heartbeatSecs = cfg.HeartbeatSeconds
+	movement = agentcore.NewMovementFilter(moveMeters, heartbeatSecs)
+	scheme, err := agentcore.NewAuthScheme(cfg.AuthScheme, cfg.AuthName)
+	if err != nil {
+		fmt.Println("[mfagent] ", err.Error())
 	}
-	if cfg.EncryptedPass != "" {
-		decrypted, err := decrypt(cfg.EncryptedPass)
-		if err == nil {
-			pass = decrypted
-		}
+	authScheme = scheme
+	paths, err := agentcore.NewAuthPaths(cfg.AuthPath, cfg.TfaVerifyPath)
+	if err != nil {
+		fmt.Println("[mfagent] ", err.Error())
+	}
+	authPaths = paths
+	telemetryOn = cfg.Telemetry
+	startJitter = agentcore.StartJitter(cfg.StartJitterSeconds)
+	startupRetry.Retries = agentcore.StartupRetries(cfg.StartupRetries)
+	heartbeatOnly = cfg.HeartbeatOnly
+	reauthCooldown = cfg.ReauthCooldownSeconds
+	reauthGate = agentcore.NewReauthGate(reauthCooldown)
+
+	if decrypted := agentcore.DecryptOrEmpty(c, cfg.EncryptedUser); decrypted != "" {
+		user = decrypted
+	}
+	if decrypted := agentcore.DecryptOrEmpty(c, cfg.EncryptedPass); decrypted != "" {
+		pass = decrypted
 	}
+	refreshToken = agentcore.DecryptOrEmpty(c, cfg.EncryptedRefresh)
+	signingKey = agentcore.DecryptOrEmpty(c, cfg.EncryptedSigningKey)
 
+	if migrated {
+		fmt.Println("[mfagent] migrating config to version", agentcore.ConfigVersion)
+		return SaveConfig()
+	}
 	return nil
 }
 
+// currentConfig returns the config of the current settings, without the
+// encrypted secrets
+func currentConfig() *Config {
+	jitterSeconds := int(startJitter / time.Second)
+	startupRetries := startupRetry.Retries
+	return &Config{
+		Config: agentcore.Config{
+			Version:                 agentcore.ConfigVersion,
+			DeviceID:                deviceID,
+			DeviceName:              deviceName,
+			Alias:                   alias,
+			Website:                 website,
+			SkipTLSVerify:           &skipTLSVerify,
+			ServiceArea:             serviceArea,
+			UseProtobuf:             useProtobuf,
+			DisableCompression:      !compression,
+			Geofences:               geofences,
+			TrustedHeartbeatSeconds: trustedBeat,
+			PostOnMoveMeters:        moveMeters,
+			HeartbeatSeconds:        heartbeatSecs,
+			AuthScheme:              authScheme.Kind,
+			AuthName:                authScheme.Name,
+			AuthPath:                authPaths.Auth,
+			TfaVerifyPath:           authPaths.TfaVerify,
+			Telemetry:               telemetryOn,
+			StartJitterSeconds:      &jitterSeconds,
+			StartupRetries:          &startupRetries,
+			ReauthCooldownSeconds:   reauthCooldown,
+			HeartbeatOnly:           heartbeatOnly,
+		},
+		TfaCodeLength:        tfaCodeLength,
+		LocalHistoryDisabled: !localHistoryEnabled,
+		TokenMaxAgeSeconds:   int(tokenMaxAge / time.Second),
+	}
+}
+
 // SaveConfig saves the current configuration to the config file.
 // Credentials are encrypted before saving.
 func SaveConfig() error {
@@ -328,43 +676,36 @@ func SaveConfig() error {
 		deviceID = uuid.New().String()
 	}
 
-	encryptedUser, err := encrypt(user)
-	if err != nil {
-		return fmt.Errorf("failed to encrypt user: %w", err)
-	}
-	encryptedPass, err := encrypt(pass)
-	if err != nil {
-		return fmt.Errorf("failed to encrypt pass: %w", err)
-	}
-
-	cfg := Config{
-		DeviceID:      deviceID,
-		DeviceName:    deviceName,
-		Website:       website,
-		EncryptedUser: encryptedUser,
-		EncryptedPass: encryptedPass,
-		SkipTLSVerify: &skipTLSVerify,
-	}
-
-	if err := os.MkdirAll(configDir, 0700); err != nil {
-		return fmt.Errorf("failed to create config directory: %w", err)
+	cfg := currentConfig()
+	c := getCipher()
+	if err := cfg.SetCredentials(c, user, pass); err != nil {
+		return err
 	}
-
-	data, err := json.MarshalIndent(cfg, "", "  ")
-	if err != nil {
-		return fmt.Errorf("failed to marshal config: %w", err)
+	if refreshToken != "" {
+		encryptedRefresh, err := c.Encrypt(refreshToken)
+		if err != nil {
+			return fmt.Errorf("failed to encrypt refresh token: %w", err)
+		}
+		cfg.EncryptedRefresh = encryptedRefresh
 	}
-
-	if err := os.WriteFile(configPath, data, 0600); err != nil {
-		return fmt.Errorf("failed to write config: %w", err)
+	if signingKey != "" {
+		encryptedKey, err := c.Encrypt(signingKey)
+		if err != nil {
+			return fmt.Errorf("failed to encrypt signing key: %w", err)
+		}
+		cfg.EncryptedSigningKey = encryptedKey
 	}
 
-	return nil
+	return agentcore.SaveConfig(configPath, cfg)
 }
 
 // ErrTfaRequired is returned when TFA verification is needed
 var ErrTfaRequired = fmt.Errorf("TFA_REQUIRED")
 
+// errTfaSetupRequired is returned for an account that must set up TFA in
+// the web application first
+var errTfaSetupRequired = errors.New("TFA setup required - please complete TFA setup via web browser first")
+
 // Authenticate performs authentication against the server.
 // Returns ErrTfaRequired if TFA verification is needed (call VerifyTfa next).
 // Returns an error if authentication fails.
@@ -380,7 +721,7 @@ func Authenticate() error {
 	tfaRequired = false
 	pendingTfaToken = ""
 
-	authURL := strings.TrimSuffix(website, "/") + "/auth"
+	authURL := authPaths.AuthURL(website)
 
 	authReq := map[string]string{
 		"user": user,
@@ -392,11 +733,13 @@ func Authenticate() error {
 	}
 
 	client := getHTTPClient()
+	sent := time.Now()
 	resp, err := client.Post(authURL, "application/json", bytes.NewReader(data))
 	if err != nil {
 		return fmt.Errorf("auth request failed: %w", err)
 	}
 	defer resp.Body.Close()
+	observeServerTime(resp.Header, sent)
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
@@ -404,7 +747,11 @@ func Authenticate() error {
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("authentication failed: %s", strings.TrimSpace(string(body)))
+		// A server error is not a rejection of the credentials
+		if resp.StatusCode >= http.StatusInternalServerError {
+			return fmt.Errorf("authentication failed: %s", strings.TrimSpace(string(body)))
+		}
+		return fmt.Errorf("%w: %s", errAuthRejected, strings.TrimSpace(string(body)))
 	}
 
 	// Try to parse as JSON first (for TFA detection)
@@ -414,21 +761,29 @@ func Authenticate() error {
 		if authResp.NeedTfa {
 			tfaRequired = true
 			pendingTfaToken = authResp.Token
+			pendingTfaExpiresIn = authResp.ExpiresIn
 			return ErrTfaRequired
 		}
 
 		// Check if TFA setup is required (first time login with TFA)
 		if authResp.SetupTfa {
 			// For mobile, we don't support TFA setup - user must set up TFA via web
-			return fmt.Errorf("TFA setup required - please complete TFA setup via web browser first")
+			return errTfaSetupRequired
 		}
 
 		// Normal successful auth with token in JSON
 		if authResp.Token != "" {
 			bearerToken = authResp.Token
+			tokenRenewal.Issued(bearerToken, authResp.ExpiresIn, serverClock.Now())
+			learnSchemaVersion()
 			initialized = true
+			if authResp.RefreshToken != "" {
+				setRefreshToken(authResp.RefreshToken)
+			}
 			return nil
 		}
+		// A JSON response is not a legacy plain token
+		return fmt.Errorf("authentication failed: no token in response")
 	}
 
 	// Fallback: treat response as plain token string (legacy support)
@@ -438,24 +793,27 @@ func Authenticate() error {
 	}
 
 	bearerToken = token
+	tokenRenewal.Issued(bearerToken, 0, serverClock.Now())
+	learnSchemaVersion()
 	initialized = true
 	return nil
 }
 
 // VerifyTfa verifies the TFA code after Authenticate returns ErrTfaRequired.
-// The code should be a 6-digit string from the authenticator app.
-// Returns nil on success, error otherwise.
+// The code should be a GetTfaCodeLength digit string from the authenticator app.
+// Returns nil on success, ErrTfaLocked while the client lockout after repeated
+// failures is active, or another error otherwise.
 func VerifyTfa(code string) error {
 	if !tfaRequired || pendingTfaToken == "" {
 		return fmt.Errorf("no TFA verification pending")
 	}
 
 	code = strings.TrimSpace(code)
-	if len(code) != 6 {
-		return fmt.Errorf("invalid TFA code: must be 6 digits")
+	if err := checkTfaCode(code); err != nil {
+		return err
 	}
 
-	tfaURL := strings.TrimSuffix(website, "/") + "/tfaVerify"
+	tfaURL := authPaths.TfaVerifyURL(website)
 
 	tfaReq := TfaVerifyRequest{
 		UserID: user,
@@ -489,11 +847,18 @@ func VerifyTfa(code string) error {
 		if errMsg == "" {
 			errMsg = "invalid verification code"
 		}
+		tfaFailed()
 		return fmt.Errorf("TFA verification failed: %s", errMsg)
 	}
+	tfaSucceeded()
+	if tfaResp.RefreshToken != "" {
+		setRefreshToken(tfaResp.RefreshToken)
+	}
 
 	// TFA verification successful - use the pending token as bearer token
 	bearerToken = pendingTfaToken
+	tokenRenewal.Issued(bearerToken, pendingTfaExpiresIn, serverClock.Now())
+	learnSchemaVersion()
 	initialized = true
 	tfaRequired = false
 	pendingTfaToken = ""
@@ -504,47 +869,57 @@ func VerifyTfa(code string) error {
 // RegisterDevice registers the device with the server.
 // Must be called after Authenticate.
 func RegisterDevice() error {
+	newKey := signingKey
+	if newKey == "" {
+		newKey = webapi.NewSigningKey()
+	}
+	return registerDevice(newKey)
+}
+
+// RotateSigningKey registers a new signing key for the location posts, the
+// server accepts the previous one for a few minutes. The new key is saved
+// with the config.
+func RotateSigningKey() error {
+	return registerDevice(webapi.NewSigningKey())
+}
+
+// registerDevice registers the device with the signing key, the agent signs
+// its posts with the key only after the server stored it
+func registerDevice(newKey string) error {
 	if bearerToken == "" {
 		return fmt.Errorf("not authenticated")
 	}
 
-	deviceEndpoint := strings.TrimSuffix(website, "/") + "/my-family/53/Family"
+	// The service endpoint has no authenticated caller and rejects it
+	deviceEndpoint := agentcore.EndpointURL(website, "device")
 
-	deviceReq := map[string]string{
-		"id":       deviceID,
-		"familyId": user,
-		"name":     deviceName,
-	}
-	data, err := json.Marshal(deviceReq)
+	key := uuid.New().String()
+	data, err := agentcore.RegistrationBody(deviceID, user, deviceName, key, newKey, reportInterval(), heartbeatOnly)
 	if err != nil {
 		return fmt.Errorf("failed to marshal device request: %w", err)
 	}
 
-	req, err := http.NewRequest("POST", deviceEndpoint, bytes.NewReader(data))
-	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
-	}
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+bearerToken)
-
-	client := getHTTPClient()
-	resp, err := client.Do(req)
-	if err != nil {
+	if err := postWithRetry(deviceEndpoint, webapi.ContentTypeJSON, "", data, key); err != nil {
 		return fmt.Errorf("device registration request failed: %w", err)
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("server returned status %d: %s", resp.StatusCode, string(body))
+	fmt.Println("[mfagent] device registered: ", agentcore.DeviceLabel(alias, deviceID))
+	if newKey != signingKey {
+		signingKey = newKey
+		if configDir != "" {
+			if err := SaveConfig(); err != nil {
+				fmt.Println("[mfagent] failed to save the signing key: ", err.Error())
+			}
+		}
 	}
-
 	return nil
 }
 
 // Initialize loads config and authenticates with the server.
 // This is a convenience function that combines LoadConfig and Authenticate.
 // Returns ErrTfaRequired if TFA verification is needed (call VerifyTfa next).
+// A login that fails with a network or server error, e.g. on a device that
+// boots before its network is up, is retried with a backoff, see
+// SetStartupRetries, so Initialize may block for minutes.
 func Initialize() error {
 	if err := LoadConfig(); err != nil {
 		return fmt.Errorf("failed to load config: %w", err)
@@ -554,7 +929,10 @@ func Initialize() error {
 		return fmt.Errorf("configuration required: website or credentials not set")
 	}
 
-	if err := Authenticate(); err != nil {
+	err := startupRetry.Run(Authenticate, loginRefused, func(wait time.Duration, err error) {
+		fmt.Println("[mfagent] authentication failed, retrying in ", wait, ": ", err.Error())
+	})
+	if err != nil {
 		// Pass through ErrTfaRequired so caller can handle TFA
 		if err == ErrTfaRequired {
 			return ErrTfaRequired
@@ -562,53 +940,304 @@ func Initialize() error {
 		return fmt.Errorf("failed to authenticate: %w", err)
 	}
 
+	startAt = now().Add(agentcore.RandomDelay(startJitter))
 	return nil
 }
 
+// loginRefused returns true for a login error retrying cannot fix, the
+// credentials were refused or the user must act
+func loginRefused(err error) bool {
+	return errors.Is(err, errAuthRejected) || errors.Is(err, ErrTfaRequired) || errors.Is(err, errTfaSetupRequired)
+}
+
 // PostLocation posts a GPS location to the server.
 // The agent must be initialized before calling this function.
 func PostLocation(latitude, longitude float64) error {
-	if !initialized {
-		return fmt.Errorf("agent not initialized")
+	return PostLocationWithAccuracy(latitude, longitude, 0)
+}
+
+// PostLocationWithAccuracy posts a GPS location together with its accuracy
+// radius in meters, 0 means the accuracy is unknown.
+func PostLocationWithAccuracy(latitude, longitude, accuracy float64) error {
+	return PostLocationFromProvider(latitude, longitude, accuracy, "")
+}
+
+// PostLocationFromProvider posts a location together with the name of the
+// Android provider that produced it, e.g. gps, network or fused, so the
+// server records where the fix came from
+func PostLocationFromProvider(latitude, longitude, accuracy float64, provider string) error {
+	return postLocation(&Location{
+		DeviceID:  deviceID,
+		Latitude:  latitude,
+		Longitude: longitude,
+		Source:    providerSource(provider),
+		Accuracy:  accuracy,
+	})
+}
+
+// providerSource returns the location source of an Android provider, only
+// the gps provider is known to be a GPS fix
+func providerSource(provider string) string {
+	if strings.EqualFold(provider, "gps") {
+		return sourceGPS
 	}
+	return sourceAndroid
+}
 
-	location := &Location{
+// PostEmergencyLocation posts a GPS location flagged as an emergency (SOS).
+// The server alerts the family immediately and never suppresses an emergency post.
+func PostEmergencyLocation(latitude, longitude float64) error {
+	return postLocation(&Location{
 		DeviceID:  deviceID,
 		Latitude:  latitude,
 		Longitude: longitude,
+		Emergency: true,
+		Source:    sourceAndroid,
+	})
+}
+
+func postLocation(location *Location) error {
+	if !initialized {
+		return fmt.Errorf("agent not initialized")
 	}
 
+	// The local history keeps the precise position, whether posted or not
+	local := &LocalLocation{Timestamp: now().UnixMilli(), Latitude: location.Latitude,
+		Longitude: location.Longitude, Accuracy: location.Accuracy, Emergency: location.Emergency}
+	posted, err := sendLocation(location)
+	local.Posted = posted
+	if herr := appendLocal(local); herr != nil {
+		fmt.Println("[mfagent] ", herr.Error())
+	}
+	return err
+}
+
+// sendLocation posts the location unless it is suppressed inside a trusted
+// geofence or replaced by a heartbeat because the device did not move, it
+// returns whether the position was posted
+func sendLocation(location *Location) (bool, error) {
+	// A heartbeat-only device posts its presence at the sample cadence
+	if heartbeatOnly && !location.Emergency {
+		if now().Before(startAt) {
+			return false, nil
+		}
+		return false, PostHeartbeat()
+	}
+	// An emergency always reports the precise position
+	if !location.Emergency {
+		// A fleet started together spreads its first posts
+		if now().Before(startAt) {
+			return false, nil
+		}
+		lat, lon, place, post := trustedZone.Filter(location.Latitude, location.Longitude, now())
+		if !post {
+			return false, nil
+		}
+		if place != "" {
+			location.Latitude, location.Longitude, location.Place = lat, lon, place
+			location.Geofence = place
+			location.Accuracy = 0
+		}
+		switch movement.Decide(location.Latitude, location.Longitude, now()) {
+		case agentcore.DecisionSkip:
+			return false, nil
+		case agentcore.DecisionHeartbeat:
+			return false, PostHeartbeat()
+		}
+	}
+	err := transmit(location)
+	return err == nil, err
+}
+
+// PostHeartbeat posts the device id and time without a position, the server
+// only updates the last seen time so the device stays online
+func PostHeartbeat() error {
+	if !initialized {
+		return fmt.Errorf("agent not initialized")
+	}
+	return transmit(&Location{DeviceID: deviceID, Heartbeat: true})
+}
+
+// transmit stamps and posts the location, a location without an
+// idempotency key gets a new one
+func transmit(location *Location) error {
+	if location.Timestamp == 0 {
+		location.Timestamp = serverClock.Now().UnixMilli()
+	}
+	// A heartbeat-only device never sends a position, an emergency is
+	// still flagged so the family is alerted
+	if heartbeatOnly {
+		stripPosition(location)
+	}
+	location.Battery = batteryLevel
+	location.ReportIntervalSeconds = reportInterval()
+	if telemetryOn {
+		location.Telemetry = &Telemetry{NetworkType: networkType, Charging: charging,
+			Os: "android", OsVersion: osVersion}
+	}
+	// A resent panic alarm keeps its key so the server applies it once
+	if location.IdempotencyKey == "" {
+		location.IdempotencyKey = uuid.New().String()
+	}
+	location.SchemaVersion = postedSchemaVersion()
+	signLocation(location)
+	if useProtobuf {
+		return postLocationProtobuf(location)
+	}
 	data, err := json.Marshal(location)
 	if err != nil {
 		return fmt.Errorf("failed to marshal location: %w", err)
 	}
 
-	locationEndpoint := strings.TrimSuffix(website, "/") + "/my-family/53/Location"
+	locationEndpoint := agentcore.EndpointURL(website, "location")
+	if err := postWithRetry(locationEndpoint, webapi.ContentTypeJSON, "", data, location.IdempotencyKey); err != nil {
+		return fmt.Errorf("post request failed: %w", err)
+	}
+	return nil
+}
 
-	req, err := http.NewRequest("POST", locationEndpoint, bytes.NewReader(data))
-	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
+// schemaVersion is the location schema the server decodes, learned after
+// authenticating
+var schemaVersion int32 = 1
+
+// learnSchemaVersion asks the server for the location schema it decodes
+func learnSchemaVersion() {
+	schemaVersion = agentcore.FetchSchemaVersion(getHTTPClient(), website)
+}
+
+// postedSchemaVersion returns the schema version the posts carry, 0 for a
+// server that predates the field. Every other field of Location is in
+// schema 1.
+func postedSchemaVersion() int32 {
+	l := &l8myfamily.Location{}
+	webapi.DowngradeLocation(l, schemaVersion)
+	return l.SchemaVersion
+}
+
+// stripPosition turns the location into a heartbeat without a position
+func stripPosition(location *Location) {
+	location.Heartbeat = true
+	location.Latitude, location.Longitude, location.Accuracy = 0, 0, 0
+	location.Source, location.Place, location.Geofence = "", "", ""
+}
+
+// toProto converts the location to the l8myfamily.Location the server decodes
+func toProto(location *Location) *l8myfamily.Location {
+	l := &l8myfamily.Location{
+		DeviceId:              location.DeviceID,
+		Latitude:              float32(location.Latitude),
+		Longitude:             float32(location.Longitude),
+		Emergency:             location.Emergency,
+		Source:                l8myfamily.LocationSource(l8myfamily.LocationSource_value[location.Source]),
+		Accuracy:              float32(location.Accuracy),
+		Place:                 location.Place,
+		Geofence:              location.Geofence,
+		Timestamp:             location.Timestamp,
+		Heartbeat:             location.Heartbeat,
+		Battery:               int32(location.Battery),
+		IdempotencyKey:        location.IdempotencyKey,
+		Nonce:                 location.Nonce,
+		Signature:             location.Signature,
+		SignedTime:            location.SignedTime,
+		ReportIntervalSeconds: location.ReportIntervalSeconds,
+		SchemaVersion:         location.SchemaVersion,
 	}
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+bearerToken)
+	if t := location.Telemetry; t != nil {
+		l.Telemetry = &l8myfamily.Telemetry{NetworkType: t.NetworkType, Charging: t.Charging,
+			Os: t.Os, OsVersion: t.OsVersion}
+	}
+	return l
+}
 
-	client := getHTTPClient()
-	resp, err := client.Do(req)
+// signLocation signs the location with the registered signing key, the
+// coordinates are first rounded to the float32 precision the server decodes
+// them with, so the server computes the same signature
+func signLocation(location *Location) {
+	if signingKey == "" {
+		return
+	}
+	location.Latitude = float64(float32(location.Latitude))
+	location.Longitude = float64(float32(location.Longitude))
+	location.Accuracy = float64(float32(location.Accuracy))
+	signed := toProto(location)
+	webapi.SignLocation(signed, signingKey, serverClock.Now())
+	location.Nonce, location.Signature, location.SignedTime = signed.Nonce, signed.Signature, signed.SignedTime
+}
+
+// postLocationProtobuf posts the location as a marshaled l8myfamily.Location
+// to the raw location endpoint, which negotiates the encoding by Content-Type
+func postLocationProtobuf(location *Location) error {
+	data, err := webapi.EncodeBody(webapi.ContentTypeProtobuf, toProto(location))
 	if err != nil {
+		return fmt.Errorf("failed to marshal location: %w", err)
+	}
+	locationEndpoint := agentcore.EndpointURL(website, "location")
+	data, encoding := compressBody(data)
+	if err := postWithRetry(locationEndpoint, webapi.ContentTypeProtobuf, encoding, data, location.IdempotencyKey); err != nil {
 		return fmt.Errorf("post request failed: %w", err)
 	}
-	defer resp.Body.Close()
+	return nil
+}
 
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("server returned status %d: %s", resp.StatusCode, string(body))
+// observeServerTime corrects the clock location timestamps are stamped with
+// by the server time of a response, a device clock that is far off is logged
+func observeServerTime(header http.Header, sent time.Time) {
+	if offset, warn := serverClock.Observe(header, sent); warn {
+		fmt.Println("[mfagent] device clock is off by", -offset, "from the server, timestamps are corrected")
 	}
-
-	return nil
 }
 
-// ReAuthenticate re-authenticates with the server.
-// Use this if the bearer token has expired.
-func ReAuthenticate() error {
-	return Authenticate()
+// postWithRetry posts the data, retrying on network and server errors.
+// Every attempt carries the same Idempotency-Key header, so a post that
+// reached the server before the response was lost is not applied twice.
+func postWithRetry(endpoint, contentType, contentEncoding string, data []byte, key string) error {
+	renewTokenIfDue()
+	var lastErr error
+	// retryAfter is the wait the server asked for, it replaces the backoff
+	var retryAfter time.Duration
+	hinted := false
+	for attempt := 1; attempt <= postAttempts; attempt++ {
+		if attempt > 1 {
+			delay := time.Duration(attempt-1) * retryDelay
+			if hinted {
+				delay = retryAfter
+			}
+			time.Sleep(delay)
+		}
+		hinted = false
+		req, err := http.NewRequest("POST", endpoint, bytes.NewReader(data))
+		if err != nil {
+			return fmt.Errorf("failed to create request: %w", err)
+		}
+		req.Header.Set("Content-Type", contentType)
+		if contentEncoding != "" {
+			req.Header.Set("Content-Encoding", contentEncoding)
+		}
+		authScheme.Apply(req, bearerToken)
+		req.Header.Set("Idempotency-Key", key)
+
+		sent := time.Now()
+		resp, err := getHTTPClient().Do(req)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		observeServerTime(resp.Header, sent)
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+			if reauthGate.Accepted(now()) {
+				fmt.Println("[mfagent] authentication recovered")
+			}
+			return nil
+		}
+		lastErr = fmt.Errorf("server returned status %d: %s", resp.StatusCode, string(body))
+		retryAfter, hinted = agentcore.RetryAfter(resp, serverClock.Now(), maxRetryAfter)
+		// A client error will fail the same way again, unless the server
+		// asks to retry later
+		if resp.StatusCode < 500 && !hinted {
+			return lastErr
+		}
+	}
+	return lastErr
 }
@@ -0,0 +1,414 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package mfagent
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/saichler/l8myfamiliy/go/myf/agent/agentcore"
+	"github.com/saichler/l8myfamiliy/go/myf/webapi"
+	"github.com/saichler/l8myfamiliy/go/types/l8myfamily"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestPostEmergencyLocation(t *testing.T) {
+	var posted Location
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/my-family/location" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		json.NewDecoder(r.Body).Decode(&posted)
+	}))
+	defer srv.Close()
+
+	website = srv.URL
+	deviceID = "emergency-device"
+	initialized = true
+	defer func() { initialized = false }()
+
+	if err := PostEmergencyLocation(1.5, 2.5); err != nil {
+		t.Fatal(err)
+	}
+	if !posted.Emergency || posted.DeviceID != "emergency-device" || posted.Latitude != 1.5 {
+		t.Fatalf("unexpected payload %+v", posted)
+	}
+
+	posted = Location{}
+	if err := PostLocation(1.5, 2.5); err != nil {
+		t.Fatal(err)
+	}
+	if posted.Emergency {
+		t.Fatal("regular location must not be flagged as emergency")
+	}
+}
+
+func TestPostLocationRetryKeepsKey(t *testing.T) {
+	var keys []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var posted Location
+		json.NewDecoder(r.Body).Decode(&posted)
+		if posted.IdempotencyKey != r.Header.Get("Idempotency-Key") {
+			t.Errorf("body key %s does not match header", posted.IdempotencyKey)
+		}
+		keys = append(keys, r.Header.Get("Idempotency-Key"))
+		if len(keys) < 2 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+
+	website = srv.URL
+	deviceID = "retry-device"
+	initialized = true
+	retryDelay = time.Millisecond
+	defer func() { initialized = false; retryDelay = time.Second }()
+
+	if err := PostLocation(1.5, 2.5); err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 2 || keys[0] == "" || keys[0] != keys[1] {
+		t.Fatalf("expected two attempts with the same key, got %v", keys)
+	}
+
+	if err := PostLocation(1.5, 2.5); err != nil {
+		t.Fatal(err)
+	}
+	if keys[2] == keys[0] {
+		t.Fatal("a new post must use a new key")
+	}
+}
+
+func TestPostLocationEncodings(t *testing.T) {
+	posted := make(map[string]*l8myfamily.Location)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Both encodings are posted to the raw location endpoint
+		if r.URL.Path != "/my-family/location" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		l := &l8myfamily.Location{}
+		if err := webapi.DecodeBody(r, l); err != nil {
+			t.Error(err)
+		}
+		posted[r.Header.Get("Content-Type")] = l
+	}))
+	defer srv.Close()
+
+	website = srv.URL
+	deviceID = "encoding-device"
+	initialized = true
+	defer func() { initialized = false; useProtobuf = false }()
+
+	for _, protobuf := range []bool{false, true} {
+		SetUseProtobuf(protobuf)
+		if err := PostLocationWithAccuracy(1.5, 2.5, 7); err != nil {
+			t.Fatal(err)
+		}
+	}
+	jsonLoc, pbLoc := posted[webapi.ContentTypeJSON], posted[webapi.ContentTypeProtobuf]
+	if jsonLoc == nil || pbLoc == nil {
+		t.Fatalf("expected a JSON and a protobuf post, got %v", posted)
+	}
+	if pbLoc.DeviceId != "encoding-device" || pbLoc.Latitude != 1.5 || pbLoc.Accuracy != 7 ||
+		pbLoc.Source != l8myfamily.LocationSource_LOCATION_SOURCE_ANDROID || pbLoc.IdempotencyKey == "" {
+		t.Fatalf("unexpected protobuf location %v", pbLoc)
+	}
+	if jsonLoc.DeviceId != "encoding-device" || jsonLoc.Latitude != 1.5 {
+		t.Fatalf("unexpected JSON location %v", jsonLoc)
+	}
+}
+
+func TestLoadConfigMigratesLegacyConfig(t *testing.T) {
+	legacy, err := os.ReadFile(filepath.Join("..", "..", "agentcore", "testdata", "android-config-v1.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	configDir = t.TempDir()
+	defer func() { configDir, user, pass = "", "", "" }()
+	if err := os.WriteFile(getConfigPath(), legacy, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := LoadConfig(); err != nil {
+		t.Fatal(err)
+	}
+	if user != "alice" || pass != "s3cret!" {
+		t.Fatalf("legacy credentials did not load, got %q %q", user, pass)
+	}
+
+	var saved Config
+	data, _ := os.ReadFile(getConfigPath())
+	json.Unmarshal(data, &saved)
+	if saved.Version != agentcore.ConfigVersion {
+		t.Fatalf("the config was not rewritten, version %d", saved.Version)
+	}
+	if u, p := saved.Credentials(getCipher()); u != "alice" || p != "s3cret!" {
+		t.Fatal("the rewritten config must decrypt with the unified key")
+	}
+}
+
+func TestTrustedGeofenceSuppressesPosts(t *testing.T) {
+	var posted []Location
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var location Location
+		json.NewDecoder(r.Body).Decode(&location)
+		posted = append(posted, location)
+	}))
+	defer srv.Close()
+
+	website = srv.URL
+	deviceID = "trusted-device"
+	initialized = true
+	AddTrustedGeofence("home", 40.0, -74.0, 200)
+	defer func() { initialized = false; ClearGeofences() }()
+
+	PostLocation(40.0005, -74.0005)
+	PostLocation(40.0006, -74.0004)
+	PostEmergencyLocation(40.0006, -74.0004)
+	PostLocation(40.01, -74.0)
+
+	if len(posted) != 3 {
+		t.Fatalf("expected enter, emergency and exit posts, got %+v", posted)
+	}
+	if posted[0].Place != "home" || posted[0].Latitude != 40.0 {
+		t.Fatalf("inside a trusted fence only the fence must be reported, got %+v", posted[0])
+	}
+	if posted[1].Place != "" || posted[1].Latitude != 40.0006 || !posted[1].Emergency {
+		t.Fatalf("an emergency must report the precise position, got %+v", posted[1])
+	}
+	if posted[2].Place != "" || posted[2].Latitude != 40.01 {
+		t.Fatalf("leaving the fence must report the precise position, got %+v", posted[2])
+	}
+}
+
+func TestPostLocationCorrectsClockSkew(t *testing.T) {
+	var posted []Location
+	serverAhead := 10 * time.Minute
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(agentcore.ServerTimeHeader, strconv.FormatInt(time.Now().Add(serverAhead).UnixMilli(), 10))
+		var location Location
+		json.NewDecoder(r.Body).Decode(&location)
+		posted = append(posted, location)
+	}))
+	defer srv.Close()
+	defer func() { serverClock = agentcore.NewClock() }()
+
+	website = srv.URL
+	deviceID = "skewed-device"
+	initialized = true
+	defer func() { initialized = false }()
+
+	// The first response teaches the agent the offset, the second post uses it
+	PostLocation(1, 1)
+	PostLocation(2, 2)
+	if len(posted) != 2 {
+		t.Fatalf("expected 2 posts, got %d", len(posted))
+	}
+	skew := time.UnixMilli(posted[1].Timestamp).Sub(time.Now())
+	if skew < serverAhead-time.Second || skew > serverAhead+time.Second {
+		t.Fatalf("the timestamp must follow the server clock, off by %s", skew)
+	}
+}
+
+func TestPostOnMoveSendsHeartbeats(t *testing.T) {
+	var posted []Location
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var location Location
+		json.NewDecoder(r.Body).Decode(&location)
+		posted = append(posted, location)
+	}))
+	defer srv.Close()
+
+	clock := time.Unix(1700000000, 0)
+	now = func() time.Time { return clock }
+	defer func() { now = time.Now }()
+	website = srv.URL
+	deviceID = "moving-device"
+	initialized = true
+	SetPostOnMoveMeters(50)
+	SetHeartbeatSeconds(60)
+	defer func() { initialized = false; SetPostOnMoveMeters(0); SetHeartbeatSeconds(0) }()
+
+	PostLocation(40.0, -74.0)
+	clock = clock.Add(30 * time.Second)
+	PostLocation(40.0001, -74.0)
+	clock = clock.Add(31 * time.Second)
+	PostLocation(40.0001, -74.0)
+	PostLocation(40.01, -74.0)
+
+	if len(posted) != 3 {
+		t.Fatalf("expected a post, a heartbeat and a post, got %+v", posted)
+	}
+	if posted[1].Heartbeat != true || posted[1].Latitude != 0 || posted[1].DeviceID != "moving-device" {
+		t.Fatalf("a heartbeat must carry no position, got %+v", posted[1])
+	}
+	if posted[0].Heartbeat || posted[2].Heartbeat || posted[2].Latitude != 40.01 {
+		t.Fatalf("a move must post the position, got %+v", posted)
+	}
+}
+
+func TestRegisterDeviceSignsPosts(t *testing.T) {
+	var signingKeys []string
+	var verified []bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/my-family/device":
+			var device map[string]string
+			json.NewDecoder(r.Body).Decode(&device)
+			signingKeys = append(signingKeys, device["signingKey"])
+		case "/my-family/location":
+			location := &l8myfamily.Location{}
+			webapi.DecodeBody(r, location)
+			verified = append(verified, webapi.VerifyLocation(location, signingKeys[len(signingKeys)-1]))
+		}
+	}))
+	defer srv.Close()
+
+	website = srv.URL
+	deviceID = "signed-device"
+	bearerToken = "t"
+	initialized = true
+	defer func() { initialized = false; bearerToken = ""; signingKey = "" }()
+
+	if err := RegisterDevice(); err != nil {
+		t.Fatal(err)
+	}
+	if err := PostLocationWithAccuracy(32.123456789, 34.8, 7.3); err != nil {
+		t.Fatal(err)
+	}
+	if err := RotateSigningKey(); err != nil {
+		t.Fatal(err)
+	}
+	if err := PostLocation(32.1, 34.8); err != nil {
+		t.Fatal(err)
+	}
+	if len(signingKeys) != 2 || signingKeys[0] == "" || signingKeys[0] == signingKeys[1] {
+		t.Fatalf("expected a rotated signing key, got %v", signingKeys)
+	}
+	if len(verified) != 2 || !verified[0] || !verified[1] {
+		t.Fatalf("expected the posts signed with the current key, got %v", verified)
+	}
+}
+
+func TestDumpConfigRedactsSecrets(t *testing.T) {
+	website, user, pass, signingKey = "https://family.example.com", "alice", "s3cret!", "0123456789abcdef0123456789abcdef"
+	defer func() { website, user, pass, signingKey = DefaultEndpoint, "", "", "" }()
+
+	dump := DumpConfig()
+	for _, secret := range []string{"alice", "s3cret!", signingKey} {
+		if strings.Contains(dump, secret) {
+			t.Fatalf("the dump must not contain %s: %s", secret, dump)
+		}
+	}
+	var dumped map[string]interface{}
+	if err := json.Unmarshal([]byte(dump), &dumped); err != nil {
+		t.Fatal(err)
+	}
+	if dumped["pass"] != agentcore.Redacted || dumped["signing_key"] != agentcore.Redacted || dumped["refresh_token"] != nil {
+		t.Fatalf("expected the set secrets redacted and the unset ones absent, got %v", dumped)
+	}
+	if dumped["endpoint"] != "https://family.example.com/my-family/location" {
+		t.Fatalf("unexpected endpoint %v", dumped["endpoint"])
+	}
+}
+
+func TestLocationJSONContract(t *testing.T) {
+	location := &Location{DeviceID: "d1", Latitude: 1.5, Longitude: 2.5, Emergency: true, Source: "LOCATION_SOURCE_ANDROID",
+		Accuracy: 8, Place: "home", Geofence: "home", Timestamp: 1700000000000, Heartbeat: true, Battery: 50,
+		IdempotencyKey: "k1", Nonce: "n1", Signature: "s1", SignedTime: 1700000000000,
+		Telemetry: &Telemetry{NetworkType: "wifi", Charging: true, Os: "android", OsVersion: "14"}, ReportIntervalSeconds: 240}
+	data, err := json.Marshal(location)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := webapi.CheckJSONNames(data, &l8myfamily.Location{}); err != nil {
+		t.Fatalf("the posted names must be canonical, got %v", err)
+	}
+	telemetry, _ := json.Marshal(location.Telemetry)
+	if err := webapi.CheckJSONNames(telemetry, &l8myfamily.Telemetry{}); err != nil {
+		t.Fatalf("the telemetry names must be canonical, got %v", err)
+	}
+	r := httptest.NewRequest("POST", "/my-family/location", bytes.NewReader(data))
+	decoded := &l8myfamily.Location{}
+	if err := webapi.DecodeBody(r, decoded); err != nil || !proto.Equal(decoded, toProto(location)) {
+		t.Fatalf("the server must decode the post as toProto builds it, got %v %v", decoded, err)
+	}
+}
+
+func TestPostLocationHonorsRetryAfter(t *testing.T) {
+	attempts := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if attempts == 1 {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+
+	website = srv.URL
+	deviceID = "overloaded-device"
+	initialized = true
+	// The backoff would stall the test, the capped server hint replaces it
+	retryDelay, maxRetryAfter = time.Hour, 10*time.Millisecond
+	defer func() { initialized = false; retryDelay, maxRetryAfter = time.Second, agentcore.MaxRetryAfter }()
+
+	if err := PostLocation(1.5, 2.5); err != nil {
+		t.Fatal(err)
+	}
+	if attempts != 2 {
+		t.Fatalf("expected a retry after the hinted wait, got %d attempts", attempts)
+	}
+}
+
+func TestStartJitterDelaysFirstPost(t *testing.T) {
+	var posted []Location
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var location Location
+		json.NewDecoder(r.Body).Decode(&location)
+		posted = append(posted, location)
+	}))
+	defer srv.Close()
+
+	clock := time.Unix(1700000000, 0)
+	now = func() time.Time { return clock }
+	website = srv.URL
+	deviceID = "jittered-device"
+	initialized = true
+	startAt = clock.Add(10 * time.Second)
+	defer func() { now = time.Now; initialized = false; startAt = time.Time{} }()
+
+	PostLocation(40.0, -74.0)
+	if len(posted) != 0 {
+		t.Fatalf("a post within the start jitter must be skipped, got %+v", posted)
+	}
+	PostEmergencyLocation(40.0, -74.0)
+	if len(posted) != 1 || !posted[0].Emergency {
+		t.Fatalf("an emergency must never wait for the jitter, got %+v", posted)
+	}
+	clock = clock.Add(11 * time.Second)
+	PostLocation(40.0, -74.0)
+	if len(posted) != 2 || posted[1].Emergency {
+		t.Fatalf("a post after the jitter must be sent, got %+v", posted)
+	}
+}
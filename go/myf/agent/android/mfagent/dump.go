@@ -0,0 +1,65 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package mfagent
+
+import (
+	"encoding/json"
+
+	"github.com/saichler/l8myfamiliy/go/myf/agent/agentcore"
+)
+
+// dumpedConfig is the effective configuration returned by DumpConfig
+type dumpedConfig struct {
+	*Config
+	ConfigFile string `json:"config_file"`
+	// The secrets are redacted, empty when they are not set
+	User         string `json:"user,omitempty"`
+	Pass         string `json:"pass,omitempty"`
+	BearerToken  string `json:"bearer_token,omitempty"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	SigningKey   string `json:"signing_key,omitempty"`
+	// Endpoint is the endpoint the locations are posted to
+	Endpoint    string `json:"endpoint"`
+	Initialized bool   `json:"initialized"`
+	// AuthFailing and LastAcceptedPostSeconds are the state of the
+	// re-authentications, see AuthFailing
+	AuthFailing             bool  `json:"auth_failing"`
+	LastAcceptedPostSeconds int64 `json:"last_accepted_post_seconds"`
+}
+
+// DumpConfig returns the configuration the agent runs with as indented JSON,
+// with the credentials, tokens and signing key redacted, for support requests
+func DumpConfig() string {
+	endpoint := agentcore.EndpointURL(website, "location")
+	dumped := &dumpedConfig{
+		Config:                  currentConfig(),
+		ConfigFile:              getConfigPath(),
+		User:                    agentcore.Redact(user),
+		Pass:                    agentcore.Redact(pass),
+		BearerToken:             agentcore.Redact(bearerToken),
+		RefreshToken:            agentcore.Redact(refreshToken),
+		SigningKey:              agentcore.Redact(signingKey),
+		Endpoint:                endpoint,
+		Initialized:             initialized,
+		AuthFailing:             AuthFailing(),
+		LastAcceptedPostSeconds: LastAcceptedPostSeconds(),
+	}
+	data, err := json.MarshalIndent(dumped, "", "  ")
+	if err != nil {
+		return err.Error()
+	}
+	return string(data)
+}
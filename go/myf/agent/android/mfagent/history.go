@@ -0,0 +1,216 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package mfagent
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"time"
+
+	// Pure Go SQLite driver, it builds with gomobile without cgo
+	_ "modernc.org/sqlite"
+)
+
+// DefaultLocalHistoryDays is how long the local history keeps a location
+const DefaultLocalHistoryDays = 30
+
+const historySchema = `CREATE TABLE IF NOT EXISTS locations (
+	ts INTEGER NOT NULL,
+	latitude REAL NOT NULL,
+	longitude REAL NOT NULL,
+	accuracy REAL NOT NULL DEFAULT 0,
+	emergency INTEGER NOT NULL DEFAULT 0,
+	posted INTEGER NOT NULL DEFAULT 0
+);
+CREATE INDEX IF NOT EXISTS locations_ts ON locations (ts);`
+
+var (
+	historyMtx          = &sync.Mutex{}
+	historyDB           *sql.DB
+	localHistoryEnabled = true
+)
+
+// LocalLocation is a location of the local history
+type LocalLocation struct {
+	// Timestamp is in unix milliseconds
+	Timestamp int64   `json:"timestamp"`
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+	Accuracy  float64 `json:"accuracy,omitempty"`
+	Emergency bool    `json:"emergency,omitempty"`
+	// Posted is false for a location that did not reach the server, it failed
+	// or was suppressed inside a trusted geofence
+	Posted bool `json:"posted"`
+}
+
+// IsLocalHistoryEnabled returns whether locations are recorded locally
+func IsLocalHistoryEnabled() bool {
+	return localHistoryEnabled
+}
+
+// SetLocalHistoryEnabled enables or disables the local location history, low
+// storage devices can opt out. Disabling it keeps the recorded history until
+// ClearLocal is called. Call SaveConfig to persist it.
+func SetLocalHistoryEnabled(enabled bool) {
+	historyMtx.Lock()
+	defer historyMtx.Unlock()
+	localHistoryEnabled = enabled
+	if !enabled {
+		closeHistory()
+	}
+}
+
+// getHistoryPath returns the SQLite file of the local history
+func getHistoryPath() string {
+	if configDir == "" {
+		return ""
+	}
+	return filepath.Join(configDir, "mfagent-history.db")
+}
+
+// openHistory opens the local history on first use and prunes locations older
+// than DefaultLocalHistoryDays, the caller holds historyMtx
+func openHistory() (*sql.DB, error) {
+	if historyDB != nil {
+		return historyDB, nil
+	}
+	path := getHistoryPath()
+	if path == "" {
+		return nil, fmt.Errorf("config directory not set")
+	}
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open local history: %w", err)
+	}
+	// A single connection serializes the writes of the SQLite file
+	db.SetMaxOpenConns(1)
+	if _, err := db.Exec(historySchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create local history: %w", err)
+	}
+	historyDB = db
+	pruneBefore(now().Add(-DefaultLocalHistoryDays * 24 * time.Hour).UnixMilli())
+	return db, nil
+}
+
+// closeHistory closes the local history, the caller holds historyMtx
+func closeHistory() {
+	if historyDB != nil {
+		historyDB.Close()
+		historyDB = nil
+	}
+}
+
+// AppendLocal records a location in the local history, ts is in unix
+// milliseconds and 0 means now
+func AppendLocal(latitude, longitude float64, ts int64) error {
+	return appendLocal(&LocalLocation{Timestamp: ts, Latitude: latitude, Longitude: longitude})
+}
+
+// appendLocal records the location when the local history is enabled
+func appendLocal(location *LocalLocation) error {
+	historyMtx.Lock()
+	defer historyMtx.Unlock()
+	if !localHistoryEnabled {
+		return nil
+	}
+	db, err := openHistory()
+	if err != nil {
+		return err
+	}
+	if location.Timestamp == 0 {
+		location.Timestamp = now().UnixMilli()
+	}
+	_, err = db.Exec(`INSERT INTO locations (ts, latitude, longitude, accuracy, emergency, posted) VALUES (?, ?, ?, ?, ?, ?)`,
+		location.Timestamp, location.Latitude, location.Longitude, location.Accuracy, location.Emergency, location.Posted)
+	if err != nil {
+		return fmt.Errorf("failed to record location: %w", err)
+	}
+	return nil
+}
+
+// QueryLocal returns the local locations from, to (unix milliseconds,
+// inclusive) in time order, as a JSON array of LocalLocation
+func QueryLocal(from, to int64) (string, error) {
+	locations, err := queryLocal(from, to)
+	if err != nil {
+		return "", err
+	}
+	data, err := json.Marshal(locations)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+func queryLocal(from, to int64) ([]*LocalLocation, error) {
+	historyMtx.Lock()
+	defer historyMtx.Unlock()
+	db, err := openHistory()
+	if err != nil {
+		return nil, err
+	}
+	rows, err := db.Query(`SELECT ts, latitude, longitude, accuracy, emergency, posted FROM locations WHERE ts >= ? AND ts <= ? ORDER BY ts, rowid`, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query local history: %w", err)
+	}
+	defer rows.Close()
+	locations := make([]*LocalLocation, 0)
+	for rows.Next() {
+		location := &LocalLocation{}
+		if err := rows.Scan(&location.Timestamp, &location.Latitude, &location.Longitude,
+			&location.Accuracy, &location.Emergency, &location.Posted); err != nil {
+			return nil, fmt.Errorf("failed to read local history: %w", err)
+		}
+		locations = append(locations, location)
+	}
+	return locations, rows.Err()
+}
+
+// PruneLocal removes the local locations older than maxAgeSeconds and returns
+// how many were removed
+func PruneLocal(maxAgeSeconds int64) (int64, error) {
+	historyMtx.Lock()
+	defer historyMtx.Unlock()
+	if _, err := openHistory(); err != nil {
+		return 0, err
+	}
+	return pruneBefore(now().Add(-time.Duration(maxAgeSeconds) * time.Second).UnixMilli())
+}
+
+// ClearLocal removes the whole local history
+func ClearLocal() error {
+	historyMtx.Lock()
+	defer historyMtx.Unlock()
+	db, err := openHistory()
+	if err != nil {
+		return err
+	}
+	_, err = db.Exec(`DELETE FROM locations`)
+	return err
+}
+
+// pruneBefore removes the locations before ts, the caller holds historyMtx
+func pruneBefore(ts int64) (int64, error) {
+	result, err := historyDB.Exec(`DELETE FROM locations WHERE ts < ?`, ts)
+	if err != nil {
+		return 0, fmt.Errorf("failed to prune local history: %w", err)
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,105 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package mfagent
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// withHistory points the local history at a fresh directory
+func withHistory(t *testing.T) {
+	historyMtx.Lock()
+	closeHistory()
+	historyMtx.Unlock()
+	configDir = t.TempDir()
+	t.Cleanup(func() {
+		historyMtx.Lock()
+		closeHistory()
+		historyMtx.Unlock()
+		configDir = ""
+		localHistoryEnabled = true
+	})
+}
+
+func TestLocalHistoryAppendQueryPrune(t *testing.T) {
+	withHistory(t)
+	clock := time.Unix(1700000000, 0)
+	now = func() time.Time { return clock }
+	defer func() { now = time.Now }()
+	start := clock.Add(-2 * time.Hour).UnixMilli()
+	for i := int64(0); i < 5; i++ {
+		if err := AppendLocal(1+float64(i), 2, start+i*60000); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var locations []LocalLocation
+	data, err := QueryLocal(start+60000, start+3*60000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	json.Unmarshal([]byte(data), &locations)
+	if len(locations) != 3 || locations[0].Latitude != 2 || locations[2].Latitude != 4 {
+		t.Fatalf("unexpected range %s", data)
+	}
+
+	// Everything older than the third location
+	removed, err := PruneLocal(int64(clock.Sub(time.UnixMilli(start + 2*60000)).Seconds()))
+	if err != nil || removed != 2 {
+		t.Fatalf("expected 2 pruned, got %d %v", removed, err)
+	}
+	if locations, _ := queryLocal(0, clock.UnixMilli()); len(locations) != 3 {
+		t.Fatalf("the recent locations must survive the prune, got %d", len(locations))
+	}
+}
+
+func TestPostLocationRecordsLocally(t *testing.T) {
+	withHistory(t)
+	failing := true
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if failing {
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer srv.Close()
+	website = srv.URL
+	deviceID = "history-device"
+	initialized = true
+	defer func() { initialized = false }()
+
+	PostLocation(1, 1)
+	failing = false
+	PostLocation(2, 2)
+
+	locations, err := queryLocal(0, time.Now().UnixMilli())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(locations) != 2 || locations[0].Posted || !locations[1].Posted {
+		t.Fatalf("expected a pending and a posted location, got %+v %+v", locations[0], locations[1])
+	}
+
+	SetLocalHistoryEnabled(false)
+	PostLocation(3, 3)
+	SetLocalHistoryEnabled(true)
+	if locations, _ := queryLocal(0, time.Now().UnixMilli()); len(locations) != 2 {
+		t.Fatal("nothing must be recorded while the local history is disabled")
+	}
+}
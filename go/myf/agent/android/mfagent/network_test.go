@@ -0,0 +1,344 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package mfagent
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/saichler/l8myfamiliy/go/myf/agent/agentcore"
+	"github.com/saichler/l8myfamiliy/go/myf/agent/agenttest"
+)
+
+// newFakeServer starts the fake family server and points the agent at it
+func newFakeServer(t *testing.T) *agenttest.Server {
+	fake := agenttest.NewServer(t, "alice", "s3cret!")
+	website, user, pass, deviceID, deviceName = fake.URL, "alice", "s3cret!", "phone-1", "Phone"
+	retryDelay = time.Millisecond
+	t.Cleanup(func() {
+		website, user, pass, deviceID, deviceName = DefaultEndpoint, "", "", "", ""
+		bearerToken, signingKey, initialized, retryDelay = "", "", false, time.Second
+		tfaRequired, pendingTfaToken = false, ""
+		schemaVersion = 1
+		reauthGate = agentcore.NewReauthGate(0)
+	})
+	return fake
+}
+
+func TestAuthenticateRegisterAndPost(t *testing.T) {
+	fake := newFakeServer(t)
+	if err := Authenticate(); err != nil {
+		t.Fatal(err)
+	}
+	if err := RegisterDevice(); err != nil {
+		t.Fatal(err)
+	}
+	if err := PostLocation(1.5, 2.5); err != nil {
+		t.Fatal(err)
+	}
+	registrations, locations := fake.Registrations(), fake.Locations()
+	if len(registrations) != 1 || registrations[0].Id != "phone-1" || registrations[0].FamilyId != "alice" || registrations[0].SigningKey == "" {
+		t.Fatalf("unexpected registrations %v", registrations)
+	}
+	if len(locations) != 1 || locations[0].DeviceId != "phone-1" || locations[0].Latitude != 1.5 || locations[0].Signature == "" {
+		t.Fatalf("unexpected locations %v", locations)
+	}
+}
+
+func TestPostNegotiatesSchemaVersion(t *testing.T) {
+	for reported, want := range map[int32]int32{0: 0, 2: 2} {
+		fake := newFakeServer(t)
+		fake.SchemaVersion = reported
+		if err := Authenticate(); err != nil {
+			t.Fatal(err)
+		}
+		if err := PostLocation(1.5, 2.5); err != nil {
+			t.Fatal(err)
+		}
+		if locations := fake.Locations(); len(locations) != 1 || locations[0].SchemaVersion != want {
+			t.Fatalf("server schema %d: expected a post of schema %d, got %v", reported, want, locations)
+		}
+	}
+}
+
+func TestHeartbeatOnlyPostsNoPosition(t *testing.T) {
+	fake := newFakeServer(t)
+	heartbeatOnly = true
+	defer func() { heartbeatOnly = false }()
+	if err := Authenticate(); err != nil {
+		t.Fatal(err)
+	}
+	if err := RegisterDevice(); err != nil {
+		t.Fatal(err)
+	}
+	if err := PostLocation(1.5, 2.5); err != nil {
+		t.Fatal(err)
+	}
+	if err := PostEmergencyLocation(1.5, 2.5); err != nil {
+		t.Fatal(err)
+	}
+	registrations, locations := fake.Registrations(), fake.Locations()
+	if len(registrations) != 1 || !registrations[0].HeartbeatOnly {
+		t.Fatalf("the device must register heartbeat-only, got %v", registrations)
+	}
+	if len(locations) != 2 {
+		t.Fatalf("expected a heartbeat and an emergency, got %d posts", len(locations))
+	}
+	for _, l := range locations {
+		if !l.Heartbeat || l.Latitude != 0 || l.Longitude != 0 || l.Accuracy != 0 || l.Signature == "" {
+			t.Fatalf("a heartbeat-only post must carry no position, got %v", l)
+		}
+	}
+	if locations[0].Emergency || !locations[1].Emergency {
+		t.Fatalf("the emergency must still be flagged, got %v", locations)
+	}
+}
+
+func TestPostAfterTokenExpiry(t *testing.T) {
+	fake := newFakeServer(t)
+	Authenticate()
+	fake.ExpireToken()
+	err := PostLocation(1.5, 2.5)
+	if err == nil || !strings.Contains(err.Error(), "status 401") {
+		t.Fatalf("an expired token must be reported, got %v", err)
+	}
+	if err := ReAuthenticate(); err != nil {
+		t.Fatal(err)
+	}
+	if err := PostLocation(1.5, 2.5); err != nil || fake.Auths() != 2 || len(fake.Locations()) != 1 {
+		t.Fatalf("the renewed token must be accepted, got %v %d %d", err, fake.Auths(), len(fake.Locations()))
+	}
+}
+
+func TestPostRenewsExpiringToken(t *testing.T) {
+	fake := newFakeServer(t)
+	fake.ExpiresIn = 120
+	defer SetTokenMaxAgeSeconds(0)
+	if err := Authenticate(); err != nil {
+		t.Fatal(err)
+	}
+	// A fresh token is used as is
+	if err := PostLocation(1.5, 2.5); err != nil || fake.Auths() != 1 {
+		t.Fatalf("a fresh token must not be renewed, got %v %d", err, fake.Auths())
+	}
+
+	// A token issued 100 seconds ago is due 30 seconds before its expiry, it
+	// is renewed before the post instead of after a 401
+	tokenRenewal.Issued(bearerToken, 120, serverClock.Now().Add(-100*time.Second))
+	fake.ExpireToken()
+	if err := PostLocation(1.5, 2.5); err != nil || fake.Auths() != 2 || len(fake.Locations()) != 2 {
+		t.Fatalf("the expiring token must be renewed before the post, got %v %d %d", err, fake.Auths(), len(fake.Locations()))
+	}
+
+	// Without a known expiry the token is renewed after a 401 only
+	fake.ExpiresIn = 0
+	ReAuthenticate()
+	fake.ExpireToken()
+	if err := PostLocation(1.5, 2.5); err == nil || !strings.Contains(err.Error(), "status 401") {
+		t.Fatalf("a token without an expiry must be used until it is rejected, got %v", err)
+	}
+
+	// The max age renews a token without a known expiry. The 401 above was
+	// the test expiring the token, it does not hold back the renewal.
+	SetTokenMaxAgeSeconds(600)
+	reauthGate.Accepted(now())
+	Authenticate()
+	tokenRenewal.Issued(bearerToken, 0, serverClock.Now().Add(-10*time.Minute))
+	fake.ExpireToken()
+	if err := PostLocation(1.5, 2.5); err != nil {
+		t.Fatalf("a token older than the max age must be renewed, got %v", err)
+	}
+}
+
+func TestReAuthenticateCooldown(t *testing.T) {
+	fake := newFakeServer(t)
+	clock := time.Now()
+	now = func() time.Time { return clock }
+	defer func() { now = time.Now }()
+	if err := Authenticate(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The server rejects every post, e.g. the account is disabled
+	for i, wait := range []time.Duration{0, 30 * time.Second, time.Minute} {
+		clock = clock.Add(wait - time.Second)
+		if i > 0 {
+			if err := ReAuthenticate(); !errors.Is(err, agentcore.ErrReauthCoolingDown) {
+				t.Fatalf("attempt %d: a re-authentication within %s must wait, got %v", i, wait, err)
+			}
+		}
+		clock = clock.Add(time.Second)
+		if err := ReAuthenticate(); err != nil {
+			t.Fatalf("attempt %d: %v", i, err)
+		}
+		fake.Fail(http.StatusUnauthorized)
+		if err := PostLocation(1.5, 2.5); err == nil {
+			t.Fatal("the post must be rejected")
+		}
+	}
+	if fake.Auths() != 4 || !AuthFailing() {
+		t.Fatalf("expected 3 re-authentications and a failing state, got %d %v", fake.Auths(), AuthFailing())
+	}
+	if LastAcceptedPostSeconds() != -1 || !strings.Contains(DumpConfig(), `"auth_failing": true`) {
+		t.Fatalf("the failing state must be reported, got %d %s", LastAcceptedPostSeconds(), DumpConfig())
+	}
+
+	// An emergency is never held back by the cooldown
+	if err := TriggerPanic(1.5, 2.5); err != nil || fake.Auths() != 5 {
+		t.Fatalf("the panic must re-authenticate, got %v %d", err, fake.Auths())
+	}
+	if AuthFailing() || LastAcceptedPostSeconds() != 0 {
+		t.Fatalf("an accepted post must end the failing state, got %v %d", AuthFailing(), LastAcceptedPostSeconds())
+	}
+	clock = clock.Add(90 * time.Second)
+	if LastAcceptedPostSeconds() != 90 {
+		t.Fatalf("expected 90s since the accepted post, got %d", LastAcceptedPostSeconds())
+	}
+}
+
+func TestPostBacksOffOn5xx(t *testing.T) {
+	fake := newFakeServer(t)
+	Authenticate()
+	fake.Fail(http.StatusServiceUnavailable, http.StatusBadGateway)
+	if err := PostLocation(1.5, 2.5); err != nil {
+		t.Fatalf("the third attempt must succeed, got %v", err)
+	}
+	fake.Fail(http.StatusInternalServerError, http.StatusInternalServerError, http.StatusInternalServerError)
+	if err := PostLocation(1.6, 2.5); err == nil || !strings.Contains(err.Error(), "status 500") {
+		t.Fatalf("the post must fail after the attempts, got %v", err)
+	}
+	if len(fake.Locations()) != 1 {
+		t.Fatalf("expected only the first post accepted, got %v", fake.Locations())
+	}
+}
+
+func TestAuthenticateWithTfa(t *testing.T) {
+	fake := newFakeServer(t)
+	fake.TfaCode = "123456"
+	if err := Authenticate(); err != ErrTfaRequired {
+		t.Fatalf("expected a TFA verification, got %v", err)
+	}
+	if err := VerifyTfa("654321"); err == nil || IsInitialized() {
+		t.Fatalf("a wrong code must not log in, got %v", err)
+	}
+	if err := VerifyTfa("123456"); err != nil {
+		t.Fatal(err)
+	}
+	if err := PostLocation(1.5, 2.5); err != nil || fake.TfaVerifies() != 2 {
+		t.Fatalf("the verified token must be accepted, got %v %d", err, fake.TfaVerifies())
+	}
+}
+
+func TestAuthenticateErrors(t *testing.T) {
+	fake := newFakeServer(t)
+	pass = "wrong"
+	if err := Authenticate(); err == nil || !strings.Contains(err.Error(), "invalid credentials") {
+		t.Fatalf("wrong credentials must fail, got %v", err)
+	}
+	for _, body := range []string{`{"token": ""}`, `{"setupTfa": true}`, "   "} {
+		fake.AuthBody = body
+		if err := Authenticate(); err == nil || bearerToken != "" {
+			t.Fatalf("auth response %q must fail, got %v %q", body, err, bearerToken)
+		}
+	}
+}
+
+func TestInitializeRetriesTransientLogin(t *testing.T) {
+	fake := newFakeServer(t)
+	configDir = t.TempDir()
+	origRetry := startupRetry
+	startupRetry = &agentcore.StartupRetry{MinBackoff: time.Millisecond, MaxBackoff: time.Millisecond}
+	// Initialize delays the first post by the start jitter
+	t.Cleanup(func() { configDir, startupRetry, startAt = "", origRetry, time.Time{} })
+	// The server answers 503 to the first two logins, e.g. while it starts
+	logins := 0
+	flaky := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/auth" {
+			if logins++; logins <= 2 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+		}
+		fake.Config.Handler.ServeHTTP(w, r)
+	}))
+	defer flaky.Close()
+	website = flaky.URL
+	SetStartupRetries(3)
+	if err := SaveConfig(); err != nil {
+		t.Fatal(err)
+	}
+	if err := Initialize(); err != nil || !initialized || fake.Auths() != 1 || logins != 3 {
+		t.Fatalf("the third login must initialize the agent, got %v after %d logins", err, logins)
+	}
+
+	// Refused credentials and a TFA prompt are returned at once
+	logins, initialized = 2, false
+	pass = "wrong"
+	SaveConfig()
+	if err := Initialize(); !errors.Is(err, errAuthRejected) || logins != 3 {
+		t.Fatalf("a rejected login must not be retried, got %v after %d logins", err, logins)
+	}
+	logins, pass, fake.TfaCode = 2, "s3cret!", "123456"
+	SaveConfig()
+	if err := Initialize(); err != ErrTfaRequired || logins != 3 {
+		t.Fatalf("a TFA prompt must not be retried, got %v after %d logins", err, logins)
+	}
+
+	// Without retries the first failure is returned
+	logins, fake.TfaCode = 0, ""
+	SetStartupRetries(0)
+	SaveConfig()
+	if err := Initialize(); err == nil || logins != 1 {
+		t.Fatalf("expected a single login, got %v after %d logins", err, logins)
+	}
+}
+
+func TestAliasNamesTheDeviceInTheLogsOnly(t *testing.T) {
+	fake := newFakeServer(t)
+	SetAlias("kids-tablet")
+	defer SetAlias("")
+	if err := Authenticate(); err != nil {
+		t.Fatal(err)
+	}
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	err = RegisterDevice()
+	os.Stdout = stdout
+	w.Close()
+	logs, _ := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(logs), "kids-tablet") || strings.Contains(string(logs), "phone-1") {
+		t.Fatalf("the logs must name the device by its alias, got %s", logs)
+	}
+	if registrations := fake.Registrations(); len(registrations) != 1 || registrations[0].Name != "Phone" {
+		t.Fatalf("the alias must not be posted, got %v", registrations)
+	}
+	if dump := DumpConfig(); !strings.Contains(dump, `"alias": "kids-tablet"`) {
+		t.Fatalf("the dumped config must show the alias, got %s", dump)
+	}
+}
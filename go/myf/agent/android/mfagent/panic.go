@@ -0,0 +1,88 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package mfagent
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// panicAttempts is how many times TriggerPanic sends the alarm, every send
+// already retries network and server errors postAttempts times
+const panicAttempts = 3
+
+// panicRetryDelay is the wait between two sends of the alarm
+var panicRetryDelay = 500 * time.Millisecond
+
+// TriggerPanic posts an emergency location so the server alerts the family
+// at once. Unlike PostEmergencyLocation it needs no prior Initialize: it
+// loads the config if needed and re-authenticates first so a stale session
+// does not cost the alarm a rejected attempt, then sends it up to
+// panicAttempts times. The position is never collapsed or suppressed by the
+// trusted geofences or the post on move filter. The returned error tells the
+// UI whether the family was alerted.
+func TriggerPanic(latitude, longitude float64) error {
+	if NeedsConfiguration() {
+		if err := LoadConfig(); err != nil {
+			return fmt.Errorf("panic not sent, failed to load config: %w", err)
+		}
+		if NeedsConfiguration() {
+			return fmt.Errorf("panic not sent: website or credentials not set")
+		}
+	}
+	if err := reAuthenticate(); err != nil {
+		if bearerToken == "" {
+			return fmt.Errorf("panic not sent, failed to authenticate: %w", err)
+		}
+		fmt.Println("[mfagent] re-authentication before panic failed, using the current token: ", err.Error())
+	}
+
+	location := &Location{
+		DeviceID:       deviceID,
+		Latitude:       latitude,
+		Longitude:      longitude,
+		Emergency:      true,
+		Source:         sourceAndroid,
+		IdempotencyKey: uuid.New().String(),
+	}
+	var err error
+	for attempt := 1; attempt <= panicAttempts; attempt++ {
+		if attempt > 1 {
+			time.Sleep(panicRetryDelay)
+		}
+		if err = transmit(location); err == nil {
+			break
+		}
+		fmt.Println("[mfagent] panic attempt ", attempt, " failed: ", err.Error())
+		// The token may have expired between the re-authentication and the post
+		if strings.Contains(err.Error(), "status 401") {
+			reAuthenticate()
+		}
+	}
+
+	local := &LocalLocation{Timestamp: now().UnixMilli(), Latitude: latitude, Longitude: longitude,
+		Emergency: true, Posted: err == nil}
+	if herr := appendLocal(local); herr != nil {
+		fmt.Println("[mfagent] ", herr.Error())
+	}
+	if err != nil {
+		return fmt.Errorf("panic not sent: %w", err)
+	}
+	return nil
+}
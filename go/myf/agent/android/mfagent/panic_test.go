@@ -0,0 +1,93 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package mfagent
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestTriggerPanicBypassesFilters(t *testing.T) {
+	var posted []Location
+	var keys []string
+	auths := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/auth" {
+			auths++
+			json.NewEncoder(w).Encode(map[string]string{"token": "fresh"})
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer fresh" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		var location Location
+		json.NewDecoder(r.Body).Decode(&location)
+		posted = append(posted, location)
+		keys = append(keys, r.Header.Get("Idempotency-Key"))
+		if len(posted) == 1 {
+			// The first send is rejected as a client error and resent
+			w.WriteHeader(http.StatusConflict)
+		}
+	}))
+	defer srv.Close()
+
+	website, user, pass = srv.URL, "parent", "secret"
+	deviceID = "panic-device"
+	bearerToken, refreshToken = "stale", ""
+	initialized = false
+	panicRetryDelay = time.Millisecond
+	AddTrustedGeofence("home", 40.0, -74.0, 200)
+	SetPostOnMoveMeters(500)
+	defer func() {
+		initialized = false
+		bearerToken, user, pass = "", "", ""
+		panicRetryDelay = 500 * time.Millisecond
+		ClearGeofences()
+		SetPostOnMoveMeters(0)
+	}()
+	// The device sits at home, regular posts are collapsed to the fence
+	initialized = true
+	PostLocation(40.0005, -74.0005)
+	initialized = false
+	posted, keys = nil, nil
+
+	if err := TriggerPanic(40.0005, -74.0005); err != nil {
+		t.Fatal(err)
+	}
+	if auths != 1 {
+		t.Fatalf("a panic must re-authenticate first, got %d logins", auths)
+	}
+	if len(posted) != 2 || keys[0] != keys[1] {
+		t.Fatalf("expected the alarm to be resent with the same key, got %v", keys)
+	}
+	last := posted[1]
+	if !last.Emergency || last.Place != "" || last.Latitude != 40.0005 || last.Longitude != -74.0005 {
+		t.Fatalf("a panic must report the precise position, got %+v", last)
+	}
+}
+
+func TestTriggerPanicFailsWithoutConfiguration(t *testing.T) {
+	website, user, pass = "", "", ""
+	configDir = t.TempDir()
+	defer func() { configDir = "" }()
+	if err := TriggerPanic(1, 2); err == nil {
+		t.Fatal("a panic without configuration must report the failure")
+	}
+}
@@ -0,0 +1,187 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package mfagent
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/saichler/l8myfamiliy/go/myf/agent/agentcore"
+)
+
+// errRefreshRejected is returned when the server no longer accepts the
+// refresh token, e.g. it expired or the session was revoked
+var errRefreshRejected = errors.New("refresh token rejected")
+
+// refreshToken is the server issued refresh token, it is stored encrypted
+// like the credentials and lets ReAuthenticate renew the bearer token
+// without sending the password
+var refreshToken = ""
+
+// tokenRenewal tells when the bearer token is renewed before it expires,
+// tokenMaxAge caps how long a token is used
+var (
+	tokenMaxAge  time.Duration
+	tokenRenewal = agentcore.NewTokenRenewal(0)
+)
+
+// pendingTfaExpiresIn is the lifetime of the pending TFA token
+var pendingTfaExpiresIn int64
+
+// SetTokenMaxAgeSeconds renews the bearer token at most this long after it
+// was issued, 0 renews it shortly before it expires when the server tells the
+// expiry, and otherwise only after a post was rejected with a 401. It applies
+// to the following logins. Call SaveConfig to persist it.
+func SetTokenMaxAgeSeconds(seconds int) {
+	tokenMaxAge = 0
+	if seconds > 0 {
+		tokenMaxAge = time.Duration(seconds) * time.Second
+	}
+	tokenRenewal = agentcore.NewTokenRenewal(tokenMaxAge)
+}
+
+// renewTokenIfDue re-authenticates shortly before the bearer token expires,
+// so a post is not sent with a token the server already expired. A failed
+// renewal keeps the current token, it is renewed again after a 401.
+func renewTokenIfDue() {
+	if bearerToken == "" || !tokenRenewal.Due(serverClock.Now()) {
+		return
+	}
+	if err := ReAuthenticate(); err != nil {
+		fmt.Println("[mfagent] renewing the expiring token failed, using the current token: ", err.Error())
+		tokenRenewal.Reset()
+	}
+}
+
+// HasRefreshToken returns true if a refresh token is stored
+func HasRefreshToken() bool {
+	return refreshToken != ""
+}
+
+// errAuthRejected is returned for a login the server refused, e.g. wrong
+// credentials or a disabled account
+var errAuthRejected = errors.New("authentication failed")
+
+// ReAuthenticate re-authenticates with the server.
+// Use this if the bearer token has expired. The refresh token is exchanged
+// first, the password is only sent when there is no refresh token or the
+// server rejected it. A server that keeps rejecting the posts is not asked
+// again within the cooldown, ReAuthenticate then returns an error wrapping
+// agentcore.ErrReauthCoolingDown, see AuthFailing.
+func ReAuthenticate() error {
+	if left, err := reauthGate.Ready(now()); err != nil {
+		return fmt.Errorf("%w, next attempt in %s", err, left.Round(time.Second))
+	}
+	if reauthGate.Failing() {
+		fmt.Println("[mfagent] authentication is persistently failing, ", reauthGate.FailingFor(now()),
+			", check the credentials and the account")
+	}
+	err := reAuthenticate()
+	if err == nil || errors.Is(err, errAuthRejected) {
+		reauthGate.Attempted(now())
+	}
+	return err
+}
+
+// AuthFailing returns true when the last re-authentications were not followed
+// by an accepted post, the app should ask the user to check the account
+func AuthFailing() bool {
+	return reauthGate.Failing()
+}
+
+// LastAcceptedPostSeconds returns the seconds since the server last accepted
+// a post, -1 when it accepted none since the start, see AuthFailing
+func LastAcceptedPostSeconds() int64 {
+	since, ok := reauthGate.SinceAccepted(now())
+	if !ok {
+		return -1
+	}
+	return int64(since / time.Second)
+}
+
+// reAuthenticate re-authenticates without the cooldown, an emergency is
+// never held back
+func reAuthenticate() error {
+	if refreshToken != "" {
+		err := refreshBearer()
+		if err == nil || !errors.Is(err, errRefreshRejected) {
+			return err
+		}
+	}
+	return Authenticate()
+}
+
+// refreshBearer exchanges the refresh token for a new bearer token at the
+// /refresh endpoint. A rotated refresh token in the response replaces the
+// stored one.
+func refreshBearer() error {
+	refreshURL := strings.TrimSuffix(website, "/") + "/refresh"
+	data, err := json.Marshal(map[string]string{"refreshToken": refreshToken})
+	if err != nil {
+		return fmt.Errorf("failed to marshal refresh request: %w", err)
+	}
+
+	resp, err := getHTTPClient().Post(refreshURL, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("refresh request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read refresh response: %w", err)
+	}
+	switch {
+	case resp.StatusCode == http.StatusBadRequest || resp.StatusCode == http.StatusUnauthorized ||
+		resp.StatusCode == http.StatusForbidden || resp.StatusCode == http.StatusNotFound:
+		// The server does not accept the token, or does not support refresh
+		setRefreshToken("")
+		return fmt.Errorf("%w: %s", errRefreshRejected, strings.TrimSpace(string(body)))
+	case resp.StatusCode != http.StatusOK:
+		return fmt.Errorf("refresh failed: server returned status %d", resp.StatusCode)
+	}
+
+	var authResp AuthResponse
+	if err := json.Unmarshal(body, &authResp); err != nil || authResp.Token == "" {
+		setRefreshToken("")
+		return fmt.Errorf("%w: invalid refresh response", errRefreshRejected)
+	}
+	bearerToken = authResp.Token
+	tokenRenewal.Issued(bearerToken, authResp.ExpiresIn, serverClock.Now())
+	initialized = true
+	if authResp.RefreshToken != "" {
+		setRefreshToken(authResp.RefreshToken)
+	}
+	return nil
+}
+
+// setRefreshToken replaces the refresh token and saves the config so a
+// rotated or revoked token survives a restart
+func setRefreshToken(token string) {
+	if token == refreshToken {
+		return
+	}
+	refreshToken = token
+	if configDir != "" {
+		SaveConfig()
+	}
+}
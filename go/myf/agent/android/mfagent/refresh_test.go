@@ -0,0 +1,83 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package mfagent
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/saichler/l8myfamiliy/go/myf/agent/agentcore"
+)
+
+func TestReAuthenticateWithRefreshToken(t *testing.T) {
+	var passwordAuths int
+	rejectRefresh := false
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/refresh":
+			var req map[string]string
+			json.NewDecoder(r.Body).Decode(&req)
+			if rejectRefresh || req["refreshToken"] != "refresh-1" {
+				http.Error(w, "revoked", http.StatusUnauthorized)
+				return
+			}
+			json.NewEncoder(w).Encode(&AuthResponse{Token: "bearer-2", RefreshToken: "refresh-2"})
+		case "/auth":
+			passwordAuths++
+			json.NewEncoder(w).Encode(&AuthResponse{Token: "bearer-3", RefreshToken: "refresh-3"})
+		}
+	}))
+	defer srv.Close()
+
+	website, user, pass = srv.URL, "user", "secret"
+	configDir = t.TempDir()
+	refreshToken = "refresh-1"
+	defer func() { configDir, refreshToken, initialized = "", "", false; reauthGate = agentcore.NewReauthGate(0) }()
+
+	if err := ReAuthenticate(); err != nil {
+		t.Fatal(err)
+	}
+	if passwordAuths != 0 || bearerToken != "bearer-2" || refreshToken != "refresh-2" {
+		t.Fatalf("expected a refresh without the password, got auths=%d bearer=%s refresh=%s",
+			passwordAuths, bearerToken, refreshToken)
+	}
+	data, _ := os.ReadFile(getConfigPath())
+	if !strings.Contains(string(data), "encrypted_refresh_token") || strings.Contains(string(data), "refresh-2") {
+		t.Fatal("the rotated refresh token must be saved encrypted")
+	}
+
+	rejectRefresh = true
+	reauthGate.Accepted(now())
+	if err := ReAuthenticate(); err != nil {
+		t.Fatal(err)
+	}
+	if passwordAuths != 1 || bearerToken != "bearer-3" || refreshToken != "refresh-3" {
+		t.Fatalf("a rejected refresh must fall back to the password, got auths=%d bearer=%s",
+			passwordAuths, bearerToken)
+	}
+
+	refreshToken = ""
+	if err := LoadConfig(); err != nil {
+		t.Fatal(err)
+	}
+	if refreshToken != "refresh-3" {
+		t.Fatalf("expected the saved refresh token to load, got %q", refreshToken)
+	}
+}
@@ -0,0 +1,78 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package mfagent
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// maxTermuxAccuracy is the largest accepted accuracy radius of a fed fix,
+// in meters, a larger one is not a position fix
+const maxTermuxAccuracy = 100000
+
+// termuxFix is the JSON written by termux-location, the coordinates are
+// pointers so a missing one is told from 0
+type termuxFix struct {
+	Latitude  *float64 `json:"latitude"`
+	Longitude *float64 `json:"longitude"`
+	Accuracy  float64  `json:"accuracy"`
+	Provider  string   `json:"provider"`
+	// APIError is set by termux-location when no fix is available
+	APIError string `json:"API_ERROR"`
+}
+
+// PostLocationJSON posts a fix given as the JSON output of termux-location,
+// e.g. {"latitude": 37.4, "longitude": -122.1, "accuracy": 12.5, "provider": "gps"},
+// for hosts without Play Services location that feed the agent raw fixes.
+// The provider sets the source, the other termux-location fields are ignored. Malformed JSON and out of
+// range values are rejected before anything is posted.
+func PostLocationJSON(data string) error {
+	fix, err := parseTermuxFix(data)
+	if err != nil {
+		return err
+	}
+	return PostLocationFromProvider(*fix.Latitude, *fix.Longitude, fix.Accuracy, fix.Provider)
+}
+
+// parseTermuxFix decodes and validates a termux-location fix
+func parseTermuxFix(data string) (*termuxFix, error) {
+	if strings.TrimSpace(data) == "" {
+		return nil, fmt.Errorf("invalid location JSON: empty input")
+	}
+	fix := &termuxFix{}
+	if err := json.Unmarshal([]byte(data), fix); err != nil {
+		return nil, fmt.Errorf("invalid location JSON: %w", err)
+	}
+	if fix.APIError != "" {
+		return nil, fmt.Errorf("no location fix: %s", fix.APIError)
+	}
+	if fix.Latitude == nil || fix.Longitude == nil {
+		return nil, fmt.Errorf("invalid location JSON: latitude and longitude are required")
+	}
+	if math.IsNaN(*fix.Latitude) || *fix.Latitude < -90 || *fix.Latitude > 90 {
+		return nil, fmt.Errorf("invalid location: latitude %v is out of range -90..90", *fix.Latitude)
+	}
+	if math.IsNaN(*fix.Longitude) || *fix.Longitude < -180 || *fix.Longitude > 180 {
+		return nil, fmt.Errorf("invalid location: longitude %v is out of range -180..180", *fix.Longitude)
+	}
+	if fix.Accuracy < 0 || fix.Accuracy > maxTermuxAccuracy {
+		return nil, fmt.Errorf("invalid location: accuracy %v is out of range 0..%d", fix.Accuracy, maxTermuxAccuracy)
+	}
+	return fix, nil
+}
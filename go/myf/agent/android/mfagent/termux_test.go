@@ -0,0 +1,74 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package mfagent
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/saichler/l8myfamiliy/go/types/l8myfamily"
+)
+
+func TestParseTermuxFix(t *testing.T) {
+	fix, err := parseTermuxFix(`{"latitude": 37.4219983, "longitude": -122.084, "altitude": 5.0,
+		"accuracy": 12.5, "vertical_accuracy": 3.0, "bearing": 0.0, "speed": 0.0, "elapsedMs": 33, "provider": "gps"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *fix.Latitude != 37.4219983 || *fix.Longitude != -122.084 || fix.Accuracy != 12.5 {
+		t.Fatalf("unexpected fix %+v", fix)
+	}
+
+	for input, reason := range map[string]string{
+		"":                                      "empty input",
+		`{"latitude": 1`:                        "invalid location JSON",
+		`{"latitude": "north", "longitude": 2}`: "invalid location JSON",
+		`{"longitude": 2}`:                      "latitude and longitude are required",
+		`{"latitude": 91, "longitude": 2}`:      "latitude 91 is out of range",
+		`{"latitude": 1, "longitude": -181}`:    "longitude -181 is out of range",
+		`{"latitude": 1, "longitude": 2, "accuracy": -1}`: "accuracy -1 is out of range",
+		`{"API_ERROR": "Location unavailable"}`:           "no location fix: Location unavailable",
+	} {
+		if _, err := parseTermuxFix(input); err == nil || !strings.Contains(err.Error(), reason) {
+			t.Errorf("expected %q for %q, got %v", reason, input, err)
+		}
+	}
+}
+
+func TestPostLocationJSON(t *testing.T) {
+	fake := newFakeServer(t)
+	if err := Authenticate(); err != nil {
+		t.Fatal(err)
+	}
+	if err := PostLocationJSON(`{"latitude": 1.5, "longitude": 2.5, "accuracy": 20}`); err != nil {
+		t.Fatal(err)
+	}
+	if err := PostLocationJSON(`{"latitude": 100, "longitude": 2.5}`); err == nil {
+		t.Fatal("an out of range fix must be rejected")
+	}
+	if err := PostLocationJSON(`{"latitude": 3.5, "longitude": 4.5, "accuracy": 5, "provider": "gps"}`); err != nil {
+		t.Fatal(err)
+	}
+	locations := fake.Locations()
+	if len(locations) != 2 || locations[0].Latitude != 1.5 || locations[0].Longitude != 2.5 || locations[0].Accuracy != 20 {
+		t.Fatalf("expected the valid fixes posted once, got %v", locations)
+	}
+	// The source follows the provider of the fix
+	if locations[0].Source != l8myfamily.LocationSource_LOCATION_SOURCE_ANDROID ||
+		locations[1].Source != l8myfamily.LocationSource_LOCATION_SOURCE_GPS {
+		t.Fatalf("expected the android then the gps source, got %v %v", locations[0].Source, locations[1].Source)
+	}
+}
@@ -0,0 +1,120 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package mfagent
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultTfaCodeLength is the length of a standard TOTP code
+	DefaultTfaCodeLength = 6
+	// tfaMaxFailures is the number of consecutive failed verifications
+	// allowed before the client locks out further attempts
+	tfaMaxFailures = 3
+	// tfaBaseLockout is the first lockout, it doubles with every further
+	// failure up to tfaMaxLockout
+	tfaBaseLockout = 30 * time.Second
+	tfaMaxLockout  = time.Hour
+)
+
+// ErrTfaLocked is returned by VerifyTfa while the client lockout is active,
+// GetTfaLockoutSeconds returns the time left
+var ErrTfaLocked = fmt.Errorf("TFA_LOCKED")
+
+var (
+	tfaCodeLength  = DefaultTfaCodeLength
+	tfaFailures    = 0
+	tfaLockedUntil time.Time
+	tfaMtx         = &sync.Mutex{}
+	// now is the clock, tests replace it
+	now = time.Now
+)
+
+// SetTfaCodeLength sets the expected TFA code length, e.g. 8 for TOTP setups
+// with 8 digits. Values below 6 are rejected to keep the code strength.
+func SetTfaCodeLength(length int) error {
+	if length < DefaultTfaCodeLength || length > 10 {
+		return fmt.Errorf("invalid TFA code length %d: must be 6 to 10", length)
+	}
+	tfaCodeLength = length
+	return nil
+}
+
+// GetTfaCodeLength returns the expected TFA code length
+func GetTfaCodeLength() int {
+	return tfaCodeLength
+}
+
+// GetTfaLockoutSeconds returns the seconds left until VerifyTfa may be called
+// again, 0 when there is no lockout. The UI uses it for a countdown.
+func GetTfaLockoutSeconds() int64 {
+	tfaMtx.Lock()
+	defer tfaMtx.Unlock()
+	left := tfaLockedUntil.Sub(now())
+	if left <= 0 {
+		return 0
+	}
+	return int64((left + time.Second - 1) / time.Second)
+}
+
+// IsTfaLockedError returns true if the error indicates the TFA lockout is active
+func IsTfaLockedError(err error) bool {
+	return err != nil && err.Error() == ErrTfaLocked.Error()
+}
+
+// checkTfaCode validates the code format and the lockout before the code is
+// sent to the server
+func checkTfaCode(code string) error {
+	if GetTfaLockoutSeconds() > 0 {
+		return ErrTfaLocked
+	}
+	if len(code) != tfaCodeLength {
+		return fmt.Errorf("invalid TFA code: must be %d digits", tfaCodeLength)
+	}
+	for _, c := range code {
+		if c < '0' || c > '9' {
+			return fmt.Errorf("invalid TFA code: must be %d digits", tfaCodeLength)
+		}
+	}
+	return nil
+}
+
+// tfaFailed records a code rejected by the server, after tfaMaxFailures
+// consecutive failures every further failure doubles the lockout
+func tfaFailed() {
+	tfaMtx.Lock()
+	defer tfaMtx.Unlock()
+	tfaFailures++
+	if tfaFailures < tfaMaxFailures {
+		return
+	}
+	lockout := tfaBaseLockout << (tfaFailures - tfaMaxFailures)
+	if lockout > tfaMaxLockout || lockout <= 0 {
+		lockout = tfaMaxLockout
+	}
+	tfaLockedUntil = now().Add(lockout)
+}
+
+// tfaSucceeded resets the failure count and the lockout
+func tfaSucceeded() {
+	tfaMtx.Lock()
+	defer tfaMtx.Unlock()
+	tfaFailures = 0
+	tfaLockedUntil = time.Time{}
+}
@@ -0,0 +1,89 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package mfagent
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestVerifyTfaLockout(t *testing.T) {
+	accept := false
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(&TfaVerifyResponse{Ok: accept})
+	}))
+	defer srv.Close()
+
+	clock := time.Unix(1700000000, 0)
+	now = func() time.Time { return clock }
+	website = srv.URL
+	defer func() { now = time.Now; tfaSucceeded(); initialized = false }()
+	verify := func(code string) error {
+		tfaRequired, pendingTfaToken = true, "pending"
+		return VerifyTfa(code)
+	}
+
+	if err := verify("12345"); err == nil {
+		t.Fatal("a 5 digit code must be rejected")
+	}
+	for i := 0; i < tfaMaxFailures; i++ {
+		if err := verify("123456"); err == nil || IsTfaLockedError(err) {
+			t.Fatalf("attempt %d: expected a server rejection, got %v", i, err)
+		}
+	}
+	if left := GetTfaLockoutSeconds(); left != 30 {
+		t.Fatalf("expected a 30s lockout, got %d", left)
+	}
+	if err := verify("123456"); !IsTfaLockedError(err) {
+		t.Fatalf("expected the lockout, got %v", err)
+	}
+
+	clock = clock.Add(31 * time.Second)
+	if err := verify("123456"); err == nil || IsTfaLockedError(err) {
+		t.Fatalf("the lockout expired, expected a server rejection, got %v", err)
+	}
+	if left := GetTfaLockoutSeconds(); left != 60 {
+		t.Fatalf("expected the lockout to double to 60s, got %d", left)
+	}
+
+	clock = clock.Add(61 * time.Second)
+	accept = true
+	if err := verify("123456"); err != nil {
+		t.Fatal(err)
+	}
+	if tfaFailures != 0 || GetTfaLockoutSeconds() != 0 {
+		t.Fatal("a success must reset the failures")
+	}
+}
+
+func TestTfaCodeLength(t *testing.T) {
+	defer SetTfaCodeLength(DefaultTfaCodeLength)
+	if SetTfaCodeLength(4) == nil {
+		t.Fatal("a code shorter than 6 digits must be rejected")
+	}
+	if err := SetTfaCodeLength(8); err != nil {
+		t.Fatal(err)
+	}
+	if checkTfaCode("123456") == nil || checkTfaCode("1234567a") == nil {
+		t.Fatal("only 8 digit codes are valid")
+	}
+	if err := checkTfaCode("12345678"); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,126 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultIntervalChargingSeconds   = 10
+	defaultIntervalBatterySeconds    = 60
+	defaultIntervalLowBatterySeconds = 300
+	defaultLowBatteryPercent         = 20
+)
+
+// powerSupplyDir is the Linux sysfs power supply class
+const powerSupplyDir = "/sys/class/power_supply"
+
+// getBatteryLevel returns the battery percentage and whether the laptop is on
+// external power. A machine without a battery is reported as charging.
+func getBatteryLevel() (int, bool, error) {
+	switch runtime.GOOS {
+	case "linux":
+		return getBatteryLevelLinux(powerSupplyDir)
+	case "darwin":
+		return getBatteryLevelMac()
+	}
+	return 0, false, fmt.Errorf("battery status not supported on %s", runtime.GOOS)
+}
+
+// getBatteryLevelLinux reads the first battery of the sysfs power supply class
+func getBatteryLevelLinux(dir string) (int, bool, error) {
+	batteries, _ := filepath.Glob(filepath.Join(dir, "BAT*"))
+	if len(batteries) == 0 {
+		return 100, true, nil
+	}
+	data, err := os.ReadFile(filepath.Join(batteries[0], "capacity"))
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to read battery capacity: %w", err)
+	}
+	level, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil {
+		return 0, false, fmt.Errorf("invalid battery capacity: %w", err)
+	}
+	status, _ := os.ReadFile(filepath.Join(batteries[0], "status"))
+	// "Charging", "Full" and "Not charging" (plugged in, charge limited)
+	// all mean external power
+	charging := strings.TrimSpace(string(status)) != "Discharging"
+	return level, charging, nil
+}
+
+var pmsetPercent = regexp.MustCompile(`(\d+)%`)
+
+// getBatteryLevelMac parses "pmset -g batt"
+func getBatteryLevelMac() (int, bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	output, err := exec.CommandContext(ctx, "pmset", "-g", "batt").Output()
+	if err != nil {
+		return 0, false, fmt.Errorf("pmset failed: %w", err)
+	}
+	return parsePmset(string(output))
+}
+
+func parsePmset(output string) (int, bool, error) {
+	charging := strings.Contains(output, "'AC Power'")
+	match := pmsetPercent.FindStringSubmatch(output)
+	if match == nil {
+		// A Mac without a battery
+		return 100, charging, nil
+	}
+	level, _ := strconv.Atoi(match[1])
+	return level, charging, nil
+}
+
+// postInterval returns the interval until the next post for the power state:
+// short while charging, longer on battery and longest on low battery. An
+// unknown power state uses the charging interval, as before this setting.
+func postInterval() time.Duration {
+	level, charging, err := getBatteryLevel()
+	return intervalFor(level, charging, err)
+}
+
+// batteryPercent returns the battery level reported with the posts,
+// 0 when it is unknown
+func batteryPercent() int32 {
+	level, _, err := getBatteryLevel()
+	if err != nil || level <= 0 {
+		return 0
+	}
+	return int32(level)
+}
+
+func intervalFor(level int, charging bool, err error) time.Duration {
+	seconds := intervalChargingSeconds
+	switch {
+	case err != nil || charging:
+	case level <= lowBatteryPercent:
+		seconds = intervalLowBatterySeconds
+	default:
+		seconds = intervalBatterySeconds
+	}
+	return time.Duration(seconds) * time.Second
+}
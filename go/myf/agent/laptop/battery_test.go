@@ -0,0 +1,69 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestBatteryLevelLinux(t *testing.T) {
+	dir := t.TempDir()
+	if level, charging, err := getBatteryLevelLinux(dir); err != nil || !charging || level != 100 {
+		t.Fatal("a machine without a battery must report charging")
+	}
+	bat := filepath.Join(dir, "BAT0")
+	os.MkdirAll(bat, 0755)
+	os.WriteFile(filepath.Join(bat, "capacity"), []byte("42\n"), 0644)
+	os.WriteFile(filepath.Join(bat, "status"), []byte("Discharging\n"), 0644)
+	level, charging, err := getBatteryLevelLinux(dir)
+	if err != nil || level != 42 || charging {
+		t.Fatalf("unexpected battery state %d %v %v", level, charging, err)
+	}
+	os.WriteFile(filepath.Join(bat, "status"), []byte("Not charging\n"), 0644)
+	if _, charging, _ := getBatteryLevelLinux(dir); !charging {
+		t.Fatal("a plugged in laptop with a charge limit is on external power")
+	}
+}
+
+func TestParsePmset(t *testing.T) {
+	level, charging, _ := parsePmset("Now drawing from 'Battery Power'\n -InternalBattery-0 (id=123)\t57%; discharging; 4:10 remaining present: true")
+	if level != 57 || charging {
+		t.Fatalf("unexpected %d %v", level, charging)
+	}
+	level, charging, _ = parsePmset("Now drawing from 'AC Power'\n -InternalBattery-0 (id=123)\t100%; charged; 0:00 remaining present: true")
+	if level != 100 || !charging {
+		t.Fatalf("unexpected %d %v", level, charging)
+	}
+}
+
+func TestIntervalFor(t *testing.T) {
+	if intervalFor(50, true, nil) != 10*time.Second {
+		t.Fatal("expected the charging interval")
+	}
+	if intervalFor(50, false, nil) != 60*time.Second {
+		t.Fatal("expected the battery interval")
+	}
+	if intervalFor(15, false, nil) != 300*time.Second {
+		t.Fatal("expected the low battery interval")
+	}
+	if intervalFor(0, false, errors.New("unsupported")) != 10*time.Second {
+		t.Fatal("an unknown power state must keep the charging interval")
+	}
+}
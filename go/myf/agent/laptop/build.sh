@@ -1,8 +1,10 @@
+# VERSION is reported to the server with the registration
+LDFLAGS="-X github.com/saichler/l8myfamiliy/go/myf/agent/agentcore.Version=${VERSION:-dev}"
 echo "Building Linux"
-GOOS=linux GOARCH=amd64 go build -o agent-linux
+GOOS=linux GOARCH=amd64 go build -ldflags "$LDFLAGS" -o agent-linux
 echo "Building Windows"
-GOOS=windows GOARCH=amd64 go build -o agent-windows.exe
+GOOS=windows GOARCH=amd64 go build -ldflags "$LDFLAGS" -o agent-windows.exe
 echo "Building Mac Intel"
-GOOS=darwin GOARCH=amd64 go build -o agent-mac-intel
+GOOS=darwin GOARCH=amd64 go build -ldflags "$LDFLAGS" -o agent-mac-intel
 echo "building Mac Apple"
-GOOS=darwin GOARCH=arm64 go build -o agent-mac-apple
+GOOS=darwin GOARCH=arm64 go build -ldflags "$LDFLAGS" -o agent-mac-apple
@@ -28,15 +28,65 @@ import (
 	"github.com/saichler/l8myfamiliy/go/types/l8myfamily"
 )
 
+// The GeoClue accuracy levels, a client requests one and the agent compares
+// the accuracy of the fix it got against it
+const (
+	geoClueLevelCountry = 1
+	geoClueLevelCity    = 4
+	geoClueLevelExact   = 8
+)
+
+// geoClueRequestedLevel is the accuracy level the agent asks GeoClue for
+const geoClueRequestedLevel = geoClueLevelExact
+
+// The accuracy radius GeoClue reports for a street and a city level fix
+const (
+	geoClueStreetMeters = 1000
+	geoClueCityMeters   = 15000
+)
+
+// geoClueAchievedLevel returns the accuracy level a fix of the accuracy
+// radius satisfies, a fix without an accuracy is taken as requested
+func geoClueAchievedLevel(accuracy float64) int {
+	switch {
+	case accuracy <= geoClueStreetMeters:
+		return geoClueRequestedLevel
+	case accuracy <= geoClueCityMeters:
+		return geoClueLevelCity
+	default:
+		return geoClueLevelCountry
+	}
+}
+
+// geoClueFix flags a GeoClue fix below the requested level coarse, the
+// system granted the agent only city level access, and drops it when
+// skipCoarse is set
+func geoClueFix(location *l8myfamily.Location) (*l8myfamily.Location, error) {
+	location.Coarse = geoClueAchievedLevel(float64(location.Accuracy)) < geoClueRequestedLevel
+	if location.Coarse && skipCoarse {
+		return nil, fmt.Errorf("GeoClue granted only a coarse fix of %.0f m, location skipped", location.Accuracy)
+	}
+	return location, nil
+}
+
 // getLocationFromGeoClue gets location using GeoClue2 D-Bus service
 // GeoClue is the standard location service on Linux desktops (GNOME, KDE, etc.)
 // It can use WiFi positioning, GPS (if available), and IP-based geolocation
 func getLocationFromGeoClue() (*l8myfamily.Location, error) {
+	// A started persistent client already proved the service is there
+	if geoClueMode == geoCluePersistent && geoClue.active() {
+		return geoClue.location()
+	}
+
 	// First check if GeoClue service is available
 	if !isGeoClueAvailable() {
 		return nil, fmt.Errorf("GeoClue2 service not available")
 	}
 
+	if geoClueMode == geoCluePersistent {
+		return geoClue.location()
+	}
+
 	// Try using the 'where-am-i' command if available (part of geoclue-2.0-demos)
 	location, err := getLocationFromWhereAmI()
 	if err == nil {
@@ -77,6 +127,7 @@ func getLocationFromWhereAmI() (*l8myfamily.Location, error) {
 func parseWhereAmIOutput(output string) (*l8myfamily.Location, error) {
 	latRegex := regexp.MustCompile(`Latitude:\s+([+-]?\d+\.?\d*)`)
 	lonRegex := regexp.MustCompile(`Longitude:\s+([+-]?\d+\.?\d*)`)
+	accRegex := regexp.MustCompile(`Accuracy:\s+(\d+\.?\d*)`)
 
 	latMatch := latRegex.FindStringSubmatch(output)
 	lonMatch := lonRegex.FindStringSubmatch(output)
@@ -95,18 +146,39 @@ func parseWhereAmIOutput(output string) (*l8myfamily.Location, error) {
 		return nil, fmt.Errorf("failed to parse longitude: %w", err)
 	}
 
-	return &l8myfamily.Location{
+	location := &l8myfamily.Location{
 		Latitude:  float32(lat),
 		Longitude: float32(lon),
-	}, nil
+		Source:    l8myfamily.LocationSource_LOCATION_SOURCE_GEOCLUE,
+	}
+	// Accuracy is optional in the output, leave it unset when missing
+	if accMatch := accRegex.FindStringSubmatch(output); accMatch != nil {
+		if acc, err := strconv.ParseFloat(accMatch[1], 64); err == nil {
+			location.Accuracy = float32(acc)
+		}
+	}
+	return location, nil
 }
 
 // getLocationFromGDBus gets location directly via D-Bus using gdbus command
 func getLocationFromGDBus() (*l8myfamily.Location, error) {
+	clientPath, err := newGeoClueClient()
+	if err != nil {
+		return nil, err
+	}
+	defer stopGeoClueClient(clientPath)
+
+	// Step 5: Wait for location and get it
+	return waitForGeoClueLocation(clientPath)
+}
+
+// newGeoClueClient creates, authorizes and starts a GeoClue client, the
+// caller stops it
+func newGeoClueClient() (string, error) {
 	// Step 1: Get a client from the GeoClue Manager
 	clientPath, err := getGeoClueClient()
 	if err != nil {
-		return nil, fmt.Errorf("failed to get GeoClue client: %w", err)
+		return "", fmt.Errorf("failed to get GeoClue client: %w", err)
 	}
 
 	// Step 2: Set the desktop ID (required for authorization)
@@ -128,24 +200,21 @@ func getLocationFromGDBus() (*l8myfamily.Location, error) {
 		}
 	}
 	if desktopErr != nil {
-		return nil, fmt.Errorf("failed to set desktop ID (tried %d options): %w", len(desktopIDs), desktopErr)
+		return "", fmt.Errorf("failed to set desktop ID (tried %d options): %w", len(desktopIDs), desktopErr)
 	}
 
-	// Step 3: Set the requested accuracy level (EXACT = 8)
-	err = setGeoClueAccuracyLevel(clientPath, 8)
+	// Step 3: Set the requested accuracy level
+	err = setGeoClueAccuracyLevel(clientPath, geoClueRequestedLevel)
 	if err != nil {
-		return nil, fmt.Errorf("failed to set accuracy level: %w", err)
+		return "", fmt.Errorf("failed to set accuracy level: %w", err)
 	}
 
 	// Step 4: Start the client
 	err = startGeoClueClient(clientPath)
 	if err != nil {
-		return nil, fmt.Errorf("failed to start GeoClue client: %w", err)
+		return "", fmt.Errorf("failed to start GeoClue client: %w", err)
 	}
-	defer stopGeoClueClient(clientPath)
-
-	// Step 5: Wait for location and get it
-	return waitForGeoClueLocation(clientPath)
+	return clientPath, nil
 }
 
 // getGeoClueClient requests a new client from GeoClue Manager
@@ -285,10 +354,16 @@ func getGeoClueLocationData(locationPath string) (*l8myfamily.Location, error) {
 		return nil, fmt.Errorf("failed to get longitude: %w", err)
 	}
 
-	return &l8myfamily.Location{
+	location := &l8myfamily.Location{
 		Latitude:  float32(lat),
 		Longitude: float32(lon),
-	}, nil
+		Source:    l8myfamily.LocationSource_LOCATION_SOURCE_GEOCLUE,
+	}
+	// Accuracy is the radius in meters, it is optional so ignore failures
+	if acc, err := getGeoClueProperty(locationPath, "Accuracy"); err == nil {
+		location.Accuracy = float32(acc)
+	}
+	return location, nil
 }
 
 // getGeoClueProperty gets a double property from a GeoClue Location object
@@ -338,5 +413,7 @@ func parseGeoClueJSON(data string) (*l8myfamily.Location, error) {
 	return &l8myfamily.Location{
 		Latitude:  float32(loc.Latitude),
 		Longitude: float32(loc.Longitude),
+		Accuracy:  float32(loc.Accuracy),
+		Source:    l8myfamily.LocationSource_LOCATION_SOURCE_GEOCLUE,
 	}, nil
 }
@@ -0,0 +1,60 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"testing"
+
+	"github.com/saichler/l8myfamiliy/go/types/l8myfamily"
+)
+
+func TestGeoClueAchievedLevel(t *testing.T) {
+	for accuracy, want := range map[float64]int{
+		0:      geoClueLevelExact,
+		25:     geoClueLevelExact,
+		1000:   geoClueLevelExact,
+		8000:   geoClueLevelCity,
+		15000:  geoClueLevelCity,
+		300000: geoClueLevelCountry,
+	} {
+		if got := geoClueAchievedLevel(accuracy); got != want {
+			t.Errorf("geoClueAchievedLevel(%v) = %d, want %d", accuracy, got, want)
+		}
+	}
+}
+
+func TestGeoClueFixCoarse(t *testing.T) {
+	defer func() { skipCoarse = false }()
+
+	location, err := geoClueFix(&l8myfamily.Location{Latitude: 1, Longitude: 2, Accuracy: 30})
+	if err != nil || location.Coarse {
+		t.Fatalf("a precise fix must not be coarse, got %+v %v", location, err)
+	}
+
+	// Precise access denied, GeoClue falls back to a city level fix
+	location, err = geoClueFix(&l8myfamily.Location{Latitude: 1, Longitude: 2, Accuracy: 12000})
+	if err != nil || !location.Coarse {
+		t.Fatalf("a city level fix must be flagged coarse, got %+v %v", location, err)
+	}
+
+	skipCoarse = true
+	if location, err = geoClueFix(&l8myfamily.Location{Latitude: 1, Longitude: 2, Accuracy: 12000}); err == nil {
+		t.Fatalf("a coarse fix must be skipped, got %+v", location)
+	}
+	if location, err = geoClueFix(&l8myfamily.Location{Latitude: 1, Longitude: 2, Accuracy: 30}); err != nil {
+		t.Fatalf("a precise fix must be posted, got %v", err)
+	}
+}
@@ -0,0 +1,155 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/saichler/l8myfamiliy/go/types/l8myfamily"
+	"google.golang.org/protobuf/proto"
+)
+
+// The geoclue_mode values
+const (
+	// geoClueOneShot creates, starts and stops a GeoClue client every cycle
+	geoClueOneShot = "oneshot"
+	// geoCluePersistent keeps one started client for the agent lifetime, so
+	// GeoClue does not have to find a fix from scratch every cycle
+	geoCluePersistent = "persistent"
+)
+
+// geoClueSession is the long lived GeoClue client of the persistent mode.
+// The client is created on the first cycle and recreated only after an
+// error, the Location object is read again only when GeoClue replaced it.
+type geoClueSession struct {
+	mu           sync.Mutex
+	clientPath   string
+	locationPath string
+	last         *l8myfamily.Location
+
+	// The D-Bus calls, swapped by the tests
+	newClient    func() (string, error)
+	pathOf       func(clientPath string) (string, error)
+	readLocation func(locationPath string) (*l8myfamily.Location, error)
+	stopClient   func(clientPath string)
+	pollDelay    time.Duration
+	pollAttempts int
+}
+
+func newGeoClueSession() *geoClueSession {
+	return &geoClueSession{
+		newClient:    newGeoClueClient,
+		pathOf:       getGeoClueLocationPath,
+		readLocation: getGeoClueLocationData,
+		stopClient:   stopGeoClueClient,
+		pollDelay:    500 * time.Millisecond,
+		pollAttempts: 30,
+	}
+}
+
+// active reports whether a started client is alive
+func (s *geoClueSession) active() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.clientPath != ""
+}
+
+// location returns the latest fix of the client, creating the client when
+// there is none. A copy is returned, the caller adjusts it before posting.
+func (s *geoClueSession) location() (*l8myfamily.Location, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	created := false
+	if s.clientPath == "" {
+		clientPath, err := s.newClient()
+		if err != nil {
+			return nil, err
+		}
+		s.clientPath = clientPath
+		created = true
+	}
+
+	locationPath, err := s.currentPath(created)
+	if err != nil {
+		s.reset()
+		return nil, err
+	}
+	if locationPath == "" {
+		// The client is fine, GeoClue has no fix yet
+		return nil, fmt.Errorf("no GeoClue location yet")
+	}
+	if locationPath != s.locationPath || s.last == nil {
+		location, err := s.readLocation(locationPath)
+		if err != nil {
+			s.reset()
+			return nil, err
+		}
+		s.locationPath = locationPath
+		s.last = location
+	}
+	return proto.Clone(s.last).(*l8myfamily.Location), nil
+}
+
+// currentPath returns the Location object path of the client, a new client
+// is given the same time to find a fix as in the one-shot mode
+func (s *geoClueSession) currentPath(wait bool) (string, error) {
+	attempts := 1
+	if wait {
+		attempts = s.pollAttempts
+	}
+	var lastErr error
+	for i := 0; i < attempts; i++ {
+		if wait {
+			time.Sleep(s.pollDelay)
+		}
+		locationPath, err := s.pathOf(s.clientPath)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if locationPath != "" && locationPath != "/" {
+			return locationPath, nil
+		}
+		lastErr = nil
+	}
+	if lastErr != nil {
+		return "", fmt.Errorf("failed to get GeoClue location path: %w", lastErr)
+	}
+	if wait {
+		return "", fmt.Errorf("timeout waiting for GeoClue location")
+	}
+	return "", nil
+}
+
+// reset stops the client so the next cycle creates a new one
+func (s *geoClueSession) reset() {
+	if s.clientPath != "" {
+		s.stopClient(s.clientPath)
+	}
+	s.clientPath = ""
+	s.locationPath = ""
+	s.last = nil
+}
+
+// close stops the client, it is called on shutdown
+func (s *geoClueSession) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reset()
+}
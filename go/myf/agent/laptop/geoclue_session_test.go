@@ -0,0 +1,133 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/saichler/l8myfamiliy/go/types/l8myfamily"
+)
+
+// fakeGeoClue counts the D-Bus calls of a session
+type fakeGeoClue struct {
+	clients  int
+	reads    int
+	stopped  []string
+	path     string
+	pathErr  error
+	readErr  error
+	latitude float32
+}
+
+func (f *fakeGeoClue) session() *geoClueSession {
+	return &geoClueSession{
+		newClient: func() (string, error) {
+			f.clients++
+			return "/org/freedesktop/GeoClue2/Client/1", nil
+		},
+		pathOf: func(string) (string, error) {
+			return f.path, f.pathErr
+		},
+		readLocation: func(string) (*l8myfamily.Location, error) {
+			f.reads++
+			if f.readErr != nil {
+				return nil, f.readErr
+			}
+			return &l8myfamily.Location{Latitude: f.latitude, Longitude: 2}, nil
+		},
+		stopClient: func(clientPath string) {
+			f.stopped = append(f.stopped, clientPath)
+		},
+		pollAttempts: 3,
+	}
+}
+
+func TestGeoClueSessionReusesClient(t *testing.T) {
+	f := &fakeGeoClue{path: "/org/freedesktop/GeoClue2/Client/1/Location/1", latitude: 1}
+	s := f.session()
+
+	first, err := s.location()
+	if err != nil || first.Latitude != 1 {
+		t.Fatalf("location = %v, %v", first, err)
+	}
+	// The caller adjusts the fix, the cached one must not change
+	first.Latitude = 99
+	second, err := s.location()
+	if err != nil || second.Latitude != 1 {
+		t.Fatalf("cached location = %v, %v", second, err)
+	}
+	if f.clients != 1 || f.reads != 1 {
+		t.Fatalf("clients = %d, reads = %d, want 1 and 1", f.clients, f.reads)
+	}
+
+	// GeoClue publishes a new Location object when the position changes
+	f.path = "/org/freedesktop/GeoClue2/Client/1/Location/2"
+	f.latitude = 3
+	third, err := s.location()
+	if err != nil || third.Latitude != 3 || f.reads != 2 || f.clients != 1 {
+		t.Fatalf("location = %v, %v, reads = %d, clients = %d", third, err, f.reads, f.clients)
+	}
+}
+
+func TestGeoClueSessionRecreatesOnError(t *testing.T) {
+	f := &fakeGeoClue{path: "/org/freedesktop/GeoClue2/Client/1/Location/1", latitude: 1}
+	s := f.session()
+	if _, err := s.location(); err != nil {
+		t.Fatal(err)
+	}
+
+	f.pathErr = errors.New("client vanished")
+	if _, err := s.location(); err == nil {
+		t.Fatal("expected an error")
+	}
+	if s.active() || len(f.stopped) != 1 {
+		t.Fatalf("active = %v, stopped = %v, want the client stopped", s.active(), f.stopped)
+	}
+
+	f.pathErr = nil
+	if _, err := s.location(); err != nil {
+		t.Fatal(err)
+	}
+	if f.clients != 2 {
+		t.Fatalf("clients = %d, want a new client after the error", f.clients)
+	}
+}
+
+func TestGeoClueSessionNoFixKeepsClient(t *testing.T) {
+	f := &fakeGeoClue{path: "/", latitude: 1}
+	s := f.session()
+
+	// A new client that never gets a fix times out and is stopped
+	if _, err := s.location(); err == nil || s.active() {
+		t.Fatalf("err = %v, active = %v, want a timeout", err, s.active())
+	}
+
+	f.path = "/org/freedesktop/GeoClue2/Client/1/Location/1"
+	if _, err := s.location(); err != nil {
+		t.Fatal(err)
+	}
+	// A started client that loses its fix is kept
+	f.path = "/"
+	if _, err := s.location(); err == nil || !s.active() {
+		t.Fatalf("err = %v, active = %v, want the client kept", err, s.active())
+	}
+
+	s.close()
+	if s.active() || len(f.stopped) != 2 {
+		t.Fatalf("active = %v, stopped = %v after close", s.active(), f.stopped)
+	}
+}
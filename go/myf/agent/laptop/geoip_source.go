@@ -0,0 +1,122 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"time"
+)
+
+// The geoip_source values, how the IP the GeoIP fix is looked up for is found
+const (
+	// geoIPSourceExternal geolocates the public IP of the default egress,
+	// ip-api.com sees it on the request
+	geoIPSourceExternal = "external"
+	// geoIPSourceInterface sends the request out of geoip_interface, e.g.
+	// the local interface when a split tunnel VPN is the default egress
+	geoIPSourceInterface = "interface"
+	// geoIPSourceIP geolocates the geoip_address
+	geoIPSourceIP = "ip"
+)
+
+var (
+	geoIPSource    = geoIPSourceExternal
+	geoIPInterface = ""
+	geoIPAddress   = ""
+)
+
+const geoIPTimeout = 10 * time.Second
+
+// applyGeoIPSource sets the GeoIP source of the config, an unknown source or
+// an invalid one keeps the external lookup
+func applyGeoIPSource(source, iface, address string) {
+	geoIPSource, geoIPInterface, geoIPAddress = geoIPSourceExternal, iface, address
+	switch source {
+	case "", geoIPSourceExternal:
+	case geoIPSourceInterface:
+		if iface == "" {
+			log.Printf("geoip_source %s needs a geoip_interface, using %s", source, geoIPSourceExternal)
+			return
+		}
+		geoIPSource = source
+	case geoIPSourceIP:
+		if err := validGeoIPAddress(address); err != nil {
+			log.Printf("%v, using %s", err, geoIPSourceExternal)
+			return
+		}
+		geoIPSource = source
+	default:
+		log.Printf("Unknown geoip_source %q, using %s", source, geoIPSourceExternal)
+	}
+}
+
+// validGeoIPAddress returns an error unless the address is a public IP, a
+// private or loopback one has no location
+func validGeoIPAddress(address string) error {
+	ip := net.ParseIP(address)
+	if ip == nil {
+		return fmt.Errorf("invalid geoip_address %q", address)
+	}
+	if !ip.IsGlobalUnicast() || ip.IsPrivate() {
+		return fmt.Errorf("geoip_address %s is not a public address", address)
+	}
+	return nil
+}
+
+// geoIPLookup returns the URL of the GeoIP request and the client it is sent
+// with. An interface that is missing, down or without an IPv4 address falls
+// back to the default egress.
+func geoIPLookup() (string, *http.Client) {
+	client := &http.Client{Timeout: geoIPTimeout}
+	switch geoIPSource {
+	case geoIPSourceIP:
+		return geoIPURL + geoIPAddress, client
+	case geoIPSourceInterface:
+		addr, err := interfaceAddr(geoIPInterface)
+		if err != nil {
+			log.Printf("GeoIP %v, using the default egress", err)
+			return geoIPURL, client
+		}
+		// Bound to the interface address the request leaves through it
+		dialer := &net.Dialer{Timeout: geoIPTimeout, LocalAddr: &net.TCPAddr{IP: addr}}
+		client.Transport = &http.Transport{Proxy: http.ProxyFromEnvironment, DialContext: dialer.DialContext}
+	}
+	return geoIPURL, client
+}
+
+// interfaceAddr returns the first IPv4 address of the interface
+func interfaceAddr(name string) (net.IP, error) {
+	iface, err := net.InterfaceByName(name)
+	if err != nil {
+		return nil, fmt.Errorf("interface %s not found", name)
+	}
+	if iface.Flags&net.FlagUp == 0 {
+		return nil, fmt.Errorf("interface %s is down", name)
+	}
+	addrs, err := iface.Addrs()
+	if err != nil {
+		return nil, fmt.Errorf("interface %s addresses: %w", name, err)
+	}
+	for _, addr := range addrs {
+		if ipNet, ok := addr.(*net.IPNet); ok && ipNet.IP.To4() != nil {
+			return ipNet.IP.To4(), nil
+		}
+	}
+	return nil, fmt.Errorf("interface %s has no IPv4 address", name)
+}
@@ -0,0 +1,83 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestGeoIPSource(t *testing.T) {
+	var remote, path string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		remote, path = r.RemoteAddr, r.URL.Path
+		w.Write([]byte(`{"status":"success","lat":48.1,"lon":11.6,"query":"203.0.113.7"}`))
+	}))
+	defer srv.Close()
+	defer func(u string) { geoIPURL = u; applyGeoIPSource("", "", "") }(geoIPURL)
+	geoIPURL = srv.URL + "/json/"
+
+	// An explicit address is geolocated instead of the caller
+	applyGeoIPSource(geoIPSourceIP, "", "203.0.113.7")
+	if _, err := getLocationFromGeoIP(); err != nil || path != "/json/203.0.113.7" {
+		t.Fatalf("expected the address looked up, got %s %v", path, err)
+	}
+
+	// The request leaves through the interface address
+	loopback := ""
+	ifaces, _ := net.Interfaces()
+	for _, iface := range ifaces {
+		if iface.Flags&net.FlagLoopback != 0 && iface.Flags&net.FlagUp != 0 {
+			loopback = iface.Name
+		}
+	}
+	if loopback == "" {
+		t.Skip("no loopback interface")
+	}
+	applyGeoIPSource(geoIPSourceInterface, loopback, "")
+	if _, err := getLocationFromGeoIP(); err != nil || path != "/json/" || remote[:len("127.0.0.1:")] != "127.0.0.1:" {
+		t.Fatalf("expected the lookup from the interface, got %s %s %v", path, remote, err)
+	}
+	// A missing interface falls back to the default egress
+	applyGeoIPSource(geoIPSourceInterface, "no-such-if0", "")
+	if _, err := getLocationFromGeoIP(); err != nil || path != "/json/" {
+		t.Fatalf("a missing interface must fall back, got %s %v", path, err)
+	}
+}
+
+func TestApplyGeoIPSourceValidates(t *testing.T) {
+	defer applyGeoIPSource("", "", "")
+	for _, test := range []struct {
+		source, iface, address, want string
+	}{
+		{"", "", "", geoIPSourceExternal},
+		{geoIPSourceIP, "", "2001:4860:4860::8888", geoIPSourceIP},
+		{geoIPSourceIP, "", "8.8.8.8", geoIPSourceIP},
+		{geoIPSourceIP, "", "192.168.1.10", geoIPSourceExternal},
+		{geoIPSourceIP, "", "127.0.0.1", geoIPSourceExternal},
+		{geoIPSourceIP, "", "not-an-ip", geoIPSourceExternal},
+		{geoIPSourceInterface, "", "", geoIPSourceExternal},
+		{geoIPSourceInterface, "eth0", "", geoIPSourceInterface},
+		{"stun", "", "", geoIPSourceExternal},
+	} {
+		applyGeoIPSource(test.source, test.iface, test.address)
+		if geoIPSource != test.want {
+			t.Fatalf("%s %s %s expected %s, got %s", test.source, test.iface, test.address, test.want, geoIPSource)
+		}
+	}
+}
@@ -18,17 +18,14 @@ package main
 import (
 	"bufio"
 	"bytes"
-	"crypto/aes"
-	"crypto/cipher"
-	"crypto/rand"
-	"crypto/sha256"
-	"crypto/tls"
-	"encoding/base64"
 	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
+	"net/url"
 	"os"
 	"os/signal"
 	"path/filepath"
@@ -37,32 +34,157 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/saichler/l8myfamiliy/go/myf/agent/agentcore"
+	"github.com/saichler/l8myfamiliy/go/myf/webapi"
 	"github.com/saichler/l8myfamiliy/go/types/l8myfamily"
 	"golang.org/x/term"
 )
 
 const defaultEndpoint = "https://www.probler.dev:9092"
 
+// defaultServiceArea is the service area of the family and location services
+const defaultServiceArea = 53
+
 var (
 	deviceID      = ""
+	alias         = ""
 	deviceName    = ""
 	website       = ""
 	user          = ""
 	pass          = ""
-	bearerToken   = ""
 	configFile    = ""
 	skipTLSVerify = false
+	serviceArea   = defaultServiceArea
+	useProtobuf   = false
+	compression   = true
+
+	intervalChargingSeconds   = defaultIntervalChargingSeconds
+	intervalBatterySeconds    = defaultIntervalBatterySeconds
+	intervalLowBatterySeconds = defaultIntervalLowBatterySeconds
+	lowBatteryPercent         = defaultLowBatteryPercent
+
+	geofences   []agentcore.Geofence
+	trustedBeat = 0
+	trustedZone = agentcore.NewTrustedZone(nil, 0)
+
+	geoIPURL      = "http://ip-api.com/json/"
+	geoIPLanguage = ""
+	geoIPProxy    = geoIPProxyMark
+	// geoIPDisabled removes the IP based fallback, ip-api.com is never asked
+	geoIPDisabled = false
+
+	serverClock = agentcore.NewClock()
+
+	// mirrorWebsites are servers every location is also posted to
+	mirrorWebsites []string
+	maxBufferedAge = defaultMaxBufferedAge
+	servers        []*server
+
+	transportKind = transportHTTP
+	natsURL       = ""
+	natsSubject   = defaultNatsSubject
+
+	nmeaPort = ""
+	nmeaBaud = defaultNmeaBaud
+
+	moveMeters    = 0.0
+	heartbeatSecs = 0
+	movement      = agentcore.NewMovementFilter(0, 0)
+
+	authScheme = agentcore.AuthScheme{Kind: agentcore.AuthBearer}
+	authPaths  = agentcore.DefaultAuthPaths()
+
+	healthProbe        = false
+	healthProbeTimeout = defaultHealthProbeTimeout
+
+	geoClueMode = geoClueOneShot
+	skipCoarse  = false
+	telemetry   = false
+	startJitter = agentcore.StartJitter(nil)
+	geoClue     = newGeoClueSession()
+
+	// startupRetry retries the first connection while no server is reachable
+	startupRetry = agentcore.NewStartupRetry(nil)
+
+	// heartbeatOnly posts the presence of the device, never a position
+	heartbeatOnly = false
+
+	// lockedHeartbeatSecs is the heartbeat interval while the session is
+	// locked, 0 posts as usual. sessionLock is its watcher, nil when off.
+	lockedHeartbeatSecs = 0
+	sessionLock         *lockWatcher
+
+	// reauthCooldown is the seconds between the logins that are not
+	// followed by an accepted post, 0 is the default
+	reauthCooldown = 0
 )
 
+// Config is the persistent configuration, the settings shared with the
+// Android agent are in the embedded agentcore.Config
 type Config struct {
-	DeviceID      string `json:"device_id"`
-	DeviceName    string `json:"device_name,omitempty"`
-	Website       string `json:"website,omitempty"`
-	EncryptedUser string `json:"encrypted_user,omitempty"`
-	EncryptedPass string `json:"encrypted_pass,omitempty"`
-	SkipTLSVerify *bool  `json:"skip_tls_verify,omitempty"`
+	agentcore.Config
+	// The post interval depends on the power state, on battery it is longer
+	// and below LowBatteryPercent longer still
+	IntervalChargingSeconds   int `json:"interval_charging_seconds,omitempty"`
+	IntervalBatterySeconds    int `json:"interval_battery_seconds,omitempty"`
+	IntervalLowBatterySeconds int `json:"interval_low_battery_seconds,omitempty"`
+	LowBatteryPercent         int `json:"low_battery_percent,omitempty"`
+	// GeoIPLanguage is the language of the place names of IP based fixes,
+	// one of the ip-api.com languages (en, de, es, pt-BR, fr, ja, zh-CN, ru)
+	GeoIPLanguage string `json:"geoip_language,omitempty"`
+	// GeoIPProxyPolicy is what to do with an IP based fix when the public ip
+	// is a VPN, proxy or hosting provider, see the geoIPProxy constants
+	GeoIPProxyPolicy string `json:"geoip_proxy_policy,omitempty"`
+	// GeoIPSource is how the IP of an IP based fix is found, see the
+	// geoIPSource constants, with GeoIPInterface or GeoIPAddress
+	GeoIPSource    string `json:"geoip_source,omitempty"`
+	GeoIPInterface string `json:"geoip_interface,omitempty"`
+	GeoIPAddress   string `json:"geoip_address,omitempty"`
+	// DisableGeoIP never asks ip-api.com for a fix, when GeoClue fails the
+	// cycle posts nothing
+	DisableGeoIP bool `json:"disable_geoip,omitempty"`
+	// MirrorWebsites are backup servers, every location is posted to the
+	// website and to each mirror with the same credentials
+	MirrorWebsites []string `json:"mirror_websites,omitempty"`
+	// MaxBufferedAgeSeconds drops the buffered locations older than this
+	// when a server is back
+	MaxBufferedAgeSeconds int `json:"max_buffered_age_seconds,omitempty"`
+	// NmeaPort is the serial port of a GPS receiver, e.g. /dev/ttyUSB0, it
+	// is read before GeoClue when set
+	NmeaPort string `json:"nmea_port,omitempty"`
+	NmeaBaud int    `json:"nmea_baud,omitempty"`
+	// HealthProbe checks a server is reachable before the location is
+	// collected, HealthProbeTimeoutMillis bounds the check
+	HealthProbe              bool `json:"health_probe,omitempty"`
+	HealthProbeTimeoutMillis int  `json:"health_probe_timeout_millis,omitempty"`
+	// GeoClueMode is oneshot (a client per cycle) or persistent (one client
+	// kept started across cycles), see the geoClue constants
+	GeoClueMode string `json:"geoclue_mode,omitempty"`
+	// SkipCoarse posts only precise fixes, a coarse GeoClue fix and the IP
+	// based fallback are not posted
+	SkipCoarse bool `json:"skip_coarse,omitempty"`
+	// Transport is http (the default) or nats, see the transport constants.
+	// With nats the locations are published to NatsSubject of the
+	// nats://[user:pass@]host[:port] server at NatsURL instead.
+	Transport   string `json:"transport,omitempty"`
+	NatsURL     string `json:"nats_url,omitempty"`
+	NatsSubject string `json:"nats_subject,omitempty"`
+	// LockedHeartbeatSeconds posts a heartbeat this often instead of the
+	// positions while the desktop session is locked, 0 disables it
+	LockedHeartbeatSeconds int `json:"locked_heartbeat_seconds,omitempty"`
 }
 
+// The geoip_proxy_policy values, the location of a VPN or proxy ip is the
+// exit server, often far from the device
+const (
+	// geoIPProxyMark posts the fix flagged unreliable, the map de-emphasizes it
+	geoIPProxyMark = "mark"
+	// geoIPProxySkip does not post the fix
+	geoIPProxySkip = "skip"
+	// geoIPProxyPost posts the fix like any other
+	geoIPProxyPost = "post"
+)
+
 // AuthResponse represents the response from the /auth endpoint
 type AuthResponse struct {
 	Token    string `json:"token"`
@@ -91,62 +213,10 @@ func init() {
 	configFile = filepath.Join(configDir, "l8myfamily", "laptop-agent.json")
 }
 
-func getEncryptionKey() []byte {
-	h := sha256.New()
-	h.Write([]byte(deviceID))
-	h.Write([]byte("l8myfamily-laptop-agent"))
-	return h.Sum(nil)
-}
-
-func encrypt(plaintext string) (string, error) {
-	key := getEncryptionKey()
-	block, err := aes.NewCipher(key)
-	if err != nil {
-		return "", err
-	}
-
-	gcm, err := cipher.NewGCM(block)
-	if err != nil {
-		return "", err
-	}
-
-	nonce := make([]byte, gcm.NonceSize())
-	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
-		return "", err
-	}
-
-	ciphertext := gcm.Seal(nonce, nonce, []byte(plaintext), nil)
-	return base64.StdEncoding.EncodeToString(ciphertext), nil
-}
-
-func decrypt(encoded string) (string, error) {
-	key := getEncryptionKey()
-	ciphertext, err := base64.StdEncoding.DecodeString(encoded)
-	if err != nil {
-		return "", err
-	}
-
-	block, err := aes.NewCipher(key)
-	if err != nil {
-		return "", err
-	}
-
-	gcm, err := cipher.NewGCM(block)
-	if err != nil {
-		return "", err
-	}
-
-	if len(ciphertext) < gcm.NonceSize() {
-		return "", fmt.Errorf("ciphertext too short")
-	}
-
-	nonce, ciphertext := ciphertext[:gcm.NonceSize()], ciphertext[gcm.NonceSize():]
-	plaintext, err := gcm.Open(nil, nonce, ciphertext, nil)
-	if err != nil {
-		return "", err
-	}
-
-	return string(plaintext), nil
+// getCipher returns the cipher of the stored credentials, its key depends on
+// the device id
+func getCipher() *agentcore.Cipher {
+	return agentcore.NewCipher(deviceID, agentcore.Salt)
 }
 
 func promptForInput(prompt string) string {
@@ -173,30 +243,37 @@ func promptForYesNo(prompt string) bool {
 	return input == "y" || input == "yes"
 }
 
+// httpClients reuses one HTTP/2 capable client for every request to the
+// servers
+var httpClients = &agentcore.HTTPClients{}
+
+// getHTTPClient returns the client of the current TLS and compression
+// settings
 func getHTTPClient() *http.Client {
-	if skipTLSVerify {
-		return &http.Client{
-			Timeout: 10 * time.Second,
-			Transport: &http.Transport{
-				TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
-			},
-		}
-	}
-	return &http.Client{Timeout: 10 * time.Second}
+	return httpClients.Get(skipTLSVerify, compression)
 }
 
-func loadOrCreateConfig() error {
-	data, err := os.ReadFile(configFile)
+// compressBody gzips a request body above the gzip threshold when compression
+// is enabled, it returns the body and its Content-Encoding
+func compressBody(data []byte) ([]byte, string) {
+	if !compression || len(data) < webapi.GzipThreshold {
+		return data, ""
+	}
+	compressed, err := webapi.GzipBytes(data)
 	if err != nil {
-		if os.IsNotExist(err) {
-			return createNewConfig()
-		}
-		return fmt.Errorf("failed to read config: %w", err)
+		return data, ""
 	}
+	return compressed, "gzip"
+}
 
+func loadOrCreateConfig() error {
 	var cfg Config
-	if err := json.Unmarshal(data, &cfg); err != nil {
-		return fmt.Errorf("failed to parse config: %w", err)
+	exists, err := agentcore.LoadConfig(configFile, &cfg)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return createNewConfig()
 	}
 
 	if cfg.DeviceID == "" {
@@ -207,25 +284,18 @@ func loadOrCreateConfig() error {
 		deviceID = cfg.DeviceID
 		deviceName = cfg.DeviceName
 	}
+	alias = cfg.Alias
 
-	website = cfg.Website
-	if cfg.SkipTLSVerify != nil {
-		skipTLSVerify = *cfg.SkipTLSVerify
-	}
-	if cfg.EncryptedUser != "" {
-		decrypted, err := decrypt(cfg.EncryptedUser)
-		if err == nil {
-			user = decrypted
-		}
-	}
-	if cfg.EncryptedPass != "" {
-		decrypted, err := decrypt(cfg.EncryptedPass)
-		if err == nil {
-			pass = decrypted
+	migrated := applyConfig(&cfg)
+
+	needsSave := migrated
+	// A NATS subject takes the locations without the family server
+	if transportKind == transportNATS {
+		if needsSave {
+			return saveConfig()
 		}
+		return nil
 	}
-
-	needsSave := false
 	if website == "" {
 		website = promptForInput("Enter website URL [" + defaultEndpoint + "]: ")
 		if website == "" {
@@ -253,7 +323,105 @@ func loadOrCreateConfig() error {
 	return nil
 }
 
+// applyConfig sets the settings of a loaded config, it returns true when the
+// config is of an earlier version and must be saved again
+func applyConfig(cfg *Config) bool {
+	// A config of an earlier version is read with its legacy key and saved
+	// with the unified one
+	c, migrated := cfg.Migrate(agentcore.SaltLaptop)
+	if migrated {
+		log.Printf("Migrating config to version %d", agentcore.ConfigVersion)
+	}
+	website = cfg.Website
+	if cfg.ServiceArea != 0 {
+		serviceArea = cfg.ServiceArea
+	}
+	useProtobuf = cfg.UseProtobuf
+	compression = !cfg.DisableCompression
+	if cfg.IntervalChargingSeconds > 0 {
+		intervalChargingSeconds = cfg.IntervalChargingSeconds
+	}
+	if cfg.IntervalBatterySeconds > 0 {
+		intervalBatterySeconds = cfg.IntervalBatterySeconds
+	}
+	if cfg.IntervalLowBatterySeconds > 0 {
+		intervalLowBatterySeconds = cfg.IntervalLowBatterySeconds
+	}
+	if cfg.LowBatteryPercent > 0 {
+		lowBatteryPercent = cfg.LowBatteryPercent
+	}
+	if cfg.SkipTLSVerify != nil {
+		skipTLSVerify = *cfg.SkipTLSVerify
+	}
+	geofences = cfg.Geofences
+	trustedBeat = cfg.TrustedHeartbeatSeconds
+	trustedZone = agentcore.NewTrustedZone(geofences, trustedBeat)
+	geoIPLanguage = cfg.GeoIPLanguage
+	geoIPDisabled = cfg.DisableGeoIP
+	mirrorWebsites = cfg.MirrorWebsites
+	if cfg.MaxBufferedAgeSeconds > 0 {
+		maxBufferedAge = time.Duration(cfg.MaxBufferedAgeSeconds) * time.Second
+	}
+	moveMeters = cfg.PostOnMoveMeters
+	heartbeatSecs = cfg.HeartbeatSeconds
+	movement = agentcore.NewMovementFilter(moveMeters, heartbeatSecs)
+	scheme, err := agentcore.NewAuthScheme(cfg.AuthScheme, cfg.AuthName)
+	if err != nil {
+		log.Printf("%v, using bearer", err)
+	}
+	authScheme = scheme
+	paths, err := agentcore.NewAuthPaths(cfg.AuthPath, cfg.TfaVerifyPath)
+	if err != nil {
+		log.Printf("%v, using the default", err)
+	}
+	authPaths = paths
+	nmeaPort = cfg.NmeaPort
+	if cfg.NmeaBaud > 0 {
+		nmeaBaud = cfg.NmeaBaud
+	}
+	healthProbe = cfg.HealthProbe
+	skipCoarse = cfg.SkipCoarse
+	telemetry = cfg.Telemetry
+	heartbeatOnly = cfg.HeartbeatOnly
+	lockedHeartbeatSecs = cfg.LockedHeartbeatSeconds
+	startJitter = agentcore.StartJitter(cfg.StartJitterSeconds)
+	startupRetry.Retries = agentcore.StartupRetries(cfg.StartupRetries)
+	reauthCooldown = cfg.ReauthCooldownSeconds
+	if cfg.HealthProbeTimeoutMillis > 0 {
+		healthProbeTimeout = time.Duration(cfg.HealthProbeTimeoutMillis) * time.Millisecond
+	}
+	applyGeoIPSource(cfg.GeoIPSource, cfg.GeoIPInterface, cfg.GeoIPAddress)
+	switch cfg.GeoIPProxyPolicy {
+	case geoIPProxyMark, geoIPProxySkip, geoIPProxyPost:
+		geoIPProxy = cfg.GeoIPProxyPolicy
+	case "":
+	default:
+		log.Printf("Unknown geoip_proxy_policy %q, using %s", cfg.GeoIPProxyPolicy, geoIPProxy)
+	}
+	switch cfg.Transport {
+	case transportHTTP, transportNATS:
+		transportKind = cfg.Transport
+	case "":
+	default:
+		log.Printf("Unknown transport %q, using %s", cfg.Transport, transportKind)
+	}
+	natsURL = cfg.NatsURL
+	if cfg.NatsSubject != "" {
+		natsSubject = cfg.NatsSubject
+	}
+	switch cfg.GeoClueMode {
+	case geoClueOneShot, geoCluePersistent:
+		geoClueMode = cfg.GeoClueMode
+	case "":
+	default:
+		log.Printf("Unknown geoclue_mode %q, using %s", cfg.GeoClueMode, geoClueMode)
+	}
+	user, pass = cfg.Credentials(c)
+	return migrated
+}
+
 func createNewConfig() error {
+	// A new config has no alias yet, the new id itself is logged
 	deviceID = uuid.New().String()
 	log.Printf("Generated new device ID: %s", deviceID)
 
@@ -270,45 +438,76 @@ func createNewConfig() error {
 	return saveConfig()
 }
 
-func saveConfig() error {
-	encryptedUser, err := encrypt(user)
-	if err != nil {
-		return fmt.Errorf("failed to encrypt user: %w", err)
-	}
-	encryptedPass, err := encrypt(pass)
-	if err != nil {
-		return fmt.Errorf("failed to encrypt pass: %w", err)
-	}
-
-	cfg := Config{
-		DeviceID:      deviceID,
-		DeviceName:    deviceName,
-		Website:       website,
-		EncryptedUser: encryptedUser,
-		EncryptedPass: encryptedPass,
-		SkipTLSVerify: &skipTLSVerify,
-	}
-
-	dir := filepath.Dir(configFile)
-	if err := os.MkdirAll(dir, 0700); err != nil {
-		return fmt.Errorf("failed to create config directory: %w", err)
+// currentConfig returns the config of the current settings, without the
+// credentials
+func currentConfig() *Config {
+	jitterSeconds := int(startJitter / time.Second)
+	startupRetries := startupRetry.Retries
+	return &Config{
+		Config: agentcore.Config{
+			Version:                 agentcore.ConfigVersion,
+			DeviceID:                deviceID,
+			DeviceName:              deviceName,
+			Alias:                   alias,
+			Website:                 website,
+			SkipTLSVerify:           &skipTLSVerify,
+			ServiceArea:             serviceArea,
+			UseProtobuf:             useProtobuf,
+			DisableCompression:      !compression,
+			Geofences:               geofences,
+			TrustedHeartbeatSeconds: trustedBeat,
+			PostOnMoveMeters:        moveMeters,
+			HeartbeatSeconds:        heartbeatSecs,
+			AuthScheme:              authScheme.Kind,
+			AuthName:                authScheme.Name,
+			AuthPath:                authPaths.Auth,
+			TfaVerifyPath:           authPaths.TfaVerify,
+			Telemetry:               telemetry,
+			StartJitterSeconds:      &jitterSeconds,
+			StartupRetries:          &startupRetries,
+			HeartbeatOnly:           heartbeatOnly,
+			ReauthCooldownSeconds:   reauthCooldown,
+		},
+		IntervalChargingSeconds:   intervalChargingSeconds,
+		IntervalBatterySeconds:    intervalBatterySeconds,
+		IntervalLowBatterySeconds: intervalLowBatterySeconds,
+		LowBatteryPercent:         lowBatteryPercent,
+		GeoIPLanguage:             geoIPLanguage,
+		GeoIPProxyPolicy:          geoIPProxy,
+		GeoIPSource:               geoIPSource,
+		GeoIPInterface:            geoIPInterface,
+		GeoIPAddress:              geoIPAddress,
+		DisableGeoIP:              geoIPDisabled,
+		MirrorWebsites:            mirrorWebsites,
+		MaxBufferedAgeSeconds:     int(maxBufferedAge / time.Second),
+		NmeaPort:                  nmeaPort,
+		NmeaBaud:                  nmeaBaud,
+		HealthProbe:               healthProbe,
+		HealthProbeTimeoutMillis:  int(healthProbeTimeout / time.Millisecond),
+		GeoClueMode:               geoClueMode,
+		SkipCoarse:                skipCoarse,
+		Transport:                 transportKind,
+		NatsURL:                   natsURL,
+		NatsSubject:               natsSubject,
+		LockedHeartbeatSeconds:    lockedHeartbeatSecs,
 	}
+}
 
-	data, err := json.MarshalIndent(cfg, "", "  ")
-	if err != nil {
-		return fmt.Errorf("failed to marshal config: %w", err)
+func saveConfig() error {
+	cfg := currentConfig()
+	if err := cfg.SetCredentials(getCipher(), user, pass); err != nil {
+		return err
 	}
-
-	if err := os.WriteFile(configFile, data, 0600); err != nil {
-		return fmt.Errorf("failed to write config: %w", err)
+	if err := agentcore.SaveConfig(configFile, cfg); err != nil {
+		return err
 	}
 
 	log.Printf("Config saved to: %s", configFile)
 	return nil
 }
 
-func authenticate() error {
-	authURL := strings.TrimSuffix(website, "/") + "/auth"
+func authenticate(s *server) error {
+	authURL := authPaths.AuthURL(s.website)
 
 	authReq := map[string]string{
 		"user": user,
@@ -320,11 +519,13 @@ func authenticate() error {
 	}
 
 	client := getHTTPClient()
+	sent := time.Now()
 	resp, err := client.Post(authURL, "application/json", bytes.NewReader(data))
 	if err != nil {
 		return fmt.Errorf("auth request failed: %w", err)
 	}
 	defer resp.Body.Close()
+	observeServerTime(resp.Header, sent)
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
@@ -332,7 +533,11 @@ func authenticate() error {
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("authentication failed: %s", strings.TrimSpace(string(body)))
+		// A server error is not a rejection of the credentials
+		if resp.StatusCode >= http.StatusInternalServerError {
+			return fmt.Errorf("authentication failed: %s", strings.TrimSpace(string(body)))
+		}
+		return fmt.Errorf("%w: %s", errAuthRejected, strings.TrimSpace(string(body)))
 	}
 
 	// Try to parse as JSON first (for TFA detection)
@@ -346,15 +551,17 @@ func authenticate() error {
 		// Check if TFA verification is required
 		if authResp.NeedTfa {
 			log.Printf("Two-factor authentication required")
-			return handleTfaVerification(authResp.Token)
+			return handleTfaVerification(s, authResp.Token)
 		}
 
 		// Normal successful auth with token in JSON
 		if authResp.Token != "" {
-			bearerToken = authResp.Token
-			log.Printf("Authentication successful")
+			s.bearerToken = authResp.Token
+			log.Printf("Authentication to %s successful", s.website)
 			return nil
 		}
+		// A JSON response is not a legacy plain token
+		return fmt.Errorf("authentication failed: no token in response")
 	}
 
 	// Fallback: treat response as plain token string (legacy support)
@@ -363,12 +570,12 @@ func authenticate() error {
 		return fmt.Errorf("authentication failed: empty response")
 	}
 
-	bearerToken = token
-	log.Printf("Authentication successful")
+	s.bearerToken = token
+	log.Printf("Authentication to %s successful", s.website)
 	return nil
 }
 
-func handleTfaVerification(pendingToken string) error {
+func handleTfaVerification(s *server, pendingToken string) error {
 	reader := bufio.NewReader(os.Stdin)
 
 	for attempts := 0; attempts < 3; attempts++ {
@@ -384,7 +591,7 @@ func handleTfaVerification(pendingToken string) error {
 			continue
 		}
 
-		err = verifyTfa(pendingToken, code)
+		err = verifyTfa(s, pendingToken, code)
 		if err == nil {
 			return nil
 		}
@@ -398,8 +605,8 @@ func handleTfaVerification(pendingToken string) error {
 	return fmt.Errorf("TFA verification failed after 3 attempts")
 }
 
-func verifyTfa(pendingToken, code string) error {
-	tfaURL := strings.TrimSuffix(website, "/") + "/tfaVerify"
+func verifyTfa(s *server, pendingToken, code string) error {
+	tfaURL := authPaths.TfaVerifyURL(s.website)
 
 	tfaReq := TfaVerifyRequest{
 		UserID: user,
@@ -437,136 +644,323 @@ func verifyTfa(pendingToken, code string) error {
 	}
 
 	// TFA verification successful
-	bearerToken = pendingToken
+	s.bearerToken = pendingToken
 	log.Printf("TFA verification successful")
 	return nil
 }
 
-func registerDevice() error {
-	deviceEndpoint := strings.TrimSuffix(website, "/") + "/my-family/53/Family"
+// registerDevice registers the device through the raw device endpoint, the
+// service endpoint has no authenticated caller and rejects it
+func registerDevice(s *server) error {
+	deviceEndpoint := agentcore.EndpointURL(s.website, "device")
 
-	deviceReq := map[string]string{
-		"id":       deviceID,
-		"familyId": user,
-		"name":     deviceName,
-	}
-	data, err := json.Marshal(deviceReq)
+	key := uuid.New().String()
+	signingKey := webapi.NewSigningKey()
+	data, err := agentcore.RegistrationBody(deviceID, user, deviceName, key, signingKey, reportInterval(), heartbeatOnly)
 	if err != nil {
 		return fmt.Errorf("failed to marshal device request: %w", err)
 	}
 
-	log.Printf("POST to %s: %s", deviceEndpoint, string(data))
-
-	req, err := http.NewRequest("POST", deviceEndpoint, bytes.NewReader(data))
-	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
-	}
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+bearerToken)
+	// The body and the response hold the signing key, they are not logged
+	log.Printf("POST to %s: device %s (%s)", deviceEndpoint, deviceName, agentcore.DeviceLabel(alias, deviceID))
 
-	client := getHTTPClient()
-	resp, err := client.Do(req)
+	_, err = postWithRetry(s, deviceEndpoint, webapi.ContentTypeJSON, "", data, key)
 	if err != nil {
 		return fmt.Errorf("device registration request failed: %w", err)
 	}
-	defer resp.Body.Close()
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return fmt.Errorf("failed to read response body: %w", err)
-	}
 
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return fmt.Errorf("server returned status %d: %s", resp.StatusCode, string(body))
-	}
-
-	log.Printf("Device registered: %s (%s)", deviceName, deviceID)
-	log.Printf("Response from /my-family/53/Family: %s", string(body))
+	// The posts are signed only once the server stored the key
+	s.signingKey = signingKey
+	log.Printf("Device registered: %s (%s)", deviceName, agentcore.DeviceLabel(alias, deviceID))
 	return nil
 }
 
 func main() {
+	printConfigFlag := flag.Bool("print-config", false, "print the effective config with the credentials redacted and exit")
+	selfTestFlag := flag.Bool("self-test", false, "check the TLS certificates of the servers and exit")
+	flag.Parse()
+	if *printConfigFlag {
+		if err := printConfig(os.Stdout); err != nil {
+			log.Fatalf("Failed to print config: %v", err)
+		}
+		return
+	}
+	if *selfTestFlag {
+		if err := selfTest(os.Stdout, time.Now()); err != nil {
+			log.Fatalf("Self test failed: %v", err)
+		}
+		return
+	}
+
 	if err := loadOrCreateConfig(); err != nil {
 		log.Fatalf("Failed to load config: %v", err)
 	}
+	log.Printf("Agent version %s", agentcore.Version)
 
-	if err := authenticate(); err != nil {
-		log.Fatalf("Failed to authenticate: %v", err)
+	// A fleet started together spreads its logins and first posts
+	if delay := agentcore.RandomDelay(startJitter); delay > 0 {
+		log.Printf("Waiting %s before the first post", delay.Round(time.Millisecond))
+		time.Sleep(delay)
 	}
 
-	if err := registerDevice(); err != nil {
-		log.Fatalf("Failed to register device: %v", err)
+	// A server that is down is connected again before its next post, the
+	// agent starts when at least one server is up. A device that boots
+	// before its network is retried instead of exiting.
+	var err error
+	servers, err = locationServers()
+	if err != nil {
+		log.Fatalf("Failed to configure the transport: %v", err)
+	}
+	err = startupRetry.Run(connectServers, startupRejected, func(wait time.Duration, err error) {
+		log.Printf("%v, retrying in %s", err, wait)
+	})
+	if err != nil {
+		log.Fatalf("%v", err)
 	}
 
-	locationEndpoint := strings.TrimSuffix(website, "/") + "/my-family/53/Location"
-	log.Printf("Starting location agent for device: %s", deviceID)
-	log.Printf("Posting to endpoint: %s", locationEndpoint)
+	log.Printf("Starting location agent for device: %s", agentcore.DeviceLabel(alias, deviceID))
+	for _, s := range servers {
+		log.Printf("Posting to endpoint: %s", s.transport.endpoint())
+	}
 	log.Printf("Using free location services (GeoClue -> IP geolocation fallback)")
 
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+	// SIGUSR1 posts at once, off the regular cycle. The loop runs one
+	// collection at a time, a signal during a post is handled after it and
+	// signals that arrive meanwhile are coalesced.
+	refreshChan := make(chan os.Signal, 1)
+	if notifyRefresh(refreshChan) {
+		log.Printf("Send SIGUSR1 (kill -USR1 %d) to post the location at once", os.Getpid())
+	}
 
-	ticker := time.NewTicker(10 * time.Second)
-	defer ticker.Stop()
+	if lockedHeartbeatSecs > 0 {
+		sessionLock = newLockWatcher(time.Duration(lockedHeartbeatSecs)*time.Second, readSessionLock)
+		sessionLock.start()
+	}
 
 	collectAndPost()
 
+	// The interval is re-evaluated after every post, so plugging in or
+	// unplugging the charger takes effect on the next cycle
+	interval := nextInterval()
+	timer := time.NewTimer(interval)
+	defer timer.Stop()
+
 	for {
 		select {
-		case <-ticker.C:
+		case <-timer.C:
+			collectAndPost()
+			if next := nextInterval(); next != interval {
+				log.Printf("Post interval changed to %s", next)
+				interval = next
+			}
+			timer.Reset(interval)
+		case locked := <-sessionLock.Changes():
+			// The new state is posted at once, with the interval it announces
+			if locked {
+				log.Printf("Session locked, posting a heartbeat every %s", sessionLock.heartbeat)
+			} else {
+				log.Printf("Session unlocked, posting the location")
+			}
+			collectAndPost()
+			interval = nextInterval()
+			timer.Reset(interval)
+		case <-refreshChan:
+			// The timer is left running, the regular interval is kept
+			log.Printf("Refresh requested, posting the location")
 			collectAndPost()
 		case <-sigChan:
 			log.Println("Shutting down location agent...")
+			geoClue.close()
 			return
 		}
 	}
 }
 
+// reportInterval returns the interval announced to the server with the
+// registration and every post, it follows the power state
+func reportInterval() int32 {
+	return agentcore.ReportInterval(nextInterval(), movement, trustedZone)
+}
+
+// nextInterval returns the interval until the next cycle, the locked
+// heartbeat while the session is locked and the post interval otherwise
+func nextInterval() time.Duration {
+	if sessionLock.Locked() {
+		return sessionLock.heartbeat
+	}
+	return postInterval()
+}
+
 func collectAndPost() {
-	location, err := getLocation()
+	// A heartbeat-only device never collects a position
+	if heartbeatOnly {
+		postPresence("the device shares its presence only")
+		return
+	}
+	// A locked laptop is usually stationary, only its presence is posted
+	if sessionLock.Locked() {
+		postPresence("the session is locked")
+		return
+	}
+
+	// Collecting a fix wakes GeoClue or the GPS receiver, there is no point
+	// when no server can take it. The buffered locations wait for the next cycle.
+	if healthProbe && !anyReachable(healthProbeTimeout) {
+		log.Printf("No server is reachable, location collection skipped")
+		return
+	}
+
+	location, source, err := getLocation()
 	if err != nil {
 		log.Printf("Error getting location: %v", err)
 		return
 	}
 
 	location.DeviceId = deviceID
+	location.Battery = batteryPercent()
+	location.ReportIntervalSeconds = reportInterval()
+	if telemetry {
+		location.Telemetry = collectTelemetry()
+	}
+
+	lat, lon, place, post := trustedZone.Filter(float64(location.Latitude), float64(location.Longitude), time.Now())
+	if !post {
+		log.Printf("Inside trusted geofence %s, post suppressed", place)
+		return
+	}
+	if place != "" {
+		// Only the fence is reported, not the precise position
+		location.Latitude, location.Longitude = float32(lat), float32(lon)
+		location.Accuracy = 0
+		location.Place = place
+		location.PublicIp = ""
+		location.Geofence = place
+	}
 
-	err = postLocation(location)
+	switch movement.Decide(float64(location.Latitude), float64(location.Longitude), time.Now()) {
+	case agentcore.DecisionSkip:
+		return
+	case agentcore.DecisionHeartbeat:
+		if err := postAll(&l8myfamily.Location{DeviceId: deviceID, Heartbeat: true, Battery: location.Battery,
+			Telemetry: location.Telemetry, ReportIntervalSeconds: location.ReportIntervalSeconds}); err != nil {
+			log.Printf("Error posting heartbeat: %v", err)
+			return
+		}
+		log.Printf("Posted heartbeat, the position did not change")
+		return
+	}
+
+	err = postAll(location)
 	if err != nil {
 		log.Printf("Error posting location: %v", err)
 		return
 	}
 
-	log.Printf("Posted location: lat=%.6f, lon=%.6f", location.Latitude, location.Longitude)
+	log.Printf("Posted location: lat=%.6f, lon=%.6f, source=%s", location.Latitude, location.Longitude, source)
 }
 
-func getLocation() (*l8myfamily.Location, error) {
-	// Try GeoClue first (Linux system location service - most accurate when available)
+// postPresence posts a heartbeat without any position, the device id, time,
+// battery and telemetry, of a heartbeat-only device or a locked session
+func postPresence(reason string) {
+	presence := &l8myfamily.Location{DeviceId: deviceID, Heartbeat: true, Battery: batteryPercent(),
+		ReportIntervalSeconds: reportInterval()}
+	if telemetry {
+		presence.Telemetry = collectTelemetry()
+	}
+	if err := postAll(presence); err != nil {
+		log.Printf("Error posting heartbeat: %v", err)
+		return
+	}
+	log.Printf("Posted heartbeat, %s", reason)
+}
+
+// getLocation walks the provider chain, the GPS receiver, GeoClue and then
+// the IP based fallback, and returns the fix with the source of the provider
+// that produced it. The fix Source is set from it, not left to the provider.
+func getLocation() (*l8myfamily.Location, l8myfamily.LocationSource, error) {
+	// A configured serial GPS receiver is the most accurate
+	if nmeaPort != "" {
+		location, err := getLocationFromNmea(nmeaPort, nmeaBaud)
+		if err == nil {
+			log.Printf("Location obtained via GPS receiver %s", nmeaPort)
+			return fromProvider(location, l8myfamily.LocationSource_LOCATION_SOURCE_GPS)
+		}
+		log.Printf("GPS receiver %s failed: %v, falling back to GeoClue", nmeaPort, err)
+	}
+
+	// Then GeoClue (Linux system location service - most accurate when available)
 	location, err := getLocationFromGeoClue()
 	if err == nil {
+		if location, err = geoClueFix(location); err != nil {
+			return nil, l8myfamily.LocationSource_LOCATION_SOURCE_UNKNOWN, err
+		}
 		log.Printf("Location obtained via GeoClue")
-		return location, nil
+		return fromProvider(location, l8myfamily.LocationSource_LOCATION_SOURCE_GEOCLUE)
+	}
+	if geoIPDisabled {
+		return nil, l8myfamily.LocationSource_LOCATION_SOURCE_UNKNOWN,
+			fmt.Errorf("GeoClue failed: %w, the IP based fallback is disabled", err)
+	}
+	if skipCoarse {
+		return nil, l8myfamily.LocationSource_LOCATION_SOURCE_UNKNOWN,
+			fmt.Errorf("GeoClue failed: %w, the IP based fallback is coarse", err)
 	}
 	log.Printf("GeoClue failed: %v, falling back to IP-based", err)
 
 	// Fall back to IP-based geolocation (free, but city-level accuracy only)
 	location, err = getLocationFromGeoIP()
 	if err != nil {
-		return nil, fmt.Errorf("all location methods failed: %w", err)
+		return nil, l8myfamily.LocationSource_LOCATION_SOURCE_UNKNOWN, fmt.Errorf("all location methods failed: %w", err)
 	}
 	log.Printf("Location obtained via IP geolocation")
-	return location, nil
+	return fromProvider(location, l8myfamily.LocationSource_LOCATION_SOURCE_GEOIP)
+}
+
+// fromProvider sets the source of the fix to the provider that produced it
+func fromProvider(location *l8myfamily.Location, source l8myfamily.LocationSource) (*l8myfamily.Location, l8myfamily.LocationSource, error) {
+	location.Source = source
+	return location, source, nil
 }
 
+// geoIPFields are the ip-api.com fields requested, the place and public ip
+// enrich an IP based fix without a separate reverse geocoding call,
+// and the proxy and hosting flags detect a VPN or proxy exit
+const geoIPFields = "status,message,lat,lon,city,regionName,country,query,proxy,hosting"
+
 type geoIPResponse struct {
-	Lat float64 `json:"lat"`
-	Lon float64 `json:"lon"`
+	Status     string  `json:"status"`
+	Message    string  `json:"message"`
+	Lat        float64 `json:"lat"`
+	Lon        float64 `json:"lon"`
+	City       string  `json:"city"`
+	RegionName string  `json:"regionName"`
+	Country    string  `json:"country"`
+	Query      string  `json:"query"`
+	Proxy      bool    `json:"proxy"`
+	Hosting    bool    `json:"hosting"`
+}
+
+// placeName joins the non empty city, region and country
+func (this *geoIPResponse) placeName() string {
+	parts := make([]string, 0, 3)
+	for _, part := range []string{this.City, this.RegionName, this.Country} {
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, ", ")
 }
 
 func getLocationFromGeoIP() (*l8myfamily.Location, error) {
-	client := &http.Client{Timeout: 10 * time.Second}
+	lookupURL, client := geoIPLookup()
 
-	resp, err := client.Get("http://ip-api.com/json/")
+	query := url.Values{"fields": {geoIPFields}}
+	if geoIPLanguage != "" {
+		query.Set("lang", geoIPLanguage)
+	}
+	resp, err := client.Get(lookupURL + "?" + query.Encode())
 	if err != nil {
 		return nil, fmt.Errorf("geoip request failed: %w", err)
 	}
@@ -581,39 +975,154 @@ func getLocationFromGeoIP() (*l8myfamily.Location, error) {
 	if err := json.Unmarshal(body, &geoResp); err != nil {
 		return nil, fmt.Errorf("failed to parse response: %w", err)
 	}
+	if geoResp.Status == "fail" {
+		return nil, fmt.Errorf("geoip lookup failed: %s", geoResp.Message)
+	}
+
+	unreliable := false
+	if geoResp.Proxy || geoResp.Hosting {
+		switch geoIPProxy {
+		case geoIPProxySkip:
+			return nil, fmt.Errorf("public ip %s is a vpn or proxy exit, geoip fix skipped", geoResp.Query)
+		case geoIPProxyMark:
+			unreliable = true
+		}
+	}
 
 	return &l8myfamily.Location{
-		Latitude:  float32(geoResp.Lat),
-		Longitude: float32(geoResp.Lon),
+		Latitude:   float32(geoResp.Lat),
+		Longitude:  float32(geoResp.Lon),
+		Source:     l8myfamily.LocationSource_LOCATION_SOURCE_GEOIP,
+		Place:      geoResp.placeName(),
+		PublicIp:   geoResp.Query,
+		Unreliable: unreliable,
+		Coarse:     true,
 	}, nil
 }
 
-func postLocation(location *l8myfamily.Location) error {
-	data, err := json.Marshal(location)
+// locationEndpoint returns the raw location endpoint the locations are posted
+// to, it authenticates the agent and negotiates the encoding by Content-Type
+func locationEndpoint(s *server) string {
+	return agentcore.EndpointURL(s.website, "location")
+}
+
+func postLocation(s *server, location *l8myfamily.Location) error {
+	// A buffered location keeps its key, it may have reached the server
+	if location.IdempotencyKey == "" {
+		location.IdempotencyKey = uuid.New().String()
+	}
+	// A server with an older schema does not get the fields it lacks
+	webapi.DowngradeLocation(location, s.schemaVersion)
+	// Every post, also of a buffered location, gets a fresh nonce
+	if s.signingKey != "" {
+		webapi.SignLocation(location, s.signingKey, serverClock.Now())
+	}
+	contentType := webapi.ContentTypeJSON
+	if useProtobuf {
+		contentType = webapi.ContentTypeProtobuf
+	}
+	data, err := webapi.EncodeBody(contentType, location)
 	if err != nil {
 		return fmt.Errorf("failed to marshal location: %w", err)
 	}
-
-	locationEndpoint := strings.TrimSuffix(website, "/") + "/my-family/53/Location"
-
-	req, err := http.NewRequest("POST", locationEndpoint, bytes.NewReader(data))
-	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
+	// The protobuf body is gzipped, the JSON one is posted as is
+	encoding := ""
+	if useProtobuf {
+		data, encoding = compressBody(data)
 	}
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+bearerToken)
 
-	client := getHTTPClient()
-	resp, err := client.Do(req)
-	if err != nil {
+	if _, err := postWithRetry(s, locationEndpoint(s), contentType, encoding, data, location.IdempotencyKey); err != nil {
 		return fmt.Errorf("post request failed: %w", err)
 	}
-	defer resp.Body.Close()
+	return nil
+}
+
+// postAttempts is how many times a post is tried on a network error or a
+// server error before giving up
+const postAttempts = 3
+
+// retryDelay is the delay before the second attempt, it grows with each attempt
+var retryDelay = time.Second
+
+// errUnauthorized is returned for a post the server answered with a 401, the
+// bearer token expired or was revoked
+var errUnauthorized = errors.New("unauthorized")
+
+// errAuthRejected is returned for a login the server refused, e.g. wrong
+// credentials or a disabled account
+var errAuthRejected = errors.New("authentication failed")
 
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("server returned status %d: %s", resp.StatusCode, string(body))
+// maxRetryAfter caps the wait a 503 or 429 Retry-After header asks for
+var maxRetryAfter = agentcore.MaxRetryAfter
+
+// observeServerTime corrects the clock location timestamps are stamped with
+// by the server time of a response, a device clock that is far off is logged
+func observeServerTime(header http.Header, sent time.Time) {
+	if offset, warn := serverClock.Observe(header, sent); warn {
+		log.Printf("Warning: device clock is off by %s from the server, timestamps are corrected", -offset)
 	}
+}
 
-	return nil
+// postWithRetry posts the data and returns the response body, retrying on
+// network and server errors. Every attempt carries the same Idempotency-Key
+// header, so a post that reached the server before the response was lost
+// is not applied twice.
+func postWithRetry(s *server, endpoint, contentType, contentEncoding string, data []byte, key string) ([]byte, error) {
+	var lastErr error
+	// retryAfter is the wait the server asked for, it replaces the backoff
+	var retryAfter time.Duration
+	hinted := false
+	for attempt := 1; attempt <= postAttempts; attempt++ {
+		if attempt > 1 {
+			delay := time.Duration(attempt-1) * retryDelay
+			if hinted {
+				delay = retryAfter
+			}
+			time.Sleep(delay)
+		}
+		hinted = false
+		req, err := http.NewRequest("POST", endpoint, bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create request: %w", err)
+		}
+		req.Header.Set("Content-Type", contentType)
+		if contentEncoding != "" {
+			req.Header.Set("Content-Encoding", contentEncoding)
+		}
+		authScheme.Apply(req, s.bearerToken)
+		req.Header.Set("Idempotency-Key", key)
+
+		sent := time.Now()
+		resp, err := getHTTPClient().Do(req)
+		if err != nil {
+			lastErr = err
+			log.Printf("Post attempt %d to %s failed: %v", attempt, endpoint, err)
+			continue
+		}
+		observeServerTime(resp.Header, sent)
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			lastErr = fmt.Errorf("failed to read response body: %w", err)
+			continue
+		}
+		if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+			return body, nil
+		}
+		if resp.StatusCode == http.StatusUnauthorized {
+			return nil, fmt.Errorf("%w: server returned status %d: %s", errUnauthorized, resp.StatusCode, string(body))
+		}
+		lastErr = fmt.Errorf("server returned status %d: %s", resp.StatusCode, string(body))
+		retryAfter, hinted = agentcore.RetryAfter(resp, serverClock.Now(), maxRetryAfter)
+		// A client error will fail the same way again, unless the server
+		// asks to retry later
+		if resp.StatusCode < 500 && !hinted {
+			return nil, lastErr
+		}
+		log.Printf("Post attempt %d to %s failed: %v", attempt, endpoint, lastErr)
+		if hinted {
+			log.Printf("Server asks to retry %s in %s", endpoint, retryAfter)
+		}
+	}
+	return nil, lastErr
 }
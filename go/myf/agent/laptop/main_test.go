@@ -0,0 +1,142 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/saichler/l8myfamiliy/go/types/l8myfamily"
+)
+
+func TestGetLocationFromGeoIP(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("fields") != geoIPFields || r.URL.Query().Get("lang") != "de" {
+			t.Errorf("unexpected query %s", r.URL.RawQuery)
+		}
+		w.Write([]byte(`{"status":"success","lat":48.1,"lon":11.6,"city":"München","regionName":"Bayern","country":"Deutschland","query":"203.0.113.7"}`))
+	}))
+	defer srv.Close()
+	defer func(u, l string) { geoIPURL, geoIPLanguage = u, l }(geoIPURL, geoIPLanguage)
+	geoIPURL, geoIPLanguage = srv.URL+"/json/", "de"
+
+	location, err := getLocationFromGeoIP()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if location.Place != "München, Bayern, Deutschland" || location.PublicIp != "203.0.113.7" || location.Latitude != 48.1 || !location.Coarse {
+		t.Fatalf("unexpected location %+v", location)
+	}
+}
+
+func TestGetLocationFromGeoIPFailure(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"status":"fail","message":"private range"}`))
+	}))
+	defer srv.Close()
+	defer func(u string) { geoIPURL = u }(geoIPURL)
+	geoIPURL = srv.URL + "/json/"
+
+	if _, err := getLocationFromGeoIP(); err == nil {
+		t.Fatal("a failed lookup must not report a 0,0 position")
+	}
+}
+
+func TestGetLocationFromGeoIPProxyPolicy(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"status":"success","lat":52.3,"lon":4.9,"city":"Amsterdam","query":"198.51.100.9","proxy":true}`))
+	}))
+	defer srv.Close()
+	defer func(u, p string) { geoIPURL, geoIPProxy = u, p }(geoIPURL, geoIPProxy)
+	geoIPURL = srv.URL + "/json/"
+
+	geoIPProxy = geoIPProxyMark
+	if location, err := getLocationFromGeoIP(); err != nil || !location.Unreliable {
+		t.Fatalf("a vpn exit must be marked unreliable, got %+v %v", location, err)
+	}
+	geoIPProxy = geoIPProxySkip
+	if _, err := getLocationFromGeoIP(); err == nil {
+		t.Fatal("a vpn exit must be skipped")
+	}
+	geoIPProxy = geoIPProxyPost
+	if location, err := getLocationFromGeoIP(); err != nil || location.Unreliable {
+		t.Fatalf("a vpn exit must be posted as is, got %+v %v", location, err)
+	}
+}
+
+func TestGetLocationSourceFromGeoClue(t *testing.T) {
+	defer func(m string, s *geoClueSession, p string) { geoClueMode, geoClue, nmeaPort = m, s, p }(geoClueMode, geoClue, nmeaPort)
+	f := &fakeGeoClue{path: "/org/freedesktop/GeoClue2/Client/1/Location/1", latitude: 1}
+	geoClueMode, geoClue, nmeaPort = geoCluePersistent, f.session(), ""
+	geoClue.clientPath = "/org/freedesktop/GeoClue2/Client/1"
+
+	location, source, err := getLocation()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if source != l8myfamily.LocationSource_LOCATION_SOURCE_GEOCLUE || location.Source != source || location.Latitude != 1 {
+		t.Fatalf("expected a GeoClue fix, got %v %+v", source, location)
+	}
+}
+
+func TestGetLocationSourceFromGeoIPFallback(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"status":"success","lat":48.1,"lon":11.6,"city":"München","query":"203.0.113.7"}`))
+	}))
+	defer srv.Close()
+	defer func(m string, s *geoClueSession, p, u string, c bool) {
+		geoClueMode, geoClue, nmeaPort, geoIPURL, skipCoarse = m, s, p, u, c
+	}(geoClueMode, geoClue, nmeaPort, geoIPURL, skipCoarse)
+	// GeoClue cannot start a client, the chain falls back to the IP lookup
+	f := &fakeGeoClue{}
+	geoClue = f.session()
+	geoClue.newClient = func() (string, error) { return "", errors.New("no GeoClue") }
+	geoClueMode, nmeaPort, geoIPURL, skipCoarse = geoCluePersistent, "", srv.URL+"/json/", false
+
+	location, source, err := getLocation()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if source != l8myfamily.LocationSource_LOCATION_SOURCE_GEOIP || location.Source != source || location.Latitude != 48.1 {
+		t.Fatalf("expected a GeoIP fix, got %v %+v", source, location)
+	}
+}
+
+func TestGetLocationWithGeoIPDisabled(t *testing.T) {
+	var lookups atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lookups.Add(1)
+		w.Write([]byte(`{"status":"success","lat":48.1,"lon":11.6}`))
+	}))
+	defer srv.Close()
+	defer func(m string, s *geoClueSession, p, u string, d bool) {
+		geoClueMode, geoClue, nmeaPort, geoIPURL, geoIPDisabled = m, s, p, u, d
+	}(geoClueMode, geoClue, nmeaPort, geoIPURL, geoIPDisabled)
+	f := &fakeGeoClue{}
+	geoClue = f.session()
+	geoClue.newClient = func() (string, error) { return "", errors.New("no GeoClue") }
+	geoClueMode, nmeaPort, geoIPURL, geoIPDisabled = geoCluePersistent, "", srv.URL+"/json/", true
+
+	if location, _, err := getLocation(); err == nil {
+		t.Fatalf("expected no fix without GeoClue, got %+v", location)
+	}
+	if n := lookups.Load(); n != 0 {
+		t.Fatalf("expected no IP geolocation request, got %d", n)
+	}
+}
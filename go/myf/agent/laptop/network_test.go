@@ -0,0 +1,222 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/saichler/l8myfamiliy/go/myf/agent/agentcore"
+	"github.com/saichler/l8myfamiliy/go/myf/agent/agenttest"
+	"github.com/saichler/l8myfamiliy/go/types/l8myfamily"
+)
+
+// newFakeServer starts the fake family server and points the agent at it
+func newFakeServer(t *testing.T) (*agenttest.Server, *server) {
+	fake := agenttest.NewServer(t, "alice", "s3cret!")
+	user, pass, deviceID, deviceName, retryDelay = "alice", "s3cret!", "laptop-1", "Laptop", 0
+	t.Cleanup(func() { user, pass, deviceID, deviceName = "", "", "", "" })
+	return fake, newServer(fake.URL)
+}
+
+func TestConnectAndPost(t *testing.T) {
+	fake, s := newFakeServer(t)
+	if err := s.post(&l8myfamily.Location{DeviceId: deviceID, Latitude: 1}); err != nil {
+		t.Fatal(err)
+	}
+	registrations := fake.Registrations()
+	if fake.Auths() != 1 || len(registrations) != 1 || len(fake.Locations()) != 1 {
+		t.Fatalf("expected one auth, registration and location, got %d %d %d", fake.Auths(), len(registrations), len(fake.Locations()))
+	}
+	if device := registrations[0]; device.Id != "laptop-1" || device.FamilyId != "alice" || device.Name != "Laptop" || device.SigningKey == "" {
+		t.Fatalf("unexpected registration %v", device)
+	}
+	if location := fake.Locations()[0]; location.IdempotencyKey == "" || location.Signature == "" {
+		t.Fatalf("the post must carry a key and be signed, got %v", location)
+	}
+}
+
+func TestAliasNamesTheDeviceInTheLogsOnly(t *testing.T) {
+	fake, s := newFakeServer(t)
+	alias = "kitchen"
+	defer func() { alias = "" }()
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	defer log.SetOutput(os.Stderr)
+
+	if err := s.post(&l8myfamily.Location{DeviceId: deviceID, Latitude: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(logs.String(), "kitchen") || strings.Contains(logs.String(), "laptop-1") {
+		t.Fatalf("the logs must name the device by its alias, got %s", logs.String())
+	}
+	posted, _ := json.Marshal([]interface{}{fake.Registrations(), fake.Locations()})
+	if strings.Contains(string(posted), "kitchen") || !strings.Contains(string(posted), "laptop-1") {
+		t.Fatalf("the alias must not be posted, got %s", posted)
+	}
+}
+
+func TestReauthenticationCooldown(t *testing.T) {
+	fake, s := newFakeServer(t)
+	if err := s.post(&l8myfamily.Location{DeviceId: deviceID, Latitude: 1}); err != nil {
+		t.Fatal(err)
+	}
+	// The server rejects every post, e.g. the account is disabled: the token
+	// is renewed once, then not again within the cooldown
+	fake.Fail(http.StatusUnauthorized, http.StatusUnauthorized, http.StatusUnauthorized)
+	if err := s.post(&l8myfamily.Location{DeviceId: deviceID, Latitude: 2}); err == nil || fake.Auths() != 2 {
+		t.Fatalf("expected a re-authentication and the post rejected, got %v %d", err, fake.Auths())
+	}
+	err := s.post(&l8myfamily.Location{DeviceId: deviceID, Latitude: 3})
+	if !errors.Is(err, agentcore.ErrReauthCoolingDown) || fake.Auths() != 2 {
+		t.Fatalf("a re-authentication within the cooldown must wait, got %v %d", err, fake.Auths())
+	}
+	// The next post waits for the cooldown as well, nothing is sent
+	if err := s.post(&l8myfamily.Location{DeviceId: deviceID, Latitude: 4}); !errors.Is(err, agentcore.ErrReauthCoolingDown) || len(fake.Locations()) != 1 {
+		t.Fatalf("expected no login nor post within the cooldown, got %v %d", err, len(fake.Locations()))
+	}
+}
+
+func TestPostReauthenticatesOn401(t *testing.T) {
+	fake, s := newFakeServer(t)
+	s.post(&l8myfamily.Location{DeviceId: deviceID, Latitude: 1})
+	fake.ExpireToken()
+	if err := s.post(&l8myfamily.Location{DeviceId: deviceID, Latitude: 2}); err != nil {
+		t.Fatalf("an expired token must be renewed, got %v", err)
+	}
+	if fake.Auths() != 2 || len(fake.Locations()) != 2 || fake.Locations()[1].Latitude != 2 {
+		t.Fatalf("expected a second auth and the location posted, got %d auths %v", fake.Auths(), fake.Locations())
+	}
+}
+
+func TestPostBacksOffOn5xx(t *testing.T) {
+	fake, s := newFakeServer(t)
+	s.connect()
+	fake.Fail(http.StatusServiceUnavailable, http.StatusBadGateway)
+	if err := s.post(&l8myfamily.Location{DeviceId: deviceID, Latitude: 1}); err != nil {
+		t.Fatalf("the third attempt must succeed, got %v", err)
+	}
+	fake.Fail(http.StatusInternalServerError, http.StatusInternalServerError, http.StatusInternalServerError)
+	if err := s.post(&l8myfamily.Location{DeviceId: deviceID, Latitude: 2}); err == nil || !strings.Contains(err.Error(), "status 500") {
+		t.Fatalf("the post must fail after the attempts, got %v", err)
+	}
+	fake.Fail(http.StatusBadRequest)
+	if err := s.post(&l8myfamily.Location{DeviceId: deviceID, Latitude: 3}); err == nil || len(fake.Locations()) != 1 {
+		t.Fatalf("a client error must not be retried, got %v %d", err, len(fake.Locations()))
+	}
+}
+
+func TestAuthenticateWithTfa(t *testing.T) {
+	fake, s := newFakeServer(t)
+	fake.TfaCode = "123456"
+	// The code is read from the terminal, the first one is wrong
+	r, w, _ := os.Pipe()
+	w.WriteString("654321\n123456\n")
+	w.Close()
+	defer func(stdin *os.File) { os.Stdin = stdin }(os.Stdin)
+	os.Stdin = r
+
+	if err := authenticate(s); err != nil {
+		t.Fatal(err)
+	}
+	if fake.TfaVerifies() != 2 || s.bearerToken != "token-1" {
+		t.Fatalf("expected the second code to verify the pending token, got %d %q", fake.TfaVerifies(), s.bearerToken)
+	}
+	if err := s.post(&l8myfamily.Location{DeviceId: deviceID, Latitude: 1}); err != nil {
+		t.Fatalf("the verified token must be accepted, got %v", err)
+	}
+}
+
+func TestAuthenticateErrors(t *testing.T) {
+	fake, s := newFakeServer(t)
+	pass = "wrong"
+	if err := authenticate(s); err == nil || !strings.Contains(err.Error(), "invalid credentials") {
+		t.Fatalf("wrong credentials must fail, got %v", err)
+	}
+	for _, body := range []string{`{"token": ""}`, `{"setupTfa": true}`, "   "} {
+		fake.AuthBody = body
+		if err := authenticate(s); err == nil || s.bearerToken != "" {
+			t.Fatalf("auth response %q must fail, got %v %q", body, err, s.bearerToken)
+		}
+	}
+	fake.AuthBody = "legacy-token"
+	if err := authenticate(s); err != nil || s.bearerToken != "legacy-token" {
+		t.Fatalf("a plain token must still be accepted, got %v %q", err, s.bearerToken)
+	}
+}
+
+func TestHeartbeatOnlyPostsNoPosition(t *testing.T) {
+	fake, s := newFakeServer(t)
+	servers, heartbeatOnly = []*server{s}, true
+	t.Cleanup(func() { servers, heartbeatOnly = nil, false })
+
+	collectAndPost()
+	registrations, locations := fake.Registrations(), fake.Locations()
+	if len(registrations) != 1 || !registrations[0].HeartbeatOnly {
+		t.Fatalf("the device must register heartbeat-only, got %v", registrations)
+	}
+	if len(locations) != 1 {
+		t.Fatalf("expected one heartbeat, got %d posts", len(locations))
+	}
+	if l := locations[0]; !l.Heartbeat || l.Latitude != 0 || l.Longitude != 0 || l.Accuracy != 0 || l.PublicIp != "" || l.Place != "" {
+		t.Fatalf("a heartbeat-only post must carry no position, got %v", l)
+	}
+}
+
+func TestStartupRetriesUntilConnected(t *testing.T) {
+	fake, s := newFakeServer(t)
+	origRetry := startupRetry
+	servers, startupRetry = []*server{s}, &agentcore.StartupRetry{Retries: 3, MinBackoff: time.Millisecond, MaxBackoff: time.Millisecond}
+	t.Cleanup(func() { servers, startupRetry = nil, origRetry })
+	retries := 0
+	retrying := func(wait time.Duration, err error) { retries++ }
+
+	// The registration fails every post attempt of the first two connections
+	failures := make([]int, 2*postAttempts)
+	for i := range failures {
+		failures[i] = http.StatusServiceUnavailable
+	}
+	fake.Fail(failures...)
+	if err := startupRetry.Run(connectServers, startupRejected, retrying); err != nil {
+		t.Fatalf("the third connection must succeed, got %v", err)
+	}
+	if retries != 2 || fake.Auths() != 1 || len(fake.Registrations()) != 1 {
+		t.Fatalf("expected 2 retries of the registration with one login, got %d %d %d", retries, fake.Auths(), len(fake.Registrations()))
+	}
+
+	// Refused credentials are not retried
+	retries = 0
+	s = newServer(fake.URL)
+	servers, pass = []*server{s}, "wrong"
+	err := startupRetry.Run(connectServers, startupRejected, retrying)
+	if !errors.Is(err, errAuthRejected) || retries != 0 {
+		t.Fatalf("a rejected login must stop the startup, got %v after %d retries", err, retries)
+	}
+
+	// A server that stays down fails once the retries are used up
+	fake.Close()
+	servers, pass = []*server{newServer(fake.URL)}, "s3cret!"
+	if err := startupRetry.Run(connectServers, startupRejected, retrying); err == nil || retries != 3 {
+		t.Fatalf("expected the startup to fail after 3 retries, got %v after %d", err, retries)
+	}
+}
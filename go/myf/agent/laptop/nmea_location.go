@@ -0,0 +1,256 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/saichler/l8myfamiliy/go/types/l8myfamily"
+)
+
+// defaultNmeaBaud is the baud rate of most USB GPS receivers
+const defaultNmeaBaud = 9600
+
+// nmeaTimeout is how long the receiver is read for a fix
+const nmeaTimeout = 5 * time.Second
+
+// nmeaGgaWait is how many sentences after the RMC are read for the GGA
+const nmeaGgaWait = 10
+
+// knotsToMetersPerSecond converts the RMC speed over ground
+const knotsToMetersPerSecond = 0.514444
+
+// uereMeters estimates the accuracy from the GGA horizontal dilution of
+// precision, a typical user equivalent range error of a consumer receiver
+const uereMeters = 5.0
+
+// nmeaFix collects a fix from the RMC and GGA sentences of one cycle
+type nmeaFix struct {
+	latitude  float64
+	longitude float64
+	speed     float64
+	heading   float64
+	altitude  float64
+	accuracy  float64
+	// rmc is set by a valid RMC sentence, it carries the position
+	rmc bool
+	// gga is set by a GGA sentence with a fix, it carries the altitude
+	gga bool
+}
+
+// getLocationFromNmea reads a fix from the serial GPS receiver at port, e.g.
+// /dev/ttyUSB0, for a dedicated tracker without GeoClue
+func getLocationFromNmea(port string, baud int) (*l8myfamily.Location, error) {
+	if err := configureSerial(port, baud); err != nil {
+		return nil, err
+	}
+	f, err := os.Open(port)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", port, err)
+	}
+	// Closing the port ends a read blocked on a receiver that went silent
+	timer := time.AfterFunc(nmeaTimeout, func() { f.Close() })
+	defer timer.Stop()
+	defer f.Close()
+	return readNmeaFix(f)
+}
+
+// configureSerial sets the baud rate and raw mode of the port with stty
+func configureSerial(port string, baud int) error {
+	if baud <= 0 {
+		baud = defaultNmeaBaud
+	}
+	flag := "-F"
+	if runtime.GOOS == "darwin" {
+		flag = "-f"
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, "stty", flag, port, strconv.Itoa(baud), "raw", "-echo").CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("failed to configure %s: %v %s", port, err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// readNmeaFix reads sentences until a valid RMC position, it waits for the GGA
+// of the same cycle for the altitude. Sentences with a bad checksum are skipped.
+func readNmeaFix(r io.Reader) (*l8myfamily.Location, error) {
+	fix := &nmeaFix{}
+	noFix := false
+	afterRmc := 0
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		err := fix.apply(scanner.Text())
+		if err == errNmeaNoFix {
+			noFix = true
+			continue
+		}
+		if fix.rmc && fix.gga {
+			break
+		}
+		// A receiver that sends no GGA is not waited for until the timeout
+		if fix.rmc {
+			afterRmc++
+			if afterRmc > nmeaGgaWait {
+				break
+			}
+		}
+	}
+	if !fix.rmc {
+		if noFix {
+			return nil, fmt.Errorf("gps receiver has no fix")
+		}
+		return nil, fmt.Errorf("no position read from the gps receiver")
+	}
+	return fix.location(), nil
+}
+
+// errNmeaNoFix is a well formed sentence of a receiver without a fix
+var errNmeaNoFix = fmt.Errorf("no fix")
+
+// apply parses an NMEA sentence into the fix, sentences other than RMC and
+// GGA are ignored. Any talker is accepted, e.g. $GPRMC and $GNRMC.
+func (this *nmeaFix) apply(line string) error {
+	fields, err := nmeaFields(line)
+	if err != nil {
+		return err
+	}
+	if len(fields[0]) < 5 {
+		return fmt.Errorf("invalid sentence type %s", fields[0])
+	}
+	switch fields[0][2:] {
+	case "RMC":
+		// $GPRMC,time,status,lat,N,lon,E,knots,course,date,...
+		if len(fields) < 9 {
+			return fmt.Errorf("short RMC sentence")
+		}
+		if fields[2] != "A" {
+			return errNmeaNoFix
+		}
+		lat, lon, err := nmeaPosition(fields[3], fields[4], fields[5], fields[6])
+		if err != nil {
+			return err
+		}
+		this.latitude, this.longitude, this.rmc = lat, lon, true
+		if knots, err := strconv.ParseFloat(fields[7], 64); err == nil {
+			this.speed = knots * knotsToMetersPerSecond
+		}
+		if course, err := strconv.ParseFloat(fields[8], 64); err == nil {
+			this.heading = course
+		}
+	case "GGA":
+		// $GPGGA,time,lat,N,lon,E,quality,satellites,hdop,altitude,M,...
+		if len(fields) < 10 {
+			return fmt.Errorf("short GGA sentence")
+		}
+		if fields[6] == "" || fields[6] == "0" {
+			return errNmeaNoFix
+		}
+		if hdop, err := strconv.ParseFloat(fields[8], 64); err == nil {
+			this.accuracy = hdop * uereMeters
+		}
+		if altitude, err := strconv.ParseFloat(fields[9], 64); err == nil {
+			this.altitude = altitude
+		}
+		this.gga = true
+	}
+	return nil
+}
+
+// location returns the fix as a location
+func (this *nmeaFix) location() *l8myfamily.Location {
+	return &l8myfamily.Location{
+		Latitude:  float32(this.latitude),
+		Longitude: float32(this.longitude),
+		Source:    l8myfamily.LocationSource_LOCATION_SOURCE_GPS,
+		Accuracy:  float32(this.accuracy),
+		Speed:     float32(this.speed),
+		Heading:   float32(this.heading),
+		Altitude:  float32(this.altitude),
+	}
+}
+
+// nmeaFields validates the checksum of the sentence and returns its fields,
+// the first is the talker and sentence type, e.g. GPRMC
+func nmeaFields(line string) ([]string, error) {
+	line = strings.TrimSpace(line)
+	if !strings.HasPrefix(line, "$") {
+		return nil, fmt.Errorf("not an NMEA sentence")
+	}
+	star := strings.LastIndex(line, "*")
+	if star < 0 || len(line) != star+3 {
+		return nil, fmt.Errorf("missing checksum")
+	}
+	expected, err := strconv.ParseUint(line[star+1:], 16, 8)
+	if err != nil {
+		return nil, fmt.Errorf("invalid checksum %s", line[star+1:])
+	}
+	var sum byte
+	for i := 1; i < star; i++ {
+		sum ^= line[i]
+	}
+	if sum != byte(expected) {
+		return nil, fmt.Errorf("checksum mismatch")
+	}
+	return strings.Split(line[1:star], ","), nil
+}
+
+// nmeaPosition converts the ddmm.mmmm / dddmm.mmmm coordinates to degrees
+func nmeaPosition(lat, ns, lon, ew string) (float64, float64, error) {
+	latitude, err := nmeaDegrees(lat, 2)
+	if err != nil {
+		return 0, 0, err
+	}
+	longitude, err := nmeaDegrees(lon, 3)
+	if err != nil {
+		return 0, 0, err
+	}
+	if ns == "S" {
+		latitude = -latitude
+	}
+	if ew == "W" {
+		longitude = -longitude
+	}
+	if latitude < -90 || latitude > 90 || longitude < -180 || longitude > 180 {
+		return 0, 0, fmt.Errorf("position out of range")
+	}
+	return latitude, longitude, nil
+}
+
+func nmeaDegrees(value string, degreeDigits int) (float64, error) {
+	if len(value) < degreeDigits+2 {
+		return 0, fmt.Errorf("invalid coordinate %q", value)
+	}
+	degrees, err := strconv.ParseFloat(value[:degreeDigits], 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid coordinate %q", value)
+	}
+	minutes, err := strconv.ParseFloat(value[degreeDigits:], 64)
+	if err != nil || minutes >= 60 {
+		return 0, fmt.Errorf("invalid coordinate %q", value)
+	}
+	return degrees + minutes/60, nil
+}
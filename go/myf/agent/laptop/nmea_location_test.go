@@ -0,0 +1,77 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/saichler/l8myfamiliy/go/types/l8myfamily"
+)
+
+// sentence adds the checksum to the NMEA sentence body
+func sentence(body string) string {
+	var sum byte
+	for i := 0; i < len(body); i++ {
+		sum ^= body[i]
+	}
+	return fmt.Sprintf("$%s*%02X", body, sum)
+}
+
+func TestReadNmeaFix(t *testing.T) {
+	input := strings.Join([]string{
+		"garbage from the port opening",
+		sentence("GPGSV,3,1,11,10,63,137,17,07,61,098,15,05,59,290,20,08,54,157,30"),
+		// Corrupt checksum, must be skipped
+		"$GPRMC,123519,A,0000.000,N,00000.000,E,022.4,084.4,230394,003.1,W*00",
+		sentence("GPRMC,123519,A,4807.038,N,01131.000,W,022.4,084.4,230394,003.1,W"),
+		sentence("GPGGA,123519,4807.038,N,01131.000,W,1,08,0.9,545.4,M,46.9,M,,"),
+	}, "\r\n")
+	location, err := readNmeaFix(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(float64(location.Latitude)-48.1173) > 1e-4 || math.Abs(float64(location.Longitude)+11.5167) > 1e-4 {
+		t.Fatalf("unexpected position %v %v", location.Latitude, location.Longitude)
+	}
+	if location.Source != l8myfamily.LocationSource_LOCATION_SOURCE_GPS || location.Altitude != 545.4 ||
+		location.Heading != 84.4 || math.Abs(float64(location.Speed)-11.52) > 0.01 || location.Accuracy != 4.5 {
+		t.Fatalf("unexpected fix %+v", location)
+	}
+}
+
+func TestReadNmeaNoFix(t *testing.T) {
+	input := sentence("GPRMC,123519,V,,,,,,,230394,,") + "\n" + sentence("GNGGA,123519,,,,,0,00,99.9,,M,,M,,")
+	if _, err := readNmeaFix(strings.NewReader(input)); err == nil || !strings.Contains(err.Error(), "no fix") {
+		t.Fatalf("expected a no fix error, got %v", err)
+	}
+	if _, err := readNmeaFix(strings.NewReader("")); err == nil {
+		t.Fatal("a silent receiver must fail")
+	}
+}
+
+func TestNmeaFieldsChecksum(t *testing.T) {
+	for _, line := range []string{"GPRMC,1,A", "$GPRMC,1,A", "$GPRMC,1,A*ZZ", "$GPRMC,1,A*0"} {
+		if _, err := nmeaFields(line); err == nil {
+			t.Fatalf("%q must be rejected", line)
+		}
+	}
+	if fields, err := nmeaFields(sentence("GNRMC,1,A")); err != nil || fields[0] != "GNRMC" {
+		t.Fatalf("unexpected %v %v", fields, err)
+	}
+}
@@ -0,0 +1,96 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+
+	"github.com/saichler/l8myfamiliy/go/myf/agent/agentcore"
+)
+
+// printedConfig is the effective configuration printed by --print-config
+type printedConfig struct {
+	*Config
+	ConfigFile string `json:"config_file"`
+	// User and Pass are redacted, empty when they are not configured
+	User string `json:"user,omitempty"`
+	Pass string `json:"pass,omitempty"`
+	// NatsURL is redacted like the credentials
+	NatsURL string `json:"nats_url,omitempty"`
+	// Endpoints are the location endpoints of the website and the mirrors
+	Endpoints []string `json:"endpoints"`
+	// Providers are the location providers in the order they are tried
+	Providers []string `json:"location_providers"`
+}
+
+// printConfig writes the configuration the agent would run with, the
+// defaults overridden by the config file, without prompting for the missing
+// settings and with the credentials redacted
+func printConfig(w io.Writer) error {
+	if err := loadConfigQuietly(); err != nil {
+		return err
+	}
+	printed := &printedConfig{
+		Config:     currentConfig(),
+		ConfigFile: configFile,
+		User:       agentcore.Redact(user),
+		Pass:       agentcore.Redact(pass),
+		Endpoints:  make([]string, 0),
+		Providers:  make([]string, 0, 3),
+	}
+	targets, err := locationServers()
+	if err != nil {
+		return err
+	}
+	if u, err := url.Parse(natsURL); err == nil && natsURL != "" {
+		printed.NatsURL = u.Redacted()
+	}
+	for _, s := range targets {
+		printed.Endpoints = append(printed.Endpoints, s.transport.endpoint())
+	}
+	if nmeaPort != "" {
+		printed.Providers = append(printed.Providers, fmt.Sprintf("nmea %s at %d baud", nmeaPort, nmeaBaud))
+	}
+	printed.Providers = append(printed.Providers, "geoclue")
+	if !geoIPDisabled {
+		printed.Providers = append(printed.Providers, "geoip "+geoIPURL)
+	}
+
+	data, err := json.MarshalIndent(printed, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(w, string(data))
+	return err
+}
+
+// loadConfigQuietly applies the config file over the defaults, without
+// prompting for the missing settings
+func loadConfigQuietly() error {
+	var cfg Config
+	exists, err := agentcore.LoadConfig(configFile, &cfg)
+	if err != nil {
+		return err
+	}
+	if exists {
+		deviceID, deviceName = cfg.DeviceID, cfg.DeviceName
+		applyConfig(&cfg)
+	}
+	return nil
+}
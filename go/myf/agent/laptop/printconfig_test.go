@@ -0,0 +1,68 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/saichler/l8myfamiliy/go/myf/agent/agentcore"
+)
+
+func TestPrintConfigRedactsCredentials(t *testing.T) {
+	origFile := configFile
+	configFile = filepath.Join(t.TempDir(), "laptop-agent.json")
+	deviceID, website, user, pass = "print-device", "https://family.example.com", "alice", "s3cret!"
+	mirrorWebsites, nmeaPort = []string{"https://backup.example.com"}, "/dev/ttyUSB0"
+	defer func() {
+		configFile = origFile
+		deviceID, website, user, pass = "", "", "", ""
+		mirrorWebsites, nmeaPort = nil, ""
+	}()
+	if err := saveConfig(); err != nil {
+		t.Fatal(err)
+	}
+	user, pass, website = "", "", ""
+
+	out := &bytes.Buffer{}
+	if err := printConfig(out); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(out.String(), "alice") || strings.Contains(out.String(), "s3cret!") {
+		t.Fatalf("the credentials must be redacted, got %s", out.String())
+	}
+	var printed map[string]interface{}
+	if err := json.Unmarshal(out.Bytes(), &printed); err != nil {
+		t.Fatal(err)
+	}
+	if printed["user"] != agentcore.Redacted || printed["pass"] != agentcore.Redacted {
+		t.Fatalf("configured credentials must show as redacted, got %v %v", printed["user"], printed["pass"])
+	}
+	if _, ok := printed["encrypted_pass"]; ok {
+		t.Fatal("the encrypted credentials must not be printed")
+	}
+	endpoints := printed["endpoints"].([]interface{})
+	if len(endpoints) != 2 || endpoints[0] != "https://family.example.com/my-family/location" {
+		t.Fatalf("unexpected endpoints %v", endpoints)
+	}
+	providers := printed["location_providers"].([]interface{})
+	if len(providers) != 3 || !strings.HasPrefix(providers[0].(string), "nmea /dev/ttyUSB0") || providers[1] != "geoclue" {
+		t.Fatalf("unexpected provider order %v", providers)
+	}
+}
@@ -0,0 +1,43 @@
+//go:build !windows
+
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestNotifyRefresh(t *testing.T) {
+	c := make(chan os.Signal, 1)
+	if !notifyRefresh(c) {
+		t.Fatal("SIGUSR1 must be supported")
+	}
+	defer signal.Stop(c)
+	syscall.Kill(os.Getpid(), syscall.SIGUSR1)
+	select {
+	case sig := <-c:
+		if sig != syscall.SIGUSR1 {
+			t.Fatalf("got %v", sig)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("SIGUSR1 was not relayed")
+	}
+}
@@ -0,0 +1,89 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/saichler/l8myfamiliy/go/myf/webapi"
+)
+
+// certWarning is how long before its expiry a server certificate is reported
+const certWarning = 14 * 24 * time.Hour
+
+// selfTest fetches the TLS diagnostics of the website and the mirrors and
+// warns about certificates close to their expiry, it fails when a server
+// cannot be checked
+func selfTest(w io.Writer, now time.Time) error {
+	if err := loadConfigQuietly(); err != nil {
+		return err
+	}
+	failed := 0
+	for _, s := range newServers(website, mirrorWebsites) {
+		diag, err := fetchDiag(s)
+		if err != nil {
+			failed++
+			fmt.Fprintf(w, "%s: FAILED %v\n", s.website, err)
+			continue
+		}
+		fmt.Fprintf(w, "%s: %s\n", s.website, describeDiag(diag, now))
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of the servers failed the self test", failed)
+	}
+	return nil
+}
+
+// fetchDiag gets the TLS diagnostics of the server, the endpoint needs no
+// authentication
+func fetchDiag(s *server) (*webapi.Diag, error) {
+	resp, err := getHTTPClient().Get(strings.TrimSuffix(s.website, "/") + webapi.Prefix + "diag")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("diag returned %s, it may be disabled on the server", resp.Status)
+	}
+	diag := &webapi.Diag{}
+	if err := json.NewDecoder(resp.Body).Decode(diag); err != nil {
+		return nil, fmt.Errorf("failed to parse the diag response: %w", err)
+	}
+	return diag, nil
+}
+
+// describeDiag returns one line of the diagnostics, with a warning for a
+// certificate that expired or expires within certWarning
+func describeDiag(diag *webapi.Diag, now time.Time) string {
+	if diag.CertError != "" {
+		return fmt.Sprintf("%s, certificate unknown: %s", diag.TLSVersion, diag.CertError)
+	}
+	expiry := time.UnixMilli(diag.CertNotAfter)
+	line := fmt.Sprintf("%s (min %s), certificate %s issued by %s, expires %s", diag.TLSVersion,
+		diag.TLSMinVersion, diag.CertSubject, diag.CertIssuer, expiry.Format(time.RFC3339))
+	switch left := expiry.Sub(now); {
+	case left <= 0:
+		line += " WARNING: the certificate expired"
+	case left < certWarning:
+		line += fmt.Sprintf(" WARNING: the certificate expires in %d days", int(left.Hours()/24))
+	}
+	return line
+}
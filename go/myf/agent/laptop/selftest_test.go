@@ -0,0 +1,61 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/saichler/l8myfamiliy/go/myf/webapi"
+)
+
+func TestFetchDiagWarnsOfExpiry(t *testing.T) {
+	now := time.Now()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != webapi.Prefix+"diag" {
+			http.NotFound(w, r)
+			return
+		}
+		webapi.WriteJSON(w, http.StatusOK, &webapi.Diag{CertSubject: "CN=family", CertIssuer: "CN=family",
+			CertNotAfter: now.Add(3 * 24 * time.Hour).UnixMilli(), TLSMinVersion: "TLS 1.2", TLSVersion: "TLS 1.3"})
+	}))
+	defer srv.Close()
+
+	diag, err := fetchDiag(&server{website: srv.URL + "/"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if line := describeDiag(diag, now); !strings.Contains(line, "CN=family") || !strings.Contains(line, "expires in 2 days") {
+		t.Fatalf("a certificate close to its expiry must be warned about, got %s", line)
+	}
+	if line := describeDiag(diag, now.Add(-30*24*time.Hour)); strings.Contains(line, "WARNING") {
+		t.Fatalf("a certificate far from its expiry must not be warned about, got %s", line)
+	}
+	if line := describeDiag(diag, now.Add(4*24*time.Hour)); !strings.Contains(line, "expired") {
+		t.Fatalf("an expired certificate must be reported, got %s", line)
+	}
+}
+
+func TestFetchDiagDisabled(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	defer srv.Close()
+	if _, err := fetchDiag(&server{website: srv.URL}); err == nil {
+		t.Fatal("a server without the diag endpoint must fail the check")
+	}
+}
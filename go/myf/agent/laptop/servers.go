@@ -0,0 +1,308 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/saichler/l8myfamiliy/go/myf/agent/agentcore"
+	"github.com/saichler/l8myfamiliy/go/types/l8myfamily"
+	"google.golang.org/protobuf/proto"
+)
+
+// maxPending is how many locations are buffered for a server that is down,
+// the oldest are dropped first
+const maxPending = 100
+
+// defaultMaxBufferedAge is how long a buffered location is still posted, an
+// older one posted after a long outage would be read as the current position
+const defaultMaxBufferedAge = 12 * time.Hour
+
+// server is a website the agent posts to, the configured website or a mirror
+type server struct {
+	website     string
+	bearerToken string
+	registered  bool
+	// signingKey signs the location posts, a new one is registered with every
+	// start of the agent
+	signingKey string
+	// schemaVersion is the location schema the server decodes, learned
+	// after authenticating
+	schemaVersion int32
+	// reauth spaces the logins that are not followed by an accepted post
+	reauth *agentcore.ReauthGate
+	// buffered is set with mirrors, a location that fails on one server is
+	// posted to it again before its next location
+	buffered bool
+	pending  []*l8myfamily.Location
+	// transport delivers the locations, the family server REST endpoint
+	// unless a NATS subject is configured
+	transport transport
+}
+
+// newServer returns the server of the website, not yet connected
+func newServer(website string) *server {
+	s := &server{website: website, reauth: agentcore.NewReauthGate(reauthCooldown)}
+	s.transport = &httpTransport{server: s}
+	return s
+}
+
+// httpTransport posts the locations to the REST endpoint of the server
+type httpTransport struct {
+	*server
+}
+
+// connect authenticates and registers the device on the server, the steps
+// already done are skipped
+func (this *server) connect() error {
+	return this.transport.connect()
+}
+
+// connectServers connects every server and returns an error when none is
+// up, it wraps the rejection when every server refused the credentials. A
+// server already connected is not connected again.
+func connectServers() error {
+	connected, rejected := 0, 0
+	var last error
+	for _, s := range servers {
+		if err := s.connect(); err != nil {
+			log.Printf("Failed to connect to %s: %v", s.transport.endpoint(), err)
+			if startupRejected(err) {
+				rejected++
+			}
+			last = err
+			continue
+		}
+		connected++
+	}
+	if connected > 0 {
+		return nil
+	}
+	if rejected == len(servers) {
+		return fmt.Errorf("every server rejected the agent: %w", last)
+	}
+	return fmt.Errorf("failed to connect to any server")
+}
+
+// startupRejected returns true for a connection error retrying cannot fix,
+// the credentials were refused
+func startupRejected(err error) bool {
+	return errors.Is(err, errAuthRejected) || errors.Is(err, agentcore.ErrNatsRejected)
+}
+
+// newServers returns the primary website followed by the mirrors, a mirror
+// equal to the website or to another mirror is skipped
+func newServers(website string, mirrors []string) []*server {
+	servers := []*server{newServer(website)}
+	seen := map[string]bool{strings.TrimSuffix(website, "/"): true}
+	for _, mirror := range mirrors {
+		key := strings.TrimSuffix(mirror, "/")
+		if key == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		servers = append(servers, newServer(mirror))
+	}
+	for _, s := range servers {
+		s.buffered = len(servers) > 1
+	}
+	return servers
+}
+
+func (this *httpTransport) connect() error {
+	if this.bearerToken == "" {
+		if left, err := this.reauth.Ready(time.Now()); err != nil {
+			return fmt.Errorf("%w, next attempt to %s in %s", err, this.website, left.Round(time.Second))
+		}
+		if this.reauth.Failing() {
+			log.Printf("Authentication to %s is persistently failing, %s, check the credentials and the account",
+				this.website, this.reauth.FailingFor(time.Now()))
+		}
+		err := authenticate(this.server)
+		if err == nil || errors.Is(err, errAuthRejected) {
+			this.reauth.Attempted(time.Now())
+		}
+		if err != nil {
+			return fmt.Errorf("failed to authenticate: %w", err)
+		}
+		this.schemaVersion = agentcore.FetchSchemaVersion(getHTTPClient(), this.website)
+		log.Printf("Server %s decodes location schema %d", this.website, this.schemaVersion)
+	}
+	if !this.registered {
+		if err := registerDevice(this.server); err != nil {
+			// A rejected token is renewed by the next connect
+			if errors.Is(err, errUnauthorized) {
+				this.bearerToken = ""
+			}
+			return fmt.Errorf("failed to register device: %w", err)
+		}
+		this.registered = true
+	}
+	return nil
+}
+
+// post posts the buffered locations and then the location to the server
+func (this *server) post(location *l8myfamily.Location) error {
+	if err := this.connect(); err != nil {
+		this.buffer(location)
+		return err
+	}
+	this.dropStale(serverClock.Now())
+	for len(this.pending) > 0 {
+		if err := this.send(this.pending[0]); err != nil {
+			this.buffer(location)
+			return err
+		}
+		this.pending = this.pending[1:]
+	}
+	if err := this.send(location); err != nil {
+		this.buffer(location)
+		return err
+	}
+	return nil
+}
+
+// send delivers the location with the transport of the server
+func (this *server) send(location *l8myfamily.Location) error {
+	return this.transport.send(location)
+}
+
+// send posts the location, a rejected token is renewed and the location is
+// posted again once
+func (this *httpTransport) send(location *l8myfamily.Location) error {
+	err := postLocation(this.server, location)
+	if errors.Is(err, errUnauthorized) {
+		log.Printf("Token for %s was rejected, authenticating again", this.website)
+		this.bearerToken = ""
+		if err := this.connect(); err != nil {
+			return err
+		}
+		err = postLocation(this.server, location)
+	}
+	if err == nil && this.reauth.Accepted(time.Now()) {
+		log.Printf("Authentication to %s recovered", this.website)
+	}
+	return err
+}
+
+// buffer keeps the location for the next post when the server is mirrored
+func (this *server) buffer(location *l8myfamily.Location) {
+	if !this.buffered {
+		return
+	}
+	if len(this.pending) >= maxPending {
+		log.Printf("Dropping the oldest location buffered for %s", this.website)
+		this.pending = this.pending[1:]
+	}
+	this.pending = append(this.pending, location)
+}
+
+// dropStale removes the buffered locations older than maxBufferedAge. The
+// ones kept are posted with the time they were taken, the server files them
+// as history behind a newer position.
+func (this *server) dropStale(now time.Time) {
+	minTime := now.Add(-maxBufferedAge).UnixMilli()
+	kept := this.pending[:0]
+	for _, location := range this.pending {
+		if location.Timestamp >= minTime {
+			kept = append(kept, location)
+		}
+	}
+	if dropped := len(this.pending) - len(kept); dropped > 0 {
+		log.Printf("Dropped %d locations buffered for %s, older than %s", dropped, this.website, maxBufferedAge)
+	}
+	this.pending = kept
+}
+
+// defaultHealthProbeTimeout bounds the reachability check of a server
+const defaultHealthProbeTimeout = 1500 * time.Millisecond
+
+// reachable tells whether the server can take a post now
+func (this *server) reachable(timeout time.Duration) bool {
+	return this.transport.reachable(timeout)
+}
+
+// reachable sends a HEAD request to the server, any response below 500 means
+// it can take a post
+func (this *httpTransport) reachable(timeout time.Duration) bool {
+	client := *getHTTPClient()
+	client.Timeout = timeout
+	resp, err := client.Head(strings.TrimSuffix(this.website, "/") + "/")
+	if err != nil {
+		return false
+	}
+	resp.Body.Close()
+	return resp.StatusCode < http.StatusInternalServerError
+}
+
+func (this *httpTransport) endpoint() string {
+	return locationEndpoint(this.server)
+}
+
+// anyReachable probes the servers concurrently and returns true when at
+// least one of them is reachable
+func anyReachable(timeout time.Duration) bool {
+	results := make(chan bool, len(servers))
+	for _, s := range servers {
+		go func(s *server) { results <- s.reachable(timeout) }(s)
+	}
+	for range servers {
+		if <-results {
+			return true
+		}
+	}
+	return false
+}
+
+// postAll posts the location to every server concurrently, it succeeds when
+// the location reached at least one of them
+func postAll(location *l8myfamily.Location) error {
+	if location.Timestamp == 0 {
+		location.Timestamp = serverClock.Now().UnixMilli()
+	}
+	if len(servers) == 1 {
+		return servers[0].post(location)
+	}
+	errs := make([]error, len(servers))
+	wg := sync.WaitGroup{}
+	for i, s := range servers {
+		wg.Add(1)
+		// Each server stamps its own idempotency key on its copy
+		go func(i int, s *server, location *l8myfamily.Location) {
+			defer wg.Done()
+			errs[i] = s.post(location)
+		}(i, s, proto.Clone(location).(*l8myfamily.Location))
+	}
+	wg.Wait()
+
+	failed := make([]string, 0)
+	for i, err := range errs {
+		if err != nil {
+			log.Printf("Post to %s failed, %d locations buffered: %v", servers[i].website, len(servers[i].pending), err)
+			failed = append(failed, servers[i].website)
+		}
+	}
+	if len(failed) == len(servers) {
+		return fmt.Errorf("post failed on every server: %s", strings.Join(failed, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,215 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/saichler/l8myfamiliy/go/myf/webapi"
+	"github.com/saichler/l8myfamiliy/go/types/l8myfamily"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// testServer is a family server that can be taken down
+type testServer struct {
+	*httptest.Server
+	mtx       sync.Mutex
+	down      bool
+	registers int
+	latitudes []float32
+}
+
+func newTestServer(t *testing.T) *testServer {
+	ts := &testServer{}
+	ts.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ts.mtx.Lock()
+		defer ts.mtx.Unlock()
+		if ts.down {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		switch r.URL.Path {
+		case "/auth":
+			w.Write([]byte(`{"token":"t"}`))
+		case "/my-family/device":
+			ts.registers++
+		case "/my-family/location":
+			var location l8myfamily.Location
+			json.NewDecoder(r.Body).Decode(&location)
+			ts.latitudes = append(ts.latitudes, location.Latitude)
+		}
+	}))
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func (this *testServer) setDown(down bool) {
+	this.mtx.Lock()
+	defer this.mtx.Unlock()
+	this.down = down
+}
+
+func TestPostAllMirrors(t *testing.T) {
+	retryDelay = 0
+	primary, mirror := newTestServer(t), newTestServer(t)
+	servers = newServers(primary.URL, []string{mirror.URL, primary.URL + "/"})
+	defer func() { servers = nil }()
+	if len(servers) != 2 {
+		t.Fatalf("a mirror equal to the website must be skipped, got %d servers", len(servers))
+	}
+
+	if err := postAll(&l8myfamily.Location{Latitude: 1}); err != nil {
+		t.Fatal(err)
+	}
+	mirror.setDown(true)
+	if err := postAll(&l8myfamily.Location{Latitude: 2}); err != nil {
+		t.Fatalf("a post that reached one server must succeed: %v", err)
+	}
+	mirror.setDown(false)
+	if err := postAll(&l8myfamily.Location{Latitude: 3}); err != nil {
+		t.Fatal(err)
+	}
+
+	if primary.registers != 1 || mirror.registers != 1 {
+		t.Fatalf("the device must be registered once on each server, got %d %d", primary.registers, mirror.registers)
+	}
+	for _, ts := range []*testServer{primary, mirror} {
+		if len(ts.latitudes) != 3 || ts.latitudes[1] != 2 || ts.latitudes[2] != 3 {
+			t.Fatalf("every server must get every location in order, got %v", ts.latitudes)
+		}
+	}
+
+	primary.setDown(true)
+	mirror.setDown(true)
+	if err := postAll(&l8myfamily.Location{Latitude: 4}); err == nil {
+		t.Fatal("a post that reached no server must fail")
+	}
+}
+
+func TestSingleServerDoesNotBuffer(t *testing.T) {
+	retryDelay = 0
+	primary := newTestServer(t)
+	servers = newServers(primary.URL, nil)
+	defer func() { servers = nil }()
+
+	primary.setDown(true)
+	if err := postAll(&l8myfamily.Location{Latitude: 1}); err == nil {
+		t.Fatal("the post must fail")
+	}
+	primary.setDown(false)
+	if err := postAll(&l8myfamily.Location{Latitude: 2}); err != nil {
+		t.Fatal(err)
+	}
+	if len(primary.latitudes) != 1 || primary.latitudes[0] != 2 {
+		t.Fatalf("a single server keeps the unbuffered behavior, got %v", primary.latitudes)
+	}
+}
+
+func TestDropStaleBufferedLocations(t *testing.T) {
+	defer func() { maxBufferedAge = defaultMaxBufferedAge }()
+	maxBufferedAge = time.Hour
+	now := time.UnixMilli(10 * 3600 * 1000)
+	s := &server{website: "https://mirror", buffered: true}
+	for _, age := range []time.Duration{3 * time.Hour, 2 * time.Hour, 30 * time.Minute, time.Minute} {
+		s.buffer(&l8myfamily.Location{Timestamp: now.Add(-age).UnixMilli()})
+	}
+	s.dropStale(now)
+	if len(s.pending) != 2 || s.pending[0].Timestamp != now.Add(-30*time.Minute).UnixMilli() {
+		t.Fatalf("only the locations within the max age must be kept in order, got %v", s.pending)
+	}
+}
+
+func TestPostsAreSignedWithTheRegisteredKey(t *testing.T) {
+	retryDelay = 0
+	var signingKey string
+	var verified []bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/auth":
+			w.Write([]byte(`{"token":"t"}`))
+		case "/my-family/device":
+			var device map[string]string
+			json.NewDecoder(r.Body).Decode(&device)
+			signingKey = device["signingKey"]
+		case "/my-family/location":
+			data, _ := io.ReadAll(r.Body)
+			location := &l8myfamily.Location{}
+			protojson.Unmarshal(data, location)
+			verified = append(verified, webapi.VerifyLocation(location, signingKey))
+		}
+	}))
+	defer srv.Close()
+	servers = newServers(srv.URL, nil)
+	defer func() { servers = nil }()
+
+	for i := 0; i < 2; i++ {
+		if err := postAll(&l8myfamily.Location{DeviceId: "d1", Latitude: 32.1, Longitude: 34.8}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if signingKey == "" || len(verified) != 2 || !verified[0] || !verified[1] {
+		t.Fatalf("expected the posts signed with the registered key, got %v", verified)
+	}
+}
+
+func TestAnyReachable(t *testing.T) {
+	primary, mirror := newTestServer(t), newTestServer(t)
+	servers = newServers(primary.URL, []string{mirror.URL})
+	defer func() { servers = nil }()
+
+	primary.setDown(true)
+	if !anyReachable(time.Second) {
+		t.Fatal("the mirror is up, a server must be reachable")
+	}
+	mirror.Close()
+	if anyReachable(time.Second) {
+		t.Fatal("a closed mirror and a failing primary must not be reachable")
+	}
+}
+
+func TestPostHonorsRetryAfter(t *testing.T) {
+	// The backoff would stall the test, the server hint replaces it
+	retryDelay = time.Hour
+	defer func(max time.Duration) { retryDelay = 0; maxRetryAfter = max }(maxRetryAfter)
+	maxRetryAfter = 10 * time.Millisecond
+	attempts := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		switch attempts {
+		case 1:
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+		case 2:
+			w.Header().Set("Retry-After", time.Now().Add(time.Second).UTC().Format(http.TimeFormat))
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+
+	started := time.Now()
+	if _, err := postWithRetry(&server{website: srv.URL}, srv.URL, webapi.ContentTypeJSON, "", []byte(`{}`), "k1"); err != nil {
+		t.Fatal(err)
+	}
+	if attempts != 3 || time.Since(started) > 5*time.Second {
+		t.Fatalf("expected 3 attempts paced by the capped Retry-After, got %d in %s", attempts, time.Since(started))
+	}
+}
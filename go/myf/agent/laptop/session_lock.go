@@ -0,0 +1,126 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os/exec"
+	"runtime"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// lockPollInterval is how often the session lock state is read, an unlock
+// resumes the posts within it
+var lockPollInterval = 15 * time.Second
+
+// lockWatcher follows the lock state of the session of the agent. While the
+// session is locked the laptop is usually stationary, the agent posts a
+// heartbeat every heartbeat instead of collecting positions.
+type lockWatcher struct {
+	heartbeat time.Duration
+	read      func() (bool, error)
+	locked    atomic.Bool
+	changes   chan bool
+}
+
+func newLockWatcher(heartbeat time.Duration, read func() (bool, error)) *lockWatcher {
+	return &lockWatcher{heartbeat: heartbeat, read: read, changes: make(chan bool, 1)}
+}
+
+// Locked returns whether the session is locked, false without a watcher
+func (this *lockWatcher) Locked() bool {
+	return this != nil && this.locked.Load()
+}
+
+// Changes returns the channel of the lock state changes, nil without a
+// watcher so a select on it never fires
+func (this *lockWatcher) Changes() <-chan bool {
+	if this == nil {
+		return nil
+	}
+	return this.changes
+}
+
+// start reads the lock state every lockPollInterval. Where it cannot be read,
+// e.g. without login1, the session counts as unlocked and the watcher stops.
+func (this *lockWatcher) start() {
+	if err := this.poll(); err != nil {
+		log.Printf("Session lock detection disabled: %v", err)
+		return
+	}
+	go func() {
+		for {
+			time.Sleep(lockPollInterval)
+			if err := this.poll(); err != nil {
+				log.Printf("Session lock detection stopped: %v", err)
+				this.locked.Store(false)
+				return
+			}
+		}
+	}()
+}
+
+// poll reads the lock state and reports a change, a change not taken yet
+// is replaced by the newer one
+func (this *lockWatcher) poll() error {
+	locked, err := this.read()
+	if err != nil {
+		return err
+	}
+	if this.locked.Swap(locked) == locked {
+		return nil
+	}
+	select {
+	case <-this.changes:
+	default:
+	}
+	this.changes <- locked
+	return nil
+}
+
+// readSessionLock reads the LockedHint of the login1 session of the agent,
+// set by the desktop while its screen is locked
+func readSessionLock() (bool, error) {
+	if runtime.GOOS != "linux" {
+		return false, fmt.Errorf("session lock state not supported on %s", runtime.GOOS)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	output, err := exec.CommandContext(ctx, "gdbus", "call", "--system",
+		"--dest", "org.freedesktop.login1",
+		"--object-path", "/org/freedesktop/login1/session/auto",
+		"--method", "org.freedesktop.DBus.Properties.Get",
+		"org.freedesktop.login1.Session", "LockedHint").Output()
+	if err != nil {
+		return false, fmt.Errorf("login1 session not available: %w", err)
+	}
+	return parseLockedHint(string(output))
+}
+
+// parseLockedHint parses the gdbus reply of the LockedHint property, (<true>,)
+func parseLockedHint(output string) (bool, error) {
+	switch strings.TrimSpace(output) {
+	case "(<true>,)":
+		return true, nil
+	case "(<false>,)":
+		return false, nil
+	}
+	return false, fmt.Errorf("unexpected LockedHint reply %q", strings.TrimSpace(output))
+}
@@ -0,0 +1,94 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParseLockedHint(t *testing.T) {
+	if locked, err := parseLockedHint("(<true>,)\n"); err != nil || !locked {
+		t.Fatalf("expected locked, got %v %v", locked, err)
+	}
+	if locked, err := parseLockedHint("(<false>,)"); err != nil || locked {
+		t.Fatalf("expected unlocked, got %v %v", locked, err)
+	}
+	if _, err := parseLockedHint("Error: GDBus.Error"); err == nil {
+		t.Fatal("an unexpected reply must fail")
+	}
+}
+
+func TestLockWatcherReportsChanges(t *testing.T) {
+	state := false
+	watcher := newLockWatcher(time.Hour, func() (bool, error) { return state, nil })
+	watcher.poll()
+	select {
+	case <-watcher.Changes():
+		t.Fatal("an unlocked session at start is not a change")
+	default:
+	}
+	state = true
+	watcher.poll()
+	watcher.poll()
+	if !watcher.Locked() || !<-watcher.Changes() {
+		t.Fatal("expected the lock reported once")
+	}
+	// A change not taken yet is replaced by the newer one
+	state = false
+	watcher.poll()
+	state = true
+	watcher.poll()
+	if locked := <-watcher.Changes(); !locked || len(watcher.Changes()) != 0 {
+		t.Fatal("expected only the latest state")
+	}
+
+	// Without login1 the session stays unlocked
+	failing := newLockWatcher(time.Hour, func() (bool, error) { return false, errors.New("login1 session not available") })
+	failing.start()
+	var none *lockWatcher
+	if failing.Locked() || none.Locked() || none.Changes() != nil {
+		t.Fatal("a session whose lock cannot be read must count as unlocked")
+	}
+}
+
+func TestLockedSessionPostsHeartbeats(t *testing.T) {
+	fake, s := newFakeServer(t)
+	locked := true
+	servers, sessionLock = []*server{s}, newLockWatcher(15*time.Minute, func() (bool, error) { return locked, nil })
+	t.Cleanup(func() { servers, sessionLock = nil, nil })
+	sessionLock.poll()
+
+	collectAndPost()
+	locations := fake.Locations()
+	if len(locations) != 1 || !locations[0].Heartbeat || locations[0].Latitude != 0 || locations[0].Longitude != 0 {
+		t.Fatalf("a locked session must post a heartbeat only, got %v", locations)
+	}
+	// The server expects the next post after the locked heartbeat
+	if locations[0].ReportIntervalSeconds != 900 || nextInterval() != 15*time.Minute {
+		t.Fatalf("expected the locked heartbeat announced, got %d", locations[0].ReportIntervalSeconds)
+	}
+	if registrations := fake.Registrations(); len(registrations) != 1 || registrations[0].HeartbeatOnly {
+		t.Fatalf("a locked session does not make the device heartbeat-only, got %v", registrations)
+	}
+
+	locked = false
+	sessionLock.poll()
+	if nextInterval() != postInterval() {
+		t.Fatal("an unlocked session must post at the regular interval")
+	}
+}
@@ -0,0 +1,132 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/saichler/l8myfamiliy/go/myf/webapi"
+	"github.com/saichler/l8myfamiliy/go/types/l8myfamily"
+)
+
+const (
+	// procRoute is the Linux IPv4 routing table
+	procRoute = "/proc/net/route"
+	// sysNetDir is the Linux sysfs network class
+	sysNetDir = "/sys/class/net"
+	// osRelease describes the Linux distribution
+	osRelease = "/etc/os-release"
+)
+
+// collectTelemetry returns the telemetry posted when the telemetry setting
+// is on, a field the platform cannot tell is left empty
+func collectTelemetry() *l8myfamily.Telemetry {
+	t := &l8myfamily.Telemetry{Os: runtime.GOOS}
+	if _, charging, err := getBatteryLevel(); err == nil {
+		t.Charging = charging
+	}
+	switch runtime.GOOS {
+	case "linux":
+		t.NetworkType = networkTypeLinux(procRoute, sysNetDir)
+		t.OsVersion = osVersionLinux(osRelease)
+	case "darwin":
+		t.OsVersion = osVersionMac()
+	}
+	return t
+}
+
+// networkTypeLinux returns the type of the interface of the default route
+// with the lowest metric. Virtual interfaces, e.g. a VPN tunnel, do not tell
+// the network type and are skipped, "" is returned when only they route.
+func networkTypeLinux(routeFile, netDir string) string {
+	f, err := os.Open(routeFile)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+
+	found := false
+	best, bestMetric := "", -1
+	scanner := bufio.NewScanner(f)
+	// Iface Destination Gateway Flags RefCnt Use Metric Mask ...
+	scanner.Scan()
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 8 || fields[1] != "00000000" || fields[7] != "00000000" {
+			continue
+		}
+		found = true
+		metric, err := strconv.Atoi(fields[6])
+		if err != nil {
+			continue
+		}
+		iface := fields[0]
+		if _, err := os.Stat(filepath.Join(netDir, iface, "device")); err != nil {
+			continue
+		}
+		if bestMetric < 0 || metric < bestMetric {
+			best, bestMetric = iface, metric
+		}
+	}
+	if !found {
+		return webapi.NetworkNone
+	}
+	if best == "" {
+		return ""
+	}
+	if _, err := os.Stat(filepath.Join(netDir, best, "wireless")); err == nil {
+		return webapi.NetworkWifi
+	}
+	// wwan0, wwp0s20f0u6 are mobile broadband modems
+	if strings.HasPrefix(best, "ww") {
+		return webapi.NetworkCellular
+	}
+	return webapi.NetworkEthernet
+}
+
+// osVersionLinux returns the PRETTY_NAME of the distribution, e.g.
+// "Ubuntu 24.04.1 LTS"
+func osVersionLinux(releaseFile string) string {
+	data, err := os.ReadFile(releaseFile)
+	if err != nil {
+		return ""
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if value, ok := strings.CutPrefix(line, "PRETTY_NAME="); ok {
+			return strings.Trim(strings.TrimSpace(value), `"`)
+		}
+	}
+	return ""
+}
+
+// osVersionMac returns the macOS product version, e.g. "14.5"
+func osVersionMac() string {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	output, err := exec.CommandContext(ctx, "sw_vers", "-productVersion").Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(output))
+}
@@ -0,0 +1,80 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/saichler/l8myfamiliy/go/myf/webapi"
+)
+
+// fakeNet writes a routing table and the sysfs entries of the interfaces,
+// an interface is physical unless its name starts with tun
+func fakeNet(t *testing.T, routes string, wireless ...string) (string, string) {
+	dir := t.TempDir()
+	routeFile := filepath.Join(dir, "route")
+	header := "Iface\tDestination\tGateway\tFlags\tRefCnt\tUse\tMetric\tMask\tMTU\tWindow\tIRTT\n"
+	if err := os.WriteFile(routeFile, []byte(header+routes), 0600); err != nil {
+		t.Fatal(err)
+	}
+	netDir := filepath.Join(dir, "net")
+	for _, iface := range []string{"eth0", "wlan0", "wwan0", "tun0"} {
+		os.MkdirAll(filepath.Join(netDir, iface), 0700)
+		if iface != "tun0" {
+			os.MkdirAll(filepath.Join(netDir, iface, "device"), 0700)
+		}
+	}
+	for _, iface := range wireless {
+		os.MkdirAll(filepath.Join(netDir, iface, "wireless"), 0700)
+	}
+	return routeFile, netDir
+}
+
+func TestNetworkTypeLinux(t *testing.T) {
+	tests := []struct {
+		name   string
+		routes string
+		want   string
+	}{
+		{"wifi", "wlan0\t00000000\t0100A8C0\t0003\t0\t0\t600\t00000000\t0\t0\t0\n", webapi.NetworkWifi},
+		{"lowest metric", "wlan0\t00000000\t0100A8C0\t0003\t0\t0\t600\t00000000\t0\t0\t0\n" +
+			"eth0\t00000000\t0100A8C0\t0003\t0\t0\t100\t00000000\t0\t0\t0\n", webapi.NetworkEthernet},
+		{"modem", "wwan0\t00000000\t00000000\t0001\t0\t0\t700\t00000000\t0\t0\t0\n", webapi.NetworkCellular},
+		{"vpn skipped", "tun0\t00000000\t00000000\t0001\t0\t0\t50\t00000000\t0\t0\t0\n" +
+			"wlan0\t00000000\t0100A8C0\t0003\t0\t0\t600\t00000000\t0\t0\t0\n", webapi.NetworkWifi},
+		{"vpn only", "tun0\t00000000\t00000000\t0001\t0\t0\t50\t00000000\t0\t0\t0\n", ""},
+		{"offline", "eth0\t0000A8C0\t00000000\t0001\t0\t0\t100\t00FFFFFF\t0\t0\t0\n", webapi.NetworkNone},
+	}
+	for _, tt := range tests {
+		routeFile, netDir := fakeNet(t, tt.routes, "wlan0")
+		if got := networkTypeLinux(routeFile, netDir); got != tt.want {
+			t.Errorf("%s: network type = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestOsVersionLinux(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "os-release")
+	os.WriteFile(file, []byte("NAME=\"Ubuntu\"\nPRETTY_NAME=\"Ubuntu 24.04.1 LTS\"\nID=ubuntu\n"), 0600)
+	if got := osVersionLinux(file); got != "Ubuntu 24.04.1 LTS" {
+		t.Fatalf("os version = %q", got)
+	}
+	if got := osVersionLinux(filepath.Join(t.TempDir(), "missing")); got != "" {
+		t.Fatalf("a missing file must give no version, got %q", got)
+	}
+}
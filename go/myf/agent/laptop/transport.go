@@ -0,0 +1,125 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/saichler/l8myfamiliy/go/myf/agent/agentcore"
+	"github.com/saichler/l8myfamiliy/go/myf/webapi"
+	"github.com/saichler/l8myfamiliy/go/types/l8myfamily"
+)
+
+// The transport values, how the locations leave the agent
+const (
+	// transportHTTP posts to the family server and its mirrors
+	transportHTTP = "http"
+	// transportNATS publishes to a subject of a NATS server, for self-hosters
+	// feeding their own event pipeline. There is no login or registration.
+	transportNATS = "nats"
+)
+
+// defaultNatsSubject is the subject the locations are published to
+const defaultNatsSubject = "myfamily.locations"
+
+// natsTimeout bounds a connect or a publish to the NATS server
+var natsTimeout = 5 * time.Second
+
+// transport delivers the locations of a server, the buffering of the failed
+// ones is in the server post path and shared by every transport
+type transport interface {
+	// connect prepares the delivery, the steps already done are skipped
+	connect() error
+	send(location *l8myfamily.Location) error
+	reachable(timeout time.Duration) bool
+	// endpoint is where the locations go, for the logs
+	endpoint() string
+}
+
+// locationServers returns the servers of the configured transport
+func locationServers() ([]*server, error) {
+	if transportKind == transportNATS {
+		s, err := newNatsServer(natsURL, natsSubject)
+		if err != nil {
+			return nil, err
+		}
+		return []*server{s}, nil
+	}
+	return newServers(website, mirrorWebsites), nil
+}
+
+// natsTransport publishes the marshaled locations to a NATS subject
+type natsTransport struct {
+	publisher *agentcore.NatsPublisher
+	subject   string
+}
+
+// newNatsServer returns the server of the NATS url, not yet connected
+func newNatsServer(natsURL, subject string) (*server, error) {
+	if !agentcore.ValidNatsSubject(subject) {
+		return nil, fmt.Errorf("invalid nats_subject %q", subject)
+	}
+	publisher, err := agentcore.NewNatsPublisher(natsURL, natsTimeout)
+	if err != nil {
+		return nil, err
+	}
+	return &server{website: publisher.String(), reauth: agentcore.NewReauthGate(reauthCooldown),
+		transport: &natsTransport{publisher: publisher, subject: subject}}, nil
+}
+
+func (this *natsTransport) connect() error {
+	return this.publisher.Connect()
+}
+
+// send publishes the location as JSON, or protobuf with use_protobuf. A
+// failed publish is tried again like a post, a rejected one is not.
+func (this *natsTransport) send(location *l8myfamily.Location) error {
+	// The key lets the consumers drop a location published twice
+	if location.IdempotencyKey == "" {
+		location.IdempotencyKey = uuid.New().String()
+	}
+	contentType := webapi.ContentTypeJSON
+	if useProtobuf {
+		contentType = webapi.ContentTypeProtobuf
+	}
+	data, err := webapi.EncodeBody(contentType, location)
+	if err != nil {
+		return fmt.Errorf("failed to marshal location: %w", err)
+	}
+	for attempt := 1; attempt <= postAttempts; attempt++ {
+		if attempt > 1 {
+			time.Sleep(time.Duration(attempt-1) * retryDelay)
+		}
+		if err = this.publisher.Publish(this.subject, data); err == nil || errors.Is(err, agentcore.ErrNatsRejected) {
+			return err
+		}
+		log.Printf("Publish attempt %d to %s failed: %v", attempt, this.endpoint(), err)
+	}
+	return err
+}
+
+// reachable connects to the server, the connect is bounded by natsTimeout
+func (this *natsTransport) reachable(timeout time.Duration) bool {
+	return this.publisher.Connect() == nil
+}
+
+func (this *natsTransport) endpoint() string {
+	return this.publisher.String() + " subject " + this.subject
+}
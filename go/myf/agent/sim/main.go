@@ -0,0 +1,162 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Command sim simulates family devices for demos and load tests. It
+// registers virtual devices on a server and posts random walk locations
+// inside a region, through the same endpoints as the agents, until
+// interrupted or the duration ends.
+//
+//	sim -website https://family.example.com:9092 -user demo -devices 50 \
+//	    -region 40.70,-74.02,40.80,-73.93 -interval 5s
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/saichler/l8myfamiliy/go/myf/agent/agentcore"
+	"github.com/saichler/l8myfamiliy/go/myf/geo"
+)
+
+func main() {
+	website := flag.String("website", "", "server URL, e.g. https://localhost:9092")
+	user := flag.String("user", "", "user the devices are registered for, its family")
+	pass := flag.String("pass", os.Getenv("L8MF_SIM_PASS"), "password of the user, default $L8MF_SIM_PASS")
+	devices := flag.Int("devices", 10, "number of virtual devices")
+	prefix := flag.String("prefix", "sim", "device id prefix, the ids are <prefix>-<n>")
+	regionFlag := flag.String("region", "40.70,-74.02,40.80,-73.93", "region the devices walk in: minLat,minLon,maxLat,maxLon")
+	interval := flag.Duration("interval", 10*time.Second, "post interval of every device")
+	stepMeters := flag.Float64("step", 50, "longest move of a device between two posts, in meters")
+	duration := flag.Duration("duration", 0, "how long to run, 0 runs until interrupted")
+	duplicates := flag.Float64("duplicates", 0, "share of the posts sent twice with the same idempotency key, 0 to 1")
+	insecure := flag.Bool("insecure", false, "skip the server certificate validation")
+	authPath := flag.String("auth-path", agentcore.DefaultAuthPath, "path of the login endpoint, for a gateway that mounts it elsewhere")
+	seed := flag.Int64("seed", time.Now().UnixNano(), "random seed of the walks")
+	flag.Parse()
+
+	if *website == "" || *user == "" || *pass == "" {
+		log.Fatalf("-website, -user and -pass are required")
+	}
+	region, err := parseRegion(*regionFlag)
+	if err != nil {
+		log.Fatalf("Invalid -region: %v", err)
+	}
+	if *devices <= 0 || *interval <= 0 {
+		log.Fatalf("-devices and -interval must be positive")
+	}
+
+	sim := newSimulator(*website, *user, *pass, region, *insecure)
+	sim.duplicates = *duplicates
+	sim.interval = *interval
+	if sim.paths, err = agentcore.NewAuthPaths(*authPath, ""); err != nil {
+		log.Fatalf("Invalid -auth-path: %v", err)
+	}
+	if err := sim.authenticate(); err != nil {
+		log.Fatalf("Failed to authenticate: %v", err)
+	}
+	walkers := make([]*device, 0, *devices)
+	for n := 1; n <= *devices; n++ {
+		d := sim.newDevice(*prefix, n, *seed)
+		if err := sim.register(d); err != nil {
+			log.Fatalf("Failed to register %s: %v", d.id, err)
+		}
+		walkers = append(walkers, d)
+	}
+	log.Printf("Registered %d devices, posting every %s (%.1f posts/s)", len(walkers), *interval,
+		float64(len(walkers))/interval.Seconds())
+
+	stop := make(chan struct{})
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		if *duration > 0 {
+			select {
+			case <-sigChan:
+			case <-time.After(*duration):
+			}
+		} else {
+			<-sigChan
+		}
+		close(stop)
+	}()
+
+	started := time.Now()
+	sim.run(walkers, *interval, *stepMeters, stop)
+	elapsed := time.Since(started).Round(time.Second)
+	log.Printf("Posted %d locations in %s, %d failed, %d sent twice", sim.posted.Load(), elapsed,
+		sim.failed.Load(), sim.reSent.Load())
+}
+
+// run walks and posts every device each interval until stop is closed, the
+// devices start spread over the first interval
+func (this *simulator) run(walkers []*device, interval time.Duration, stepMeters float64, stop chan struct{}) {
+	wg := sync.WaitGroup{}
+	for i, d := range walkers {
+		wg.Add(1)
+		go func(d *device, offset time.Duration) {
+			defer wg.Done()
+			select {
+			case <-time.After(offset):
+			case <-stop:
+				return
+			}
+			ticker := time.NewTicker(interval)
+			defer ticker.Stop()
+			for {
+				this.postLocation(d, time.Now())
+				d.step(this.region, stepMeters)
+				select {
+				case <-ticker.C:
+				case <-stop:
+					return
+				}
+			}
+		}(d, interval*time.Duration(i)/time.Duration(len(walkers)))
+	}
+	wg.Wait()
+}
+
+// parseRegion reads minLat,minLon,maxLat,maxLon, a region crossing the
+// antimeridian is not supported
+func parseRegion(value string) (*geo.BBox, error) {
+	parts := strings.Split(value, ",")
+	if len(parts) != 4 {
+		return nil, fmt.Errorf("expected minLat,minLon,maxLat,maxLon, got %q", value)
+	}
+	values := make([]float64, 4)
+	for i, part := range parts {
+		f, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid coordinate %q", part)
+		}
+		values[i] = f
+	}
+	box := &geo.BBox{MinLat: values[0], MinLon: values[1], MaxLat: values[2], MaxLon: values[3]}
+	if err := box.Validate(); err != nil {
+		return nil, err
+	}
+	if box.MinLon > box.MaxLon {
+		return nil, fmt.Errorf("a region crossing the antimeridian is not supported")
+	}
+	return box, nil
+}
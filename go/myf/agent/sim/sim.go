@@ -0,0 +1,213 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/saichler/l8myfamiliy/go/myf/agent/agentcore"
+	"github.com/saichler/l8myfamiliy/go/myf/geo"
+	"github.com/saichler/l8myfamiliy/go/myf/webapi"
+	"github.com/saichler/l8myfamiliy/go/types/l8myfamily"
+)
+
+// metersPerDegree is the length of a degree of latitude
+const metersPerDegree = 111320.0
+
+// simulator registers virtual devices on a server and posts their locations
+// through the same endpoints, headers and signatures as the real agents
+type simulator struct {
+	website string
+	user    string
+	pass    string
+	client  *http.Client
+	scheme  agentcore.AuthScheme
+	paths   agentcore.AuthPaths
+	token   string
+	region  *geo.BBox
+	// duplicates is the share of the posts sent twice with the same
+	// idempotency key, to exercise the server dedup
+	duplicates float64
+	// interval is the post interval of every device, announced to the server
+	interval time.Duration
+
+	posted atomic.Int64
+	failed atomic.Int64
+	reSent atomic.Int64
+}
+
+// device is a virtual device walking randomly inside the region
+type device struct {
+	id         string
+	name       string
+	signingKey string
+	lat        float64
+	lon        float64
+	heading    float64
+	rnd        *rand.Rand
+}
+
+func newSimulator(website, user, pass string, region *geo.BBox, insecure bool) *simulator {
+	client := &http.Client{Timeout: 10 * time.Second}
+	if insecure {
+		client.Transport = &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}}
+	}
+	return &simulator{website: website, user: user, pass: pass, client: client,
+		scheme: agentcore.AuthScheme{Kind: agentcore.AuthBearer}, paths: agentcore.DefaultAuthPaths(), region: region}
+}
+
+// authenticate gets the token of the simulator user, a user with two factor
+// authentication cannot be simulated
+func (this *simulator) authenticate() error {
+	data, _ := json.Marshal(map[string]string{"user": this.user, "pass": this.pass})
+	resp, err := this.client.Post(this.paths.AuthURL(this.website), webapi.ContentTypeJSON, bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("auth request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read auth response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("authentication failed: %s", strings.TrimSpace(string(body)))
+	}
+	var auth struct {
+		Token    string `json:"token"`
+		NeedTfa  bool   `json:"needTfa"`
+		SetupTfa bool   `json:"setupTfa"`
+	}
+	if err := json.Unmarshal(body, &auth); err != nil {
+		// A plain token, as the laptop agent accepts
+		auth.Token = strings.TrimSpace(string(body))
+	}
+	if auth.NeedTfa || auth.SetupTfa {
+		return fmt.Errorf("user %s has two factor authentication, use a user without it", this.user)
+	}
+	if auth.Token == "" {
+		return fmt.Errorf("authentication failed: empty token")
+	}
+	this.token = auth.Token
+	return nil
+}
+
+// newDevice places a device at a random position of the region, the ids
+// are stable so a rerun reuses the devices of the previous run
+func (this *simulator) newDevice(prefix string, n int, seed int64) *device {
+	rnd := rand.New(rand.NewSource(seed + int64(n)))
+	return &device{
+		id:         fmt.Sprintf("%s-%d", prefix, n),
+		name:       fmt.Sprintf("%s device %d", prefix, n),
+		signingKey: webapi.NewSigningKey(),
+		lat:        this.region.MinLat + rnd.Float64()*(this.region.MaxLat-this.region.MinLat),
+		lon:        this.region.MinLon + rnd.Float64()*(this.region.MaxLon-this.region.MinLon),
+		heading:    rnd.Float64() * 2 * math.Pi,
+		rnd:        rnd,
+	}
+}
+
+// register registers the device in the family of the user with its signing key
+func (this *simulator) register(d *device) error {
+	key := uuid.New().String()
+	data, err := agentcore.RegistrationBody(d.id, this.user, d.name, key, d.signingKey,
+		agentcore.ReportInterval(this.interval, nil, nil), false)
+	if err != nil {
+		return err
+	}
+	_, err = this.post(agentcore.EndpointURL(this.website, "device"), data, key)
+	return err
+}
+
+// step moves the device up to maxMeters, mostly ahead with a small turn, and
+// turns it back at the edge of the region
+func (d *device) step(region *geo.BBox, maxMeters float64) {
+	d.heading += (d.rnd.Float64() - 0.5) * math.Pi / 4
+	meters := maxMeters * (0.2 + 0.8*d.rnd.Float64())
+	for i := 0; i < 2; i++ {
+		lat := d.lat + meters*math.Cos(d.heading)/metersPerDegree
+		lon := d.lon + meters*math.Sin(d.heading)/(metersPerDegree*math.Cos(d.lat*math.Pi/180))
+		if region.Contains(lat, lon) {
+			d.lat, d.lon = lat, lon
+			return
+		}
+		d.heading += math.Pi
+	}
+}
+
+// postLocation posts the current position of the device, signed, and sends
+// a share of the posts a second time with the same idempotency key
+func (this *simulator) postLocation(d *device, now time.Time) {
+	location := &l8myfamily.Location{
+		DeviceId:       d.id,
+		Latitude:       float32(d.lat),
+		Longitude:      float32(d.lon),
+		Accuracy:       float32(5 + d.rnd.Intn(20)),
+		Timestamp:      now.UnixMilli(),
+		IdempotencyKey: uuid.New().String(),
+	}
+	webapi.SignLocation(location, d.signingKey, now)
+	data, err := webapi.EncodeBody(webapi.ContentTypeJSON, location)
+	if err != nil {
+		this.failed.Add(1)
+		return
+	}
+	endpoint := agentcore.EndpointURL(this.website, "location")
+	if _, err := this.post(endpoint, data, location.IdempotencyKey); err != nil {
+		this.failed.Add(1)
+		fmt.Println("[sim] ", d.id, ": ", err.Error())
+		return
+	}
+	this.posted.Add(1)
+	if d.rnd.Float64() < this.duplicates {
+		if _, err := this.post(endpoint, data, location.IdempotencyKey); err == nil {
+			this.reSent.Add(1)
+		}
+	}
+}
+
+func (this *simulator) post(endpoint string, data []byte, key string) ([]byte, error) {
+	req, err := http.NewRequest(http.MethodPost, endpoint, bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", webapi.ContentTypeJSON)
+	req.Header.Set("Idempotency-Key", key)
+	this.scheme.Apply(req, this.token)
+	resp, err := this.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("%s returned status %d: %s", endpoint, resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return body, nil
+}
@@ -0,0 +1,113 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/saichler/l8myfamiliy/go/myf/webapi"
+	"github.com/saichler/l8myfamiliy/go/types/l8myfamily"
+)
+
+func TestSimulatorRegistersAndPostsSigned(t *testing.T) {
+	mtx := sync.Mutex{}
+	keys := make(map[string]string)
+	postKeys := make(map[string]int)
+	verified := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mtx.Lock()
+		defer mtx.Unlock()
+		if r.URL.Path != "/auth" && r.Header.Get("Authorization") != "Bearer t" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/auth":
+			w.Write([]byte(`{"token":"t"}`))
+		case "/my-family/device":
+			var device map[string]string
+			json.NewDecoder(r.Body).Decode(&device)
+			if device["familyId"] != "demo" {
+				t.Errorf("unexpected family %s", device["familyId"])
+			}
+			keys[device["id"]] = device["signingKey"]
+		case "/my-family/location":
+			location := &l8myfamily.Location{}
+			webapi.DecodeBody(r, location)
+			if webapi.VerifyLocation(location, keys[location.DeviceId]) {
+				verified++
+			}
+			postKeys[r.Header.Get("Idempotency-Key")]++
+		}
+	}))
+	defer srv.Close()
+
+	region, _ := parseRegion("40.70,-74.02,40.80,-73.93")
+	sim := newSimulator(srv.URL, "demo", "secret", region, false)
+	sim.duplicates = 1
+	if err := sim.authenticate(); err != nil {
+		t.Fatal(err)
+	}
+	walkers := []*device{sim.newDevice("sim", 1, 7), sim.newDevice("sim", 2, 7)}
+	for _, d := range walkers {
+		if err := sim.register(d); err != nil {
+			t.Fatal(err)
+		}
+	}
+	stop := make(chan struct{})
+	time.AfterFunc(50*time.Millisecond, func() { close(stop) })
+	sim.run(walkers, 20*time.Millisecond, 50, stop)
+
+	mtx.Lock()
+	defer mtx.Unlock()
+	if len(keys) != 2 || sim.posted.Load() < 2 || sim.failed.Load() != 0 {
+		t.Fatalf("expected 2 registered devices posting, got %d devices %d posts %d failed", len(keys), sim.posted.Load(), sim.failed.Load())
+	}
+	if verified != int(sim.posted.Load()+sim.reSent.Load()) {
+		t.Fatalf("every post must be signed with the device key, %d of %d", verified, sim.posted.Load()+sim.reSent.Load())
+	}
+	for key, n := range postKeys {
+		if n != 2 {
+			t.Fatalf("with duplicates 1 every post must be sent twice, key %s sent %d times", key, n)
+		}
+	}
+}
+
+func TestDeviceWalkStaysInRegion(t *testing.T) {
+	region, err := parseRegion("40.70,-74.02,40.71,-74.01")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sim := &simulator{region: region}
+	d := sim.newDevice("sim", 1, 1)
+	for i := 0; i < 1000; i++ {
+		d.step(region, 200)
+		if !region.Contains(d.lat, d.lon) {
+			t.Fatalf("step %d left the region at %f,%f", i, d.lat, d.lon)
+		}
+	}
+	if _, err := parseRegion("1,170,2,-170"); err == nil {
+		t.Fatal("a region crossing the antimeridian must be rejected")
+	}
+	if _, err := parseRegion("1,2,3"); err == nil {
+		t.Fatal("a region needs 4 coordinates")
+	}
+}
@@ -0,0 +1,93 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package audit keeps an append only log of security and safety relevant events,
+// one JSON record per line.
+package audit
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/saichler/l8myfamiliy/go/myf/config"
+)
+
+const filename = "audit.log"
+
+type Record struct {
+	Time     int64  `json:"time"`
+	Action   string `json:"action"`
+	Actor    string `json:"actor,omitempty"`
+	DeviceId string `json:"deviceId,omitempty"`
+	FamilyId string `json:"familyId,omitempty"`
+	Detail   string `json:"detail,omitempty"`
+}
+
+var mtx = &sync.Mutex{}
+
+// Log appends a record to the audit log, stamping it with the current time
+func Log(action, actor, deviceId, familyId, detail string) {
+	Append(&Record{Time: time.Now().UnixMilli(), Action: action, Actor: actor,
+		DeviceId: deviceId, FamilyId: familyId, Detail: detail})
+}
+
+// Append writes the record to the audit log, failures are printed but never
+// interrupt the calling flow
+func Append(record *Record) {
+	data, err := json.Marshal(record)
+	if err != nil {
+		fmt.Println("[Audit] failed to marshal record: ", err.Error())
+		return
+	}
+	mtx.Lock()
+	defer mtx.Unlock()
+	dir := config.Get().DataDir
+	os.MkdirAll(dir, 0777)
+	file, err := os.OpenFile(filepath.Join(dir, filename), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		fmt.Println("[Audit] failed to open log: ", err.Error())
+		return
+	}
+	defer file.Close()
+	file.Write(append(data, '\n'))
+}
+
+// Read returns all the records in the audit log, oldest first
+func Read() ([]*Record, error) {
+	mtx.Lock()
+	defer mtx.Unlock()
+	data, err := os.ReadFile(filepath.Join(config.Get().DataDir, filename))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	records := make([]*Record, 0)
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	for decoder.More() {
+		record := &Record{}
+		if err := decoder.Decode(record); err != nil {
+			return records, err
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
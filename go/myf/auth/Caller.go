@@ -0,0 +1,93 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package auth resolves the authenticated identity of the raw http endpoints
+// served next to the family services.
+package auth
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/saichler/l8myfamiliy/go/myf/config"
+	"github.com/saichler/l8types/go/ifs"
+)
+
+// Caller is the authenticated user of a request.
+// Agents register their devices with the user as the family id, so the
+// user is also the caller family, unless it is a member login of a family.
+type Caller struct {
+	User     string
+	FamilyId string
+	// MemberId is the family member of a member login, empty for the
+	// family account
+	MemberId string
+	Admin    bool
+}
+
+// ValidateToken resolves a bearer token to its user, it is a variable so
+// tests can run the handlers without a security provider.
+var ValidateToken = func(token string, vnic ifs.IVNic) (string, bool) {
+	if vnic == nil || vnic.Resources().Security() == nil {
+		return "", false
+	}
+	return vnic.Resources().Security().ValidateToken(token, vnic)
+}
+
+// FromRequest extracts and validates the bearer token of the request
+func FromRequest(r *http.Request, vnic ifs.IVNic) (*Caller, error) {
+	header := r.Header.Get("Authorization")
+	token := strings.TrimSpace(strings.TrimPrefix(header, "Bearer "))
+	if token == "" || token == header {
+		return nil, fmt.Errorf("missing bearer token")
+	}
+	user, ok := ValidateToken(token, vnic)
+	if !ok || user == "" {
+		return nil, fmt.Errorf("invalid bearer token")
+	}
+	return CallerFor(user), nil
+}
+
+// CallerFor returns the caller of the user in its primary family, e.g. the
+// owner a device list is filtered by
+func CallerFor(user string) *Caller {
+	familyId, memberId := config.MemberLoginFor(user)
+	return &Caller{User: user, FamilyId: familyId, MemberId: memberId, Admin: config.IsAdmin(user)}
+}
+
+// Authenticate returns the caller or writes a 401 and returns nil
+func Authenticate(w http.ResponseWriter, r *http.Request, vnic ifs.IVNic) *Caller {
+	caller, err := FromRequest(r, vnic)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return nil
+	}
+	return caller
+}
+
+// AuthenticateAdmin returns the caller if it is an admin, otherwise writes
+// a 401 or 403 and returns nil
+func AuthenticateAdmin(w http.ResponseWriter, r *http.Request, vnic ifs.IVNic) *Caller {
+	caller := Authenticate(w, r, vnic)
+	if caller == nil {
+		return nil
+	}
+	if !caller.Admin {
+		http.Error(w, "admin access required", http.StatusForbidden)
+		return nil
+	}
+	return caller
+}
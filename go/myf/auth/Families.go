@@ -0,0 +1,108 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package auth
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/saichler/l8myfamiliy/go/myf/config"
+	"github.com/saichler/l8types/go/ifs"
+)
+
+// The roles of a caller in a family
+const (
+	RoleOwner  = "owner"
+	RoleAdmin  = "admin"
+	RoleMember = "member"
+)
+
+// ErrFamilyDenied is returned for a family the caller may not access
+var ErrFamilyDenied = errors.New("the caller may not access the family")
+
+// FamilyAccess is a family the caller may access and its role in it
+type FamilyAccess struct {
+	FamilyId string `json:"familyId"`
+	MemberId string `json:"memberId,omitempty"`
+	Role     string `json:"role"`
+	// Primary is the family of the login, selected by default
+	Primary bool `json:"primary,omitempty"`
+}
+
+// FamilyAccessList is the response of the families endpoint
+type FamilyAccessList struct {
+	Families []*FamilyAccess `json:"families"`
+}
+
+// role returns the role of the member in the family, the family account is
+// its owner
+func role(familyId, memberId string) string {
+	switch {
+	case memberId == "":
+		return RoleOwner
+	case config.IsFamilyAdmin(familyId, memberId):
+		return RoleAdmin
+	}
+	return RoleMember
+}
+
+// Families returns the families the caller may access, its primary family
+// first. A family listed twice is returned once, as the first listed member.
+func (this *Caller) Families() []*FamilyAccess {
+	familyId, memberId := config.MemberLoginFor(this.User)
+	families := []*FamilyAccess{{FamilyId: familyId, MemberId: memberId, Role: role(familyId, memberId), Primary: true}}
+	seen := map[string]bool{familyId: true}
+	for _, login := range config.FamilyAccessFor(this.User) {
+		if seen[login.FamilyId] {
+			continue
+		}
+		seen[login.FamilyId] = true
+		families = append(families, &FamilyAccess{FamilyId: login.FamilyId, MemberId: login.MemberId,
+			Role: role(login.FamilyId, login.MemberId)})
+	}
+	return families
+}
+
+// InFamily returns the caller as its member of the family, the caller itself
+// for an empty familyId or its own family, and ErrFamilyDenied when the
+// caller may not access the family
+func (this *Caller) InFamily(familyId string) (*Caller, error) {
+	if familyId == "" || familyId == this.FamilyId {
+		return this, nil
+	}
+	for _, family := range this.Families() {
+		if family.FamilyId == familyId {
+			return &Caller{User: this.User, FamilyId: family.FamilyId, MemberId: family.MemberId, Admin: this.Admin}, nil
+		}
+	}
+	return nil, ErrFamilyDenied
+}
+
+// AuthenticateFamily returns the caller in the family of the familyId
+// parameter, in its primary family without it, or writes a 401 or 403 and
+// returns nil
+func AuthenticateFamily(w http.ResponseWriter, r *http.Request, vnic ifs.IVNic) *Caller {
+	caller := Authenticate(w, r, vnic)
+	if caller == nil {
+		return nil
+	}
+	inFamily, err := caller.InFamily(r.URL.Query().Get("familyId"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return nil
+	}
+	return inFamily
+}
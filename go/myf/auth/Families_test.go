@@ -0,0 +1,97 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package auth
+
+import (
+	"errors"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/saichler/l8myfamiliy/go/myf/config"
+	"github.com/saichler/l8types/go/ifs"
+)
+
+// setFamilies configures dad as a member login of the smith family, where
+// he is an admin, and as a caregiver member of the jones family
+func setFamilies(t *testing.T) {
+	cfg := config.Default()
+	cfg.MemberLogins = map[string]*config.MemberLogin{"dad": {FamilyId: "smith", MemberId: "dad"}}
+	cfg.FamilyAccess = map[string][]*config.MemberLogin{
+		"dad":   {{FamilyId: "jones", MemberId: "grandpa"}, {FamilyId: "smith", MemberId: "other"}},
+		"jones": {{FamilyId: "smith", MemberId: "neighbour"}},
+	}
+	cfg.Families = map[string]*config.FamilyConfig{"smith": {Admins: []string{"dad"}}}
+	if err := cfg.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	config.Set(cfg)
+	t.Cleanup(func() { config.Set(config.Default()) })
+}
+
+func TestCallerFamilies(t *testing.T) {
+	setFamilies(t)
+	for user, want := range map[string][]FamilyAccess{
+		"dad": {{FamilyId: "smith", MemberId: "dad", Role: RoleAdmin, Primary: true},
+			{FamilyId: "jones", MemberId: "grandpa", Role: RoleMember}},
+		"jones": {{FamilyId: "jones", Role: RoleOwner, Primary: true},
+			{FamilyId: "smith", MemberId: "neighbour", Role: RoleMember}},
+		"miller": {{FamilyId: "miller", Role: RoleOwner, Primary: true}},
+	} {
+		caller := &Caller{User: user}
+		got := caller.Families()
+		if len(got) != len(want) {
+			t.Fatalf("%s: expected %v, got %d families", user, want, len(got))
+		}
+		for i := range want {
+			if *got[i] != want[i] {
+				t.Fatalf("%s: expected %v, got %v", user, want[i], *got[i])
+			}
+		}
+	}
+}
+
+func TestCallerInFamily(t *testing.T) {
+	setFamilies(t)
+	dad := &Caller{User: "dad", FamilyId: "smith", MemberId: "dad"}
+	if caller, err := dad.InFamily(""); err != nil || caller != dad {
+		t.Fatalf("no family must select the primary one, got %v %v", caller, err)
+	}
+	caller, err := dad.InFamily("jones")
+	if err != nil || caller.User != "dad" || caller.FamilyId != "jones" || caller.MemberId != "grandpa" {
+		t.Fatalf("expected dad as the jones member, got %v %v", caller, err)
+	}
+	if _, err := dad.InFamily("miller"); !errors.Is(err, ErrFamilyDenied) {
+		t.Fatalf("a family not granted must be denied, got %v", err)
+	}
+
+	origValidate := ValidateToken
+	ValidateToken = func(token string, vnic ifs.IVNic) (string, bool) { return token, true }
+	defer func() { ValidateToken = origValidate }()
+	r := httptest.NewRequest("GET", "/my-family/devices?familyId=miller", nil)
+	r.Header.Set("Authorization", "Bearer dad")
+	w := httptest.NewRecorder()
+	if AuthenticateFamily(w, r, nil) != nil || w.Code != 403 {
+		t.Fatalf("an unauthorized family selection must be answered 403, got %d", w.Code)
+	}
+}
+
+func TestFamilyAccessValidation(t *testing.T) {
+	cfg := config.Default()
+	cfg.FamilyAccess = map[string][]*config.MemberLogin{"dad": {{FamilyId: "jones"}}}
+	if err := cfg.Validate(); err == nil {
+		t.Fatal("a family access without a member must be rejected")
+	}
+}
@@ -0,0 +1,61 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package auth
+
+import "sync"
+
+// The service callbacks receive only the element, the action and the vnic.
+// A raw http handler binds its authenticated caller to the element it posts
+// to a service, and the callbacks of that post look it up by the element.
+// The element is handed to the local service as is, so the binding holds for
+// the posts made in this process. A post to a Layer8 service endpoint has no
+// bound caller, the rest server runs without authentication, so the callbacks
+// reject it.
+var bound sync.Map
+
+// Internal is the caller of the posts the server makes on its own behalf,
+// e.g. a location bus without a family. It may act on every family, a post
+// is unrestricted only when it is bound to it explicitly.
+var Internal = &Caller{User: "internal", Admin: true}
+
+// Bind binds the caller to the element until the returned release is called,
+// the release is usually deferred right after the bind
+func Bind(elem interface{}, caller *Caller) func() {
+	if elem == nil || caller == nil {
+		return func() {}
+	}
+	bound.Store(elem, caller)
+	return func() { bound.Delete(elem) }
+}
+
+// CallerOf returns the caller bound to the element, or nil when the element
+// was not posted by an authenticated web request
+func CallerOf(elem interface{}) *Caller {
+	if elem == nil {
+		return nil
+	}
+	caller, ok := bound.Load(elem)
+	if !ok {
+		return nil
+	}
+	return caller.(*Caller)
+}
+
+// MayAccess returns whether the caller may act on the devices of the family,
+// a nil caller is an unauthenticated post and may act on none
+func (this *Caller) MayAccess(familyId string) bool {
+	return this != nil && (this.Admin || this.FamilyId == familyId)
+}
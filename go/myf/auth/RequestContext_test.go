@@ -0,0 +1,52 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package auth
+
+import "testing"
+
+type element struct{ id string }
+
+func TestBindCaller(t *testing.T) {
+	elem := &element{id: "d1"}
+	caller := &Caller{User: "fam", FamilyId: "fam"}
+	release := Bind(elem, caller)
+	if CallerOf(elem) != caller {
+		t.Fatal("the bound caller must be found by the element")
+	}
+	if CallerOf(&element{id: "d1"}) != nil {
+		t.Fatal("another element must have no caller")
+	}
+	release()
+	if CallerOf(elem) != nil {
+		t.Fatal("the release must unbind the caller")
+	}
+}
+
+func TestCallerMayAccess(t *testing.T) {
+	var unbound *Caller
+	if unbound.MayAccess("fam") {
+		t.Fatal("a post without a caller must not access any family")
+	}
+	if !Internal.MayAccess("fam") {
+		t.Fatal("an internal post must not be restricted")
+	}
+	if !(&Caller{FamilyId: "fam"}).MayAccess("fam") || (&Caller{FamilyId: "other"}).MayAccess("fam") {
+		t.Fatal("a caller may only access its own family")
+	}
+	if !(&Caller{FamilyId: "other", Admin: true}).MayAccess("fam") {
+		t.Fatal("an admin may access any family")
+	}
+}
@@ -0,0 +1,71 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ParseVersion returns the numeric parts of an agent version, e.g. 1.4.2,
+// v1.4.2 or 1.4.2-rc1, a pre-release or build suffix is ignored
+func ParseVersion(version string) ([]int, error) {
+	trimmed := strings.TrimPrefix(strings.TrimSpace(version), "v")
+	if i := strings.IndexAny(trimmed, "-+"); i >= 0 {
+		trimmed = trimmed[:i]
+	}
+	if trimmed == "" {
+		return nil, fmt.Errorf("version %q has no number", version)
+	}
+	parts := strings.Split(trimmed, ".")
+	numbers := make([]int, len(parts))
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("version %q is not dotted numbers", version)
+		}
+		numbers[i] = n
+	}
+	return numbers, nil
+}
+
+// AgentVersionAllowed returns true if the agent version is at least the
+// configured minimum. An agent without a version, or a development build,
+// predates the version reporting and is refused once a minimum is set.
+func AgentVersionAllowed(version string) bool {
+	minimum, err := ParseVersion(Get().MinAgentVersion)
+	if err != nil {
+		return true
+	}
+	reported, err := ParseVersion(version)
+	if err != nil {
+		return false
+	}
+	for i := 0; i < len(minimum) || i < len(reported); i++ {
+		var m, r int
+		if i < len(minimum) {
+			m = minimum[i]
+		}
+		if i < len(reported) {
+			r = reported[i]
+		}
+		if r != m {
+			return r > m
+		}
+	}
+	return true
+}
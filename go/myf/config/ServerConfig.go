@@ -0,0 +1,693 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package config holds the server side configuration shared by the family services.
+// The configuration is loaded once at startup from a JSON file, a missing file
+// means all defaults.
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultFile    = "/data/my-family/config.json"
+	DefaultDataDir = "/data/my-family/"
+)
+
+// Webhook is an http endpoint that receives family events as JSON posts
+type Webhook struct {
+	Name string `json:"name,omitempty"`
+	Url  string `json:"url"`
+	// Events limits the event types sent to this webhook, empty means all events
+	Events []string `json:"events,omitempty"`
+	// Secret signs the posts to this webhook, empty posts them unsigned
+	Secret string `json:"secret,omitempty"`
+}
+
+// Retention bounds the location history kept per device, a zero value means no limit
+type Retention struct {
+	MaxAgeSeconds int64 `json:"maxAgeSeconds,omitempty"`
+	MaxPoints     int   `json:"maxPoints,omitempty"`
+}
+
+// Rollover policies of the history files of a device
+const (
+	// RolloverDaily starts a new history file per device every UTC day
+	RolloverDaily = "daily"
+	// RolloverHourly starts a new history file per device every UTC hour
+	RolloverHourly = "hourly"
+)
+
+// Compression codecs of the closed history files
+const (
+	CompressionNone = "none"
+	CompressionGzip = "gzip"
+)
+
+// HistoryStorage is the on-disk format of the location history. With a
+// Rollover each device has a history file per period, the files of the past
+// periods are closed and compressed with the Compression codec. Pruning
+// removes whole files. No rollover keeps a single plain file per device.
+type HistoryStorage struct {
+	Rollover    string `json:"rollover,omitempty"`
+	Compression string `json:"compression,omitempty"`
+}
+
+// Policies for a location posted with the id of a device that is not registered
+const (
+	// UnknownDeviceReject rejects the post with a 404 so the agent registers
+	UnknownDeviceReject = "reject"
+	// UnknownDeviceCreate registers a minimal device in the caller family
+	UnknownDeviceCreate = "create"
+)
+
+// Policies for the registration of a device that is already registered
+const (
+	// RegistrationUpsert updates the name and the agent reported state and
+	// keeps the position, last seen time, tags, visibility and avatar
+	RegistrationUpsert = "upsert"
+	// RegistrationReplace replaces the device record, only the tags, the
+	// visibility and the avatar are kept
+	RegistrationReplace = "replace"
+)
+
+// RoadSnapping is an OSRM compatible map matching service, the position of
+// vehicle devices is snapped to the nearest road. A snap further away than
+// MaxDistanceMeters is GPS jitter off the road and is dropped.
+type RoadSnapping struct {
+	Url               string  `json:"url"`
+	Profile           string  `json:"profile,omitempty"`
+	TimeoutMillis     int64   `json:"timeoutMillis,omitempty"`
+	MaxDistanceMeters float64 `json:"maxDistanceMeters,omitempty"`
+}
+
+// LocationBus subscribes the location service to a NATS subject, each
+// Location message runs through the same callbacks as a post. FamilyId is
+// the family the messages post for, like the user of a post; empty accepts
+// any registered device. Several servers with the same QueueGroup share the
+// messages. Up to MaxPending decoded messages wait for the service, beyond
+// that the subscription is not read until the service catches up.
+type LocationBus struct {
+	Url        string `json:"url"`
+	Subject    string `json:"subject"`
+	QueueGroup string `json:"queueGroup,omitempty"`
+	FamilyId   string `json:"familyId,omitempty"`
+	// Encoding of the messages, protobuf (the default) or json
+	Encoding   string `json:"encoding,omitempty"`
+	MaxPending int    `json:"maxPending,omitempty"`
+}
+
+// The encodings of the location bus messages
+const (
+	BusEncodingProtobuf = "protobuf"
+	BusEncodingJSON     = "json"
+)
+
+// AccuracyGate keeps a recent precise position of a device when a much
+// less accurate fix arrives: a fix whose accuracy radius is over MaxRatio
+// times the current one is history only until the current position is
+// StaleSeconds old
+type AccuracyGate struct {
+	MaxRatio     float64 `json:"maxRatio"`
+	StaleSeconds int64   `json:"staleSeconds"`
+}
+
+// Movement classifies a device as stationary, walking or driving from its
+// median speed (m/s) over the last WindowSeconds, so a stop at a light does
+// not flip a driving device to stationary
+type Movement struct {
+	WalkingSpeed  float64 `json:"walkingSpeed"`
+	DrivingSpeed  float64 `json:"drivingSpeed"`
+	WindowSeconds int64   `json:"windowSeconds"`
+}
+
+// FamilyConfig overrides the global settings for a single family
+type FamilyConfig struct {
+	HistoryRetention *Retention `json:"historyRetention,omitempty"`
+	// LowBatteryPercent overrides the global threshold, a negative value
+	// disables the alert for the family
+	LowBatteryPercent int `json:"lowBatteryPercent,omitempty"`
+	// OfflineGraceSeconds overrides the global offline grace
+	OfflineGraceSeconds int64 `json:"offlineGraceSeconds,omitempty"`
+	// ArchiveAfterSeconds overrides the global archive threshold, a
+	// negative value never archives the devices of the family
+	ArchiveAfterSeconds int64 `json:"archiveAfterSeconds,omitempty"`
+	// LocationsPerMinute overrides the global family location ceiling, a
+	// negative value lifts it for the family
+	LocationsPerMinute int `json:"locationsPerMinute,omitempty"`
+	// Timezone is the tz database name, e.g. America/New_York, of the family
+	// local day, UTC when unset
+	Timezone string `json:"timezone,omitempty"`
+	// Admins are the member ids that see every device of the family and may
+	// change who sees a device
+	Admins []string `json:"admins,omitempty"`
+	// Geofences are evaluated by the server on every posted position, an
+	// enter or exit event is recorded when a device crosses a boundary
+	Geofences []*Geofence `json:"geofences,omitempty"`
+	// HomeRegions are the home regions of the devices by device id, the
+	// family is notified the first time a device leaves its region each
+	// local day
+	HomeRegions map[string]*HomeRegion `json:"homeRegions,omitempty"`
+}
+
+// Geofence is a circle the server evaluates the device positions against
+type Geofence struct {
+	Name         string  `json:"name"`
+	Latitude     float64 `json:"latitude"`
+	Longitude    float64 `json:"longitude"`
+	RadiusMeters float64 `json:"radiusMeters"`
+}
+
+// DefaultHomeRegionName is the geofence name of a home region without one
+const DefaultHomeRegionName = "Home region"
+
+// HomeRegion is the region a device is expected to stay in, e.g. the
+// neighborhood. Its crossings are recorded like those of the family
+// geofences and the first exit of a local day is notified.
+type HomeRegion struct {
+	Geofence
+	Enabled bool `json:"enabled"`
+}
+
+// MemberLogin maps a login user to a member of a family, so the members of
+// a family can log in with their own credentials. A user without a member
+// login is the family account, its user is the family id.
+type MemberLogin struct {
+	FamilyId string `json:"familyId"`
+	MemberId string `json:"memberId"`
+}
+
+type ServerConfig struct {
+	DataDir  string     `json:"dataDir,omitempty"`
+	Webhooks []*Webhook `json:"webhooks,omitempty"`
+	// NotifyDedupSeconds notifies the same event type of a device at most
+	// once within the window, across all channels. Emergencies are never
+	// suppressed, 0 disables it.
+	NotifyDedupSeconds int64 `json:"notifyDedupSeconds"`
+	// AdminUsers may call the admin endpoints
+	AdminUsers []string `json:"adminUsers,omitempty"`
+
+	HistoryRetention            *Retention      `json:"historyRetention,omitempty"`
+	HistoryPruneIntervalSeconds int64           `json:"historyPruneIntervalSeconds,omitempty"`
+	HistoryStorage              *HistoryStorage `json:"historyStorage,omitempty"`
+	// HistoryMaxWindowSeconds is the longest time window of a history query
+	HistoryMaxWindowSeconds int64 `json:"historyMaxWindowSeconds,omitempty"`
+
+	// DeviceUpdateConcurrency is the number of background device update shards,
+	// each queues up to DeviceUpdateQueueSize locations. A post to a full shard
+	// waits up to DeviceUpdateQueueMillis before it is rejected with a 503.
+	// A shard batches the locations posted within DeviceUpdateFlushMillis and
+	// writes each device once per batch, 0 writes every location.
+	DeviceUpdateConcurrency int   `json:"deviceUpdateConcurrency,omitempty"`
+	DeviceUpdateQueueSize   int   `json:"deviceUpdateQueueSize,omitempty"`
+	DeviceUpdateQueueMillis int64 `json:"deviceUpdateQueueMillis,omitempty"`
+	DeviceUpdateFlushMillis int64 `json:"deviceUpdateFlushMillis"`
+
+	// DeviceCacheSize is the number of devices kept in the in-memory LRU cache
+	// in front of the device store, 0 disables the cache. A cached device is
+	// re-read from disk after DeviceCacheTTLSeconds.
+	DeviceCacheSize       int   `json:"deviceCacheSize"`
+	DeviceCacheTTLSeconds int64 `json:"deviceCacheTTLSeconds,omitempty"`
+
+	// OnlineWindowSeconds is how long after its last location a device is
+	// considered online
+	OnlineWindowSeconds int64 `json:"onlineWindowSeconds,omitempty"`
+	// OfflineGraceSeconds is how long after its last location a device is
+	// marked offline and the family is notified, a device between the online
+	// window and the grace only missed a post or two
+	OfflineGraceSeconds int64 `json:"offlineGraceSeconds,omitempty"`
+	// ArchiveAfterSeconds archives a device that neither posted nor changed
+	// for this long, 0 never archives
+	ArchiveAfterSeconds int64 `json:"archiveAfterSeconds,omitempty"`
+
+	// Compression gzips the raw list responses for clients that accept it
+	Compression bool `json:"compression"`
+	// Diag serves the unauthenticated TLS diagnostics, the certificate
+	// subject, expiry and the TLS versions
+	Diag bool `json:"diag"`
+	// Export serves the family data export, every device followed by its
+	// location history as NDJSON
+	Export bool `json:"export"`
+	// StreamReplayEvents is how many recent device updates of a family the
+	// device stream keeps to replay to a reconnecting client, 0 disables the
+	// stream
+	StreamReplayEvents int `json:"streamReplayEvents"`
+	// StreamRetryMillis is the reconnect delay the device stream asks its
+	// clients to wait after a dropped connection
+	StreamRetryMillis int64 `json:"streamRetryMillis"`
+	// MinAgentVersion refuses the registrations and the posts of agents older
+	// than it, e.g. 1.4.0, with a please upgrade error. Empty disables it.
+	MinAgentVersion string `json:"minAgentVersion,omitempty"`
+	// MaxBodyBytes caps the request body of the raw POST endpoints, a larger
+	// body is answered 413. 0 disables it.
+	MaxBodyBytes int64 `json:"maxBodyBytes"`
+	// LogUnknownFields logs the fields of a post the server does not know and
+	// ignores, e.g. of an agent with a newer schema, a debug aid
+	LogUnknownFields bool `json:"logUnknownFields,omitempty"`
+	// StrictJSONNames rejects JSON bodies of the raw endpoints that use the
+	// proto field names, e.g. device_id, instead of the canonical names
+	StrictJSONNames bool `json:"strictJsonNames,omitempty"`
+
+	// ServiceArea is the service area of the family and location services,
+	// it is part of every service URL, e.g. /my-family/53/Location
+	ServiceArea int `json:"serviceArea"`
+
+	// IdempotencyWindowSeconds is how long a post idempotency key is remembered
+	IdempotencyWindowSeconds int64 `json:"idempotencyWindowSeconds,omitempty"`
+
+	// RegistrationCooldownSeconds rejects the registration of a new device id
+	// with the name of a device the family registered within the window, an
+	// agent that loses its device id would otherwise pile up ghost devices.
+	// 0 disables it.
+	RegistrationCooldownSeconds int64 `json:"registrationCooldownSeconds"`
+
+	// LowBatteryPercent alerts the family once when a device battery drops to
+	// or below it, 0 disables it
+	LowBatteryPercent int `json:"lowBatteryPercent"`
+
+	// FamilyLocationsPerMinute caps the combined location posts of a family,
+	// shared equally by its posting devices. Emergencies are never capped,
+	// 0 disables it.
+	FamilyLocationsPerMinute int `json:"familyLocationsPerMinute"`
+
+	// UnknownDevicePolicy is UnknownDeviceReject (the default) or
+	// UnknownDeviceCreate
+	UnknownDevicePolicy string `json:"unknownDevicePolicy,omitempty"`
+
+	// RegistrationPolicy is RegistrationUpsert (the default) or
+	// RegistrationReplace
+	RegistrationPolicy string `json:"registrationPolicy,omitempty"`
+
+	// ReplayWindowSeconds is how long the nonce of a signed location post is
+	// remembered, a signed post older than the window is rejected
+	ReplayWindowSeconds int64 `json:"replayWindowSeconds,omitempty"`
+
+	// RoadSnapping enables snapping vehicle positions to roads, nil disables it
+	RoadSnapping *RoadSnapping `json:"roadSnapping,omitempty"`
+	// LocationBus, when set, also takes the locations from a NATS subject
+	LocationBus *LocationBus `json:"locationBus,omitempty"`
+	// AccuracyGate keeps precise positions from being overwritten by coarse
+	// fixes, null disables it
+	AccuracyGate *AccuracyGate `json:"accuracyGate"`
+	// Movement sets the movement state of the devices, null disables it
+	Movement *Movement `json:"movement"`
+
+	Families map[string]*FamilyConfig `json:"families,omitempty"`
+	// MemberLogins maps login users to family members, by user
+	MemberLogins map[string]*MemberLogin `json:"memberLogins,omitempty"`
+	// FamilyAccess are the further families a login user is a member of, by
+	// user, e.g. of a caregiver. The family of the login is its primary one.
+	FamilyAccess map[string][]*MemberLogin `json:"familyAccess,omitempty"`
+}
+
+var (
+	mtx     = &sync.RWMutex{}
+	current = Default()
+)
+
+// Default returns the configuration used when no config file exist
+func Default() *ServerConfig {
+	return &ServerConfig{
+		DataDir:                     DefaultDataDir,
+		HistoryRetention:            &Retention{MaxAgeSeconds: 30 * 24 * 3600},
+		HistoryPruneIntervalSeconds: 3600,
+		HistoryMaxWindowSeconds:     31 * 24 * 3600,
+		DeviceUpdateConcurrency:     8,
+		DeviceUpdateQueueSize:       256,
+		DeviceUpdateQueueMillis:     2000,
+		DeviceUpdateFlushMillis:     500,
+		IdempotencyWindowSeconds:    300,
+		RegistrationCooldownSeconds: 600,
+		LowBatteryPercent:           15,
+		ReplayWindowSeconds:         300,
+		ServiceArea:                 53,
+		Compression:                 true,
+		Diag:                        true,
+		Export:                      true,
+		StreamReplayEvents:          256,
+		StreamRetryMillis:           3000,
+		OnlineWindowSeconds:         300,
+		OfflineGraceSeconds:         900,
+		DeviceCacheSize:             1024,
+		DeviceCacheTTLSeconds:       300,
+		AccuracyGate:                &AccuracyGate{MaxRatio: 5, StaleSeconds: 300},
+		NotifyDedupSeconds:          300,
+		MaxBodyBytes:                64 << 10,
+		Movement:                    &Movement{WalkingSpeed: 0.5, DrivingSpeed: 7, WindowSeconds: 120},
+	}
+}
+
+// Load reads the configuration from filename, a missing file keeps the defaults
+func Load(filename string) error {
+	cfg := Default()
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		if os.IsNotExist(err) {
+			Set(cfg)
+			return nil
+		}
+		return fmt.Errorf("failed to read config: %w", err)
+	}
+	if err := json.Unmarshal(data, cfg); err != nil {
+		return fmt.Errorf("failed to parse config: %w", err)
+	}
+	if err := cfg.Validate(); err != nil {
+		return fmt.Errorf("invalid config %s: %w", filename, err)
+	}
+	Set(cfg)
+	return nil
+}
+
+// Validate checks the settings a service cannot start without
+func (this *ServerConfig) Validate() error {
+	switch this.UnknownDevicePolicy {
+	case "", UnknownDeviceReject, UnknownDeviceCreate:
+	default:
+		return fmt.Errorf("unknownDevicePolicy %q is neither %s nor %s", this.UnknownDevicePolicy,
+			UnknownDeviceReject, UnknownDeviceCreate)
+	}
+	switch this.RegistrationPolicy {
+	case "", RegistrationUpsert, RegistrationReplace:
+	default:
+		return fmt.Errorf("registrationPolicy %q is neither %s nor %s", this.RegistrationPolicy,
+			RegistrationUpsert, RegistrationReplace)
+	}
+	for familyId, family := range this.Families {
+		if family == nil {
+			continue
+		}
+		if family.Timezone != "" {
+			if _, err := time.LoadLocation(family.Timezone); err != nil {
+				return fmt.Errorf("family %s timezone: %w", familyId, err)
+			}
+		}
+		for deviceId, region := range family.HomeRegions {
+			if region == nil || region.RadiusMeters <= 0 ||
+				region.Latitude < -90 || region.Latitude > 90 || region.Longitude < -180 || region.Longitude > 180 {
+				return fmt.Errorf("family %s home region of %s needs a valid center and a positive radius", familyId, deviceId)
+			}
+		}
+		for _, fence := range family.Geofences {
+			if fence == nil || fence.Name == "" || fence.RadiusMeters <= 0 ||
+				fence.Latitude < -90 || fence.Latitude > 90 || fence.Longitude < -180 || fence.Longitude > 180 {
+				return fmt.Errorf("family %s geofences need a name, a valid center and a positive radius", familyId)
+			}
+		}
+	}
+	if this.AccuracyGate != nil && (this.AccuracyGate.MaxRatio < 1 || this.AccuracyGate.StaleSeconds <= 0) {
+		return fmt.Errorf("accuracyGate needs a maxRatio of at least 1 and a positive staleSeconds")
+	}
+	if this.Movement != nil && (this.Movement.WalkingSpeed <= 0 || this.Movement.DrivingSpeed <= this.Movement.WalkingSpeed ||
+		this.Movement.WindowSeconds <= 0) {
+		return fmt.Errorf("movement needs a positive walkingSpeed, a higher drivingSpeed and a positive windowSeconds")
+	}
+	if storage := this.HistoryStorage; storage != nil {
+		switch storage.Rollover {
+		case "", RolloverDaily, RolloverHourly:
+		default:
+			return fmt.Errorf("historyStorage rollover %q is neither %s nor %s", storage.Rollover, RolloverDaily, RolloverHourly)
+		}
+		switch storage.Compression {
+		case "", CompressionNone:
+		case CompressionGzip:
+			if storage.Rollover == "" {
+				return fmt.Errorf("historyStorage compression needs a rollover, only the closed files are compressed")
+			}
+		default:
+			return fmt.Errorf("historyStorage compression %q is not supported, use %s or %s", storage.Compression,
+				CompressionGzip, CompressionNone)
+		}
+	}
+	if bus := this.LocationBus; bus != nil {
+		if !strings.HasPrefix(bus.Url, "nats://") || bus.Subject == "" || strings.ContainsAny(bus.Subject, " \t\r\n") {
+			return fmt.Errorf("locationBus needs a nats:// url and a subject")
+		}
+		switch bus.Encoding {
+		case "", BusEncodingProtobuf, BusEncodingJSON:
+		default:
+			return fmt.Errorf("locationBus encoding %q is neither %s nor %s", bus.Encoding, BusEncodingProtobuf, BusEncodingJSON)
+		}
+	}
+	if this.MinAgentVersion != "" {
+		if _, err := ParseVersion(this.MinAgentVersion); err != nil {
+			return fmt.Errorf("minAgentVersion: %w", err)
+		}
+	}
+	for user, login := range this.MemberLogins {
+		if login == nil || login.FamilyId == "" || login.MemberId == "" {
+			return fmt.Errorf("member login %s needs a familyId and a memberId", user)
+		}
+	}
+	for user, logins := range this.FamilyAccess {
+		for _, login := range logins {
+			if login == nil || login.FamilyId == "" || login.MemberId == "" {
+				return fmt.Errorf("family access of %s needs a familyId and a memberId", user)
+			}
+		}
+	}
+	// Area 0 is the area of the framework services, e.g. health
+	if this.ServiceArea < 1 || this.ServiceArea > 255 {
+		return fmt.Errorf("serviceArea %d is out of range 1-255", this.ServiceArea)
+	}
+	return nil
+}
+
+// Get returns the current configuration, callers must not modify it
+func Get() *ServerConfig {
+	mtx.RLock()
+	defer mtx.RUnlock()
+	return current
+}
+
+// Set replaces the current configuration
+func Set(cfg *ServerConfig) {
+	if cfg.DataDir == "" {
+		cfg.DataDir = DefaultDataDir
+	}
+	mtx.Lock()
+	defer mtx.Unlock()
+	current = cfg
+}
+
+// Family returns the overrides of a family, or nil if there are none
+func Family(familyId string) *FamilyConfig {
+	cfg := Get()
+	if cfg.Families == nil {
+		return nil
+	}
+	return cfg.Families[familyId]
+}
+
+// HistoryRetentionFor returns the history retention of a family,
+// falling back to the global retention
+func HistoryRetentionFor(familyId string) *Retention {
+	family := Family(familyId)
+	if family != nil && family.HistoryRetention != nil {
+		return family.HistoryRetention
+	}
+	if retention := Get().HistoryRetention; retention != nil {
+		return retention
+	}
+	return &Retention{}
+}
+
+// OfflineGraceFor returns the offline grace of a family, never shorter than
+// the online window
+func OfflineGraceFor(familyId string) time.Duration {
+	grace := Get().OfflineGraceSeconds
+	if family := Family(familyId); family != nil && family.OfflineGraceSeconds > 0 {
+		grace = family.OfflineGraceSeconds
+	}
+	if grace < Get().OnlineWindowSeconds {
+		grace = Get().OnlineWindowSeconds
+	}
+	return time.Duration(grace) * time.Second
+}
+
+// ArchiveAfterFor returns the archive threshold of a family, 0 when its
+// devices are never archived
+func ArchiveAfterFor(familyId string) time.Duration {
+	after := Get().ArchiveAfterSeconds
+	if family := Family(familyId); family != nil && family.ArchiveAfterSeconds != 0 {
+		after = family.ArchiveAfterSeconds
+	}
+	if after < 0 {
+		return 0
+	}
+	return time.Duration(after) * time.Second
+}
+
+// GeofencesFor returns the geofences the server evaluates for a family
+func GeofencesFor(familyId string) []*Geofence {
+	if family := Family(familyId); family != nil {
+		return family.Geofences
+	}
+	return nil
+}
+
+// HomeRegionFor returns the enabled home region of a device of the family as
+// a geofence, nil when it has none
+func HomeRegionFor(familyId, deviceId string) *Geofence {
+	family := Family(familyId)
+	if family == nil {
+		return nil
+	}
+	region := family.HomeRegions[deviceId]
+	if region == nil || !region.Enabled {
+		return nil
+	}
+	fence := region.Geofence
+	if fence.Name == "" {
+		fence.Name = DefaultHomeRegionName
+	}
+	return &fence
+}
+
+// TimezoneFor returns the timezone of a family, UTC when it is unset or
+// unknown to the tz database
+func TimezoneFor(familyId string) *time.Location {
+	family := Family(familyId)
+	if family == nil || family.Timezone == "" {
+		return time.UTC
+	}
+	location, err := time.LoadLocation(family.Timezone)
+	if err != nil {
+		return time.UTC
+	}
+	return location
+}
+
+// StartOfDay returns the local midnight of the family day containing t
+func StartOfDay(familyId string, t time.Time) time.Time {
+	local := t.In(TimezoneFor(familyId))
+	return time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, local.Location())
+}
+
+// HistoryFormat returns the history storage format, a single plain file
+// per device when unset
+func HistoryFormat() *HistoryStorage {
+	if storage := Get().HistoryStorage; storage != nil {
+		return storage
+	}
+	return &HistoryStorage{}
+}
+
+// HistoryMaxWindow returns the history query window cap, 31 days when unset
+func HistoryMaxWindow() time.Duration {
+	if Get().HistoryMaxWindowSeconds <= 0 {
+		return 31 * 24 * time.Hour
+	}
+	return time.Duration(Get().HistoryMaxWindowSeconds) * time.Second
+}
+
+// IsAdmin returns true if the user is a configured admin
+func IsAdmin(user string) bool {
+	for _, admin := range Get().AdminUsers {
+		if admin == user {
+			return true
+		}
+	}
+	return false
+}
+
+// MemberLoginFor returns the family and member of a login user, the family
+// account of a user without a member login has no member id
+func MemberLoginFor(user string) (familyId, memberId string) {
+	if login := Get().MemberLogins[user]; login != nil {
+		return login.FamilyId, login.MemberId
+	}
+	return user, ""
+}
+
+// FamilyAccessFor returns the further families the user is a member of
+func FamilyAccessFor(user string) []*MemberLogin {
+	return Get().FamilyAccess[user]
+}
+
+// IsFamilyAdmin returns true if the member is an admin of the family
+func IsFamilyAdmin(familyId, memberId string) bool {
+	family := Family(familyId)
+	if family == nil || memberId == "" {
+		return false
+	}
+	for _, admin := range family.Admins {
+		if admin == memberId {
+			return true
+		}
+	}
+	return false
+}
+
+// IdempotencyWindow returns the idempotency key window as a duration
+func IdempotencyWindow() time.Duration {
+	return time.Duration(Get().IdempotencyWindowSeconds) * time.Second
+}
+
+// LowBatteryPercentFor returns the low battery threshold of a family,
+// 0 means the alert is disabled
+func LowBatteryPercentFor(familyId string) int {
+	family := Family(familyId)
+	if family != nil && family.LowBatteryPercent != 0 {
+		if family.LowBatteryPercent < 0 {
+			return 0
+		}
+		return family.LowBatteryPercent
+	}
+	return Get().LowBatteryPercent
+}
+
+// LocationsPerMinuteFor returns the location ceiling of the family, 0 when
+// it is not capped
+func LocationsPerMinuteFor(familyId string) int {
+	family := Family(familyId)
+	if family != nil && family.LocationsPerMinute != 0 {
+		if family.LocationsPerMinute < 0 {
+			return 0
+		}
+		return family.LocationsPerMinute
+	}
+	return Get().FamilyLocationsPerMinute
+}
+
+// CreateUnknownDevices returns true if a location of an unregistered device
+// registers it
+func CreateUnknownDevices() bool {
+	return Get().UnknownDevicePolicy == UnknownDeviceCreate
+}
+
+// UpsertRegistrations returns true if a registration of a registered device
+// keeps its position and last seen time
+func UpsertRegistrations() bool {
+	return Get().RegistrationPolicy != RegistrationReplace
+}
+
+// ReplayWindow returns the signed location replay window, 5 minutes when unset
+func ReplayWindow() time.Duration {
+	if Get().ReplayWindowSeconds <= 0 {
+		return 5 * time.Minute
+	}
+	return time.Duration(Get().ReplayWindowSeconds) * time.Second
+}
+
+// RegistrationCooldown returns the device registration cooldown as a duration
+func RegistrationCooldown() time.Duration {
+	return time.Duration(Get().RegistrationCooldownSeconds) * time.Second
+}
@@ -0,0 +1,200 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFamilyTimezone(t *testing.T) {
+	defer Set(Default())
+	cfg := Default()
+	cfg.Families = map[string]*FamilyConfig{"smith": {Timezone: "Mars/Olympus_Mons"}}
+	if err := cfg.Validate(); err == nil {
+		t.Fatal("a zone missing from the tz database must be rejected")
+	}
+
+	cfg.Families["smith"].Timezone = "America/New_York"
+	if err := cfg.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	Set(cfg)
+	if TimezoneFor("jones") != time.UTC {
+		t.Fatal("a family without a timezone must use UTC")
+	}
+
+	// 03:00 UTC is still the previous day in New York
+	at := time.Date(2025, 3, 10, 3, 0, 0, 0, time.UTC)
+	start := StartOfDay("smith", at)
+	if start.Day() != 9 || start.Hour() != 0 || start.Location().String() != "America/New_York" {
+		t.Fatalf("unexpected local midnight %s", start)
+	}
+	if !StartOfDay("jones", at).Equal(time.Date(2025, 3, 10, 0, 0, 0, 0, time.UTC)) {
+		t.Fatal("unexpected UTC midnight")
+	}
+}
+
+func TestHomeRegions(t *testing.T) {
+	defer Set(Default())
+	cfg := Default()
+	cfg.Families = map[string]*FamilyConfig{"smith": {HomeRegions: map[string]*HomeRegion{
+		"d1": {Geofence: Geofence{Latitude: 40, Longitude: -74}, Enabled: true},
+	}}}
+	if err := cfg.Validate(); err == nil {
+		t.Fatal("a home region without a radius must be rejected")
+	}
+
+	cfg.Families["smith"].HomeRegions["d1"].RadiusMeters = 500
+	cfg.Families["smith"].HomeRegions["d2"] = &HomeRegion{Geofence: Geofence{Latitude: 40, Longitude: -74, RadiusMeters: 500}}
+	if err := cfg.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	Set(cfg)
+	if region := HomeRegionFor("smith", "d1"); region == nil || region.Name != DefaultHomeRegionName || region.RadiusMeters != 500 {
+		t.Fatalf("expected the region with the default name, got %v", region)
+	}
+	if HomeRegionFor("smith", "d2") != nil || HomeRegionFor("smith", "d3") != nil || HomeRegionFor("jones", "d1") != nil {
+		t.Fatal("a disabled or missing home region must be nil")
+	}
+}
+
+func TestMemberLogins(t *testing.T) {
+	defer Set(Default())
+	cfg := Default()
+	cfg.MemberLogins = map[string]*MemberLogin{"emma": {FamilyId: "smith"}}
+	if err := cfg.Validate(); err == nil {
+		t.Fatal("a member login without a member must be rejected")
+	}
+	cfg.MemberLogins["emma"].MemberId = "emma"
+	cfg.Families = map[string]*FamilyConfig{"smith": {Admins: []string{"mom"}}}
+	if err := cfg.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	Set(cfg)
+	if familyId, memberId := MemberLoginFor("emma"); familyId != "smith" || memberId != "emma" {
+		t.Fatalf("unexpected member login %s %s", familyId, memberId)
+	}
+	if familyId, memberId := MemberLoginFor("smith"); familyId != "smith" || memberId != "" {
+		t.Fatalf("a user without a member login is its family account, got %s %s", familyId, memberId)
+	}
+	if !IsFamilyAdmin("smith", "mom") || IsFamilyAdmin("smith", "emma") || IsFamilyAdmin("smith", "") {
+		t.Fatal("only the listed members are family admins")
+	}
+}
+
+func TestAccuracyGate(t *testing.T) {
+	cfg := Default()
+	cfg.AccuracyGate = &AccuracyGate{MaxRatio: 0.5, StaleSeconds: 300}
+	if err := cfg.Validate(); err == nil {
+		t.Fatal("a ratio below 1 must be rejected")
+	}
+	filename := filepath.Join(t.TempDir(), "server.json")
+	if err := os.WriteFile(filename, []byte(`{"serviceArea": 53, "accuracyGate": null}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	defer Set(Default())
+	if err := Load(filename); err != nil {
+		t.Fatal(err)
+	}
+	if Get().AccuracyGate != nil {
+		t.Fatal("a null accuracyGate must disable the gate")
+	}
+}
+
+func TestMovementThresholds(t *testing.T) {
+	cfg := Default()
+	cfg.Movement = &Movement{WalkingSpeed: 5, DrivingSpeed: 2, WindowSeconds: 60}
+	if err := cfg.Validate(); err == nil {
+		t.Fatal("a driving speed below the walking speed must be rejected")
+	}
+	cfg.Movement = nil
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("a null movement disables it: %v", err)
+	}
+}
+
+func TestAgentVersionAllowed(t *testing.T) {
+	cfg := Default()
+	if !AgentVersionAllowed("") {
+		t.Fatal("without a minimum every agent is allowed")
+	}
+	cfg.MinAgentVersion = "1.10"
+	Set(cfg)
+	defer Set(Default())
+	for version, allowed := range map[string]bool{"1.10": true, "1.10.0": true, "v1.10.1-rc1": true, "2": true,
+		"1.9.9": false, "1.2": false, "": false, "dev": false} {
+		if AgentVersionAllowed(version) != allowed {
+			t.Fatalf("agent %q allowed must be %v", version, allowed)
+		}
+	}
+	cfg.MinAgentVersion = "latest"
+	if err := cfg.Validate(); err == nil {
+		t.Fatal("a minimum that is not a version must be rejected")
+	}
+}
+
+func TestHistoryStorage(t *testing.T) {
+	cfg := Default()
+	for _, storage := range []*HistoryStorage{
+		{Rollover: "weekly"},
+		{Rollover: RolloverDaily, Compression: "zstd"},
+		{Compression: CompressionGzip},
+	} {
+		cfg.HistoryStorage = storage
+		if err := cfg.Validate(); err == nil {
+			t.Fatalf("expected %v to be rejected", storage)
+		}
+	}
+	cfg.HistoryStorage = &HistoryStorage{Rollover: RolloverHourly, Compression: CompressionGzip}
+	if err := cfg.Validate(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLocationBus(t *testing.T) {
+	cfg := Default()
+	for _, bus := range []*LocationBus{
+		{Url: "http://bus:4222", Subject: "family.locations"},
+		{Url: "nats://bus:4222"},
+		{Url: "nats://bus:4222", Subject: "family.locations", Encoding: "avro"},
+	} {
+		cfg.LocationBus = bus
+		if err := cfg.Validate(); err == nil {
+			t.Fatalf("expected %v to be rejected", bus)
+		}
+	}
+	cfg.LocationBus = &LocationBus{Url: "nats://bus", Subject: "family.locations", Encoding: BusEncodingJSON}
+	if err := cfg.Validate(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestArchiveAfter(t *testing.T) {
+	defer Set(Default())
+	cfg := Default()
+	cfg.ArchiveAfterSeconds = 3600
+	cfg.Families = map[string]*FamilyConfig{"smith": {ArchiveAfterSeconds: 60}, "jones": {ArchiveAfterSeconds: -1}}
+	Set(cfg)
+	if ArchiveAfterFor("smith") != time.Minute || ArchiveAfterFor("other") != time.Hour {
+		t.Fatal("a family threshold must override the global one")
+	}
+	if ArchiveAfterFor("jones") != 0 {
+		t.Fatal("a negative family threshold must never archive")
+	}
+}
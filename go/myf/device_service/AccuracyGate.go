@@ -0,0 +1,58 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package device_service
+
+import (
+	"time"
+
+	"github.com/saichler/l8myfamiliy/go/myf/config"
+	"github.com/saichler/l8myfamiliy/go/types/l8myfamily"
+)
+
+// ipFixAccuracyMeters is the accuracy of an IP based or coarse fix that
+// does not report one, the radius of a city
+const ipFixAccuracyMeters = 15000
+
+// fixAccuracy returns the accuracy radius of a fix in meters, 0 is unknown
+func fixAccuracy(accuracy float32, source l8myfamily.LocationSource, coarse bool) float64 {
+	if accuracy > 0 {
+		return float64(accuracy)
+	}
+	if coarse || source == l8myfamily.LocationSource_LOCATION_SOURCE_GEOIP {
+		return ipFixAccuracyMeters
+	}
+	return 0
+}
+
+// Gated returns true if the location must not replace the device position,
+// its accuracy is much worse than that of a position that is not stale yet.
+// An emergency always moves the device.
+func Gated(device *l8myfamily.Device, location *l8myfamily.Location) bool {
+	gate := config.Get().AccuracyGate
+	if gate == nil || device == nil || location.Emergency || device.PositionTime == 0 {
+		return false
+	}
+	current := fixAccuracy(device.Accuracy, device.Source, device.Coarse)
+	next := fixAccuracy(location.Accuracy, location.Source, location.Coarse)
+	if current == 0 || next <= current*gate.MaxRatio {
+		return false
+	}
+	at := location.Timestamp
+	if at == 0 {
+		at = time.Now().UnixMilli()
+	}
+	return time.Duration(at-device.PositionTime)*time.Millisecond < time.Duration(gate.StaleSeconds)*time.Second
+}
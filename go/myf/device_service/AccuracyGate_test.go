@@ -0,0 +1,109 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package device_service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/saichler/l8myfamiliy/go/myf/config"
+	"github.com/saichler/l8myfamiliy/go/types/l8myfamily"
+)
+
+func TestAccuracyGateGoodThenBad(t *testing.T) {
+	config.Set(config.Default())
+	now := time.Now()
+	at := func(ago time.Duration) int64 { return now.Add(-ago).UnixMilli() }
+
+	device := &l8myfamily.Device{Id: "d1"}
+	applyLocation(device, &l8myfamily.Location{DeviceId: "d1", Latitude: 1, Longitude: 1, Accuracy: 20,
+		Timestamp: at(time.Minute)})
+
+	// A fix 100 times worse a minute later only keeps the device online
+	applyLocation(device, &l8myfamily.Location{DeviceId: "d1", Latitude: 2, Longitude: 2, Accuracy: 2000,
+		Timestamp: at(0)})
+	if device.Latitude != 1 || device.Accuracy != 20 || device.LastSeen != at(0) {
+		t.Fatalf("a much worse fix must not replace a recent good one, got %+v", device)
+	}
+
+	// An IP based fix without an accuracy is city level
+	applyLocation(device, &l8myfamily.Location{DeviceId: "d1", Latitude: 3, Longitude: 3,
+		Source: l8myfamily.LocationSource_LOCATION_SOURCE_GEOIP, Timestamp: at(0)})
+	if device.Latitude != 1 {
+		t.Fatalf("an IP based fix must not replace a recent good one, got %+v", device)
+	}
+
+	// A somewhat worse fix is within the ratio
+	applyLocation(device, &l8myfamily.Location{DeviceId: "d1", Latitude: 4, Longitude: 4, Accuracy: 80,
+		Timestamp: at(0)})
+	if device.Latitude != 4 || device.Accuracy != 80 {
+		t.Fatalf("a fix within the ratio must replace the position, got %+v", device)
+	}
+}
+
+func TestAccuracyGateStaleOrEmergency(t *testing.T) {
+	config.Set(config.Default())
+	now := time.Now()
+	at := func(ago time.Duration) int64 { return now.Add(-ago).UnixMilli() }
+
+	device := &l8myfamily.Device{Id: "d1"}
+	applyLocation(device, &l8myfamily.Location{DeviceId: "d1", Latitude: 1, Longitude: 1, Accuracy: 20,
+		Timestamp: at(10 * time.Minute)})
+	applyLocation(device, &l8myfamily.Location{DeviceId: "d1", Latitude: 2, Longitude: 2, Accuracy: 2000,
+		Timestamp: at(0)})
+	if device.Latitude != 2 {
+		t.Fatalf("a stale good fix must be replaced, got %+v", device)
+	}
+
+	device = &l8myfamily.Device{Id: "d1"}
+	applyLocation(device, &l8myfamily.Location{DeviceId: "d1", Latitude: 1, Longitude: 1, Accuracy: 20,
+		Timestamp: at(time.Minute)})
+	applyLocation(device, &l8myfamily.Location{DeviceId: "d1", Latitude: 2, Longitude: 2, Accuracy: 2000,
+		Timestamp: at(0), Emergency: true})
+	if device.Latitude != 2 || !device.Emergency {
+		t.Fatalf("an emergency must always move the device, got %+v", device)
+	}
+}
+
+func TestAccuracyGateBadThenGood(t *testing.T) {
+	config.Set(config.Default())
+	now := time.Now()
+	at := func(ago time.Duration) int64 { return now.Add(-ago).UnixMilli() }
+
+	device := &l8myfamily.Device{Id: "d1"}
+	applyLocation(device, &l8myfamily.Location{DeviceId: "d1", Latitude: 1, Longitude: 1, Accuracy: 2000,
+		Timestamp: at(time.Minute)})
+	applyLocation(device, &l8myfamily.Location{DeviceId: "d1", Latitude: 2, Longitude: 2, Accuracy: 20,
+		Timestamp: at(0)})
+	if device.Latitude != 2 || device.Accuracy != 20 || device.PositionTime != at(0) {
+		t.Fatalf("a better fix must always replace the position, got %+v", device)
+	}
+}
+
+func TestAccuracyGateDisabled(t *testing.T) {
+	cfg := config.Default()
+	cfg.AccuracyGate = nil
+	config.Set(cfg)
+	defer config.Set(config.Default())
+	now := time.Now().UnixMilli()
+
+	device := &l8myfamily.Device{Id: "d1"}
+	applyLocation(device, &l8myfamily.Location{DeviceId: "d1", Latitude: 1, Longitude: 1, Accuracy: 20, Timestamp: now - 1000})
+	applyLocation(device, &l8myfamily.Location{DeviceId: "d1", Latitude: 2, Longitude: 2, Accuracy: 2000, Timestamp: now})
+	if device.Latitude != 2 {
+		t.Fatalf("without the gate every fix must replace the position, got %+v", device)
+	}
+}
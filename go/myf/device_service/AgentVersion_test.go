@@ -0,0 +1,49 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package device_service
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/saichler/l8myfamiliy/go/myf/config"
+	"github.com/saichler/l8myfamiliy/go/myf/webapi"
+	"github.com/saichler/l8myfamiliy/go/types/l8myfamily"
+)
+
+func TestRegistrationBelowMinimumAgentVersion(t *testing.T) {
+	cfg := config.Default()
+	cfg.MinAgentVersion = "1.4.0"
+	config.Set(cfg)
+	defer config.Set(config.Default())
+	storage = newTestStorage(t)
+	defer func() { storage = nil }()
+	storage.Put("phone", &l8myfamily.Device{Id: "phone", FamilyId: "fam", Name: "Phone", AgentVersion: "1.3.0"})
+
+	for _, version := range []string{"1.3.9", "", "dev"} {
+		registration := &l8myfamily.Device{Id: "phone", FamilyId: "fam", Name: "Phone", AgentVersion: version}
+		if ok, err := registerInternal(registration); ok || !errors.Is(err, webapi.ErrAgentOutdated) {
+			t.Fatalf("agent %q must be told to upgrade, got %v %v", version, ok, err)
+		}
+	}
+	registration := &l8myfamily.Device{Id: "phone", FamilyId: "fam", Name: "Phone", AgentVersion: "v1.4.1"}
+	if ok, err := registerInternal(registration); ok || err != nil {
+		t.Fatalf("an upgraded agent must register, got %v %v", ok, err)
+	}
+	if stored := storedDevice(t, "phone"); stored.AgentVersion != "v1.4.1" {
+		t.Fatalf("the registration must store the agent version, got %q", stored.AgentVersion)
+	}
+}
@@ -17,21 +17,222 @@ package device_service
 
 import (
 	"fmt"
+	"time"
 
+	"github.com/saichler/l8myfamiliy/go/myf/auth"
+	"github.com/saichler/l8myfamiliy/go/myf/config"
+	"github.com/saichler/l8myfamiliy/go/myf/webapi"
 	"github.com/saichler/l8myfamiliy/go/types/l8myfamily"
 	"github.com/saichler/l8types/go/ifs"
 )
 
 type DeviceCallback struct{}
 
+// registrationKeys remembers the idempotency keys of recent device registrations
+var registrationKeys = webapi.NewIdempotencyCache(config.IdempotencyWindow())
+
+// registrations rejects a burst of registrations of one device under new ids
+var registrations = NewRegistrationCooldown(config.RegistrationCooldown())
+
+// minSigningKeyLength is the shortest signing key accepted, in hex characters
+const minSigningKeyLength = 32
+
 func (lc *DeviceCallback) Before(elem interface{}, action ifs.Action, notify bool, vnic ifs.IVNic) (interface{}, bool, error) {
 	if action == ifs.POST {
 		device := elem.(*l8myfamily.Device)
+		if storage != nil && storage.Degraded() {
+			return nil, false, webapi.ErrStorageUnavailable
+		}
+		// A registration through the service endpoint has no caller, the
+		// agents register through the raw device endpoint
+		caller := auth.CallerOf(device)
+		if caller == nil {
+			fmt.Println("[Device] rejecting ", device.Id, ", the registration has no authenticated caller")
+			return nil, false, webapi.ErrUnauthenticated
+		}
+		// A registered device is only registered again, moved to another
+		// family or given a new signing key by a caller of its family
+		if family, ok := storedFamily(device.Id); ok && !caller.MayAccess(family) {
+			fmt.Println("[Device] ", caller.User, " may not register ", device.Id, " of family ", family)
+			return nil, false, webapi.ErrOtherFamily
+		}
+		if !caller.MayAccess(device.FamilyId) {
+			fmt.Println("[Device] ", caller.User, " may not register ", device.Id, " in family ", device.FamilyId)
+			return nil, false, webapi.ErrOtherFamily
+		}
+		// The key is only for the request, it is not stored on the device
+		key := device.IdempotencyKey
+		device.IdempotencyKey = ""
+		if key != "" && registrationKeys.Seen(device.Id+"/"+key) {
+			fmt.Println("[Device] duplicate registration ", key, " for ", device.Id)
+			return nil, false, nil
+		}
+		owner := defaultOwner(device.Id, caller)
+		if device.OwnerId == "" {
+			device.OwnerId = owner
+		} else if device.OwnerId != owner && !mayChangeOwner(device, caller) {
+			fmt.Println("[Device] ", caller.User, " may not change the owner of ", device.Id, " from ", owner)
+			return nil, false, webapi.ErrNotOwner
+		}
+		if !allowlists.Allowed(device.FamilyId, device.Id) {
+			fmt.Println("[Device] ", device.Id, " is not on the allowlist of family ", device.FamilyId)
+			return nil, false, webapi.ErrNotAllowed
+		}
+		if !config.AgentVersionAllowed(device.AgentVersion) {
+			fmt.Println("[Device] ", device.Id, " agent version ", device.AgentVersion, " is below ", config.Get().MinAgentVersion)
+			return nil, false, fmt.Errorf("%w: %q is below %s", webapi.ErrAgentOutdated, device.AgentVersion,
+				config.Get().MinAgentVersion)
+		}
+		if existing := registrations.Conflict(device.FamilyId, device.Name, device.Id, time.Now()); existing != "" && !isStored(device.Id) {
+			fmt.Println("[Device] registration cooldown, ", device.Id, " has the name ", device.Name,
+				" of ", existing, " in family ", device.FamilyId)
+			return nil, false, fmt.Errorf("device %q of family %s was registered as %s within the last %s, "+
+				"a new device id with the same name is rejected", device.Name, device.FamilyId, existing, config.RegistrationCooldown())
+		}
+		// An agent registers without tags, visibility and avatar, a
+		// re-registration keeps those the family set
+		if !config.UpsertRegistrations() {
+			keepStored(device)
+		}
 		fmt.Println("[Device] ", device.Id, "-", device.FamilyId, "-", device.Name)
 	}
+	if action == ifs.POST || action == ifs.PUT {
+		// The signing key is kept in the key store, never on the device
+		device := elem.(*l8myfamily.Device)
+		if err := ValidateTags(device.Tags); err != nil {
+			return nil, false, err
+		}
+		if err := ValidateAvatar(device.Avatar); err != nil {
+			return nil, false, err
+		}
+		device.Telemetry = webapi.CleanTelemetry(device.Telemetry)
+		if device.HeartbeatOnly {
+			clearPosition(device)
+		}
+		device.ReportIntervalSeconds = clampReportInterval(device.ReportIntervalSeconds)
+		key := device.SigningKey
+		device.SigningKey = ""
+		if key != "" {
+			// A registration was authorized above, an update only rotates
+			// the key of a device of the caller's family
+			if action == ifs.PUT {
+				if family, ok := storedFamily(device.Id); ok && !auth.CallerOf(device).MayAccess(family) {
+					fmt.Println("[Device] rejecting the signing key of ", device.Id, ", the caller is not of family ", family)
+					return nil, false, webapi.ErrOtherFamily
+				}
+			}
+			if len(key) < minSigningKeyLength {
+				return nil, false, fmt.Errorf("signing key of %s is too short", device.Id)
+			}
+			if err := signingKeys.Set(device.Id, key, time.Now()); err != nil {
+				return nil, false, fmt.Errorf("failed to store the signing key of %s: %w", device.Id, err)
+			}
+		}
+	}
+	// A patch ignores zero values, the coordinates of a heartbeat-only
+	// device are dropped from it
+	if action == ifs.PATCH {
+		if device := elem.(*l8myfamily.Device); device.HeartbeatOnly || storedHeartbeatOnly(device.Id) {
+			clearPosition(device)
+		}
+	}
+	// A registered device is updated in place, the service creates a new one
+	if action == ifs.POST && config.UpsertRegistrations() {
+		if upserted, err := upsertStored(elem.(*l8myfamily.Device)); err != nil || upserted {
+			return nil, false, err
+		}
+	}
 	return nil, true, nil
 }
 
+// isStored returns true if the device is already registered, a re-registration
+// of a known device is never subject to the cooldown
+func isStored(id string) bool {
+	if storage == nil {
+		return false
+	}
+	_, err := storage.Get(id)
+	return err == nil
+}
+
+// storedFamily returns the family of the registered device, false if the
+// device is not registered
+func storedFamily(id string) (string, bool) {
+	if storage == nil {
+		return "", false
+	}
+	elem, err := storage.Get(id)
+	if err != nil {
+		return "", false
+	}
+	return elem.(*l8myfamily.Device).FamilyId, true
+}
+
+// defaultOwner returns the owner of a device registered without one: the
+// owner of the registered device, none for a device registered before it had
+// an owner so its member keeps owning it, else the registering user
+func defaultOwner(id string, caller *auth.Caller) string {
+	if storage != nil {
+		if elem, err := storage.Get(id); err == nil {
+			return elem.(*l8myfamily.Device).OwnerId
+		}
+	}
+	if caller == nil {
+		return ""
+	}
+	return caller.User
+}
+
+// mayChangeOwner returns true if the caller may give the device another
+// owner. The admins, the family account and the family admins manage every
+// device of the family, see canManage, so they may, as may the owner of the
+// registered device. A new device is owned by whoever registers it until it
+// names another owner.
+func mayChangeOwner(device *l8myfamily.Device, caller *auth.Caller) bool {
+	if caller.Admin || caller.MemberId == "" || config.IsFamilyAdmin(device.FamilyId, caller.MemberId) {
+		return true
+	}
+	if storage == nil {
+		return true
+	}
+	elem, err := storage.Get(device.Id)
+	return err != nil || isOwner(elem.(*l8myfamily.Device), caller)
+}
+
+// storedHeartbeatOnly returns true if the registered device is heartbeat-only
+func storedHeartbeatOnly(id string) bool {
+	if storage == nil {
+		return false
+	}
+	elem, err := storage.Get(id)
+	return err == nil && elem.(*l8myfamily.Device).HeartbeatOnly
+}
+
+// keepStored sets the tags, the visibility and the avatar of a re-registered
+// device that registers without them to those of the registered device
+func keepStored(device *l8myfamily.Device) {
+	if storage == nil {
+		return
+	}
+	elem, err := storage.Get(device.Id)
+	if err != nil {
+		return
+	}
+	stored := elem.(*l8myfamily.Device)
+	if device.Tags == nil && len(stored.Tags) > 0 {
+		device.Tags = make(map[string]string, len(stored.Tags))
+		for key, value := range stored.Tags {
+			device.Tags[key] = value
+		}
+	}
+	if device.VisibleTo == nil && len(stored.VisibleTo) > 0 {
+		device.VisibleTo = append([]string(nil), stored.VisibleTo...)
+	}
+	if device.Avatar == "" {
+		device.Avatar = stored.Avatar
+	}
+}
+
 func (lc *DeviceCallback) After(elem interface{}, action ifs.Action, notify bool, vnic ifs.IVNic) (interface{}, bool, error) {
 	return nil, true, nil
 }
@@ -0,0 +1,148 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package device_service
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// allowlistFile is the device allowlist store under the data dir
+const allowlistFile = "device-allowlists.json"
+
+// allowlists holds the device allowlists of the activated service
+var allowlists = NewDeviceAllowlist("")
+
+// DeviceAllowlist holds the device ids each centrally provisioned family
+// approved, persisted to a JSON file. A family without an allowlist is open,
+// any device may register and post.
+type DeviceAllowlist struct {
+	mtx      *sync.RWMutex
+	filename string
+	families map[string]map[string]bool
+}
+
+// NewDeviceAllowlist loads the allowlists from filename, an empty filename
+// keeps them in memory only
+func NewDeviceAllowlist(filename string) *DeviceAllowlist {
+	allowlist := &DeviceAllowlist{mtx: &sync.RWMutex{}, filename: filename, families: make(map[string]map[string]bool)}
+	if filename == "" {
+		return allowlist
+	}
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			fmt.Println("[Device] failed to read device allowlists: ", err.Error())
+		}
+		return allowlist
+	}
+	stored := make(map[string][]string)
+	if err := json.Unmarshal(data, &stored); err != nil {
+		fmt.Println("[Device] failed to parse device allowlists: ", err.Error())
+		return allowlist
+	}
+	for familyId, ids := range stored {
+		allowlist.families[familyId] = toSet(ids)
+	}
+	return allowlist
+}
+
+// Allowed returns true if the family has no allowlist or the device is on it
+func (this *DeviceAllowlist) Allowed(familyId, deviceId string) bool {
+	this.mtx.RLock()
+	defer this.mtx.RUnlock()
+	ids, ok := this.families[familyId]
+	return !ok || ids[deviceId]
+}
+
+// Get returns the sorted allowlist of the family and whether it has one
+func (this *DeviceAllowlist) Get(familyId string) ([]string, bool) {
+	this.mtx.RLock()
+	defer this.mtx.RUnlock()
+	ids, ok := this.families[familyId]
+	if !ok {
+		return nil, false
+	}
+	return sortedIds(ids), true
+}
+
+// Set enables the allowlist of the family with the device ids, an empty
+// list blocks every device of the family
+func (this *DeviceAllowlist) Set(familyId string, deviceIds []string) error {
+	this.mtx.Lock()
+	defer this.mtx.Unlock()
+	this.families[familyId] = toSet(deviceIds)
+	return this.save()
+}
+
+// Remove disables the allowlist of the family, it is open again
+func (this *DeviceAllowlist) Remove(familyId string) error {
+	this.mtx.Lock()
+	defer this.mtx.Unlock()
+	delete(this.families, familyId)
+	return this.save()
+}
+
+// save must be called under the write lock
+func (this *DeviceAllowlist) save() error {
+	if this.filename == "" {
+		return nil
+	}
+	stored := make(map[string][]string, len(this.families))
+	for familyId, ids := range this.families {
+		stored[familyId] = sortedIds(ids)
+	}
+	data, err := json.Marshal(stored)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(this.filename), 0777); err != nil {
+		return err
+	}
+	tmp := this.filename + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, this.filename)
+}
+
+func toSet(ids []string) map[string]bool {
+	set := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		if id != "" {
+			set[id] = true
+		}
+	}
+	return set
+}
+
+func sortedIds(set map[string]bool) []string {
+	ids := make([]string, 0, len(set))
+	for id := range set {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// DeviceAllowed returns true if the device may register and post for the family
+func DeviceAllowed(familyId, deviceId string) bool {
+	return allowlists.Allowed(familyId, deviceId)
+}
@@ -0,0 +1,101 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package device_service
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/saichler/l8myfamiliy/go/myf/auth"
+	"github.com/saichler/l8myfamiliy/go/myf/config"
+	"github.com/saichler/l8myfamiliy/go/myf/webapi"
+	"github.com/saichler/l8myfamiliy/go/types/l8myfamily"
+	"github.com/saichler/l8types/go/ifs"
+)
+
+func TestAllowlistBlocksRegistration(t *testing.T) {
+	orig := allowlists
+	allowlists = NewDeviceAllowlist("")
+	defer func() { allowlists = orig }()
+	register := func(familyId, id string) error {
+		_, err := registerInternal(&l8myfamily.Device{Id: id, FamilyId: familyId})
+		return err
+	}
+
+	allowlists.Set("managed", []string{"approved"})
+	if err := register("managed", "approved"); err != nil {
+		t.Fatalf("an allowed device must register: %v", err)
+	}
+	if err := register("managed", "rogue"); err != webapi.ErrNotAllowed {
+		t.Fatalf("a device not on the allowlist must be rejected, got %v", err)
+	}
+	if err := register("open", "rogue"); err != nil {
+		t.Fatalf("a family without an allowlist must stay open: %v", err)
+	}
+	allowlists.Remove("managed")
+	if err := register("managed", "rogue"); err != nil {
+		t.Fatalf("a disabled allowlist must not block: %v", err)
+	}
+}
+
+func TestAllowlistHandler(t *testing.T) {
+	dir := t.TempDir()
+	config.Set(&config.ServerConfig{DataDir: dir, AdminUsers: []string{"admin"}})
+	orig, origValidate := allowlists, auth.ValidateToken
+	allowlists = NewDeviceAllowlist(filepath.Join(dir, allowlistFile))
+	auth.ValidateToken = func(token string, vnic ifs.IVNic) (string, bool) {
+		return token, true
+	}
+	defer func() {
+		allowlists, auth.ValidateToken = orig, origValidate
+		config.Set(config.Default())
+	}()
+
+	call := func(method, token, query string, body *Allowlist) (int, *Allowlist) {
+		data, _ := json.Marshal(body)
+		r := httptest.NewRequest(method, "/my-family/admin/allowlist?"+query, bytes.NewReader(data))
+		r.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		allowlistHandler(w, r, nil)
+		result := &Allowlist{}
+		json.Unmarshal(w.Body.Bytes(), result)
+		return w.Code, result
+	}
+
+	if code, _ := call("PUT", "parent", "", &Allowlist{FamilyId: "f"}); code != http.StatusForbidden {
+		t.Fatalf("only an admin may edit allowlists, got %d", code)
+	}
+	code, list := call("PUT", "admin", "", &Allowlist{FamilyId: "f", DeviceIds: []string{"b", "a"}})
+	if code != http.StatusOK || !list.Enabled || len(list.DeviceIds) != 2 || list.DeviceIds[0] != "a" {
+		t.Fatalf("unexpected allowlist %d %+v", code, list)
+	}
+	if reloaded := NewDeviceAllowlist(filepath.Join(dir, allowlistFile)); !reloaded.Allowed("f", "a") || reloaded.Allowed("f", "c") {
+		t.Fatal("the allowlist must be persisted")
+	}
+	if code, list := call("DELETE", "admin", "familyId=f", nil); code != http.StatusOK || list.Enabled {
+		t.Fatalf("expected the allowlist disabled, got %d %+v", code, list)
+	}
+	if code, list := call("GET", "admin", "familyId=f", nil); code != http.StatusOK || list.Enabled || !DeviceAllowed("f", "c") {
+		t.Fatalf("a family without an allowlist is open, got %d %+v", code, list)
+	}
+	if code, _ := call("POST", "admin", "familyId=f", nil); code != http.StatusMethodNotAllowed {
+		t.Fatalf("expected 405, got %d", code)
+	}
+}
@@ -0,0 +1,145 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package device_service
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/saichler/l8myfamiliy/go/myf/audit"
+	"github.com/saichler/l8myfamiliy/go/myf/auth"
+	"github.com/saichler/l8myfamiliy/go/myf/config"
+	"github.com/saichler/l8myfamiliy/go/types/l8myfamily"
+	"google.golang.org/protobuf/proto"
+)
+
+// archiveSweepInterval is how often the stale devices are archived
+var archiveSweepInterval = time.Hour
+
+var archiveOnce = &sync.Once{}
+
+// ErrRestoreDenied is returned when the caller may not restore an archived device
+var ErrRestoreDenied = errors.New("only the family account, a family admin or an admin may restore a device")
+
+// lastActivity returns the time the device last posted or changed
+func lastActivity(device *l8myfamily.Device) time.Time {
+	return time.UnixMilli(max(device.LastSeen, device.ModifiedAt))
+}
+
+// IsStale returns true if the device neither posted nor changed within the
+// archive threshold of its family
+func IsStale(device *l8myfamily.Device, now time.Time) bool {
+	after := config.ArchiveAfterFor(device.FamilyId)
+	return after > 0 && now.Sub(lastActivity(device)) > after
+}
+
+// StartArchiveSweep starts the periodic background archive of the stale devices
+func StartArchiveSweep() {
+	archiveOnce.Do(func() {
+		go func() {
+			for {
+				sweepArchive(time.Now())
+				time.Sleep(archiveSweepInterval)
+			}
+		}()
+	})
+}
+
+// sweepArchive archives the stale devices and returns how many it archived
+func sweepArchive(now time.Time) int {
+	if storage == nil {
+		return 0
+	}
+	collected := storage.Collect(func(elem interface{}) (bool, interface{}) {
+		device, ok := elem.(*l8myfamily.Device)
+		return ok && device.ArchivedTime == 0 && IsStale(device, now), elem
+	})
+	archived := 0
+	for _, elem := range collected {
+		device, err := archiveDevice(elem.(*l8myfamily.Device).Id, now)
+		if err != nil {
+			fmt.Println("[Device] failed to archive ", elem.(*l8myfamily.Device).Id, ": ", err.Error())
+			continue
+		}
+		if device != nil {
+			archived++
+		}
+	}
+	return archived
+}
+
+// archiveDevice archives the device unless it posted since it was found
+// stale, it returns nil when it was not archived
+func archiveDevice(deviceId string, now time.Time) (*l8myfamily.Device, error) {
+	defer deviceLocks.lock(deviceId)()
+	elem, err := storage.Get(deviceId)
+	if err != nil {
+		return nil, nil
+	}
+	stored := elem.(*l8myfamily.Device)
+	if stored.ArchivedTime != 0 || !IsStale(stored, now) {
+		return nil, nil
+	}
+	// The stored device is shared with the cache, it is changed on a copy
+	device := proto.Clone(stored).(*l8myfamily.Device)
+	device.ArchivedTime = now.UnixMilli()
+	if err := storage.Put(deviceId, device); err != nil {
+		return nil, err
+	}
+	deviceStream.Publish(device)
+	fmt.Println("[Device] ", deviceId, "-", device.FamilyId, " archived, last active ", lastActivity(stored).UTC().Format(time.RFC3339))
+	audit.Log("device-archived", deviceId, deviceId, device.FamilyId,
+		"lastActive="+lastActivity(stored).UTC().Format(time.RFC3339))
+	return device, nil
+}
+
+// RestoreDevice returns an archived device of the caller family to the
+// active devices and returns it, nil when the device is not in the caller
+// family or not visible to the caller. An active device is returned as is.
+func RestoreDevice(caller *auth.Caller, deviceId string) (*l8myfamily.Device, error) {
+	if storage == nil {
+		return nil, fmt.Errorf("device service is not active")
+	}
+	defer deviceLocks.lock(deviceId)()
+	elem, err := storage.Get(deviceId)
+	if err != nil {
+		return nil, nil
+	}
+	stored := elem.(*l8myfamily.Device)
+	if stored.FamilyId != caller.FamilyId || !CanSee(stored, caller) {
+		return nil, nil
+	}
+	// Like an emergency, the family account, a family admin or an admin
+	if !canClearEmergency(stored, caller) {
+		return nil, ErrRestoreDenied
+	}
+	if stored.ArchivedTime == 0 {
+		return stored, nil
+	}
+	device := proto.Clone(stored).(*l8myfamily.Device)
+	device.ArchivedTime = 0
+	// The write stamps the device modified, it is archived again only after
+	// another threshold without activity
+	if err := storage.Put(deviceId, device); err != nil {
+		return nil, err
+	}
+	deviceStream.Publish(device)
+	audit.Log("device-restored", caller.User, deviceId, device.FamilyId,
+		"archived="+time.UnixMilli(stored.ArchivedTime).UTC().Format(time.RFC3339))
+	return device, nil
+}
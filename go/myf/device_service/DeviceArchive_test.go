@@ -0,0 +1,119 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package device_service
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/saichler/l8myfamiliy/go/myf/audit"
+	"github.com/saichler/l8myfamiliy/go/myf/auth"
+	"github.com/saichler/l8myfamiliy/go/myf/config"
+	"github.com/saichler/l8myfamiliy/go/types/l8myfamily"
+	"github.com/saichler/l8types/go/ifs"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+func TestArchiveStaleDevices(t *testing.T) {
+	storeFamily(t)
+	defer func() { storage = nil; config.Set(config.Default()) }()
+	cfg := config.Get()
+	cfg.DataDir = t.TempDir()
+	cfg.ArchiveAfterSeconds = 3600
+	config.Set(cfg)
+	family := &auth.Caller{User: "fam", FamilyId: "fam"}
+
+	if archived := sweepArchive(time.Now()); archived != 0 {
+		t.Fatalf("the devices just stored are active, archived %d", archived)
+	}
+	// A family threshold below 0 never archives its devices
+	cfg.Families["fam"].ArchiveAfterSeconds = -1
+	if archived := sweepArchive(time.Now().Add(2 * time.Hour)); archived != 0 {
+		t.Fatalf("the family must not be archived, archived %d", archived)
+	}
+	cfg.Families["fam"].ArchiveAfterSeconds = 0
+	later := time.Now().Add(2 * time.Hour)
+	if archived := sweepArchive(later); archived != 2 {
+		t.Fatalf("expected the 2 stale devices archived, archived %d", archived)
+	}
+	if stored := storedDevice(t, "mom-phone"); stored.ArchivedTime != later.UnixMilli() {
+		t.Fatalf("expected the archive time stored, got %v", stored)
+	}
+	if got := deviceIds(ListDevices(&DeviceFilter{FamilyId: "fam", Viewer: family})); got != "" {
+		t.Fatalf("the archived devices must leave the device list, got %s", got)
+	}
+	if got := deviceIds(ListDevices(&DeviceFilter{FamilyId: "fam", Viewer: family, Archived: true})); got != "mom-phone,teen-phone" {
+		t.Fatalf("expected the archived devices listed, got %s", got)
+	}
+	if archived := sweepArchive(later.Add(2 * time.Hour)); archived != 0 {
+		t.Fatalf("an archived device must not be archived again, archived %d", archived)
+	}
+	records, _ := audit.Read()
+	if len(records) != 2 || records[0].Action != "device-archived" {
+		t.Fatalf("expected the archives audited, got %v", records)
+	}
+}
+
+func TestDeviceArchiveHandler(t *testing.T) {
+	storeFamily(t)
+	defer func() { storage = nil; config.Set(config.Default()) }()
+	cfg := config.Get()
+	cfg.DataDir = t.TempDir()
+	cfg.ArchiveAfterSeconds = 3600
+	config.Set(cfg)
+	origValidate := auth.ValidateToken
+	auth.ValidateToken = func(token string, vnic ifs.IVNic) (string, bool) { return token, true }
+	defer func() { auth.ValidateToken = origValidate }()
+	sweepArchive(time.Now().Add(2 * time.Hour))
+
+	call := func(method, query, token string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, "/my-family/device-archive"+query, nil)
+		r.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		deviceArchiveHandler(w, r, nil)
+		return w
+	}
+
+	// The sibling does not see the restricted teen phone
+	w := call(http.MethodGet, "", "sib-login")
+	list := &l8myfamily.DeviceList{}
+	if w.Code != http.StatusOK || protojson.Unmarshal(w.Body.Bytes(), list) != nil || deviceIds(list.List) != "mom-phone" {
+		t.Fatalf("expected the visible archived device, got %d %s", w.Code, w.Body.String())
+	}
+	if w := call(http.MethodDelete, "?deviceId=mom-phone", "sib-login"); w.Code != http.StatusForbidden {
+		t.Fatalf("a member must not restore a device, got %d", w.Code)
+	}
+	if w := call(http.MethodDelete, "?deviceId=teen-phone", "sib-login"); w.Code != http.StatusNotFound {
+		t.Fatalf("a hidden device must not be found, got %d", w.Code)
+	}
+	if w := call(http.MethodDelete, "", "fam"); w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 without a device, got %d", w.Code)
+	}
+	w = call(http.MethodDelete, "?deviceId=mom-phone", "fam")
+	device := &l8myfamily.Device{}
+	if w.Code != http.StatusOK || protojson.Unmarshal(w.Body.Bytes(), device) != nil || device.ArchivedTime != 0 {
+		t.Fatalf("the family account must restore the device, got %d %s", w.Code, w.Body.String())
+	}
+	if got := deviceIds(ListDevices(&DeviceFilter{FamilyId: "fam", Viewer: &auth.Caller{User: "fam", FamilyId: "fam"}})); got != "mom-phone" {
+		t.Fatalf("the restored device must be listed again, got %s", got)
+	}
+	// The restore stamps the device modified, it is not archived right away
+	if archived := sweepArchive(time.Now()); archived != 0 {
+		t.Fatalf("the restored device must stay active, archived %d", archived)
+	}
+}
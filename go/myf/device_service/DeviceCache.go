@@ -0,0 +1,149 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package device_service
+
+import (
+	"container/list"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/saichler/l8myfamiliy/go/types/l8myfamily"
+	"google.golang.org/protobuf/proto"
+)
+
+// DeviceCache is an LRU cache of the recently read and written devices.
+// It holds private copies, callers always get a clone they can modify.
+type DeviceCache struct {
+	mtx     *sync.Mutex
+	size    int
+	ttl     time.Duration
+	entries map[string]*list.Element
+	lru     *list.List
+	hits    atomic.Int64
+	misses  atomic.Int64
+}
+
+type cacheEntry struct {
+	key     string
+	device  *l8myfamily.Device
+	expires time.Time
+}
+
+// CacheStats is the hit / miss metric of the device cache
+type CacheStats struct {
+	Size     int     `json:"size"`
+	Capacity int     `json:"capacity"`
+	Hits     int64   `json:"hits"`
+	Misses   int64   `json:"misses"`
+	HitRatio float64 `json:"hitRatio"`
+}
+
+// newDeviceCache creates a cache of up to size devices, each kept for up to
+// ttl. A size of 0 or less disables the cache.
+func newDeviceCache(size int, ttl time.Duration) *DeviceCache {
+	return &DeviceCache{mtx: &sync.Mutex{}, size: size, ttl: ttl,
+		entries: make(map[string]*list.Element), lru: list.New()}
+}
+
+// get returns a clone of the cached device or nil on a miss
+func (this *DeviceCache) get(k string) *l8myfamily.Device {
+	return this.lookup(k, true)
+}
+
+// lookup is get, count is false for the lookups of a full scan so they do
+// not skew the hit ratio
+func (this *DeviceCache) lookup(k string, count bool) *l8myfamily.Device {
+	if this.size <= 0 {
+		return nil
+	}
+	this.mtx.Lock()
+	defer this.mtx.Unlock()
+	elem, ok := this.entries[k]
+	if !ok {
+		if count {
+			this.misses.Add(1)
+		}
+		return nil
+	}
+	entry := elem.Value.(*cacheEntry)
+	if this.ttl > 0 && time.Now().After(entry.expires) {
+		this.lru.Remove(elem)
+		delete(this.entries, k)
+		if count {
+			this.misses.Add(1)
+		}
+		return nil
+	}
+	if count {
+		this.lru.MoveToFront(elem)
+		this.hits.Add(1)
+	}
+	return proto.Clone(entry.device).(*l8myfamily.Device)
+}
+
+// put caches a copy of the device, evicting the least recently used device
+// when the cache is full
+func (this *DeviceCache) put(k string, device *l8myfamily.Device) {
+	if this.size <= 0 {
+		return
+	}
+	entry := &cacheEntry{key: k, device: proto.Clone(device).(*l8myfamily.Device),
+		expires: time.Now().Add(this.ttl)}
+	this.mtx.Lock()
+	defer this.mtx.Unlock()
+	if elem, ok := this.entries[k]; ok {
+		elem.Value = entry
+		this.lru.MoveToFront(elem)
+		return
+	}
+	this.entries[k] = this.lru.PushFront(entry)
+	for this.lru.Len() > this.size {
+		oldest := this.lru.Back()
+		this.lru.Remove(oldest)
+		delete(this.entries, oldest.Value.(*cacheEntry).key)
+	}
+}
+
+// invalidate drops the device from the cache
+func (this *DeviceCache) invalidate(k string) {
+	this.mtx.Lock()
+	defer this.mtx.Unlock()
+	if elem, ok := this.entries[k]; ok {
+		this.lru.Remove(elem)
+		delete(this.entries, k)
+	}
+}
+
+// clear drops all the cached devices
+func (this *DeviceCache) clear() {
+	this.mtx.Lock()
+	defer this.mtx.Unlock()
+	this.entries = make(map[string]*list.Element)
+	this.lru.Init()
+}
+
+// Stats returns the cache hit / miss metric
+func (this *DeviceCache) Stats() *CacheStats {
+	this.mtx.Lock()
+	size := this.lru.Len()
+	this.mtx.Unlock()
+	stats := &CacheStats{Size: size, Capacity: this.size, Hits: this.hits.Load(), Misses: this.misses.Load()}
+	if total := stats.Hits + stats.Misses; total > 0 {
+		stats.HitRatio = float64(stats.Hits) / float64(total)
+	}
+	return stats
+}
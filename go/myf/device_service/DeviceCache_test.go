@@ -0,0 +1,77 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package device_service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/saichler/l8myfamiliy/go/types/l8myfamily"
+)
+
+func TestDeviceCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	cache := newDeviceCache(2, time.Minute)
+	cache.put("d1", &l8myfamily.Device{Id: "d1"})
+	cache.put("d2", &l8myfamily.Device{Id: "d2"})
+	cache.get("d1")
+	cache.put("d3", &l8myfamily.Device{Id: "d3"})
+	if cache.get("d2") != nil {
+		t.Fatal("d2 is the least recently used and must be evicted")
+	}
+	if cache.get("d1") == nil || cache.get("d3") == nil {
+		t.Fatal("d1 and d3 must still be cached")
+	}
+	stats := cache.Stats()
+	if stats.Hits != 3 || stats.Misses != 1 || stats.Size != 2 {
+		t.Fatalf("unexpected stats %+v", stats)
+	}
+}
+
+func TestDeviceCacheExpiresAndCopies(t *testing.T) {
+	cache := newDeviceCache(10, 20*time.Millisecond)
+	device := &l8myfamily.Device{Id: "d1", Name: "Phone"}
+	cache.put("d1", device)
+	device.Name = "changed"
+	got := cache.get("d1")
+	if got.Name != "Phone" {
+		t.Fatal("the cache must hold its own copy")
+	}
+	got.Name = "changed"
+	if cache.get("d1").Name != "Phone" {
+		t.Fatal("a caller must not modify the cached device")
+	}
+	time.Sleep(30 * time.Millisecond)
+	if cache.get("d1") != nil {
+		t.Fatal("the device must expire after the ttl")
+	}
+}
+
+func TestStorageCacheWriteThrough(t *testing.T) {
+	store := newTestStorage(t)
+	store.Put("d1", &l8myfamily.Device{Id: "d1", FamilyId: "fam", Latitude: 1})
+	store.Put("d1", &l8myfamily.Device{Id: "d1", FamilyId: "fam", Latitude: 2})
+	got, err := store.Get("d1")
+	if err != nil || got.(*l8myfamily.Device).Latitude != 2 {
+		t.Fatalf("expected the last written device, got %v %v", got, err)
+	}
+	if stats := store.CacheStats(); stats.Hits != 1 {
+		t.Fatalf("a read after a write must hit the cache, got %+v", stats)
+	}
+	store.Delete("d1")
+	if _, err := store.Get("d1"); err == nil {
+		t.Fatal("a deleted device must not be served from the cache")
+	}
+}
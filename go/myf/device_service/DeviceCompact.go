@@ -0,0 +1,88 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package device_service
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// CompactStats reports what a Compact run found and fixed
+type CompactStats struct {
+	Scanned  int      `json:"scanned"`
+	Valid    int      `json:"valid"`
+	Removed  []string `json:"removed,omitempty"`
+	Families int      `json:"families"`
+}
+
+// Compact verifies every stored device unmarshals, removes zero-byte and corrupt
+// files and rebuilds the family index from the remaining devices.
+func (this *DeviceStorage) Compact() (*CompactStats, error) {
+	files, err := os.ReadDir(this.dir)
+	if err != nil {
+		return nil, err
+	}
+	stats := &CompactStats{}
+	for _, file := range files {
+		if file.IsDir() {
+			continue
+		}
+		filename := this.buildFilename(file.Name())
+		if strings.HasPrefix(file.Name(), tempPrefix) {
+			// Leftover of a write interrupted by a crash
+			fmt.Println("[Compact] removing stale temp file ", filename)
+			os.Remove(filename)
+			continue
+		}
+		stats.Scanned++
+		reason := verifyDeviceFile(filename)
+		if reason == "" {
+			stats.Valid++
+			continue
+		}
+		fmt.Println("[Compact] removing ", filename, ": ", reason)
+		if err := os.Remove(filename); err != nil {
+			fmt.Println("[Compact] failed to remove ", filename, ": ", err.Error())
+			continue
+		}
+		stats.Removed = append(stats.Removed, file.Name())
+	}
+	this.cache.clear()
+	this.rebuildIndex()
+	this.indexMtx.RLock()
+	stats.Families = len(this.familyIndex)
+	this.indexMtx.RUnlock()
+	fmt.Println("[Compact] scanned ", stats.Scanned, " valid ", stats.Valid,
+		" removed ", len(stats.Removed), " families ", stats.Families)
+	return stats, nil
+}
+
+// verifyDeviceFile returns why the file is not a valid device, or "" if it is
+func verifyDeviceFile(filename string) string {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return err.Error()
+	}
+	device, err := decodeDevice(data)
+	if err != nil {
+		return err.Error()
+	}
+	if device.Id == "" {
+		return "corrupt: missing device id"
+	}
+	return ""
+}
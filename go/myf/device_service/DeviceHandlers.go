@@ -0,0 +1,523 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package device_service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/saichler/l8myfamiliy/go/myf/audit"
+	"github.com/saichler/l8myfamiliy/go/myf/auth"
+	"github.com/saichler/l8myfamiliy/go/myf/webapi"
+	"github.com/saichler/l8myfamiliy/go/types/l8myfamily"
+	"github.com/saichler/l8types/go/ifs"
+)
+
+// RegisterHandlers registers the raw device endpoints that are not served by
+// the service framework
+func RegisterHandlers(mux *http.ServeMux, vnic ifs.IVNic) {
+	mux.HandleFunc(webapi.AdminPrefix+"compact", func(w http.ResponseWriter, r *http.Request) {
+		compactHandler(w, r, vnic)
+	})
+	mux.HandleFunc(webapi.AdminPrefix+"cache", func(w http.ResponseWriter, r *http.Request) {
+		cacheStatsHandler(w, r, vnic)
+	})
+	mux.HandleFunc(webapi.AdminPrefix+"allowlist", webapi.LimitBody(func(w http.ResponseWriter, r *http.Request) {
+		allowlistHandler(w, r, vnic)
+	}))
+	mux.HandleFunc(fmt.Sprintf("%s%d/summary", webapi.Prefix, ServiceArea), webapi.Gzip(func(w http.ResponseWriter, r *http.Request) {
+		summaryHandler(w, r, vnic)
+	}))
+	mux.HandleFunc(webapi.Prefix+"device", webapi.LimitBody(func(w http.ResponseWriter, r *http.Request) {
+		registerHandler(w, r, vnic)
+	}))
+	mux.HandleFunc(webapi.Prefix+"devices", webapi.Gzip(func(w http.ResponseWriter, r *http.Request) {
+		devicesHandler(w, r, vnic)
+	}))
+	mux.HandleFunc(webapi.Prefix+"devices-near", webapi.Gzip(func(w http.ResponseWriter, r *http.Request) {
+		devicesNearHandler(w, r, vnic)
+	}))
+	mux.HandleFunc(webapi.Prefix+"health", healthHandler)
+	mux.HandleFunc(webapi.Prefix+"families", func(w http.ResponseWriter, r *http.Request) {
+		familiesHandler(w, r, vnic)
+	})
+	mux.HandleFunc(webapi.Prefix+"device-tags", webapi.LimitBody(func(w http.ResponseWriter, r *http.Request) {
+		deviceTagsHandler(w, r, vnic)
+	}))
+	mux.HandleFunc(webapi.Prefix+"device-visibility", webapi.LimitBody(func(w http.ResponseWriter, r *http.Request) {
+		deviceVisibilityHandler(w, r, vnic)
+	}))
+	mux.HandleFunc(webapi.Prefix+"device-metadata", webapi.LimitBody(func(w http.ResponseWriter, r *http.Request) {
+		deviceMetadataHandler(w, r, vnic)
+	}))
+	mux.HandleFunc(webapi.Prefix+"device-emergency", func(w http.ResponseWriter, r *http.Request) {
+		deviceEmergencyHandler(w, r, vnic)
+	})
+	mux.HandleFunc(webapi.Prefix+"device-archive", func(w http.ResponseWriter, r *http.Request) {
+		deviceArchiveHandler(w, r, vnic)
+	})
+	mux.HandleFunc(webapi.Prefix+"device-stream", func(w http.ResponseWriter, r *http.Request) {
+		deviceStreamHandler(w, r, vnic)
+	})
+}
+
+// createDevice registers the device through the service, it is a variable
+// so tests can run the registration endpoint without a service topology
+var createDevice = CreateDevice
+
+// registerHandler registers the device of an agent, in JSON or in the
+// protobuf wire format chosen by the Content-Type. The service callbacks run
+// as the authenticated caller, a registration posted to the service endpoint
+// has no caller and is rejected.
+func registerHandler(w http.ResponseWriter, r *http.Request, vnic ifs.IVNic) {
+	if !webapi.RequireMethod(w, r, http.MethodPost) {
+		return
+	}
+	caller := auth.Authenticate(w, r, vnic)
+	if caller == nil {
+		return
+	}
+	device := &l8myfamily.Device{}
+	if err := webapi.DecodeBody(r, device); err != nil {
+		http.Error(w, err.Error(), webapi.DecodeStatus(err))
+		return
+	}
+	release := auth.Bind(device, caller)
+	err := createDevice(device, vnic)
+	release()
+	if err != nil {
+		http.Error(w, err.Error(), webapi.ErrorStatus(err))
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// devicesHandler lists the devices of the caller family, optionally limited
+// to a bounding box with the minLat, minLon, maxLat and maxLon parameters, to
+// a partial device or place name with the name parameter, to the devices
+// with the tags of the tag parameters, each key or key=value, and to the
+// devices of a user with the owner parameter. With the since parameter only
+// the devices modified since the serverTime of a previous response are
+// returned. The familyId parameter selects another family the caller may
+// access and the coordFormat parameter the format of the coordinates field,
+// see geo.ParseCoordFormat.
+func devicesHandler(w http.ResponseWriter, r *http.Request, vnic ifs.IVNic) {
+	if !webapi.RequireMethod(w, r, http.MethodGet) {
+		return
+	}
+	caller := auth.AuthenticateFamily(w, r, vnic)
+	if caller == nil {
+		return
+	}
+	box, err := parseBBox(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	tags, err := parseTagFilter(r.URL.Query()["tag"])
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	since, err := parseSince(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	format, err := parseCoordFormat(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	filter := &DeviceFilter{FamilyId: caller.FamilyId, Box: box, Name: strings.TrimSpace(r.URL.Query().Get("name")), Tags: tags,
+		Viewer: caller, Since: since, Owner: ownerFilter(strings.TrimSpace(r.URL.Query().Get("owner")), caller.FamilyId)}
+	// The server time is read before the devices, a device written while
+	// they are listed is returned again by the next poll from it
+	serverTime := time.Now().UnixMilli()
+	webapi.WriteJSON(w, http.StatusOK, &l8myfamily.DeviceList{List: formatCoordinates(ListDevices(filter), format), ServerTime: serverTime})
+}
+
+// ownerFilter returns the owner of the owner parameter as its member of the
+// listed family, nil without the parameter
+func ownerFilter(user, familyId string) *auth.Caller {
+	if user == "" {
+		return nil
+	}
+	owner, err := auth.CallerFor(user).InFamily(familyId)
+	if err != nil {
+		// A user of another family only owns the devices of its ownerId
+		return &auth.Caller{User: user}
+	}
+	return owner
+}
+
+// devicesNearHandler lists the devices of the caller family within the
+// radiusMeters of the lat and lon center, the nearest first with their
+// distanceMeters, at most limit of them. The familyId and coordFormat
+// parameters are those of the device list.
+func devicesNearHandler(w http.ResponseWriter, r *http.Request, vnic ifs.IVNic) {
+	if !webapi.RequireMethod(w, r, http.MethodGet) {
+		return
+	}
+	caller := auth.AuthenticateFamily(w, r, vnic)
+	if caller == nil {
+		return
+	}
+	near, err := parseNear(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	format, err := parseCoordFormat(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	serverTime := time.Now().UnixMilli()
+	devices := ListDevicesNear(&DeviceFilter{FamilyId: caller.FamilyId, Viewer: caller}, near)
+	webapi.WriteJSON(w, http.StatusOK, &l8myfamily.DeviceList{List: formatCoordinates(devices, format), ServerTime: serverTime})
+}
+
+// familiesHandler lists the families the caller may access and its role in
+// each, its primary family first
+func familiesHandler(w http.ResponseWriter, r *http.Request, vnic ifs.IVNic) {
+	if !webapi.RequireMethod(w, r, http.MethodGet) {
+		return
+	}
+	caller := auth.Authenticate(w, r, vnic)
+	if caller == nil {
+		return
+	}
+	webapi.WriteJSON(w, http.StatusOK, &auth.FamilyAccessList{Families: caller.Families()})
+}
+
+// deviceTagsHandler sets and clears tags of a device of the caller family
+// with a TagUpdate body, PATCH ?deviceId=, and returns the device
+func deviceTagsHandler(w http.ResponseWriter, r *http.Request, vnic ifs.IVNic) {
+	if !webapi.RequireMethod(w, r, http.MethodPatch) {
+		return
+	}
+	caller := auth.Authenticate(w, r, vnic)
+	if caller == nil {
+		return
+	}
+	deviceId := r.URL.Query().Get("deviceId")
+	if deviceId == "" {
+		http.Error(w, "missing deviceId", http.StatusBadRequest)
+		return
+	}
+	update := &TagUpdate{}
+	if err := json.NewDecoder(r.Body).Decode(update); err != nil {
+		http.Error(w, "invalid body: "+err.Error(), webapi.DecodeStatus(err))
+		return
+	}
+	device, err := UpdateTags(caller.FamilyId, deviceId, update)
+	if errors.Is(err, ErrInvalidTags) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if device == nil {
+		http.Error(w, "device not found", http.StatusNotFound)
+		return
+	}
+	fmt.Println("[Device] tags of ", deviceId, " set to ", tagKeys(device.Tags))
+	webapi.WriteJSON(w, http.StatusOK, device)
+}
+
+// deviceMetadataHandler changes the metadata of a device of the caller family
+// with a MetadataUpdate body, PATCH ?deviceId=, and returns the device
+func deviceMetadataHandler(w http.ResponseWriter, r *http.Request, vnic ifs.IVNic) {
+	if !webapi.RequireMethod(w, r, http.MethodPatch) {
+		return
+	}
+	caller := auth.Authenticate(w, r, vnic)
+	if caller == nil {
+		return
+	}
+	deviceId := r.URL.Query().Get("deviceId")
+	if deviceId == "" {
+		http.Error(w, "missing deviceId", http.StatusBadRequest)
+		return
+	}
+	update := &MetadataUpdate{}
+	if err := json.NewDecoder(r.Body).Decode(update); err != nil {
+		http.Error(w, "invalid body: "+err.Error(), webapi.DecodeStatus(err))
+		return
+	}
+	device, err := UpdateMetadata(caller, deviceId, update)
+	if errors.Is(err, ErrInvalidAvatar) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if device == nil {
+		http.Error(w, "device not found", http.StatusNotFound)
+		return
+	}
+	fmt.Println("[Device] metadata of ", deviceId, " updated by ", caller.User)
+	webapi.WriteJSON(w, http.StatusOK, device)
+}
+
+// deviceVisibilityHandler replaces the members a device of the caller family
+// is shared with, PUT ?deviceId= with a VisibilityUpdate body, and returns
+// the device
+func deviceVisibilityHandler(w http.ResponseWriter, r *http.Request, vnic ifs.IVNic) {
+	if !webapi.RequireMethod(w, r, http.MethodPut) {
+		return
+	}
+	caller := auth.Authenticate(w, r, vnic)
+	if caller == nil {
+		return
+	}
+	deviceId := r.URL.Query().Get("deviceId")
+	if deviceId == "" {
+		http.Error(w, "missing deviceId", http.StatusBadRequest)
+		return
+	}
+	update := &VisibilityUpdate{}
+	if err := json.NewDecoder(r.Body).Decode(update); err != nil {
+		http.Error(w, "invalid body: "+err.Error(), webapi.DecodeStatus(err))
+		return
+	}
+	device, err := SetVisibility(caller, deviceId, update)
+	if errors.Is(err, ErrVisibilityDenied) {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	if errors.Is(err, ErrInvalidVisibility) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if device == nil {
+		http.Error(w, "device not found", http.StatusNotFound)
+		return
+	}
+	fmt.Println("[Device] ", caller.User, " shared ", deviceId, " with ", device.VisibleTo)
+	webapi.WriteJSON(w, http.StatusOK, device)
+}
+
+// deviceEmergencyHandler clears the emergency of a device of the caller
+// family, DELETE ?deviceId=, and returns the device
+func deviceEmergencyHandler(w http.ResponseWriter, r *http.Request, vnic ifs.IVNic) {
+	if !webapi.RequireMethod(w, r, http.MethodDelete) {
+		return
+	}
+	caller := auth.Authenticate(w, r, vnic)
+	if caller == nil {
+		return
+	}
+	deviceId := r.URL.Query().Get("deviceId")
+	if deviceId == "" {
+		http.Error(w, "missing deviceId", http.StatusBadRequest)
+		return
+	}
+	device, err := ClearEmergency(caller, deviceId)
+	if errors.Is(err, ErrEmergencyDenied) {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if device == nil {
+		http.Error(w, "device not found", http.StatusNotFound)
+		return
+	}
+	fmt.Println("[Device] emergency of ", deviceId, " cleared by ", caller.User)
+	webapi.WriteJSON(w, http.StatusOK, device)
+}
+
+// deviceArchiveHandler lists the archived devices of the caller family, GET
+// with the familyId and coordFormat parameters of the device list, and
+// restores one, DELETE ?deviceId=, returning the device
+func deviceArchiveHandler(w http.ResponseWriter, r *http.Request, vnic ifs.IVNic) {
+	if !webapi.RequireMethod(w, r, http.MethodGet, http.MethodDelete) {
+		return
+	}
+	if r.Method == http.MethodGet {
+		caller := auth.AuthenticateFamily(w, r, vnic)
+		if caller == nil {
+			return
+		}
+		format, err := parseCoordFormat(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		devices := formatCoordinates(ListDevices(&DeviceFilter{FamilyId: caller.FamilyId, Viewer: caller, Archived: true}), format)
+		webapi.WriteJSON(w, http.StatusOK, &l8myfamily.DeviceList{List: devices, ServerTime: time.Now().UnixMilli()})
+		return
+	}
+	caller := auth.Authenticate(w, r, vnic)
+	if caller == nil {
+		return
+	}
+	deviceId := r.URL.Query().Get("deviceId")
+	if deviceId == "" {
+		http.Error(w, "missing deviceId", http.StatusBadRequest)
+		return
+	}
+	device, err := RestoreDevice(caller, deviceId)
+	if errors.Is(err, ErrRestoreDenied) {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if device == nil {
+		http.Error(w, "device not found", http.StatusNotFound)
+		return
+	}
+	fmt.Println("[Device] ", deviceId, " restored by ", caller.User)
+	webapi.WriteJSON(w, http.StatusOK, device)
+}
+
+func compactHandler(w http.ResponseWriter, r *http.Request, vnic ifs.IVNic) {
+	if !webapi.RequireMethod(w, r, http.MethodPost) {
+		return
+	}
+	caller := auth.AuthenticateAdmin(w, r, vnic)
+	if caller == nil {
+		return
+	}
+	stats, err := storage.Compact()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	audit.Log("compact", caller.User, "", "", fmt.Sprintf("scanned=%d removed=%d", stats.Scanned, len(stats.Removed)))
+	webapi.WriteJSON(w, http.StatusOK, stats)
+}
+
+// Allowlist is the device allowlist of a family, Enabled is false for a
+// family with open registration
+type Allowlist struct {
+	FamilyId  string   `json:"familyId"`
+	Enabled   bool     `json:"enabled"`
+	DeviceIds []string `json:"deviceIds"`
+}
+
+// allowlistHandler lets an admin read (GET ?familyId=), replace (PUT with an
+// Allowlist body, which enables it) and disable (DELETE ?familyId=) the
+// device allowlist of a family
+func allowlistHandler(w http.ResponseWriter, r *http.Request, vnic ifs.IVNic) {
+	if !webapi.RequireMethod(w, r, http.MethodGet, http.MethodPut, http.MethodDelete) {
+		return
+	}
+	caller := auth.AuthenticateAdmin(w, r, vnic)
+	if caller == nil {
+		return
+	}
+	body := &Allowlist{FamilyId: r.URL.Query().Get("familyId")}
+	if r.Method == http.MethodPut {
+		if err := json.NewDecoder(r.Body).Decode(body); err != nil {
+			http.Error(w, "invalid body: "+err.Error(), webapi.DecodeStatus(err))
+			return
+		}
+	}
+	familyId := body.FamilyId
+	if familyId == "" {
+		http.Error(w, "missing familyId", http.StatusBadRequest)
+		return
+	}
+	switch r.Method {
+	case http.MethodPut:
+		if err := allowlists.Set(familyId, body.DeviceIds); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	case http.MethodDelete:
+		if err := allowlists.Remove(familyId); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+	ids, enabled := allowlists.Get(familyId)
+	if r.Method != http.MethodGet {
+		audit.Log("allowlist", caller.User, "", familyId, fmt.Sprintf("enabled=%t devices=%d", enabled, len(ids)))
+	}
+	webapi.WriteJSON(w, http.StatusOK, &Allowlist{FamilyId: familyId, Enabled: enabled, DeviceIds: ids})
+}
+
+// cacheStatsHandler returns the hit / miss metric of the device cache
+func cacheStatsHandler(w http.ResponseWriter, r *http.Request, vnic ifs.IVNic) {
+	if !webapi.RequireMethod(w, r, http.MethodGet) {
+		return
+	}
+	if auth.AuthenticateAdmin(w, r, vnic) == nil {
+		return
+	}
+	webapi.WriteJSON(w, http.StatusOK, storage.CacheStats())
+}
+
+// Health is the response of the health endpoint
+type Health struct {
+	Status string `json:"status"`
+	// SchemaVersion is the newest location post schema the server decodes,
+	// the agents learn it after authenticating
+	SchemaVersion int32          `json:"schemaVersion"`
+	Storage       *StorageStatus `json:"storage"`
+}
+
+// healthHandler reports whether the server can take posts, it answers 503
+// while the device store cannot write to disk. It needs no authentication
+// so monitoring and load balancers can call it.
+func healthHandler(w http.ResponseWriter, r *http.Request) {
+	if !webapi.RequireMethod(w, r, http.MethodGet, http.MethodHead) {
+		return
+	}
+	health := &Health{Status: "ok", SchemaVersion: webapi.LocationSchemaVersion, Storage: &StorageStatus{}}
+	if storage != nil {
+		health.Storage = storage.Status()
+	}
+	status := http.StatusOK
+	if health.Storage.Degraded {
+		health.Status = "degraded"
+		status = http.StatusServiceUnavailable
+	}
+	webapi.WriteJSON(w, status, health)
+}
+
+// summaryHandler returns the summary of the caller family in one call, the
+// familyId parameter selects another family the caller may access
+func summaryHandler(w http.ResponseWriter, r *http.Request, vnic ifs.IVNic) {
+	if !webapi.RequireMethod(w, r, http.MethodGet) {
+		return
+	}
+	caller := auth.AuthenticateFamily(w, r, vnic)
+	if caller == nil {
+		return
+	}
+	webapi.WriteJSON(w, http.StatusOK, Summarize(caller.FamilyId, caller, time.Now()))
+}
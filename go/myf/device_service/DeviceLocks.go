@@ -0,0 +1,65 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package device_service
+
+import (
+	"hash/fnv"
+	"sync"
+
+	"github.com/saichler/l8myfamiliy/go/types/l8myfamily"
+	"google.golang.org/protobuf/proto"
+)
+
+// deviceLockStripes is the number of locks the device ids are spread over
+const deviceLockStripes = 64
+
+// deviceLocks serializes the read-modify-write of a stored device, so a
+// location update, a re-registration and a tag, visibility or avatar change
+// of one device do not lose one another
+var deviceLocks = newDeviceLocks(deviceLockStripes)
+
+// DeviceLocks is a fixed set of locks the device ids are hashed to, the
+// devices of one stripe share a lock
+type DeviceLocks struct {
+	stripes []sync.Mutex
+}
+
+func newDeviceLocks(stripes int) *DeviceLocks {
+	return &DeviceLocks{stripes: make([]sync.Mutex, stripes)}
+}
+
+// lock locks the device id and returns the function that unlocks it
+func (this *DeviceLocks) lock(id string) func() {
+	h := fnv.New32a()
+	h.Write([]byte(id))
+	mtx := &this.stripes[h.Sum32()%uint32(len(this.stripes))]
+	mtx.Lock()
+	return mtx.Unlock
+}
+
+// WithDevice runs fn with a copy of the stored device under the device lock,
+// so no write of the device interleaves with fn. An unknown device is nil.
+// fn should only take a snapshot, the writes of the device wait for it.
+func WithDevice(id string, fn func(*l8myfamily.Device) error) error {
+	defer deviceLocks.lock(id)()
+	var device *l8myfamily.Device
+	if storage != nil {
+		if elem, err := storage.Get(id); err == nil {
+			device = proto.Clone(elem.(*l8myfamily.Device)).(*l8myfamily.Device)
+		}
+	}
+	return fn(device)
+}
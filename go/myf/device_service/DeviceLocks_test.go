@@ -0,0 +1,46 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package device_service
+
+import (
+	"testing"
+
+	"github.com/saichler/l8myfamiliy/go/types/l8myfamily"
+)
+
+func TestWithDevice(t *testing.T) {
+	storage = newTestStorage(t)
+	defer func() { storage = nil }()
+	storage.Put("phone", &l8myfamily.Device{Id: "phone", FamilyId: "fam", Name: "Phone"})
+
+	WithDevice("phone", func(device *l8myfamily.Device) error {
+		if device == nil || device.Name != "Phone" {
+			t.Fatalf("expected the stored device, got %v", device)
+		}
+		// The device is a copy, changing it does not change the store
+		device.Name = "changed"
+		return nil
+	})
+	if storedDevice(t, "phone").Name != "Phone" {
+		t.Fatal("WithDevice must pass a copy of the stored device")
+	}
+	WithDevice("none", func(device *l8myfamily.Device) error {
+		if device != nil {
+			t.Fatalf("expected nil for an unknown device, got %v", device)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,97 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package device_service
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/saichler/l8myfamiliy/go/myf/auth"
+	"github.com/saichler/l8myfamiliy/go/types/l8myfamily"
+	"google.golang.org/protobuf/proto"
+)
+
+// MaxAvatarLength bounds an avatar URL, in bytes
+const MaxAvatarLength = 512
+
+// AvatarIcons are the icon ids of the markers the web map draws, an avatar
+// is one of them or an https image URL
+var AvatarIcons = map[string]bool{
+	"person": true, "child": true, "baby": true, "senior": true,
+	"car": true, "bike": true, "dog": true, "cat": true,
+	"phone": true, "laptop": true, "watch": true, "bag": true,
+}
+
+// ErrInvalidAvatar is returned for an avatar that is neither an icon id nor
+// an https URL
+var ErrInvalidAvatar = errors.New("invalid avatar")
+
+// MetadataUpdate changes the metadata of a device, a nil field is kept and
+// an empty one is cleared
+type MetadataUpdate struct {
+	Avatar *string `json:"avatar,omitempty"`
+}
+
+// ValidateAvatar checks the avatar is empty, a known icon id or an https URL
+// of an image host
+func ValidateAvatar(avatar string) error {
+	if avatar == "" || AvatarIcons[avatar] {
+		return nil
+	}
+	if len(avatar) > MaxAvatarLength {
+		return fmt.Errorf("%w: an avatar URL has at most %d bytes", ErrInvalidAvatar, MaxAvatarLength)
+	}
+	u, err := url.Parse(avatar)
+	if err != nil || u.Scheme != "https" || u.Host == "" || u.User != nil ||
+		strings.ContainsAny(avatar, " \"'<>\\") {
+		return fmt.Errorf("%w: %q is neither a known icon nor an https URL", ErrInvalidAvatar, avatar)
+	}
+	return nil
+}
+
+// UpdateMetadata applies the update to a device of the caller family and
+// returns the updated device, nil when the device is not in the family or
+// not visible to the caller
+func UpdateMetadata(caller *auth.Caller, deviceId string, update *MetadataUpdate) (*l8myfamily.Device, error) {
+	if storage == nil {
+		return nil, fmt.Errorf("device service is not active")
+	}
+	if update.Avatar != nil {
+		if err := ValidateAvatar(*update.Avatar); err != nil {
+			return nil, err
+		}
+	}
+	defer deviceLocks.lock(deviceId)()
+	elem, err := storage.Get(deviceId)
+	if err != nil {
+		return nil, nil
+	}
+	stored := elem.(*l8myfamily.Device)
+	if stored.FamilyId != caller.FamilyId || !CanSee(stored, caller) {
+		return nil, nil
+	}
+	// The stored device is shared with the cache, it is changed on a copy
+	device := proto.Clone(stored).(*l8myfamily.Device)
+	if update.Avatar != nil {
+		device.Avatar = *update.Avatar
+	}
+	if err := storage.Put(deviceId, device); err != nil {
+		return nil, err
+	}
+	return device, nil
+}
@@ -0,0 +1,106 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package device_service
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"github.com/saichler/l8myfamiliy/go/myf/geo"
+	"github.com/saichler/l8myfamiliy/go/types/l8myfamily"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	// maxNearRadiusMeters caps the radius of a near query
+	maxNearRadiusMeters = 100000.0
+	// maxNearResults caps the devices of a near query, the nearest first
+	maxNearResults = 50
+)
+
+// NearQuery selects the devices within RadiusMeters of a center, at most
+// Limit of them
+type NearQuery struct {
+	Latitude     float64
+	Longitude    float64
+	RadiusMeters float64
+	Limit        int
+}
+
+// parseNear reads the lat, lon, radiusMeters and limit query parameters, the
+// limit defaults to maxNearResults
+func parseNear(r *http.Request) (*NearQuery, error) {
+	query := r.URL.Query()
+	values := make([]float64, 3)
+	for i, name := range []string{"lat", "lon", "radiusMeters"} {
+		v := query.Get(name)
+		if v == "" {
+			return nil, fmt.Errorf("a near query needs lat, lon and radiusMeters")
+		}
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %s", name, v)
+		}
+		values[i] = f
+	}
+	near := &NearQuery{Latitude: values[0], Longitude: values[1], RadiusMeters: values[2], Limit: maxNearResults}
+	if near.Latitude < -90 || near.Latitude > 90 || near.Longitude < -180 || near.Longitude > 180 {
+		return nil, fmt.Errorf("invalid center %v,%v", near.Latitude, near.Longitude)
+	}
+	if near.RadiusMeters <= 0 || near.RadiusMeters > maxNearRadiusMeters {
+		return nil, fmt.Errorf("invalid radiusMeters %v, expected up to %v", near.RadiusMeters, maxNearRadiusMeters)
+	}
+	if v := query.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 1 || limit > maxNearResults {
+			return nil, fmt.Errorf("invalid limit %s, expected 1..%d", v, maxNearResults)
+		}
+		near.Limit = limit
+	}
+	return near, nil
+}
+
+// hasPosition returns false for a device that never reported a position, a
+// heartbeat-only one or one that only posted heartbeats
+func hasPosition(device *l8myfamily.Device) bool {
+	return !device.HeartbeatOnly && (device.PositionTime != 0 || device.Latitude != 0 || device.Longitude != 0)
+}
+
+// ListDevicesNear returns the devices of the filter within the radius of the
+// center, the nearest first, each a copy with its distanceMeters
+func ListDevicesNear(filter *DeviceFilter, near *NearQuery) []*l8myfamily.Device {
+	devices := make([]*l8myfamily.Device, 0)
+	for _, device := range ListDevices(filter) {
+		if !hasPosition(device) {
+			continue
+		}
+		distance := geo.Distance(near.Latitude, near.Longitude, float64(device.Latitude), float64(device.Longitude))
+		if distance > near.RadiusMeters {
+			continue
+		}
+		// The listed devices are shared with the cache
+		device = proto.Clone(device).(*l8myfamily.Device)
+		device.DistanceMeters = float32(distance)
+		devices = append(devices, device)
+	}
+	sort.SliceStable(devices, func(i, j int) bool { return devices[i].DistanceMeters < devices[j].DistanceMeters })
+	if len(devices) > near.Limit {
+		devices = devices[:near.Limit]
+	}
+	return devices
+}
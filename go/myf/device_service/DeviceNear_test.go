@@ -0,0 +1,89 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package device_service
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/saichler/l8myfamiliy/go/myf/auth"
+	"github.com/saichler/l8myfamiliy/go/myf/config"
+	"github.com/saichler/l8myfamiliy/go/types/l8myfamily"
+	"github.com/saichler/l8types/go/ifs"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+func TestDevicesNear(t *testing.T) {
+	storeFamily(t)
+	origValidate := auth.ValidateToken
+	auth.ValidateToken = func(token string, vnic ifs.IVNic) (string, bool) { return token, true }
+	defer func() { storage = nil; config.Set(config.Default()); auth.ValidateToken = origValidate }()
+	// The center is 1km west of the antimeridian on the equator, a degree of
+	// longitude is about 111km there
+	for _, device := range []*l8myfamily.Device{
+		{Id: "nearest", Latitude: 0, Longitude: 179.996, PositionTime: 1},
+		{Id: "inside", Latitude: 0, Longitude: -179.9961, PositionTime: 1},
+		{Id: "outside", Latitude: 0, Longitude: -179.9959, PositionTime: 1},
+		{Id: "far", Latitude: 10, Longitude: 179.995, PositionTime: 1},
+		{Id: "restricted", Latitude: 0, Longitude: 179.995, PositionTime: 1, MemberId: "teen", VisibleTo: []string{"dad"}},
+		{Id: "heartbeat", HeartbeatOnly: true},
+	} {
+		device.FamilyId = "fam"
+		storage.Put(device.Id, device)
+	}
+	storage.Put("other", &l8myfamily.Device{Id: "other", FamilyId: "other", Latitude: 0, Longitude: 179.995, PositionTime: 1})
+
+	near := func(token, query string) (*l8myfamily.DeviceList, int) {
+		r := httptest.NewRequest("GET", "/my-family/devices-near?"+query, nil)
+		r.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		devicesNearHandler(w, r, nil)
+		result := &l8myfamily.DeviceList{}
+		protojson.Unmarshal(w.Body.Bytes(), result)
+		return result, w.Code
+	}
+	list, code := near("fam", "lat=0&lon=179.995&radiusMeters=1000")
+	if code != http.StatusOK || deviceIds(list.List) != "restricted,nearest,inside" {
+		t.Fatalf("expected the devices within 1km nearest first, got %d %s", code, deviceIds(list.List))
+	}
+	if d := list.List[2].DistanceMeters; d < 980 || d > 1000 {
+		t.Fatalf("the device across the antimeridian must be about 990m away, got %v", d)
+	}
+	if elem, _ := storage.Get("inside"); elem.(*l8myfamily.Device).DistanceMeters != 0 {
+		t.Fatal("the distance must not be stored")
+	}
+	// The sibling does not see the restricted device
+	if list, _ := near("sib-login", "lat=0&lon=179.995&radiusMeters=1000&limit=1"); deviceIds(list.List) != "nearest" {
+		t.Fatalf("expected the nearest visible device only, got %s", deviceIds(list.List))
+	}
+	// A device that never posted a position is not at 0,0
+	if list, _ := near("fam", "lat=0&lon=0&radiusMeters=1000"); len(list.List) != 0 {
+		t.Fatalf("the devices without a position must not be listed, got %s", deviceIds(list.List))
+	}
+	for _, query := range []string{
+		"lat=0&lon=179.995",
+		"lat=91&lon=0&radiusMeters=10",
+		"lat=0&lon=0&radiusMeters=0",
+		"lat=0&lon=0&radiusMeters=100001",
+		"lat=0&lon=0&radiusMeters=10&limit=51",
+		"lat=north&lon=0&radiusMeters=10",
+	} {
+		if _, code := near("fam", query); code != http.StatusBadRequest {
+			t.Fatalf("%s must be rejected, got %d", query, code)
+		}
+	}
+}
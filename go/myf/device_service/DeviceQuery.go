@@ -0,0 +1,167 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package device_service
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/saichler/l8myfamiliy/go/myf/auth"
+	"github.com/saichler/l8myfamiliy/go/myf/geo"
+	"github.com/saichler/l8myfamiliy/go/types/l8myfamily"
+	"google.golang.org/protobuf/proto"
+)
+
+// DeviceFilter selects the devices returned by the device list query
+type DeviceFilter struct {
+	// FamilyId limits the result to the devices of the family
+	FamilyId string
+	// Box, when set, limits the result to the devices whose last position
+	// is inside the box
+	Box *geo.BBox
+	// Name, when set, limits the result to the devices whose name or
+	// reported place contains it, case-insensitive
+	Name string
+	// Tags, when set, limits the result to the devices with every tag, an
+	// empty value matches any value of the key
+	Tags map[string]string
+	// Viewer, when set, limits the result to the devices it may see
+	Viewer *auth.Caller
+	// Since, when set, limits the result to the devices modified at or
+	// after it, in unix millis
+	Since int64
+	// Archived selects the archived devices instead of the active ones
+	Archived bool
+	// Owner, when set, limits the result to the devices it owns, see isOwner
+	Owner *auth.Caller
+}
+
+// Match returns true if the device passes the filter
+func (this *DeviceFilter) Match(device *l8myfamily.Device) bool {
+	if device.FamilyId != this.FamilyId || (device.ArchivedTime != 0) != this.Archived {
+		return false
+	}
+	if this.Owner != nil && !isOwner(device, this.Owner) {
+		return false
+	}
+	if this.Since > 0 && device.ModifiedAt < this.Since {
+		return false
+	}
+	if this.Box != nil && !this.Box.Contains(float64(device.Latitude), float64(device.Longitude)) {
+		return false
+	}
+	if this.Name != "" && !containsFold(device.Name, this.Name) && !containsFold(device.Place, this.Name) {
+		return false
+	}
+	if !matchTags(device, this.Tags) {
+		return false
+	}
+	if !CanSee(device, this.Viewer) {
+		return false
+	}
+	return true
+}
+
+// containsFold returns whether s contains substr, ignoring case
+func containsFold(s, substr string) bool {
+	return strings.Contains(strings.ToLower(s), strings.ToLower(substr))
+}
+
+// ListDevices returns the devices that pass the filter, sorted by id
+func ListDevices(filter *DeviceFilter) []*l8myfamily.Device {
+	if storage == nil {
+		return nil
+	}
+	collected := storage.Collect(func(elem interface{}) (bool, interface{}) {
+		device, ok := elem.(*l8myfamily.Device)
+		return ok && filter.Match(device), elem
+	})
+	devices := make([]*l8myfamily.Device, 0, len(collected))
+	for _, elem := range collected {
+		devices = append(devices, elem.(*l8myfamily.Device))
+	}
+	sort.Slice(devices, func(i, j int) bool { return devices[i].Id < devices[j].Id })
+	return devices
+}
+
+// parseSince reads the since watermark query parameter, 0 when it is not given
+func parseSince(r *http.Request) (int64, error) {
+	v := r.URL.Query().Get("since")
+	if v == "" {
+		return 0, nil
+	}
+	since, err := strconv.ParseInt(v, 10, 64)
+	if err != nil || since < 0 {
+		return 0, fmt.Errorf("invalid since: %s", v)
+	}
+	return since, nil
+}
+
+// parseCoordFormat reads the coordFormat and coordPrecision query parameters
+func parseCoordFormat(r *http.Request) (*geo.CoordFormat, error) {
+	return geo.ParseCoordFormat(r.URL.Query().Get("coordFormat"), r.URL.Query().Get("coordPrecision"))
+}
+
+// formatCoordinates returns the devices with their coordinates in the format,
+// the listed devices are shared with the cache so formatted ones are copies
+func formatCoordinates(devices []*l8myfamily.Device, format *geo.CoordFormat) []*l8myfamily.Device {
+	if format.Decimal() {
+		return devices
+	}
+	formatted := make([]*l8myfamily.Device, len(devices))
+	for i, device := range devices {
+		formatted[i] = proto.Clone(device).(*l8myfamily.Device)
+		formatted[i].Coordinates = format.Format(device.Latitude, device.Longitude)
+	}
+	return formatted
+}
+
+// bboxParams are the query parameters of the bounding box
+var bboxParams = []string{"minLat", "minLon", "maxLat", "maxLon"}
+
+// parseBBox reads the bounding box query parameters, it returns nil when none
+// is given and an error when only some are given or they are out of range
+func parseBBox(r *http.Request) (*geo.BBox, error) {
+	query := r.URL.Query()
+	values := make([]float64, len(bboxParams))
+	given := 0
+	for i, name := range bboxParams {
+		v := query.Get(name)
+		if v == "" {
+			continue
+		}
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %s", name, v)
+		}
+		values[i] = f
+		given++
+	}
+	if given == 0 {
+		return nil, nil
+	}
+	if given != len(bboxParams) {
+		return nil, fmt.Errorf("a bounding box needs minLat, minLon, maxLat and maxLon")
+	}
+	box := &geo.BBox{MinLat: values[0], MinLon: values[1], MaxLat: values[2], MaxLon: values[3]}
+	if err := box.Validate(); err != nil {
+		return nil, err
+	}
+	return box, nil
+}
@@ -0,0 +1,211 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package device_service
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/saichler/l8myfamiliy/go/myf/auth"
+	"github.com/saichler/l8myfamiliy/go/myf/config"
+	"github.com/saichler/l8myfamiliy/go/myf/geo"
+	"github.com/saichler/l8myfamiliy/go/types/l8myfamily"
+	"github.com/saichler/l8types/go/ifs"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestListDevicesInBBox(t *testing.T) {
+	storage = newTestStorage(t)
+	defer func() { storage = nil }()
+	storage.Put("fiji", &l8myfamily.Device{Id: "fiji", FamilyId: "fam", Latitude: -17.7, Longitude: 178})
+	storage.Put("samoa", &l8myfamily.Device{Id: "samoa", FamilyId: "fam", Latitude: -13.8, Longitude: -172})
+	storage.Put("london", &l8myfamily.Device{Id: "london", FamilyId: "fam", Latitude: 51.5, Longitude: -0.1})
+	storage.Put("other", &l8myfamily.Device{Id: "other", FamilyId: "other", Latitude: -17.7, Longitude: 178})
+
+	box := &geo.BBox{MinLat: -20, MinLon: 170, MaxLat: -10, MaxLon: -170}
+	devices := ListDevices(&DeviceFilter{FamilyId: "fam", Box: box})
+	if len(devices) != 2 || devices[0].Id != "fiji" || devices[1].Id != "samoa" {
+		t.Fatalf("expected fiji and samoa, got %v", devices)
+	}
+	if devices := ListDevices(&DeviceFilter{FamilyId: "fam"}); len(devices) != 3 {
+		t.Fatalf("expected all 3 family devices without a box, got %d", len(devices))
+	}
+}
+
+func TestParseBBox(t *testing.T) {
+	box, err := parseBBox(httptest.NewRequest("GET", "/my-family/devices", nil))
+	if box != nil || err != nil {
+		t.Fatal("no parameters means no box")
+	}
+	if _, err := parseBBox(httptest.NewRequest("GET", "/my-family/devices?minLat=1&minLon=2", nil)); err == nil {
+		t.Fatal("a partial box must be rejected")
+	}
+	if _, err := parseBBox(httptest.NewRequest("GET", "/my-family/devices?minLat=1&minLon=2&maxLat=x&maxLon=3", nil)); err == nil {
+		t.Fatal("a non numeric value must be rejected")
+	}
+	box, err = parseBBox(httptest.NewRequest("GET", "/my-family/devices?minLat=-20&minLon=170&maxLat=-10&maxLon=-170", nil))
+	if err != nil || box.MinLon != 170 || box.MaxLon != -170 {
+		t.Fatalf("unexpected box %v %v", box, err)
+	}
+}
+
+func TestListDevicesByName(t *testing.T) {
+	storage = newTestStorage(t)
+	defer func() { storage = nil }()
+	storage.Put("a", &l8myfamily.Device{Id: "a", FamilyId: "fam", Name: "Alice's Pixel"})
+	storage.Put("b", &l8myfamily.Device{Id: "b", FamilyId: "fam", Name: "Bob Laptop", Place: "Home"})
+	storage.Put("c", &l8myfamily.Device{Id: "c", FamilyId: "fam", Name: "Carol Phone"})
+	storage.Put("d", &l8myfamily.Device{Id: "d", FamilyId: "other", Name: "Dave Pixel"})
+
+	devices := ListDevices(&DeviceFilter{FamilyId: "fam", Name: "PIXEL"})
+	if len(devices) != 1 || devices[0].Id != "a" {
+		t.Fatalf("expected only the family pixel, got %v", devices)
+	}
+	devices = ListDevices(&DeviceFilter{FamilyId: "fam", Name: "home"})
+	if len(devices) != 1 || devices[0].Id != "b" {
+		t.Fatalf("expected the device at home, got %v", devices)
+	}
+	if devices := ListDevices(&DeviceFilter{FamilyId: "fam", Name: "zzz"}); len(devices) != 0 {
+		t.Fatalf("expected no match, got %v", devices)
+	}
+}
+
+func TestListDevicesSince(t *testing.T) {
+	storage = newTestStorage(t)
+	origValidate := auth.ValidateToken
+	auth.ValidateToken = func(token string, vnic ifs.IVNic) (string, bool) { return token, true }
+	defer func() { storage = nil; auth.ValidateToken = origValidate }()
+	storage.Put("phone", &l8myfamily.Device{Id: "phone", FamilyId: "fam", Latitude: 1})
+	storage.Put("laptop", &l8myfamily.Device{Id: "laptop", FamilyId: "fam", Latitude: 2})
+	// The watermark is inclusive, a write in the millisecond of the poll is
+	// returned again by the next one
+	time.Sleep(2 * time.Millisecond)
+
+	list := func(query string) (*l8myfamily.DeviceList, int) {
+		r := httptest.NewRequest("GET", "/my-family/devices"+query, nil)
+		r.Header.Set("Authorization", "Bearer fam")
+		w := httptest.NewRecorder()
+		devicesHandler(w, r, nil)
+		result := &l8myfamily.DeviceList{}
+		protojson.Unmarshal(w.Body.Bytes(), result)
+		return result, w.Code
+	}
+	full, code := list("")
+	if code != http.StatusOK || len(full.List) != 2 || full.ServerTime == 0 {
+		t.Fatalf("the first poll must return every device and the server time, got %d %v", code, full)
+	}
+
+	// Only the device written after the watermark is returned
+	time.Sleep(2 * time.Millisecond)
+	elem, _ := storage.Get("phone")
+	phone := proto.Clone(elem.(*l8myfamily.Device)).(*l8myfamily.Device)
+	phone.Latitude = 5
+	storage.Put("phone", phone)
+	delta, _ := list(fmt.Sprintf("?since=%d", full.ServerTime))
+	if len(delta.List) != 1 || delta.List[0].Id != "phone" || delta.List[0].Latitude != 5 {
+		t.Fatalf("expected only the moved phone, got %v", delta.List)
+	}
+	if delta.ServerTime < full.ServerTime {
+		t.Fatalf("the watermark must not go back, got %d after %d", delta.ServerTime, full.ServerTime)
+	}
+	if empty, _ := list(fmt.Sprintf("?since=%d", time.Now().Add(time.Second).UnixMilli())); len(empty.List) != 0 {
+		t.Fatalf("nothing changed after a future watermark, got %v", empty.List)
+	}
+	if _, code := list("?since=yesterday"); code != http.StatusBadRequest {
+		t.Fatalf("an invalid watermark must be rejected, got %d", code)
+	}
+}
+
+func TestListDevicesCoordFormat(t *testing.T) {
+	storage = newTestStorage(t)
+	origValidate := auth.ValidateToken
+	auth.ValidateToken = func(token string, vnic ifs.IVNic) (string, bool) { return token, true }
+	defer func() { storage = nil; auth.ValidateToken = origValidate }()
+	storage.Put("phone", &l8myfamily.Device{Id: "phone", FamilyId: "fam", Latitude: -33.8688, Longitude: 151.2093})
+
+	list := func(query string) (*l8myfamily.DeviceList, int) {
+		r := httptest.NewRequest("GET", "/my-family/devices"+query, nil)
+		r.Header.Set("Authorization", "Bearer fam")
+		w := httptest.NewRecorder()
+		devicesHandler(w, r, nil)
+		result := &l8myfamily.DeviceList{}
+		protojson.Unmarshal(w.Body.Bytes(), result)
+		return result, w.Code
+	}
+	if decimal, _ := list(""); len(decimal.List) != 1 || decimal.List[0].Coordinates != "" {
+		t.Fatalf("decimal is the default, got %v", decimal.List)
+	}
+	if dms, _ := list("?coordFormat=dms"); len(dms.List) != 1 || dms.List[0].Coordinates != `33°52'07.68"S 151°12'33.48"E` {
+		t.Fatalf("expected the dms coordinates, got %v", dms.List)
+	}
+	hash, _ := list("?coordFormat=geohash&coordPrecision=5")
+	if len(hash.List) != 1 || hash.List[0].Coordinates != "r3gx2" || hash.List[0].Latitude != float32(-33.8688) {
+		t.Fatalf("expected the geohash next to the decimal coordinates, got %v", hash.List)
+	}
+	if elem, _ := storage.Get("phone"); elem.(*l8myfamily.Device).Coordinates != "" {
+		t.Fatal("the formatted coordinates must not be stored")
+	}
+	for _, query := range []string{"?coordFormat=utm", "?coordFormat=geohash&coordPrecision=13", "?coordPrecision=5"} {
+		if _, code := list(query); code != http.StatusBadRequest {
+			t.Fatalf("%s must be rejected, got %d", query, code)
+		}
+	}
+}
+
+func TestListDevicesByOwner(t *testing.T) {
+	storage = newTestStorage(t)
+	origValidate := auth.ValidateToken
+	auth.ValidateToken = func(token string, vnic ifs.IVNic) (string, bool) { return token, true }
+	cfg := config.Default()
+	cfg.MemberLogins = map[string]*config.MemberLogin{"sib-login": {FamilyId: "fam", MemberId: "sib"}}
+	config.Set(cfg)
+	defer func() { storage = nil; auth.ValidateToken = origValidate; config.Set(config.Default()) }()
+	storage.Put("mom-phone", &l8myfamily.Device{Id: "mom-phone", FamilyId: "fam", OwnerId: "mom"})
+	// Registered before the devices had an owner, its member owns it
+	storage.Put("sib-phone", &l8myfamily.Device{Id: "sib-phone", FamilyId: "fam", MemberId: "sib"})
+	storage.Put("mom-laptop", &l8myfamily.Device{Id: "mom-laptop", FamilyId: "fam", OwnerId: "mom"})
+	storage.Put("teen-phone", &l8myfamily.Device{Id: "teen-phone", FamilyId: "fam", OwnerId: "teen"})
+	storage.Put("other-phone", &l8myfamily.Device{Id: "other-phone", FamilyId: "other", OwnerId: "mom"})
+
+	list := func(query string) string {
+		r := httptest.NewRequest("GET", "/my-family/devices"+query, nil)
+		r.Header.Set("Authorization", "Bearer fam")
+		w := httptest.NewRecorder()
+		devicesHandler(w, r, nil)
+		result := &l8myfamily.DeviceList{}
+		protojson.Unmarshal(w.Body.Bytes(), result)
+		return deviceIds(result.List)
+	}
+	if ids := list("?owner=mom"); ids != "mom-laptop,mom-phone" {
+		t.Fatalf("expected only the family devices of mom, got %v", ids)
+	}
+	if ids := list("?owner=teen"); ids != "teen-phone" {
+		t.Fatalf("expected only the teen phone, got %v", ids)
+	}
+	if ids := list("?owner=sib-login"); ids != "sib-phone" {
+		t.Fatalf("expected the device of the sibling member, got %v", ids)
+	}
+	if ids := list("?owner=nobody"); ids != "" {
+		t.Fatalf("an unknown owner has no devices, got %v", ids)
+	}
+	if ids := list("?owner=%20"); ids != "mom-laptop,mom-phone,sib-phone,teen-phone" {
+		t.Fatalf("a blank owner must not filter, got %v", ids)
+	}
+}
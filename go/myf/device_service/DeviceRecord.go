@@ -0,0 +1,103 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package device_service
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"os"
+	"path/filepath"
+
+	"github.com/saichler/l8myfamiliy/go/types/l8myfamily"
+	"google.golang.org/protobuf/proto"
+)
+
+// A stored device record is the magic, the payload length, the payload CRC32
+// and the marshaled device. Files without the magic are legacy records that
+// hold only the marshaled device, they are rewritten on the next Put.
+var recordMagic = []byte("L8D1")
+
+const (
+	recordHeaderSize = 12
+	tempPrefix       = "."
+)
+
+// ErrCorruptDevice is returned when a stored device fails its integrity check
+var ErrCorruptDevice = errors.New("corrupt device record")
+
+func encodeDevice(device *l8myfamily.Device) ([]byte, error) {
+	payload, err := proto.Marshal(device)
+	if err != nil {
+		return nil, err
+	}
+	data := make([]byte, recordHeaderSize, recordHeaderSize+len(payload))
+	copy(data, recordMagic)
+	binary.BigEndian.PutUint32(data[4:], uint32(len(payload)))
+	binary.BigEndian.PutUint32(data[8:], crc32.ChecksumIEEE(payload))
+	return append(data, payload...), nil
+}
+
+func decodeDevice(data []byte) (*l8myfamily.Device, error) {
+	device := &l8myfamily.Device{}
+	if len(data) == 0 {
+		return nil, fmt.Errorf("%w: zero-byte file", ErrCorruptDevice)
+	}
+	if !bytes.HasPrefix(data, recordMagic) {
+		if err := proto.Unmarshal(data, device); err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrCorruptDevice, err.Error())
+		}
+		return device, nil
+	}
+	if len(data) < recordHeaderSize {
+		return nil, fmt.Errorf("%w: truncated header", ErrCorruptDevice)
+	}
+	size := binary.BigEndian.Uint32(data[4:])
+	payload := data[recordHeaderSize:]
+	if uint32(len(payload)) != size {
+		return nil, fmt.Errorf("%w: expected %d bytes, found %d", ErrCorruptDevice, size, len(payload))
+	}
+	if crc32.ChecksumIEEE(payload) != binary.BigEndian.Uint32(data[8:]) {
+		return nil, fmt.Errorf("%w: checksum mismatch", ErrCorruptDevice)
+	}
+	if err := proto.Unmarshal(payload, device); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrCorruptDevice, err.Error())
+	}
+	return device, nil
+}
+
+// writeAtomic writes the data to a temp file and renames it over the target,
+// so a crash never leaves a half written record behind
+func writeAtomic(filename string, data []byte) error {
+	tmp := filepath.Join(filepath.Dir(filename), tempPrefix+filepath.Base(filename)+".tmp")
+	file, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0777)
+	if err != nil {
+		return err
+	}
+	if _, err = file.Write(data); err == nil {
+		err = file.Sync()
+	}
+	if cerr := file.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, filename)
+}
@@ -17,7 +17,13 @@ package device_service
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
+	"time"
 
+	"github.com/saichler/l8myfamiliy/go/myf/audit"
+	"github.com/saichler/l8myfamiliy/go/myf/config"
+	"github.com/saichler/l8myfamiliy/go/myf/webapi"
 	"github.com/saichler/l8myfamiliy/go/types/l8myfamily"
 	"github.com/saichler/l8services/go/services/base"
 	"github.com/saichler/l8srlz/go/serialize/object"
@@ -29,11 +35,42 @@ import (
 )
 
 const (
-	ServiceName = "Family"
-	ServiceArea = byte(53)
+	DefaultServiceName = "Family"
+	DefaultServiceArea = byte(53)
 )
 
-func Activate(vnic ifs.IVNic) {
+// primaryKey is the Device field the devices are keyed by
+const primaryKey = "Id"
+
+// DeviceKindVehicle is the kind of a device mounted in a car, its position
+// is snapped to roads when road snapping is configured
+const DeviceKindVehicle = "vehicle"
+
+// ServiceName and ServiceArea are the name and area the service was
+// activated with, they default to DefaultServiceName and DefaultServiceArea
+var (
+	ServiceName = DefaultServiceName
+	ServiceArea = DefaultServiceArea
+)
+
+// storage is the device store of the activated service
+var storage *DeviceStorage
+
+// Activate registers the service under the given name and area, an empty
+// name means DefaultServiceName. It returns an error, before anything is
+// registered, when the service configuration or the device store is invalid.
+func Activate(serviceName string, serviceArea byte, vnic ifs.IVNic) error {
+	if serviceName == "" {
+		serviceName = DefaultServiceName
+	}
+	if err := webapi.ValidateService(serviceName, serviceArea, &l8myfamily.Device{}, primaryKey); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(location, 0777); err != nil {
+		return fmt.Errorf("service %s: device store %s: %w", serviceName, location, err)
+	}
+	ServiceName = serviceName
+	ServiceArea = serviceArea
 	serviceConfig := ifs.NewServiceLevelAgreement(&base.BaseService{}, ServiceName, ServiceArea, true, &DeviceCallback{})
 
 	services := &l8services.L8Services{}
@@ -47,29 +84,210 @@ func Activate(vnic ifs.IVNic) {
 
 	serviceConfig.SetVoter(true)
 	serviceConfig.SetTransactional(false)
-	serviceConfig.SetPrimaryKeys("Id")
-	serviceConfig.SetStore(newDeviceStorage())
+	serviceConfig.SetPrimaryKeys(primaryKey)
+	registrationKeys = webapi.NewIdempotencyCache(config.IdempotencyWindow())
+	signingKeys = NewSigningKeyStore(filepath.Join(config.Get().DataDir, signingKeysFile))
+	allowlists = NewDeviceAllowlist(filepath.Join(config.Get().DataDir, allowlistFile))
+	registrations = NewRegistrationCooldown(config.RegistrationCooldown())
+	storage = newDeviceStorage(location)
+	serviceConfig.SetStore(storage)
 	webs := web.New(ServiceName, ServiceArea, 0)
 	webs.AddEndpoint(&l8myfamily.Device{}, ifs.POST, &l8web.L8Empty{})
 	webs.AddEndpoint(&l8api.L8Query{}, ifs.GET, &l8myfamily.DeviceList{})
 	base.Activate(serviceConfig, vnic)
+	StartOfflineSweep()
+	StartArchiveSweep()
+	return nil
+}
+
+// Compact runs a compaction of the device store directory, it is used by
+// the command line when the service is not running.
+func Compact() (*CompactStats, error) {
+	return newDeviceStorage(location).Compact()
 }
 
-func UpdateDevice(id string, lg, lt float32, vnic ifs.IVNic) {
+// StorageDegraded returns true while the device store cannot write to disk,
+// the posts are refused with a retryable error until it recovers
+func StorageDegraded() bool {
+	return storage != nil && storage.Degraded()
+}
+
+// GetDevice returns the registered device, or nil if it does not exist
+func GetDevice(id string, vnic ifs.IVNic) *l8myfamily.Device {
 	sv, ok := vnic.Resources().Services().ServiceHandler(ServiceName, ServiceArea)
-	if ok {
-		device := &l8myfamily.Device{Id: id, Longitude: lg, Latitude: lt}
-		exist := sv.Get(object.New(nil, device), vnic)
-		if exist != nil && exist.Error() != nil {
-			fmt.Println("Error for ", id, ": ", exist.Error())
-			return
+	if !ok {
+		return nil
+	}
+	exist := sv.Get(object.New(nil, &l8myfamily.Device{Id: id}), vnic)
+	if exist == nil || exist.Error() != nil || exist.Element() == nil {
+		return nil
+	}
+	return exist.Element().(*l8myfamily.Device)
+}
+
+// CreateDevice registers the device through the service, so it passes the
+// same checks as an agent registration
+func CreateDevice(device *l8myfamily.Device, vnic ifs.IVNic) error {
+	sv, ok := vnic.Resources().Services().ServiceHandler(ServiceName, ServiceArea)
+	if !ok {
+		return fmt.Errorf("device service is not active")
+	}
+	resp := sv.Post(object.New(nil, device), vnic)
+	if resp != nil && resp.Error() != nil {
+		return resp.Error()
+	}
+	return nil
+}
+
+// UpdateDevice applies a posted location to its registered device and returns the
+// updated device, or nil if the device does not exist.
+func UpdateDevice(location *l8myfamily.Location, vnic ifs.IVNic) *l8myfamily.Device {
+	return UpdateDevices([]*l8myfamily.Location{location}, vnic)[location.DeviceId]
+}
+
+// UpdateDevices applies a batch of posted locations to their registered devices
+// and returns the updated devices by id, devices that do not exist are missing.
+// The locations of a device are applied in order and the device is read and
+// written once, so the last location wins the position while an emergency
+// anywhere in the batch still flags the device.
+func UpdateDevices(locations []*l8myfamily.Location, vnic ifs.IVNic) map[string]*l8myfamily.Device {
+	updated := make(map[string]*l8myfamily.Device)
+	sv, ok := vnic.Resources().Services().ServiceHandler(ServiceName, ServiceArea)
+	if !ok {
+		return updated
+	}
+	order := make([]string, 0, len(locations))
+	byDevice := make(map[string][]*l8myfamily.Location)
+	for _, location := range locations {
+		if _, ok := byDevice[location.DeviceId]; !ok {
+			order = append(order, location.DeviceId)
 		}
-		if exist == nil || exist.Element() == nil {
-			fmt.Println("No Device exist for ", id)
-			return
+		byDevice[location.DeviceId] = append(byDevice[location.DeviceId], location)
+	}
+	for _, id := range order {
+		if device := updateStored(sv, id, byDevice[id], vnic); device != nil {
+			updated[id] = device
 		}
-		existDevice := exist.Element().(*l8myfamily.Device)
-		sv.Patch(object.New(nil, device), vnic)
-		fmt.Println("Device ", id, "-", existDevice.FamilyId, "-", existDevice.Name, " updated")
 	}
+	return updated
+}
+
+// updateStored applies the locations to the stored device and writes it,
+// it returns nil if the device does not exist
+func updateStored(sv ifs.IServiceHandler, id string, locations []*l8myfamily.Location, vnic ifs.IVNic) *l8myfamily.Device {
+	defer deviceLocks.lock(id)()
+	exist := sv.Get(object.New(nil, &l8myfamily.Device{Id: id}), vnic)
+	if exist != nil && exist.Error() != nil {
+		fmt.Println("Error for ", id, ": ", exist.Error())
+		return nil
+	}
+	if exist == nil || exist.Element() == nil {
+		fmt.Println("No Device exist for ", id)
+		return nil
+	}
+	existDevice := exist.Element().(*l8myfamily.Device)
+	// A post of an archived device makes it active again
+	if existDevice.ArchivedTime != 0 {
+		fmt.Println("[Device] ", id, "-", existDevice.FamilyId, " posted, no longer archived")
+		audit.Log("device-restored", id, id, existDevice.FamilyId,
+			"archived="+time.UnixMilli(existDevice.ArchivedTime).UTC().Format(time.RFC3339))
+		existDevice.ArchivedTime = 0
+	}
+	for _, location := range locations {
+		applyLocation(existDevice, location)
+	}
+	// Put the whole record rather than patch it, as a patch ignores zero
+	// values and an older agent must reset the source to unknown.
+	sv.Put(object.New(nil, existDevice), vnic)
+	deviceStream.Publish(existDevice)
+	fmt.Println("Device ", id, "-", existDevice.FamilyId, "-", existDevice.Name, " updated, ",
+		len(locations), " locations")
+	return existDevice
+}
+
+// touchLastSeen sets the device last seen time to the location time
+func touchLastSeen(device *l8myfamily.Device, location *l8myfamily.Location) {
+	device.LastSeen = location.Timestamp
+	if device.LastSeen == 0 {
+		device.LastSeen = time.Now().UnixMilli()
+	}
+}
+
+// applyLocation copies the location attributes onto the device record
+func applyLocation(device *l8myfamily.Device, location *l8myfamily.Location) {
+	// A location older than the last one seen, e.g. flushed from an agent
+	// buffer after a newer post, is history only, the device keeps its
+	// current state. An emergency is still flagged.
+	if location.Timestamp > 0 && location.Timestamp < device.LastSeen {
+		if location.Emergency {
+			flagEmergency(device, location)
+		}
+		return
+	}
+	// The battery level is a percentage, 0 means the agent did not report it
+	if location.Battery > 0 {
+		device.Battery = location.Battery
+	}
+	if interval := clampReportInterval(location.ReportIntervalSeconds); interval > 0 {
+		device.ReportIntervalSeconds = interval
+	}
+	// Telemetry is optional, a post without it keeps the last reported one
+	if telemetry := webapi.CleanTelemetry(location.Telemetry); telemetry != nil {
+		device.Telemetry = telemetry
+	}
+	// A heartbeat-only device never has a position, whatever was posted
+	if device.HeartbeatOnly {
+		clearPosition(device)
+		touchLastSeen(device, location)
+		if location.Emergency {
+			flagEmergency(device, location)
+		}
+		return
+	}
+	// A heartbeat of an agent that did not move, or a fix much less accurate
+	// than a recent one, only keeps the device online
+	if location.Heartbeat || Gated(device, location) {
+		touchLastSeen(device, location)
+		if location.Movement != "" {
+			device.Movement = location.Movement
+		}
+		return
+	}
+	device.Longitude = location.Longitude
+	device.Latitude = location.Latitude
+	device.Source = location.Source
+	device.Accuracy = location.Accuracy
+	// The place is the trusted geofence the agent is in, or the city of an
+	// IP based fix, a place-less location clears it
+	device.Place = location.Place
+	device.PublicIp = location.PublicIp
+	// An IP based fix through a VPN or proxy is the exit location
+	device.Unreliable = location.Unreliable
+	// A coarse fix is city level, e.g. GeoClue without precise access
+	device.Coarse = location.Coarse
+	// Speed (m/s), heading (degrees) and altitude (m) are reported by GPS
+	// receivers only
+	device.Speed = location.Speed
+	device.Heading = location.Heading
+	device.Altitude = location.Altitude
+	// The road snapped position of a vehicle, the raw one is kept above
+	device.SnappedLatitude = location.SnappedLatitude
+	device.SnappedLongitude = location.SnappedLongitude
+	// The movement state the location service classified the location as
+	device.Movement = location.Movement
+	touchLastSeen(device, location)
+	device.PositionTime = device.LastSeen
+	if location.Emergency {
+		flagEmergency(device, location)
+	}
+}
+
+// flagEmergency marks the device in an emergency as of now, unless the
+// emergency was posted before it was last cleared
+func flagEmergency(device *l8myfamily.Device, location *l8myfamily.Location) {
+	if EmergencyCleared(device, location) {
+		return
+	}
+	device.Emergency = true
+	device.EmergencyTime = time.Now().UnixMilli()
 }
@@ -0,0 +1,164 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package device_service
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/saichler/l8myfamiliy/go/myf/auth"
+	"github.com/saichler/l8myfamiliy/go/myf/webapi"
+	"github.com/saichler/l8myfamiliy/go/types/l8myfamily"
+	"github.com/saichler/l8types/go/ifs"
+)
+
+// registerInternal runs the registration callback of a device bound to the
+// internal caller, like a registration the server makes on its own behalf
+func registerInternal(device *l8myfamily.Device) (bool, error) {
+	defer auth.Bind(device, auth.Internal)()
+	_, ok, err := (&DeviceCallback{}).Before(device, ifs.POST, false, nil)
+	return ok, err
+}
+
+func TestRegistrationWithoutCallerIsRejected(t *testing.T) {
+	storage = newTestStorage(t)
+	defer func() { storage = nil }()
+
+	// A registration posted to the service endpoint has no bound caller
+	_, ok, err := (&DeviceCallback{}).Before(&l8myfamily.Device{Id: "d1", FamilyId: "fam"}, ifs.POST, false, nil)
+	if ok || err != webapi.ErrUnauthenticated {
+		t.Fatalf("a registration without a caller must be rejected, got %v %v", ok, err)
+	}
+	if _, err := storage.Get("d1"); err == nil {
+		t.Fatal("a rejected registration must not be stored")
+	}
+}
+
+func TestRegisterHandler(t *testing.T) {
+	storage = newTestStorage(t)
+	origValidate, origCreate := auth.ValidateToken, createDevice
+	auth.ValidateToken = func(token string, vnic ifs.IVNic) (string, bool) { return token, true }
+	var registered []string
+	// The service runs the Before callback on the posted device
+	createDevice = func(device *l8myfamily.Device, vnic ifs.IVNic) error {
+		if _, _, err := (&DeviceCallback{}).Before(device, ifs.POST, false, vnic); err != nil {
+			return err
+		}
+		registered = append(registered, auth.CallerOf(device).User+":"+device.Id)
+		return nil
+	}
+	defer func() { storage = nil; auth.ValidateToken, createDevice = origValidate, origCreate }()
+
+	post := func(token, body string) int {
+		r := httptest.NewRequest("POST", "/my-family/device", bytes.NewBufferString(body))
+		if token != "" {
+			r.Header.Set("Authorization", "Bearer "+token)
+		}
+		r.Header.Set("Content-Type", webapi.ContentTypeJSON)
+		w := httptest.NewRecorder()
+		registerHandler(w, r, nil)
+		return w.Code
+	}
+	if code := post("", `{"id":"phone","familyId":"fam"}`); code != http.StatusUnauthorized {
+		t.Fatalf("a registration without a token must be rejected, got %d", code)
+	}
+	if code := post("other", `{"id":"phone","familyId":"fam"}`); code != http.StatusForbidden {
+		t.Fatalf("a registration in another family must be rejected, got %d", code)
+	}
+	if code := post("fam", `{"id":"phone","familyId":"fam"}`); code != http.StatusOK {
+		t.Fatalf("expected the device registered, got %d", code)
+	}
+	if len(registered) != 1 || registered[0] != "fam:phone" {
+		t.Fatalf("the callbacks must run as the caller, got %v", registered)
+	}
+}
+
+func TestApplyHeartbeatKeepsPosition(t *testing.T) {
+	device := &l8myfamily.Device{Id: "d1", Latitude: 1.5, Longitude: 2.5, Place: "Home", LastSeen: 1000}
+	applyLocation(device, &l8myfamily.Location{DeviceId: "d1", Heartbeat: true, Timestamp: 5000})
+	if device.Latitude != 1.5 || device.Longitude != 2.5 || device.Place != "Home" {
+		t.Fatalf("a heartbeat must not move the device, got %+v", device)
+	}
+	if device.LastSeen != 5000 {
+		t.Fatalf("a heartbeat must update the last seen time, got %d", device.LastSeen)
+	}
+
+	applyLocation(device, &l8myfamily.Location{DeviceId: "d1", Latitude: 3, Longitude: 4, Timestamp: 6000})
+	if device.Latitude != 3 || device.Place != "" || device.LastSeen != 6000 {
+		t.Fatalf("a location must move the device, got %+v", device)
+	}
+}
+
+func TestApplyOlderLocationIsHistory(t *testing.T) {
+	device := &l8myfamily.Device{Id: "d1", Latitude: 3, Longitude: 4, Battery: 80, LastSeen: 6000}
+	applyLocation(device, &l8myfamily.Location{DeviceId: "d1", Latitude: 1, Longitude: 2, Battery: 20, Timestamp: 5000})
+	if device.Latitude != 3 || device.Battery != 80 || device.LastSeen != 6000 {
+		t.Fatalf("an older location must not replace the current one, got %+v", device)
+	}
+	applyLocation(device, &l8myfamily.Location{DeviceId: "d1", Latitude: 1, Longitude: 2, Timestamp: 5000, Emergency: true})
+	if !device.Emergency || device.Latitude != 3 {
+		t.Fatalf("an older emergency must still be flagged, got %+v", device)
+	}
+}
+
+func TestApplyTelemetry(t *testing.T) {
+	device := &l8myfamily.Device{Id: "d1"}
+	applyLocation(device, &l8myfamily.Location{DeviceId: "d1", Heartbeat: true,
+		Telemetry: &l8myfamily.Telemetry{NetworkType: "wifi", Charging: true, Os: "android", OsVersion: "14"}})
+	if device.Telemetry.GetNetworkType() != "wifi" || !device.Telemetry.GetCharging() {
+		t.Fatalf("a heartbeat must carry the telemetry, got %v", device.Telemetry)
+	}
+	applyLocation(device, &l8myfamily.Location{DeviceId: "d1", Latitude: 3, Longitude: 4})
+	if device.Telemetry.GetOsVersion() != "14" {
+		t.Fatalf("a post without telemetry must keep the last one, got %v", device.Telemetry)
+	}
+}
+
+func TestApplyReportInterval(t *testing.T) {
+	device := &l8myfamily.Device{Id: "d1"}
+	applyLocation(device, &l8myfamily.Location{DeviceId: "d1", Heartbeat: true, ReportIntervalSeconds: 1800})
+	if device.ReportIntervalSeconds != 1800 {
+		t.Fatalf("a heartbeat must carry the report interval, got %d", device.ReportIntervalSeconds)
+	}
+	applyLocation(device, &l8myfamily.Location{DeviceId: "d1", Latitude: 3, Longitude: 4})
+	if device.ReportIntervalSeconds != 1800 {
+		t.Fatalf("a post without an interval must keep the last one, got %d", device.ReportIntervalSeconds)
+	}
+}
+
+func TestApplyCoarse(t *testing.T) {
+	device := &l8myfamily.Device{Id: "d1"}
+	applyLocation(device, &l8myfamily.Location{DeviceId: "d1", Latitude: 1, Longitude: 2, Accuracy: 12000, Coarse: true})
+	if !device.Coarse {
+		t.Fatal("a coarse fix must flag the device coarse")
+	}
+	applyLocation(device, &l8myfamily.Location{DeviceId: "d1", Latitude: 1, Longitude: 2, Heartbeat: true})
+	if !device.Coarse {
+		t.Fatal("a heartbeat must keep the position and its flag")
+	}
+	applyLocation(device, &l8myfamily.Location{DeviceId: "d1", Latitude: 3, Longitude: 4, Accuracy: 20})
+	if device.Coarse {
+		t.Fatal("a precise fix must clear the flag")
+	}
+}
+
+func TestActivateRejectsInvalidArea(t *testing.T) {
+	if err := Activate("", 0, nil); err == nil {
+		t.Fatal("area 0 must be rejected before the service is registered")
+	}
+}
@@ -18,69 +18,134 @@ package device_service
 import (
 	"fmt"
 	"os"
+	gostrings "strings"
+	"sync"
+	"time"
 
+	"github.com/saichler/l8myfamiliy/go/myf/config"
 	"github.com/saichler/l8myfamiliy/go/types/l8myfamily"
 	"github.com/saichler/l8utils/go/utils/strings"
-	"google.golang.org/protobuf/proto"
 )
 
 const (
 	location = "/data/my-family/devices/"
 )
 
-type DeviceStorage struct{}
+type DeviceStorage struct {
+	dir string
+	// familyIndex is a secondary index of family id to its device ids
+	familyIndex map[string]map[string]bool
+	indexMtx    *sync.RWMutex
+	// cache holds the recently read and written devices, the disk is
+	// always written first so the cache never holds an unsaved device
+	cache *DeviceCache
+	// hold keeps the devices that failed to be written until the disk takes them
+	hold *writeHold
+}
 
-func newDeviceStorage() *DeviceStorage {
-	os.MkdirAll(location, 0777)
-	return &DeviceStorage{}
+func newDeviceStorage(dir string) *DeviceStorage {
+	os.MkdirAll(dir, 0777)
+	cfg := config.Get()
+	this := &DeviceStorage{dir: dir, indexMtx: &sync.RWMutex{},
+		cache: newDeviceCache(cfg.DeviceCacheSize, time.Duration(cfg.DeviceCacheTTLSeconds)*time.Second)}
+	this.hold = newWriteHold(this.buildFilename)
+	this.rebuildIndex()
+	return this
 }
 
-func buildFilename(k string) string {
-	return strings.New(location, k).String()
+func (this *DeviceStorage) buildFilename(k string) string {
+	return strings.New(this.dir, k).String()
 }
 
 func (this *DeviceStorage) Put(k string, v interface{}) error {
 	device := v.(*l8myfamily.Device)
-	d, e := proto.Marshal(device)
+	// Every write is stamped, an incremental device list returns the
+	// devices modified since the watermark of the client
+	device.ModifiedAt = time.Now().UnixMilli()
+	d, e := encodeDevice(device)
 	if e != nil {
 		return e
 	}
-	filename := buildFilename(k)
-	return os.WriteFile(filename, d, 0777)
+	// A device that cannot be written is held in memory and served from
+	// there until the disk takes it, the posts are refused while degraded
+	this.hold.put(k, device, d)
+	this.cache.put(k, device)
+	this.index(k, device.FamilyId)
+	return nil
 }
 
 func (this *DeviceStorage) Get(k string) (interface{}, error) {
-	filename := buildFilename(k)
+	if device := this.hold.get(k); device != nil {
+		return device, nil
+	}
+	if device := this.cache.get(k); device != nil {
+		return device, nil
+	}
+	device, e := this.load(k)
+	if e != nil {
+		return nil, e
+	}
+	this.cache.put(k, device)
+	return device, nil
+}
+
+// load reads the device from disk
+func (this *DeviceStorage) load(k string) (*l8myfamily.Device, error) {
+	filename := this.buildFilename(k)
 	d, e := os.ReadFile(filename)
 	if e != nil {
 		return nil, e
 	}
-	device := &l8myfamily.Device{}
-	e = proto.Unmarshal(d, device)
-	return device, e
+	device, e := decodeDevice(d)
+	if e != nil {
+		return nil, fmt.Errorf("device %s: %w", k, e)
+	}
+	return device, nil
 }
 
 func (this *DeviceStorage) Delete(k string) (interface{}, error) {
-	filename := buildFilename(k)
+	held := this.hold.get(k)
+	this.hold.remove(k)
+	filename := this.buildFilename(k)
 	d, e := os.ReadFile(filename)
 	if e != nil {
+		if held != nil {
+			// The device was never written
+			this.cache.invalidate(k)
+			this.unindex(k)
+			return held, nil
+		}
 		return nil, e
 	}
-	device := &l8myfamily.Device{}
-	e = proto.Unmarshal(d, device)
+	// A corrupt record can still be deleted
+	device, _ := decodeDevice(d)
+	this.cache.invalidate(k)
+	this.unindex(k)
 	return device, os.Remove(filename)
 }
 
 func (this *DeviceStorage) Collect(f func(interface{}) (bool, interface{})) map[string]interface{} {
 	result := make(map[string]interface{})
-	devices, err := os.ReadDir(location)
+	devices, err := os.ReadDir(this.dir)
 	if err != nil {
 		return nil
 	}
+	// The held devices are newer than their files, or have none yet
+	held := this.hold.snapshot()
+	for k, device := range held {
+		if ok, elem := f(device); ok {
+			result[k] = elem
+		}
+	}
 	for _, devFile := range devices {
-		vClone, e := this.Get(devFile.Name())
+		if devFile.IsDir() || gostrings.HasPrefix(devFile.Name(), tempPrefix) || held[devFile.Name()] != nil {
+			continue
+		}
+		// Read from disk without caching, a full scan would evict the hot devices
+		vClone, e := this.collectOne(devFile.Name())
 		if e != nil {
-			fmt.Println(e.Error())
+			// Skip corrupt or unreadable records rather than failing the whole collect
+			fmt.Println("[DeviceStorage] skipping ", e.Error())
 			continue
 		}
 		ok, elem := f(vClone)
@@ -91,6 +156,84 @@ func (this *DeviceStorage) Collect(f func(interface{}) (bool, interface{})) map[
 	return result
 }
 
+func (this *DeviceStorage) collectOne(k string) (interface{}, error) {
+	if device := this.cache.lookup(k, false); device != nil {
+		return device, nil
+	}
+	return this.load(k)
+}
+
+// Status returns the state of the store, degraded while devices are held
+func (this *DeviceStorage) Status() *StorageStatus {
+	return this.hold.Status()
+}
+
+// Degraded returns true while devices could not be written to disk
+func (this *DeviceStorage) Degraded() bool {
+	return this.hold.degraded.Load()
+}
+
+// CacheStats returns the hit / miss metric of the device cache
+func (this *DeviceStorage) CacheStats() *CacheStats {
+	return this.cache.Stats()
+}
+
 func (this *DeviceStorage) CacheEnabled() bool {
 	return true
 }
+
+// FamilyDevices returns the ids of the devices that belong to the family
+func (this *DeviceStorage) FamilyDevices(familyId string) []string {
+	this.indexMtx.RLock()
+	defer this.indexMtx.RUnlock()
+	ids := make([]string, 0, len(this.familyIndex[familyId]))
+	for id := range this.familyIndex[familyId] {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+func (this *DeviceStorage) index(k, familyId string) {
+	this.indexMtx.Lock()
+	defer this.indexMtx.Unlock()
+	for fid, ids := range this.familyIndex {
+		if fid != familyId && ids[k] {
+			delete(ids, k)
+			if len(ids) == 0 {
+				delete(this.familyIndex, fid)
+			}
+		}
+	}
+	ids, ok := this.familyIndex[familyId]
+	if !ok {
+		ids = make(map[string]bool)
+		this.familyIndex[familyId] = ids
+	}
+	ids[k] = true
+}
+
+func (this *DeviceStorage) unindex(k string) {
+	this.indexMtx.Lock()
+	defer this.indexMtx.Unlock()
+	for fid, ids := range this.familyIndex {
+		delete(ids, k)
+		if len(ids) == 0 {
+			delete(this.familyIndex, fid)
+		}
+	}
+}
+
+func (this *DeviceStorage) rebuildIndex() {
+	index := make(map[string]map[string]bool)
+	this.Collect(func(elem interface{}) (bool, interface{}) {
+		device := elem.(*l8myfamily.Device)
+		if index[device.FamilyId] == nil {
+			index[device.FamilyId] = make(map[string]bool)
+		}
+		index[device.FamilyId][device.Id] = true
+		return false, nil
+	})
+	this.indexMtx.Lock()
+	this.familyIndex = index
+	this.indexMtx.Unlock()
+}
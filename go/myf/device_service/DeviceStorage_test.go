@@ -0,0 +1,93 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package device_service
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/saichler/l8myfamiliy/go/types/l8myfamily"
+	"google.golang.org/protobuf/proto"
+)
+
+func newTestStorage(t *testing.T) *DeviceStorage {
+	return newDeviceStorage(t.TempDir() + "/")
+}
+
+func TestStorageRoundTrip(t *testing.T) {
+	store := newTestStorage(t)
+	device := &l8myfamily.Device{Id: "d1", FamilyId: "fam", Name: "Phone", Latitude: 1.5}
+	if err := store.Put("d1", device); err != nil {
+		t.Fatal(err)
+	}
+	got, err := store.Get("d1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !proto.Equal(got.(*l8myfamily.Device), device) {
+		t.Fatalf("expected %v got %v", device, got)
+	}
+	if ids := store.FamilyDevices("fam"); len(ids) != 1 || ids[0] != "d1" {
+		t.Fatalf("family index not updated %v", ids)
+	}
+	entries, _ := os.ReadDir(store.dir)
+	if len(entries) != 1 {
+		t.Fatalf("expected no temp files to be left, got %d entries", len(entries))
+	}
+}
+
+func TestStorageDetectsTruncatedFile(t *testing.T) {
+	store := newTestStorage(t)
+	store.Put("d1", &l8myfamily.Device{Id: "d1", FamilyId: "fam", Name: "Phone"})
+	store.Put("d2", &l8myfamily.Device{Id: "d2", FamilyId: "fam", Name: "Laptop"})
+
+	// Simulate a crash in the middle of writing d1
+	filename := filepath.Join(store.dir, "d1")
+	data, _ := os.ReadFile(filename)
+	os.WriteFile(filename, data[:len(data)-3], 0777)
+	// The crash restarts the service with an empty cache
+	store = newDeviceStorage(store.dir)
+
+	_, err := store.Get("d1")
+	if !errors.Is(err, ErrCorruptDevice) {
+		t.Fatalf("expected ErrCorruptDevice, got %v", err)
+	}
+
+	collected := store.Collect(func(elem interface{}) (bool, interface{}) { return true, elem })
+	if len(collected) != 1 || collected["d2"] == nil {
+		t.Fatalf("collect should skip the corrupt device, got %v", collected)
+	}
+
+	stats, err := store.Compact()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Valid != 1 || len(stats.Removed) != 1 || stats.Removed[0] != "d1" {
+		t.Fatalf("unexpected compact stats %+v", stats)
+	}
+}
+
+func TestStorageReadsLegacyRecords(t *testing.T) {
+	store := newTestStorage(t)
+	legacy, _ := proto.Marshal(&l8myfamily.Device{Id: "old", FamilyId: "fam"})
+	os.WriteFile(filepath.Join(store.dir, "old"), legacy, 0777)
+	got, err := store.Get("old")
+	if err != nil || got.(*l8myfamily.Device).Id != "old" {
+		t.Fatalf("legacy record not readable: %v %v", got, err)
+	}
+}
@@ -0,0 +1,234 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package device_service
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/saichler/l8myfamiliy/go/myf/auth"
+	"github.com/saichler/l8myfamiliy/go/myf/config"
+	"github.com/saichler/l8myfamiliy/go/myf/webapi"
+	"github.com/saichler/l8myfamiliy/go/types/l8myfamily"
+	"github.com/saichler/l8types/go/ifs"
+	"google.golang.org/protobuf/proto"
+)
+
+// The device stream event types, a reset tells the client the updates since
+// its last event are lost and it reloads the devices
+const (
+	StreamEventDevice = "device"
+	StreamEventReset  = "reset"
+)
+
+// streamKeepalive is how often an idle stream sends a comment, so a dropped
+// mobile connection is noticed by both ends
+const streamKeepalive = 15 * time.Second
+
+// streamBuffer is the events a subscriber may fall behind before it is
+// disconnected, it then reconnects and is replayed what it missed
+const streamBuffer = 64
+
+// streamEvent is a device update, its sequence orders the events of a family
+type streamEvent struct {
+	seq    uint64
+	device *l8myfamily.Device
+}
+
+// familyStream keeps the recent events of a family and its subscribers
+type familyStream struct {
+	events      []*streamEvent
+	last        uint64
+	subscribers map[chan *streamEvent]bool
+}
+
+// DeviceStream fans the device updates out to the subscribed clients of each
+// family and keeps the recent ones to replay. An event id is the stream epoch
+// and the family sequence, an id of another epoch is from before a restart.
+type DeviceStream struct {
+	mtx      sync.Mutex
+	epoch    string
+	families map[string]*familyStream
+}
+
+// NewDeviceStream returns an empty stream, its epoch is the creation time
+func NewDeviceStream() *DeviceStream {
+	return &DeviceStream{epoch: strconv.FormatInt(time.Now().UnixMilli(), 36),
+		families: make(map[string]*familyStream)}
+}
+
+var deviceStream = NewDeviceStream()
+
+func (this *DeviceStream) family(familyId string) *familyStream {
+	family, ok := this.families[familyId]
+	if !ok {
+		family = &familyStream{subscribers: make(map[chan *streamEvent]bool)}
+		this.families[familyId] = family
+	}
+	return family
+}
+
+// EventId returns the id of the event with the sequence
+func (this *DeviceStream) EventId(seq uint64) string {
+	return this.epoch + "-" + strconv.FormatUint(seq, 10)
+}
+
+// Publish sends a copy of the device to the subscribers of its family and
+// keeps it for replay. A subscriber too far behind is disconnected.
+func (this *DeviceStream) Publish(device *l8myfamily.Device) {
+	capacity := config.Get().StreamReplayEvents
+	if capacity <= 0 || device == nil {
+		return
+	}
+	this.mtx.Lock()
+	defer this.mtx.Unlock()
+	family := this.family(device.FamilyId)
+	family.last++
+	event := &streamEvent{seq: family.last, device: proto.Clone(device).(*l8myfamily.Device)}
+	family.events = append(family.events, event)
+	if len(family.events) > capacity {
+		family.events = append(family.events[:0], family.events[len(family.events)-capacity:]...)
+	}
+	for ch := range family.subscribers {
+		select {
+		case ch <- event:
+		default:
+			delete(family.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+// Subscribe registers a subscriber of the family and returns the events
+// after lastEventId to replay first, and the id of the newest event. Reset
+// is true when the events after lastEventId are no longer kept or the id is
+// from before a restart, the client then reloads the devices. An empty
+// lastEventId is a new client, it replays nothing.
+func (this *DeviceStream) Subscribe(familyId, lastEventId string) (ch chan *streamEvent, replay []*streamEvent, newest string, reset bool) {
+	this.mtx.Lock()
+	defer this.mtx.Unlock()
+	family := this.family(familyId)
+	ch = make(chan *streamEvent, streamBuffer)
+	family.subscribers[ch] = true
+	newest = this.EventId(family.last)
+	if lastEventId == "" {
+		return ch, nil, newest, false
+	}
+	epoch, seqText, _ := strings.Cut(lastEventId, "-")
+	seq, err := strconv.ParseUint(seqText, 10, 64)
+	if err != nil || epoch != this.epoch || seq > family.last {
+		return ch, nil, newest, true
+	}
+	// The oldest kept event must directly follow the last one seen
+	if len(family.events) > 0 && family.events[0].seq > seq+1 {
+		return ch, nil, newest, true
+	}
+	for _, event := range family.events {
+		if event.seq > seq {
+			replay = append(replay, event)
+		}
+	}
+	return ch, replay, newest, false
+}
+
+// Unsubscribe removes the subscriber, it may already be disconnected
+func (this *DeviceStream) Unsubscribe(familyId string, ch chan *streamEvent) {
+	this.mtx.Lock()
+	defer this.mtx.Unlock()
+	family, ok := this.families[familyId]
+	if !ok || !family.subscribers[ch] {
+		return
+	}
+	delete(family.subscribers, ch)
+	close(ch)
+}
+
+// deviceStreamHandler streams the updates of the caller family devices as
+// server-sent events. A reconnecting client sends the id of the last event
+// it got as the Last-Event-ID header, or the lastEventId parameter, and is
+// replayed the updates it missed.
+func deviceStreamHandler(w http.ResponseWriter, r *http.Request, vnic ifs.IVNic) {
+	if !webapi.RequireMethod(w, r, http.MethodGet) {
+		return
+	}
+	if config.Get().StreamReplayEvents <= 0 {
+		http.NotFound(w, r)
+		return
+	}
+	caller := auth.Authenticate(w, r, vnic)
+	if caller == nil {
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+	lastEventId := r.Header.Get("Last-Event-ID")
+	if lastEventId == "" {
+		lastEventId = r.URL.Query().Get("lastEventId")
+	}
+	ch, replay, newest, reset := deviceStream.Subscribe(caller.FamilyId, lastEventId)
+	defer deviceStream.Unsubscribe(caller.FamilyId, ch)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, "retry: %d\n\n", config.Get().StreamRetryMillis)
+	if reset {
+		fmt.Println("[Device] stream of ", caller.User, " reset after ", lastEventId)
+		fmt.Fprintf(w, "id: %s\nevent: %s\ndata: {}\n\n", newest, StreamEventReset)
+	}
+	for _, event := range replay {
+		writeStreamEvent(w, event, caller)
+	}
+	flusher.Flush()
+
+	keepalive := time.NewTicker(streamKeepalive)
+	defer keepalive.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case event, ok := <-ch:
+			// A closed channel is a subscriber too far behind, the client
+			// reconnects and is replayed the events
+			if !ok {
+				return
+			}
+			writeStreamEvent(w, event, caller)
+		case <-keepalive.C:
+			fmt.Fprint(w, ": keepalive\n\n")
+		}
+		flusher.Flush()
+	}
+}
+
+// writeStreamEvent writes the device update if the caller may see the device
+func writeStreamEvent(w http.ResponseWriter, event *streamEvent, caller *auth.Caller) {
+	if !CanSee(event.device, caller) {
+		return
+	}
+	data, err := webapi.MarshalJSON(event.device)
+	if err != nil {
+		return
+	}
+	fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", deviceStream.EventId(event.seq), StreamEventDevice, data)
+}
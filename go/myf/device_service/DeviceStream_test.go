@@ -0,0 +1,203 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package device_service
+
+import (
+	"bufio"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/saichler/l8myfamiliy/go/myf/auth"
+	"github.com/saichler/l8myfamiliy/go/myf/config"
+	"github.com/saichler/l8myfamiliy/go/types/l8myfamily"
+	"github.com/saichler/l8types/go/ifs"
+)
+
+func streamSeqs(events []*streamEvent) string {
+	seqs := make([]uint64, 0, len(events))
+	for _, event := range events {
+		seqs = append(seqs, event.seq)
+	}
+	return fmt.Sprint(seqs)
+}
+
+func TestDeviceStreamReplay(t *testing.T) {
+	cfg := config.Default()
+	cfg.StreamReplayEvents = 3
+	config.Set(cfg)
+	defer config.Set(config.Default())
+	stream := NewDeviceStream()
+	for i := 0; i < 4; i++ {
+		stream.Publish(&l8myfamily.Device{Id: "d1", FamilyId: "fam"})
+	}
+	stream.Publish(&l8myfamily.Device{Id: "d2", FamilyId: "other"})
+
+	for _, test := range []struct {
+		lastEventId string
+		want        string
+		reset       bool
+	}{
+		{"", "[]", false},
+		{stream.EventId(2), "[3 4]", false},
+		{stream.EventId(1), "[2 3 4]", false},
+		{stream.EventId(4), "[]", false},
+		// The event after 0 is no longer kept
+		{stream.EventId(0), "[]", true},
+		{stream.EventId(5), "[]", true},
+		{"0-1", "[]", true},
+		{"garbage", "[]", true},
+	} {
+		ch, replay, newest, reset := stream.Subscribe("fam", test.lastEventId)
+		if got := streamSeqs(replay); got != test.want || reset != test.reset {
+			t.Fatalf("after %q: expected %s reset=%v, got %s reset=%v", test.lastEventId, test.want, test.reset, got, reset)
+		}
+		if newest != stream.EventId(4) {
+			t.Fatalf("expected the newest event %s, got %s", stream.EventId(4), newest)
+		}
+		stream.Unsubscribe("fam", ch)
+	}
+}
+
+func TestDeviceStreamDisconnectsSlowSubscriber(t *testing.T) {
+	stream := NewDeviceStream()
+	ch, _, _, _ := stream.Subscribe("fam", "")
+	for i := 0; i <= streamBuffer; i++ {
+		stream.Publish(&l8myfamily.Device{Id: "d1", FamilyId: "fam"})
+	}
+	received := 0
+	for range ch {
+		received++
+	}
+	if received != streamBuffer {
+		t.Fatalf("expected the %d buffered events before the disconnect, got %d", streamBuffer, received)
+	}
+	// Unsubscribing a disconnected subscriber is harmless
+	stream.Unsubscribe("fam", ch)
+}
+
+// streamMessage is a server-sent event, or the retry and comment blocks
+type streamMessage struct {
+	id, event, data, retry string
+}
+
+func readStreamMessage(t *testing.T, reader *bufio.Reader) *streamMessage {
+	t.Helper()
+	msg := &streamMessage{}
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatalf("stream ended: %v", err)
+		}
+		line = strings.TrimSuffix(line, "\n")
+		if line == "" {
+			return msg
+		}
+		field, value, _ := strings.Cut(line, ": ")
+		switch field {
+		case "id":
+			msg.id = value
+		case "event":
+			msg.event = value
+		case "data":
+			msg.data = value
+		case "retry":
+			msg.retry = value
+		}
+	}
+}
+
+func openDeviceStream(t *testing.T, url, user, lastEventId string) (*http.Response, *bufio.Reader) {
+	t.Helper()
+	r, _ := http.NewRequest("GET", url, nil)
+	r.Header.Set("Authorization", "Bearer "+user)
+	if lastEventId != "" {
+		r.Header.Set("Last-Event-ID", lastEventId)
+	}
+	resp, err := http.DefaultClient.Do(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("expected an event stream, got %d %s", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+	reader := bufio.NewReader(resp.Body)
+	if msg := readStreamMessage(t, reader); msg.retry != "3000" {
+		t.Fatalf("expected the retry delay first, got %+v", msg)
+	}
+	return resp, reader
+}
+
+func TestDeviceStreamReplayAfterReconnect(t *testing.T) {
+	storeFamily(t)
+	defer func() { storage = nil; config.Set(config.Default()) }()
+	origStream := deviceStream
+	deviceStream = NewDeviceStream()
+	defer func() { deviceStream = origStream }()
+	origValidate := auth.ValidateToken
+	auth.ValidateToken = func(token string, vnic ifs.IVNic) (string, bool) { return token, true }
+	defer func() { auth.ValidateToken = origValidate }()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		deviceStreamHandler(w, r, nil)
+	}))
+	defer srv.Close()
+
+	resp, reader := openDeviceStream(t, srv.URL, "sib-login", "")
+	deviceStream.Publish(&l8myfamily.Device{Id: "mom-phone", FamilyId: "fam", MemberId: "mom", Latitude: 1})
+	first := readStreamMessage(t, reader)
+	if first.event != StreamEventDevice || !strings.Contains(first.data, `"latitude":1`) {
+		t.Fatalf("expected the live update, got %+v", first)
+	}
+	// The connection drops, the updates meanwhile are kept
+	resp.Body.Close()
+	deviceStream.Publish(&l8myfamily.Device{Id: "teen-phone", FamilyId: "fam", MemberId: "teen", VisibleTo: []string{"dad"}})
+	deviceStream.Publish(&l8myfamily.Device{Id: "mom-phone", FamilyId: "fam", MemberId: "mom", Latitude: 2})
+
+	resp, reader = openDeviceStream(t, srv.URL, "sib-login", first.id)
+	defer resp.Body.Close()
+	// The device hidden from the sibling is not replayed
+	replayed := readStreamMessage(t, reader)
+	if replayed.event != StreamEventDevice || !strings.Contains(replayed.data, `"latitude":2`) || replayed.id != deviceStream.EventId(3) {
+		t.Fatalf("expected the missed update replayed, got %+v", replayed)
+	}
+	deviceStream.Publish(&l8myfamily.Device{Id: "mom-phone", FamilyId: "fam", MemberId: "mom", Latitude: 3})
+	if live := readStreamMessage(t, reader); !strings.Contains(live.data, `"latitude":3`) {
+		t.Fatalf("expected the live updates after the replay, got %+v", live)
+	}
+}
+
+func TestDeviceStreamResetAfterRestart(t *testing.T) {
+	storeFamily(t)
+	defer func() { storage = nil; config.Set(config.Default()) }()
+	origStream := deviceStream
+	deviceStream = NewDeviceStream()
+	defer func() { deviceStream = origStream }()
+	origValidate := auth.ValidateToken
+	auth.ValidateToken = func(token string, vnic ifs.IVNic) (string, bool) { return token, true }
+	defer func() { auth.ValidateToken = origValidate }()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		deviceStreamHandler(w, r, nil)
+	}))
+	defer srv.Close()
+
+	resp, reader := openDeviceStream(t, srv.URL, "fam", "0-7")
+	defer resp.Body.Close()
+	if msg := readStreamMessage(t, reader); msg.event != StreamEventReset || msg.id != deviceStream.EventId(0) {
+		t.Fatalf("an id from before a restart must reset the client, got %+v", msg)
+	}
+}
@@ -0,0 +1,130 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package device_service
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/saichler/l8myfamiliy/go/types/l8myfamily"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	// MaxDeviceTags is the most tags a device can have
+	MaxDeviceTags = 16
+	// MaxTagKeyLength and MaxTagValueLength bound a tag, in bytes
+	MaxTagKeyLength   = 32
+	MaxTagValueLength = 128
+)
+
+// ErrInvalidTags is returned for tags beyond the caps
+var ErrInvalidTags = errors.New("invalid tags")
+
+// TagUpdate sets and clears tags of a device, a key both set and cleared
+// is cleared
+type TagUpdate struct {
+	Set   map[string]string `json:"set,omitempty"`
+	Clear []string          `json:"clear,omitempty"`
+}
+
+// ValidateTags checks the tags of a device against the caps, a tag needs a
+// key and a value
+func ValidateTags(tags map[string]string) error {
+	if len(tags) > MaxDeviceTags {
+		return fmt.Errorf("%w: a device has at most %d tags, got %d", ErrInvalidTags, MaxDeviceTags, len(tags))
+	}
+	for key, value := range tags {
+		if strings.TrimSpace(key) == "" || len(key) > MaxTagKeyLength {
+			return fmt.Errorf("%w: tag key %q must have 1 to %d bytes", ErrInvalidTags, key, MaxTagKeyLength)
+		}
+		if value == "" || len(value) > MaxTagValueLength {
+			return fmt.Errorf("%w: value of tag %q must have 1 to %d bytes", ErrInvalidTags, key, MaxTagValueLength)
+		}
+	}
+	return nil
+}
+
+// UpdateTags applies the update to the tags of a device of the family and
+// returns the updated device, nil when the device is not in the family
+func UpdateTags(familyId, deviceId string, update *TagUpdate) (*l8myfamily.Device, error) {
+	if storage == nil {
+		return nil, fmt.Errorf("device service is not active")
+	}
+	defer deviceLocks.lock(deviceId)()
+	elem, err := storage.Get(deviceId)
+	if err != nil || elem.(*l8myfamily.Device).FamilyId != familyId {
+		return nil, nil
+	}
+	// The stored device is shared with the cache, it is changed on a copy
+	device := proto.Clone(elem.(*l8myfamily.Device)).(*l8myfamily.Device)
+	if device.Tags == nil {
+		device.Tags = make(map[string]string)
+	}
+	for key, value := range update.Set {
+		device.Tags[key] = value
+	}
+	for _, key := range update.Clear {
+		delete(device.Tags, key)
+	}
+	if err := ValidateTags(device.Tags); err != nil {
+		return nil, err
+	}
+	if err := storage.Put(deviceId, device); err != nil {
+		return nil, err
+	}
+	return device, nil
+}
+
+// matchTags returns true if the device has every tag of filter, an empty
+// filter value matches any value of the key
+func matchTags(device *l8myfamily.Device, filter map[string]string) bool {
+	for key, value := range filter {
+		tag, ok := device.Tags[key]
+		if !ok || (value != "" && tag != value) {
+			return false
+		}
+	}
+	return true
+}
+
+// parseTagFilter reads the tag query parameters, each is key or key=value
+func parseTagFilter(params []string) (map[string]string, error) {
+	if len(params) == 0 {
+		return nil, nil
+	}
+	filter := make(map[string]string, len(params))
+	for _, param := range params {
+		key, value, _ := strings.Cut(param, "=")
+		if key == "" {
+			return nil, fmt.Errorf("invalid tag filter %q", param)
+		}
+		filter[key] = value
+	}
+	return filter, nil
+}
+
+// tagKeys returns the sorted tag keys, for the log
+func tagKeys(tags map[string]string) []string {
+	keys := make([]string, 0, len(tags))
+	for key := range tags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
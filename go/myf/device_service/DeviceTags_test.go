@@ -0,0 +1,133 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package device_service
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/saichler/l8myfamiliy/go/myf/auth"
+	"github.com/saichler/l8myfamiliy/go/types/l8myfamily"
+	"github.com/saichler/l8types/go/ifs"
+)
+
+func TestUpdateTags(t *testing.T) {
+	storage = newTestStorage(t)
+	defer func() { storage = nil }()
+	storage.Put("bike", &l8myfamily.Device{Id: "bike", FamilyId: "fam", Name: "Tracker"})
+
+	device, err := UpdateTags("fam", "bike", &TagUpdate{Set: map[string]string{"owner": "Emma", "kind": "bike"}})
+	if err != nil || device.Tags["owner"] != "Emma" || len(device.Tags) != 2 {
+		t.Fatalf("unexpected tags %v %v", device, err)
+	}
+	device, err = UpdateTags("fam", "bike", &TagUpdate{Clear: []string{"kind"}})
+	if err != nil || len(device.Tags) != 1 || device.Tags["owner"] != "Emma" {
+		t.Fatalf("expected the kind tag cleared, got %v %v", device, err)
+	}
+	if device, err := UpdateTags("other", "bike", &TagUpdate{Set: map[string]string{"a": "b"}}); device != nil || err != nil {
+		t.Fatalf("another family must not tag the device, got %v %v", device, err)
+	}
+
+	tooMany := make(map[string]string)
+	for i := 0; i <= MaxDeviceTags; i++ {
+		tooMany[fmt.Sprintf("k%d", i)] = "v"
+	}
+	for _, update := range []*TagUpdate{
+		{Set: tooMany},
+		{Set: map[string]string{strings.Repeat("k", MaxTagKeyLength+1): "v"}},
+		{Set: map[string]string{"note": strings.Repeat("v", MaxTagValueLength+1)}},
+		{Set: map[string]string{"empty": ""}},
+	} {
+		if _, err := UpdateTags("fam", "bike", update); !errors.Is(err, ErrInvalidTags) {
+			t.Fatalf("expected the update to be rejected, got %v", err)
+		}
+	}
+	stored, _ := storage.Get("bike")
+	if tags := stored.(*l8myfamily.Device).Tags; len(tags) != 1 {
+		t.Fatalf("a rejected update must not be stored, got %v", tags)
+	}
+}
+
+func TestListDevicesByTag(t *testing.T) {
+	storage = newTestStorage(t)
+	defer func() { storage = nil }()
+	storage.Put("a", &l8myfamily.Device{Id: "a", FamilyId: "fam", Tags: map[string]string{"owner": "Mom", "use": "work"}})
+	storage.Put("b", &l8myfamily.Device{Id: "b", FamilyId: "fam", Tags: map[string]string{"owner": "Emma"}})
+	storage.Put("c", &l8myfamily.Device{Id: "c", FamilyId: "fam"})
+
+	tags, _ := parseTagFilter([]string{"owner=Mom", "use"})
+	if devices := ListDevices(&DeviceFilter{FamilyId: "fam", Tags: tags}); len(devices) != 1 || devices[0].Id != "a" {
+		t.Fatalf("expected Mom's work device, got %v", devices)
+	}
+	tags, _ = parseTagFilter([]string{"owner"})
+	if devices := ListDevices(&DeviceFilter{FamilyId: "fam", Tags: tags}); len(devices) != 2 {
+		t.Fatalf("expected the 2 devices with an owner, got %v", devices)
+	}
+	if _, err := parseTagFilter([]string{"=x"}); err == nil {
+		t.Fatal("a filter without a key must be rejected")
+	}
+}
+
+func TestDeviceTagsHandler(t *testing.T) {
+	storage = newTestStorage(t)
+	origValidate := auth.ValidateToken
+	auth.ValidateToken = func(token string, vnic ifs.IVNic) (string, bool) { return token, true }
+	defer func() { storage = nil; auth.ValidateToken = origValidate }()
+	storage.Put("phone", &l8myfamily.Device{Id: "phone", FamilyId: "fam"})
+
+	patch := func(method, token, query, body string) int {
+		r := httptest.NewRequest(method, "/my-family/device-tags?"+query, bytes.NewBufferString(body))
+		r.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		deviceTagsHandler(w, r, nil)
+		return w.Code
+	}
+	if code := patch("PATCH", "fam", "deviceId=phone", `{"set":{"label":"Mom's work phone"}}`); code != http.StatusOK {
+		t.Fatalf("expected the tag set, got %d", code)
+	}
+	if code := patch("PATCH", "fam", "deviceId=phone", `{"set":{"label":""}}`); code != http.StatusBadRequest {
+		t.Fatalf("an empty value must be rejected, got %d", code)
+	}
+	if code := patch("PATCH", "stranger", "deviceId=phone", `{"clear":["label"]}`); code != http.StatusNotFound {
+		t.Fatalf("another family device must not be found, got %d", code)
+	}
+	if code := patch("PUT", "fam", "deviceId=phone", `{}`); code != http.StatusMethodNotAllowed {
+		t.Fatalf("only PATCH is allowed, got %d", code)
+	}
+	stored, _ := storage.Get("phone")
+	if stored.(*l8myfamily.Device).Tags["label"] != "Mom's work phone" {
+		t.Fatalf("unexpected stored tags %v", stored.(*l8myfamily.Device).Tags)
+	}
+}
+
+func TestReRegistrationKeepsTags(t *testing.T) {
+	storage = newTestStorage(t)
+	defer func() { storage = nil }()
+	storage.Put("phone", &l8myfamily.Device{Id: "phone", FamilyId: "fam", Tags: map[string]string{"owner": "Mom"}})
+
+	device := &l8myfamily.Device{Id: "phone", FamilyId: "fam", Name: "Phone"}
+	if ok, err := registerInternal(device); ok || err != nil {
+		t.Fatalf("a re-registration must update the stored device, got %v %v", ok, err)
+	}
+	if stored := storedDevice(t, "phone"); stored.Tags["owner"] != "Mom" || stored.Name != "Phone" {
+		t.Fatalf("a re-registration must keep the tags, got %v", stored)
+	}
+}
@@ -0,0 +1,90 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package device_service
+
+import (
+	"fmt"
+
+	"github.com/saichler/l8myfamiliy/go/types/l8myfamily"
+	"google.golang.org/protobuf/proto"
+)
+
+// upsert returns the stored device updated with a registration: the name,
+// the family, the agent version and the state the agent reports are taken
+// from the registration, the owner, the position, last seen time, emergency, tags,
+// visibility and avatar are kept unless the registration sets them. A device
+// that registers heartbeat-only loses its position, an archived device is
+// active again.
+func upsert(stored, registration *l8myfamily.Device) *l8myfamily.Device {
+	// The stored device is shared with the cache, it is changed on a copy
+	device := proto.Clone(stored).(*l8myfamily.Device)
+	device.Name = registration.Name
+	device.FamilyId = registration.FamilyId
+	if registration.FamilyName != "" {
+		device.FamilyName = registration.FamilyName
+	}
+	if registration.MemberId != "" {
+		device.MemberId = registration.MemberId
+		device.MemberName = registration.MemberName
+	}
+	if registration.OwnerId != "" {
+		device.OwnerId = registration.OwnerId
+	}
+	if registration.Kind != "" {
+		device.Kind = registration.Kind
+	}
+	if registration.Avatar != "" {
+		device.Avatar = registration.Avatar
+	}
+	if registration.Telemetry != nil {
+		device.Telemetry = registration.Telemetry
+	}
+	if registration.ReportIntervalSeconds > 0 {
+		device.ReportIntervalSeconds = registration.ReportIntervalSeconds
+	}
+	if registration.Tags != nil {
+		device.Tags = registration.Tags
+	}
+	if registration.VisibleTo != nil {
+		device.VisibleTo = registration.VisibleTo
+	}
+	device.AgentVersion = registration.AgentVersion
+	device.HeartbeatOnly = registration.HeartbeatOnly
+	device.ArchivedTime = 0
+	if device.HeartbeatOnly {
+		clearPosition(device)
+	}
+	return device
+}
+
+// upsertStored writes the registration of a registered device as an
+// upsert, it returns false if the device is not registered yet and the
+// service creates it
+func upsertStored(registration *l8myfamily.Device) (bool, error) {
+	if storage == nil {
+		return false, nil
+	}
+	defer deviceLocks.lock(registration.Id)()
+	elem, err := storage.Get(registration.Id)
+	if err != nil {
+		return false, nil
+	}
+	if err := storage.Put(registration.Id, upsert(elem.(*l8myfamily.Device), registration)); err != nil {
+		return false, fmt.Errorf("failed to update device %s: %w", registration.Id, err)
+	}
+	fmt.Println("[Device] ", registration.Id, " registered again, its position and tags are kept")
+	return true, nil
+}
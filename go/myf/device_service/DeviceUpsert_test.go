@@ -0,0 +1,250 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package device_service
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/saichler/l8myfamiliy/go/myf/auth"
+	"github.com/saichler/l8myfamiliy/go/myf/config"
+	"github.com/saichler/l8myfamiliy/go/myf/webapi"
+	"github.com/saichler/l8myfamiliy/go/types/l8myfamily"
+	"github.com/saichler/l8types/go/ifs"
+)
+
+// storedDevice returns the device in the test storage
+func storedDevice(t *testing.T, id string) *l8myfamily.Device {
+	elem, err := storage.Get(id)
+	if err != nil {
+		t.Fatalf("device %s is not stored: %v", id, err)
+	}
+	return elem.(*l8myfamily.Device)
+}
+
+func TestReRegistrationKeepsPosition(t *testing.T) {
+	storage = newTestStorage(t)
+	newTestCooldown(t, config.RegistrationCooldown())
+	defer func() { storage = nil }()
+	storage.Put("phone", &l8myfamily.Device{Id: "phone", FamilyId: "fam", Name: "Phone", Latitude: 40.7,
+		Longitude: -74, Accuracy: 12, LastSeen: 5000, PositionTime: 5000, Battery: 80,
+		Tags: map[string]string{"owner": "Mom"}})
+
+	registration := &l8myfamily.Device{Id: "phone", FamilyId: "fam", Name: "Mom's Phone", ReportIntervalSeconds: 600}
+	if ok, err := registerInternal(registration); ok || err != nil {
+		t.Fatalf("a re-registration must update the stored device, got %v %v", ok, err)
+	}
+	stored := storedDevice(t, "phone")
+	if stored.Name != "Mom's Phone" || stored.ReportIntervalSeconds != 600 {
+		t.Fatalf("the registration must update the name and the interval, got %v", stored)
+	}
+	if stored.Latitude != 40.7 || stored.LastSeen != 5000 || stored.Battery != 80 || stored.Tags["owner"] != "Mom" {
+		t.Fatalf("the registration must keep the position, last seen and tags, got %v", stored)
+	}
+
+	// A new device is created by the service
+	registration = &l8myfamily.Device{Id: "laptop", FamilyId: "fam", Name: "Laptop"}
+	if ok, err := registerInternal(registration); !ok || err != nil {
+		t.Fatalf("a new device must be left to the service, got %v %v", ok, err)
+	}
+}
+
+func TestReRegistrationReplacePolicy(t *testing.T) {
+	cfg := config.Default()
+	cfg.RegistrationPolicy = config.RegistrationReplace
+	config.Set(cfg)
+	defer config.Set(config.Default())
+	storage = newTestStorage(t)
+	defer func() { storage = nil }()
+	storage.Put("phone", &l8myfamily.Device{Id: "phone", FamilyId: "fam", Name: "Phone", Latitude: 40.7,
+		Tags: map[string]string{"owner": "Mom"}})
+
+	registration := &l8myfamily.Device{Id: "phone", FamilyId: "fam", Name: "Phone"}
+	if ok, err := registerInternal(registration); !ok || err != nil {
+		t.Fatalf("the service must replace the device, got %v %v", ok, err)
+	}
+	if registration.Tags["owner"] != "Mom" || registration.Latitude != 0 {
+		t.Fatalf("a replacing registration keeps only the tags, got %v", registration)
+	}
+}
+
+func TestReRegistrationRacesLocationUpdates(t *testing.T) {
+	storage = newTestStorage(t)
+	defer func() { storage = nil }()
+	storage.Put("phone", &l8myfamily.Device{Id: "phone", FamilyId: "fam", Name: "Phone"})
+
+	// A location update holding the device lock is not overwritten by a
+	// registration, the registration waits for it
+	wg := sync.WaitGroup{}
+	for i := 1; i <= 20; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			defer deviceLocks.lock("phone")()
+			elem, _ := storage.Get("phone")
+			device := elem.(*l8myfamily.Device)
+			applyLocation(device, &l8myfamily.Location{DeviceId: "phone", Latitude: float32(i), Longitude: 1, Timestamp: int64(i)})
+			storage.Put("phone", device)
+		}(i)
+		go func() {
+			defer wg.Done()
+			upsertStored(&l8myfamily.Device{Id: "phone", FamilyId: "fam", Name: "Phone"})
+		}()
+	}
+	wg.Wait()
+	if stored := storedDevice(t, "phone"); stored.Latitude == 0 || stored.LastSeen == 0 {
+		t.Fatalf("a registration must never reset the position, got %v", stored)
+	}
+}
+
+func TestRegistrationOwner(t *testing.T) {
+	storage = newTestStorage(t)
+	newTestCooldown(t, config.RegistrationCooldown())
+	defer func() { storage = nil }()
+	register := func(device *l8myfamily.Device, user string) *l8myfamily.Device {
+		defer auth.Bind(device, &auth.Caller{User: user, FamilyId: "fam"})()
+		if _, ok, err := (&DeviceCallback{}).Before(device, ifs.POST, false, nil); err != nil {
+			t.Fatalf("registration of %s failed: %v", device.Id, err)
+		} else if ok {
+			storage.Put(device.Id, device)
+		}
+		return storedDevice(t, device.Id)
+	}
+
+	if phone := register(&l8myfamily.Device{Id: "phone", FamilyId: "fam", Name: "Phone"}, "mom"); phone.OwnerId != "mom" {
+		t.Fatalf("the registering user must own the device, got %q", phone.OwnerId)
+	}
+	if tablet := register(&l8myfamily.Device{Id: "tablet", FamilyId: "fam", Name: "Tablet", OwnerId: "teen"}, "mom"); tablet.OwnerId != "teen" {
+		t.Fatalf("the owner of the registration must be kept, got %q", tablet.OwnerId)
+	}
+	// A re-registration by another user keeps the owner
+	if phone := register(&l8myfamily.Device{Id: "phone", FamilyId: "fam", Name: "Phone"}, "dad"); phone.OwnerId != "mom" {
+		t.Fatalf("a re-registration must keep the owner, got %q", phone.OwnerId)
+	}
+	if phone := register(&l8myfamily.Device{Id: "phone", FamilyId: "fam", Name: "Phone", OwnerId: "dad"}, "dad"); phone.OwnerId != "dad" {
+		t.Fatalf("a re-registration with an owner must change it, got %q", phone.OwnerId)
+	}
+}
+
+func TestRegistrationCannotTakeOverADeviceOfAnotherFamily(t *testing.T) {
+	storage = newTestStorage(t)
+	defer func() { storage = nil }()
+	orig := signingKeys
+	signingKeys = NewSigningKeyStore("")
+	defer func() { signingKeys = orig }()
+	const familyKey = "0123456789abcdef0123456789abcdef"
+	signingKeys.Set("phone", familyKey, time.Now())
+	storage.Put("phone", &l8myfamily.Device{Id: "phone", FamilyId: "fam", Name: "Phone", OwnerId: "mom"})
+
+	takeover := &l8myfamily.Device{Id: "phone", FamilyId: "other", Name: "Mine", SigningKey: "fedcba9876543210fedcba9876543210"}
+	unbind := auth.Bind(takeover, &auth.Caller{User: "eve", FamilyId: "other"})
+	_, _, err := (&DeviceCallback{}).Before(takeover, ifs.POST, false, nil)
+	unbind()
+	if !errors.Is(err, webapi.ErrOtherFamily) {
+		t.Fatalf("a registration of a device of another family must be rejected, got %v", err)
+	}
+	if stored := storedDevice(t, "phone"); stored.FamilyId != "fam" || stored.Name != "Phone" {
+		t.Fatalf("the device must stay in its family, got %v", stored)
+	}
+	if keys := SigningKeys("phone"); len(keys) != 1 || keys[0] != familyKey {
+		t.Fatalf("the signing key must not be rotated, got %v", keys)
+	}
+
+	// An update of another family may not rotate the key either
+	update := &l8myfamily.Device{Id: "phone", FamilyId: "fam", Name: "Phone", SigningKey: "fedcba9876543210fedcba9876543210"}
+	unbind = auth.Bind(update, &auth.Caller{User: "eve", FamilyId: "other"})
+	_, _, err = (&DeviceCallback{}).Before(update, ifs.PUT, false, nil)
+	unbind()
+	if !errors.Is(err, webapi.ErrOtherFamily) {
+		t.Fatalf("a key rotation by another family must be rejected, got %v", err)
+	}
+	if keys := SigningKeys("phone"); len(keys) != 1 || keys[0] != familyKey {
+		t.Fatalf("the signing key must not be rotated, got %v", keys)
+	}
+
+	// The family itself may still rotate the key and move the device
+	rotation := &l8myfamily.Device{Id: "phone", FamilyId: "fam", Name: "Phone", SigningKey: "fedcba9876543210fedcba9876543210"}
+	unbind = auth.Bind(rotation, &auth.Caller{User: "mom", FamilyId: "fam"})
+	_, _, err = (&DeviceCallback{}).Before(rotation, ifs.POST, false, nil)
+	unbind()
+	if err != nil {
+		t.Fatalf("the family must be able to rotate the key, got %v", err)
+	}
+	if keys := SigningKeys("phone"); len(keys) != 2 {
+		t.Fatalf("the key must be rotated, got %v", keys)
+	}
+}
+
+// TestOwnerChangePolicy gives the stored phone of mom to the teen as each
+// caller: the admins, the family account, the family admins and the owner may
+func TestOwnerChangePolicy(t *testing.T) {
+	cfg := config.Default()
+	cfg.Families = map[string]*config.FamilyConfig{"fam": {Admins: []string{"dad"}}}
+	config.Set(cfg)
+	defer config.Set(config.Default())
+
+	for _, tc := range []struct {
+		name   string
+		caller *auth.Caller
+		err    error
+	}{
+		{"plain member", &auth.Caller{User: "sib-login", FamilyId: "fam", MemberId: "sib"}, webapi.ErrNotOwner},
+		{"new owner", &auth.Caller{User: "teen-login", FamilyId: "fam", MemberId: "teen"}, webapi.ErrNotOwner},
+		{"family admin", &auth.Caller{User: "dad-login", FamilyId: "fam", MemberId: "dad"}, nil},
+		{"family account", &auth.Caller{User: "fam", FamilyId: "fam"}, nil},
+		{"admin", &auth.Caller{User: "root", FamilyId: "ops", MemberId: "ops", Admin: true}, nil},
+		{"owner", &auth.Caller{User: "mom-login", FamilyId: "fam", MemberId: "mom"}, nil},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			storage = newTestStorage(t)
+			newTestCooldown(t, config.RegistrationCooldown())
+			defer func() { storage = nil }()
+			storage.Put("phone", &l8myfamily.Device{Id: "phone", FamilyId: "fam", Name: "Phone", OwnerId: "mom-login"})
+
+			device := &l8myfamily.Device{Id: "phone", FamilyId: "fam", Name: "Phone", OwnerId: "teen-login"}
+			unbind := auth.Bind(device, tc.caller)
+			_, _, err := (&DeviceCallback{}).Before(device, ifs.POST, false, nil)
+			unbind()
+			if !errors.Is(err, tc.err) {
+				t.Fatalf("expected %v, got %v", tc.err, err)
+			}
+			want := "teen-login"
+			if tc.err != nil {
+				want = "mom-login"
+			}
+			if owner := storedDevice(t, "phone").OwnerId; owner != want {
+				t.Fatalf("expected the owner %q, got %q", want, owner)
+			}
+		})
+	}
+}
+
+func TestMemberKeepsTheOwnerOnReRegistration(t *testing.T) {
+	storage = newTestStorage(t)
+	newTestCooldown(t, config.RegistrationCooldown())
+	defer func() { storage = nil }()
+	storage.Put("phone", &l8myfamily.Device{Id: "phone", FamilyId: "fam", Name: "Phone", OwnerId: "mom-login"})
+	device := &l8myfamily.Device{Id: "phone", FamilyId: "fam", Name: "Phone"}
+	defer auth.Bind(device, &auth.Caller{User: "teen-login", FamilyId: "fam", MemberId: "teen"})()
+	if _, _, err := (&DeviceCallback{}).Before(device, ifs.POST, false, nil); err != nil {
+		t.Fatalf("a member may register the device again without an owner, got %v", err)
+	}
+	if owner := storedDevice(t, "phone").OwnerId; owner != "mom-login" {
+		t.Fatalf("the owner must be kept, got %q", owner)
+	}
+}
@@ -0,0 +1,113 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package device_service
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/saichler/l8myfamiliy/go/myf/auth"
+	"github.com/saichler/l8myfamiliy/go/myf/config"
+	"github.com/saichler/l8myfamiliy/go/types/l8myfamily"
+	"google.golang.org/protobuf/proto"
+)
+
+// MaxVisibleTo is the most members a device can be shared with
+const MaxVisibleTo = 64
+
+// ErrInvalidVisibility is returned for a visibility list beyond the cap
+var ErrInvalidVisibility = errors.New("invalid visibility")
+
+// ErrVisibilityDenied is returned when the caller may not change who sees a device
+var ErrVisibilityDenied = errors.New("only the device owner or a family admin may change its visibility")
+
+// VisibilityUpdate replaces the members a device is shared with, empty
+// shares it with every member of the family
+type VisibilityUpdate struct {
+	VisibleTo []string `json:"visibleTo"`
+}
+
+// CanSee returns true if the viewer may see the device. A device without a
+// visibility list is seen by every member of its family, the family account,
+// the family admins, the admins and the device owner always see it. A nil
+// viewer is an internal query and sees every device.
+func CanSee(device *l8myfamily.Device, viewer *auth.Caller) bool {
+	if viewer == nil || len(device.VisibleTo) == 0 || canManage(device, viewer) {
+		return true
+	}
+	for _, memberId := range device.VisibleTo {
+		if memberId == viewer.MemberId {
+			return true
+		}
+	}
+	return false
+}
+
+// canManage returns true if the caller may change who sees the device: the
+// admins, the family account, the family admins and the device owner
+func canManage(device *l8myfamily.Device, caller *auth.Caller) bool {
+	if caller.Admin || caller.MemberId == "" {
+		return true
+	}
+	return isOwner(device, caller) || config.IsFamilyAdmin(device.FamilyId, caller.MemberId)
+}
+
+// isOwner returns true if the caller owns the device, the user of its
+// ownerId. A device registered before it had an owner is owned by its member.
+func isOwner(device *l8myfamily.Device, caller *auth.Caller) bool {
+	if device.OwnerId != "" {
+		return caller.User == device.OwnerId
+	}
+	return caller.MemberId != "" && caller.MemberId == device.MemberId
+}
+
+// SetVisibility replaces the members the device of the caller family is
+// shared with and returns the updated device, nil when the device is not in
+// the family or not visible to the caller
+func SetVisibility(caller *auth.Caller, deviceId string, update *VisibilityUpdate) (*l8myfamily.Device, error) {
+	if storage == nil {
+		return nil, fmt.Errorf("device service is not active")
+	}
+	if len(update.VisibleTo) > MaxVisibleTo {
+		return nil, fmt.Errorf("%w: a device is shared with at most %d members, got %d", ErrInvalidVisibility, MaxVisibleTo, len(update.VisibleTo))
+	}
+	visibleTo := make([]string, 0, len(update.VisibleTo))
+	for _, memberId := range update.VisibleTo {
+		if memberId = strings.TrimSpace(memberId); memberId != "" {
+			visibleTo = append(visibleTo, memberId)
+		}
+	}
+	defer deviceLocks.lock(deviceId)()
+	elem, err := storage.Get(deviceId)
+	if err != nil {
+		return nil, nil
+	}
+	stored := elem.(*l8myfamily.Device)
+	if stored.FamilyId != caller.FamilyId || !CanSee(stored, caller) {
+		return nil, nil
+	}
+	if !canManage(stored, caller) {
+		return nil, ErrVisibilityDenied
+	}
+	// The stored device is shared with the cache, it is changed on a copy
+	device := proto.Clone(stored).(*l8myfamily.Device)
+	device.VisibleTo = visibleTo
+	if err := storage.Put(deviceId, device); err != nil {
+		return nil, err
+	}
+	return device, nil
+}
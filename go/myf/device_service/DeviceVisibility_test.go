@@ -0,0 +1,197 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package device_service
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/saichler/l8myfamiliy/go/myf/auth"
+	"github.com/saichler/l8myfamiliy/go/myf/config"
+	"github.com/saichler/l8myfamiliy/go/types/l8myfamily"
+	"github.com/saichler/l8types/go/ifs"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// storeFamily stores a teen phone shared with the parents only and a mom phone
+// shared with everyone, mom is a family admin
+func storeFamily(t *testing.T) {
+	storage = newTestStorage(t)
+	cfg := config.Default()
+	cfg.Families = map[string]*config.FamilyConfig{"fam": {Admins: []string{"mom"}}}
+	cfg.MemberLogins = map[string]*config.MemberLogin{"sib-login": {FamilyId: "fam", MemberId: "sib"}}
+	config.Set(cfg)
+	storage.Put("teen-phone", &l8myfamily.Device{Id: "teen-phone", FamilyId: "fam", MemberId: "teen", VisibleTo: []string{"dad"}})
+	storage.Put("mom-phone", &l8myfamily.Device{Id: "mom-phone", FamilyId: "fam", MemberId: "mom"})
+}
+
+func deviceIds(devices []*l8myfamily.Device) string {
+	ids := make([]string, 0, len(devices))
+	for _, device := range devices {
+		ids = append(ids, device.Id)
+	}
+	return strings.Join(ids, ",")
+}
+
+func TestRestrictedDeviceHiddenFromSibling(t *testing.T) {
+	storeFamily(t)
+	defer func() { storage = nil; config.Set(config.Default()) }()
+
+	sibling := &auth.Caller{User: "sib-login", FamilyId: "fam", MemberId: "sib"}
+	for _, test := range []struct {
+		viewer *auth.Caller
+		want   string
+	}{
+		{sibling, "mom-phone"},
+		{&auth.Caller{User: "fam", FamilyId: "fam"}, "mom-phone,teen-phone"},
+		{&auth.Caller{User: "dad-login", FamilyId: "fam", MemberId: "dad"}, "mom-phone,teen-phone"},
+		{&auth.Caller{User: "teen-login", FamilyId: "fam", MemberId: "teen"}, "mom-phone,teen-phone"},
+		{&auth.Caller{User: "mom-login", FamilyId: "fam", MemberId: "mom"}, "mom-phone,teen-phone"},
+	} {
+		if got := deviceIds(ListDevices(&DeviceFilter{FamilyId: "fam", Viewer: test.viewer})); got != test.want {
+			t.Fatalf("%s must see %s, got %s", test.viewer.User, test.want, got)
+		}
+	}
+	if summary := Summarize("fam", sibling, time.Now()); summary.Devices != 1 {
+		t.Fatalf("the sibling summary must not count the hidden device, got %d", summary.Devices)
+	}
+
+	// The sibling logs in with a member login
+	origValidate := auth.ValidateToken
+	auth.ValidateToken = func(token string, vnic ifs.IVNic) (string, bool) { return token, true }
+	defer func() { auth.ValidateToken = origValidate }()
+	r := httptest.NewRequest("GET", "/my-family/devices", nil)
+	r.Header.Set("Authorization", "Bearer sib-login")
+	w := httptest.NewRecorder()
+	devicesHandler(w, r, nil)
+	list := &l8myfamily.DeviceList{}
+	protojson.Unmarshal(w.Body.Bytes(), list)
+	if w.Code != http.StatusOK || deviceIds(list.List) != "mom-phone" {
+		t.Fatalf("the sibling must only list the shared device, got %d %s", w.Code, w.Body.String())
+	}
+}
+
+func TestSetVisibility(t *testing.T) {
+	storeFamily(t)
+	defer func() { storage = nil; config.Set(config.Default()) }()
+	sibling := &auth.Caller{User: "sib-login", FamilyId: "fam", MemberId: "sib"}
+	teen := &auth.Caller{User: "teen-login", FamilyId: "fam", MemberId: "teen"}
+
+	if device, err := SetVisibility(sibling, "teen-phone", &VisibilityUpdate{VisibleTo: []string{"sib"}}); device != nil || err != nil {
+		t.Fatalf("a hidden device must not be found, got %v %v", device, err)
+	}
+	if _, err := SetVisibility(sibling, "mom-phone", &VisibilityUpdate{VisibleTo: []string{"sib"}}); !errors.Is(err, ErrVisibilityDenied) {
+		t.Fatalf("only the owner or an admin may share a device, got %v", err)
+	}
+	device, err := SetVisibility(teen, "teen-phone", &VisibilityUpdate{VisibleTo: []string{"dad", " sib "}})
+	if err != nil || strings.Join(device.VisibleTo, ",") != "dad,sib" || !CanSee(device, sibling) {
+		t.Fatalf("the owner must share the device, got %v %v", device, err)
+	}
+	tooMany := make([]string, MaxVisibleTo+1)
+	if _, err := SetVisibility(teen, "teen-phone", &VisibilityUpdate{VisibleTo: tooMany}); !errors.Is(err, ErrInvalidVisibility) {
+		t.Fatalf("expected the cap to be enforced, got %v", err)
+	}
+
+	// An agent re-registers without the visibility
+	registered := &l8myfamily.Device{Id: "teen-phone", FamilyId: "fam", MemberId: "teen", Name: "Phone"}
+	if ok, err := registerInternal(registered); ok || err != nil {
+		t.Fatalf("a re-registration must update the stored device, got %v %v", ok, err)
+	}
+	if visibleTo := storedDevice(t, "teen-phone").VisibleTo; strings.Join(visibleTo, ",") != "dad,sib" {
+		t.Fatalf("a re-registration must keep the visibility, got %v", visibleTo)
+	}
+}
+
+func TestOwnerManagesTheirDevice(t *testing.T) {
+	storeFamily(t)
+	defer func() { storage = nil; config.Set(config.Default()) }()
+	// Dad registered the teen tablet for the teen and owns it, he is no admin
+	storage.Put("tablet", &l8myfamily.Device{Id: "tablet", FamilyId: "fam", MemberId: "teen", OwnerId: "dad-login",
+		VisibleTo: []string{"mom"}})
+	dad := &auth.Caller{User: "dad-login", FamilyId: "fam", MemberId: "dad"}
+	teen := &auth.Caller{User: "teen-login", FamilyId: "fam", MemberId: "teen"}
+
+	if !CanSee(storedDevice(t, "tablet"), dad) {
+		t.Fatal("the owner must see the restricted device")
+	}
+	device, err := SetVisibility(dad, "tablet", &VisibilityUpdate{VisibleTo: []string{"teen"}})
+	if err != nil || device == nil || strings.Join(device.VisibleTo, ",") != "teen" {
+		t.Fatalf("the owner must manage the device, got %v %v", device, err)
+	}
+	if _, err := SetVisibility(teen, "tablet", &VisibilityUpdate{}); !errors.Is(err, ErrVisibilityDenied) {
+		t.Fatalf("the member of a device with an owner does not manage it, got %v", err)
+	}
+}
+
+func TestDevicesOfAnotherFamily(t *testing.T) {
+	storeFamily(t)
+	defer func() { storage = nil; config.Set(config.Default()) }()
+	cfg := config.Get()
+	cfg.FamilyAccess = map[string][]*config.MemberLogin{"sib-login": {{FamilyId: "grandma", MemberId: "sib"}}}
+	config.Set(cfg)
+	storage.Put("grandma-phone", &l8myfamily.Device{Id: "grandma-phone", FamilyId: "grandma", MemberId: "grandma"})
+	origValidate := auth.ValidateToken
+	auth.ValidateToken = func(token string, vnic ifs.IVNic) (string, bool) { return token, true }
+	defer func() { auth.ValidateToken = origValidate }()
+	get := func(path string, handler func(http.ResponseWriter, *http.Request, ifs.IVNic)) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("GET", path, nil)
+		r.Header.Set("Authorization", "Bearer sib-login")
+		w := httptest.NewRecorder()
+		handler(w, r, nil)
+		return w
+	}
+
+	w := get("/my-family/families", familiesHandler)
+	families := &auth.FamilyAccessList{}
+	json.Unmarshal(w.Body.Bytes(), families)
+	if w.Code != http.StatusOK || len(families.Families) != 2 || families.Families[0].FamilyId != "fam" || !families.Families[0].Primary ||
+		families.Families[1].FamilyId != "grandma" || families.Families[1].Role != auth.RoleMember {
+		t.Fatalf("expected the primary and the granted family, got %d %s", w.Code, w.Body.String())
+	}
+
+	for path, want := range map[string]string{
+		"/my-family/devices":                  "mom-phone",
+		"/my-family/devices?familyId=fam":     "mom-phone",
+		"/my-family/devices?familyId=grandma": "grandma-phone",
+	} {
+		w := get(path, devicesHandler)
+		list := &l8myfamily.DeviceList{}
+		protojson.Unmarshal(w.Body.Bytes(), list)
+		if w.Code != http.StatusOK || deviceIds(list.List) != want {
+			t.Fatalf("%s: expected %s, got %d %s", path, want, w.Code, w.Body.String())
+		}
+	}
+	summary := &FamilySummary{}
+	w = get("/my-family/53/summary?familyId=grandma", summaryHandler)
+	json.Unmarshal(w.Body.Bytes(), summary)
+	if w.Code != http.StatusOK || summary.Devices != 1 {
+		t.Fatalf("expected the summary of the selected family, got %d %s", w.Code, w.Body.String())
+	}
+	for _, path := range []string{"/my-family/devices?familyId=other", "/my-family/53/summary?familyId=other"} {
+		handler := devicesHandler
+		if strings.Contains(path, "summary") {
+			handler = summaryHandler
+		}
+		if w := get(path, handler); w.Code != http.StatusForbidden {
+			t.Fatalf("%s: a family not granted must be answered 403, got %d", path, w.Code)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package device_service
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/saichler/l8myfamiliy/go/myf/audit"
+	"github.com/saichler/l8myfamiliy/go/myf/auth"
+	"github.com/saichler/l8myfamiliy/go/myf/config"
+	"github.com/saichler/l8myfamiliy/go/types/l8myfamily"
+	"google.golang.org/protobuf/proto"
+)
+
+// ErrEmergencyDenied is returned when the caller may not clear an emergency
+var ErrEmergencyDenied = errors.New("only the family account or a family admin may clear an emergency")
+
+// EmergencyCleared returns true if the emergency location was taken before
+// the device emergency was last cleared, e.g. an agent retry of the resolved
+// SOS, it neither flags the device again nor alerts the family
+func EmergencyCleared(device *l8myfamily.Device, location *l8myfamily.Location) bool {
+	return device != nil && location.Timestamp > 0 && location.Timestamp <= device.EmergencyClearedTime
+}
+
+// canClearEmergency returns true if the caller may resolve an emergency of
+// the device, the family account, a family admin or an admin
+func canClearEmergency(device *l8myfamily.Device, caller *auth.Caller) bool {
+	return caller.Admin || caller.MemberId == "" || config.IsFamilyAdmin(device.FamilyId, caller.MemberId)
+}
+
+// ClearEmergency marks the emergency of the device resolved by the caller
+// and returns the device, nil when the device is not in the caller family or
+// not visible to the caller. A device not in an emergency is returned as is.
+func ClearEmergency(caller *auth.Caller, deviceId string) (*l8myfamily.Device, error) {
+	if storage == nil {
+		return nil, fmt.Errorf("device service is not active")
+	}
+	defer deviceLocks.lock(deviceId)()
+	elem, err := storage.Get(deviceId)
+	if err != nil {
+		return nil, nil
+	}
+	stored := elem.(*l8myfamily.Device)
+	if stored.FamilyId != caller.FamilyId || !CanSee(stored, caller) {
+		return nil, nil
+	}
+	if !canClearEmergency(stored, caller) {
+		return nil, ErrEmergencyDenied
+	}
+	if !stored.Emergency {
+		return stored, nil
+	}
+	// The stored device is shared with the cache, it is changed on a copy
+	device := proto.Clone(stored).(*l8myfamily.Device)
+	device.Emergency = false
+	device.EmergencyClearedTime = time.Now().UnixMilli()
+	device.EmergencyClearedBy = caller.User
+	if err := storage.Put(deviceId, device); err != nil {
+		return nil, err
+	}
+	deviceStream.Publish(device)
+	audit.Log("emergency-cleared", caller.User, deviceId, device.FamilyId,
+		"since="+time.UnixMilli(stored.EmergencyTime).UTC().Format(time.RFC3339))
+	return device, nil
+}
@@ -0,0 +1,119 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package device_service
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/saichler/l8myfamiliy/go/myf/audit"
+	"github.com/saichler/l8myfamiliy/go/myf/auth"
+	"github.com/saichler/l8myfamiliy/go/myf/config"
+	"github.com/saichler/l8myfamiliy/go/types/l8myfamily"
+	"github.com/saichler/l8types/go/ifs"
+	"google.golang.org/protobuf/proto"
+)
+
+// postEmergency applies an emergency location taken at ts to the stored device
+func postEmergency(t *testing.T, id string, ts int64) *l8myfamily.Device {
+	device := proto.Clone(storedDevice(t, id)).(*l8myfamily.Device)
+	applyLocation(device, &l8myfamily.Location{DeviceId: id, Latitude: 1, Longitude: 2, Timestamp: ts, Emergency: true})
+	storage.Put(id, device)
+	return device
+}
+
+func TestEmergencySetClearReset(t *testing.T) {
+	storeFamily(t)
+	defer func() { storage = nil; config.Set(config.Default()) }()
+	cfg := config.Get()
+	cfg.DataDir = t.TempDir()
+	config.Set(cfg)
+	sibling := &auth.Caller{User: "sib-login", FamilyId: "fam", MemberId: "sib"}
+	mom := &auth.Caller{User: "mom-login", FamilyId: "fam", MemberId: "mom"}
+
+	sos := time.Now().UnixMilli()
+	if device := postEmergency(t, "mom-phone", sos); !device.Emergency || device.EmergencyTime == 0 {
+		t.Fatalf("an emergency post must flag the device, got %v", device)
+	}
+	if _, err := ClearEmergency(sibling, "mom-phone"); !errors.Is(err, ErrEmergencyDenied) {
+		t.Fatalf("a member must not clear an emergency, got %v", err)
+	}
+	if device, err := ClearEmergency(&auth.Caller{User: "other", FamilyId: "other"}, "mom-phone"); device != nil || err != nil {
+		t.Fatalf("another family must not find the device, got %v %v", device, err)
+	}
+	device, err := ClearEmergency(mom, "mom-phone")
+	if err != nil || device.Emergency || device.EmergencyClearedBy != "mom-login" || device.EmergencyClearedTime < sos {
+		t.Fatalf("a family admin must clear the emergency, got %v %v", device, err)
+	}
+	if stored := storedDevice(t, "mom-phone"); stored.Emergency || stored.EmergencyClearedBy != "mom-login" {
+		t.Fatalf("the cleared emergency must be stored, got %v", stored)
+	}
+	records, _ := audit.Read()
+	if len(records) != 1 || records[0].Action != "emergency-cleared" || records[0].Actor != "mom-login" || records[0].DeviceId != "mom-phone" {
+		t.Fatalf("expected the clear audited, got %v", records)
+	}
+
+	// A retry of the resolved SOS stays cleared, a new one reactivates it
+	if device := postEmergency(t, "mom-phone", sos); device.Emergency {
+		t.Fatalf("a retried emergency must not reactivate the device, got %v", device)
+	}
+	if !EmergencyCleared(storedDevice(t, "mom-phone"), &l8myfamily.Location{Timestamp: sos}) {
+		t.Fatal("the retried emergency must be reported as cleared")
+	}
+	again := time.Now().UnixMilli() + 1000
+	if device := postEmergency(t, "mom-phone", again); !device.Emergency || device.EmergencyClearedBy != "mom-login" {
+		t.Fatalf("a new emergency must reactivate the device, got %v", device)
+	}
+	if EmergencyCleared(storedDevice(t, "mom-phone"), &l8myfamily.Location{Timestamp: again}) {
+		t.Fatal("a new emergency must not be reported as cleared")
+	}
+}
+
+func TestDeviceEmergencyHandler(t *testing.T) {
+	storeFamily(t)
+	defer func() { storage = nil; config.Set(config.Default()) }()
+	cfg := config.Get()
+	cfg.DataDir = t.TempDir()
+	config.Set(cfg)
+	origValidate := auth.ValidateToken
+	auth.ValidateToken = func(token string, vnic ifs.IVNic) (string, bool) { return token, true }
+	defer func() { auth.ValidateToken = origValidate }()
+	postEmergency(t, "teen-phone", time.Now().UnixMilli())
+
+	for _, test := range []struct {
+		method, user, deviceId string
+		want                   int
+	}{
+		{"POST", "fam", "teen-phone", http.StatusMethodNotAllowed},
+		{"DELETE", "fam", "", http.StatusBadRequest},
+		{"DELETE", "sib-login", "teen-phone", http.StatusNotFound},
+		{"DELETE", "fam", "teen-phone", http.StatusOK},
+	} {
+		r := httptest.NewRequest(test.method, "/my-family/device-emergency?deviceId="+test.deviceId, nil)
+		r.Header.Set("Authorization", "Bearer "+test.user)
+		w := httptest.NewRecorder()
+		deviceEmergencyHandler(w, r, nil)
+		if w.Code != test.want {
+			t.Fatalf("%s by %s: expected %d, got %d %s", test.method, test.user, test.want, w.Code, w.Body.String())
+		}
+	}
+	if storedDevice(t, "teen-phone").Emergency {
+		t.Fatal("the family account must clear the emergency")
+	}
+}
@@ -0,0 +1,136 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package device_service
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/saichler/l8myfamiliy/go/myf/auth"
+	"github.com/saichler/l8myfamiliy/go/myf/config"
+	"github.com/saichler/l8myfamiliy/go/types/l8myfamily"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// FamilySummary is the overview of a family for the mobile home screen
+type FamilySummary struct {
+	FamilyId string `json:"familyId"`
+	// Timezone is the family timezone the client shows local times in
+	Timezone string `json:"timezone"`
+	// Members is the number of distinct members, a device without a member
+	// counts as its own member
+	Members int `json:"members"`
+	Devices int `json:"devices"`
+	// Online devices posted within the online window, Offline devices did
+	// not post within the offline grace, the Late devices in between missed
+	// a post or two
+	Online  int `json:"online"`
+	Late    int `json:"late"`
+	Offline int `json:"offline"`
+	// LastUpdated is the device with the most recent location
+	LastUpdated *l8myfamily.Device `json:"lastUpdated,omitempty"`
+	// Emergencies are the ids of the devices in an emergency
+	Emergencies []string `json:"emergencies,omitempty"`
+	// Avatars are the marker avatars of the devices that have one, by id
+	Avatars map[string]string `json:"avatars,omitempty"`
+	// Movements are the movement states of the devices that have one, by id
+	Movements map[string]string `json:"movements,omitempty"`
+}
+
+// summaryFields are the fields of a FamilySummary without its json methods
+type summaryFields FamilySummary
+
+// MarshalJSON writes the last updated device as protobuf JSON, like the
+// device responses
+func (this FamilySummary) MarshalJSON() ([]byte, error) {
+	summary := struct {
+		summaryFields
+		LastUpdated json.RawMessage `json:"lastUpdated,omitempty"`
+	}{summaryFields: summaryFields(this)}
+	if this.LastUpdated != nil {
+		data, err := protojson.Marshal(this.LastUpdated)
+		if err != nil {
+			return nil, err
+		}
+		summary.LastUpdated = data
+	}
+	return json.Marshal(summary)
+}
+
+func (this *FamilySummary) UnmarshalJSON(data []byte) error {
+	summary := struct {
+		*summaryFields
+		LastUpdated json.RawMessage `json:"lastUpdated,omitempty"`
+	}{summaryFields: (*summaryFields)(this)}
+	if err := json.Unmarshal(data, &summary); err != nil {
+		return err
+	}
+	this.LastUpdated = nil
+	if len(summary.LastUpdated) == 0 {
+		return nil
+	}
+	this.LastUpdated = &l8myfamily.Device{}
+	return protojson.Unmarshal(summary.LastUpdated, this.LastUpdated)
+}
+
+// IsOnline returns true if the device posted a location within the online window
+func IsOnline(device *l8myfamily.Device, now time.Time) bool {
+	window := time.Duration(config.Get().OnlineWindowSeconds) * time.Second
+	return device.LastSeen > 0 && now.Sub(time.UnixMilli(device.LastSeen)) <= window
+}
+
+// Summarize builds the summary of the family devices the viewer may see
+// from the device store
+func Summarize(familyId string, viewer *auth.Caller, now time.Time) *FamilySummary {
+	summary := &FamilySummary{FamilyId: familyId, Timezone: config.TimezoneFor(familyId).String()}
+	members := make(map[string]bool)
+	for _, device := range ListDevices(&DeviceFilter{FamilyId: familyId, Viewer: viewer}) {
+		summary.Devices++
+		member := device.MemberId
+		if member == "" {
+			member = "device:" + device.Id
+		}
+		members[member] = true
+		switch {
+		case IsOnline(device, now):
+			summary.Online++
+		case IsOffline(device, now):
+			summary.Offline++
+		default:
+			summary.Late++
+		}
+		if device.Emergency {
+			summary.Emergencies = append(summary.Emergencies, device.Id)
+		}
+		if device.Avatar != "" {
+			if summary.Avatars == nil {
+				summary.Avatars = make(map[string]string)
+			}
+			summary.Avatars[device.Id] = device.Avatar
+		}
+		if device.Movement != "" {
+			if summary.Movements == nil {
+				summary.Movements = make(map[string]string)
+			}
+			summary.Movements[device.Id] = device.Movement
+		}
+		if device.LastSeen > 0 && (summary.LastUpdated == nil || device.LastSeen > summary.LastUpdated.LastSeen) {
+			summary.LastUpdated = device
+		}
+	}
+	summary.Members = len(members)
+	return summary
+}
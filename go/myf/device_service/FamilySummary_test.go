@@ -0,0 +1,56 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package device_service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/saichler/l8myfamiliy/go/types/l8myfamily"
+)
+
+func TestSummarize(t *testing.T) {
+	storage = newTestStorage(t)
+	defer func() { storage = nil }()
+	now := time.Now()
+	recent := now.Add(-time.Minute).UnixMilli()
+	latest := now.Add(-10 * time.Second).UnixMilli()
+	stale := now.Add(-time.Hour).UnixMilli()
+	late := now.Add(-10 * time.Minute).UnixMilli()
+	storage.Put("p1", &l8myfamily.Device{Id: "p1", FamilyId: "fam", MemberId: "mom", LastSeen: recent})
+	storage.Put("l1", &l8myfamily.Device{Id: "l1", FamilyId: "fam", MemberId: "mom", LastSeen: stale})
+	storage.Put("p2", &l8myfamily.Device{Id: "p2", FamilyId: "fam", MemberId: "kid", LastSeen: latest, Emergency: true, Movement: "driving"})
+	storage.Put("t1", &l8myfamily.Device{Id: "t1", FamilyId: "fam"})
+	storage.Put("w1", &l8myfamily.Device{Id: "w1", FamilyId: "fam", MemberId: "kid", LastSeen: late})
+	storage.Put("x1", &l8myfamily.Device{Id: "x1", FamilyId: "other", LastSeen: latest})
+
+	summary := Summarize("fam", nil, now)
+	if summary.Devices != 5 || summary.Members != 3 || summary.Online != 2 || summary.Late != 1 || summary.Offline != 2 {
+		t.Fatalf("unexpected counts %+v", summary)
+	}
+	if summary.Timezone != "UTC" {
+		t.Fatalf("a family without a timezone must report UTC, got %s", summary.Timezone)
+	}
+	if summary.LastUpdated == nil || summary.LastUpdated.Id != "p2" {
+		t.Fatalf("expected p2 as the last updated device, got %v", summary.LastUpdated)
+	}
+	if len(summary.Emergencies) != 1 || summary.Emergencies[0] != "p2" {
+		t.Fatalf("expected the p2 emergency, got %v", summary.Emergencies)
+	}
+	if len(summary.Movements) != 1 || summary.Movements["p2"] != "driving" {
+		t.Fatalf("expected p2 driving, got %v", summary.Movements)
+	}
+}
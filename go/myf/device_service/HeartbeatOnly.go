@@ -0,0 +1,64 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package device_service
+
+import (
+	"github.com/saichler/l8myfamiliy/go/types/l8myfamily"
+)
+
+// A heartbeat-only device shares its presence, online or offline, and never
+// a position. Its agent posts heartbeats only, the server still strips any
+// coordinate that reaches it so the device never has a stored position.
+
+// StripPosition turns the location into a heartbeat without any position
+// attribute. The emergency flag, the battery and the telemetry are kept.
+func StripPosition(location *l8myfamily.Location) {
+	location.Heartbeat = true
+	location.Latitude = 0
+	location.Longitude = 0
+	location.Accuracy = 0
+	location.Source = l8myfamily.LocationSource_LOCATION_SOURCE_UNKNOWN
+	location.Place = ""
+	location.PublicIp = ""
+	location.Geofence = ""
+	location.Unreliable = false
+	location.Coarse = false
+	location.Speed = 0
+	location.Heading = 0
+	location.Altitude = 0
+	location.SnappedLatitude = 0
+	location.SnappedLongitude = 0
+	location.Movement = ""
+}
+
+// clearPosition removes the position of a heartbeat-only device
+func clearPosition(device *l8myfamily.Device) {
+	device.Latitude = 0
+	device.Longitude = 0
+	device.Accuracy = 0
+	device.Source = l8myfamily.LocationSource_LOCATION_SOURCE_UNKNOWN
+	device.Place = ""
+	device.PublicIp = ""
+	device.Unreliable = false
+	device.Coarse = false
+	device.Speed = 0
+	device.Heading = 0
+	device.Altitude = 0
+	device.SnappedLatitude = 0
+	device.SnappedLongitude = 0
+	device.PositionTime = 0
+	device.Movement = ""
+}
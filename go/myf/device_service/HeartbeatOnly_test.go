@@ -0,0 +1,78 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package device_service
+
+import (
+	"testing"
+
+	"github.com/saichler/l8myfamiliy/go/myf/config"
+	"github.com/saichler/l8myfamiliy/go/types/l8myfamily"
+	"github.com/saichler/l8types/go/ifs"
+)
+
+func TestHeartbeatOnlyNeverStoresPosition(t *testing.T) {
+	device := &l8myfamily.Device{Id: "d1", HeartbeatOnly: true}
+	applyLocation(device, &l8myfamily.Location{DeviceId: "d1", Latitude: 1, Longitude: 2, Accuracy: 5,
+		Place: "Home", PublicIp: "1.2.3.4", Timestamp: 5000})
+	if device.Latitude != 0 || device.Longitude != 0 || device.Accuracy != 0 || device.Place != "" || device.PublicIp != "" ||
+		device.PositionTime != 0 {
+		t.Fatalf("a heartbeat-only device must not store a position, got %v", device)
+	}
+	if device.LastSeen != 5000 {
+		t.Fatalf("the location must keep the device online, got %d", device.LastSeen)
+	}
+	applyLocation(device, &l8myfamily.Location{DeviceId: "d1", Latitude: 1, Longitude: 2, Emergency: true, Timestamp: 6000})
+	if device.Latitude != 0 || !device.Emergency {
+		t.Fatalf("an emergency must be flagged without a position, got %v", device)
+	}
+}
+
+func TestHeartbeatOnlyRegistrationDropsPosition(t *testing.T) {
+	storage = newTestStorage(t)
+	newTestCooldown(t, config.RegistrationCooldown())
+	defer func() { storage = nil }()
+	cb := &DeviceCallback{}
+
+	// A new device is created by the service without the leaked position
+	registration := &l8myfamily.Device{Id: "laptop", FamilyId: "fam", Name: "Laptop", HeartbeatOnly: true, Latitude: 1, Longitude: 2}
+	if ok, err := registerInternal(registration); !ok || err != nil {
+		t.Fatalf("a new device must be left to the service, got %v %v", ok, err)
+	}
+	if registration.Latitude != 0 || registration.Longitude != 0 {
+		t.Fatalf("the registration must not carry a position, got %v", registration)
+	}
+
+	// A registered device that turns heartbeat-only loses its position
+	storage.Put("phone", &l8myfamily.Device{Id: "phone", FamilyId: "fam", Name: "Phone", Latitude: 40.7,
+		Longitude: -74, PositionTime: 5000, LastSeen: 5000})
+	registration = &l8myfamily.Device{Id: "phone", FamilyId: "fam", Name: "Phone", HeartbeatOnly: true}
+	if ok, err := registerInternal(registration); ok || err != nil {
+		t.Fatalf("a re-registration must update the stored device, got %v %v", ok, err)
+	}
+	stored := storedDevice(t, "phone")
+	if !stored.HeartbeatOnly || stored.Latitude != 0 || stored.Longitude != 0 || stored.PositionTime != 0 || stored.LastSeen != 5000 {
+		t.Fatalf("the stored device must keep its presence only, got %v", stored)
+	}
+
+	// A patch cannot set a position either
+	patch := &l8myfamily.Device{Id: "phone", Latitude: 1, Longitude: 2}
+	if _, ok, err := cb.Before(patch, ifs.PATCH, false, nil); !ok || err != nil {
+		t.Fatalf("unexpected patch result %v %v", ok, err)
+	}
+	if patch.Latitude != 0 || patch.Longitude != 0 {
+		t.Fatalf("the patch must not carry a position, got %v", patch)
+	}
+}
@@ -0,0 +1,163 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package device_service
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/saichler/l8myfamiliy/go/myf/audit"
+	"github.com/saichler/l8myfamiliy/go/myf/config"
+	"github.com/saichler/l8myfamiliy/go/myf/notify"
+	"github.com/saichler/l8myfamiliy/go/types/l8myfamily"
+)
+
+// offlineSweepInterval is how often the devices are checked for going offline
+var offlineSweepInterval = time.Minute
+
+var (
+	sweepOnce = &sync.Once{}
+	offline   = NewOfflineTracker()
+)
+
+// MaxReportIntervalSeconds bounds the report interval an agent announces
+const MaxReportIntervalSeconds = 24 * 3600
+
+// missedReports is how many announced report intervals a device may miss
+// before it is offline
+const missedReports = 2
+
+// clampReportInterval bounds an announced report interval, 0 is not announced
+func clampReportInterval(seconds int32) int32 {
+	if seconds < 0 {
+		return 0
+	}
+	if seconds > MaxReportIntervalSeconds {
+		return MaxReportIntervalSeconds
+	}
+	return seconds
+}
+
+// OfflineGraceOf returns the offline grace of the device, the grace of its
+// family or, for a device that announced it reports less often, the time of
+// missedReports report intervals
+func OfflineGraceOf(device *l8myfamily.Device) time.Duration {
+	grace := config.OfflineGraceFor(device.FamilyId)
+	if announced := time.Duration(device.ReportIntervalSeconds) * missedReports * time.Second; announced > grace {
+		return announced
+	}
+	return grace
+}
+
+// IsOffline returns true if the device did not post a location within its
+// offline grace, a device that never posted is offline
+func IsOffline(device *l8myfamily.Device, now time.Time) bool {
+	return device.LastSeen == 0 || now.Sub(time.UnixMilli(device.LastSeen)) > OfflineGraceOf(device)
+}
+
+// OfflineTracker notifies the family once when a device goes offline, the
+// device is tracked again once it posts
+type OfflineTracker struct {
+	mtx     *sync.Mutex
+	primed  bool
+	offline map[string]bool
+}
+
+func NewOfflineTracker() *OfflineTracker {
+	return &OfflineTracker{mtx: &sync.Mutex{}, offline: make(map[string]bool)}
+}
+
+// Sweep returns the devices that went offline since the previous sweep. The
+// first sweep only records the devices already offline, so a restart does
+// not notify them again.
+func (this *OfflineTracker) Sweep(devices []*l8myfamily.Device, now time.Time) []*l8myfamily.Device {
+	this.mtx.Lock()
+	defer this.mtx.Unlock()
+	wentOffline := make([]*l8myfamily.Device, 0)
+	seen := make(map[string]bool, len(devices))
+	for _, device := range devices {
+		seen[device.Id] = true
+		if !IsOffline(device, now) {
+			delete(this.offline, device.Id)
+			continue
+		}
+		if this.offline[device.Id] {
+			continue
+		}
+		this.offline[device.Id] = true
+		// A device that never posted was never online
+		if this.primed && device.LastSeen > 0 {
+			wentOffline = append(wentOffline, device)
+		}
+	}
+	// Forget the deleted devices
+	for id := range this.offline {
+		if !seen[id] {
+			delete(this.offline, id)
+		}
+	}
+	this.primed = true
+	return wentOffline
+}
+
+// StartOfflineSweep starts the periodic background offline check of all devices
+func StartOfflineSweep() {
+	sweepOnce.Do(func() {
+		go func() {
+			for {
+				sweepOffline(time.Now())
+				time.Sleep(offlineSweepInterval)
+			}
+		}()
+	})
+}
+
+func sweepOffline(now time.Time) {
+	if storage == nil {
+		return
+	}
+	// An archived device is not expected to post, it is not tracked
+	collected := storage.Collect(func(elem interface{}) (bool, interface{}) {
+		device, ok := elem.(*l8myfamily.Device)
+		return ok && device.ArchivedTime == 0, elem
+	})
+	devices := make([]*l8myfamily.Device, 0, len(collected))
+	for _, elem := range collected {
+		devices = append(devices, elem.(*l8myfamily.Device))
+	}
+	for _, device := range offline.Sweep(devices, now) {
+		onOffline(device, now)
+	}
+}
+
+func onOffline(device *l8myfamily.Device, now time.Time) {
+	name := device.Id
+	if device.Name != "" {
+		name = device.Name
+	}
+	since := now.Sub(time.UnixMilli(device.LastSeen)).Round(time.Minute)
+	fmt.Println("[Device] ", device.Id, "-", device.FamilyId, " offline for ", since)
+	notify.Send(&notify.Event{
+		Type:      notify.EventOffline,
+		DeviceId:  device.Id,
+		FamilyId:  device.FamilyId,
+		Latitude:  device.Latitude,
+		Longitude: device.Longitude,
+		Message:   fmt.Sprintf("%s is offline, last seen %s ago", name, since),
+	})
+	audit.Log(notify.EventOffline, device.Id, device.Id, device.FamilyId, "lastSeen="+time.UnixMilli(device.LastSeen).UTC().Format(time.RFC3339))
+}
@@ -0,0 +1,105 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package device_service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/saichler/l8myfamiliy/go/myf/config"
+	"github.com/saichler/l8myfamiliy/go/types/l8myfamily"
+)
+
+func TestOfflineTrackerNotifiesOnce(t *testing.T) {
+	config.Set(&config.ServerConfig{OnlineWindowSeconds: 300, OfflineGraceSeconds: 900,
+		Families: map[string]*config.FamilyConfig{"patient": {OfflineGraceSeconds: 3600}}})
+	defer config.Set(config.Default())
+	now := time.Now()
+	seen := func(ago time.Duration) int64 { return now.Add(-ago).UnixMilli() }
+
+	tracker := NewOfflineTracker()
+	devices := []*l8myfamily.Device{
+		{Id: "gone", FamilyId: "fam", LastSeen: seen(time.Hour)},
+		{Id: "phone", FamilyId: "fam", LastSeen: seen(time.Minute)},
+		{Id: "never", FamilyId: "fam"},
+	}
+	if offline := tracker.Sweep(devices, now); len(offline) != 0 {
+		t.Fatalf("the first sweep must only record the offline devices, got %v", offline)
+	}
+
+	// A missed post or two is within the grace
+	devices[1].LastSeen = seen(10 * time.Minute)
+	if offline := tracker.Sweep(devices, now); len(offline) != 0 {
+		t.Fatalf("a device within the grace must not go offline, got %v", offline)
+	}
+	devices[1].LastSeen = seen(16 * time.Minute)
+	if offline := tracker.Sweep(devices, now); len(offline) != 1 || offline[0].Id != "phone" {
+		t.Fatalf("expected the phone to go offline, got %v", offline)
+	}
+	if offline := tracker.Sweep(devices, now); len(offline) != 0 {
+		t.Fatalf("an offline device must be notified once, got %v", offline)
+	}
+
+	// Posting again starts a new episode
+	devices[1].LastSeen = seen(0)
+	tracker.Sweep(devices, now)
+	devices[1].LastSeen = seen(20 * time.Minute)
+	if offline := tracker.Sweep(devices, now); len(offline) != 1 {
+		t.Fatalf("a device back online must be notified again, got %v", offline)
+	}
+
+	patient := []*l8myfamily.Device{{Id: "tablet", FamilyId: "patient", LastSeen: seen(30 * time.Minute)}}
+	if IsOffline(patient[0], now) {
+		t.Fatal("the family grace must override the global grace")
+	}
+}
+
+func TestOfflineGraceOfAnnouncedInterval(t *testing.T) {
+	config.Set(&config.ServerConfig{OnlineWindowSeconds: 300, OfflineGraceSeconds: 900})
+	defer config.Set(config.Default())
+	now := time.Now()
+
+	device := &l8myfamily.Device{Id: "laptop", FamilyId: "fam", LastSeen: now.Add(-time.Hour).UnixMilli()}
+	if grace := OfflineGraceOf(device); grace != 15*time.Minute {
+		t.Fatalf("a device without an interval must use the family grace, got %v", grace)
+	}
+	if !IsOffline(device, now) {
+		t.Fatalf("an hour of silence must be offline with the family grace")
+	}
+
+	// A laptop that only reports every 45 minutes may miss two reports
+	device.ReportIntervalSeconds = 45 * 60
+	if grace := OfflineGraceOf(device); grace != 90*time.Minute {
+		t.Fatalf("expected two report intervals, got %v", grace)
+	}
+	if IsOffline(device, now) {
+		t.Fatalf("an hour of silence must be within two announced intervals")
+	}
+
+	// A short interval never shrinks the family grace
+	device.ReportIntervalSeconds = 30
+	if grace := OfflineGraceOf(device); grace != 15*time.Minute {
+		t.Fatalf("a short interval must keep the family grace, got %v", grace)
+	}
+}
+
+func TestClampReportInterval(t *testing.T) {
+	for in, want := range map[int32]int32{-5: 0, 0: 0, 600: 600, MaxReportIntervalSeconds + 1: MaxReportIntervalSeconds} {
+		if got := clampReportInterval(in); got != want {
+			t.Errorf("clampReportInterval(%d) = %d, want %d", in, got, want)
+		}
+	}
+}
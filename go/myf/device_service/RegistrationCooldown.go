@@ -0,0 +1,68 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package device_service
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// registration is the device id a family device name was last registered with
+type registration struct {
+	id string
+	at time.Time
+}
+
+// RegistrationCooldown remembers the recent registrations by family and device
+// name, so an agent that regenerates its device id on every start does not
+// create a new device each time
+type RegistrationCooldown struct {
+	mtx       *sync.Mutex
+	recent    map[string]registration
+	window    time.Duration
+	lastPrune time.Time
+}
+
+func NewRegistrationCooldown(window time.Duration) *RegistrationCooldown {
+	return &RegistrationCooldown{mtx: &sync.Mutex{}, recent: make(map[string]registration), window: window}
+}
+
+// Conflict returns the id of another device the family registered with the
+// same name within the window, or "" and records the registration when there
+// is none. Names are compared case-insensitive, an empty name never conflicts.
+func (this *RegistrationCooldown) Conflict(familyId, name, id string, now time.Time) string {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" || this.window <= 0 {
+		return ""
+	}
+	key := familyId + "/" + name
+	this.mtx.Lock()
+	defer this.mtx.Unlock()
+	if now.Sub(this.lastPrune) > this.window {
+		for k, r := range this.recent {
+			if now.Sub(r.at) > this.window {
+				delete(this.recent, k)
+			}
+		}
+		this.lastPrune = now
+	}
+	if r, ok := this.recent[key]; ok && r.id != id && now.Sub(r.at) <= this.window {
+		return r.id
+	}
+	this.recent[key] = registration{id: id, at: now}
+	return ""
+}
@@ -0,0 +1,84 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package device_service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/saichler/l8myfamiliy/go/types/l8myfamily"
+)
+
+// newTestCooldown replaces the registration cooldown for the test, the
+// registrations of the other tests are not in its window
+func newTestCooldown(t *testing.T, window time.Duration) {
+	orig := registrations
+	registrations = NewRegistrationCooldown(window)
+	t.Cleanup(func() { registrations = orig })
+}
+
+func TestRapidReRegistrationIsRejected(t *testing.T) {
+	storage = newTestStorage(t)
+	newTestCooldown(t, time.Minute)
+	defer func() { storage = nil }()
+
+	register := func(id, name string) error {
+		_, err := registerInternal(&l8myfamily.Device{Id: id, FamilyId: "fam", Name: name})
+		if err == nil {
+			storage.Put(id, &l8myfamily.Device{Id: id, FamilyId: "fam", Name: name})
+		}
+		return err
+	}
+
+	if err := register("id-1", "Laptop"); err != nil {
+		t.Fatal(err)
+	}
+	// A buggy agent restarting with a fresh id every time
+	for _, id := range []string{"id-2", "id-3", "id-4"} {
+		if err := register(id, "laptop"); err == nil {
+			t.Fatalf("the re-registration as %s must be rejected", id)
+		}
+	}
+	if err := register("id-1", "Laptop"); err != nil {
+		t.Fatalf("re-registering the known device must pass: %v", err)
+	}
+	if err := register("id-5", "Phone"); err != nil {
+		t.Fatalf("another device name must pass: %v", err)
+	}
+	if devices := ListDevices(&DeviceFilter{FamilyId: "fam"}); len(devices) != 2 {
+		t.Fatalf("expected no ghost devices, got %v", devices)
+	}
+}
+
+func TestRegistrationCooldownExpires(t *testing.T) {
+	cooldown := NewRegistrationCooldown(time.Minute)
+	now := time.Now()
+	if cooldown.Conflict("fam", "Laptop", "id-1", now) != "" {
+		t.Fatal("the first registration must not conflict")
+	}
+	if cooldown.Conflict("other", "Laptop", "id-2", now) != "" {
+		t.Fatal("another family must not conflict")
+	}
+	if cooldown.Conflict("fam", "Laptop", "id-2", now.Add(30*time.Second)) != "id-1" {
+		t.Fatal("a new id within the window must conflict")
+	}
+	if cooldown.Conflict("fam", "Laptop", "id-2", now.Add(2*time.Minute)) != "" {
+		t.Fatal("a new id after the window must pass")
+	}
+	if NewRegistrationCooldown(0).Conflict("fam", "Laptop", "id-3", now) != "" {
+		t.Fatal("a zero window disables the cooldown")
+	}
+}
@@ -0,0 +1,129 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package device_service
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// signingKeysFile is the signing key store under the data dir, the keys
+// are kept apart from the devices so they are never part of a device response
+const signingKeysFile = "signing-keys.json"
+
+// SigningKeyRotationGrace is how long the previous key of a device is still
+// accepted after the agent registered a new one, so posts signed before the
+// rotation are not rejected
+const SigningKeyRotationGrace = 10 * time.Minute
+
+// signingKeys holds the device signing keys of the activated service
+var signingKeys = NewSigningKeyStore("")
+
+type signingKey struct {
+	Key      string `json:"key"`
+	Previous string `json:"previous,omitempty"`
+	Rotated  int64  `json:"rotated,omitempty"`
+}
+
+// SigningKeyStore holds the secret each agent registered to sign its
+// location posts with, persisted to a JSON file
+type SigningKeyStore struct {
+	mtx      *sync.Mutex
+	filename string
+	keys     map[string]*signingKey
+}
+
+// NewSigningKeyStore loads the store from filename, an empty filename keeps
+// the keys in memory only
+func NewSigningKeyStore(filename string) *SigningKeyStore {
+	store := &SigningKeyStore{mtx: &sync.Mutex{}, filename: filename, keys: make(map[string]*signingKey)}
+	if filename == "" {
+		return store
+	}
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			fmt.Println("[Device] failed to read signing keys: ", err.Error())
+		}
+		return store
+	}
+	if err := json.Unmarshal(data, &store.keys); err != nil {
+		fmt.Println("[Device] failed to parse signing keys: ", err.Error())
+	}
+	return store
+}
+
+// Set registers the signing key of a device, a different key rotates the
+// current one, which stays valid for SigningKeyRotationGrace
+func (this *SigningKeyStore) Set(deviceId, key string, now time.Time) error {
+	this.mtx.Lock()
+	defer this.mtx.Unlock()
+	existing, ok := this.keys[deviceId]
+	if ok && existing.Key == key {
+		return nil
+	}
+	entry := &signingKey{Key: key}
+	if ok {
+		entry.Previous = existing.Key
+		entry.Rotated = now.UnixMilli()
+	}
+	this.keys[deviceId] = entry
+	return this.save()
+}
+
+// Keys returns the keys a post of the device may be signed with, the current
+// one first. A device without keys does not sign its posts.
+func (this *SigningKeyStore) Keys(deviceId string, now time.Time) []string {
+	this.mtx.Lock()
+	defer this.mtx.Unlock()
+	entry, ok := this.keys[deviceId]
+	if !ok {
+		return nil
+	}
+	keys := []string{entry.Key}
+	if entry.Previous != "" && now.Sub(time.UnixMilli(entry.Rotated)) < SigningKeyRotationGrace {
+		keys = append(keys, entry.Previous)
+	}
+	return keys
+}
+
+// save must be called under the lock
+func (this *SigningKeyStore) save() error {
+	if this.filename == "" {
+		return nil
+	}
+	data, err := json.Marshal(this.keys)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(this.filename), 0700); err != nil {
+		return err
+	}
+	tmp := this.filename + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, this.filename)
+}
+
+// SigningKeys returns the keys a location post of the device may be signed with
+func SigningKeys(deviceId string) []string {
+	return signingKeys.Keys(deviceId, time.Now())
+}
@@ -0,0 +1,66 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package device_service
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/saichler/l8myfamiliy/go/types/l8myfamily"
+)
+
+func TestSigningKeyRotation(t *testing.T) {
+	fn := filepath.Join(t.TempDir(), signingKeysFile)
+	store := NewSigningKeyStore(fn)
+	now := time.Now()
+	if keys := store.Keys("d1", now); len(keys) != 0 {
+		t.Fatalf("a device without a key must have none, got %v", keys)
+	}
+	store.Set("d1", "old", now)
+	store.Set("d1", "new", now)
+	if keys := store.Keys("d1", now.Add(time.Minute)); len(keys) != 2 || keys[0] != "new" || keys[1] != "old" {
+		t.Fatalf("the previous key must be valid during the grace period, got %v", keys)
+	}
+	if keys := store.Keys("d1", now.Add(SigningKeyRotationGrace)); len(keys) != 1 || keys[0] != "new" {
+		t.Fatalf("the previous key must expire after the grace period, got %v", keys)
+	}
+	reloaded := NewSigningKeyStore(fn)
+	if keys := reloaded.Keys("d1", now); len(keys) != 2 || keys[0] != "new" {
+		t.Fatalf("the keys must be persisted, got %v", keys)
+	}
+}
+
+func TestRegistrationKeepsSigningKeyOffTheDevice(t *testing.T) {
+	orig := signingKeys
+	signingKeys = NewSigningKeyStore("")
+	defer func() { signingKeys = orig }()
+
+	device := &l8myfamily.Device{Id: "d1", FamilyId: "f", SigningKey: "0123456789abcdef0123456789abcdef"}
+	if ok, err := registerInternal(device); !ok || err != nil {
+		t.Fatalf("registration failed: %v", err)
+	}
+	if device.SigningKey != "" {
+		t.Fatal("the signing key must not be stored on the device")
+	}
+	if keys := SigningKeys("d1"); len(keys) != 1 {
+		t.Fatalf("expected the registered key, got %v", keys)
+	}
+	short := &l8myfamily.Device{Id: "d2", FamilyId: "f", SigningKey: "abc"}
+	if _, err := registerInternal(short); err == nil {
+		t.Fatal("a short signing key must be rejected")
+	}
+}
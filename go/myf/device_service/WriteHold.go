@@ -0,0 +1,173 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package device_service
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/saichler/l8myfamiliy/go/types/l8myfamily"
+	"google.golang.org/protobuf/proto"
+)
+
+// writeRetryInterval is how often the held devices are written again
+var writeRetryInterval = 5 * time.Second
+
+// writeDevice writes a device record, a variable so tests can fail the disk
+var writeDevice = writeAtomic
+
+// StorageStatus is the state of the device store, Degraded while devices
+// could not be written to disk and are held in memory
+type StorageStatus struct {
+	Degraded bool `json:"degraded"`
+	// Since is when the first write failed, in unix milliseconds
+	Since     int64  `json:"since,omitempty"`
+	LastError string `json:"lastError,omitempty"`
+	// Held is the number of devices waiting to be written
+	Held    int   `json:"held"`
+	Retries int64 `json:"retries"`
+}
+
+// writeHold keeps the devices that failed to be written, e.g. on a full or
+// read-only disk, and writes them again in the background until the disk
+// takes them. While it holds devices the store is degraded. The held devices
+// are copies, the callers change the devices they put and get while the
+// retry encodes them.
+type writeHold struct {
+	filename  func(k string) string
+	mtx       *sync.Mutex
+	held      map[string]*l8myfamily.Device
+	degraded  atomic.Bool
+	since     time.Time
+	lastError string
+	retries   int64
+}
+
+func newWriteHold(filename func(k string) string) *writeHold {
+	return &writeHold{filename: filename, mtx: &sync.Mutex{}, held: make(map[string]*l8myfamily.Device)}
+}
+
+// put writes the device, a failed write is held. While degraded the writes
+// are serialized with the retry, so a retry never overwrites a newer device.
+func (this *writeHold) put(k string, device *l8myfamily.Device, data []byte) {
+	filename := this.filename(k)
+	if !this.degraded.Load() {
+		err := writeDevice(filename, data)
+		if err == nil {
+			return
+		}
+		this.mtx.Lock()
+		defer this.mtx.Unlock()
+		this.hold(filename, k, device, err)
+		return
+	}
+	this.mtx.Lock()
+	defer this.mtx.Unlock()
+	if err := writeDevice(filename, data); err != nil {
+		this.hold(filename, k, device, err)
+		return
+	}
+	delete(this.held, k)
+}
+
+// hold must be called under the mutex
+func (this *writeHold) hold(filename, k string, device *l8myfamily.Device, err error) {
+	this.held[k] = proto.Clone(device).(*l8myfamily.Device)
+	this.lastError = err.Error()
+	if this.degraded.Swap(true) {
+		return
+	}
+	this.since = time.Now()
+	fmt.Println("[DeviceStorage] failed to write ", filename, ", holding the device writes in memory: ", err.Error())
+	go this.retryLoop()
+}
+
+// retryLoop retries the held writes until they all succeed
+func (this *writeHold) retryLoop() {
+	for {
+		time.Sleep(writeRetryInterval)
+		if this.retry() {
+			return
+		}
+	}
+}
+
+// get returns a copy of the held device, or nil
+func (this *writeHold) get(k string) *l8myfamily.Device {
+	if !this.degraded.Load() {
+		return nil
+	}
+	this.mtx.Lock()
+	defer this.mtx.Unlock()
+	device, ok := this.held[k]
+	if !ok {
+		return nil
+	}
+	return proto.Clone(device).(*l8myfamily.Device)
+}
+
+// snapshot returns a copy of the held devices by key
+func (this *writeHold) snapshot() map[string]*l8myfamily.Device {
+	this.mtx.Lock()
+	defer this.mtx.Unlock()
+	held := make(map[string]*l8myfamily.Device, len(this.held))
+	for k, device := range this.held {
+		held[k] = proto.Clone(device).(*l8myfamily.Device)
+	}
+	return held
+}
+
+func (this *writeHold) remove(k string) {
+	this.mtx.Lock()
+	defer this.mtx.Unlock()
+	delete(this.held, k)
+}
+
+// retry writes the held devices again and returns true when all are on disk
+func (this *writeHold) retry() bool {
+	this.mtx.Lock()
+	defer this.mtx.Unlock()
+	this.retries++
+	for k, device := range this.held {
+		data, err := encodeDevice(device)
+		if err == nil {
+			err = writeDevice(this.filename(k), data)
+		}
+		if err != nil {
+			this.lastError = err.Error()
+			return false
+		}
+		delete(this.held, k)
+	}
+	if this.degraded.Swap(false) {
+		fmt.Println("[DeviceStorage] writes recovered after ", time.Since(this.since).Round(time.Second))
+	}
+	return true
+}
+
+// Status returns the state of the store
+func (this *writeHold) Status() *StorageStatus {
+	this.mtx.Lock()
+	defer this.mtx.Unlock()
+	status := &StorageStatus{Degraded: this.degraded.Load(), Held: len(this.held), Retries: this.retries}
+	if status.Degraded {
+		status.Since = this.since.UnixMilli()
+		status.LastError = this.lastError
+	}
+	return status
+}
@@ -0,0 +1,129 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package device_service
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/saichler/l8myfamiliy/go/types/l8myfamily"
+)
+
+func TestStorageHoldsWritesWhileDiskFails(t *testing.T) {
+	storage = newTestStorage(t)
+	var failing atomic.Bool
+	failing.Store(true)
+	origWrite, origInterval := writeDevice, writeRetryInterval
+	writeDevice = func(filename string, data []byte) error {
+		if failing.Load() {
+			return errors.New("read-only file system")
+		}
+		return writeAtomic(filename, data)
+	}
+	writeRetryInterval = time.Millisecond
+	defer func() { storage = nil; writeDevice, writeRetryInterval = origWrite, origInterval }()
+
+	if err := storage.Put("d1", &l8myfamily.Device{Id: "d1", FamilyId: "fam", Latitude: 1}); err != nil {
+		t.Fatalf("a failed write must be held, got %v", err)
+	}
+	if !StorageDegraded() {
+		t.Fatal("the store must be degraded while a write is held")
+	}
+	storage.Put("d1", &l8myfamily.Device{Id: "d1", FamilyId: "fam", Latitude: 2})
+	if elem, err := storage.Get("d1"); err != nil || elem.(*l8myfamily.Device).Latitude != 2 {
+		t.Fatalf("the held device must be served, got %v %v", elem, err)
+	}
+	if devices := ListDevices(&DeviceFilter{FamilyId: "fam"}); len(devices) != 1 {
+		t.Fatalf("the held device must be listed, got %v", devices)
+	}
+	w := httptest.NewRecorder()
+	healthHandler(w, httptest.NewRequest("GET", "/my-family/health", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("the health must report the degraded store, got %d", w.Code)
+	}
+	if ok, err := registerInternal(&l8myfamily.Device{Id: "d2", FamilyId: "fam"}); ok || err == nil {
+		t.Fatal("a registration must be refused while degraded")
+	}
+
+	failing.Store(false)
+	deadline := time.Now().Add(time.Second)
+	for StorageDegraded() && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if StorageDegraded() {
+		t.Fatal("the store must recover once the disk takes the writes")
+	}
+	device, err := storage.load("d1")
+	if err != nil || device.Latitude != 2 {
+		t.Fatalf("the latest held device must be on disk, got %v %v", device, err)
+	}
+	w = httptest.NewRecorder()
+	healthHandler(w, httptest.NewRequest("GET", "/my-family/health", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("the health must recover, got %d", w.Code)
+	}
+}
+
+// TestHeldDeviceUpdatedDuringRetry is meant for go test -race, an update of
+// a held device must not change the device the retry encodes
+func TestHeldDeviceUpdatedDuringRetry(t *testing.T) {
+	storage = newTestStorage(t)
+	origWrite, origInterval := writeDevice, writeRetryInterval
+	var failing atomic.Bool
+	failing.Store(true)
+	writeDevice = func(filename string, data []byte) error {
+		if failing.Load() {
+			return errors.New("no space left on device")
+		}
+		return writeAtomic(filename, data)
+	}
+	writeRetryInterval = time.Millisecond
+	defer func() { storage = nil; writeDevice, writeRetryInterval = origWrite, origInterval }()
+
+	storage.Put("d1", &l8myfamily.Device{Id: "d1", FamilyId: "fam"})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 20; i++ {
+			elem, err := storage.Get("d1")
+			if err != nil {
+				t.Errorf("the held device must be served, got %v", err)
+				return
+			}
+			// The retry runs while the update is in progress
+			time.Sleep(2 * writeRetryInterval)
+			device := elem.(*l8myfamily.Device)
+			device.Latitude = float32(i)
+			storage.Put("d1", device)
+		}
+	}()
+	<-done
+	if elem, err := storage.Get("d1"); err != nil || elem.(*l8myfamily.Device).Latitude != 19 {
+		t.Fatalf("the latest update must be held, got %v %v", elem, err)
+	}
+	failing.Store(false)
+	deadline := time.Now().Add(time.Second)
+	for StorageDegraded() && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if device, err := storage.load("d1"); err != nil || device.Latitude != 19 {
+		t.Fatalf("the latest update must be written, got %v %v", device, err)
+	}
+}
@@ -0,0 +1,51 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package geo
+
+import "fmt"
+
+// BBox is a latitude / longitude bounding box. A box whose MinLon is greater
+// than its MaxLon crosses the antimeridian, e.g. MinLon=170, MaxLon=-170 is
+// the 20 degrees wide box around longitude 180.
+type BBox struct {
+	MinLat float64
+	MinLon float64
+	MaxLat float64
+	MaxLon float64
+}
+
+// Validate returns an error if the box coordinates are out of range
+func (this *BBox) Validate() error {
+	if this.MinLat < -90 || this.MaxLat > 90 || this.MinLat > this.MaxLat {
+		return fmt.Errorf("invalid latitude range %v..%v", this.MinLat, this.MaxLat)
+	}
+	if this.MinLon < -180 || this.MinLon > 180 || this.MaxLon < -180 || this.MaxLon > 180 {
+		return fmt.Errorf("invalid longitude range %v..%v", this.MinLon, this.MaxLon)
+	}
+	return nil
+}
+
+// Contains returns true if the point is inside the box, edges included
+func (this *BBox) Contains(lat, lon float64) bool {
+	if lat < this.MinLat || lat > this.MaxLat {
+		return false
+	}
+	if this.MinLon <= this.MaxLon {
+		return lon >= this.MinLon && lon <= this.MaxLon
+	}
+	// Crosses the antimeridian
+	return lon >= this.MinLon || lon <= this.MaxLon
+}
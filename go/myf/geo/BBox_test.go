@@ -0,0 +1,53 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package geo
+
+import "testing"
+
+func TestBBoxContains(t *testing.T) {
+	box := &BBox{MinLat: 30, MinLon: -125, MaxLat: 40, MaxLon: -115}
+	if !box.Contains(37.77, -122.42) {
+		t.Fatal("San Francisco must be inside the box")
+	}
+	if box.Contains(40.71, -74.0) {
+		t.Fatal("New York must be outside the box")
+	}
+	if !box.Contains(30, -125) {
+		t.Fatal("edges are inside the box")
+	}
+}
+
+func TestBBoxAcrossAntimeridian(t *testing.T) {
+	box := &BBox{MinLat: -20, MinLon: 170, MaxLat: -10, MaxLon: -170}
+	if err := box.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if !box.Contains(-17.7, 178.0) || !box.Contains(-15, -175) {
+		t.Fatal("points on both sides of the antimeridian must be inside the box")
+	}
+	if box.Contains(-15, 0) || box.Contains(-15, 160) {
+		t.Fatal("points outside the wrapped range must be outside the box")
+	}
+}
+
+func TestBBoxValidate(t *testing.T) {
+	if (&BBox{MinLat: 10, MinLon: 0, MaxLat: 5, MaxLon: 1}).Validate() == nil {
+		t.Fatal("min latitude above max latitude must be invalid")
+	}
+	if (&BBox{MinLat: 0, MinLon: 0, MaxLat: 1, MaxLon: 181}).Validate() == nil {
+		t.Fatal("longitude out of range must be invalid")
+	}
+}
@@ -0,0 +1,150 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package geo
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// The coordinate formats of the responses, the stored coordinates are decimal
+const (
+	CoordDecimal = "decimal"
+	CoordDMS     = "dms"
+	CoordGeohash = "geohash"
+)
+
+const (
+	DefaultGeohashPrecision = 9
+	MaxGeohashPrecision     = 12
+)
+
+const geohashAlphabet = "0123456789bcdefghjkmnpqrstuvwxyz"
+
+// CoordFormat formats the coordinates of a response, Precision is the
+// geohash length
+type CoordFormat struct {
+	Name      string
+	Precision int
+}
+
+// ParseCoordFormat validates a format name and a geohash precision, an
+// empty name is decimal and an empty precision the default one
+func ParseCoordFormat(name, precision string) (*CoordFormat, error) {
+	format := &CoordFormat{Name: strings.ToLower(strings.TrimSpace(name))}
+	if format.Name == "" {
+		format.Name = CoordDecimal
+	}
+	switch format.Name {
+	case CoordDecimal, CoordDMS:
+		if precision != "" {
+			return nil, fmt.Errorf("coordPrecision applies to %s only", CoordGeohash)
+		}
+	case CoordGeohash:
+		format.Precision = DefaultGeohashPrecision
+		if precision != "" {
+			p, err := strconv.Atoi(precision)
+			if err != nil || p < 1 || p > MaxGeohashPrecision {
+				return nil, fmt.Errorf("invalid coordPrecision %s, expected 1..%d", precision, MaxGeohashPrecision)
+			}
+			format.Precision = p
+		}
+	default:
+		return nil, fmt.Errorf("invalid coordFormat %s, expected %s, %s or %s", name, CoordDecimal, CoordDMS, CoordGeohash)
+	}
+	return format, nil
+}
+
+// Decimal returns true if the coordinates are left as they are stored
+func (this *CoordFormat) Decimal() bool {
+	return this == nil || this.Name == CoordDecimal
+}
+
+// Format returns the stored coordinates in the format, empty for decimal
+func (this *CoordFormat) Format(lat, lon float32) string {
+	switch {
+	case this.Decimal():
+		return ""
+	case this.Name == CoordDMS:
+		return DMS(widen(lat), widen(lon))
+	default:
+		return Geohash(widen(lat), widen(lon), this.Precision)
+	}
+}
+
+// widen returns the float64 of the decimal a float32 coordinate prints as,
+// so -33.8688 is not formatted as -33.86880111694336
+func widen(v float32) float64 {
+	wide, _ := strconv.ParseFloat(strconv.FormatFloat(float64(v), 'f', -1, 32), 64)
+	return wide
+}
+
+// DMS returns the coordinates in degrees, minutes and seconds to the
+// hundredth of a second, e.g. 33°52'07.68"S 151°12'33.48"E
+func DMS(lat, lon float64) string {
+	return dms(lat, "N", "S") + " " + dms(lon, "E", "W")
+}
+
+func dms(v float64, positive, negative string) string {
+	hemisphere := positive
+	if v < 0 {
+		hemisphere = negative
+		v = -v
+	}
+	// Rounded once in hundredths so 59.999 seconds carries to the minute
+	hundredths := int64(math.Round(v * 3600 * 100))
+	degrees := hundredths / (3600 * 100)
+	minutes := hundredths / (60 * 100) % 60
+	seconds := hundredths % (60 * 100)
+	return fmt.Sprintf("%d°%02d'%02d.%02d\"%s", degrees, minutes, seconds/100, seconds%100, hemisphere)
+}
+
+// Geohash returns the geohash of the coordinates with precision characters
+func Geohash(lat, lon float64, precision int) string {
+	minLat, maxLat, minLon, maxLon := -90.0, 90.0, -180.0, 180.0
+	hash := make([]byte, 0, precision)
+	even, bit, ch := true, 0, 0
+	for len(hash) < precision {
+		// The bits alternate between the longitude and the latitude
+		if even {
+			mid := (minLon + maxLon) / 2
+			if lon >= mid {
+				ch = ch<<1 | 1
+				minLon = mid
+			} else {
+				ch <<= 1
+				maxLon = mid
+			}
+		} else {
+			mid := (minLat + maxLat) / 2
+			if lat >= mid {
+				ch = ch<<1 | 1
+				minLat = mid
+			} else {
+				ch <<= 1
+				maxLat = mid
+			}
+		}
+		even = !even
+		if bit++; bit == 5 {
+			hash = append(hash, geohashAlphabet[ch])
+			bit, ch = 0, 0
+		}
+	}
+	return string(hash)
+}
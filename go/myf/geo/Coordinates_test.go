@@ -0,0 +1,81 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package geo
+
+import "testing"
+
+func TestGeohash(t *testing.T) {
+	for _, test := range []struct {
+		lat, lon  float64
+		precision int
+		want      string
+	}{
+		{57.64911, 10.40744, 11, "u4pruydqqvj"},
+		{-25.382708, -49.265506, 12, "6gkzwgjzn820"},
+		{-33.8688, 151.2093, 9, "r3gx2f77b"},
+		{89.99, -179.99, 7, "bpbpbnp"},
+		{0, 0, 1, "s"},
+	} {
+		if got := Geohash(test.lat, test.lon, test.precision); got != test.want {
+			t.Fatalf("geohash of %v,%v expected %s, got %s", test.lat, test.lon, test.want, got)
+		}
+	}
+}
+
+func TestDMS(t *testing.T) {
+	for _, test := range []struct {
+		lat, lon float64
+		want     string
+	}{
+		{40.446195, -79.948862, `40°26'46.30"N 79°56'55.90"W`},
+		{-33.8688, 151.2093, `33°52'07.68"S 151°12'33.48"E`},
+		{0, 0, `0°00'00.00"N 0°00'00.00"E`},
+		// The rounded seconds carry to the minute and degree
+		{89.999999, -179.9999999, `90°00'00.00"N 180°00'00.00"W`},
+		{-89.5, 0.0001, `89°30'00.00"S 0°00'00.36"E`},
+	} {
+		if got := DMS(test.lat, test.lon); got != test.want {
+			t.Fatalf("dms of %v,%v expected %s, got %s", test.lat, test.lon, test.want, got)
+		}
+	}
+}
+
+func TestParseCoordFormat(t *testing.T) {
+	for _, test := range []struct {
+		name, precision string
+		want            string
+		ok              bool
+	}{
+		{"", "", "", true},
+		{"decimal", "", "", true},
+		{"DMS", "", `10°30'00.00"N 20°15'00.00"W`, true},
+		{"geohash", "", "e9ceepqqu", true},
+		{"geohash", "4", "e9ce", true},
+		{"geohash", "0", "", false},
+		{"geohash", "13", "", false},
+		{"geohash", "x", "", false},
+		{"dms", "5", "", false},
+		{"utm", "", "", false},
+	} {
+		format, err := ParseCoordFormat(test.name, test.precision)
+		if (err == nil) != test.ok {
+			t.Fatalf("%s/%s expected valid %v, got %v", test.name, test.precision, test.ok, err)
+		}
+		if err == nil && format.Format(10.5, -20.25) != test.want {
+			t.Fatalf("%s/%s expected %s, got %s", test.name, test.precision, test.want, format.Format(10.5, -20.25))
+		}
+	}
+}
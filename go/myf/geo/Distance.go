@@ -0,0 +1,65 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package geo
+
+import "math"
+
+// EarthRadiusMeters is the mean earth radius
+const EarthRadiusMeters = 6371008.8
+
+func toRadians(deg float64) float64 {
+	return deg * math.Pi / 180
+}
+
+// Distance returns the great circle distance in meters between two points
+// using the haversine formula. The longitude difference only enters through
+// sin²(Δλ/2), which is periodic, so tracks crossing the ±180 meridian are
+// measured the short way around. The atan2 form stays accurate for nearly
+// antipodal points and near the poles where the acos form loses precision.
+func Distance(lat1, lon1, lat2, lon2 float64) float64 {
+	phi1 := toRadians(clampLat(lat1))
+	phi2 := toRadians(clampLat(lat2))
+	dPhi := phi2 - phi1
+	dLambda := toRadians(NormalizeLon(lon2 - lon1))
+
+	sinPhi := math.Sin(dPhi / 2)
+	sinLambda := math.Sin(dLambda / 2)
+	a := sinPhi*sinPhi + math.Cos(phi1)*math.Cos(phi2)*sinLambda*sinLambda
+	if a > 1 {
+		a = 1
+	}
+	return 2 * EarthRadiusMeters * math.Atan2(math.Sqrt(a), math.Sqrt(1-a))
+}
+
+// TrackLength returns the total distance in meters along a track of
+// [lat, lon] points
+func TrackLength(points [][2]float64) float64 {
+	total := 0.0
+	for i := 1; i < len(points); i++ {
+		total += Distance(points[i-1][0], points[i-1][1], points[i][0], points[i][1])
+	}
+	return total
+}
+
+func clampLat(lat float64) float64 {
+	if lat > 90 {
+		return 90
+	}
+	if lat < -90 {
+		return -90
+	}
+	return lat
+}
@@ -0,0 +1,73 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package geo
+
+import (
+	"math"
+	"testing"
+)
+
+func near(a, b, tolerance float64) bool {
+	return math.Abs(a-b) <= tolerance
+}
+
+func TestDistanceKnownCities(t *testing.T) {
+	// San Francisco to Los Angeles is ~559 km
+	d := Distance(37.7749, -122.4194, 34.0522, -118.2437)
+	if !near(d, 559000, 2000) {
+		t.Fatalf("unexpected SF-LA distance %v", d)
+	}
+	if Distance(10, 10, 10, 10) != 0 {
+		t.Fatal("distance to self must be 0")
+	}
+}
+
+func TestDistanceAcrossAntimeridian(t *testing.T) {
+	// 0.2 degrees of longitude at the equator is ~22.2 km, not ~40,000 km
+	d := Distance(0, 179.9, 0, -179.9)
+	if !near(d, 22239, 50) {
+		t.Fatalf("antimeridian distance should be short, got %v", d)
+	}
+	track := [][2]float64{{-16.5, 179.8}, {-16.5, 179.95}, {-16.5, -179.95}, {-16.5, -179.8}}
+	length := TrackLength(track)
+	straight := Distance(-16.5, 179.8, -16.5, -179.8)
+	if !near(length, straight, 10) {
+		t.Fatalf("track crossing 180 should be ~%v meters, got %v", straight, length)
+	}
+}
+
+func TestDistanceNearNorthPole(t *testing.T) {
+	// Two points 0.001 degrees from the pole on opposite meridians are
+	// ~222 meters apart through the pole
+	d := Distance(89.999, 0, 89.999, 180)
+	if !near(d, 222.4, 1) {
+		t.Fatalf("unexpected distance across the pole %v", d)
+	}
+	// Longitude is meaningless at the pole itself
+	if d := Distance(90, 10, 90, -170); d > 1e-6 {
+		t.Fatalf("distance between two pole points should be 0, got %v", d)
+	}
+	// A 12 point track circling the pole at 89.9N is a ~11.1 km radius
+	// 12-gon with a ~69 km perimeter
+	track := [][2]float64{}
+	for lon := -180.0; lon <= 180; lon += 30 {
+		track = append(track, [2]float64{89.9, lon})
+	}
+	expected := 12 * 2 * 11119.5 * math.Sin(math.Pi/12)
+	if length := TrackLength(track); !near(length, expected, 100) {
+		t.Fatalf("circling the pole at 89.9N should be ~%v meters, got %v", expected, length)
+	}
+}
@@ -0,0 +1,64 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package geo holds the geographic math shared by the family services.
+package geo
+
+import "math"
+
+// MetersPerDegreeLat is the length of one degree of latitude, it is close to
+// constant everywhere on earth.
+const MetersPerDegreeLat = 111320.0
+
+// RoundToGrid snaps a coordinate to a grid of roughly meters x meters cells.
+//
+// One degree of latitude is ~111,320 meters, so the latitude step is
+// meters / 111320 degrees. One degree of longitude is 111,320 * cos(latitude)
+// meters, so the longitude step grows toward the poles. The longitude step is
+// computed from the already rounded latitude, so every point in the same
+// latitude row shares the same grid and the result is deterministic.
+// At the poles longitude is meaningless and is reported as 0, and the
+// rounded longitude is normalized into [-180, 180) across the antimeridian.
+func RoundToGrid(lat, lon, meters float64) (float64, float64) {
+	if meters <= 0 {
+		return lat, lon
+	}
+	latStep := meters / MetersPerDegreeLat
+	rlat := math.Round(lat/latStep) * latStep
+	if rlat > 90 {
+		rlat = 90
+	} else if rlat < -90 {
+		rlat = -90
+	}
+	cos := math.Cos(rlat * math.Pi / 180)
+	if cos < 1e-9 {
+		return rlat, 0
+	}
+	lonStep := latStep / cos
+	if lonStep >= 360 {
+		return rlat, 0
+	}
+	rlon := NormalizeLon(math.Round(lon/lonStep) * lonStep)
+	return rlat, rlon
+}
+
+// NormalizeLon wraps a longitude into [-180, 180)
+func NormalizeLon(lon float64) float64 {
+	lon = math.Mod(lon+180, 360)
+	if lon < 0 {
+		lon += 360
+	}
+	return lon - 180
+}
@@ -0,0 +1,73 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package geo
+
+import (
+	"math"
+	"testing"
+)
+
+func TestRoundToGridIsDeterministic(t *testing.T) {
+	lat1, lon1 := RoundToGrid(37.77491, -122.41941, 500)
+	lat2, lon2 := RoundToGrid(37.77493, -122.41938, 500)
+	if lat1 != lat2 || lon1 != lon2 {
+		t.Fatalf("nearby points should land in the same cell: %v,%v vs %v,%v", lat1, lon1, lat2, lon2)
+	}
+	if math.Abs(lat1-37.77491)*MetersPerDegreeLat > 250 {
+		t.Fatalf("rounded latitude moved more than half a cell: %v", lat1)
+	}
+}
+
+func TestRoundToGridDisabled(t *testing.T) {
+	lat, lon := RoundToGrid(1.23456, 2.34567, 0)
+	if lat != 1.23456 || lon != 2.34567 {
+		t.Fatal("zero precision must not change the coordinate")
+	}
+}
+
+func TestRoundToGridNearPoles(t *testing.T) {
+	lat, lon := RoundToGrid(89.9999, 45, 1000)
+	if lat > 90 || lat < 89.9 {
+		t.Fatalf("unexpected latitude near the pole %v", lat)
+	}
+	if lon != 0 && (lon < -180 || lon >= 180) {
+		t.Fatalf("longitude out of range near the pole %v", lon)
+	}
+	lat, lon = RoundToGrid(-90, 123, 1000)
+	if lat != -90 || lon != 0 {
+		t.Fatalf("south pole should round to -90,0 got %v,%v", lat, lon)
+	}
+	// The longitude cell near the pole is much wider than at the equator
+	_, lon1 := RoundToGrid(85, 10.0, 1000)
+	_, lon2 := RoundToGrid(85, 10.03, 1000)
+	if lon1 != lon2 {
+		t.Fatalf("points 0.03 degrees apart at 85N should share a 1km cell: %v %v", lon1, lon2)
+	}
+}
+
+func TestRoundToGridAntimeridian(t *testing.T) {
+	step := 1000 / MetersPerDegreeLat
+	for _, lon := range []float64{179.99999, -179.99999, 180, -180} {
+		_, rlon := RoundToGrid(0, lon, 1000)
+		if rlon < -180 || rlon >= 180 {
+			t.Fatalf("rounded longitude %v out of range for %v", rlon, lon)
+		}
+		diff := math.Abs(NormalizeLon(rlon - lon))
+		if diff > step/2+1e-9 {
+			t.Fatalf("rounded longitude %v is %v degrees away from %v", rlon, diff, lon)
+		}
+	}
+}
@@ -0,0 +1,238 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package history
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/saichler/l8myfamiliy/go/myf/config"
+	"github.com/saichler/l8myfamiliy/go/types/l8myfamily"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// eventExt is the extension of the geofence event files, they are kept next
+// to the device history file
+const eventExt = ".geofence"
+
+var (
+	fencesMtx = &sync.Mutex{}
+	// fences caches the geofence each device is in, "" when outside all
+	fences = make(map[string]string)
+)
+
+func eventFilename(familyId, deviceId string) (string, error) {
+	if !validKey(familyId) || !validKey(deviceId) {
+		return "", fmt.Errorf("invalid history key %s/%s", familyId, deviceId)
+	}
+	return filepath.Join(rootDir(), familyId, deviceId+eventExt), nil
+}
+
+// RecordGeofence compares the trusted geofence the agent reported with the
+// one the device was in and appends the exit and entry events of a change.
+// It returns the recorded events.
+func RecordGeofence(familyId string, location *l8myfamily.Location) ([]*l8myfamily.GeofenceEvent, error) {
+	fn, err := eventFilename(familyId, location.DeviceId)
+	if err != nil {
+		return nil, err
+	}
+	l := lock(fn)
+	defer l.Unlock()
+	current, err := currentFence(fn)
+	if err != nil {
+		return nil, err
+	}
+	if current == location.Geofence {
+		return nil, nil
+	}
+	events := make([]*l8myfamily.GeofenceEvent, 0, 2)
+	if current != "" {
+		events = append(events, newEvent(location, current, false))
+	}
+	if location.Geofence != "" {
+		events = append(events, newEvent(location, location.Geofence, true))
+	}
+	if err := appendEvents(fn, events); err != nil {
+		return nil, err
+	}
+	fencesMtx.Lock()
+	fences[fn] = location.Geofence
+	fencesMtx.Unlock()
+	return events, nil
+}
+
+// AppendGeofenceEvents appends the server evaluated geofence crossings of a
+// device, they do not change the trusted geofence RecordGeofence tracks
+func AppendGeofenceEvents(familyId, deviceId string, events []*l8myfamily.GeofenceEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+	fn, err := eventFilename(familyId, deviceId)
+	if err != nil {
+		return err
+	}
+	l := lock(fn)
+	defer l.Unlock()
+	return appendEvents(fn, events)
+}
+
+func newEvent(location *l8myfamily.Location, geofence string, entered bool) *l8myfamily.GeofenceEvent {
+	return &l8myfamily.GeofenceEvent{DeviceId: location.DeviceId, Geofence: geofence, Entered: entered,
+		Timestamp: location.Timestamp, Latitude: location.Latitude, Longitude: location.Longitude}
+}
+
+// currentFence returns the geofence of the last recorded event, it must be
+// called under the file lock
+func currentFence(fn string) (string, error) {
+	fencesMtx.Lock()
+	current, ok := fences[fn]
+	fencesMtx.Unlock()
+	if ok {
+		return current, nil
+	}
+	events, err := readEvents(fn)
+	if err != nil {
+		return "", err
+	}
+	// The server evaluated crossings are not the trusted geofence
+	for i := len(events) - 1; i >= 0; i-- {
+		if events[i].ServerEvaluated {
+			continue
+		}
+		if events[i].Entered {
+			current = events[i].Geofence
+		}
+		break
+	}
+	fencesMtx.Lock()
+	fences[fn] = current
+	fencesMtx.Unlock()
+	return current, nil
+}
+
+// QueryGeofence returns the device geofence events with from <= timestamp <= to
+// ordered by time. A zero to means no upper bound.
+func QueryGeofence(familyId, deviceId string, from, to int64) ([]*l8myfamily.GeofenceEvent, error) {
+	fn, err := eventFilename(familyId, deviceId)
+	if err != nil {
+		return nil, err
+	}
+	l := lock(fn)
+	events, err := readEvents(fn)
+	l.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*l8myfamily.GeofenceEvent, 0, len(events))
+	for _, e := range events {
+		if e.Timestamp >= from && (to == 0 || e.Timestamp <= to) {
+			result = append(result, e)
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].Timestamp < result[j].Timestamp
+	})
+	return result, nil
+}
+
+// pruneEvents drops the events older than the retention max age, the last
+// trusted geofence event is always kept as it holds the geofence the device
+// is in
+func pruneEvents(fn string, retention *config.Retention, now int64) error {
+	if retention.MaxAgeSeconds <= 0 {
+		return nil
+	}
+	l := lock(fn)
+	defer l.Unlock()
+	events, err := readEvents(fn)
+	if err != nil || len(events) == 0 {
+		return err
+	}
+	minTime := now - retention.MaxAgeSeconds*1000
+	last := len(events) - 1
+	for last > 0 && events[last].ServerEvaluated {
+		last--
+	}
+	kept := make([]*l8myfamily.GeofenceEvent, 0, len(events))
+	for i, e := range events {
+		if e.Timestamp >= minTime || i == last {
+			kept = append(kept, e)
+		}
+	}
+	if len(kept) == len(events) {
+		return nil
+	}
+	tmp := fn + ".tmp"
+	os.Remove(tmp)
+	if err := appendEvents(tmp, kept); err != nil {
+		return err
+	}
+	return os.Rename(tmp, fn)
+}
+
+// appendEvents must be called under the file lock
+func appendEvents(fn string, events []*l8myfamily.GeofenceEvent) error {
+	buff := &bytes.Buffer{}
+	for _, e := range events {
+		data, err := protojson.Marshal(e)
+		if err != nil {
+			return err
+		}
+		buff.Write(data)
+		buff.WriteByte('\n')
+	}
+	if err := os.MkdirAll(filepath.Dir(fn), 0777); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(fn, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	_, err = file.Write(buff.Bytes())
+	return err
+}
+
+// readEvents must be called under the file lock
+func readEvents(fn string) ([]*l8myfamily.GeofenceEvent, error) {
+	data, err := os.ReadFile(fn)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	events := make([]*l8myfamily.GeofenceEvent, 0)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		e := &l8myfamily.GeofenceEvent{}
+		if err := protojson.Unmarshal(line, e); err != nil {
+			fmt.Println("[History] skipping corrupt line in ", fn, ": ", err.Error())
+			continue
+		}
+		events = append(events, e)
+	}
+	return events, scanner.Err()
+}
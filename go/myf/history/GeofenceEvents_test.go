@@ -0,0 +1,96 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package history
+
+import (
+	"testing"
+	"time"
+
+	"github.com/saichler/l8myfamiliy/go/myf/config"
+	"github.com/saichler/l8myfamiliy/go/types/l8myfamily"
+)
+
+func TestRecordGeofenceTransitions(t *testing.T) {
+	setupHistory(t, config.Default())
+	post := func(fence string, ts int64) int {
+		events, err := RecordGeofence("fam", &l8myfamily.Location{DeviceId: "d1", Geofence: fence, Timestamp: ts})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return len(events)
+	}
+	// outside, home, home, school (exit + enter), outside
+	for i, step := range []struct {
+		fence  string
+		events int
+	}{{"", 0}, {"Home", 1}, {"Home", 0}, {"School", 2}, {"", 1}} {
+		if n := post(step.fence, int64(i+1)*1000); n != step.events {
+			t.Fatalf("step %d: expected %d events, got %d", i, step.events, n)
+		}
+	}
+	events, err := QueryGeofence("fam", "d1", 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 4 || !events[0].Entered || events[0].Geofence != "Home" ||
+		events[1].Entered || events[1].Geofence != "Home" || events[3].Entered || events[3].Geofence != "School" {
+		t.Fatalf("unexpected events %v", events)
+	}
+
+	// After a restart the geofence the device is in is read from the file
+	post("Park", 6000)
+	fencesMtx.Lock()
+	fences = make(map[string]string)
+	fencesMtx.Unlock()
+	if n := post("Park", 7000); n != 0 {
+		t.Fatalf("staying in a geofence across a restart must not record events, got %d", n)
+	}
+	if events, _ := QueryGeofence("fam", "d1", 5000, 6000); len(events) != 2 {
+		t.Fatalf("expected the events of the window, got %v", events)
+	}
+}
+
+func TestPruneGeofenceKeepsLastEvent(t *testing.T) {
+	setupHistory(t, &config.ServerConfig{HistoryRetention: &config.Retention{MaxAgeSeconds: 60}})
+	now := time.Now().UnixMilli()
+	RecordGeofence("fam", &l8myfamily.Location{DeviceId: "d1", Geofence: "Home", Timestamp: now - 300000})
+	RecordGeofence("fam", &l8myfamily.Location{DeviceId: "d1", Geofence: "School", Timestamp: now - 200000})
+	Prune()
+	events, _ := QueryGeofence("fam", "d1", 0, 0)
+	if len(events) != 1 || events[0].Geofence != "School" || !events[0].Entered {
+		t.Fatalf("expected only the last entry to be kept, got %v", events)
+	}
+}
+
+func TestServerEventsKeepTrustedGeofence(t *testing.T) {
+	setupHistory(t, config.Default())
+	RecordGeofence("fam", &l8myfamily.Location{DeviceId: "d1", Geofence: "Home", Timestamp: 1000})
+	err := AppendGeofenceEvents("fam", "d1", []*l8myfamily.GeofenceEvent{
+		{DeviceId: "d1", Geofence: "Park", Entered: true, Timestamp: 2000, ServerEvaluated: true}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// After a restart the trusted geofence is still read from the file
+	fencesMtx.Lock()
+	fences = make(map[string]string)
+	fencesMtx.Unlock()
+	if events, _ := RecordGeofence("fam", &l8myfamily.Location{DeviceId: "d1", Geofence: "Home", Timestamp: 3000}); len(events) != 0 {
+		t.Fatalf("a server event must not change the trusted geofence, got %v", events)
+	}
+	if events, _ := QueryGeofence("fam", "d1", 0, 0); len(events) != 2 || !events[1].ServerEvaluated {
+		t.Fatalf("expected the trusted and the server event, got %v", events)
+	}
+}
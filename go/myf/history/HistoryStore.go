@@ -0,0 +1,251 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package history keeps the location history of every device.
+// Each device has its own file under <DataDir>/history/<familyId>/ holding one
+// JSON encoded location per line in arrival order. With a rollover the
+// device has a file per period in <deviceId>.segments/ instead, the files of
+// the past periods are compressed.
+package history
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/saichler/l8myfamiliy/go/myf/config"
+	"github.com/saichler/l8myfamiliy/go/types/l8myfamily"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+const (
+	dirName = "history"
+	fileExt = ".history"
+)
+
+var (
+	locksMtx = &sync.Mutex{}
+	locks    = make(map[string]*sync.Mutex)
+)
+
+// lock serializes all access to a single device history file
+func lock(key string) *sync.Mutex {
+	locksMtx.Lock()
+	defer locksMtx.Unlock()
+	l, ok := locks[key]
+	if !ok {
+		l = &sync.Mutex{}
+		locks[key] = l
+	}
+	l.Lock()
+	return l
+}
+
+func validKey(k string) bool {
+	return k != "" && k != "." && k != ".." && !strings.ContainsAny(k, "/\\")
+}
+
+func rootDir() string {
+	return filepath.Join(config.Get().DataDir, dirName)
+}
+
+func filename(familyId, deviceId string) (string, error) {
+	if !validKey(familyId) || !validKey(deviceId) {
+		return "", fmt.Errorf("invalid history key %s/%s", familyId, deviceId)
+	}
+	return filepath.Join(rootDir(), familyId, deviceId+fileExt), nil
+}
+
+// Append adds a location to the device history
+func Append(familyId string, location *l8myfamily.Location) error {
+	fn, err := filename(familyId, location.DeviceId)
+	if err != nil {
+		return err
+	}
+	data, err := protojson.Marshal(location)
+	if err != nil {
+		return err
+	}
+	l := lock(fn)
+	defer l.Unlock()
+	storage := config.HistoryFormat()
+	path := fn
+	rolled := false
+	if storage.Rollover != "" {
+		path = filepath.Join(segmentDir(fn), segmentName(storage.Rollover, clock())+fileExt)
+		_, err := os.Stat(path)
+		rolled = os.IsNotExist(err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0777); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	if _, err = file.Write(append(data, '\n')); err != nil {
+		return err
+	}
+	if rolled && storage.Compression == config.CompressionGzip {
+		compressLater(fn)
+	}
+	return nil
+}
+
+// Query returns the device locations with from <= timestamp <= to ordered by time.
+// A zero to means no upper bound.
+func Query(familyId, deviceId string, from, to int64) ([]*l8myfamily.Location, error) {
+	result := make([]*l8myfamily.Location, 0)
+	err := Scan(familyId, deviceId, from, to, func(p *l8myfamily.Location) error {
+		result = append(result, p)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].Timestamp < result[j].Timestamp
+	})
+	return result, nil
+}
+
+// Scan streams the device locations with from <= timestamp <= to to each in
+// arrival order, without loading the history into memory. A zero to means no
+// upper bound. The scan reads the history as it was when it started, the
+// file lock is not held while each runs so a slow reader does not block appends.
+// An error of each stops the scan and is returned.
+func Scan(familyId, deviceId string, from, to int64, each func(*l8myfamily.Location) error) error {
+	snapshot, err := OpenSnapshot(familyId, deviceId)
+	if err != nil {
+		return err
+	}
+	defer snapshot.Close()
+	return snapshot.Scan(from, to, each)
+}
+
+// Snapshot is the device history as it was when it was opened, appends after
+// the open are not seen
+type Snapshot struct {
+	parts []*snapshotPart
+}
+
+type snapshotPart struct {
+	seg  *segment
+	file *os.File
+	size int64
+}
+
+// OpenSnapshot opens the device history as it is now, a device without a
+// history has an empty snapshot. The snapshot must be closed.
+func OpenSnapshot(familyId, deviceId string) (*Snapshot, error) {
+	fn, err := filename(familyId, deviceId)
+	if err != nil {
+		return nil, err
+	}
+	l := lock(fn)
+	defer l.Unlock()
+	segs, err := segments(fn)
+	if err != nil {
+		return nil, err
+	}
+	// A prune or a compression replaces or removes the files, the open files
+	// keep their content
+	snapshot := &Snapshot{parts: make([]*snapshotPart, 0, len(segs))}
+	for _, seg := range segs {
+		file, size, err := open(seg.path)
+		if err != nil {
+			snapshot.Close()
+			return nil, err
+		}
+		snapshot.parts = append(snapshot.parts, &snapshotPart{seg: seg, file: file, size: size})
+	}
+	return snapshot, nil
+}
+
+// Scan streams the snapshot locations with from <= timestamp <= to to each,
+// see Scan
+func (this *Snapshot) Scan(from, to int64, each func(*l8myfamily.Location) error) error {
+	for _, part := range this.parts {
+		if _, err := part.file.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+		reader, err := part.seg.reader(part.file, part.size)
+		if err != nil {
+			return err
+		}
+		err = scan(part.seg.path, reader, func(p *l8myfamily.Location) error {
+			if p.Timestamp >= from && (to == 0 || p.Timestamp <= to) {
+				return each(p)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close closes the snapshot files
+func (this *Snapshot) Close() error {
+	var result error
+	for _, part := range this.parts {
+		if err := part.file.Close(); err != nil && result == nil {
+			result = err
+		}
+	}
+	return result
+}
+
+// open returns the file and its current size, must be called under the file lock
+func open(fn string) (*os.File, int64, error) {
+	file, err := os.Open(fn)
+	if err != nil {
+		return nil, 0, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, 0, err
+	}
+	return file, info.Size(), nil
+}
+
+// scan decodes the history lines of reader, a corrupt line is skipped
+func scan(fn string, reader io.Reader, each func(*l8myfamily.Location) error) error {
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 0, 4096), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		p := &l8myfamily.Location{}
+		if err := protojson.Unmarshal(line, p); err != nil {
+			fmt.Println("[History] skipping corrupt line in ", fn, ": ", err.Error())
+			continue
+		}
+		if err := each(p); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
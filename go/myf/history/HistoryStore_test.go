@@ -0,0 +1,146 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package history
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/saichler/l8myfamiliy/go/myf/config"
+	"github.com/saichler/l8myfamiliy/go/types/l8myfamily"
+)
+
+func setupHistory(t *testing.T, cfg *config.ServerConfig) {
+	cfg.DataDir = t.TempDir()
+	config.Set(cfg)
+	t.Cleanup(func() { config.Set(config.Default()) })
+}
+
+func TestAppendAndQuery(t *testing.T) {
+	setupHistory(t, config.Default())
+	for i := int64(1); i <= 5; i++ {
+		if err := Append("fam", &l8myfamily.Location{DeviceId: "d1", Latitude: float32(i), Timestamp: i * 1000}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	points, err := Query("fam", "d1", 2000, 4000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(points) != 3 || points[0].Timestamp != 2000 || points[2].Timestamp != 4000 {
+		t.Fatalf("unexpected points %v", points)
+	}
+	if _, err := Query("..", "d1", 0, 0); err == nil {
+		t.Fatal("expected invalid key error")
+	}
+}
+
+func TestScanStopsOnError(t *testing.T) {
+	setupHistory(t, config.Default())
+	for i := int64(1); i <= 5; i++ {
+		Append("fam", &l8myfamily.Location{DeviceId: "d1", Timestamp: i * 1000})
+	}
+	stop := errors.New("stop")
+	seen := make([]int64, 0)
+	err := Scan("fam", "d1", 2000, 0, func(l *l8myfamily.Location) error {
+		seen = append(seen, l.Timestamp)
+		if len(seen) == 2 {
+			return stop
+		}
+		return nil
+	})
+	if err != stop || len(seen) != 2 || seen[0] != 2000 || seen[1] != 3000 {
+		t.Fatalf("expected the scan to stop after 2 points, got %v %v", err, seen)
+	}
+	if err := Scan("fam", "none", 0, 0, func(*l8myfamily.Location) error { return stop }); err != nil {
+		t.Fatalf("a device without history must scan nothing, got %v", err)
+	}
+}
+
+func TestSnapshotIgnoresLaterAppends(t *testing.T) {
+	setupHistory(t, config.Default())
+	for i := int64(1); i <= 3; i++ {
+		Append("fam", &l8myfamily.Location{DeviceId: "d1", Timestamp: i * 1000})
+	}
+	snapshot, err := OpenSnapshot("fam", "d1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer snapshot.Close()
+	Append("fam", &l8myfamily.Location{DeviceId: "d1", Timestamp: 4000})
+	count := 0
+	snapshot.Scan(0, 0, func(*l8myfamily.Location) error {
+		count++
+		return nil
+	})
+	if count != 3 {
+		t.Fatalf("expected the 3 points of the snapshot, got %d", count)
+	}
+	empty, err := OpenSnapshot("fam", "none")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := empty.Scan(0, 0, func(*l8myfamily.Location) error { return errors.New("point") }); err != nil || empty.Close() != nil {
+		t.Fatalf("a device without history must have an empty snapshot, got %v", err)
+	}
+}
+
+func TestPruneByAgeAndCount(t *testing.T) {
+	setupHistory(t, &config.ServerConfig{
+		HistoryRetention: &config.Retention{MaxAgeSeconds: 60},
+		Families: map[string]*config.FamilyConfig{
+			"small": {HistoryRetention: &config.Retention{MaxPoints: 2}},
+		},
+	})
+	now := time.Now().UnixMilli()
+	for _, ts := range []int64{now - 120000, now - 90000, now - 1000, now} {
+		Append("fam", &l8myfamily.Location{DeviceId: "d1", Timestamp: ts})
+		Append("small", &l8myfamily.Location{DeviceId: "d2", Timestamp: ts})
+	}
+	if removed := Prune(); removed != 4 {
+		t.Fatalf("expected 4 removed points, got %d", removed)
+	}
+	points, _ := Query("fam", "d1", 0, 0)
+	if len(points) != 2 || points[0].Timestamp != now-1000 {
+		t.Fatalf("age retention failed %v", points)
+	}
+	points, _ = Query("small", "d2", 0, 0)
+	if len(points) != 2 || points[1].Timestamp != now {
+		t.Fatalf("count retention failed %v", points)
+	}
+}
+
+func TestPruneWithConcurrentAppends(t *testing.T) {
+	setupHistory(t, &config.ServerConfig{HistoryRetention: &config.Retention{MaxPoints: 1000}})
+	wg := &sync.WaitGroup{}
+	for i := 0; i < 200; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			Append("fam", &l8myfamily.Location{DeviceId: "d1", Timestamp: time.Now().UnixMilli()})
+			if i%20 == 0 {
+				Prune()
+			}
+		}(i)
+	}
+	wg.Wait()
+	points, _ := Query("fam", "d1", 0, 0)
+	if len(points) != 200 {
+		t.Fatalf("expected all 200 points to survive concurrent prunes, got %d", len(points))
+	}
+}
@@ -0,0 +1,181 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package history
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/saichler/l8myfamiliy/go/myf/config"
+	"github.com/saichler/l8myfamiliy/go/types/l8myfamily"
+)
+
+var pruneOnce = &sync.Once{}
+
+// StartPruning starts the periodic background prune of all device histories
+func StartPruning() {
+	pruneOnce.Do(func() {
+		go func() {
+			for {
+				interval := config.Get().HistoryPruneIntervalSeconds
+				if interval <= 0 {
+					interval = 3600
+				}
+				time.Sleep(time.Duration(interval) * time.Second)
+				Prune()
+			}
+		}()
+	})
+}
+
+// Prune enforces the retention policy of each family on its device histories
+// and returns the number of points removed.
+func Prune() int {
+	families, err := os.ReadDir(rootDir())
+	if err != nil {
+		return 0
+	}
+	removed := 0
+	now := clock().UnixMilli()
+	for _, family := range families {
+		if !family.IsDir() {
+			continue
+		}
+		retention := config.HistoryRetentionFor(family.Name())
+		devices, err := os.ReadDir(filepath.Join(rootDir(), family.Name()))
+		if err != nil {
+			continue
+		}
+		// A device may have both a single history file and rolled segments
+		histories := make(map[string]bool)
+		for _, device := range devices {
+			fn := filepath.Join(rootDir(), family.Name(), device.Name())
+			switch {
+			case strings.HasSuffix(device.Name(), eventExt):
+				if err := pruneEvents(fn, retention, now); err != nil {
+					fmt.Println("[History] failed to prune ", fn, ": ", err.Error())
+				}
+			case device.IsDir() && strings.HasSuffix(device.Name(), segmentDirExt):
+				histories[strings.TrimSuffix(fn, segmentDirExt)+fileExt] = true
+			case strings.HasSuffix(device.Name(), fileExt):
+				histories[fn] = true
+			}
+		}
+		for fn := range histories {
+			n, err := pruneFile(fn, retention, now)
+			if err != nil {
+				fmt.Println("[History] failed to prune ", fn, ": ", err.Error())
+				continue
+			}
+			removed += n
+		}
+	}
+	if removed > 0 {
+		fmt.Println("[History] pruned ", removed, " location points")
+	}
+	return removed
+}
+
+// pruneFile enforces the retention on the history files of a device and
+// compresses its closed segments. A rolled segment whose period ended before
+// the max age is removed whole, the points of the one the max age falls in
+// are filtered. The points beyond the max point count are removed from the
+// oldest files.
+func pruneFile(fn string, retention *config.Retention, now int64) (int, error) {
+	l := lock(fn)
+	defer l.Unlock()
+	if err := compressClosed(fn); err != nil {
+		return 0, err
+	}
+	segs, err := segments(fn)
+	if err != nil {
+		return 0, err
+	}
+	removed := 0
+	if retention.MaxAgeSeconds > 0 {
+		minTime := now - retention.MaxAgeSeconds*1000
+		kept := make([]*segment, 0, len(segs))
+		for _, seg := range segs {
+			n := 0
+			switch {
+			case seg.end > 0 && seg.end <= minTime:
+				n, err = removeSegment(seg)
+			case seg.end == 0 || seg.start < minTime:
+				n, err = filterSegment(seg, func(points []*l8myfamily.Location) []*l8myfamily.Location {
+					return applyRetention(points, &config.Retention{MaxAgeSeconds: retention.MaxAgeSeconds}, now)
+				})
+				kept = append(kept, seg)
+			default:
+				kept = append(kept, seg)
+			}
+			if err != nil {
+				return removed, err
+			}
+			removed += n
+		}
+		segs = kept
+	}
+	if retention.MaxPoints > 0 {
+		remaining := retention.MaxPoints
+		for i := len(segs) - 1; i >= 0; i-- {
+			if remaining == 0 {
+				n, err := removeSegment(segs[i])
+				if err != nil {
+					return removed, err
+				}
+				removed += n
+				continue
+			}
+			keep := remaining
+			n, err := filterSegment(segs[i], func(points []*l8myfamily.Location) []*l8myfamily.Location {
+				if len(points) <= keep {
+					remaining -= len(points)
+					return points
+				}
+				remaining = 0
+				return points[len(points)-keep:]
+			})
+			if err != nil {
+				return removed, err
+			}
+			removed += n
+		}
+	}
+	return removed, nil
+}
+
+// applyRetention drops the points older than the max age and then the oldest
+// points beyond the max point count
+func applyRetention(points []*l8myfamily.Location, retention *config.Retention, now int64) []*l8myfamily.Location {
+	kept := points
+	if retention.MaxAgeSeconds > 0 {
+		minTime := now - retention.MaxAgeSeconds*1000
+		kept = make([]*l8myfamily.Location, 0, len(points))
+		for _, p := range points {
+			if p.Timestamp >= minTime {
+				kept = append(kept, p)
+			}
+		}
+	}
+	if retention.MaxPoints > 0 && len(kept) > retention.MaxPoints {
+		kept = kept[len(kept)-retention.MaxPoints:]
+	}
+	return kept
+}
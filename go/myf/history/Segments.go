@@ -0,0 +1,292 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package history
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/saichler/l8myfamiliy/go/myf/config"
+	"github.com/saichler/l8myfamiliy/go/types/l8myfamily"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+const (
+	// segmentDirExt is the extension of the directory holding the rolled
+	// history files of a device, next to its single history file
+	segmentDirExt = ".segments"
+	gzipExt       = ".gz"
+	dailyLayout   = "2006-01-02"
+	hourlyLayout  = "2006-01-02T15"
+)
+
+var (
+	// clock is the time of the rollover
+	clock = time.Now
+	// compressing tracks the background compressions started by a rollover
+	compressing = &sync.WaitGroup{}
+)
+
+// segment is a history file of a device. A rolled segment holds the points
+// that arrived between start and end, the single file has no bounds.
+type segment struct {
+	path       string
+	name       string
+	start      int64
+	end        int64
+	compressed bool
+}
+
+func segmentDir(fn string) string {
+	return strings.TrimSuffix(fn, fileExt) + segmentDirExt
+}
+
+// segmentName returns the name of the rollover period of t
+func segmentName(rollover string, t time.Time) string {
+	if rollover == config.RolloverHourly {
+		return t.UTC().Format(hourlyLayout)
+	}
+	return t.UTC().Format(dailyLayout)
+}
+
+// parseSegment returns the segment of a file of the segment directory,
+// false for a file that is not one
+func parseSegment(dir, file string) (*segment, bool) {
+	name := strings.TrimSuffix(file, gzipExt)
+	if !strings.HasSuffix(name, fileExt) {
+		return nil, false
+	}
+	name = strings.TrimSuffix(name, fileExt)
+	layout, period := dailyLayout, 24*time.Hour
+	if len(name) == len(hourlyLayout) {
+		layout, period = hourlyLayout, time.Hour
+	}
+	start, err := time.Parse(layout, name)
+	if err != nil {
+		return nil, false
+	}
+	return &segment{path: filepath.Join(dir, file), name: name, start: start.UnixMilli(),
+		end: start.Add(period).UnixMilli(), compressed: strings.HasSuffix(file, gzipExt)}, true
+}
+
+// segments returns the history files of a device oldest first, the single
+// file and then the rolled ones. Must be called under the file lock.
+func segments(fn string) ([]*segment, error) {
+	result := make([]*segment, 0)
+	if _, err := os.Stat(fn); err == nil {
+		result = append(result, &segment{path: fn})
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+	dir := segmentDir(fn)
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return result, nil
+		}
+		return nil, err
+	}
+	byName := make(map[string]*segment)
+	for _, file := range files {
+		seg, ok := parseSegment(dir, file.Name())
+		if !ok {
+			continue
+		}
+		// A compression interrupted before the plain file was removed leaves
+		// both, the compressed one is complete
+		if other, ok := byName[seg.name]; ok && other.compressed {
+			continue
+		}
+		byName[seg.name] = seg
+	}
+	rolled := make([]*segment, 0, len(byName))
+	for _, seg := range byName {
+		rolled = append(rolled, seg)
+	}
+	sort.Slice(rolled, func(i, j int) bool {
+		return rolled[i].start < rolled[j].start
+	})
+	return append(result, rolled...), nil
+}
+
+// reader decodes the first size bytes of the segment file
+func (this *segment) reader(file io.Reader, size int64) (io.Reader, error) {
+	reader := io.LimitReader(file, size)
+	if this.compressed {
+		return gzip.NewReader(reader)
+	}
+	return reader, nil
+}
+
+// readSegment must be called under the file lock
+func readSegment(seg *segment) ([]*l8myfamily.Location, error) {
+	file, size, err := open(seg.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer file.Close()
+	reader, err := seg.reader(file, size)
+	if err != nil {
+		return nil, err
+	}
+	points := make([]*l8myfamily.Location, 0)
+	err = scan(seg.path, reader, func(p *l8myfamily.Location) error {
+		points = append(points, p)
+		return nil
+	})
+	return points, err
+}
+
+// writeSegment atomically replaces the segment content, an empty rolled
+// segment is removed. Must be called under the file lock.
+func writeSegment(seg *segment, points []*l8myfamily.Location) error {
+	if len(points) == 0 && seg.end > 0 {
+		return removeFile(seg.path)
+	}
+	buff := &bytes.Buffer{}
+	var out io.Writer = buff
+	var zw *gzip.Writer
+	if seg.compressed {
+		zw = gzip.NewWriter(buff)
+		out = zw
+	}
+	for _, p := range points {
+		data, err := protojson.Marshal(p)
+		if err != nil {
+			return err
+		}
+		out.Write(append(data, '\n'))
+	}
+	if zw != nil {
+		if err := zw.Close(); err != nil {
+			return err
+		}
+	}
+	tmp := seg.path + ".tmp"
+	if err := os.WriteFile(tmp, buff.Bytes(), 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, seg.path)
+}
+
+// filterSegment replaces the segment points by the ones keep returns and
+// returns the number of points removed. Must be called under the file lock.
+func filterSegment(seg *segment, keep func([]*l8myfamily.Location) []*l8myfamily.Location) (int, error) {
+	points, err := readSegment(seg)
+	if err != nil {
+		return 0, err
+	}
+	kept := keep(points)
+	if len(kept) == len(points) {
+		return 0, nil
+	}
+	return len(points) - len(kept), writeSegment(seg, kept)
+}
+
+// removeSegment deletes the segment file and returns the number of points it had
+func removeSegment(seg *segment) (int, error) {
+	points, err := readSegment(seg)
+	if err != nil {
+		return 0, err
+	}
+	return len(points), removeFile(seg.path)
+}
+
+func removeFile(path string) error {
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// compressLater compresses the closed segments of the device in the
+// background, so the append that rolled over does not wait for it
+func compressLater(fn string) {
+	compressing.Add(1)
+	go func() {
+		defer compressing.Done()
+		l := lock(fn)
+		defer l.Unlock()
+		if err := compressClosed(fn); err != nil {
+			fmt.Println("[History] failed to compress ", fn, ": ", err.Error())
+		}
+	}()
+}
+
+// compressClosed compresses the plain rolled segments of the past periods
+// with the configured codec. Must be called under the file lock.
+func compressClosed(fn string) error {
+	storage := config.HistoryFormat()
+	if storage.Compression != config.CompressionGzip {
+		return nil
+	}
+	segs, err := segments(fn)
+	if err != nil {
+		return err
+	}
+	current := segmentName(storage.Rollover, clock())
+	for _, seg := range segs {
+		if seg.end == 0 || seg.compressed || seg.name == current {
+			continue
+		}
+		if err := compressSegment(seg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// compressSegment writes the gzip of a plain segment next to it and then
+// removes the plain file
+func compressSegment(seg *segment) error {
+	in, err := os.Open(seg.path)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	tmp := seg.path + gzipExt + ".tmp"
+	out, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	zw := gzip.NewWriter(out)
+	_, err = io.Copy(zw, in)
+	if err == nil {
+		err = zw.Close()
+	}
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp, seg.path+gzipExt)
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Remove(seg.path)
+}
@@ -0,0 +1,146 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package history
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/saichler/l8myfamiliy/go/myf/config"
+	"github.com/saichler/l8myfamiliy/go/types/l8myfamily"
+)
+
+// setClock sets the rollover and prune time
+func setClock(t *testing.T, now time.Time) {
+	clock = func() time.Time { return now }
+	t.Cleanup(func() { clock = time.Now })
+}
+
+func appendAt(t *testing.T, familyId, deviceId string, at time.Time, timestamp int64) {
+	setClock(t, at)
+	if err := Append(familyId, &l8myfamily.Location{DeviceId: deviceId, Timestamp: timestamp}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func segmentFiles(t *testing.T, familyId, deviceId string) []string {
+	entries, err := os.ReadDir(filepath.Join(rootDir(), familyId, deviceId+segmentDirExt))
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	return names
+}
+
+func timestamps(t *testing.T, familyId, deviceId string) []int64 {
+	result := make([]int64, 0)
+	err := Scan(familyId, deviceId, 0, 0, func(p *l8myfamily.Location) error {
+		result = append(result, p.Timestamp)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return result
+}
+
+func TestRolloverCompressesClosedDays(t *testing.T) {
+	setupHistory(t, &config.ServerConfig{
+		HistoryStorage: &config.HistoryStorage{Rollover: config.RolloverDaily, Compression: config.CompressionGzip},
+	})
+	day1 := time.Date(2026, 10, 16, 22, 0, 0, 0, time.UTC)
+	day2 := day1.Add(4 * time.Hour)
+	// A history of before the rollover is read first
+	config.Set(&config.ServerConfig{DataDir: config.Get().DataDir})
+	appendAt(t, "fam", "d1", day1, 1)
+	config.Set(&config.ServerConfig{DataDir: config.Get().DataDir,
+		HistoryStorage: &config.HistoryStorage{Rollover: config.RolloverDaily, Compression: config.CompressionGzip}})
+	for ts := int64(2); ts <= 4; ts++ {
+		appendAt(t, "fam", "d1", day1, ts)
+	}
+	snapshot, err := OpenSnapshot("fam", "d1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer snapshot.Close()
+	appendAt(t, "fam", "d1", day2, 5)
+	compressing.Wait()
+
+	files := segmentFiles(t, "fam", "d1")
+	if len(files) != 2 || files[0] != "2026-10-16.history.gz" || files[1] != "2026-10-17.history" {
+		t.Fatalf("expected the closed day compressed and the current one plain, got %v", files)
+	}
+	if got := timestamps(t, "fam", "d1"); len(got) != 5 || got[0] != 1 || got[3] != 4 || got[4] != 5 {
+		t.Fatalf("expected the single file and both days in order, got %v", got)
+	}
+	count := 0
+	snapshot.Scan(0, 0, func(*l8myfamily.Location) error {
+		count++
+		return nil
+	})
+	if count != 4 {
+		t.Fatalf("a snapshot must keep reading the day it opened plain, got %d points", count)
+	}
+	appendAt(t, "fam", "d1", day2, 6)
+	compressing.Wait()
+	if files := segmentFiles(t, "fam", "d1"); len(files) != 2 {
+		t.Fatalf("an append to the current day must not roll over, got %v", files)
+	}
+}
+
+func TestPruneRemovesWholeSegments(t *testing.T) {
+	setupHistory(t, &config.ServerConfig{
+		HistoryRetention: &config.Retention{MaxAgeSeconds: 90 * 60},
+		HistoryStorage:   &config.HistoryStorage{Rollover: config.RolloverHourly, Compression: config.CompressionGzip},
+		Families: map[string]*config.FamilyConfig{
+			"small": {HistoryRetention: &config.Retention{MaxPoints: 3}},
+		},
+	})
+	now := time.Date(2026, 10, 17, 12, 30, 0, 0, time.UTC)
+	for hours := 3; hours >= 0; hours-- {
+		at := now.Add(-time.Duration(hours) * time.Hour)
+		appendAt(t, "fam", "d1", at, at.UnixMilli())
+		appendAt(t, "small", "d2", at, at.UnixMilli())
+		appendAt(t, "small", "d2", at, at.UnixMilli()+1)
+	}
+	// A late offline point arrives in the current hour
+	appendAt(t, "fam", "d1", now, now.Add(-5*time.Hour).UnixMilli())
+	compressing.Wait()
+
+	setClock(t, now)
+	if removed := Prune(); removed != 2+5 {
+		t.Fatalf("expected 7 removed points, got %d", removed)
+	}
+	if files := segmentFiles(t, "fam", "d1"); len(files) != 2 || files[0] != "2026-10-17T11.history.gz" {
+		t.Fatalf("expected the hours before the max age removed whole, got %v", files)
+	}
+	got := timestamps(t, "fam", "d1")
+	if len(got) != 3 || got[0] != now.Add(-time.Hour).UnixMilli() || got[2] != now.Add(-5*time.Hour).UnixMilli() {
+		t.Fatalf("expected the recent hours and the late point, got %v", got)
+	}
+	if files := segmentFiles(t, "small", "d2"); len(files) != 2 {
+		t.Fatalf("expected the oldest hours of the small family removed, got %v", files)
+	}
+	got = timestamps(t, "small", "d2")
+	if len(got) != 3 || got[0] != now.Add(-time.Hour).UnixMilli()+1 || got[2] != now.UnixMilli()+1 {
+		t.Fatalf("expected the 3 newest points, got %v", got)
+	}
+}
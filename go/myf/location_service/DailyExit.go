@@ -0,0 +1,88 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package location_service
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/saichler/l8myfamiliy/go/myf/audit"
+	"github.com/saichler/l8myfamiliy/go/myf/config"
+	"github.com/saichler/l8myfamiliy/go/myf/notify"
+	"github.com/saichler/l8myfamiliy/go/types/l8myfamily"
+)
+
+// dailyExits remembers the day each device last left its home region
+var dailyExits = NewDailyExitTracker()
+
+// DailyExitTracker notifies the family the first time a device leaves its
+// home region each local day of the family, a later exit of the same day is
+// only recorded as a geofence event
+type DailyExitTracker struct {
+	mtx      *sync.Mutex
+	notified map[string]int64
+}
+
+func NewDailyExitTracker() *DailyExitTracker {
+	return &DailyExitTracker{mtx: &sync.Mutex{}, notified: make(map[string]int64)}
+}
+
+// Check notifies when one of the events is the first exit of the home region
+// on its local day
+func (t *DailyExitTracker) Check(device *l8myfamily.Device, region *config.Geofence, events []*l8myfamily.GeofenceEvent) {
+	for _, e := range events {
+		if e.Entered || e.Geofence != region.Name {
+			continue
+		}
+		at := time.UnixMilli(e.Timestamp)
+		if e.Timestamp == 0 {
+			at = time.Now()
+		}
+		if t.first(e.DeviceId, config.StartOfDay(device.FamilyId, at).UnixMilli()) {
+			onLeftHome(e, device, region)
+		}
+	}
+}
+
+// first records the exit and returns true when it is the first of the day
+// that starts at day
+func (t *DailyExitTracker) first(deviceId string, day int64) bool {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	if t.notified[deviceId] >= day {
+		return false
+	}
+	t.notified[deviceId] = day
+	return true
+}
+
+func onLeftHome(e *l8myfamily.GeofenceEvent, device *l8myfamily.Device, region *config.Geofence) {
+	name := e.DeviceId
+	if device.Name != "" {
+		name = device.Name
+	}
+	fmt.Println("[Geofence] ", e.DeviceId, "-", device.FamilyId, " left ", region.Name, " for the first time today")
+	notify.Send(&notify.Event{
+		Type:      notify.EventLeftHome,
+		DeviceId:  e.DeviceId,
+		FamilyId:  device.FamilyId,
+		Latitude:  e.Latitude,
+		Longitude: e.Longitude,
+		Message:   name + " left " + region.Name,
+	})
+	audit.Log(notify.EventLeftHome, e.DeviceId, e.DeviceId, device.FamilyId, "region="+region.Name)
+}
@@ -0,0 +1,88 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package location_service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/saichler/l8myfamiliy/go/myf/config"
+	"github.com/saichler/l8myfamiliy/go/myf/history"
+	"github.com/saichler/l8myfamiliy/go/types/l8myfamily"
+)
+
+func TestDailyExitResetsAtLocalMidnight(t *testing.T) {
+	received := setupEmergencyTest(t)
+	cfg := *config.Get()
+	cfg.Families = map[string]*config.FamilyConfig{"family": {
+		Timezone: "America/New_York",
+		HomeRegions: map[string]*config.HomeRegion{
+			"d1": {Geofence: config.Geofence{Name: "Neighborhood", Latitude: 40, Longitude: -74, RadiusMeters: 1000}, Enabled: true},
+			"d2": {Geofence: config.Geofence{Latitude: 40, Longitude: -74, RadiusMeters: 1000}},
+		},
+	}}
+	config.Set(&cfg)
+	dailyExits = NewDailyExitTracker()
+
+	ny, _ := time.LoadLocation("America/New_York")
+	at := func(day, hour, minute int) int64 {
+		return time.Date(2026, time.March, day, hour, minute, 0, 0, ny).UnixMilli()
+	}
+	device := &l8myfamily.Device{Id: "d1", FamilyId: "family", Name: "Emma's Phone"}
+	previous := position{latitude: 40, longitude: -74, timestamp: at(10, 8, 0), known: true}
+	move := func(id string, lat float32, timestamp int64) {
+		l := &l8myfamily.Location{DeviceId: id, Latitude: lat, Longitude: -74, Timestamp: timestamp}
+		afterUpdate(l, &l8myfamily.Device{Id: id, FamilyId: device.FamilyId, Name: device.Name}, previous)
+		previous = previous.next(l)
+	}
+	expect := func(alerts int, when string) {
+		t.Helper()
+		for i := 0; i < alerts; i++ {
+			select {
+			case <-received:
+			case <-time.After(5 * time.Second):
+				t.Fatalf("expected an alert %s", when)
+			}
+		}
+		select {
+		case <-received:
+			t.Fatalf("unexpected alert %s", when)
+		case <-time.After(200 * time.Millisecond):
+		}
+	}
+
+	// The first exit of the day is notified, the later ones only recorded
+	move("d1", 40.1, at(10, 23, 30))
+	expect(1, "on the first exit of the day")
+	move("d1", 40, at(10, 23, 40))
+	move("d1", 40.1, at(10, 23, 50))
+	expect(0, "on the second exit of the day")
+
+	// The local day starts at midnight of the family timezone, not UTC
+	move("d1", 40, at(10, 23, 55))
+	move("d1", 40.1, at(11, 0, 10))
+	expect(1, "on the first exit after midnight")
+
+	events, err := history.QueryGeofence("family", "d1", 0, 0)
+	if err != nil || len(events) != 5 || events[0].Geofence != "Neighborhood" || !events[0].ServerEvaluated {
+		t.Fatalf("expected every crossing recorded, got %v %v", events, err)
+	}
+
+	// A disabled home region is not evaluated
+	previous = position{latitude: 40, longitude: -74, timestamp: at(11, 8, 0), known: true}
+	move("d2", 40.1, at(11, 9, 0))
+	expect(0, "for a disabled home region")
+}
@@ -0,0 +1,49 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package location_service
+
+import (
+	"fmt"
+
+	"github.com/saichler/l8myfamiliy/go/myf/audit"
+	"github.com/saichler/l8myfamiliy/go/myf/notify"
+	"github.com/saichler/l8myfamiliy/go/types/l8myfamily"
+)
+
+// onEmergency alerts the family integrations and records the emergency.
+// The device may be nil when it is not registered, the alert is still sent.
+func onEmergency(l *l8myfamily.Location, device *l8myfamily.Device) {
+	familyId := ""
+	message := "Emergency from device " + l.DeviceId
+	if device != nil {
+		familyId = device.FamilyId
+		if device.Name != "" {
+			message = "Emergency from " + device.Name
+		}
+	}
+	fmt.Println("[Emergency] ", l.DeviceId, "-", familyId, " at ", l.Latitude, ",", l.Longitude)
+	notify.Send(&notify.Event{
+		Type:      notify.EventEmergency,
+		Priority:  notify.PriorityHigh,
+		DeviceId:  l.DeviceId,
+		FamilyId:  familyId,
+		Latitude:  l.Latitude,
+		Longitude: l.Longitude,
+		Message:   message,
+	})
+	audit.Log(notify.EventEmergency, l.DeviceId, l.DeviceId, familyId,
+		fmt.Sprintf("lat=%f lon=%f", l.Latitude, l.Longitude))
+}
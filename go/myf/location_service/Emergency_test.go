@@ -0,0 +1,159 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package location_service
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/saichler/l8myfamiliy/go/myf/audit"
+	"github.com/saichler/l8myfamiliy/go/myf/config"
+	"github.com/saichler/l8myfamiliy/go/myf/history"
+	"github.com/saichler/l8myfamiliy/go/myf/notify"
+	"github.com/saichler/l8myfamiliy/go/types/l8myfamily"
+	"github.com/saichler/l8types/go/ifs"
+)
+
+func setupEmergencyTest(t *testing.T) chan *http.Request {
+	received := make(chan *http.Request, 10)
+	events := make(chan *notify.Event, 10)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		event := &notify.Event{}
+		json.NewDecoder(r.Body).Decode(event)
+		events <- event
+		received <- r
+	}))
+	t.Cleanup(srv.Close)
+	config.Set(&config.ServerConfig{DataDir: t.TempDir(), Webhooks: []*config.Webhook{{Url: srv.URL}}})
+	t.Cleanup(func() { config.Set(config.Default()) })
+
+	orig := updateDevice
+	updateDevice = func(l *l8myfamily.Location, vnic ifs.IVNic) *l8myfamily.Device {
+		return &l8myfamily.Device{Id: l.DeviceId, FamilyId: "family", Name: "Kid Phone",
+			Latitude: l.Latitude, Longitude: l.Longitude, Emergency: l.Emergency}
+	}
+	origGet := getDevice
+	getDevice = func(id string, vnic ifs.IVNic) *l8myfamily.Device {
+		return &l8myfamily.Device{Id: id, FamilyId: "family", PrecisionMeters: 1000}
+	}
+	t.Cleanup(func() {
+		updateDevice = orig
+		getDevice = origGet
+	})
+	return received
+}
+
+func TestEmergencyBeforeNeverSuppresses(t *testing.T) {
+	setupEmergencyTest(t)
+	l := &l8myfamily.Location{DeviceId: "d1", Latitude: 37.774912, Longitude: -122.419416, Emergency: true}
+	ok, err := postInternal(l)
+	if err != nil || !ok {
+		t.Fatalf("emergency post was suppressed: ok=%v err=%v", ok, err)
+	}
+	if l.Latitude != 37.774912 || l.Longitude != -122.419416 {
+		t.Fatalf("emergency post must keep its exact position, got %v,%v", l.Latitude, l.Longitude)
+	}
+}
+
+func TestEmergencyAfterAlertsWithHighPriority(t *testing.T) {
+	received := setupEmergencyTest(t)
+	cb := &LocationCallback{}
+	cb.After(&l8myfamily.Location{DeviceId: "d1", Latitude: 1, Longitude: 2, Emergency: true}, ifs.POST, false, nil)
+
+	select {
+	case r := <-received:
+		if r.Header.Get("X-Priority") != string(notify.PriorityHigh) {
+			t.Fatalf("expected high priority header, got %s", r.Header.Get("X-Priority"))
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("emergency webhook was not called")
+	}
+
+	records, err := audit.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 1 || records[0].Action != notify.EventEmergency || records[0].FamilyId != "family" {
+		t.Fatalf("expected one emergency audit record, got %v", records)
+	}
+}
+
+func TestRegularLocationDoesNotAlert(t *testing.T) {
+	received := setupEmergencyTest(t)
+	cb := &LocationCallback{}
+	cb.After(&l8myfamily.Location{DeviceId: "d1", Latitude: 1, Longitude: 2}, ifs.POST, false, nil)
+
+	select {
+	case <-received:
+		t.Fatal("regular location should not trigger a webhook")
+	case <-time.After(200 * time.Millisecond):
+	}
+	records, _ := audit.Read()
+	if len(records) != 0 {
+		t.Fatalf("regular location should not be audited, got %v", records)
+	}
+}
+
+func TestHeartbeatOnlyDeviceNeverPostsPosition(t *testing.T) {
+	received := setupEmergencyTest(t)
+	getDevice = func(id string, vnic ifs.IVNic) *l8myfamily.Device {
+		return &l8myfamily.Device{Id: id, FamilyId: "family", HeartbeatOnly: true}
+	}
+	cb := &LocationCallback{}
+	for _, emergency := range []bool{false, true} {
+		l := &l8myfamily.Location{DeviceId: "d1", Latitude: 1, Longitude: 2, Accuracy: 5, Place: "Home", Emergency: emergency}
+		if ok, err := postInternal(l); !ok || err != nil {
+			t.Fatalf("the post must be accepted, got %v %v", ok, err)
+		}
+		if !l.Heartbeat || l.Latitude != 0 || l.Longitude != 0 || l.Accuracy != 0 || l.Place != "" || l.Emergency != emergency {
+			t.Fatalf("a leaked position must be dropped, got %v", l)
+		}
+		cb.After(l, ifs.POST, false, nil)
+	}
+	if points, _ := history.Query("family", "d1", 0, 0); len(points) != 0 {
+		t.Fatalf("a heartbeat-only device must have no history, got %v", points)
+	}
+	select {
+	case <-received:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the emergency of a heartbeat-only device must still be alerted")
+	}
+}
+
+func TestClearedEmergencyRetryIsNotAlerted(t *testing.T) {
+	received := setupEmergencyTest(t)
+	cleared := time.Now().UnixMilli()
+	updateDevice = func(l *l8myfamily.Location, vnic ifs.IVNic) *l8myfamily.Device {
+		return &l8myfamily.Device{Id: l.DeviceId, FamilyId: "family", EmergencyClearedTime: cleared}
+	}
+	cb := &LocationCallback{}
+	cb.After(&l8myfamily.Location{DeviceId: "d1", Latitude: 1, Longitude: 2, Timestamp: cleared - 1000, Emergency: true}, ifs.POST, false, nil)
+	select {
+	case <-received:
+		t.Fatal("an emergency taken before the clear must not be alerted")
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	cb.After(&l8myfamily.Location{DeviceId: "d1", Latitude: 1, Longitude: 2, Timestamp: cleared + 1000, Emergency: true}, ifs.POST, false, nil)
+	select {
+	case <-received:
+	case <-time.After(5 * time.Second):
+		t.Fatal("a new emergency after the clear must be alerted")
+	}
+}
@@ -0,0 +1,199 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package location_service
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/saichler/l8myfamiliy/go/myf/auth"
+	"github.com/saichler/l8myfamiliy/go/myf/config"
+	"github.com/saichler/l8myfamiliy/go/myf/device_service"
+	"github.com/saichler/l8myfamiliy/go/myf/geo"
+	"github.com/saichler/l8myfamiliy/go/myf/history"
+	"github.com/saichler/l8myfamiliy/go/myf/webapi"
+	"github.com/saichler/l8myfamiliy/go/types/l8myfamily"
+	"github.com/saichler/l8types/go/ifs"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// The record types of the export lines
+const (
+	ExportRecordDevice   = "device"
+	ExportRecordLocation = "location"
+)
+
+// ExportRecord is a line of the family export, a device or one of the
+// location history points of the device before it
+type ExportRecord struct {
+	Type     string               `json:"type"`
+	Device   *l8myfamily.Device   `json:"device,omitempty"`
+	Location *l8myfamily.Location `json:"location,omitempty"`
+}
+
+// exportLine is an ExportRecord with the device and the location as
+// protobuf JSON, like the other responses
+type exportLine struct {
+	Type     string          `json:"type"`
+	Device   json.RawMessage `json:"device,omitempty"`
+	Location json.RawMessage `json:"location,omitempty"`
+}
+
+func (this ExportRecord) MarshalJSON() ([]byte, error) {
+	line := &exportLine{Type: this.Type}
+	var err error
+	if this.Device != nil {
+		if line.Device, err = protojson.Marshal(this.Device); err != nil {
+			return nil, err
+		}
+	}
+	if this.Location != nil {
+		if line.Location, err = protojson.Marshal(this.Location); err != nil {
+			return nil, err
+		}
+	}
+	return json.Marshal(line)
+}
+
+func (this *ExportRecord) UnmarshalJSON(data []byte) error {
+	line := &exportLine{}
+	if err := json.Unmarshal(data, line); err != nil {
+		return err
+	}
+	*this = ExportRecord{Type: line.Type}
+	if len(line.Device) > 0 {
+		this.Device = &l8myfamily.Device{}
+		if err := protojson.Unmarshal(line.Device, this.Device); err != nil {
+			return err
+		}
+	}
+	if len(line.Location) > 0 {
+		this.Location = &l8myfamily.Location{}
+		if err := protojson.Unmarshal(line.Location, this.Location); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// listDevices and withDevice are variables so tests can run the export
+// without a device store
+var (
+	listDevices = device_service.ListDevices
+	withDevice  = device_service.WithDevice
+)
+
+// exportHandler streams the caller family data as NDJSON, every device the
+// caller may see, the archived ones after the active ones, followed by its
+// whole location history in arrival order. The coordFormat parameter sets the
+// coordinates field of the devices and points, see geo.ParseCoordFormat.
+// Each device is read together with the state of its history under the device
+// lock, the points are then written as they are read.
+func exportHandler(w http.ResponseWriter, r *http.Request, vnic ifs.IVNic) {
+	if !webapi.RequireMethod(w, r, http.MethodGet) {
+		return
+	}
+	if !config.Get().Export {
+		http.NotFound(w, r)
+		return
+	}
+	caller := auth.Authenticate(w, r, vnic)
+	if caller == nil {
+		return
+	}
+	format, err := geo.ParseCoordFormat(r.URL.Query().Get("coordFormat"), r.URL.Query().Get("coordPrecision"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	// The archived devices keep their history, it is exported too
+	devices := listDevices(&device_service.DeviceFilter{FamilyId: caller.FamilyId, Viewer: caller})
+	devices = append(devices, listDevices(&device_service.DeviceFilter{FamilyId: caller.FamilyId, Viewer: caller, Archived: true})...)
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.ndjson"`, caller.FamilyId))
+	w.WriteHeader(http.StatusOK)
+	flusher, _ := w.(http.Flusher)
+	stream := &exportStream{w: w, flusher: flusher, format: format}
+	for _, device := range devices {
+		if err := stream.device(device.Id, caller); err != nil {
+			// The status is sent, the client sees a truncated export
+			fmt.Println("[Location] export of ", caller.FamilyId, " failed at ", device.Id, ": ", err.Error())
+			return
+		}
+	}
+}
+
+// exportStream writes the lines of an export as they are read
+type exportStream struct {
+	w       http.ResponseWriter
+	flusher http.Flusher
+	format  *geo.CoordFormat
+	lines   int
+}
+
+// device writes the device and its history, a device removed or hidden from
+// the caller since it was listed is skipped
+func (this *exportStream) device(id string, caller *auth.Caller) error {
+	var device *l8myfamily.Device
+	var snapshot *history.Snapshot
+	err := withDevice(id, func(stored *l8myfamily.Device) error {
+		if stored == nil || stored.FamilyId != caller.FamilyId || !device_service.CanSee(stored, caller) {
+			return nil
+		}
+		var err error
+		snapshot, err = history.OpenSnapshot(stored.FamilyId, id)
+		device = stored
+		return err
+	})
+	if err != nil || device == nil {
+		return err
+	}
+	defer snapshot.Close()
+	if !this.format.Decimal() {
+		// The stored device is shared with the cache, it is formatted on a copy
+		device = proto.Clone(device).(*l8myfamily.Device)
+		device.Coordinates = this.format.Format(device.Latitude, device.Longitude)
+	}
+	if err := this.write(&ExportRecord{Type: ExportRecordDevice, Device: device}); err != nil {
+		return err
+	}
+	err = snapshot.Scan(0, 0, func(l *l8myfamily.Location) error {
+		l.Coordinates = this.format.Format(l.Latitude, l.Longitude)
+		return this.write(&ExportRecord{Type: ExportRecordLocation, Location: l})
+	})
+	if err == nil && this.flusher != nil {
+		this.flusher.Flush()
+	}
+	return err
+}
+
+func (this *exportStream) write(record *ExportRecord) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	if _, err := this.w.Write(append(data, '\n')); err != nil {
+		return err
+	}
+	this.lines++
+	if this.flusher != nil && this.lines%historyFlushPoints == 0 {
+		this.flusher.Flush()
+	}
+	return nil
+}
@@ -0,0 +1,131 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package location_service
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/saichler/l8myfamiliy/go/myf/auth"
+	"github.com/saichler/l8myfamiliy/go/myf/config"
+	"github.com/saichler/l8myfamiliy/go/myf/device_service"
+	"github.com/saichler/l8myfamiliy/go/myf/history"
+	"github.com/saichler/l8myfamiliy/go/types/l8myfamily"
+	"github.com/saichler/l8types/go/ifs"
+)
+
+func TestExportHandler(t *testing.T) {
+	cfg := config.Default()
+	cfg.DataDir = t.TempDir()
+	config.Set(cfg)
+	stored := map[string]*l8myfamily.Device{
+		"d1": {Id: "d1", FamilyId: "family", Name: "phone"},
+		"d2": {Id: "d2", FamilyId: "family", Name: "laptop", ArchivedTime: 1},
+		// Moved to another family after it was listed
+		"d3": {Id: "d3", FamilyId: "other"},
+	}
+	origList, origWith, origValidate := listDevices, withDevice, auth.ValidateToken
+	listDevices = func(filter *device_service.DeviceFilter) []*l8myfamily.Device {
+		if filter.Archived {
+			return []*l8myfamily.Device{{Id: "d2"}}
+		}
+		return []*l8myfamily.Device{{Id: "d1"}, {Id: "d3"}}
+	}
+	withDevice = func(id string, fn func(*l8myfamily.Device) error) error {
+		return fn(stored[id])
+	}
+	auth.ValidateToken = func(token string, vnic ifs.IVNic) (string, bool) {
+		return token, true
+	}
+	t.Cleanup(func() {
+		listDevices, withDevice, auth.ValidateToken = origList, origWith, origValidate
+		config.Set(config.Default())
+	})
+	for i := int64(1); i <= 150; i++ {
+		history.Append("family", &l8myfamily.Location{DeviceId: "d1", Timestamp: i})
+	}
+	history.Append("family", &l8myfamily.Location{DeviceId: "d3", Timestamp: 1})
+
+	get := func(token string, query ...string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("GET", "/my-family/export"+strings.Join(query, ""), nil)
+		if token != "" {
+			r.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		exportHandler(w, r, nil)
+		return w
+	}
+
+	w := get("family")
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "application/x-ndjson" {
+		t.Fatalf("unexpected export response %d %s", w.Code, w.Header().Get("Content-Type"))
+	}
+	records := make([]*ExportRecord, 0)
+	scanner := bufio.NewScanner(w.Body)
+	for scanner.Scan() {
+		record := &ExportRecord{}
+		if err := json.Unmarshal(scanner.Bytes(), record); err != nil {
+			t.Fatalf("invalid export line %s: %v", scanner.Text(), err)
+		}
+		records = append(records, record)
+	}
+	if len(records) != 152 {
+		t.Fatalf("expected 2 devices and 150 points, got %d lines", len(records))
+	}
+	if records[0].Type != ExportRecordDevice || records[0].Device.Name != "phone" {
+		t.Fatalf("expected the first device first, got %+v", records[0])
+	}
+	for i, record := range records[1:151] {
+		if record.Type != ExportRecordLocation || record.Location.Timestamp != int64(i+1) {
+			t.Fatalf("expected point %d of the first device, got %+v", i+1, record)
+		}
+	}
+	if records[151].Type != ExportRecordDevice || records[151].Device.Id != "d2" {
+		t.Fatalf("expected the archived device without history last, got %+v", records[151])
+	}
+
+	// The points and devices carry the formatted coordinates
+	w = get("family", "?coordFormat=geohash&coordPrecision=4")
+	scanner = bufio.NewScanner(w.Body)
+	for scanner.Scan() {
+		record := &ExportRecord{}
+		json.Unmarshal(scanner.Bytes(), record)
+		if record.Location != nil && record.Location.Coordinates != "s000" {
+			t.Fatalf("expected the point geohash, got %+v", record.Location)
+		}
+		if record.Device != nil && record.Device.Coordinates != "s000" {
+			t.Fatalf("expected the device geohash, got %+v", record.Device)
+		}
+	}
+	if stored["d1"].Coordinates != "" {
+		t.Fatal("the stored device must not be formatted")
+	}
+	if w := get("family", "?coordFormat=mgrs"); w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for an unknown format, got %d", w.Code)
+	}
+
+	if w := get(""); w.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 without a token, got %d", w.Code)
+	}
+	cfg.Export = false
+	if w := get("family"); w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 when the export is disabled, got %d", w.Code)
+	}
+}
@@ -0,0 +1,122 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package location_service
+
+import (
+	"sync"
+	"time"
+
+	"github.com/saichler/l8myfamiliy/go/myf/config"
+)
+
+// rateWindow is the period of the family ceiling, a device that did not post
+// within it is no longer counted as sharing the ceiling
+const rateWindow = time.Minute
+
+// familyRates caps the location posts of each family
+var familyRates = NewFamilyRateLimiter()
+
+// FamilyRateLimiter caps the combined location posts of a family. The ceiling
+// is split equally between the devices that posted within the window, so a
+// device stuck in a tight loop cannot starve the rest of the family. Only the
+// families that posted within the window hold state.
+type FamilyRateLimiter struct {
+	mtx      *sync.Mutex
+	families map[string]map[string]*rateShare
+	swept    time.Time
+}
+
+// rateShare is the token bucket of a single device
+type rateShare struct {
+	tokens   float64
+	refilled time.Time
+	posted   time.Time
+}
+
+func NewFamilyRateLimiter() *FamilyRateLimiter {
+	return &FamilyRateLimiter{mtx: &sync.Mutex{}, families: make(map[string]map[string]*rateShare)}
+}
+
+// Allow returns false when the device exceeded its share of the family
+// location ceiling
+func (r *FamilyRateLimiter) Allow(familyId, deviceId string, now time.Time) bool {
+	ceiling := config.LocationsPerMinuteFor(familyId)
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	r.sweep(now)
+	if ceiling <= 0 {
+		return true
+	}
+	devices := r.families[familyId]
+	if devices == nil {
+		devices = make(map[string]*rateShare)
+		r.families[familyId] = devices
+	}
+	for id, share := range devices {
+		if id != deviceId && now.Sub(share.posted) > rateWindow {
+			delete(devices, id)
+		}
+	}
+	share := devices[deviceId]
+	if share == nil {
+		// A new device starts with a single post, not a full window burst
+		share = &rateShare{tokens: 1, refilled: now}
+		devices[deviceId] = share
+	}
+	perWindow := float64(ceiling) / float64(len(devices))
+	share.refill(perWindow, now)
+	share.posted = now
+	if share.tokens < 1 {
+		return false
+	}
+	share.tokens--
+	return true
+}
+
+// refill adds the tokens earned since the last refill, a device may bank at
+// most one window of its share
+func (s *rateShare) refill(perWindow float64, now time.Time) {
+	if elapsed := now.Sub(s.refilled); elapsed > 0 {
+		s.tokens += perWindow * elapsed.Seconds() / rateWindow.Seconds()
+		s.refilled = now
+	}
+	capacity := perWindow
+	if capacity < 1 {
+		capacity = 1
+	}
+	if s.tokens > capacity {
+		s.tokens = capacity
+	}
+}
+
+// sweep drops the devices and families that did not post within the window,
+// it runs at most once per window
+func (r *FamilyRateLimiter) sweep(now time.Time) {
+	if now.Sub(r.swept) < rateWindow {
+		return
+	}
+	r.swept = now
+	for familyId, devices := range r.families {
+		for id, share := range devices {
+			if now.Sub(share.posted) > rateWindow {
+				delete(devices, id)
+			}
+		}
+		if len(devices) == 0 {
+			delete(r.families, familyId)
+		}
+	}
+}
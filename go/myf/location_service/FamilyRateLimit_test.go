@@ -0,0 +1,143 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package location_service
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/saichler/l8myfamiliy/go/myf/config"
+	"github.com/saichler/l8myfamiliy/go/myf/webapi"
+	"github.com/saichler/l8myfamiliy/go/types/l8myfamily"
+	"github.com/saichler/l8types/go/ifs"
+)
+
+func setFamilyRate(t *testing.T, perMinute int, families map[string]*config.FamilyConfig) {
+	t.Helper()
+	cfg := *config.Get()
+	cfg.FamilyLocationsPerMinute = perMinute
+	cfg.Families = families
+	config.Set(&cfg)
+	t.Cleanup(func() { config.Set(config.Default()) })
+}
+
+func TestFamilyRateSharedFairly(t *testing.T) {
+	setFamilyRate(t, 30, nil)
+	limiter := NewFamilyRateLimiter()
+	start := time.Date(2026, time.May, 4, 12, 0, 0, 0, time.UTC)
+
+	// A device stuck posting every 100ms and a device posting every 6s
+	// contend for the ceiling over five minutes
+	allowed := map[string]int{}
+	posted := map[string]int{}
+	for ms := int64(0); ms < 5*60*1000; ms += 100 {
+		now := start.Add(time.Duration(ms) * time.Millisecond)
+		devices := []string{"loop"}
+		if ms%6000 == 0 {
+			devices = append(devices, "quiet")
+		}
+		for _, id := range devices {
+			posted[id]++
+			if limiter.Allow("family", id, now) {
+				allowed[id]++
+			}
+		}
+	}
+	if allowed["quiet"] != posted["quiet"] {
+		t.Fatalf("a device within its share must not be capped, %d of %d allowed", allowed["quiet"], posted["quiet"])
+	}
+	// The looping device gets its half of the ceiling and no more
+	if allowed["loop"] < 70 || allowed["loop"] > 80 {
+		t.Fatalf("the looping device must be held to its share, %d allowed", allowed["loop"])
+	}
+	if total := allowed["loop"] + allowed["quiet"]; total > 5*30+2 {
+		t.Fatalf("the family must stay under its ceiling, %d allowed", total)
+	}
+}
+
+func TestFamilyRateSingleDeviceUsesCeiling(t *testing.T) {
+	setFamilyRate(t, 12, map[string]*config.FamilyConfig{
+		"uncapped": {LocationsPerMinute: -1},
+		"strict":   {LocationsPerMinute: 2},
+	})
+	limiter := NewFamilyRateLimiter()
+	start := time.Date(2026, time.May, 4, 12, 0, 0, 0, time.UTC)
+
+	count := func(familyId string) int {
+		allowed := 0
+		for s := 0; s < 60; s++ {
+			if limiter.Allow(familyId, "d1", start.Add(time.Duration(s)*time.Second)) {
+				allowed++
+			}
+		}
+		return allowed
+	}
+	if n := count("family"); n < 11 || n > 13 {
+		t.Fatalf("a lone device must get the whole global ceiling, %d allowed", n)
+	}
+	if n := count("strict"); n < 2 || n > 3 {
+		t.Fatalf("the family ceiling must override the global one, %d allowed", n)
+	}
+	if n := count("uncapped"); n != 60 {
+		t.Fatalf("a negative family ceiling must lift the cap, %d allowed", n)
+	}
+}
+
+func TestFamilyRateDropsIdleFamilies(t *testing.T) {
+	setFamilyRate(t, 60, nil)
+	limiter := NewFamilyRateLimiter()
+	start := time.Date(2026, time.May, 4, 12, 0, 0, 0, time.UTC)
+
+	limiter.Allow("a", "d1", start)
+	limiter.Allow("a", "d2", start)
+	limiter.Allow("b", "d3", start)
+	if len(limiter.families) != 2 {
+		t.Fatalf("expected two families, got %d", len(limiter.families))
+	}
+	// Once d2 is idle its share goes back to d1
+	limiter.Allow("a", "d1", start.Add(2*rateWindow))
+	if len(limiter.families) != 1 || len(limiter.families["a"]) != 1 {
+		t.Fatalf("idle devices and families must be dropped, got %v", limiter.families)
+	}
+}
+
+func TestBeforeRejectsOverFamilyRate(t *testing.T) {
+	setFamilyRate(t, 1, nil)
+	origGet, origAllowed, origRates := getDevice, deviceAllowed, familyRates
+	getDevice = func(id string, vnic ifs.IVNic) *l8myfamily.Device { return &l8myfamily.Device{Id: id, FamilyId: "fam"} }
+	deviceAllowed = func(familyId, deviceId string) bool { return true }
+	familyRates = NewFamilyRateLimiter()
+	defer func() { getDevice, deviceAllowed, familyRates = origGet, origAllowed, origRates }()
+
+	if ok, err := postInternal(&l8myfamily.Location{DeviceId: "d1"}); !ok || err != nil {
+		t.Fatalf("the first post must pass, got %v %v", ok, err)
+	}
+	if ok, err := postInternal(&l8myfamily.Location{DeviceId: "d1", IdempotencyKey: "k1"}); ok || err != webapi.ErrRateLimited {
+		t.Fatalf("a post over the family rate must be rejected, got %v %v", ok, err)
+	}
+	if webapi.ErrorStatus(webapi.ErrRateLimited) != http.StatusTooManyRequests {
+		t.Fatal("a rate limited post must answer 429")
+	}
+	if ok, err := postInternal(&l8myfamily.Location{DeviceId: "d1", Emergency: true}); !ok || err != nil {
+		t.Fatalf("an emergency must never be capped, got %v %v", ok, err)
+	}
+	// The rejected key was not remembered, its retry is processed
+	familyRates = NewFamilyRateLimiter()
+	if ok, err := postInternal(&l8myfamily.Location{DeviceId: "d1", IdempotencyKey: "k1"}); !ok || err != nil {
+		t.Fatalf("the retry of a rejected post must not be a duplicate, got %v %v", ok, err)
+	}
+}
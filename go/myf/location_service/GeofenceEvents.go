@@ -0,0 +1,150 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package location_service
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/saichler/l8myfamiliy/go/myf/auth"
+	"github.com/saichler/l8myfamiliy/go/myf/device_service"
+	"github.com/saichler/l8myfamiliy/go/myf/history"
+	"github.com/saichler/l8myfamiliy/go/myf/webapi"
+	"github.com/saichler/l8myfamiliy/go/types/l8myfamily"
+	"github.com/saichler/l8types/go/ifs"
+)
+
+const (
+	// maxEventWindow is the longest time window of a geofence event query,
+	// a query without from returns the last defaultEventWindow
+	maxEventWindow     = 31 * 24 * time.Hour
+	defaultEventWindow = 24 * time.Hour
+	defaultEventPage   = 100
+	maxEventPage       = 500
+)
+
+// geofenceEventsHandler returns a page of the geofence entries and exits of a
+// device of the caller family, e.g. arrived at school 8:05, left 15:10.
+// Parameters: deviceId, from and to in unix milliseconds, page (from 0) and
+// pageSize.
+func geofenceEventsHandler(w http.ResponseWriter, r *http.Request, vnic ifs.IVNic) {
+	if !webapi.RequireMethod(w, r, http.MethodGet) {
+		return
+	}
+	caller := auth.Authenticate(w, r, vnic)
+	if caller == nil {
+		return
+	}
+	query := r.URL.Query()
+	deviceId := query.Get("deviceId")
+	if deviceId == "" {
+		http.Error(w, "missing deviceId", http.StatusBadRequest)
+		return
+	}
+	from, to, err := parseWindow(query.Get("from"), query.Get("to"), time.Now(), defaultEventWindow, maxEventWindow)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	page, err := parseInt(query.Get("page"), 0)
+	if err != nil || page < 0 {
+		http.Error(w, "invalid page", http.StatusBadRequest)
+		return
+	}
+	pageSize, err := parseInt(query.Get("pageSize"), defaultEventPage)
+	if err != nil || pageSize <= 0 {
+		http.Error(w, "invalid pageSize", http.StatusBadRequest)
+		return
+	}
+	if pageSize > maxEventPage {
+		pageSize = maxEventPage
+	}
+	device := getDevice(deviceId, vnic)
+	if device == nil {
+		http.Error(w, "device not found", http.StatusNotFound)
+		return
+	}
+	if device.FamilyId != caller.FamilyId {
+		http.Error(w, "device belongs to another family", http.StatusForbidden)
+		return
+	}
+	// A device hidden from the member is not revealed to exist
+	if !device_service.CanSee(device, caller) {
+		http.Error(w, "device not found", http.StatusNotFound)
+		return
+	}
+	events, err := history.QueryGeofence(device.FamilyId, deviceId, from, to)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	webapi.WriteJSON(w, http.StatusOK, pageEvents(events, page, pageSize))
+}
+
+// parseWindow returns the from and to of a query, a missing to is now and a
+// missing from is def, at most max, before to. A window longer than max is
+// rejected.
+func parseWindow(fromParam, toParam string, now time.Time, def, max time.Duration) (int64, int64, error) {
+	if def > max {
+		def = max
+	}
+	to, err := strconv.ParseInt(toParam, 10, 64)
+	if toParam == "" {
+		to, err = now.UnixMilli(), nil
+	}
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid to")
+	}
+	from, err := strconv.ParseInt(fromParam, 10, 64)
+	if fromParam == "" {
+		from, err = to-def.Milliseconds(), nil
+	}
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid from")
+	}
+	if from > to {
+		return 0, 0, fmt.Errorf("from is after to")
+	}
+	if to-from > max.Milliseconds() {
+		return 0, 0, fmt.Errorf("the time window exceeds the maximum of %s", max)
+	}
+	return from, to, nil
+}
+
+func parseInt(param string, def int) (int, error) {
+	if param == "" {
+		return def, nil
+	}
+	return strconv.Atoi(param)
+}
+
+func pageEvents(events []*l8myfamily.GeofenceEvent, page, pageSize int) *l8myfamily.GeofenceEventList {
+	list := &l8myfamily.GeofenceEventList{Page: int32(page), PageSize: int32(pageSize)}
+	start := page * pageSize
+	if start >= len(events) {
+		return list
+	}
+	end := start + pageSize
+	if end < len(events) {
+		list.More = true
+	} else {
+		end = len(events)
+	}
+	list.List = events[start:end]
+	return list
+}
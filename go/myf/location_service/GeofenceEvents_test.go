@@ -0,0 +1,82 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package location_service
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/saichler/l8myfamiliy/go/myf/auth"
+	"github.com/saichler/l8myfamiliy/go/myf/config"
+	"github.com/saichler/l8myfamiliy/go/myf/history"
+	"github.com/saichler/l8myfamiliy/go/types/l8myfamily"
+	"github.com/saichler/l8types/go/ifs"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+func TestGeofenceEventsHandler(t *testing.T) {
+	config.Set(&config.ServerConfig{DataDir: t.TempDir()})
+	origGet, origValidate := getDevice, auth.ValidateToken
+	getDevice = func(id string, vnic ifs.IVNic) *l8myfamily.Device {
+		return &l8myfamily.Device{Id: id, FamilyId: "family"}
+	}
+	auth.ValidateToken = func(token string, vnic ifs.IVNic) (string, bool) {
+		return token, true
+	}
+	t.Cleanup(func() {
+		getDevice, auth.ValidateToken = origGet, origValidate
+		config.Set(config.Default())
+	})
+
+	now := time.Now().UnixMilli()
+	for i, fence := range []string{"Home", "", "School", ""} {
+		l := &l8myfamily.Location{DeviceId: "d1", Geofence: fence, Timestamp: now - int64(10-i)*60000}
+		if _, err := history.RecordGeofence("family", l); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	get := func(token, query string) (int, *l8myfamily.GeofenceEventList) {
+		r := httptest.NewRequest("GET", "/my-family/geofence-events?"+query, nil)
+		r.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		geofenceEventsHandler(w, r, nil)
+		list := &l8myfamily.GeofenceEventList{}
+		protojson.Unmarshal(w.Body.Bytes(), list)
+		return w.Code, list
+	}
+
+	code, list := get("family", "deviceId=d1&pageSize=3")
+	if code != http.StatusOK || len(list.List) != 3 || !list.More || list.List[0].Geofence != "Home" {
+		t.Fatalf("unexpected first page %d %v", code, list)
+	}
+	code, list = get("family", "deviceId=d1&pageSize=3&page=1")
+	if code != http.StatusOK || len(list.List) != 1 || list.More || list.List[0].Geofence != "School" || list.List[0].Entered {
+		t.Fatalf("unexpected second page %d %v", code, list)
+	}
+	if code, _ := get("stranger", "deviceId=d1"); code != http.StatusForbidden {
+		t.Fatalf("another family device must be forbidden, got %d", code)
+	}
+	if code, _ := get("family", fmt.Sprintf("deviceId=d1&from=0&to=%d", now)); code != http.StatusBadRequest {
+		t.Fatalf("a window beyond the cap must be rejected, got %d", code)
+	}
+	if code, _ := get("family", ""); code != http.StatusBadRequest {
+		t.Fatalf("a missing device id must be rejected, got %d", code)
+	}
+}
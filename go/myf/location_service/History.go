@@ -0,0 +1,124 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package location_service
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/saichler/l8myfamiliy/go/myf/auth"
+	"github.com/saichler/l8myfamiliy/go/myf/config"
+	"github.com/saichler/l8myfamiliy/go/myf/device_service"
+	"github.com/saichler/l8myfamiliy/go/myf/history"
+	"github.com/saichler/l8myfamiliy/go/myf/webapi"
+	"github.com/saichler/l8myfamiliy/go/types/l8myfamily"
+	"github.com/saichler/l8types/go/ifs"
+)
+
+const (
+	// defaultHistoryWindow is the window of a history query without from
+	defaultHistoryWindow = 24 * time.Hour
+	// historyFlushPoints is how many points are written between flushes of
+	// a streamed history response
+	historyFlushPoints = 100
+)
+
+// historyHandler streams the location history of a device of the caller
+// family as {"list":[...]} in arrival order. Parameters: deviceId, from and to
+// in unix milliseconds. The window is capped by config.HistoryMaxWindow, the
+// points are written as they are read so a long window is not held in memory.
+func historyHandler(w http.ResponseWriter, r *http.Request, vnic ifs.IVNic) {
+	if !webapi.RequireMethod(w, r, http.MethodGet) {
+		return
+	}
+	caller := auth.Authenticate(w, r, vnic)
+	if caller == nil {
+		return
+	}
+	query := r.URL.Query()
+	deviceId := query.Get("deviceId")
+	if deviceId == "" {
+		http.Error(w, "missing deviceId", http.StatusBadRequest)
+		return
+	}
+	from, to, err := parseWindow(query.Get("from"), query.Get("to"), time.Now(), defaultHistoryWindow, config.HistoryMaxWindow())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	device := getDevice(deviceId, vnic)
+	if device == nil {
+		http.Error(w, "device not found", http.StatusNotFound)
+		return
+	}
+	if device.FamilyId != caller.FamilyId {
+		http.Error(w, "device belongs to another family", http.StatusForbidden)
+		return
+	}
+	// A device hidden from the member is not revealed to exist
+	if !device_service.CanSee(device, caller) {
+		http.Error(w, "device not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	flusher, _ := w.(http.Flusher)
+	stream := &historyStream{w: w, flusher: flusher}
+	err = history.Scan(device.FamilyId, deviceId, from, to, stream.write)
+	if err == nil {
+		_, err = w.Write([]byte(stream.end()))
+	}
+	if err != nil {
+		// The status is sent, the client sees a truncated body
+		fmt.Println("[Location] history stream of ", deviceId, " failed: ", err.Error())
+	}
+}
+
+// historyStream writes the points of a history response as they are scanned
+type historyStream struct {
+	w       http.ResponseWriter
+	flusher http.Flusher
+	points  int
+}
+
+func (this *historyStream) write(l *l8myfamily.Location) error {
+	data, err := webapi.MarshalJSON(l)
+	if err != nil {
+		return err
+	}
+	separator := ","
+	if this.points == 0 {
+		separator = `{"list":[`
+	}
+	if _, err := this.w.Write(append([]byte(separator), data...)); err != nil {
+		return err
+	}
+	this.points++
+	if this.flusher != nil && this.points%historyFlushPoints == 0 {
+		this.flusher.Flush()
+	}
+	return nil
+}
+
+// end returns the closing of the response
+func (this *historyStream) end() string {
+	if this.points == 0 {
+		return `{"list":[]}` + "\n"
+	}
+	return "]}\n"
+}
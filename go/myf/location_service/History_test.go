@@ -0,0 +1,110 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package location_service
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/saichler/l8myfamiliy/go/myf/auth"
+	"github.com/saichler/l8myfamiliy/go/myf/config"
+	"github.com/saichler/l8myfamiliy/go/myf/history"
+	"github.com/saichler/l8myfamiliy/go/types/l8myfamily"
+	"github.com/saichler/l8types/go/ifs"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+func TestHistoryHandler(t *testing.T) {
+	config.Set(&config.ServerConfig{DataDir: t.TempDir(), HistoryMaxWindowSeconds: 3600})
+	origGet, origValidate := getDevice, auth.ValidateToken
+	getDevice = func(id string, vnic ifs.IVNic) *l8myfamily.Device {
+		return &l8myfamily.Device{Id: id, FamilyId: "family"}
+	}
+	auth.ValidateToken = func(token string, vnic ifs.IVNic) (string, bool) {
+		return token, true
+	}
+	t.Cleanup(func() {
+		getDevice, auth.ValidateToken = origGet, origValidate
+		config.Set(config.Default())
+	})
+
+	now := time.Now().UnixMilli()
+	for i := 0; i < 250; i++ {
+		history.Append("family", &l8myfamily.Location{DeviceId: "d1", Latitude: float32(i), Timestamp: now - int64(250-i)*1000,
+			Source: l8myfamily.LocationSource_LOCATION_SOURCE_GEOCLUE})
+	}
+
+	type locationList struct {
+		List []*l8myfamily.Location
+	}
+	get := func(token, query string) (int, *locationList) {
+		r := httptest.NewRequest("GET", "/my-family/history?"+query, nil)
+		r.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		historyHandler(w, r, nil)
+		list := &locationList{}
+		if w.Code == http.StatusOK {
+			body := &struct {
+				List []json.RawMessage `json:"list"`
+			}{}
+			if err := json.Unmarshal(w.Body.Bytes(), body); err != nil {
+				t.Fatalf("invalid streamed body: %v", err)
+			}
+			for _, data := range body.List {
+				l := &l8myfamily.Location{}
+				if err := protojson.Unmarshal(data, l); err != nil {
+					t.Fatalf("invalid streamed point: %v", err)
+				}
+				list.List = append(list.List, l)
+			}
+		}
+		return w.Code, list
+	}
+
+	// The points are protobuf JSON, like the posted locations
+	r := httptest.NewRequest("GET", "/my-family/history?deviceId=d1", nil)
+	r.Header.Set("Authorization", "Bearer family")
+	w := httptest.NewRecorder()
+	historyHandler(w, r, nil)
+	if body := w.Body.String(); !strings.Contains(body, `"deviceId":"d1"`) ||
+		!strings.Contains(body, `"source":"LOCATION_SOURCE_GEOCLUE"`) || strings.Contains(body, `"device_id"`) {
+		t.Fatalf("expected lowerCamelCase keys and enum names, got %s", body)
+	}
+
+	code, list := get("family", "deviceId=d1")
+	if code != http.StatusOK || len(list.List) != 250 || list.List[249].Latitude != 249 {
+		t.Fatalf("unexpected history %d %d", code, len(list.List))
+	}
+	code, list = get("family", fmt.Sprintf("deviceId=d1&from=%d&to=%d", now-10000, now))
+	if code != http.StatusOK || len(list.List) != 10 {
+		t.Fatalf("expected 10 points in the window, got %d %d", code, len(list.List))
+	}
+	code, list = get("family", fmt.Sprintf("deviceId=d1&from=%d&to=%d", now, now))
+	if code != http.StatusOK || len(list.List) != 0 {
+		t.Fatalf("expected an empty list, got %d %d", code, len(list.List))
+	}
+	if code, _ := get("family", fmt.Sprintf("deviceId=d1&from=%d&to=%d", now-7200000, now)); code != http.StatusBadRequest {
+		t.Fatalf("a window beyond the cap must be rejected, got %d", code)
+	}
+	if code, _ := get("stranger", "deviceId=d1"); code != http.StatusForbidden {
+		t.Fatalf("another family device must be forbidden, got %d", code)
+	}
+}
@@ -0,0 +1,341 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package location_service
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/saichler/l8myfamiliy/go/myf/auth"
+	"github.com/saichler/l8myfamiliy/go/myf/config"
+	"github.com/saichler/l8myfamiliy/go/myf/webapi"
+	"github.com/saichler/l8myfamiliy/go/types/l8myfamily"
+	"github.com/saichler/l8types/go/ifs"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	// defaultBusMaxPending is the MaxPending of a location bus without one
+	defaultBusMaxPending = 256
+	// busTimeout bounds the connect and the subscribe to the NATS server
+	busTimeout = 10 * time.Second
+	// busMaxPayload caps a message, a larger one is not a location
+	busMaxPayload = 1024 * 1024
+)
+
+// busMinBackoff and busMaxBackoff bound the wait before a reconnect and
+// before a message the service could not take is submitted again
+var (
+	busMinBackoff = time.Second
+	busMaxBackoff = 30 * time.Second
+)
+
+// locationBus is the consumer of the configured location bus, nil without one
+var locationBus *LocationBus
+
+// BusStats counts the messages of the location bus
+type BusStats struct {
+	// Applied were taken by the service, Rejected failed its checks or did
+	// not decode and Retries counts the submits the service was too busy for
+	Applied  int64 `json:"applied"`
+	Rejected int64 `json:"rejected"`
+	Retries  int64 `json:"retries"`
+}
+
+// LocationBus consumes the Location messages of a NATS subject and posts
+// them to the service like the raw location endpoint. A message the service
+// is too busy for is submitted again until it is taken, the subscription is
+// not read meanwhile, so a slow store slows the consumer down instead of
+// losing locations. Core NATS drops the messages of a subscriber that stays
+// behind, the server reports it as a slow consumer and it is logged.
+type LocationBus struct {
+	cfg     *config.LocationBus
+	vnic    ifs.IVNic
+	caller  *auth.Caller
+	pending chan *l8myfamily.Location
+	done    chan struct{}
+	mtx     sync.Mutex
+	conn    net.Conn
+	readers sync.WaitGroup
+	workers sync.WaitGroup
+
+	applied  atomic.Int64
+	rejected atomic.Int64
+	retries  atomic.Int64
+}
+
+// NewLocationBus returns the consumer of the bus, it is started with Start
+func NewLocationBus(cfg *config.LocationBus, vnic ifs.IVNic) *LocationBus {
+	maxPending := cfg.MaxPending
+	if maxPending <= 0 {
+		maxPending = defaultBusMaxPending
+	}
+	this := &LocationBus{cfg: cfg, vnic: vnic, pending: make(chan *l8myfamily.Location, maxPending),
+		done: make(chan struct{})}
+	// A bus without a family is configured by the operator for any
+	// registered device, its messages post as the server itself
+	this.caller = auth.Internal
+	if cfg.FamilyId != "" {
+		this.caller = &auth.Caller{User: "bus:" + cfg.Subject, FamilyId: cfg.FamilyId}
+	}
+	return this
+}
+
+// Start subscribes in the background, a lost connection is subscribed again
+func (this *LocationBus) Start() {
+	this.readers.Add(1)
+	go this.read()
+	this.workers.Add(1)
+	go this.work()
+}
+
+// Stop unsubscribes and applies the messages already received
+func (this *LocationBus) Stop() {
+	close(this.done)
+	this.mtx.Lock()
+	if this.conn != nil {
+		this.conn.Close()
+	}
+	this.mtx.Unlock()
+	this.readers.Wait()
+	close(this.pending)
+	this.workers.Wait()
+}
+
+// Stats returns the message counters
+func (this *LocationBus) Stats() *BusStats {
+	return &BusStats{Applied: this.applied.Load(), Rejected: this.rejected.Load(), Retries: this.retries.Load()}
+}
+
+// read keeps a subscription and queues its messages for the worker
+func (this *LocationBus) read() {
+	defer this.readers.Done()
+	backoff := busMinBackoff
+	for {
+		reader, err := this.subscribe()
+		if err == nil {
+			fmt.Println("[LocationBus] subscribed to ", this.cfg.Subject)
+			backoff = busMinBackoff
+			err = this.receive(reader)
+		}
+		select {
+		case <-this.done:
+			return
+		default:
+		}
+		fmt.Println("[LocationBus] subscription to ", this.cfg.Subject, " lost, retrying in ", backoff, ": ", err.Error())
+		if !this.sleep(backoff) {
+			return
+		}
+		backoff = min(2*backoff, busMaxBackoff)
+	}
+}
+
+// sleep waits d, it returns false when the bus is stopped meanwhile
+func (this *LocationBus) sleep(d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-this.done:
+		return false
+	}
+}
+
+// subscribe connects to the NATS server and subscribes to the subject
+func (this *LocationBus) subscribe() (*bufio.Reader, error) {
+	u, err := url.Parse(this.cfg.Url)
+	if err != nil {
+		return nil, err
+	}
+	host := u.Host
+	if u.Port() == "" {
+		host = net.JoinHostPort(u.Hostname(), "4222")
+	}
+	conn, err := net.DialTimeout("tcp", host, busTimeout)
+	if err != nil {
+		return nil, err
+	}
+	this.mtx.Lock()
+	select {
+	case <-this.done:
+		this.mtx.Unlock()
+		conn.Close()
+		return nil, fmt.Errorf("stopped")
+	default:
+	}
+	this.conn = conn
+	this.mtx.Unlock()
+
+	conn.SetDeadline(time.Now().Add(busTimeout))
+	reader := bufio.NewReader(conn)
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if !strings.HasPrefix(line, "INFO ") {
+		conn.Close()
+		return nil, fmt.Errorf("%s is not a nats server", host)
+	}
+	options := map[string]interface{}{"verbose": false, "pedantic": false, "lang": "go", "name": "my-family"}
+	if u.User != nil {
+		if pass, ok := u.User.Password(); ok {
+			options["user"], options["pass"] = u.User.Username(), pass
+		} else {
+			options["auth_token"] = u.User.Username()
+		}
+	}
+	data, _ := json.Marshal(options)
+	sub := "SUB " + this.cfg.Subject
+	if this.cfg.QueueGroup != "" {
+		sub += " " + this.cfg.QueueGroup
+	}
+	fmt.Fprintf(conn, "CONNECT %s\r\n%s 1\r\nPING\r\n", data, sub)
+	// A rejected CONNECT or SUB is answered -ERR instead of the PONG
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			conn.Close()
+			return nil, err
+		}
+		line = strings.TrimRight(line, "\r\n")
+		if line == "PONG" {
+			break
+		}
+		if strings.HasPrefix(line, "-ERR") {
+			conn.Close()
+			return nil, fmt.Errorf("nats server rejected the subscription: %s", strings.TrimSpace(line[4:]))
+		}
+	}
+	conn.SetDeadline(time.Time{})
+	return reader, nil
+}
+
+// receive queues the messages of the subscription until the connection fails,
+// a full queue is waited for
+func (this *LocationBus) receive(reader *bufio.Reader) error {
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return err
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		switch fields[0] {
+		case "PING":
+			this.mtx.Lock()
+			_, err = this.conn.Write([]byte("PONG\r\n"))
+			this.mtx.Unlock()
+			if err != nil {
+				return err
+			}
+		case "-ERR":
+			return fmt.Errorf("nats server error: %s", strings.TrimSpace(line[4:]))
+		case "MSG":
+			// MSG <subject> <sid> [reply-to] <size>
+			if len(fields) < 4 {
+				return fmt.Errorf("malformed message %q", strings.TrimSpace(line))
+			}
+			size, err := strconv.Atoi(fields[len(fields)-1])
+			if err != nil || size < 0 || size > busMaxPayload {
+				return fmt.Errorf("malformed message %q", strings.TrimSpace(line))
+			}
+			data := make([]byte, size+2)
+			if _, err := io.ReadFull(reader, data); err != nil {
+				return err
+			}
+			l, err := this.decode(data[:size])
+			if err != nil {
+				this.rejected.Add(1)
+				fmt.Println("[LocationBus] skipping a message of ", fields[1], ": ", err.Error())
+				continue
+			}
+			select {
+			case this.pending <- l:
+			case <-this.done:
+				return fmt.Errorf("stopped")
+			}
+		}
+	}
+}
+
+func (this *LocationBus) decode(data []byte) (*l8myfamily.Location, error) {
+	l := &l8myfamily.Location{}
+	if this.cfg.Encoding == config.BusEncodingJSON {
+		return l, protojson.UnmarshalOptions{DiscardUnknown: true}.Unmarshal(data, l)
+	}
+	return l, proto.Unmarshal(data, l)
+}
+
+// work applies the queued locations in order
+func (this *LocationBus) work() {
+	defer this.workers.Done()
+	for l := range this.pending {
+		this.apply(l)
+	}
+}
+
+// apply submits the location like a post of the bus family, a submit the
+// service is too busy for is repeated until it is taken or the bus stops
+func (this *LocationBus) apply(l *l8myfamily.Location) {
+	backoff := busMinBackoff
+	for {
+		err := this.submit(l)
+		if err == nil {
+			this.applied.Add(1)
+			return
+		}
+		if webapi.ErrorStatus(err) != http.StatusServiceUnavailable {
+			this.rejected.Add(1)
+			fmt.Println("[LocationBus] rejecting ", l.DeviceId, ": ", err.Error())
+			return
+		}
+		this.retries.Add(1)
+		fmt.Println("[LocationBus] service busy, retrying ", l.DeviceId, " in ", backoff, ": ", err.Error())
+		if !this.sleep(backoff) {
+			this.rejected.Add(1)
+			fmt.Println("[LocationBus] stopped, the location of ", l.DeviceId, " is not applied")
+			return
+		}
+		backoff = min(2*backoff, busMaxBackoff)
+	}
+}
+
+func (this *LocationBus) submit(l *l8myfamily.Location) error {
+	if this.caller != auth.Internal {
+		// A device of another family is rejected by the callback
+		if _, err := deviceOrRegister(l, this.caller, this.vnic); err != nil {
+			return err
+		}
+	}
+	defer auth.Bind(l, this.caller)()
+	return submitLocation(l, this.vnic)
+}
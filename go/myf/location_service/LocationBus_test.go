@@ -0,0 +1,187 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package location_service
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/saichler/l8myfamiliy/go/myf/auth"
+	"github.com/saichler/l8myfamiliy/go/myf/config"
+	"github.com/saichler/l8myfamiliy/go/myf/webapi"
+	"github.com/saichler/l8myfamiliy/go/types/l8myfamily"
+	"github.com/saichler/l8types/go/ifs"
+	"google.golang.org/protobuf/proto"
+)
+
+// fakeNats accepts subscribers and delivers the messages published to it
+type fakeNats struct {
+	listener net.Listener
+	subs     chan net.Conn
+}
+
+func newFakeNats(t *testing.T) *fakeNats {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	this := &fakeNats{listener: listener, subs: make(chan net.Conn, 4)}
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			t.Cleanup(func() { conn.Close() })
+			go this.serve(conn)
+		}
+	}()
+	return this
+}
+
+func (this *fakeNats) serve(conn net.Conn) {
+	conn.Write([]byte("INFO {\"server_id\":\"test\"}\r\n"))
+	reader := bufio.NewReader(conn)
+	subscribed := false
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		switch {
+		case strings.HasPrefix(line, "SUB "):
+			subscribed = true
+		case strings.HasPrefix(line, "PING"):
+			conn.Write([]byte("PONG\r\n"))
+			if subscribed {
+				this.subs <- conn
+				subscribed = false
+			}
+		}
+	}
+}
+
+// subscriber waits for the next subscription
+func (this *fakeNats) subscriber(t *testing.T) net.Conn {
+	select {
+	case conn := <-this.subs:
+		return conn
+	case <-time.After(5 * time.Second):
+		t.Fatal("no subscription")
+	}
+	return nil
+}
+
+func publish(conn net.Conn, data []byte) {
+	fmt.Fprintf(conn, "MSG family.locations 1 %d\r\n%s\r\n", len(data), data)
+}
+
+func locationMessage(latitude float32) []byte {
+	data, _ := proto.Marshal(&l8myfamily.Location{DeviceId: "bus-device", Latitude: latitude})
+	return data
+}
+
+func TestLocationBusAppliesEveryMessage(t *testing.T) {
+	nats := newFakeNats(t)
+	mtx := &sync.Mutex{}
+	gate := make(chan struct{})
+	busy := 2
+	var latitudes []float32
+	origSubmit, origGet, origBackoff := submitLocation, getDevice, busMinBackoff
+	submitLocation = func(l *l8myfamily.Location, vnic ifs.IVNic) error {
+		<-gate
+		mtx.Lock()
+		defer mtx.Unlock()
+		if caller := auth.CallerOf(l); caller == nil || caller.FamilyId != "fam" {
+			t.Errorf("the callbacks must see the bus family, got %v", caller)
+		}
+		// The store is slow, the first submits are answered busy
+		if busy > 0 {
+			busy--
+			return webapi.ErrOverloaded
+		}
+		latitudes = append(latitudes, l.Latitude)
+		return nil
+	}
+	getDevice = func(id string, vnic ifs.IVNic) *l8myfamily.Device {
+		return &l8myfamily.Device{Id: id, FamilyId: "fam"}
+	}
+	busMinBackoff = time.Millisecond
+	defer func() { submitLocation, getDevice, busMinBackoff = origSubmit, origGet, origBackoff }()
+
+	bus := NewLocationBus(&config.LocationBus{Url: "nats://" + nats.listener.Addr().String(), Subject: "family.locations",
+		FamilyId: "fam", MaxPending: 1}, nil)
+	bus.Start()
+	conn := nats.subscriber(t)
+	// More messages than the queue holds arrive while the service is stuck
+	for i := 1; i <= 5; i++ {
+		publish(conn, locationMessage(float32(i)))
+	}
+	publish(conn, []byte{0xff})
+	close(gate)
+	// A lost connection is subscribed again
+	conn.Close()
+	publish(nats.subscriber(t), locationMessage(6))
+
+	deadline := time.Now().Add(5 * time.Second)
+	for bus.Stats().Applied < 6 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	bus.Stop()
+	mtx.Lock()
+	defer mtx.Unlock()
+	if len(latitudes) != 6 || latitudes[0] != 1 || latitudes[5] != 6 {
+		t.Fatalf("expected every location applied in order, got %v", latitudes)
+	}
+	if stats := bus.Stats(); stats.Applied != 6 || stats.Retries != 2 || stats.Rejected != 1 {
+		t.Fatalf("unexpected stats %+v", stats)
+	}
+}
+
+func TestLocationBusRejectsOtherFamilies(t *testing.T) {
+	nats := newFakeNats(t)
+	origGet, origStorageDegraded := getDevice, storageDegraded
+	getDevice = func(id string, vnic ifs.IVNic) *l8myfamily.Device {
+		return &l8myfamily.Device{Id: id, FamilyId: "other"}
+	}
+	storageDegraded = func() bool { return false }
+	origSubmit := submitLocation
+	// The service runs the Before callback, like the service endpoint
+	submitLocation = func(l *l8myfamily.Location, vnic ifs.IVNic) error {
+		_, _, err := (&LocationCallback{}).Before(l, ifs.POST, false, vnic)
+		return err
+	}
+	defer func() { getDevice, storageDegraded, submitLocation = origGet, origStorageDegraded, origSubmit }()
+
+	bus := NewLocationBus(&config.LocationBus{Url: "nats://" + nats.listener.Addr().String(), Subject: "family.locations",
+		FamilyId: "fam"}, nil)
+	bus.Start()
+	publish(nats.subscriber(t), locationMessage(1))
+	deadline := time.Now().Add(5 * time.Second)
+	for bus.Stats().Rejected < 1 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	bus.Stop()
+	if stats := bus.Stats(); stats.Rejected != 1 || stats.Applied != 0 {
+		t.Fatalf("a device of another family must be rejected, got %+v", stats)
+	}
+}
@@ -0,0 +1,175 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package location_service
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/saichler/l8myfamiliy/go/myf/audit"
+	"github.com/saichler/l8myfamiliy/go/myf/auth"
+	"github.com/saichler/l8myfamiliy/go/myf/config"
+	"github.com/saichler/l8myfamiliy/go/myf/webapi"
+	"github.com/saichler/l8myfamiliy/go/types/l8myfamily"
+	"github.com/saichler/l8srlz/go/serialize/object"
+	"github.com/saichler/l8types/go/ifs"
+)
+
+// ManualLocation is the body of the manual location override
+type ManualLocation struct {
+	DeviceId  string  `json:"deviceId"`
+	Latitude  float32 `json:"latitude"`
+	Longitude float32 `json:"longitude"`
+}
+
+// RegisterHandlers registers the raw location endpoints that are not served by
+// the service framework
+func RegisterHandlers(mux *http.ServeMux, vnic ifs.IVNic) {
+	mux.HandleFunc(webapi.AdminPrefix+"location", webapi.LimitBody(func(w http.ResponseWriter, r *http.Request) {
+		manualLocationHandler(w, r, vnic)
+	}))
+	mux.HandleFunc(webapi.Prefix+"location", webapi.LimitBody(func(w http.ResponseWriter, r *http.Request) {
+		postLocationHandler(w, r, vnic)
+	}))
+	mux.HandleFunc(webapi.Prefix+"geofence-events", webapi.Gzip(func(w http.ResponseWriter, r *http.Request) {
+		geofenceEventsHandler(w, r, vnic)
+	}))
+	mux.HandleFunc(webapi.Prefix+"history", webapi.Gzip(func(w http.ResponseWriter, r *http.Request) {
+		historyHandler(w, r, vnic)
+	}))
+	mux.HandleFunc(webapi.Prefix+"export", webapi.Gzip(func(w http.ResponseWriter, r *http.Request) {
+		exportHandler(w, r, vnic)
+	}))
+}
+
+// submitLocation posts the location to the Location service, so it runs the
+// same callbacks as a post to the service endpoint. It is a variable so tests
+// can run the handler without a service topology.
+var submitLocation = func(l *l8myfamily.Location, vnic ifs.IVNic) error {
+	sv, ok := vnic.Resources().Services().ServiceHandler(ServiceName, ServiceArea)
+	if !ok {
+		return fmt.Errorf("location service is not active")
+	}
+	resp := sv.Post(object.New(nil, l), vnic)
+	if resp != nil && resp.Error() != nil {
+		return resp.Error()
+	}
+	return nil
+}
+
+// postLocationHandler accepts a location post in JSON or, for agents on metered
+// connections, in the compact protobuf wire format, chosen by the Content-Type.
+func postLocationHandler(w http.ResponseWriter, r *http.Request, vnic ifs.IVNic) {
+	if !webapi.RequireMethod(w, r, http.MethodPost) {
+		return
+	}
+	caller := auth.Authenticate(w, r, vnic)
+	if caller == nil {
+		return
+	}
+	l := &l8myfamily.Location{}
+	if err := webapi.DecodeBody(r, l); err != nil {
+		http.Error(w, err.Error(), webapi.DecodeStatus(err))
+		return
+	}
+	device, err := deviceOrRegister(l, caller, vnic)
+	if err != nil {
+		http.Error(w, err.Error(), webapi.ErrorStatus(err))
+		return
+	}
+	if device == nil {
+		http.Error(w, webapi.ErrUnknownDevice.Error(), http.StatusNotFound)
+		return
+	}
+	defer auth.Bind(l, caller)()
+	if err := submitLocation(l, vnic); err != nil {
+		http.Error(w, err.Error(), webapi.ErrorStatus(err))
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// deviceOrRegister returns the device of the location, with the create
+// unknown device policy a device that is not registered is created in the
+// caller family. It returns nil for an unregistered device otherwise, and
+// ErrOtherFamily for a device of a family the caller may not post for, the
+// same rule as the Location callback, checked before a device is created.
+func deviceOrRegister(l *l8myfamily.Location, caller *auth.Caller, vnic ifs.IVNic) (*l8myfamily.Device, error) {
+	device := getDevice(l.DeviceId, vnic)
+	if device != nil {
+		if !caller.MayAccess(device.FamilyId) {
+			return nil, webapi.ErrOtherFamily
+		}
+		return device, nil
+	}
+	if l.DeviceId == "" || !config.CreateUnknownDevices() {
+		return nil, nil
+	}
+	device = &l8myfamily.Device{Id: l.DeviceId, FamilyId: caller.FamilyId, Name: l.DeviceId}
+	if !caller.MayAccess(device.FamilyId) {
+		return nil, webapi.ErrOtherFamily
+	}
+	release := auth.Bind(device, caller)
+	err := createDevice(device, vnic)
+	release()
+	if err != nil {
+		return nil, err
+	}
+	audit.Log("auto-register", caller.User, device.Id, device.FamilyId, "")
+	return device, nil
+}
+
+// manualLocationHandler lets an admin set a device position without an agent,
+// e.g. to correct a bad GeoIP fix. The position is written to the device store
+// and then goes through the same post update processing as an agent post.
+func manualLocationHandler(w http.ResponseWriter, r *http.Request, vnic ifs.IVNic) {
+	if !webapi.RequireMethod(w, r, http.MethodPost) {
+		return
+	}
+	caller := auth.AuthenticateAdmin(w, r, vnic)
+	if caller == nil {
+		return
+	}
+	body := &ManualLocation{}
+	if err := json.NewDecoder(r.Body).Decode(body); err != nil {
+		http.Error(w, "invalid body: "+err.Error(), webapi.DecodeStatus(err))
+		return
+	}
+	if body.DeviceId == "" || body.Latitude < -90 || body.Latitude > 90 ||
+		body.Longitude < -180 || body.Longitude > 180 {
+		http.Error(w, "invalid device id or coordinates", http.StatusBadRequest)
+		return
+	}
+	l := &l8myfamily.Location{
+		DeviceId:  body.DeviceId,
+		Latitude:  body.Latitude,
+		Longitude: body.Longitude,
+		Source:    l8myfamily.LocationSource_LOCATION_SOURCE_MANUAL,
+		Timestamp: time.Now().UnixMilli(),
+	}
+	previous := positionOf(getDevice(l.DeviceId, vnic))
+	device := updateDevice(l, vnic)
+	if device == nil {
+		http.Error(w, "device not found", http.StatusNotFound)
+		return
+	}
+	afterUpdate(l, device, previous)
+	audit.Log("manual-location", caller.User, device.Id, device.FamilyId,
+		fmt.Sprintf("lat=%f lon=%f", l.Latitude, l.Longitude))
+	webapi.WriteJSON(w, http.StatusOK, device)
+}
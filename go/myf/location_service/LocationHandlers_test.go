@@ -0,0 +1,200 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package location_service
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/saichler/l8myfamiliy/go/myf/audit"
+	"github.com/saichler/l8myfamiliy/go/myf/auth"
+	"github.com/saichler/l8myfamiliy/go/myf/config"
+	"github.com/saichler/l8myfamiliy/go/myf/webapi"
+	"github.com/saichler/l8myfamiliy/go/types/l8myfamily"
+	"github.com/saichler/l8types/go/ifs"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestPostLocationHandlerEncodings(t *testing.T) {
+	var submitted []*l8myfamily.Location
+	origSubmit, origGet, origValidate := submitLocation, getDevice, auth.ValidateToken
+	submitLocation = func(l *l8myfamily.Location, vnic ifs.IVNic) error {
+		if caller := auth.CallerOf(l); caller == nil || (caller.FamilyId != "family" && !caller.Admin) {
+			t.Errorf("the callbacks must see the caller of the post, got %v", caller)
+		}
+		submitted = append(submitted, l)
+		return nil
+	}
+	getDevice = func(id string, vnic ifs.IVNic) *l8myfamily.Device {
+		return &l8myfamily.Device{Id: id, FamilyId: "family"}
+	}
+	auth.ValidateToken = func(token string, vnic ifs.IVNic) (string, bool) {
+		return token, true
+	}
+	cfg := config.Default()
+	cfg.AdminUsers = []string{"root"}
+	config.Set(cfg)
+	defer func() {
+		submitLocation, getDevice, auth.ValidateToken = origSubmit, origGet, origValidate
+		config.Set(config.Default())
+	}()
+
+	location := &l8myfamily.Location{DeviceId: "d1", Latitude: 32.1, Longitude: 34.8,
+		Source: l8myfamily.LocationSource_LOCATION_SOURCE_ANDROID, Accuracy: 8}
+	post := func(contentType, token string) int {
+		data, _ := webapi.EncodeBody(contentType, location)
+		r := httptest.NewRequest("POST", "/my-family/location", bytes.NewReader(data))
+		r.Header.Set("Content-Type", contentType)
+		r.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		postLocationHandler(w, r, nil)
+		return w.Code
+	}
+
+	for _, contentType := range []string{webapi.ContentTypeJSON, webapi.ContentTypeProtobuf} {
+		if code := post(contentType, "family"); code != http.StatusOK {
+			t.Fatalf("%s: expected 200, got %d", contentType, code)
+		}
+		if !proto.Equal(submitted[len(submitted)-1], location) {
+			t.Fatalf("%s: expected %v got %v", contentType, location, submitted[len(submitted)-1])
+		}
+	}
+	if code := post(webapi.ContentTypeProtobuf, "stranger"); code != http.StatusForbidden {
+		t.Fatalf("a post for another family device must be forbidden, got %d", code)
+	}
+	// The handler authorizes like the Location callback, an admin may post
+	if code := post(webapi.ContentTypeJSON, "root"); code != http.StatusOK {
+		t.Fatalf("an admin may post for any family, got %d", code)
+	}
+	if code := post("text/plain", "family"); code != http.StatusUnsupportedMediaType {
+		t.Fatalf("expected 415, got %d", code)
+	}
+}
+
+func TestPostLocationUnknownDevice(t *testing.T) {
+	devices := map[string]*l8myfamily.Device{}
+	submitted := 0
+	origSubmit, origGet, origCreate, origValidate := submitLocation, getDevice, createDevice, auth.ValidateToken
+	submitLocation = func(l *l8myfamily.Location, vnic ifs.IVNic) error {
+		submitted++
+		return nil
+	}
+	getDevice = func(id string, vnic ifs.IVNic) *l8myfamily.Device {
+		return devices[id]
+	}
+	createDevice = func(device *l8myfamily.Device, vnic ifs.IVNic) error {
+		devices[device.Id] = device
+		return nil
+	}
+	auth.ValidateToken = func(token string, vnic ifs.IVNic) (string, bool) {
+		return token, true
+	}
+	defer func() {
+		submitLocation, getDevice, createDevice, auth.ValidateToken = origSubmit, origGet, origCreate, origValidate
+		config.Set(config.Default())
+	}()
+
+	postAs := func(token, deviceId string) int {
+		data, _ := webapi.EncodeBody(webapi.ContentTypeJSON, &l8myfamily.Location{DeviceId: deviceId, Latitude: 1})
+		r := httptest.NewRequest("POST", "/my-family/location", bytes.NewReader(data))
+		r.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		postLocationHandler(w, r, nil)
+		return w.Code
+	}
+	post := func() int { return postAs("family", "new") }
+
+	config.Set(&config.ServerConfig{DataDir: t.TempDir(), UnknownDevicePolicy: config.UnknownDeviceReject})
+	if code := post(); code != http.StatusNotFound || submitted != 0 || len(devices) != 0 {
+		t.Fatalf("an unknown device must get a 404, got %d", code)
+	}
+
+	config.Set(&config.ServerConfig{DataDir: t.TempDir(), UnknownDevicePolicy: config.UnknownDeviceCreate})
+	if code := post(); code != http.StatusOK || submitted != 1 {
+		t.Fatalf("an unknown device must be registered and its location posted, got %d", code)
+	}
+	if device := devices["new"]; device == nil || device.FamilyId != "family" {
+		t.Fatalf("expected a device in the caller family, got %v", device)
+	}
+
+	// The family is checked before a device is created, with the rule of the
+	// Location callback
+	if code := postAs("stranger", "new"); code != http.StatusForbidden || submitted != 1 || devices["new"].FamilyId != "family" {
+		t.Fatalf("a post for a device of another family must be forbidden, got %d", code)
+	}
+	config.Set(&config.ServerConfig{DataDir: t.TempDir(), UnknownDevicePolicy: config.UnknownDeviceCreate, AdminUsers: []string{"root"}})
+	if code := postAs("root", "new"); code != http.StatusOK || submitted != 2 || len(devices) != 1 {
+		t.Fatalf("an admin may post for a device of any family, got %d", code)
+	}
+	if code := postAs("root", "spare"); code != http.StatusOK || submitted != 3 {
+		t.Fatalf("the post that registers a device of the admin must be accepted, got %d", code)
+	}
+	if device := devices["spare"]; device == nil || device.FamilyId != "root" {
+		t.Fatalf("expected a device in the admin family, got %v", device)
+	}
+}
+
+func TestManualLocationHandler(t *testing.T) {
+	origUpdate, origGet, origValidate := updateDevice, getDevice, auth.ValidateToken
+	var stored *l8myfamily.Device
+	updateDevice = func(l *l8myfamily.Location, vnic ifs.IVNic) *l8myfamily.Device {
+		stored = &l8myfamily.Device{Id: l.DeviceId, FamilyId: "family",
+			Latitude: l.Latitude, Longitude: l.Longitude, Source: l.Source}
+		return stored
+	}
+	getDevice = func(id string, vnic ifs.IVNic) *l8myfamily.Device {
+		return &l8myfamily.Device{Id: id, FamilyId: "family"}
+	}
+	auth.ValidateToken = func(token string, vnic ifs.IVNic) (string, bool) {
+		return token, true
+	}
+	config.Set(&config.ServerConfig{DataDir: t.TempDir(), AdminUsers: []string{"root"}})
+	defer func() {
+		updateDevice, getDevice, auth.ValidateToken = origUpdate, origGet, origValidate
+		config.Set(config.Default())
+	}()
+
+	post := func(token string) int {
+		data, _ := json.Marshal(&ManualLocation{DeviceId: "d1", Latitude: 32.1, Longitude: 34.8})
+		r := httptest.NewRequest("POST", "/my-family/admin/location", bytes.NewReader(data))
+		r.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		manualLocationHandler(w, r, nil)
+		return w.Code
+	}
+
+	if code := post("family"); code != http.StatusForbidden || stored != nil {
+		t.Fatalf("a non admin must not set a position, got %d", code)
+	}
+	if code := post("root"); code != http.StatusOK {
+		t.Fatalf("expected 200 for an admin, got %d", code)
+	}
+	if stored == nil || stored.Source != l8myfamily.LocationSource_LOCATION_SOURCE_MANUAL ||
+		stored.Latitude != 32.1 || stored.Longitude != 34.8 {
+		t.Fatalf("expected the posted manual position to be stored, got %v", stored)
+	}
+	records, err := audit.Read()
+	if err != nil || len(records) != 1 {
+		t.Fatalf("expected one audit record, got %v %v", records, err)
+	}
+	if record := records[0]; record.Action != "manual-location" || record.Actor != "root" ||
+		record.DeviceId != "d1" || record.FamilyId != "family" {
+		t.Fatalf("unexpected audit record %+v", record)
+	}
+}
@@ -16,7 +16,15 @@
 package location_service
 
 import (
+	"fmt"
+	"time"
+
+	"github.com/saichler/l8myfamiliy/go/myf/auth"
+	"github.com/saichler/l8myfamiliy/go/myf/config"
 	"github.com/saichler/l8myfamiliy/go/myf/device_service"
+	"github.com/saichler/l8myfamiliy/go/myf/geo"
+	"github.com/saichler/l8myfamiliy/go/myf/history"
+	"github.com/saichler/l8myfamiliy/go/myf/webapi"
 	"github.com/saichler/l8myfamiliy/go/types/l8myfamily"
 	"github.com/saichler/l8services/go/services/base"
 	"github.com/saichler/l8types/go/ifs"
@@ -26,11 +34,51 @@ import (
 )
 
 const (
-	ServiceName = "Location"
-	ServiceArea = byte(53)
+	DefaultServiceName = "Location"
+	DefaultServiceArea = byte(53)
+)
+
+// primaryKey is the Location field the locations are keyed by
+const primaryKey = "DeviceId"
+
+// ServiceName and ServiceArea are the name and area the service was
+// activated with, they default to DefaultServiceName and DefaultServiceArea
+var (
+	ServiceName = DefaultServiceName
+	ServiceArea = DefaultServiceArea
 )
 
-func Activate(vnic ifs.IVNic) {
+// updateQueue applies the posted locations to the devices in the background,
+// the number of shards bounds the concurrent device read-modify-writes.
+// When nil, e.g. in tests, locations are applied synchronously.
+var updateQueue *UpdateQueue
+
+// postKeys remembers the idempotency keys of recent location posts
+var postKeys = webapi.NewIdempotencyCache(config.IdempotencyWindow())
+
+// Activate registers the service under the given name and area, an empty
+// name means DefaultServiceName. It returns an error, before anything is
+// registered, when the service configuration is invalid.
+func Activate(serviceName string, serviceArea byte, vnic ifs.IVNic) error {
+	if serviceName == "" {
+		serviceName = DefaultServiceName
+	}
+	if err := webapi.ValidateService(serviceName, serviceArea, &l8myfamily.Location{}, primaryKey); err != nil {
+		return err
+	}
+	ServiceName = serviceName
+	ServiceArea = serviceArea
+	cfg := config.Get()
+	postKeys = webapi.NewIdempotencyCache(config.IdempotencyWindow())
+	nonces = webapi.NewIdempotencyCache(config.ReplayWindow())
+	lowBattery = NewLowBatteryTracker()
+	dailyExits = NewDailyExitTracker()
+	familyRates = NewFamilyRateLimiter()
+	movements = NewMovementTracker()
+	updateQueue = NewUpdateQueue(cfg.DeviceUpdateConcurrency, cfg.DeviceUpdateQueueSize,
+		time.Duration(cfg.DeviceUpdateQueueMillis)*time.Millisecond,
+		time.Duration(cfg.DeviceUpdateFlushMillis)*time.Millisecond, vnic)
+
 	serviceConfig := ifs.NewServiceLevelAgreement(&base.BaseService{}, ServiceName, ServiceArea, true, &LocationCallback{})
 
 	services := &l8services.L8Services{}
@@ -43,22 +91,181 @@ func Activate(vnic ifs.IVNic) {
 
 	serviceConfig.SetVoter(true)
 	serviceConfig.SetTransactional(false)
-	serviceConfig.SetPrimaryKeys("DeviceId")
+	serviceConfig.SetPrimaryKeys(primaryKey)
 	webs := web.New(ServiceName, ServiceArea, 0)
 	webs.AddEndpoint(&l8myfamily.Location{}, ifs.POST, &l8web.L8Empty{})
 	base.Activate(serviceConfig, vnic)
+	history.StartPruning()
+	if cfg.LocationBus != nil {
+		locationBus = NewLocationBus(cfg.LocationBus, vnic)
+		locationBus.Start()
+	}
+	return nil
+}
+
+// Shutdown stops the location bus and drains the pending device updates
+func Shutdown() {
+	if locationBus != nil {
+		locationBus.Stop()
+		locationBus = nil
+	}
+	if updateQueue != nil {
+		updateQueue.Shutdown()
+	}
 }
 
 type LocationCallback struct{}
 
+// updateDevice, updateDevices, getDevice, createDevice, deviceAllowed and
+// storageDegraded are variables so tests can run the callbacks without a
+// service topology
+var (
+	updateDevice    = device_service.UpdateDevice
+	updateDevices   = device_service.UpdateDevices
+	getDevice       = device_service.GetDevice
+	createDevice    = device_service.CreateDevice
+	deviceAllowed   = device_service.DeviceAllowed
+	storageDegraded = device_service.StorageDegraded
+)
+
 func (lc *LocationCallback) Before(elem interface{}, action ifs.Action, notify bool, vnic ifs.IVNic) (interface{}, bool, error) {
+	if action == ifs.POST || action == ifs.PUT {
+		l := elem.(*l8myfamily.Location)
+		// The schema version is for the decoding only, it is not stored
+		l.SchemaVersion = 0
+		// A post to the service endpoint has no caller, the agents post to
+		// the raw location endpoint that authenticates them
+		caller := auth.CallerOf(l)
+		if caller == nil {
+			fmt.Println("[Location] rejecting ", l.DeviceId, ", the post has no authenticated caller")
+			return nil, false, webapi.ErrUnauthenticated
+		}
+		// The agent buffers the location until the store can write again,
+		// before the key is remembered so its retry is not a duplicate.
+		// An emergency is still alerted.
+		if !l.Emergency && storageDegraded() {
+			return nil, false, webapi.ErrStorageUnavailable
+		}
+		device := getDevice(l.DeviceId, vnic)
+		// The location of an unregistered device would be dropped, the agent
+		// is told to register. An emergency is still alerted.
+		if device == nil && !l.Emergency {
+			fmt.Println("[Location] rejecting ", l.DeviceId, ", the device is not registered")
+			return nil, false, webapi.ErrUnknownDevice
+		}
+		if device != nil && !caller.MayAccess(device.FamilyId) {
+			fmt.Println("[Location] rejecting ", l.DeviceId, ", the caller is not of family ", device.FamilyId)
+			return nil, false, webapi.ErrOtherFamily
+		}
+		if device != nil && !deviceAllowed(device.FamilyId, l.DeviceId) {
+			fmt.Println("[Location] rejecting ", l.DeviceId, ", not on the allowlist of family ", device.FamilyId)
+			return nil, false, webapi.ErrNotAllowed
+		}
+		// An outdated agent is told to upgrade, its emergency is still alerted
+		if device != nil && !l.Emergency && !config.AgentVersionAllowed(device.AgentVersion) {
+			fmt.Println("[Location] rejecting ", l.DeviceId, ", agent version ", device.AgentVersion, " is below ",
+				config.Get().MinAgentVersion)
+			return nil, false, fmt.Errorf("%w: %q is below %s", webapi.ErrAgentOutdated, device.AgentVersion,
+				config.Get().MinAgentVersion)
+		}
+		// Over the family ceiling the post is rejected before its key is
+		// remembered, so the agent retry is not a duplicate. An emergency is
+		// never capped.
+		if device != nil && !l.Emergency && !familyRates.Allow(device.FamilyId, l.DeviceId, time.Now()) {
+			fmt.Println("[Location] rejecting ", l.DeviceId, ", family ", device.FamilyId, " is over its location rate")
+			return nil, false, webapi.ErrRateLimited
+		}
+		// A retry of a post that was already applied is acknowledged without
+		// processing it again. The key is remembered in After, once the
+		// location is queued, so the retry of a post rejected meanwhile, e.g.
+		// by the signature or a full queue, is processed.
+		if l.IdempotencyKey != "" && postKeys.Contains(l.DeviceId+"/"+l.IdempotencyKey) {
+			fmt.Println("[Location] duplicate post ", l.IdempotencyKey, " from ", l.DeviceId)
+			return nil, false, nil
+		}
+		if err := verifySignature(l, time.Now()); err != nil {
+			fmt.Println("[Location] rejecting ", l.DeviceId, ": ", err.Error())
+			return nil, false, webapi.ErrBadSignature
+		}
+		// A heartbeat-only device never has a position, a coordinate that
+		// leaks through is dropped before the history and the geofences
+		if device != nil && device.HeartbeatOnly {
+			device_service.StripPosition(l)
+		}
+		// An emergency post is safety critical and is never suppressed
+		// or made less precise
+		if l.Emergency || l.Heartbeat {
+			return nil, true, nil
+		}
+		if device != nil && device.PrecisionMeters > 0 {
+			lat, lon := geo.RoundToGrid(float64(l.Latitude), float64(l.Longitude), float64(device.PrecisionMeters))
+			l.Latitude = float32(lat)
+			l.Longitude = float32(lon)
+		}
+	}
 	return nil, true, nil
 }
 
 func (lc *LocationCallback) After(elem interface{}, action ifs.Action, notify bool, vnic ifs.IVNic) (interface{}, bool, error) {
 	if action == ifs.POST || action == ifs.PUT {
 		l := elem.(*l8myfamily.Location)
-		device_service.UpdateDevice(l.DeviceId, l.Longitude, l.Latitude, vnic)
+		// The key is only for the request, it is not stored
+		key := l.IdempotencyKey
+		l.IdempotencyKey = ""
+		if l.Timestamp == 0 {
+			l.Timestamp = time.Now().UnixMilli()
+		}
+		snapToRoad(l, vnic)
+		l.Movement = movements.Classify(l)
+		if updateQueue == nil {
+			// The stored position is read before the update moves it
+			previous := positionOf(getDevice(l.DeviceId, vnic))
+			afterUpdate(l, updateDevice(l, vnic), previous)
+			rememberKey(l.DeviceId, key)
+			return nil, true, nil
+		}
+		if err := updateQueue.Enqueue(l); err != nil {
+			fmt.Println("[Location] rejecting ", l.DeviceId, ": ", err.Error())
+			return nil, false, err
+		}
+		rememberKey(l.DeviceId, key)
 	}
 	return nil, true, nil
 }
+
+// rememberKey records the idempotency key of an accepted post, so its retry
+// is acknowledged as a duplicate
+func rememberKey(deviceId, key string) {
+	if key != "" {
+		postKeys.Remember(deviceId + "/" + key)
+	}
+}
+
+// afterUpdate runs the processing that follows a device position change,
+// device is nil when the location belongs to an unregistered device and
+// previous is the device position before the location
+func afterUpdate(l *l8myfamily.Location, device *l8myfamily.Device, previous position) {
+	lowBattery.Check(l, device)
+	// A heartbeat carries no position, the emergency of a heartbeat-only
+	// device is still alerted
+	if device != nil && !l.Heartbeat {
+		if err := history.Append(device.FamilyId, l); err != nil {
+			fmt.Println("[History] failed to append for ", l.DeviceId, ": ", err.Error())
+		}
+		// An emergency post skips the agent geofence filter, it is not an exit
+		if !l.Emergency {
+			if _, err := history.RecordGeofence(device.FamilyId, l); err != nil {
+				fmt.Println("[History] failed to record geofence for ", l.DeviceId, ": ", err.Error())
+			}
+		}
+		evaluateGeofences(device, previous, l)
+	}
+	if l.Emergency {
+		// A retry of an emergency the family already cleared is not alerted again
+		if device_service.EmergencyCleared(device, l) {
+			fmt.Println("[Emergency] ", l.DeviceId, " was cleared after this post, not alerted")
+			return
+		}
+		onEmergency(l, device)
+	}
+}
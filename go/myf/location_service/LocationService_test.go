@@ -0,0 +1,198 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package location_service
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/saichler/l8myfamiliy/go/myf/auth"
+	"github.com/saichler/l8myfamiliy/go/myf/config"
+	"github.com/saichler/l8myfamiliy/go/myf/webapi"
+	"github.com/saichler/l8myfamiliy/go/types/l8myfamily"
+	"github.com/saichler/l8types/go/ifs"
+)
+
+// postInternal runs the Before callback of a post bound to the internal
+// caller, like a post the server makes on its own behalf
+func postInternal(l *l8myfamily.Location) (bool, error) {
+	defer auth.Bind(l, auth.Internal)()
+	_, ok, err := (&LocationCallback{}).Before(l, ifs.POST, false, nil)
+	return ok, err
+}
+
+func TestBeforeRejectsPostWithoutCaller(t *testing.T) {
+	origGet, origAllowed := getDevice, deviceAllowed
+	getDevice = func(id string, vnic ifs.IVNic) *l8myfamily.Device { return &l8myfamily.Device{Id: id, FamilyId: "fam"} }
+	deviceAllowed = func(familyId, deviceId string) bool { return true }
+	defer func() { getDevice, deviceAllowed = origGet, origAllowed }()
+
+	// A post to the service endpoint reaches the callback without a caller,
+	// an emergency included
+	for _, l := range []*l8myfamily.Location{{DeviceId: "d1", Latitude: 1}, {DeviceId: "d1", Emergency: true}} {
+		_, ok, err := (&LocationCallback{}).Before(l, ifs.POST, false, nil)
+		if ok || err != webapi.ErrUnauthenticated {
+			t.Fatalf("a post without a caller must be rejected, got %v %v", ok, err)
+		}
+		if webapi.ErrorStatus(err) != http.StatusUnauthorized {
+			t.Fatalf("a post without a caller must answer 401, got %d", webapi.ErrorStatus(err))
+		}
+	}
+}
+
+func TestBeforeRejectsDeviceNotOnAllowlist(t *testing.T) {
+	origGet, origAllowed := getDevice, deviceAllowed
+	getDevice = func(id string, vnic ifs.IVNic) *l8myfamily.Device {
+		return &l8myfamily.Device{Id: id, FamilyId: "managed"}
+	}
+	deviceAllowed = func(familyId, deviceId string) bool {
+		return familyId == "managed" && deviceId == "approved"
+	}
+	defer func() { getDevice, deviceAllowed = origGet, origAllowed }()
+
+	if ok, err := postInternal(&l8myfamily.Location{DeviceId: "approved"}); !ok || err != nil {
+		t.Fatalf("an allowed device must post: %v", err)
+	}
+	for _, l := range []*l8myfamily.Location{{DeviceId: "rogue"}, {DeviceId: "rogue", Emergency: true}} {
+		if ok, err := postInternal(l); ok || err != webapi.ErrNotAllowed {
+			t.Fatalf("a device not on the allowlist must be rejected, got %v %v", ok, err)
+		}
+	}
+}
+
+func TestBeforeRejectsUnknownDevice(t *testing.T) {
+	origGet := getDevice
+	getDevice = func(id string, vnic ifs.IVNic) *l8myfamily.Device { return nil }
+	defer func() { getDevice = origGet }()
+
+	if ok, err := postInternal(&l8myfamily.Location{DeviceId: "unknown"}); ok || err != webapi.ErrUnknownDevice {
+		t.Fatalf("the location of an unknown device must be rejected, got %v %v", ok, err)
+	}
+	if ok, err := postInternal(&l8myfamily.Location{DeviceId: "unknown", Emergency: true}); !ok || err != nil {
+		t.Fatalf("an emergency of an unknown device must still be alerted, got %v %v", ok, err)
+	}
+}
+
+func TestBeforeRejectsWhileStorageDegraded(t *testing.T) {
+	origDegraded, origGet := storageDegraded, getDevice
+	storageDegraded = func() bool { return true }
+	getDevice = func(id string, vnic ifs.IVNic) *l8myfamily.Device { return &l8myfamily.Device{Id: id} }
+	defer func() { storageDegraded, getDevice = origDegraded, origGet }()
+
+	if ok, err := postInternal(&l8myfamily.Location{DeviceId: "d1"}); ok || err != webapi.ErrStorageUnavailable {
+		t.Fatalf("a location must be refused while the store cannot write, got %v %v", ok, err)
+	}
+	if webapi.ErrorStatus(webapi.ErrStorageUnavailable) != http.StatusServiceUnavailable {
+		t.Fatal("an unavailable store must answer 503")
+	}
+	if ok, err := postInternal(&l8myfamily.Location{DeviceId: "d1", Emergency: true}); !ok || err != nil {
+		t.Fatalf("an emergency must pass while degraded, got %v %v", ok, err)
+	}
+}
+
+func TestBeforeRejectsCallerOfAnotherFamily(t *testing.T) {
+	origGet, origAllowed := getDevice, deviceAllowed
+	getDevice = func(id string, vnic ifs.IVNic) *l8myfamily.Device { return &l8myfamily.Device{Id: id, FamilyId: "fam"} }
+	deviceAllowed = func(familyId, deviceId string) bool { return true }
+	defer func() { getDevice, deviceAllowed = origGet, origAllowed }()
+
+	cb := &LocationCallback{}
+	l := &l8myfamily.Location{DeviceId: "d1", Latitude: 1}
+	release := auth.Bind(l, &auth.Caller{User: "other", FamilyId: "other"})
+	if _, ok, err := cb.Before(l, ifs.POST, false, nil); ok || err != webapi.ErrOtherFamily {
+		t.Fatalf("a caller of another family must be rejected, got %v %v", ok, err)
+	}
+	release()
+	if ok, err := postInternal(l); !ok || err != nil {
+		t.Fatalf("an internal post must pass, got %v %v", ok, err)
+	}
+	defer auth.Bind(l, &auth.Caller{User: "fam", FamilyId: "fam"})()
+	if _, ok, err := cb.Before(l, ifs.POST, false, nil); !ok || err != nil {
+		t.Fatalf("a caller of the device family must pass, got %v %v", ok, err)
+	}
+}
+
+func TestBeforeRejectsOutdatedAgent(t *testing.T) {
+	cfg := config.Default()
+	cfg.MinAgentVersion = "2.0.0"
+	config.Set(cfg)
+	defer config.Set(config.Default())
+	origGet, origAllowed := getDevice, deviceAllowed
+	getDevice = func(id string, vnic ifs.IVNic) *l8myfamily.Device {
+		return &l8myfamily.Device{Id: id, FamilyId: "fam", AgentVersion: "1.9.0"}
+	}
+	deviceAllowed = func(familyId, deviceId string) bool { return true }
+	defer func() { getDevice, deviceAllowed = origGet, origAllowed }()
+
+	ok, err := postInternal(&l8myfamily.Location{DeviceId: "d1"})
+	if ok || !errors.Is(err, webapi.ErrAgentOutdated) {
+		t.Fatalf("the post of an outdated agent must be rejected, got %v %v", ok, err)
+	}
+	if webapi.ErrorStatus(err) != http.StatusUpgradeRequired {
+		t.Fatalf("an outdated agent must be answered 426, got %d", webapi.ErrorStatus(err))
+	}
+	if ok, err := postInternal(&l8myfamily.Location{DeviceId: "d1", Emergency: true}); !ok || err != nil {
+		t.Fatalf("the emergency of an outdated agent must still be alerted, got %v %v", ok, err)
+	}
+}
+
+func TestRetryOfOverloadedPostIsStored(t *testing.T) {
+	cfg := config.Default()
+	cfg.DataDir = t.TempDir()
+	config.Set(cfg)
+	defer config.Set(config.Default())
+	origGet, origAllowed, origUpdate, origQueue, origKeys := getDevice, deviceAllowed, updateDevice, updateQueue, postKeys
+	getDevice = func(id string, vnic ifs.IVNic) *l8myfamily.Device { return &l8myfamily.Device{Id: id, FamilyId: "fam"} }
+	deviceAllowed = func(familyId, deviceId string) bool { return true }
+	var stored []*l8myfamily.Location
+	updateDevice = func(l *l8myfamily.Location, vnic ifs.IVNic) *l8myfamily.Device {
+		stored = append(stored, l)
+		return &l8myfamily.Device{Id: l.DeviceId, FamilyId: "fam"}
+	}
+	postKeys = webapi.NewIdempotencyCache(time.Minute)
+	// A shut down queue takes no location, like a full one
+	updateQueue = NewUpdateQueue(1, 1, time.Millisecond, 0, nil)
+	updateQueue.Shutdown()
+	defer func() {
+		getDevice, deviceAllowed, updateDevice, updateQueue, postKeys = origGet, origAllowed, origUpdate, origQueue, origKeys
+	}()
+
+	post := func() (bool, error) {
+		l := &l8myfamily.Location{DeviceId: "d1", Latitude: 1, Longitude: 2, IdempotencyKey: "k1"}
+		defer auth.Bind(l, auth.Internal)()
+		cb := &LocationCallback{}
+		if _, ok, err := cb.Before(l, ifs.POST, false, nil); !ok || err != nil {
+			return ok, err
+		}
+		_, ok, err := cb.After(l, ifs.POST, false, nil)
+		return ok, err
+	}
+	if ok, err := post(); ok || err == nil || webapi.ErrorStatus(err) != http.StatusServiceUnavailable {
+		t.Fatalf("a post the queue cannot take must answer 503, got %v %v", ok, err)
+	}
+	updateQueue = nil
+	if ok, err := post(); !ok || err != nil || len(stored) != 1 {
+		t.Fatalf("the retry of a 503 post must be stored, got %v %v %v", ok, err, stored)
+	}
+	if stored[0].IdempotencyKey != "" {
+		t.Fatalf("the key must not be stored, got %q", stored[0].IdempotencyKey)
+	}
+	if ok, err := post(); ok || err != nil || len(stored) != 1 {
+		t.Fatalf("the retry of a stored post must be acknowledged as a duplicate, got %v %v %v", ok, err, stored)
+	}
+}
@@ -0,0 +1,87 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package location_service
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+
+	"github.com/saichler/l8myfamiliy/go/myf/audit"
+	"github.com/saichler/l8myfamiliy/go/myf/config"
+	"github.com/saichler/l8myfamiliy/go/myf/notify"
+	"github.com/saichler/l8myfamiliy/go/types/l8myfamily"
+)
+
+// lowBattery remembers which devices were already alerted
+var lowBattery = NewLowBatteryTracker()
+
+// LowBatteryTracker alerts the family once per low battery episode, the
+// episode ends when the battery is reported above the threshold again
+type LowBatteryTracker struct {
+	mtx      *sync.Mutex
+	notified map[string]bool
+}
+
+func NewLowBatteryTracker() *LowBatteryTracker {
+	return &LowBatteryTracker{mtx: &sync.Mutex{}, notified: make(map[string]bool)}
+}
+
+// Check alerts when the reported battery level crossed the family threshold.
+// Locations without a battery level and unregistered devices are ignored.
+func (t *LowBatteryTracker) Check(l *l8myfamily.Location, device *l8myfamily.Device) {
+	if l.Battery <= 0 || device == nil {
+		return
+	}
+	if !t.crossed(l.DeviceId, l.Battery, config.LowBatteryPercentFor(device.FamilyId)) {
+		return
+	}
+	onLowBattery(l, device)
+}
+
+// crossed records the level and returns true when the device just entered
+// the low battery state
+func (t *LowBatteryTracker) crossed(deviceId string, battery int32, threshold int) bool {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	if threshold <= 0 || int(battery) > threshold {
+		delete(t.notified, deviceId)
+		return false
+	}
+	if t.notified[deviceId] {
+		return false
+	}
+	t.notified[deviceId] = true
+	return true
+}
+
+func onLowBattery(l *l8myfamily.Location, device *l8myfamily.Device) {
+	name := l.DeviceId
+	if device.Name != "" {
+		name = device.Name
+	}
+	level := strconv.Itoa(int(l.Battery))
+	fmt.Println("[LowBattery] ", l.DeviceId, "-", device.FamilyId, " at ", level, "%")
+	notify.Send(&notify.Event{
+		Type:      notify.EventLowBattery,
+		DeviceId:  l.DeviceId,
+		FamilyId:  device.FamilyId,
+		Latitude:  device.Latitude,
+		Longitude: device.Longitude,
+		Message:   "Low battery on " + name + " (" + level + "%)",
+	})
+	audit.Log(notify.EventLowBattery, l.DeviceId, l.DeviceId, device.FamilyId, "battery="+level)
+}
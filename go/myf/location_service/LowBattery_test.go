@@ -0,0 +1,90 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package location_service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/saichler/l8myfamiliy/go/myf/audit"
+	"github.com/saichler/l8myfamiliy/go/myf/config"
+	"github.com/saichler/l8myfamiliy/go/myf/notify"
+	"github.com/saichler/l8myfamiliy/go/types/l8myfamily"
+	"github.com/saichler/l8types/go/ifs"
+)
+
+func TestLowBatteryAlertsOncePerEpisode(t *testing.T) {
+	received := setupEmergencyTest(t)
+	cfg := *config.Get()
+	cfg.LowBatteryPercent = 15
+	config.Set(&cfg)
+	lowBattery = NewLowBatteryTracker()
+
+	cb := &LocationCallback{}
+	for _, level := range []int32{40, 15, 12, 0, 9} {
+		cb.After(&l8myfamily.Location{DeviceId: "d1", Latitude: 1, Longitude: 2, Battery: level}, ifs.POST, false, nil)
+	}
+	select {
+	case <-received:
+	case <-time.After(5 * time.Second):
+		t.Fatal("low battery webhook was not called")
+	}
+	select {
+	case <-received:
+		t.Fatal("low battery should alert once per episode")
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	// Charging above the threshold ends the episode
+	for _, level := range []int32{50, 14} {
+		cb.After(&l8myfamily.Location{DeviceId: "d1", Heartbeat: true, Battery: level}, ifs.POST, false, nil)
+	}
+	select {
+	case <-received:
+	case <-time.After(5 * time.Second):
+		t.Fatal("a new low battery episode was not alerted")
+	}
+
+	records, _ := audit.Read()
+	if len(records) != 2 || records[0].Action != notify.EventLowBattery {
+		t.Fatalf("expected two low battery audit records, got %v", records)
+	}
+}
+
+func TestLowBatteryFamilyThreshold(t *testing.T) {
+	config.Set(&config.ServerConfig{LowBatteryPercent: 15, Families: map[string]*config.FamilyConfig{
+		"strict": {LowBatteryPercent: 30},
+		"off":    {LowBatteryPercent: -1},
+	}})
+	t.Cleanup(func() { config.Set(config.Default()) })
+
+	if got := config.LowBatteryPercentFor("other"); got != 15 {
+		t.Fatalf("expected the global threshold, got %d", got)
+	}
+	if got := config.LowBatteryPercentFor("strict"); got != 30 {
+		t.Fatalf("expected the family threshold, got %d", got)
+	}
+	if got := config.LowBatteryPercentFor("off"); got != 0 {
+		t.Fatalf("expected the alert disabled, got %d", got)
+	}
+	tracker := NewLowBatteryTracker()
+	if tracker.crossed("d1", 20, 0) {
+		t.Fatal("a disabled threshold must not alert")
+	}
+	if !tracker.crossed("d1", 20, 30) {
+		t.Fatal("expected an alert below the family threshold")
+	}
+}
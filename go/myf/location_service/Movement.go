@@ -0,0 +1,149 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package location_service
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/saichler/l8myfamiliy/go/myf/config"
+	"github.com/saichler/l8myfamiliy/go/myf/geo"
+	"github.com/saichler/l8myfamiliy/go/types/l8myfamily"
+)
+
+// The movement states of a device, empty until its speed is known
+const (
+	MovementStationary = "stationary"
+	MovementWalking    = "walking"
+	MovementDriving    = "driving"
+)
+
+// movements tracks the recent speeds of the devices
+var movements = NewMovementTracker()
+
+// MovementTracker classifies the movement of each device from its recent
+// speeds, the reported GPS speed or the speed derived from the previous
+// position
+type MovementTracker struct {
+	mtx     *sync.Mutex
+	devices map[string]*movementTrack
+}
+
+type movementTrack struct {
+	latitude  float64
+	longitude float64
+	at        int64
+	known     bool
+	samples   []speedSample
+	state     string
+}
+
+type speedSample struct {
+	at    int64
+	speed float64
+}
+
+func NewMovementTracker() *MovementTracker {
+	return &MovementTracker{mtx: &sync.Mutex{}, devices: make(map[string]*movementTrack)}
+}
+
+// Classify records the speed of the location and returns the movement state
+// of its device, empty while disabled or unknown. A heartbeat is an agent
+// that did not move.
+func (t *MovementTracker) Classify(l *l8myfamily.Location) string {
+	thresholds := config.Get().Movement
+	if thresholds == nil {
+		return ""
+	}
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	track := t.devices[l.DeviceId]
+	if track == nil {
+		track = &movementTrack{}
+		t.devices[l.DeviceId] = track
+	}
+	at := l.Timestamp
+	if at == 0 {
+		at = time.Now().UnixMilli()
+	}
+	// An older location is history, it does not change the current state
+	if at < track.at {
+		return track.state
+	}
+	speed, ok := track.speed(l, at)
+	track.at = at
+	if !l.Heartbeat {
+		track.latitude, track.longitude, track.known = float64(l.Latitude), float64(l.Longitude), true
+	}
+	window := thresholds.WindowSeconds * 1000
+	kept := track.samples[:0]
+	for _, sample := range track.samples {
+		if at-sample.at <= window {
+			kept = append(kept, sample)
+		}
+	}
+	track.samples = kept
+	if ok {
+		track.samples = append(track.samples, speedSample{at: at, speed: speed})
+	}
+	if len(track.samples) == 0 {
+		return track.state
+	}
+	median := medianSpeed(track.samples)
+	switch {
+	case median >= thresholds.DrivingSpeed:
+		track.state = MovementDriving
+	case median >= thresholds.WalkingSpeed:
+		track.state = MovementWalking
+	default:
+		track.state = MovementStationary
+	}
+	return track.state
+}
+
+// speed returns the speed of the location, false for the first position of
+// a device that did not report one. A move within the accuracy radius is
+// jitter, not a speed.
+func (this *movementTrack) speed(l *l8myfamily.Location, at int64) (float64, bool) {
+	if l.Heartbeat {
+		return 0, true
+	}
+	if l.Speed > 0 {
+		return float64(l.Speed), true
+	}
+	if !this.known || at <= this.at {
+		return 0, false
+	}
+	meters := geo.Distance(this.latitude, this.longitude, float64(l.Latitude), float64(l.Longitude))
+	if meters <= float64(l.Accuracy) {
+		return 0, true
+	}
+	return meters / (float64(at-this.at) / 1000), true
+}
+
+func medianSpeed(samples []speedSample) float64 {
+	speeds := make([]float64, len(samples))
+	for i, sample := range samples {
+		speeds[i] = sample.speed
+	}
+	sort.Float64s(speeds)
+	middle := len(speeds) / 2
+	if len(speeds)%2 == 0 {
+		return (speeds[middle-1] + speeds[middle]) / 2
+	}
+	return speeds[middle]
+}
@@ -0,0 +1,91 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package location_service
+
+import (
+	"testing"
+
+	"github.com/saichler/l8myfamiliy/go/myf/config"
+	"github.com/saichler/l8myfamiliy/go/types/l8myfamily"
+)
+
+func TestMovementFromReportedSpeed(t *testing.T) {
+	tracker := NewMovementTracker()
+	start := int64(1_700_000_000_000)
+	// A car posting every 10s cruises, waits 30s at a light and drives on
+	speeds := []float32{0, 12, 14, 13, 15, 0, 0, 0, 11, 14, 13}
+	for i, speed := range speeds {
+		state := tracker.Classify(&l8myfamily.Location{DeviceId: "car", Latitude: 40, Longitude: -74,
+			Speed: speed, Timestamp: start + int64(i)*10_000})
+		if i == 0 && state != "" {
+			t.Fatalf("the first post without a speed is unknown, got %s", state)
+		}
+		if i >= 3 && state != MovementDriving {
+			t.Fatalf("post %d at %v m/s: a stop at a light must not flap the state, got %s", i, speed, state)
+		}
+	}
+	// A parked car reports heartbeats until the window forgets the drive
+	at := start + int64(len(speeds))*10_000
+	for i := 0; i < 13; i++ {
+		tracker.Classify(&l8myfamily.Location{DeviceId: "car", Heartbeat: true, Timestamp: at + int64(i)*10_000})
+	}
+	if state := tracker.Classify(&l8myfamily.Location{DeviceId: "car", Heartbeat: true, Timestamp: at + 130_000}); state != MovementStationary {
+		t.Fatalf("a parked car must become stationary, got %s", state)
+	}
+}
+
+func TestMovementFromDerivedSpeed(t *testing.T) {
+	tracker := NewMovementTracker()
+	start := int64(1_700_000_000_000)
+	if state := tracker.Classify(&l8myfamily.Location{DeviceId: "d1", Latitude: 40, Longitude: -74, Timestamp: start}); state != "" {
+		t.Fatalf("the first position without a speed is unknown, got %s", state)
+	}
+	// About 1.4 m/s, 0.00013 degrees of latitude every 10s
+	lat := float32(40)
+	for i := 1; i <= 6; i++ {
+		lat += 0.00013
+		tracker.Classify(&l8myfamily.Location{DeviceId: "d1", Latitude: lat, Longitude: -74, Accuracy: 5,
+			Timestamp: start + int64(i)*10_000})
+	}
+	if state := tracker.Classify(&l8myfamily.Location{DeviceId: "d1", Latitude: lat + 0.00013, Longitude: -74, Accuracy: 5,
+		Timestamp: start + 70_000}); state != MovementWalking {
+		t.Fatalf("expected walking, got %s", state)
+	}
+	// Jitter within the accuracy radius is not movement
+	tracker = NewMovementTracker()
+	for i := 0; i < 5; i++ {
+		lat := float32(40)
+		if i%2 == 1 {
+			lat += 0.0002
+		}
+		tracker.Classify(&l8myfamily.Location{DeviceId: "d1", Latitude: lat, Longitude: -74, Accuracy: 50,
+			Timestamp: start + int64(i)*10_000})
+	}
+	if state := tracker.Classify(&l8myfamily.Location{DeviceId: "d1", Latitude: 40, Longitude: -74, Accuracy: 50,
+		Timestamp: start + 50_000}); state != MovementStationary {
+		t.Fatalf("jitter must be stationary, got %s", state)
+	}
+}
+
+func TestMovementDisabled(t *testing.T) {
+	cfg := *config.Get()
+	cfg.Movement = nil
+	config.Set(&cfg)
+	defer config.Set(config.Default())
+	if state := NewMovementTracker().Classify(&l8myfamily.Location{DeviceId: "d1", Speed: 20}); state != "" {
+		t.Fatalf("a disabled movement must not be set, got %s", state)
+	}
+}
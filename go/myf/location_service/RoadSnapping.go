@@ -0,0 +1,115 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package location_service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/saichler/l8myfamiliy/go/myf/config"
+	"github.com/saichler/l8myfamiliy/go/myf/device_service"
+	"github.com/saichler/l8myfamiliy/go/types/l8myfamily"
+	"github.com/saichler/l8types/go/ifs"
+)
+
+const (
+	defaultSnapProfile     = "driving"
+	defaultSnapTimeout     = 300 * time.Millisecond
+	defaultSnapMaxDistance = 50.0
+)
+
+var snapClient = &http.Client{}
+
+// osrmNearest is the part of the OSRM nearest service response that is used
+type osrmNearest struct {
+	Code      string `json:"code"`
+	Waypoints []struct {
+		Location []float64 `json:"location"`
+		Distance float64   `json:"distance"`
+	} `json:"waypoints"`
+}
+
+// snapToRoad sets the snapped position of a vehicle location, the raw
+// position is kept. It does nothing when road snapping is not configured,
+// the device is not a vehicle or the location has no position. The service
+// call is bounded by the configured timeout, a failed or timed out call
+// leaves the location without a snapped position.
+func snapToRoad(l *l8myfamily.Location, vnic ifs.IVNic) {
+	snapping := config.Get().RoadSnapping
+	if snapping == nil || snapping.Url == "" || l.Heartbeat {
+		return
+	}
+	device := getDevice(l.DeviceId, vnic)
+	if device == nil || device.Kind != device_service.DeviceKindVehicle {
+		return
+	}
+	lat, lon, err := snap(snapping, l.Latitude, l.Longitude)
+	if err != nil {
+		fmt.Println("[RoadSnapping] ", l.DeviceId, ": ", err.Error())
+		return
+	}
+	l.SnappedLatitude = float32(lat)
+	l.SnappedLongitude = float32(lon)
+}
+
+// snap returns the nearest road position from the OSRM nearest service
+func snap(snapping *config.RoadSnapping, lat, lon float32) (float64, float64, error) {
+	profile := snapping.Profile
+	if profile == "" {
+		profile = defaultSnapProfile
+	}
+	timeout := time.Duration(snapping.TimeoutMillis) * time.Millisecond
+	if timeout <= 0 {
+		timeout = defaultSnapTimeout
+	}
+	maxDistance := snapping.MaxDistanceMeters
+	if maxDistance <= 0 {
+		maxDistance = defaultSnapMaxDistance
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	url := fmt.Sprintf("%s/nearest/v1/%s/%s,%s?number=1", strings.TrimSuffix(snapping.Url, "/"), profile,
+		strconv.FormatFloat(float64(lon), 'f', -1, 32), strconv.FormatFloat(float64(lat), 'f', -1, 32))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return 0, 0, err
+	}
+	resp, err := snapClient.Do(req)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return 0, 0, fmt.Errorf("snapping service returned status %d", resp.StatusCode)
+	}
+	nearest := &osrmNearest{}
+	if err := json.NewDecoder(resp.Body).Decode(nearest); err != nil {
+		return 0, 0, err
+	}
+	if nearest.Code != "Ok" || len(nearest.Waypoints) == 0 || len(nearest.Waypoints[0].Location) != 2 {
+		return 0, 0, fmt.Errorf("no road found, code %s", nearest.Code)
+	}
+	waypoint := nearest.Waypoints[0]
+	if waypoint.Distance > maxDistance {
+		return 0, 0, fmt.Errorf("nearest road is %.0fm away", waypoint.Distance)
+	}
+	return waypoint.Location[1], waypoint.Location[0], nil
+}
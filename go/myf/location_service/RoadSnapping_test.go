@@ -0,0 +1,94 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package location_service
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/saichler/l8myfamiliy/go/myf/config"
+	"github.com/saichler/l8myfamiliy/go/myf/device_service"
+	"github.com/saichler/l8myfamiliy/go/types/l8myfamily"
+	"github.com/saichler/l8types/go/ifs"
+)
+
+func setupRoadSnapping(t *testing.T, response string, delay time.Duration) *int {
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if r.URL.Path != "/nearest/v1/driving/-122.419,37.774" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		time.Sleep(delay)
+		w.Write([]byte(response))
+	}))
+	t.Cleanup(srv.Close)
+	config.Set(&config.ServerConfig{RoadSnapping: &config.RoadSnapping{Url: srv.URL, TimeoutMillis: 100}})
+	origGet := getDevice
+	getDevice = func(id string, vnic ifs.IVNic) *l8myfamily.Device {
+		if id == "car" {
+			return &l8myfamily.Device{Id: id, Kind: device_service.DeviceKindVehicle}
+		}
+		return &l8myfamily.Device{Id: id}
+	}
+	t.Cleanup(func() {
+		getDevice = origGet
+		config.Set(config.Default())
+	})
+	return &calls
+}
+
+func TestSnapToRoadVehicleOnly(t *testing.T) {
+	calls := setupRoadSnapping(t, `{"code":"Ok","waypoints":[{"location":[-122.4191,37.7741],"distance":12}]}`, 0)
+
+	phone := &l8myfamily.Location{DeviceId: "phone", Latitude: 37.774, Longitude: -122.419}
+	snapToRoad(phone, nil)
+	if *calls != 0 || phone.SnappedLatitude != 0 {
+		t.Fatalf("only vehicles are snapped, got %d calls %v", *calls, phone)
+	}
+
+	car := &l8myfamily.Location{DeviceId: "car", Latitude: 37.774, Longitude: -122.419}
+	snapToRoad(car, nil)
+	if car.SnappedLatitude != 37.7741 || car.SnappedLongitude != -122.4191 || car.Latitude != 37.774 {
+		t.Fatalf("expected the snapped and the raw position, got %v", car)
+	}
+
+	config.Set(config.Default())
+	unconfigured := &l8myfamily.Location{DeviceId: "car", Latitude: 37.774, Longitude: -122.419}
+	snapToRoad(unconfigured, nil)
+	if *calls != 1 || unconfigured.SnappedLatitude != 0 {
+		t.Fatal("snapping must be a no-op without a configured service")
+	}
+}
+
+func TestSnapToRoadDropsJitterAndTimeouts(t *testing.T) {
+	setupRoadSnapping(t, `{"code":"Ok","waypoints":[{"location":[-122.42,37.78],"distance":400}]}`, 0)
+	far := &l8myfamily.Location{DeviceId: "car", Latitude: 37.774, Longitude: -122.419}
+	snapToRoad(far, nil)
+	if far.SnappedLatitude != 0 {
+		t.Fatalf("a road beyond the max distance must not be used, got %v", far)
+	}
+
+	setupRoadSnapping(t, `{"code":"Ok","waypoints":[{"location":[-122.4191,37.7741],"distance":1}]}`, 500*time.Millisecond)
+	slow := &l8myfamily.Location{DeviceId: "car", Latitude: 37.774, Longitude: -122.419}
+	start := time.Now()
+	snapToRoad(slow, nil)
+	if slow.SnappedLatitude != 0 || time.Since(start) > 400*time.Millisecond {
+		t.Fatalf("a slow service must be timed out, got %v after %v", slow, time.Since(start))
+	}
+}
@@ -0,0 +1,126 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package location_service
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/saichler/l8myfamiliy/go/myf/config"
+	"github.com/saichler/l8myfamiliy/go/myf/device_service"
+	"github.com/saichler/l8myfamiliy/go/myf/geo"
+	"github.com/saichler/l8myfamiliy/go/myf/history"
+	"github.com/saichler/l8myfamiliy/go/types/l8myfamily"
+)
+
+// position is the stored device position a posted location moves it from,
+// with the fix the accuracy gate compares the location to
+type position struct {
+	latitude  float64
+	longitude float64
+	timestamp int64
+	known     bool
+	fix       *l8myfamily.Device
+}
+
+// positionOf returns the position on the device record, unknown for a
+// device that never posted one
+func positionOf(device *l8myfamily.Device) position {
+	if device == nil || (device.Latitude == 0 && device.Longitude == 0) {
+		return position{}
+	}
+	return position{latitude: float64(device.Latitude), longitude: float64(device.Longitude),
+		timestamp: device.LastSeen, known: true,
+		fix: &l8myfamily.Device{Accuracy: device.Accuracy, Source: device.Source, Coarse: device.Coarse,
+			PositionTime: device.PositionTime}}
+}
+
+// moved reports whether the location moves the device, a heartbeat has no
+// position, an older location is history and a much less accurate fix is
+// gated
+func (this position) moved(l *l8myfamily.Location) bool {
+	if l.Heartbeat {
+		return false
+	}
+	if !this.known {
+		return true
+	}
+	return (l.Timestamp == 0 || l.Timestamp >= this.timestamp) && !device_service.Gated(this.fix, l)
+}
+
+// next returns the position after the location
+func (this position) next(l *l8myfamily.Location) position {
+	if !this.moved(l) {
+		return this
+	}
+	at := l.Timestamp
+	if at == 0 {
+		at = time.Now().UnixMilli()
+	}
+	return position{latitude: float64(l.Latitude), longitude: float64(l.Longitude),
+		timestamp: l.Timestamp, known: true,
+		fix: &l8myfamily.Device{Accuracy: l.Accuracy, Source: l.Source, Coarse: l.Coarse, PositionTime: at}}
+}
+
+// inside reports whether the point is within the fence radius
+func inside(fence *config.Geofence, lat, lon float64) bool {
+	return geo.Distance(fence.Latitude, fence.Longitude, lat, lon) <= fence.RadiusMeters
+}
+
+// crossings returns one enter or exit event per geofence whose boundary the
+// device crossed from the previous position to the location. A device
+// without a previous position was outside every geofence.
+func crossings(fences []*config.Geofence, previous position, l *l8myfamily.Location) []*l8myfamily.GeofenceEvent {
+	if !previous.moved(l) {
+		return nil
+	}
+	var events []*l8myfamily.GeofenceEvent
+	for _, fence := range fences {
+		was := previous.known && inside(fence, previous.latitude, previous.longitude)
+		is := inside(fence, float64(l.Latitude), float64(l.Longitude))
+		if was == is {
+			continue
+		}
+		events = append(events, &l8myfamily.GeofenceEvent{DeviceId: l.DeviceId, Geofence: fence.Name,
+			Entered: is, Timestamp: l.Timestamp, Latitude: l.Latitude, Longitude: l.Longitude,
+			ServerEvaluated: true})
+	}
+	return events
+}
+
+// evaluateGeofences records the crossings of the family geofences and of the
+// device home region, and notifies the first exit of the home region of the day
+func evaluateGeofences(device *l8myfamily.Device, previous position, l *l8myfamily.Location) {
+	familyId := device.FamilyId
+	fences := config.GeofencesFor(familyId)
+	region := config.HomeRegionFor(familyId, l.DeviceId)
+	if region != nil {
+		fences = append(append([]*config.Geofence(nil), fences...), region)
+	}
+	if len(fences) == 0 {
+		return
+	}
+	events := crossings(fences, previous, l)
+	for _, e := range events {
+		fmt.Println("[Geofence] ", l.DeviceId, "-", familyId, " entered=", e.Entered, " ", e.Geofence)
+	}
+	if err := history.AppendGeofenceEvents(familyId, l.DeviceId, events); err != nil {
+		fmt.Println("[Geofence] failed to record for ", l.DeviceId, ": ", err.Error())
+	}
+	if region != nil {
+		dailyExits.Check(device, region, events)
+	}
+}
@@ -0,0 +1,127 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package location_service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/saichler/l8myfamiliy/go/myf/config"
+	"github.com/saichler/l8myfamiliy/go/myf/history"
+	"github.com/saichler/l8myfamiliy/go/types/l8myfamily"
+	"github.com/saichler/l8types/go/ifs"
+)
+
+// school is a 100m geofence, 0.001 degrees of latitude are about 111m
+var school = &config.Geofence{Name: "School", Latitude: 40, Longitude: -74, RadiusMeters: 100}
+
+// walk moves a device through the latitudes north of the school and
+// returns the events of every step
+func walk(previous position, latitudes ...float32) [][]*l8myfamily.GeofenceEvent {
+	steps := make([][]*l8myfamily.GeofenceEvent, 0, len(latitudes))
+	for i, lat := range latitudes {
+		l := &l8myfamily.Location{DeviceId: "d1", Latitude: lat, Longitude: -74, Timestamp: int64(i+1) * 1000}
+		steps = append(steps, crossings([]*config.Geofence{school}, previous, l))
+		previous = previous.next(l)
+	}
+	return steps
+}
+
+func TestCrossingsApproachCrossDwell(t *testing.T) {
+	start := position{latitude: 40.01, longitude: -74, known: true}
+	// approach, enter, dwell, exit, leave
+	steps := walk(start, 40.005, 40.0015, 40.0005, 40.0002, 40.0008, 40.003, 40.01)
+	want := []int{0, 0, 1, 0, 0, 1, 0}
+	for i, events := range steps {
+		if len(events) != want[i] {
+			t.Fatalf("step %d: expected %d events, got %v", i, want[i], events)
+		}
+	}
+	if !steps[2][0].Entered || steps[5][0].Entered || steps[2][0].Geofence != "School" || !steps[2][0].ServerEvaluated {
+		t.Fatalf("expected an enter then an exit, got %v %v", steps[2], steps[5])
+	}
+}
+
+func TestCrossingsWithoutPreviousPosition(t *testing.T) {
+	if steps := walk(position{}, 40.0002); len(steps[0]) != 1 || !steps[0][0].Entered {
+		t.Fatalf("a first position inside is an entry, got %v", steps[0])
+	}
+	if steps := walk(position{}, 40.01); len(steps[0]) != 0 {
+		t.Fatalf("a first position outside is no event, got %v", steps[0])
+	}
+}
+
+func TestCrossingsIgnoreHeartbeatsAndHistory(t *testing.T) {
+	inside := position{latitude: 40, longitude: -74, timestamp: 5000, known: true}
+	fences := []*config.Geofence{school}
+	if events := crossings(fences, inside, &l8myfamily.Location{DeviceId: "d1", Heartbeat: true, Timestamp: 6000}); len(events) != 0 {
+		t.Fatalf("a heartbeat has no position, got %v", events)
+	}
+	older := &l8myfamily.Location{DeviceId: "d1", Latitude: 41, Longitude: -74, Timestamp: 4000}
+	if events := crossings(fences, inside, older); len(events) != 0 {
+		t.Fatalf("an older location is history, got %v", events)
+	}
+	if inside.next(older) != inside {
+		t.Fatal("an older location must not move the device")
+	}
+}
+
+func TestUpdateQueueEvaluatesGeofencesInOrder(t *testing.T) {
+	config.Set(&config.ServerConfig{DataDir: t.TempDir(),
+		Families: map[string]*config.FamilyConfig{"family": {Geofences: []*config.Geofence{school}}}})
+	origGet, origUpdate := getDevice, updateDevices
+	getDevice = func(id string, vnic ifs.IVNic) *l8myfamily.Device {
+		return &l8myfamily.Device{Id: id, FamilyId: "family", Latitude: 40.01, Longitude: -74, LastSeen: 500}
+	}
+	updateDevices = func(batch []*l8myfamily.Location, vnic ifs.IVNic) map[string]*l8myfamily.Device {
+		return map[string]*l8myfamily.Device{"d1": {Id: "d1", FamilyId: "family"}}
+	}
+	t.Cleanup(func() {
+		getDevice, updateDevices = origGet, origUpdate
+		config.Set(config.Default())
+	})
+
+	queue := &UpdateQueue{}
+	// cross in, dwell, cross out in one batch
+	queue.apply([]*l8myfamily.Location{
+		{DeviceId: "d1", Latitude: 40.0002, Longitude: -74, Timestamp: 1000},
+		{DeviceId: "d1", Latitude: 40.0004, Longitude: -74, Timestamp: 2000},
+		{DeviceId: "d1", Latitude: 40.005, Longitude: -74, Timestamp: 3000},
+	})
+	events, err := history.QueryGeofence("family", "d1", 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 2 || !events[0].Entered || events[0].Timestamp != 1000 || events[1].Entered || events[1].Timestamp != 3000 {
+		t.Fatalf("expected one entry and one exit, got %v", events)
+	}
+}
+
+func TestCrossingsIgnoreGatedFix(t *testing.T) {
+	config.Set(config.Default())
+	now := time.Now().UnixMilli()
+	device := &l8myfamily.Device{Latitude: 40, Longitude: -74, Accuracy: 10, LastSeen: now - 1000, PositionTime: now - 1000}
+	inside := positionOf(device)
+	// An IP based fix far away is much less accurate than the recent GPS fix
+	far := &l8myfamily.Location{DeviceId: "d1", Latitude: 41, Longitude: -74, Timestamp: now,
+		Source: l8myfamily.LocationSource_LOCATION_SOURCE_GEOIP}
+	if events := crossings([]*config.Geofence{school}, inside, far); len(events) != 0 {
+		t.Fatalf("a gated fix must not exit the geofence, got %v", events)
+	}
+	if inside.next(far) != inside {
+		t.Fatal("a gated fix must not move the device")
+	}
+}
@@ -0,0 +1,71 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package location_service
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/saichler/l8myfamiliy/go/myf/config"
+	"github.com/saichler/l8myfamiliy/go/myf/device_service"
+	"github.com/saichler/l8myfamiliy/go/myf/webapi"
+	"github.com/saichler/l8myfamiliy/go/types/l8myfamily"
+)
+
+// nonces remembers the nonces of the signed location posts
+var nonces = webapi.NewIdempotencyCache(config.ReplayWindow())
+
+// signingKeys is a variable so tests can verify without a device service
+var signingKeys = device_service.SigningKeys
+
+// verifySignature rejects a captured location post replayed to spoof the
+// position of a device. A device that registered a signing key must sign
+// every post with a fresh nonce, the nonce is remembered for the replay
+// window and a post signed before the window is rejected. Devices without
+// a key post unsigned. The signature fields are cleared, they are not stored.
+func verifySignature(l *l8myfamily.Location, now time.Time) error {
+	defer func() {
+		l.Nonce, l.Signature, l.SignedTime = "", "", 0
+	}()
+	keys := signingKeys(l.DeviceId)
+	if len(keys) == 0 {
+		return nil
+	}
+	if l.Signature == "" {
+		return fmt.Errorf("unsigned post")
+	}
+	verified := false
+	for _, key := range keys {
+		if webapi.VerifyLocation(l, key) {
+			verified = true
+			break
+		}
+	}
+	if !verified {
+		return fmt.Errorf("invalid signature")
+	}
+	age := now.Sub(time.UnixMilli(l.SignedTime))
+	if age < 0 {
+		age = -age
+	}
+	if age > config.ReplayWindow() {
+		return fmt.Errorf("signed %s ago, outside the replay window", age)
+	}
+	if nonces.Seen(l.DeviceId + "/" + l.Nonce) {
+		return fmt.Errorf("replayed nonce %s", l.Nonce)
+	}
+	return nil
+}
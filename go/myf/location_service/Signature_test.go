@@ -0,0 +1,87 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package location_service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/saichler/l8myfamiliy/go/myf/webapi"
+	"github.com/saichler/l8myfamiliy/go/types/l8myfamily"
+	"github.com/saichler/l8types/go/ifs"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestSignedLocationReplayRejected(t *testing.T) {
+	key, rotated := webapi.NewSigningKey(), webapi.NewSigningKey()
+	keys := []string{key}
+	origKeys, origGet := signingKeys, getDevice
+	signingKeys = func(deviceId string) []string {
+		if deviceId == "legacy" {
+			return nil
+		}
+		return keys
+	}
+	getDevice = func(id string, vnic ifs.IVNic) *l8myfamily.Device { return &l8myfamily.Device{Id: id} }
+	nonces = webapi.NewIdempotencyCache(time.Minute)
+	defer func() { signingKeys, getDevice = origKeys, origGet }()
+
+	post := func(l *l8myfamily.Location) error {
+		_, err := postInternal(l)
+		return err
+	}
+	signed := func(k string, signedAt time.Time) *l8myfamily.Location {
+		l := &l8myfamily.Location{DeviceId: "d1", Latitude: 32.1, Longitude: 34.8, Timestamp: 1000}
+		webapi.SignLocation(l, k, signedAt)
+		return l
+	}
+
+	l := signed(key, time.Now())
+	replay := proto.Clone(l).(*l8myfamily.Location)
+	if err := post(l); err != nil {
+		t.Fatalf("a signed post must be accepted: %v", err)
+	}
+	if l.Signature != "" || l.Nonce != "" {
+		t.Fatal("the signature must not be stored")
+	}
+	if err := post(replay); err != webapi.ErrBadSignature {
+		t.Fatalf("a replayed nonce must be rejected, got %v", err)
+	}
+
+	spoofed := signed(key, time.Now())
+	spoofed.Latitude = 40
+	if err := post(spoofed); err != webapi.ErrBadSignature {
+		t.Fatalf("a changed position must be rejected, got %v", err)
+	}
+	if err := post(&l8myfamily.Location{DeviceId: "d1", Latitude: 1}); err != webapi.ErrBadSignature {
+		t.Fatalf("an unsigned post of a device with a key must be rejected, got %v", err)
+	}
+	if err := post(signed(key, time.Now().Add(-time.Hour))); err != webapi.ErrBadSignature {
+		t.Fatalf("a post signed before the replay window must be rejected, got %v", err)
+	}
+	if err := post(&l8myfamily.Location{DeviceId: "legacy", Latitude: 1}); err != nil {
+		t.Fatalf("a device without a key posts unsigned: %v", err)
+	}
+
+	// During a rotation both keys are accepted
+	keys = []string{rotated, key}
+	if err := post(signed(key, time.Now())); err != nil {
+		t.Fatalf("the previous key must be accepted during the rotation: %v", err)
+	}
+	if err := post(signed(rotated, time.Now())); err != nil {
+		t.Fatalf("the new key must be accepted: %v", err)
+	}
+}
@@ -0,0 +1,161 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package location_service
+
+import (
+	"hash/fnv"
+	"sync"
+	"time"
+
+	"github.com/saichler/l8myfamiliy/go/myf/webapi"
+	"github.com/saichler/l8myfamiliy/go/types/l8myfamily"
+	"github.com/saichler/l8types/go/ifs"
+)
+
+// UpdateQueue applies accepted locations to their devices in the background,
+// so a location post returns without waiting for the device read-modify-write.
+//
+// Locations are sharded by device id and each shard has a single consumer,
+// so updates of the same device are applied in post order while different
+// devices update in parallel, at most one update per shard at a time.
+//
+// A consumer batches the locations that arrive within the flush window and
+// applies them in one pass, reading and writing each device once no matter
+// how many of its locations are in the batch.
+//
+// Delivery is at-most-once: an accepted location is applied exactly once
+// unless the process dies before its shard consumed it. Shutdown stops
+// accepting new locations and drains everything already queued.
+type UpdateQueue struct {
+	shards       []chan *l8myfamily.Location
+	queueTimeout time.Duration
+	flushWindow  time.Duration
+	vnic         ifs.IVNic
+	wg           *sync.WaitGroup
+	mtx          *sync.RWMutex
+	closed       bool
+}
+
+// NewUpdateQueue creates and starts a queue with the given number of shards,
+// each buffering up to size locations. A flush window of 0 applies every
+// location as soon as it is consumed.
+func NewUpdateQueue(shards, size int, queueTimeout, flushWindow time.Duration, vnic ifs.IVNic) *UpdateQueue {
+	if shards <= 0 {
+		shards = 1
+	}
+	queue := &UpdateQueue{shards: make([]chan *l8myfamily.Location, shards),
+		queueTimeout: queueTimeout, flushWindow: flushWindow, vnic: vnic, wg: &sync.WaitGroup{}, mtx: &sync.RWMutex{}}
+	for i := range queue.shards {
+		queue.shards[i] = make(chan *l8myfamily.Location, size)
+		queue.wg.Add(1)
+		go queue.consume(queue.shards[i])
+	}
+	return queue
+}
+
+// Enqueue queues the location of a device, waiting up to the queue timeout
+// when the device shard is full
+func (this *UpdateQueue) Enqueue(l *l8myfamily.Location) error {
+	this.mtx.RLock()
+	defer this.mtx.RUnlock()
+	if this.closed {
+		return webapi.ErrOverloaded
+	}
+	shard := this.shards[this.shardOf(l.DeviceId)]
+	select {
+	case shard <- l:
+		return nil
+	default:
+	}
+	timer := time.NewTimer(this.queueTimeout)
+	defer timer.Stop()
+	select {
+	case shard <- l:
+		return nil
+	case <-timer.C:
+		return webapi.ErrOverloaded
+	}
+}
+
+// Shutdown stops accepting locations and waits until the queued ones are applied
+func (this *UpdateQueue) Shutdown() {
+	this.mtx.Lock()
+	if this.closed {
+		this.mtx.Unlock()
+		return
+	}
+	this.closed = true
+	for _, shard := range this.shards {
+		close(shard)
+	}
+	this.mtx.Unlock()
+	this.wg.Wait()
+}
+
+func (this *UpdateQueue) shardOf(deviceId string) int {
+	h := fnv.New32a()
+	h.Write([]byte(deviceId))
+	return int(h.Sum32() % uint32(len(this.shards)))
+}
+
+func (this *UpdateQueue) consume(shard chan *l8myfamily.Location) {
+	defer this.wg.Done()
+	for l := range shard {
+		batch, open := this.collect(shard, []*l8myfamily.Location{l})
+		this.apply(batch)
+		if !open {
+			return
+		}
+	}
+}
+
+// collect adds the locations that arrive within the flush window to the batch,
+// it returns early with open=false when the shard is closed by Shutdown so the
+// batch is flushed and nothing is lost
+func (this *UpdateQueue) collect(shard chan *l8myfamily.Location, batch []*l8myfamily.Location) ([]*l8myfamily.Location, bool) {
+	if this.flushWindow <= 0 {
+		return batch, true
+	}
+	timer := time.NewTimer(this.flushWindow)
+	defer timer.Stop()
+	for {
+		select {
+		case l, ok := <-shard:
+			if !ok {
+				return batch, false
+			}
+			batch = append(batch, l)
+		case <-timer.C:
+			return batch, true
+		}
+	}
+}
+
+func (this *UpdateQueue) apply(batch []*l8myfamily.Location) {
+	// The stored positions are read before the batch moves them, each
+	// location then moves its device from the one before it
+	previous := make(map[string]position)
+	for _, l := range batch {
+		if _, ok := previous[l.DeviceId]; !ok {
+			previous[l.DeviceId] = positionOf(getDevice(l.DeviceId, this.vnic))
+		}
+	}
+	devices := updateDevices(batch, this.vnic)
+	for _, l := range batch {
+		afterUpdate(l, devices[l.DeviceId], previous[l.DeviceId])
+		previous[l.DeviceId] = previous[l.DeviceId].next(l)
+	}
+}
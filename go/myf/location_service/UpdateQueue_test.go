@@ -0,0 +1,127 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package location_service
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/saichler/l8myfamiliy/go/myf/config"
+	"github.com/saichler/l8myfamiliy/go/myf/webapi"
+	"github.com/saichler/l8myfamiliy/go/types/l8myfamily"
+	"github.com/saichler/l8types/go/ifs"
+)
+
+// noStoredDevices stubs the stored position lookup of the queue
+func noStoredDevices(t *testing.T) {
+	orig := getDevice
+	getDevice = func(string, ifs.IVNic) *l8myfamily.Device { return nil }
+	t.Cleanup(func() { getDevice = orig })
+}
+
+func TestUpdateQueueKeepsPerDeviceOrderAndDrains(t *testing.T) {
+	noStoredDevices(t)
+	config.Set(&config.ServerConfig{DataDir: t.TempDir()})
+	defer config.Set(config.Default())
+	mtx := &sync.Mutex{}
+	applied := make(map[string][]int64)
+	orig := updateDevices
+	updateDevices = func(batch []*l8myfamily.Location, vnic ifs.IVNic) map[string]*l8myfamily.Device {
+		time.Sleep(time.Millisecond)
+		mtx.Lock()
+		for _, l := range batch {
+			applied[l.DeviceId] = append(applied[l.DeviceId], l.Timestamp)
+		}
+		mtx.Unlock()
+		return nil
+	}
+	defer func() { updateDevices = orig }()
+
+	queue := NewUpdateQueue(4, 1000, time.Second, 5*time.Millisecond, nil)
+	devices := []string{"a", "b", "c", "d", "e"}
+	for ts := int64(1); ts <= 20; ts++ {
+		for _, id := range devices {
+			if err := queue.Enqueue(&l8myfamily.Location{DeviceId: id, Timestamp: ts}); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	queue.Shutdown()
+
+	for _, id := range devices {
+		if len(applied[id]) != 20 {
+			t.Fatalf("device %s: expected 20 drained updates, got %d", id, len(applied[id]))
+		}
+		for i, ts := range applied[id] {
+			if ts != int64(i+1) {
+				t.Fatalf("device %s updates out of order: %v", id, applied[id])
+			}
+		}
+	}
+	if err := queue.Enqueue(&l8myfamily.Location{DeviceId: "a"}); err != webapi.ErrOverloaded {
+		t.Fatalf("a closed queue must reject, got %v", err)
+	}
+}
+
+func TestUpdateQueueRejectsWhenFull(t *testing.T) {
+	noStoredDevices(t)
+	block := make(chan struct{})
+	orig := updateDevices
+	updateDevices = func(batch []*l8myfamily.Location, vnic ifs.IVNic) map[string]*l8myfamily.Device {
+		<-block
+		return nil
+	}
+	defer func() { updateDevices = orig }()
+
+	queue := NewUpdateQueue(1, 1, 10*time.Millisecond, 0, nil)
+	// one in the consumer, one buffered, the third has no room
+	queue.Enqueue(&l8myfamily.Location{DeviceId: "a"})
+	time.Sleep(10 * time.Millisecond)
+	queue.Enqueue(&l8myfamily.Location{DeviceId: "a"})
+	if err := queue.Enqueue(&l8myfamily.Location{DeviceId: "a"}); err != webapi.ErrOverloaded {
+		t.Fatalf("expected ErrOverloaded, got %v", err)
+	}
+	close(block)
+	queue.Shutdown()
+}
+
+func TestUpdateQueueBatchesWithinFlushWindow(t *testing.T) {
+	noStoredDevices(t)
+	config.Set(&config.ServerConfig{DataDir: t.TempDir()})
+	defer config.Set(config.Default())
+	mtx := &sync.Mutex{}
+	var batches [][]*l8myfamily.Location
+	orig := updateDevices
+	updateDevices = func(batch []*l8myfamily.Location, vnic ifs.IVNic) map[string]*l8myfamily.Device {
+		mtx.Lock()
+		batches = append(batches, batch)
+		mtx.Unlock()
+		return nil
+	}
+	defer func() { updateDevices = orig }()
+
+	// A long window, only Shutdown flushes the batch
+	queue := NewUpdateQueue(1, 100, time.Second, time.Hour, nil)
+	for ts := int64(1); ts <= 5; ts++ {
+		queue.Enqueue(&l8myfamily.Location{DeviceId: "a", Timestamp: ts})
+	}
+	queue.Shutdown()
+
+	if len(batches) != 1 || len(batches[0]) != 5 {
+		t.Fatalf("expected one batch of 5 locations flushed on shutdown, got %v", batches)
+	}
+}
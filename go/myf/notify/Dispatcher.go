@@ -0,0 +1,114 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package notify
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/saichler/l8myfamiliy/go/myf/config"
+)
+
+// Channel delivers the events to one kind of integration, e.g. webhooks,
+// push or email. Deliver must not block the caller.
+type Channel interface {
+	Name() string
+	Deliver(event *Event, data []byte)
+}
+
+// dispatcher is the dispatcher Send uses, the webhooks are always a channel
+var dispatcher = NewDispatcher(webhooks{})
+
+// AddChannel adds a channel to the default dispatcher
+func AddChannel(channel Channel) {
+	dispatcher.AddChannel(channel)
+}
+
+// Dispatcher fans out the events to all its channels. The same event type of
+// the same device notifies at most once per de-duplication window, whatever
+// the channels, so an alert raised by several paths reaches the family once.
+type Dispatcher struct {
+	mtx      *sync.Mutex
+	channels []Channel
+	sent     map[string]int64
+	swept    int64
+}
+
+func NewDispatcher(channels ...Channel) *Dispatcher {
+	return &Dispatcher{mtx: &sync.Mutex{}, channels: channels, sent: make(map[string]int64)}
+}
+
+func (this *Dispatcher) AddChannel(channel Channel) {
+	this.mtx.Lock()
+	defer this.mtx.Unlock()
+	this.channels = append(this.channels, channel)
+}
+
+// Send delivers the event to every channel unless it repeats an event
+// notified within the window. An emergency is never suppressed.
+func (this *Dispatcher) Send(event *Event) {
+	if event.Time == 0 {
+		event.Time = time.Now().UnixMilli()
+	}
+	if event.Priority == "" {
+		event.Priority = PriorityNormal
+	}
+	channels, ok := this.admit(event)
+	if !ok {
+		fmt.Println("[Notify] suppressed duplicate ", event.Type, " of ", event.DeviceId)
+		return
+	}
+	data, err := json.Marshal(event)
+	if err != nil {
+		fmt.Println("[Notify] failed to marshal event: ", err.Error())
+		return
+	}
+	for _, channel := range channels {
+		channel.Deliver(event, data)
+	}
+}
+
+// admit records the event and returns the channels to deliver it to, false
+// when it is a duplicate
+func (this *Dispatcher) admit(event *Event) ([]Channel, bool) {
+	window := config.Get().NotifyDedupSeconds * 1000
+	this.mtx.Lock()
+	defer this.mtx.Unlock()
+	if window > 0 && event.Type != EventEmergency && event.DeviceId != "" {
+		this.sweep(event.Time, window)
+		key := event.DeviceId + "/" + event.Type
+		if last, ok := this.sent[key]; ok && event.Time-last < window {
+			return nil, false
+		}
+		this.sent[key] = event.Time
+	}
+	return append([]Channel(nil), this.channels...), true
+}
+
+// sweep forgets the events older than the window, at most once per window
+func (this *Dispatcher) sweep(now, window int64) {
+	if now-this.swept < window {
+		return
+	}
+	this.swept = now
+	for key, last := range this.sent {
+		if now-last >= window {
+			delete(this.sent, key)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package notify
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/saichler/l8myfamiliy/go/myf/config"
+)
+
+type recordingChannel struct {
+	mtx    sync.Mutex
+	events []*Event
+}
+
+func (this *recordingChannel) Name() string {
+	return "recording"
+}
+
+func (this *recordingChannel) Deliver(event *Event, data []byte) {
+	this.mtx.Lock()
+	defer this.mtx.Unlock()
+	this.events = append(this.events, event)
+}
+
+func (this *recordingChannel) count() int {
+	this.mtx.Lock()
+	defer this.mtx.Unlock()
+	return len(this.events)
+}
+
+func TestDispatcherDedupWindow(t *testing.T) {
+	cfg := config.Default()
+	cfg.NotifyDedupSeconds = 60
+	config.Set(cfg)
+	defer config.Set(config.Default())
+
+	push, email := &recordingChannel{}, &recordingChannel{}
+	dispatcher := NewDispatcher(push)
+	dispatcher.AddChannel(email)
+	start := time.Date(2026, time.May, 4, 12, 0, 0, 0, time.UTC).UnixMilli()
+	send := func(eventType, deviceId string, offset time.Duration) {
+		dispatcher.Send(&Event{Type: eventType, DeviceId: deviceId, Time: start + offset.Milliseconds()})
+	}
+
+	send(EventOffline, "d1", 0)
+	send(EventOffline, "d1", 30*time.Second)
+	if push.count() != 1 || email.count() != 1 {
+		t.Fatalf("a repeat within the window must be suppressed on every channel, got %d %d", push.count(), email.count())
+	}
+	// Another device or another event type is a different event
+	send(EventOffline, "d2", 30*time.Second)
+	send(EventLowBattery, "d1", 30*time.Second)
+	if push.count() != 3 {
+		t.Fatalf("distinct events must be notified, got %d", push.count())
+	}
+	// After the window the event notifies again
+	send(EventOffline, "d1", 61*time.Second)
+	if push.count() != 4 || email.count() != 4 {
+		t.Fatalf("the event must notify again after the window, got %d %d", push.count(), email.count())
+	}
+	// An emergency is never suppressed
+	send(EventEmergency, "d1", 62*time.Second)
+	send(EventEmergency, "d1", 63*time.Second)
+	if push.count() != 6 {
+		t.Fatalf("every emergency must be notified, got %d", push.count())
+	}
+	// Once every event aged out nothing is remembered
+	send(EventLowBattery, "d3", 10*time.Minute)
+	if len(dispatcher.sent) != 1 {
+		t.Fatalf("expired events must be forgotten, got %v", dispatcher.sent)
+	}
+}
+
+func TestDispatcherDedupDisabled(t *testing.T) {
+	cfg := config.Default()
+	cfg.NotifyDedupSeconds = 0
+	config.Set(cfg)
+	defer config.Set(config.Default())
+
+	channel := &recordingChannel{}
+	dispatcher := NewDispatcher(channel)
+	for i := 0; i < 3; i++ {
+		dispatcher.Send(&Event{Type: EventOffline, DeviceId: "d1"})
+	}
+	if channel.count() != 3 {
+		t.Fatalf("a disabled window must notify every event, got %d", channel.count())
+	}
+}
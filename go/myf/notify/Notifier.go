@@ -0,0 +1,139 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package notify fans out family events (emergencies, alerts) to the configured
+// integrations, e.g. webhooks.
+package notify
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/saichler/l8myfamiliy/go/myf/config"
+)
+
+const (
+	EventEmergency  = "emergency"
+	EventLowBattery = "low_battery"
+	EventOffline    = "offline"
+	EventLeftHome   = "left_home_region"
+)
+
+type Priority string
+
+const (
+	PriorityNormal = Priority("normal")
+	PriorityHigh   = Priority("high")
+)
+
+const (
+	highPriorityAttempts = 3
+	retryDelay           = time.Second
+)
+
+// Event is the JSON body posted to the webhooks
+type Event struct {
+	Type      string   `json:"type"`
+	Priority  Priority `json:"priority"`
+	Time      int64    `json:"time"`
+	DeviceId  string   `json:"deviceId,omitempty"`
+	FamilyId  string   `json:"familyId,omitempty"`
+	Latitude  float32  `json:"latitude,omitempty"`
+	Longitude float32  `json:"longitude,omitempty"`
+	Message   string   `json:"message,omitempty"`
+}
+
+var client = &http.Client{Timeout: 5 * time.Second}
+
+// Send notifies the event through the default dispatcher, see Dispatcher.Send
+func Send(event *Event) {
+	dispatcher.Send(event)
+}
+
+// webhooks is the channel of the configured webhooks
+type webhooks struct{}
+
+func (webhooks) Name() string {
+	return "webhook"
+}
+
+// Deliver posts the event to every webhook subscribed to its type.
+// Delivery is asynchronous so it never delays the caller, high priority
+// events are retried a few times before giving up.
+func (webhooks) Deliver(event *Event, data []byte) {
+	for _, webhook := range config.Get().Webhooks {
+		if !subscribed(webhook, event.Type) {
+			continue
+		}
+		go deliver(webhook, event, data)
+	}
+}
+
+func subscribed(webhook *config.Webhook, eventType string) bool {
+	if len(webhook.Events) == 0 {
+		return true
+	}
+	for _, e := range webhook.Events {
+		if e == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+func deliver(webhook *config.Webhook, event *Event, data []byte) {
+	attempts := 1
+	if event.Priority == PriorityHigh {
+		attempts = highPriorityAttempts
+	}
+	var err error
+	for i := 0; i < attempts; i++ {
+		if i > 0 {
+			time.Sleep(retryDelay)
+		}
+		err = post(webhook, event, data)
+		if err == nil {
+			return
+		}
+	}
+	fmt.Println("[Notify] failed to deliver ", event.Type, " to ", webhook.Url, ": ", err.Error())
+}
+
+func post(webhook *config.Webhook, event *Event, data []byte) error {
+	req, err := http.NewRequest("POST", webhook.Url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Priority", string(event.Priority))
+	if webhook.Secret != "" {
+		// Signed per attempt, a retry carries a fresh timestamp
+		timestamp := time.Now().Unix()
+		req.Header.Set(TimestampHeader, strconv.FormatInt(timestamp, 10))
+		req.Header.Set(SignatureHeader, SignEvent(webhook.Secret, timestamp, data))
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package notify
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"time"
+)
+
+const (
+	// SignatureHeader is the signature of a webhook post, sha256=<hex>
+	SignatureHeader = "X-Signature"
+	// TimestampHeader is the unix time in seconds the post was signed at
+	TimestampHeader = "X-Signature-Timestamp"
+)
+
+// SignEvent returns the signature header of a webhook post, the hex
+// HMAC-SHA256 with the webhook secret of the timestamp, a dot and the body.
+// The timestamp is signed so a receiver rejecting old posts rejects replays.
+func SignEvent(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyEvent returns true if the signature and timestamp headers of a
+// webhook post match the body and the post was signed within the tolerance
+func VerifyEvent(secret, timestamp, signature string, body []byte, now time.Time, tolerance time.Duration) bool {
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return false
+	}
+	age := now.Sub(time.Unix(ts, 0))
+	if age < -tolerance || age > tolerance {
+		return false
+	}
+	return hmac.Equal([]byte(signature), []byte(SignEvent(secret, ts, body)))
+}
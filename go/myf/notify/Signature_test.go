@@ -0,0 +1,81 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package notify
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/saichler/l8myfamiliy/go/myf/config"
+)
+
+// The test vector of the README webhook verification recipe
+const (
+	vectorSecret    = "my-webhook-secret"
+	vectorTimestamp = int64(1700000000)
+	vectorBody      = `{"type":"emergency","priority":"high","time":1700000000000,"deviceId":"emma-phone","familyId":"smith"}`
+	vectorSignature = "sha256=1f399549c134e910ef7f96227b6983440c6d2d1655eefdd3cde0641bc2e0aa80"
+)
+
+func TestSignEventVector(t *testing.T) {
+	if got := SignEvent(vectorSecret, vectorTimestamp, []byte(vectorBody)); got != vectorSignature {
+		t.Fatalf("unexpected signature %s", got)
+	}
+	signed := time.Unix(vectorTimestamp, 0)
+	if !VerifyEvent(vectorSecret, "1700000000", vectorSignature, []byte(vectorBody), signed.Add(time.Minute), 5*time.Minute) {
+		t.Fatal("the vector must verify within the tolerance")
+	}
+	if VerifyEvent(vectorSecret, "1700000000", vectorSignature, []byte(vectorBody), signed.Add(time.Hour), 5*time.Minute) {
+		t.Fatal("a replayed post must be rejected after the tolerance")
+	}
+	if VerifyEvent(vectorSecret, "1700000001", vectorSignature, []byte(vectorBody), signed, 5*time.Minute) {
+		t.Fatal("a changed timestamp must be rejected")
+	}
+	if VerifyEvent("other-secret", "1700000000", vectorSignature, []byte(vectorBody), signed, 5*time.Minute) {
+		t.Fatal("another secret must be rejected")
+	}
+}
+
+func TestWebhookPostSigned(t *testing.T) {
+	headers := make(chan http.Header, 2)
+	bodies := make(chan []byte, 2)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		headers <- r.Header
+		bodies <- body
+	}))
+	defer srv.Close()
+
+	event := &Event{Type: EventEmergency, Priority: PriorityHigh, DeviceId: "emma-phone"}
+	data := []byte(vectorBody)
+	if err := post(&config.Webhook{Url: srv.URL, Secret: vectorSecret}, event, data); err != nil {
+		t.Fatal(err)
+	}
+	header, body := <-headers, <-bodies
+	if !VerifyEvent(vectorSecret, header.Get(TimestampHeader), header.Get(SignatureHeader), body, time.Now(), time.Minute) {
+		t.Fatalf("expected a signed post, got %v", header)
+	}
+
+	if err := post(&config.Webhook{Url: srv.URL}, event, data); err != nil {
+		t.Fatal(err)
+	}
+	if header := <-headers; header.Get(SignatureHeader) != "" || header.Get(TimestampHeader) != "" {
+		t.Fatalf("a webhook without a secret must be posted unsigned, got %v", header)
+	}
+}
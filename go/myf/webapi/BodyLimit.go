@@ -0,0 +1,43 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package webapi
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/saichler/l8myfamiliy/go/myf/config"
+)
+
+// ErrBodyTooLarge is returned for a request body over the configured limit
+var ErrBodyTooLarge = errors.New("request body too large")
+
+// LimitBody caps the request body of next at the configured maxBodyBytes, a
+// handler reading past it gets an error that DecodeStatus maps to 413
+func LimitBody(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if limit := config.Get().MaxBodyBytes; limit > 0 && r.Body != nil {
+			r.Body = http.MaxBytesReader(w, r.Body, limit)
+		}
+		next(w, r)
+	}
+}
+
+// tooLarge reports whether err is a read past the body limit
+func tooLarge(err error) bool {
+	var maxBytes *http.MaxBytesError
+	return errors.Is(err, ErrBodyTooLarge) || errors.As(err, &maxBytes)
+}
@@ -0,0 +1,81 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package webapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/saichler/l8myfamiliy/go/myf/config"
+	"github.com/saichler/l8myfamiliy/go/types/l8myfamily"
+)
+
+func TestLimitBody(t *testing.T) {
+	cfg := config.Default()
+	cfg.MaxBodyBytes = 1024
+	config.Set(cfg)
+	defer config.Set(config.Default())
+
+	decode := LimitBody(func(w http.ResponseWriter, r *http.Request) {
+		if err := DecodeBody(r, &l8myfamily.Location{}); err != nil {
+			http.Error(w, err.Error(), DecodeStatus(err))
+		}
+	})
+	post := func(handler http.HandlerFunc, body []byte, encoding string) int {
+		r := httptest.NewRequest("POST", "/my-family/location", bytes.NewReader(body))
+		r.Header.Set("Content-Encoding", encoding)
+		w := httptest.NewRecorder()
+		handler(w, r)
+		return w.Code
+	}
+
+	if code := post(decode, []byte(`{"deviceId":"d1","latitude":1}`), ""); code != http.StatusOK {
+		t.Fatalf("a small body must be accepted, got %d", code)
+	}
+	oversized := []byte(`{"deviceId":"` + strings.Repeat("d", 2048) + `"}`)
+	if code := post(decode, oversized, ""); code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("an oversized body must be answered 413, got %d", code)
+	}
+	// A small gzip body that inflates past the limit is refused too
+	bomb, _ := GzipBytes(oversized)
+	if len(bomb) > 1024 {
+		t.Fatalf("the compressed body must be under the limit, it has %d bytes", len(bomb))
+	}
+	if code := post(decode, bomb, "gzip"); code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("an oversized inflated body must be answered 413, got %d", code)
+	}
+
+	// The JSON endpoints decode the limited body directly
+	plain := LimitBody(func(w http.ResponseWriter, r *http.Request) {
+		body := map[string]string{}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			http.Error(w, err.Error(), DecodeStatus(err))
+		}
+	})
+	if code := post(plain, oversized, ""); code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("an oversized JSON body must be answered 413, got %d", code)
+	}
+
+	cfg.MaxBodyBytes = 0
+	config.Set(cfg)
+	if code := post(decode, oversized, ""); code != http.StatusOK {
+		t.Fatalf("a disabled limit must accept any body, got %d", code)
+	}
+}
@@ -0,0 +1,68 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package webapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// The JSON contract of the device and location payloads is the protobuf JSON
+// mapping of l8myfamily.Device and l8myfamily.Location with the canonical,
+// lowerCamelCase field names, e.g. deviceId and idempotencyKey. It is what
+// EncodeBody writes, so the agents build the proto message and encode it
+// instead of hand writing the field names. DecodeBody also accepts the proto
+// field names, e.g. device_id, for agents released before the contract, unless
+// strictJsonNames is set.
+
+// JSONNames returns the canonical JSON field names of the message, sorted
+func JSONNames(msg proto.Message) []string {
+	fields := msg.ProtoReflect().Descriptor().Fields()
+	names := make([]string, 0, fields.Len())
+	for i := 0; i < fields.Len(); i++ {
+		names = append(names, fields.Get(i).JSONName())
+	}
+	sort.Strings(names)
+	return names
+}
+
+// CheckJSONNames returns an error if a top level field of the JSON object is
+// not a canonical field name of the message, unknown fields are ignored as
+// DecodeBody discards them
+func CheckJSONNames(data []byte, msg proto.Message) error {
+	object := make(map[string]json.RawMessage)
+	if err := json.Unmarshal(data, &object); err != nil {
+		return err
+	}
+	fields := msg.ProtoReflect().Descriptor().Fields()
+	var legacy []string
+	for name := range object {
+		field := fields.ByName(protoreflect.Name(name))
+		if field != nil && field.JSONName() != name {
+			legacy = append(legacy, name+" (use "+field.JSONName()+")")
+		}
+	}
+	if len(legacy) > 0 {
+		sort.Strings(legacy)
+		return fmt.Errorf("non canonical JSON field names: %s", strings.Join(legacy, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package webapi
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/saichler/l8myfamiliy/go/myf/config"
+	"github.com/saichler/l8myfamiliy/go/types/l8myfamily"
+	"google.golang.org/protobuf/proto"
+)
+
+// jsonKeys returns the sorted top level keys of a JSON object
+func jsonKeys(t *testing.T, data []byte) []string {
+	object := make(map[string]interface{})
+	if err := json.Unmarshal(data, &object); err != nil {
+		t.Fatal(err)
+	}
+	keys := make([]string, 0, len(object))
+	for key := range object {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func decodeJSON(body string, msg proto.Message) error {
+	r := httptest.NewRequest("POST", "/my-family/location", strings.NewReader(body))
+	r.Header.Set("Content-Type", ContentTypeJSON)
+	return DecodeBody(r, msg)
+}
+
+func TestJSONContract(t *testing.T) {
+	device := &l8myfamily.Device{Id: "d1", FamilyId: "fam", Name: "phone", IdempotencyKey: "k1", SigningKey: "s1"}
+	location := &l8myfamily.Location{DeviceId: "d1", Latitude: 1.5, Longitude: 2.5, IdempotencyKey: "k1",
+		Source: l8myfamily.LocationSource_LOCATION_SOURCE_ANDROID, Timestamp: 1700000000000, SignedTime: 1700000000000}
+	contract := map[proto.Message]string{
+		device:   "familyId,id,idempotencyKey,name,signingKey",
+		location: "deviceId,idempotencyKey,latitude,longitude,signedTime,source,timestamp",
+	}
+	for msg, want := range contract {
+		data, err := EncodeBody(ContentTypeJSON, msg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if keys := strings.Join(jsonKeys(t, data), ","); keys != want {
+			t.Fatalf("the JSON names of %T drifted from the contract, got %s want %s", msg, keys, want)
+		}
+		decoded := msg.ProtoReflect().New().Interface()
+		if err := decodeJSON(string(data), decoded); err != nil || !proto.Equal(decoded, msg) {
+			t.Fatalf("%T must round trip, got %v %v", msg, decoded, err)
+		}
+		if err := CheckJSONNames(data, decoded); err != nil {
+			t.Fatalf("the encoded %T must be canonical, got %v", msg, err)
+		}
+	}
+}
+
+func TestLegacyJSONNames(t *testing.T) {
+	legacy := `{"device_id":"d1","latitude":1.5,"idempotency_key":"k1","battery":50}`
+	location := &l8myfamily.Location{}
+	if err := decodeJSON(legacy, location); err != nil || location.DeviceId != "d1" || location.IdempotencyKey != "k1" {
+		t.Fatalf("the proto field names must still decode, got %v %v", location, err)
+	}
+
+	cfg := config.Default()
+	cfg.StrictJSONNames = true
+	config.Set(cfg)
+	defer config.Set(config.Default())
+	err := decodeJSON(legacy, &l8myfamily.Location{})
+	if err == nil || !strings.Contains(err.Error(), "device_id (use deviceId)") || DecodeStatus(err) != 400 {
+		t.Fatalf("strict names must reject the proto field names, got %v", err)
+	}
+	if err := decodeJSON(`{"deviceId":"d1","latitude":1.5,"unknown":1}`, &l8myfamily.Location{}); err != nil {
+		t.Fatalf("canonical and unknown names must pass, got %v", err)
+	}
+}
@@ -0,0 +1,94 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package webapi
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"os"
+)
+
+// CertFileSuffix is appended to the certificate name of the web server to
+// get the certificate file it serves
+const CertFileSuffix = ".crt"
+
+// TLSMinVersion is the minimum TLS version of the web server, it does not set
+// one so it is the crypto/tls default
+const TLSMinVersion = tls.VersionTLS12
+
+// Diag is the TLS diagnostics of the server for self-hosters debugging
+// certificate issues, it carries no secrets
+type Diag struct {
+	CertSubject string `json:"certSubject,omitempty"`
+	CertIssuer  string `json:"certIssuer,omitempty"`
+	// CertNotAfter is the certificate expiry in unix millis
+	CertNotAfter  int64  `json:"certNotAfter,omitempty"`
+	CertError     string `json:"certError,omitempty"`
+	TLSMinVersion string `json:"tlsMinVersion"`
+	// TLSVersion is the version negotiated by the request connection
+	TLSVersion string `json:"tlsVersion,omitempty"`
+}
+
+// ReadDiag reads the certificate of the PEM file, a certificate that cannot
+// be read is reported in CertError
+func ReadDiag(certFile string) *Diag {
+	diag := &Diag{TLSMinVersion: tls.VersionName(TLSMinVersion)}
+	cert, err := readCertificate(certFile)
+	if err != nil {
+		diag.CertError = err.Error()
+		return diag
+	}
+	diag.CertSubject = cert.Subject.String()
+	diag.CertIssuer = cert.Issuer.String()
+	diag.CertNotAfter = cert.NotAfter.UnixMilli()
+	return diag
+}
+
+// readCertificate parses the first certificate of the PEM file
+func readCertificate(certFile string) (*x509.Certificate, error) {
+	data, err := os.ReadFile(certFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the certificate: %w", err)
+	}
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			return nil, fmt.Errorf("no certificate in %s", certFile)
+		}
+		if block.Type == "CERTIFICATE" {
+			return x509.ParseCertificate(block.Bytes)
+		}
+	}
+}
+
+// DiagHandler returns the unauthenticated handler of the diag endpoint, the
+// certificate is read on every request so a renewed one is reported
+func DiagHandler(certFile string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !RequireMethod(w, r, http.MethodGet) {
+			return
+		}
+		diag := ReadDiag(certFile)
+		if r.TLS != nil {
+			diag.TLSVersion = tls.VersionName(r.TLS.Version)
+		}
+		WriteJSON(w, http.StatusOK, diag)
+	}
+}
@@ -0,0 +1,61 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package webapi
+
+import (
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDiagHandler(t *testing.T) {
+	certFile := filepath.Join(t.TempDir(), "server"+CertFileSuffix)
+	mux := http.NewServeMux()
+	mux.HandleFunc(Prefix+"diag", DiagHandler(certFile))
+	srv := httptest.NewTLSServer(mux)
+	defer srv.Close()
+	cert := srv.Certificate()
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw}), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := srv.Client().Get(srv.URL + Prefix + "diag")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	diag := &Diag{}
+	if err := json.NewDecoder(resp.Body).Decode(diag); err != nil {
+		t.Fatal(err)
+	}
+	if diag.CertSubject != cert.Subject.String() || diag.CertNotAfter != cert.NotAfter.UnixMilli() || diag.CertError != "" {
+		t.Fatalf("unexpected certificate diagnostics %+v", diag)
+	}
+	if diag.TLSMinVersion != "TLS 1.2" || diag.TLSVersion == "" {
+		t.Fatalf("unexpected TLS versions %+v", diag)
+	}
+}
+
+func TestReadDiagMissingCertificate(t *testing.T) {
+	diag := ReadDiag(filepath.Join(t.TempDir(), "missing"+CertFileSuffix))
+	if diag.CertError == "" || diag.CertSubject != "" {
+		t.Fatalf("a missing certificate must be reported, got %+v", diag)
+	}
+}
@@ -0,0 +1,113 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package webapi
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/saichler/l8myfamiliy/go/myf/config"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	ContentTypeJSON     = "application/json"
+	ContentTypeProtobuf = "application/x-protobuf"
+)
+
+// ErrUnsupportedMediaType is returned for a body that is neither JSON nor protobuf
+var ErrUnsupportedMediaType = errors.New("unsupported content type")
+
+// DecodeBody unmarshals the request body into msg. The encoding is chosen by
+// the Content-Type header only, application/x-protobuf is the binary wire
+// format and application/json, or no content type, is protobuf JSON.
+// A body sent with Content-Encoding: gzip is decompressed first.
+func DecodeBody(r *http.Request, msg proto.Message) error {
+	contentType := ContentTypeJSON
+	if header := r.Header.Get("Content-Type"); header != "" {
+		mediaType, _, err := mime.ParseMediaType(header)
+		if err != nil {
+			return fmt.Errorf("%w: %s", ErrUnsupportedMediaType, header)
+		}
+		contentType = mediaType
+	}
+	body, err := requestBody(r)
+	if err != nil {
+		return fmt.Errorf("%w: content encoding %s", err, r.Header.Get("Content-Encoding"))
+	}
+	defer body.Close()
+	// A gzip body is also capped once decompressed
+	reader := io.Reader(body)
+	limit := config.Get().MaxBodyBytes
+	if limit > 0 {
+		reader = io.LimitReader(body, limit+1)
+	}
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return err
+	}
+	if limit > 0 && int64(len(data)) > limit {
+		return ErrBodyTooLarge
+	}
+	switch contentType {
+	case ContentTypeProtobuf:
+		err = proto.Unmarshal(data, msg)
+	case ContentTypeJSON:
+		if config.Get().StrictJSONNames {
+			if err := CheckJSONNames(data, msg); err != nil {
+				return err
+			}
+		}
+		// The fields of a newer schema are ignored, see LocationSchemaVersion
+		err = protojson.UnmarshalOptions{DiscardUnknown: true}.Unmarshal(data, msg)
+	default:
+		return fmt.Errorf("%w: %s", ErrUnsupportedMediaType, contentType)
+	}
+	if err == nil && config.Get().LogUnknownFields {
+		if unknown := UnknownFields(contentType, data, msg); len(unknown) > 0 {
+			fmt.Println("[Decode] ignored unknown fields of ", r.URL.Path, ": ", strings.Join(unknown, ", "))
+		}
+	}
+	return err
+}
+
+// EncodeBody marshals msg in the given content type, it is the agent side of
+// DecodeBody
+func EncodeBody(contentType string, msg proto.Message) ([]byte, error) {
+	switch contentType {
+	case ContentTypeProtobuf:
+		return proto.Marshal(msg)
+	case ContentTypeJSON:
+		return protojson.Marshal(msg)
+	}
+	return nil, fmt.Errorf("%w: %s", ErrUnsupportedMediaType, contentType)
+}
+
+// DecodeStatus maps a DecodeBody or a body read error to its http status code
+func DecodeStatus(err error) int {
+	if errors.Is(err, ErrUnsupportedMediaType) {
+		return http.StatusUnsupportedMediaType
+	}
+	if tooLarge(err) {
+		return http.StatusRequestEntityTooLarge
+	}
+	return http.StatusBadRequest
+}
@@ -0,0 +1,62 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package webapi
+
+import (
+	"bytes"
+	"errors"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/saichler/l8myfamiliy/go/types/l8myfamily"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestBodyRoundTrip(t *testing.T) {
+	location := &l8myfamily.Location{DeviceId: "d1", Latitude: 32.1, Longitude: 34.8,
+		Emergency: true, Source: l8myfamily.LocationSource_LOCATION_SOURCE_ANDROID, Accuracy: 12, Timestamp: 1700000000000}
+	for _, contentType := range []string{ContentTypeJSON, ContentTypeProtobuf} {
+		data, err := EncodeBody(contentType, location)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r := httptest.NewRequest("POST", "/my-family/location", bytes.NewReader(data))
+		r.Header.Set("Content-Type", contentType+"; charset=utf-8")
+		decoded := &l8myfamily.Location{}
+		if err := DecodeBody(r, decoded); err != nil {
+			t.Fatalf("%s: %v", contentType, err)
+		}
+		if !proto.Equal(location, decoded) {
+			t.Fatalf("%s: expected %v got %v", contentType, location, decoded)
+		}
+	}
+}
+
+func TestDecodeBodyNegotiatesOnContentTypeOnly(t *testing.T) {
+	data, _ := EncodeBody(ContentTypeProtobuf, &l8myfamily.Location{DeviceId: "d1"})
+	// A protobuf body sent as JSON must not be sniffed and accepted
+	r := httptest.NewRequest("POST", "/my-family/location", bytes.NewReader(data))
+	r.Header.Set("Content-Type", ContentTypeJSON)
+	if err := DecodeBody(r, &l8myfamily.Location{}); err == nil {
+		t.Fatal("a protobuf body labeled JSON must fail")
+	}
+	r = httptest.NewRequest("POST", "/my-family/location", bytes.NewReader(data))
+	r.Header.Set("Content-Type", "text/plain")
+	err := DecodeBody(r, &l8myfamily.Location{})
+	if !errors.Is(err, ErrUnsupportedMediaType) || DecodeStatus(err) != 415 {
+		t.Fatalf("expected unsupported media type, got %v", err)
+	}
+}
@@ -0,0 +1,103 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package webapi
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/saichler/l8myfamiliy/go/myf/config"
+)
+
+// GzipThreshold is the body size from which the agents gzip a request body,
+// smaller bodies do not gain from the gzip header overhead
+const GzipThreshold = 1024
+
+// Gzip wraps a handler so its response is gzipped when the client accepts it
+// and compression is enabled in the server config
+func Gzip(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept-Encoding")
+		if !config.Get().Compression || !acceptsGzip(r) {
+			next(w, r)
+			return
+		}
+		gz := gzip.NewWriter(w)
+		defer gz.Close()
+		w.Header().Set("Content-Encoding", "gzip")
+		next(&gzipResponseWriter{ResponseWriter: w, writer: gz}, r)
+	}
+}
+
+func acceptsGzip(r *http.Request) bool {
+	for _, encoding := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		if strings.TrimSpace(strings.SplitN(encoding, ";", 2)[0]) == "gzip" {
+			return true
+		}
+	}
+	return false
+}
+
+type gzipResponseWriter struct {
+	http.ResponseWriter
+	writer *gzip.Writer
+}
+
+func (this *gzipResponseWriter) WriteHeader(status int) {
+	this.Header().Del("Content-Length")
+	this.ResponseWriter.WriteHeader(status)
+}
+
+func (this *gzipResponseWriter) Write(data []byte) (int, error) {
+	return this.writer.Write(data)
+}
+
+// Flush sends the data compressed so far, for streamed responses
+func (this *gzipResponseWriter) Flush() {
+	this.writer.Flush()
+	if flusher, ok := this.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// GzipBytes compresses a request body, the request must carry
+// Content-Encoding: gzip
+func GzipBytes(data []byte) ([]byte, error) {
+	buff := &bytes.Buffer{}
+	gz := gzip.NewWriter(buff)
+	if _, err := gz.Write(data); err != nil {
+		return nil, err
+	}
+	if err := gz.Close(); err != nil {
+		return nil, err
+	}
+	return buff.Bytes(), nil
+}
+
+// requestBody returns the request body, decompressing it when it was sent
+// with Content-Encoding: gzip
+func requestBody(r *http.Request) (io.ReadCloser, error) {
+	switch strings.TrimSpace(r.Header.Get("Content-Encoding")) {
+	case "", "identity":
+		return r.Body, nil
+	case "gzip":
+		return gzip.NewReader(r.Body)
+	}
+	return nil, ErrUnsupportedMediaType
+}
@@ -0,0 +1,84 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package webapi
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/saichler/l8myfamiliy/go/myf/config"
+	"github.com/saichler/l8myfamiliy/go/types/l8myfamily"
+)
+
+func TestGzipResponse(t *testing.T) {
+	payload := strings.Repeat("device ", 500)
+	handler := Gzip(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(payload))
+	})
+
+	r := httptest.NewRequest("GET", "/my-family/devices", nil)
+	r.Header.Set("Accept-Encoding", "br, gzip;q=0.8")
+	w := httptest.NewRecorder()
+	handler(w, r)
+	if w.Header().Get("Content-Encoding") != "gzip" {
+		t.Fatal("expected a gzipped response")
+	}
+	gz, err := gzip.NewReader(w.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := io.ReadAll(gz)
+	if string(data) != payload {
+		t.Fatal("gzipped response does not match the payload")
+	}
+
+	cfg := config.Default()
+	cfg.Compression = false
+	config.Set(cfg)
+	defer config.Set(config.Default())
+	w = httptest.NewRecorder()
+	handler(w, r)
+	if w.Header().Get("Content-Encoding") != "" || w.Body.String() != payload {
+		t.Fatal("compression is disabled, the response must be plain")
+	}
+}
+
+func TestDecodeGzippedBody(t *testing.T) {
+	location := &l8myfamily.Location{DeviceId: "d1", Latitude: 1}
+	data, _ := EncodeBody(ContentTypeProtobuf, location)
+	compressed, err := GzipBytes(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := httptest.NewRequest("POST", "/my-family/location", bytes.NewReader(compressed))
+	r.Header.Set("Content-Type", ContentTypeProtobuf)
+	r.Header.Set("Content-Encoding", "gzip")
+	decoded := &l8myfamily.Location{}
+	if err := DecodeBody(r, decoded); err != nil || decoded.DeviceId != "d1" {
+		t.Fatalf("unexpected %v %v", decoded, err)
+	}
+
+	r = httptest.NewRequest("POST", "/my-family/location", bytes.NewReader(data))
+	r.Header.Set("Content-Encoding", "br")
+	if err := DecodeBody(r, decoded); DecodeStatus(err) != http.StatusUnsupportedMediaType {
+		t.Fatalf("expected 415 for an unknown content encoding, got %v", err)
+	}
+}
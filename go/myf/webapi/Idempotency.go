@@ -0,0 +1,96 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package webapi
+
+import (
+	"sync"
+	"time"
+)
+
+// IdempotencyCache remembers the idempotency keys seen within a time window,
+// so a post retried by an agent after a timeout is not applied twice
+type IdempotencyCache struct {
+	mtx       *sync.Mutex
+	seen      map[string]time.Time
+	window    time.Duration
+	lastPrune time.Time
+}
+
+func NewIdempotencyCache(window time.Duration) *IdempotencyCache {
+	return &IdempotencyCache{mtx: &sync.Mutex{}, seen: make(map[string]time.Time), window: window}
+}
+
+// Seen records the key and returns true if it was already recorded within
+// the window. An empty key is never seen, as the client did not ask for it.
+func (this *IdempotencyCache) Seen(key string) bool {
+	if key == "" || this.window <= 0 {
+		return false
+	}
+	now := time.Now()
+	this.mtx.Lock()
+	defer this.mtx.Unlock()
+	if this.contains(key, now) {
+		return true
+	}
+	this.seen[key] = now
+	return false
+}
+
+// Contains returns true if the key was recorded within the window, without
+// recording it. A post checks its key with Contains and records it with
+// Remember only once it is accepted, so the retry of a rejected post is
+// processed again.
+func (this *IdempotencyCache) Contains(key string) bool {
+	if key == "" || this.window <= 0 {
+		return false
+	}
+	this.mtx.Lock()
+	defer this.mtx.Unlock()
+	return this.contains(key, time.Now())
+}
+
+// Remember records the key, an empty key is not recorded
+func (this *IdempotencyCache) Remember(key string) {
+	if key == "" || this.window <= 0 {
+		return
+	}
+	now := time.Now()
+	this.mtx.Lock()
+	defer this.mtx.Unlock()
+	this.prune(now)
+	this.seen[key] = now
+}
+
+// contains prunes the expired keys and returns true if the key was recorded
+// within the window, the caller holds the lock
+func (this *IdempotencyCache) contains(key string, now time.Time) bool {
+	this.prune(now)
+	t, ok := this.seen[key]
+	return ok && now.Sub(t) <= this.window
+}
+
+// prune drops the expired keys at most once per window
+func (this *IdempotencyCache) prune(now time.Time) {
+	if now.Sub(this.lastPrune) <= this.window {
+		return
+	}
+	for k, t := range this.seen {
+		if now.Sub(t) > this.window {
+			delete(this.seen, k)
+		}
+	}
+	this.lastPrune = now
+}
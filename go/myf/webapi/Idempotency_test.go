@@ -0,0 +1,53 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package webapi
+
+import (
+	"testing"
+	"time"
+)
+
+func TestIdempotencyCache(t *testing.T) {
+	cache := NewIdempotencyCache(50 * time.Millisecond)
+	if cache.Seen("k1") {
+		t.Fatal("first use of a key must not be seen")
+	}
+	if !cache.Seen("k1") {
+		t.Fatal("a retry with the same key must be seen")
+	}
+	if cache.Seen("") || cache.Seen("") {
+		t.Fatal("an empty key is never deduplicated")
+	}
+	time.Sleep(60 * time.Millisecond)
+	if cache.Seen("k1") {
+		t.Fatal("a key must expire after the window")
+	}
+}
+
+func TestIdempotencyCacheContainsDoesNotRemember(t *testing.T) {
+	cache := NewIdempotencyCache(time.Minute)
+	if cache.Contains("k1") || cache.Contains("k1") {
+		t.Fatal("a key that was not remembered must not be contained")
+	}
+	cache.Remember("k1")
+	if !cache.Contains("k1") || !cache.Seen("k1") {
+		t.Fatal("a remembered key must be contained")
+	}
+	cache.Remember("")
+	if cache.Contains("") {
+		t.Fatal("an empty key is never remembered")
+	}
+}
@@ -0,0 +1,86 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package webapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/saichler/l8myfamiliy/go/types/l8myfamily"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// LocationSchemaVersion is the newest Location post schema this code knows.
+// Schema 1 is every field up to movement, the posts before the schema was
+// versioned. Schema 2 adds schema_version.
+const LocationSchemaVersion = 2
+
+// locationSchemaLastField is the highest Location field number of each
+// schema version, a new version adds the fields after the previous one
+var locationSchemaLastField = []protoreflect.FieldNumber{1: 27, 2: 28}
+
+// NegotiateSchema returns the schema version an agent posts to a server that
+// supports serverVersion, a server that does not tell supports schema 1
+func NegotiateSchema(serverVersion int32) int32 {
+	if serverVersion < 1 {
+		return 1
+	}
+	if serverVersion > LocationSchemaVersion {
+		return LocationSchemaVersion
+	}
+	return serverVersion
+}
+
+// DowngradeLocation clears the fields of the location the negotiated schema
+// of the server does not have, and sets the schema version when it has it
+func DowngradeLocation(l *l8myfamily.Location, serverVersion int32) {
+	version := NegotiateSchema(serverVersion)
+	l.SchemaVersion = version
+	last := locationSchemaLastField[version]
+	m := l.ProtoReflect()
+	m.Range(func(field protoreflect.FieldDescriptor, _ protoreflect.Value) bool {
+		if field.Number() > last {
+			m.Clear(field)
+		}
+		return true
+	})
+}
+
+// UnknownFields returns the names of the top level JSON fields that are not
+// fields of the message, or a note of the unknown bytes of a protobuf body
+func UnknownFields(contentType string, data []byte, msg proto.Message) []string {
+	if contentType == ContentTypeProtobuf {
+		if unknown := msg.ProtoReflect().GetUnknown(); len(unknown) > 0 {
+			return []string{fmt.Sprintf("%d bytes of protobuf fields", len(unknown))}
+		}
+		return nil
+	}
+	object := make(map[string]json.RawMessage)
+	if err := json.Unmarshal(data, &object); err != nil {
+		return nil
+	}
+	fields := msg.ProtoReflect().Descriptor().Fields()
+	var unknown []string
+	for name := range object {
+		if fields.ByJSONName(name) == nil && fields.ByName(protoreflect.Name(name)) == nil {
+			unknown = append(unknown, name)
+		}
+	}
+	sort.Strings(unknown)
+	return unknown
+}
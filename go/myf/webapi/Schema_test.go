@@ -0,0 +1,89 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package webapi
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/saichler/l8myfamiliy/go/types/l8myfamily"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestDowngradeLocation(t *testing.T) {
+	location := func() *l8myfamily.Location {
+		return &l8myfamily.Location{DeviceId: "d1", Latitude: 1, Longitude: 2, Battery: 50, Coarse: true}
+	}
+	for server, want := range map[int32]int32{0: 0, 1: 0, 2: 2, 7: LocationSchemaVersion} {
+		l := location()
+		DowngradeLocation(l, server)
+		if l.SchemaVersion != want {
+			t.Fatalf("server schema %d: expected the posted version %d, got %d", server, want, l.SchemaVersion)
+		}
+		// The schema 1 fields are kept for every server
+		if l.DeviceId != "d1" || l.Latitude != 1 || l.Battery != 50 || !l.Coarse {
+			t.Fatalf("server schema %d: a schema 1 field was dropped, got %v", server, l)
+		}
+	}
+}
+
+func TestLocationSchemaRoundTrip(t *testing.T) {
+	decode := func(contentType string, data []byte) *l8myfamily.Location {
+		t.Helper()
+		r := httptest.NewRequest("POST", "/my-family/location", bytes.NewReader(data))
+		r.Header.Set("Content-Type", contentType)
+		l := &l8myfamily.Location{}
+		if err := DecodeBody(r, l); err != nil {
+			t.Fatalf("%s: %v", contentType, err)
+		}
+		return l
+	}
+	sent := &l8myfamily.Location{DeviceId: "d1", Latitude: 1, Longitude: 2, Accuracy: 5}
+
+	// An agent of this schema posting to a server of this schema, and to a
+	// server of schema 1 that gets no schema version
+	for _, server := range []int32{LocationSchemaVersion, 1} {
+		l := proto.Clone(sent).(*l8myfamily.Location)
+		DowngradeLocation(l, server)
+		for _, contentType := range []string{ContentTypeJSON, ContentTypeProtobuf} {
+			data, _ := EncodeBody(contentType, l)
+			if got := decode(contentType, data); !proto.Equal(got, l) {
+				t.Fatalf("schema %d %s: expected %v, got %v", server, contentType, l, got)
+			}
+		}
+	}
+
+	// A newer agent sends a field this server does not know, it is ignored
+	json := []byte(`{"deviceId":"d1","latitude":1,"longitude":2,"accuracy":5,"schemaVersion":3,"floor":2}`)
+	if got := decode(ContentTypeJSON, json); got.DeviceId != "d1" || got.Accuracy != 5 || got.SchemaVersion != 3 {
+		t.Fatalf("a newer JSON schema must decode leniently, got %v", got)
+	}
+	if unknown := UnknownFields(ContentTypeJSON, json, &l8myfamily.Location{}); !reflect.DeepEqual(unknown, []string{"floor"}) {
+		t.Fatalf("expected the unknown floor field, got %v", unknown)
+	}
+	wire, _ := proto.Marshal(sent)
+	wire = protowire.AppendVarint(protowire.AppendTag(wire, 99, protowire.VarintType), 2)
+	got := decode(ContentTypeProtobuf, wire)
+	if got.DeviceId != "d1" || got.Accuracy != 5 {
+		t.Fatalf("a newer protobuf schema must decode leniently, got %v", got)
+	}
+	if unknown := UnknownFields(ContentTypeProtobuf, wire, got); len(unknown) != 1 {
+		t.Fatalf("expected the unknown protobuf field reported, got %v", unknown)
+	}
+}
@@ -0,0 +1,54 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package webapi
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// ValidateService checks the configuration of a service before its
+// activation, so a misconfigured service fails to start with an error
+// instead of panicking on its first request. The primary keys must be
+// fields of the service item and the area must not be 0, the area of the
+// framework services.
+func ValidateService(serviceName string, serviceArea byte, item interface{}, primaryKeys ...string) error {
+	if serviceName == "" {
+		return fmt.Errorf("service name is empty")
+	}
+	if serviceArea == 0 {
+		return fmt.Errorf("service %s: area 0 is reserved", serviceName)
+	}
+	if item == nil {
+		return fmt.Errorf("service %s: no service item", serviceName)
+	}
+	if len(primaryKeys) == 0 {
+		return fmt.Errorf("service %s: no primary keys", serviceName)
+	}
+	itemType := reflect.TypeOf(item)
+	if itemType.Kind() == reflect.Ptr {
+		itemType = itemType.Elem()
+	}
+	if itemType.Kind() != reflect.Struct {
+		return fmt.Errorf("service %s: item %s is not a struct", serviceName, itemType)
+	}
+	for _, key := range primaryKeys {
+		if _, ok := itemType.FieldByName(key); !ok {
+			return fmt.Errorf("service %s: primary key %s is not a field of %s", serviceName, key, itemType.Name())
+		}
+	}
+	return nil
+}
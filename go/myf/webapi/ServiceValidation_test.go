@@ -0,0 +1,41 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package webapi
+
+import (
+	"testing"
+
+	"github.com/saichler/l8myfamiliy/go/types/l8myfamily"
+)
+
+func TestValidateService(t *testing.T) {
+	if err := ValidateService("Family", 53, &l8myfamily.Device{}, "Id"); err != nil {
+		t.Fatalf("a valid service must pass: %v", err)
+	}
+	invalid := map[string]error{
+		"area 0":             ValidateService("Family", 0, &l8myfamily.Device{}, "Id"),
+		"missing key field":  ValidateService("Family", 53, &l8myfamily.Device{}, "DeviceId"),
+		"no primary key":     ValidateService("Family", 53, &l8myfamily.Device{}),
+		"no service item":    ValidateService("Family", 53, nil, "Id"),
+		"empty name":         ValidateService("", 53, &l8myfamily.Device{}, "Id"),
+		"item is not struct": ValidateService("Family", 53, "device", "Id"),
+	}
+	for name, err := range invalid {
+		if err == nil {
+			t.Errorf("%s must be rejected", name)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package webapi
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/saichler/l8myfamiliy/go/types/l8myfamily"
+)
+
+// ErrBadSignature is returned for a location post of a device with a signing
+// key that is unsigned, wrongly signed, too old or a replay
+var ErrBadSignature = errors.New("403 location signature rejected")
+
+// NewSigningKey returns a random device signing key, hex encoded
+func NewSigningKey() string {
+	key := make([]byte, 32)
+	rand.Read(key)
+	return hex.EncodeToString(key)
+}
+
+// SignLocation sets a new nonce, the signing time and the signature of the
+// location with the device signing key
+func SignLocation(l *l8myfamily.Location, key string, now time.Time) {
+	nonce := make([]byte, 16)
+	rand.Read(nonce)
+	l.Nonce = hex.EncodeToString(nonce)
+	l.SignedTime = now.UnixMilli()
+	l.Signature = signature(l, key)
+}
+
+// VerifyLocation returns true if the location is signed with the key
+func VerifyLocation(l *l8myfamily.Location, key string) bool {
+	if l.Signature == "" || l.Nonce == "" {
+		return false
+	}
+	return hmac.Equal([]byte(l.Signature), []byte(signature(l, key)))
+}
+
+// signature is the HMAC-SHA256 of the signed location fields. Coordinates
+// are formatted at float32 precision, so a location decoded from JSON signs
+// the same as the protobuf one.
+func signature(l *l8myfamily.Location, key string) string {
+	fields := []string{
+		l.DeviceId,
+		strconv.FormatInt(l.Timestamp, 10),
+		strconv.FormatFloat(float64(l.Latitude), 'f', -1, 32),
+		strconv.FormatFloat(float64(l.Longitude), 'f', -1, 32),
+		strconv.FormatFloat(float64(l.Accuracy), 'f', -1, 32),
+		strconv.FormatBool(l.Emergency),
+		strconv.FormatBool(l.Heartbeat),
+		l.Nonce,
+		strconv.FormatInt(l.SignedTime, 10),
+	}
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write([]byte(strings.Join(fields, "|")))
+	return hex.EncodeToString(mac.Sum(nil))
+}
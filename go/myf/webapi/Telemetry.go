@@ -0,0 +1,59 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package webapi
+
+import (
+	"strings"
+
+	"github.com/saichler/l8myfamiliy/go/types/l8myfamily"
+)
+
+// The Telemetry network_type values
+const (
+	NetworkWifi     = "wifi"
+	NetworkCellular = "cellular"
+	NetworkEthernet = "ethernet"
+	NetworkNone     = "none"
+)
+
+// MaxTelemetryText bounds the os and os_version an agent reports
+const MaxTelemetryText = 64
+
+// CleanTelemetry returns the telemetry as it is stored on the device record,
+// an unknown network type is dropped and the os texts are trimmed and bounded
+func CleanTelemetry(t *l8myfamily.Telemetry) *l8myfamily.Telemetry {
+	if t == nil {
+		return nil
+	}
+	clean := &l8myfamily.Telemetry{
+		Charging:  t.Charging,
+		Os:        boundText(t.Os),
+		OsVersion: boundText(t.OsVersion),
+	}
+	switch networkType := strings.ToLower(strings.TrimSpace(t.NetworkType)); networkType {
+	case NetworkWifi, NetworkCellular, NetworkEthernet, NetworkNone:
+		clean.NetworkType = networkType
+	}
+	return clean
+}
+
+func boundText(text string) string {
+	text = strings.TrimSpace(text)
+	if len(text) > MaxTelemetryText {
+		text = strings.ToValidUTF8(text[:MaxTelemetryText], "")
+	}
+	return text
+}
@@ -0,0 +1,40 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package webapi
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/saichler/l8myfamiliy/go/types/l8myfamily"
+)
+
+func TestCleanTelemetry(t *testing.T) {
+	if CleanTelemetry(nil) != nil {
+		t.Fatal("a post without telemetry must stay without it")
+	}
+	clean := CleanTelemetry(&l8myfamily.Telemetry{NetworkType: " WiFi ", Charging: true,
+		Os: "linux", OsVersion: strings.Repeat("x", 100)})
+	if clean.NetworkType != NetworkWifi || !clean.Charging || clean.Os != "linux" {
+		t.Fatalf("clean = %v", clean)
+	}
+	if len(clean.OsVersion) != MaxTelemetryText {
+		t.Fatalf("os version of %d bytes, want %d", len(clean.OsVersion), MaxTelemetryText)
+	}
+	if clean := CleanTelemetry(&l8myfamily.Telemetry{NetworkType: "carrier-pigeon", Os: "android"}); clean.NetworkType != "" {
+		t.Fatalf("an unknown network type must be dropped, got %q", clean.NetworkType)
+	}
+}
@@ -0,0 +1,124 @@
+/*
+ * © 2025 Sharon Aicler (saichler@gmail.com)
+ *
+ * Layer 8 Ecosystem is licensed under the Apache License, Version 2.0.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package webapi holds the http plumbing shared by the raw endpoints that are
+// served next to the family services.
+package webapi
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	Prefix      = "/my-family/"
+	AdminPrefix = Prefix + "admin/"
+)
+
+// ErrOverloaded is returned when a request could not be queued in time,
+// it maps to a retryable 503 for the agents.
+var ErrOverloaded = errors.New("503 service overloaded, retry later")
+
+// ErrRateLimited is returned when a device posts above its share of the
+// family location ceiling
+var ErrRateLimited = errors.New("429 family location rate exceeded, retry later")
+
+// ErrAgentOutdated is returned for the registration or the post of an agent
+// older than the configured minimum version
+var ErrAgentOutdated = errors.New("426 the agent version is below the minimum, please upgrade the agent")
+
+// ErrStorageUnavailable is returned while the device store cannot write to
+// disk, the agents buffer and retry
+var ErrStorageUnavailable = errors.New("503 device storage is unavailable, retry later")
+
+// ErrUnknownDevice is returned for a location of a device that is not
+// registered, so the agent knows to register it
+var ErrUnknownDevice = errors.New("404 device is not registered")
+
+// ErrNotAllowed is returned for a device that is not on the allowlist of its family
+var ErrNotAllowed = errors.New("403 device is not on the family allowlist")
+
+// ErrOtherFamily is returned when the caller acts on a device of another family
+var ErrOtherFamily = errors.New("403 device belongs to another family")
+
+// ErrNotOwner is returned when a member who neither owns the device nor
+// administers the family gives it another owner
+var ErrNotOwner = errors.New("403 only an admin, the family account, a family admin or the device owner may change its owner")
+
+// ErrUnauthenticated is returned for a post without an authenticated caller,
+// e.g. to a service endpoint, the agents post to the raw endpoints
+var ErrUnauthenticated = errors.New("401 the post has no authenticated caller")
+
+// ErrorStatus maps a service error to its http status, the errors travel
+// through the service as text
+func ErrorStatus(err error) int {
+	switch {
+	case strings.Contains(err.Error(), ErrOverloaded.Error()),
+		strings.Contains(err.Error(), ErrStorageUnavailable.Error()):
+		return http.StatusServiceUnavailable
+	case strings.Contains(err.Error(), ErrRateLimited.Error()):
+		return http.StatusTooManyRequests
+	case strings.Contains(err.Error(), ErrAgentOutdated.Error()):
+		return http.StatusUpgradeRequired
+	case strings.Contains(err.Error(), ErrBadSignature.Error()),
+		strings.Contains(err.Error(), ErrNotAllowed.Error()),
+		strings.Contains(err.Error(), ErrOtherFamily.Error()),
+		strings.Contains(err.Error(), ErrNotOwner.Error()):
+		return http.StatusForbidden
+	case strings.Contains(err.Error(), ErrUnknownDevice.Error()):
+		return http.StatusNotFound
+	case strings.Contains(err.Error(), ErrUnauthenticated.Error()):
+		return http.StatusUnauthorized
+	}
+	return http.StatusInternalServerError
+}
+
+// WriteJSON writes v as a JSON response with the given status code, see MarshalJSON
+func WriteJSON(w http.ResponseWriter, status int, v interface{}) {
+	data, err := MarshalJSON(v)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(append(data, '\n'))
+}
+
+// MarshalJSON marshals v as JSON, a proto message is protobuf JSON like the
+// posted bodies: lowerCamelCase field names and the enum value names
+func MarshalJSON(v interface{}) ([]byte, error) {
+	if msg, ok := v.(proto.Message); ok {
+		return protojson.Marshal(msg)
+	}
+	return json.Marshal(v)
+}
+
+// RequireMethod writes a 405 and returns false if the request method is none of methods
+func RequireMethod(w http.ResponseWriter, r *http.Request, methods ...string) bool {
+	for _, method := range methods {
+		if r.Method == method {
+			return true
+		}
+	}
+	w.Header().Set("Allow", strings.Join(methods, ", "))
+	http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	return false
+}
@@ -16,15 +16,22 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
 	"github.com/saichler/l8types/go/sec"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"github.com/saichler/l8bus/go/overlay/health"
 	"github.com/saichler/l8bus/go/overlay/vnet"
 	"github.com/saichler/l8bus/go/overlay/vnic"
+	"github.com/saichler/l8myfamiliy/go/myf/config"
 	"github.com/saichler/l8myfamiliy/go/myf/device_service"
 	"github.com/saichler/l8myfamiliy/go/myf/location_service"
+	"github.com/saichler/l8myfamiliy/go/myf/webapi"
 	"github.com/saichler/l8myfamiliy/go/types/l8myfamily"
 	"github.com/saichler/l8reflect/go/reflect/introspecting"
 	"github.com/saichler/l8services/go/services/manager"
@@ -45,14 +52,54 @@ const (
 )
 
 func main() {
+	if len(os.Args) > 1 {
+		runCommand(os.Args[1])
+		return
+	}
 	resources := CreateResources("vnetfamily")
 	resources.Logger().SetLogLevel(ifs.Info_Level)
+	if err := config.Load(config.DefaultFile); err != nil {
+		panic(err)
+	}
 	net := vnet.NewVNet(resources)
 	net.Start()
 	resources.Logger().Info("vnet started!")
+	go handleShutdown()
 	startWebServer(9093, "/data/probler")
 }
 
+// handleShutdown drains the pending device updates before the process exits
+func handleShutdown() {
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+	<-sigChan
+	fmt.Println("Shutting down, draining pending device updates...")
+	location_service.Shutdown()
+	os.Exit(0)
+}
+
+// runCommand runs a maintenance subcommand instead of the server
+func runCommand(cmd string) {
+	if err := config.Load(config.DefaultFile); err != nil {
+		fmt.Println(err.Error())
+		os.Exit(1)
+	}
+	switch cmd {
+	case "compact":
+		stats, err := device_service.Compact()
+		if err != nil {
+			fmt.Println("Compact failed: ", err.Error())
+			os.Exit(1)
+		}
+		data, _ := json.MarshalIndent(stats, "", "  ")
+		fmt.Println(string(data))
+	default:
+		fmt.Println("Unknown command: ", cmd)
+		fmt.Println("Usage: fweb [compact]")
+		os.Exit(1)
+	}
+}
+
 func startWebServer(port int, cert string) {
 	serverConfig := &server.RestServerConfig{
 		Host:           ipsegment.MachineIP,
@@ -74,8 +121,20 @@ func startWebServer(port int, cert string) {
 		svr.RegisterWebService(ws, nic)
 	}
 
-	location_service.Activate(nic)
-	device_service.Activate(nic)
+	area := byte(config.Get().ServiceArea)
+	if err := location_service.Activate(location_service.DefaultServiceName, area, nic); err != nil {
+		fmt.Println("Failed to activate the location service: ", err.Error())
+		os.Exit(1)
+	}
+	if err := device_service.Activate(device_service.DefaultServiceName, area, nic); err != nil {
+		fmt.Println("Failed to activate the device service: ", err.Error())
+		os.Exit(1)
+	}
+	device_service.RegisterHandlers(http.DefaultServeMux, nic)
+	location_service.RegisterHandlers(http.DefaultServeMux, nic)
+	if config.Get().Diag {
+		http.DefaultServeMux.HandleFunc(webapi.Prefix+"diag", webapi.DiagHandler(cert+webapi.CertFileSuffix))
+	}
 	time.Sleep(time.Second)
 
 	//Activate the webpoints topo_service
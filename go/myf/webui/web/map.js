@@ -8,6 +8,7 @@
 
     const SESSION_KEY = 'familyLocatorSession';
     const REFRESH_INTERVAL = 30000; // 30 seconds
+    const SERVICE_AREA = 53; // must match serviceArea in the server config
 
     let map = null;
     let markers = {};
@@ -153,7 +154,7 @@
 
         const query = `select * from Device where familyId=${familyName}`;
         const bodyParam = encodeURIComponent(JSON.stringify({ text: query }));
-        const url = `/my-family/53/Family?body=${bodyParam}`;
+        const url = `/my-family/${SERVICE_AREA}/Family?body=${bodyParam}`;
 
         const response = await fetch(url, {
             method: 'GET',
@@ -173,9 +174,20 @@
             name: device.name || device.id.substring(0, 8),
             memberId: device.memberId || device.familyId,
             memberName: device.memberName || device.name || 'Device',
-            activity: device.activity || 'stationary',
-            latitude: device.latitude,
-            longitude: device.longitude
+            // The server movement state wins over the activity an agent reports
+            activity: device.movement || device.activity || 'stationary',
+            // A vehicle is drawn on the road it was snapped to
+            latitude: device.snappedLatitude || device.latitude,
+            longitude: device.snappedLongitude || device.longitude,
+            source: device.source || 'LOCATION_SOURCE_UNKNOWN',
+            accuracy: device.accuracy || 0,
+            unreliable: device.unreliable || false,
+            coarse: device.coarse || false,
+            // A heartbeat-only device shares its presence, never a position
+            heartbeatOnly: device.heartbeatOnly || false,
+            avatar: device.avatar || '',
+            battery: device.battery || 0,
+            telemetry: device.telemetry || null
         }));
     }
 
@@ -263,6 +275,33 @@
         return (parts[0].charAt(0) + parts[parts.length - 1].charAt(0)).toUpperCase();
     }
 
+    // Emoji of the avatar icon ids the server accepts
+    const AVATAR_ICONS = {
+        person: '🧑', child: '🧒', baby: '👶', senior: '🧓',
+        car: '🚗', bike: '🚲', dog: '🐕', cat: '🐈',
+        phone: '📱', laptop: '💻', watch: '⌚', bag: '🎒'
+    };
+
+    /**
+     * Get the marker content of a device avatar, the initials when it has none
+     * @param {Object} device - Device object
+     * @returns {string} - Marker html
+     */
+    function getMarkerContent(device) {
+        const avatar = device.avatar || '';
+        if (AVATAR_ICONS[avatar]) {
+            return AVATAR_ICONS[avatar];
+        }
+        if (avatar.startsWith('https://')) {
+            const img = document.createElement('img');
+            img.className = 'marker-avatar';
+            img.src = avatar;
+            img.alt = '';
+            return img.outerHTML;
+        }
+        return getInitials(device.memberName);
+    }
+
     /**
      * Create custom marker icon
      * @param {Object} device - Device object
@@ -270,11 +309,11 @@
      */
     function createMarkerIcon(device) {
         const color = getMemberColor(device.memberId);
-        const initials = getInitials(device.memberName);
+        const content = getMarkerContent(device);
 
         const html = `
             <div class="marker-pin" style="background-color: ${color};">
-                ${initials}
+                ${content}
             </div>
         `;
 
@@ -299,13 +338,16 @@
         devices.forEach(device => {
             if (device.latitude && device.longitude) {
                 const marker = L.marker([device.latitude, device.longitude], {
-                    icon: createMarkerIcon(device)
+                    icon: createMarkerIcon(device),
+                    // A VPN or proxy exit location is likely far off
+                    opacity: device.unreliable ? 0.5 : 1
                 });
 
                 const popupContent = `
                     <strong>${device.memberName}</strong>
                     <div>${device.name}</div>
                     <div style="color: #666; font-size: 0.85em;">${formatActivity(device.activity)}</div>
+                    ${lowConfidenceNote(device)}
                 `;
 
                 marker.bindPopup(popupContent);
@@ -363,6 +405,7 @@
             <div class="member-info">
                 <div class="member-name">${device.memberName}</div>
                 <div class="member-device">${device.name}</div>
+                ${device.heartbeatOnly ? '<div style="color: #666; font-size: 0.85em;">Presence only</div>' : ''}
             </div>
             <div class="member-activity ${activityClass}">
                 ${formatActivity(device.activity)}
@@ -391,6 +434,31 @@
         return '';
     }
 
+    /**
+     * Check if the device position came from a low confidence source
+     * @param {Object} device - Device object
+     * @returns {boolean} - True for IP based and coarse fixes
+     */
+    function isLowConfidence(device) {
+        return device.source === 'LOCATION_SOURCE_GEOIP' || device.coarse;
+    }
+
+    /**
+     * Describe a low confidence position for the popup
+     * @param {Object} device - Device object
+     * @returns {string} - Warning html, empty for a reliable position
+     */
+    function lowConfidenceNote(device) {
+        if (device.unreliable) {
+            return '<div style="color: #b00020; font-size: 0.85em;">Unreliable (VPN / proxy IP)</div>';
+        }
+        if (!isLowConfidence(device)) {
+            return '';
+        }
+        const reason = device.source === 'LOCATION_SOURCE_GEOIP' ? 'IP based' : 'precise location denied';
+        return '<div style="color: #b26a00; font-size: 0.85em;">Approximate (' + reason + ')</div>';
+    }
+
     /**
      * Format activity for display
      * @param {string} activity - Activity type
@@ -431,14 +499,51 @@
         document.getElementById('popupMemberName').textContent = device.memberName;
         document.getElementById('popupDeviceName').textContent = device.name;
         document.getElementById('popupActivity').textContent = formatActivity(device.activity);
-        document.getElementById('popupLocation').textContent =
-            `${device.latitude.toFixed(4)}, ${device.longitude.toFixed(4)}`;
+        document.getElementById('popupLocation').textContent = device.heartbeatOnly
+            ? 'Presence only, no location shared'
+            : `${device.latitude.toFixed(4)}, ${device.longitude.toFixed(4)}`;
+        const telemetry = formatTelemetry(device);
+        document.getElementById('popupTelemetry').textContent = telemetry;
+        document.getElementById('popupTelemetryRow').classList.toggle('hidden', telemetry === '');
 
         // Store device reference for center button
         popup.dataset.deviceId = device.id;
         popup.classList.remove('hidden');
     }
 
+    // Labels of the telemetry network types
+    const NETWORK_LABELS = {
+        wifi: 'Wi-Fi',
+        cellular: 'Cellular',
+        ethernet: 'Ethernet',
+        none: 'Offline'
+    };
+
+    /**
+     * Format the battery and telemetry of a device for the popup
+     * @param {Object} device - Device object
+     * @returns {string} - e.g. "Wi-Fi · 80% charging · android 14", empty when unknown
+     */
+    function formatTelemetry(device) {
+        const telemetry = device.telemetry || {};
+        const parts = [];
+        if (NETWORK_LABELS[telemetry.networkType]) {
+            parts.push(NETWORK_LABELS[telemetry.networkType]);
+        }
+        let power = device.battery ? `${device.battery}%` : '';
+        if (telemetry.charging) {
+            power = power ? `${power} charging` : 'Charging';
+        }
+        if (power) {
+            parts.push(power);
+        }
+        const os = [telemetry.os, telemetry.osVersion].filter(Boolean).join(' ');
+        if (os) {
+            parts.push(os);
+        }
+        return parts.join(' · ');
+    }
+
     /**
      * Hide device popup modal
      */
@@ -16,6 +16,7 @@
 package tests
 
 import (
+	"net/http"
 	"time"
 
 	"github.com/saichler/l8bus/go/overlay/health"
@@ -47,8 +48,10 @@ func startWebServer(port int, cert string) {
 		svr.RegisterWebService(ws, nic)
 	}
 
-	location_service.Activate(nic)
-	device_service.Activate(nic)
+	location_service.Activate(location_service.DefaultServiceName, location_service.DefaultServiceArea, nic)
+	device_service.Activate(device_service.DefaultServiceName, device_service.DefaultServiceArea, nic)
+	device_service.RegisterHandlers(http.DefaultServeMux, nic)
+	location_service.RegisterHandlers(http.DefaultServeMux, nic)
 	time.Sleep(time.Second)
 
 	//Activate the webpoints topo_service
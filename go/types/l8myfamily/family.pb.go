@@ -21,14 +21,104 @@ const (
 	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
 )
 
+type LocationSource int32
+
+const (
+	LocationSource_LOCATION_SOURCE_UNKNOWN      LocationSource = 0
+	LocationSource_LOCATION_SOURCE_GEOCLUE      LocationSource = 1
+	LocationSource_LOCATION_SOURCE_CORELOCATION LocationSource = 2
+	LocationSource_LOCATION_SOURCE_WINDOWS      LocationSource = 3
+	LocationSource_LOCATION_SOURCE_GEOIP        LocationSource = 4
+	LocationSource_LOCATION_SOURCE_MANUAL       LocationSource = 5
+	LocationSource_LOCATION_SOURCE_ANDROID      LocationSource = 6
+	LocationSource_LOCATION_SOURCE_GPS          LocationSource = 7
+)
+
+// Enum value maps for LocationSource.
+var (
+	LocationSource_name = map[int32]string{
+		0: "LOCATION_SOURCE_UNKNOWN",
+		1: "LOCATION_SOURCE_GEOCLUE",
+		2: "LOCATION_SOURCE_CORELOCATION",
+		3: "LOCATION_SOURCE_WINDOWS",
+		4: "LOCATION_SOURCE_GEOIP",
+		5: "LOCATION_SOURCE_MANUAL",
+		6: "LOCATION_SOURCE_ANDROID",
+		7: "LOCATION_SOURCE_GPS",
+	}
+	LocationSource_value = map[string]int32{
+		"LOCATION_SOURCE_UNKNOWN":      0,
+		"LOCATION_SOURCE_GEOCLUE":      1,
+		"LOCATION_SOURCE_CORELOCATION": 2,
+		"LOCATION_SOURCE_WINDOWS":      3,
+		"LOCATION_SOURCE_GEOIP":        4,
+		"LOCATION_SOURCE_MANUAL":       5,
+		"LOCATION_SOURCE_ANDROID":      6,
+		"LOCATION_SOURCE_GPS":          7,
+	}
+)
+
+func (x LocationSource) Enum() *LocationSource {
+	p := new(LocationSource)
+	*p = x
+	return p
+}
+
+func (x LocationSource) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (LocationSource) Descriptor() protoreflect.EnumDescriptor {
+	return file_family_proto_enumTypes[0].Descriptor()
+}
+
+func (LocationSource) Type() protoreflect.EnumType {
+	return &file_family_proto_enumTypes[0]
+}
+
+func (x LocationSource) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use LocationSource.Descriptor instead.
+func (LocationSource) EnumDescriptor() ([]byte, []int) {
+	return file_family_proto_rawDescGZIP(), []int{0}
+}
+
 type Location struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	DeviceId  string  `protobuf:"bytes,1,opt,name=device_id,json=deviceId,proto3" json:"device_id,omitempty"`
-	Longitude float32 `protobuf:"fixed32,3,opt,name=longitude,proto3" json:"longitude,omitempty"`
-	Latitude  float32 `protobuf:"fixed32,4,opt,name=latitude,proto3" json:"latitude,omitempty"`
+	DeviceId              string         `protobuf:"bytes,1,opt,name=device_id,json=deviceId,proto3" json:"device_id,omitempty"`
+	Longitude             float32        `protobuf:"fixed32,3,opt,name=longitude,proto3" json:"longitude,omitempty"`
+	Latitude              float32        `protobuf:"fixed32,4,opt,name=latitude,proto3" json:"latitude,omitempty"`
+	Emergency             bool           `protobuf:"varint,5,opt,name=emergency,proto3" json:"emergency,omitempty"`
+	Source                LocationSource `protobuf:"varint,6,opt,name=source,proto3,enum=l8myfamily.LocationSource" json:"source,omitempty"`
+	Accuracy              float32        `protobuf:"fixed32,7,opt,name=accuracy,proto3" json:"accuracy,omitempty"`
+	Timestamp             int64          `protobuf:"varint,8,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	IdempotencyKey        string         `protobuf:"bytes,9,opt,name=idempotency_key,json=idempotencyKey,proto3" json:"idempotency_key,omitempty"`
+	Place                 string         `protobuf:"bytes,10,opt,name=place,proto3" json:"place,omitempty"`
+	PublicIp              string         `protobuf:"bytes,11,opt,name=public_ip,json=publicIp,proto3" json:"public_ip,omitempty"`
+	Unreliable            bool           `protobuf:"varint,12,opt,name=unreliable,proto3" json:"unreliable,omitempty"`
+	Speed                 float32        `protobuf:"fixed32,13,opt,name=speed,proto3" json:"speed,omitempty"`
+	Heading               float32        `protobuf:"fixed32,14,opt,name=heading,proto3" json:"heading,omitempty"`
+	Altitude              float32        `protobuf:"fixed32,15,opt,name=altitude,proto3" json:"altitude,omitempty"`
+	Heartbeat             bool           `protobuf:"varint,16,opt,name=heartbeat,proto3" json:"heartbeat,omitempty"`
+	Battery               int32          `protobuf:"varint,17,opt,name=battery,proto3" json:"battery,omitempty"`
+	Geofence              string         `protobuf:"bytes,18,opt,name=geofence,proto3" json:"geofence,omitempty"`
+	SnappedLatitude       float32        `protobuf:"fixed32,19,opt,name=snapped_latitude,json=snappedLatitude,proto3" json:"snapped_latitude,omitempty"`
+	SnappedLongitude      float32        `protobuf:"fixed32,20,opt,name=snapped_longitude,json=snappedLongitude,proto3" json:"snapped_longitude,omitempty"`
+	Nonce                 string         `protobuf:"bytes,21,opt,name=nonce,proto3" json:"nonce,omitempty"`
+	Signature             string         `protobuf:"bytes,22,opt,name=signature,proto3" json:"signature,omitempty"`
+	SignedTime            int64          `protobuf:"varint,23,opt,name=signed_time,json=signedTime,proto3" json:"signed_time,omitempty"`
+	Telemetry             *Telemetry     `protobuf:"bytes,24,opt,name=telemetry,proto3" json:"telemetry,omitempty"`
+	ReportIntervalSeconds int32          `protobuf:"varint,25,opt,name=report_interval_seconds,json=reportIntervalSeconds,proto3" json:"report_interval_seconds,omitempty"`
+	Coarse                bool           `protobuf:"varint,26,opt,name=coarse,proto3" json:"coarse,omitempty"`
+	Movement              string         `protobuf:"bytes,27,opt,name=movement,proto3" json:"movement,omitempty"`
+	SchemaVersion         int32          `protobuf:"varint,28,opt,name=schema_version,json=schemaVersion,proto3" json:"schema_version,omitempty"`
+	// coordinates is the position in the coordFormat of a response, never stored
+	Coordinates string `protobuf:"bytes,29,opt,name=coordinates,proto3" json:"coordinates,omitempty"`
 }
 
 func (x *Location) Reset() {
@@ -40,14 +130,423 @@ func (x *Location) Reset() {
 	}
 }
 
-func (x *Location) String() string {
+func (x *Location) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Location) ProtoMessage() {}
+
+func (x *Location) ProtoReflect() protoreflect.Message {
+	mi := &file_family_proto_msgTypes[0]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Location.ProtoReflect.Descriptor instead.
+func (*Location) Descriptor() ([]byte, []int) {
+	return file_family_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Location) GetDeviceId() string {
+	if x != nil {
+		return x.DeviceId
+	}
+	return ""
+}
+
+func (x *Location) GetLongitude() float32 {
+	if x != nil {
+		return x.Longitude
+	}
+	return 0
+}
+
+func (x *Location) GetLatitude() float32 {
+	if x != nil {
+		return x.Latitude
+	}
+	return 0
+}
+
+func (x *Location) GetEmergency() bool {
+	if x != nil {
+		return x.Emergency
+	}
+	return false
+}
+
+func (x *Location) GetSource() LocationSource {
+	if x != nil {
+		return x.Source
+	}
+	return LocationSource_LOCATION_SOURCE_UNKNOWN
+}
+
+func (x *Location) GetAccuracy() float32 {
+	if x != nil {
+		return x.Accuracy
+	}
+	return 0
+}
+
+func (x *Location) GetTimestamp() int64 {
+	if x != nil {
+		return x.Timestamp
+	}
+	return 0
+}
+
+func (x *Location) GetIdempotencyKey() string {
+	if x != nil {
+		return x.IdempotencyKey
+	}
+	return ""
+}
+
+func (x *Location) GetPlace() string {
+	if x != nil {
+		return x.Place
+	}
+	return ""
+}
+
+func (x *Location) GetPublicIp() string {
+	if x != nil {
+		return x.PublicIp
+	}
+	return ""
+}
+
+func (x *Location) GetUnreliable() bool {
+	if x != nil {
+		return x.Unreliable
+	}
+	return false
+}
+
+func (x *Location) GetSpeed() float32 {
+	if x != nil {
+		return x.Speed
+	}
+	return 0
+}
+
+func (x *Location) GetHeading() float32 {
+	if x != nil {
+		return x.Heading
+	}
+	return 0
+}
+
+func (x *Location) GetAltitude() float32 {
+	if x != nil {
+		return x.Altitude
+	}
+	return 0
+}
+
+func (x *Location) GetHeartbeat() bool {
+	if x != nil {
+		return x.Heartbeat
+	}
+	return false
+}
+
+func (x *Location) GetBattery() int32 {
+	if x != nil {
+		return x.Battery
+	}
+	return 0
+}
+
+func (x *Location) GetGeofence() string {
+	if x != nil {
+		return x.Geofence
+	}
+	return ""
+}
+
+func (x *Location) GetSnappedLatitude() float32 {
+	if x != nil {
+		return x.SnappedLatitude
+	}
+	return 0
+}
+
+func (x *Location) GetSnappedLongitude() float32 {
+	if x != nil {
+		return x.SnappedLongitude
+	}
+	return 0
+}
+
+func (x *Location) GetNonce() string {
+	if x != nil {
+		return x.Nonce
+	}
+	return ""
+}
+
+func (x *Location) GetSignature() string {
+	if x != nil {
+		return x.Signature
+	}
+	return ""
+}
+
+func (x *Location) GetSignedTime() int64 {
+	if x != nil {
+		return x.SignedTime
+	}
+	return 0
+}
+
+func (x *Location) GetTelemetry() *Telemetry {
+	if x != nil {
+		return x.Telemetry
+	}
+	return nil
+}
+
+func (x *Location) GetReportIntervalSeconds() int32 {
+	if x != nil {
+		return x.ReportIntervalSeconds
+	}
+	return 0
+}
+
+func (x *Location) GetCoarse() bool {
+	if x != nil {
+		return x.Coarse
+	}
+	return false
+}
+
+func (x *Location) GetMovement() string {
+	if x != nil {
+		return x.Movement
+	}
+	return ""
+}
+
+func (x *Location) GetSchemaVersion() int32 {
+	if x != nil {
+		return x.SchemaVersion
+	}
+	return 0
+}
+
+func (x *Location) GetCoordinates() string {
+	if x != nil {
+		return x.Coordinates
+	}
+	return ""
+}
+
+// Telemetry is the optional device state an agent reports with its posts,
+// every field is absent when the agent cannot tell
+type Telemetry struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	NetworkType string `protobuf:"bytes,1,opt,name=network_type,json=networkType,proto3" json:"network_type,omitempty"`
+	Charging    bool   `protobuf:"varint,2,opt,name=charging,proto3" json:"charging,omitempty"`
+	Os          string `protobuf:"bytes,3,opt,name=os,proto3" json:"os,omitempty"`
+	OsVersion   string `protobuf:"bytes,4,opt,name=os_version,json=osVersion,proto3" json:"os_version,omitempty"`
+}
+
+func (x *Telemetry) Reset() {
+	*x = Telemetry{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_family_proto_msgTypes[1]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Telemetry) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Telemetry) ProtoMessage() {}
+
+func (x *Telemetry) ProtoReflect() protoreflect.Message {
+	mi := &file_family_proto_msgTypes[1]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Telemetry.ProtoReflect.Descriptor instead.
+func (*Telemetry) Descriptor() ([]byte, []int) {
+	return file_family_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *Telemetry) GetNetworkType() string {
+	if x != nil {
+		return x.NetworkType
+	}
+	return ""
+}
+
+func (x *Telemetry) GetCharging() bool {
+	if x != nil {
+		return x.Charging
+	}
+	return false
+}
+
+func (x *Telemetry) GetOs() string {
+	if x != nil {
+		return x.Os
+	}
+	return ""
+}
+
+func (x *Telemetry) GetOsVersion() string {
+	if x != nil {
+		return x.OsVersion
+	}
+	return ""
+}
+
+type GeofenceEvent struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	DeviceId  string  `protobuf:"bytes,1,opt,name=device_id,json=deviceId,proto3" json:"device_id,omitempty"`
+	Geofence  string  `protobuf:"bytes,2,opt,name=geofence,proto3" json:"geofence,omitempty"`
+	Entered   bool    `protobuf:"varint,3,opt,name=entered,proto3" json:"entered,omitempty"`
+	Timestamp int64   `protobuf:"varint,4,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	Latitude  float32 `protobuf:"fixed32,5,opt,name=latitude,proto3" json:"latitude,omitempty"`
+	Longitude float32 `protobuf:"fixed32,6,opt,name=longitude,proto3" json:"longitude,omitempty"`
+	// server_evaluated is set on the crossings of the family geofences the
+	// server evaluates, unset on the trusted geofences the agent reports
+	ServerEvaluated bool `protobuf:"varint,7,opt,name=server_evaluated,json=serverEvaluated,proto3" json:"server_evaluated,omitempty"`
+}
+
+func (x *GeofenceEvent) Reset() {
+	*x = GeofenceEvent{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_family_proto_msgTypes[2]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *GeofenceEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GeofenceEvent) ProtoMessage() {}
+
+func (x *GeofenceEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_family_proto_msgTypes[2]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GeofenceEvent.ProtoReflect.Descriptor instead.
+func (*GeofenceEvent) Descriptor() ([]byte, []int) {
+	return file_family_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *GeofenceEvent) GetDeviceId() string {
+	if x != nil {
+		return x.DeviceId
+	}
+	return ""
+}
+
+func (x *GeofenceEvent) GetGeofence() string {
+	if x != nil {
+		return x.Geofence
+	}
+	return ""
+}
+
+func (x *GeofenceEvent) GetEntered() bool {
+	if x != nil {
+		return x.Entered
+	}
+	return false
+}
+
+func (x *GeofenceEvent) GetTimestamp() int64 {
+	if x != nil {
+		return x.Timestamp
+	}
+	return 0
+}
+
+func (x *GeofenceEvent) GetLatitude() float32 {
+	if x != nil {
+		return x.Latitude
+	}
+	return 0
+}
+
+func (x *GeofenceEvent) GetLongitude() float32 {
+	if x != nil {
+		return x.Longitude
+	}
+	return 0
+}
+
+func (x *GeofenceEvent) GetServerEvaluated() bool {
+	if x != nil {
+		return x.ServerEvaluated
+	}
+	return false
+}
+
+type GeofenceEventList struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	List     []*GeofenceEvent `protobuf:"bytes,1,rep,name=list,proto3" json:"list,omitempty"`
+	Page     int32            `protobuf:"varint,2,opt,name=page,proto3" json:"page,omitempty"`
+	PageSize int32            `protobuf:"varint,3,opt,name=page_size,json=pageSize,proto3" json:"page_size,omitempty"`
+	More     bool             `protobuf:"varint,4,opt,name=more,proto3" json:"more,omitempty"`
+}
+
+func (x *GeofenceEventList) Reset() {
+	*x = GeofenceEventList{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_family_proto_msgTypes[3]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *GeofenceEventList) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*Location) ProtoMessage() {}
+func (*GeofenceEventList) ProtoMessage() {}
 
-func (x *Location) ProtoReflect() protoreflect.Message {
-	mi := &file_family_proto_msgTypes[0]
+func (x *GeofenceEventList) ProtoReflect() protoreflect.Message {
+	mi := &file_family_proto_msgTypes[3]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -58,45 +557,53 @@ func (x *Location) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use Location.ProtoReflect.Descriptor instead.
-func (*Location) Descriptor() ([]byte, []int) {
-	return file_family_proto_rawDescGZIP(), []int{0}
+// Deprecated: Use GeofenceEventList.ProtoReflect.Descriptor instead.
+func (*GeofenceEventList) Descriptor() ([]byte, []int) {
+	return file_family_proto_rawDescGZIP(), []int{3}
 }
 
-func (x *Location) GetDeviceId() string {
+func (x *GeofenceEventList) GetList() []*GeofenceEvent {
 	if x != nil {
-		return x.DeviceId
+		return x.List
 	}
-	return ""
+	return nil
 }
 
-func (x *Location) GetLongitude() float32 {
+func (x *GeofenceEventList) GetPage() int32 {
 	if x != nil {
-		return x.Longitude
+		return x.Page
 	}
 	return 0
 }
 
-func (x *Location) GetLatitude() float32 {
+func (x *GeofenceEventList) GetPageSize() int32 {
 	if x != nil {
-		return x.Latitude
+		return x.PageSize
 	}
 	return 0
 }
 
+func (x *GeofenceEventList) GetMore() bool {
+	if x != nil {
+		return x.More
+	}
+	return false
+}
+
 type DeviceList struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	List     []*Device         `protobuf:"bytes,1,rep,name=list,proto3" json:"list,omitempty"`
-	Metadata *l8api.L8MetaData `protobuf:"bytes,2,opt,name=metadata,proto3" json:"metadata,omitempty"`
+	List       []*Device         `protobuf:"bytes,1,rep,name=list,proto3" json:"list,omitempty"`
+	Metadata   *l8api.L8MetaData `protobuf:"bytes,2,opt,name=metadata,proto3" json:"metadata,omitempty"`
+	ServerTime int64             `protobuf:"varint,3,opt,name=serverTime,proto3" json:"serverTime,omitempty"`
 }
 
 func (x *DeviceList) Reset() {
 	*x = DeviceList{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_family_proto_msgTypes[1]
+		mi := &file_family_proto_msgTypes[4]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -109,7 +616,7 @@ func (x *DeviceList) String() string {
 func (*DeviceList) ProtoMessage() {}
 
 func (x *DeviceList) ProtoReflect() protoreflect.Message {
-	mi := &file_family_proto_msgTypes[1]
+	mi := &file_family_proto_msgTypes[4]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -122,7 +629,7 @@ func (x *DeviceList) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use DeviceList.ProtoReflect.Descriptor instead.
 func (*DeviceList) Descriptor() ([]byte, []int) {
-	return file_family_proto_rawDescGZIP(), []int{1}
+	return file_family_proto_rawDescGZIP(), []int{4}
 }
 
 func (x *DeviceList) GetList() []*Device {
@@ -139,26 +646,72 @@ func (x *DeviceList) GetMetadata() *l8api.L8MetaData {
 	return nil
 }
 
+func (x *DeviceList) GetServerTime() int64 {
+	if x != nil {
+		return x.ServerTime
+	}
+	return 0
+}
+
 type Device struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	Id         string  `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
-	Name       string  `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
-	FamilyId   string  `protobuf:"bytes,3,opt,name=familyId,proto3" json:"familyId,omitempty"`
-	FamilyName string  `protobuf:"bytes,4,opt,name=familyName,proto3" json:"familyName,omitempty"`
-	MemberId   string  `protobuf:"bytes,5,opt,name=memberId,proto3" json:"memberId,omitempty"`
-	MemberName string  `protobuf:"bytes,6,opt,name=memberName,proto3" json:"memberName,omitempty"`
-	Activity   string  `protobuf:"bytes,7,opt,name=activity,proto3" json:"activity,omitempty"`
-	Longitude  float32 `protobuf:"fixed32,8,opt,name=longitude,proto3" json:"longitude,omitempty"`
-	Latitude   float32 `protobuf:"fixed32,9,opt,name=latitude,proto3" json:"latitude,omitempty"`
+	Id                    string            `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Name                  string            `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	FamilyId              string            `protobuf:"bytes,3,opt,name=familyId,proto3" json:"familyId,omitempty"`
+	FamilyName            string            `protobuf:"bytes,4,opt,name=familyName,proto3" json:"familyName,omitempty"`
+	MemberId              string            `protobuf:"bytes,5,opt,name=memberId,proto3" json:"memberId,omitempty"`
+	MemberName            string            `protobuf:"bytes,6,opt,name=memberName,proto3" json:"memberName,omitempty"`
+	Activity              string            `protobuf:"bytes,7,opt,name=activity,proto3" json:"activity,omitempty"`
+	Longitude             float32           `protobuf:"fixed32,8,opt,name=longitude,proto3" json:"longitude,omitempty"`
+	Latitude              float32           `protobuf:"fixed32,9,opt,name=latitude,proto3" json:"latitude,omitempty"`
+	Emergency             bool              `protobuf:"varint,10,opt,name=emergency,proto3" json:"emergency,omitempty"`
+	EmergencyTime         int64             `protobuf:"varint,11,opt,name=emergencyTime,proto3" json:"emergencyTime,omitempty"`
+	Source                LocationSource    `protobuf:"varint,12,opt,name=source,proto3,enum=l8myfamily.LocationSource" json:"source,omitempty"`
+	Accuracy              float32           `protobuf:"fixed32,13,opt,name=accuracy,proto3" json:"accuracy,omitempty"`
+	PrecisionMeters       float32           `protobuf:"fixed32,14,opt,name=precisionMeters,proto3" json:"precisionMeters,omitempty"`
+	IdempotencyKey        string            `protobuf:"bytes,15,opt,name=idempotencyKey,proto3" json:"idempotencyKey,omitempty"`
+	LastSeen              int64             `protobuf:"varint,16,opt,name=lastSeen,proto3" json:"lastSeen,omitempty"`
+	Place                 string            `protobuf:"bytes,17,opt,name=place,proto3" json:"place,omitempty"`
+	PublicIp              string            `protobuf:"bytes,18,opt,name=publicIp,proto3" json:"publicIp,omitempty"`
+	Unreliable            bool              `protobuf:"varint,19,opt,name=unreliable,proto3" json:"unreliable,omitempty"`
+	Speed                 float32           `protobuf:"fixed32,20,opt,name=speed,proto3" json:"speed,omitempty"`
+	Heading               float32           `protobuf:"fixed32,21,opt,name=heading,proto3" json:"heading,omitempty"`
+	Altitude              float32           `protobuf:"fixed32,22,opt,name=altitude,proto3" json:"altitude,omitempty"`
+	Battery               int32             `protobuf:"varint,23,opt,name=battery,proto3" json:"battery,omitempty"`
+	Kind                  string            `protobuf:"bytes,24,opt,name=kind,proto3" json:"kind,omitempty"`
+	SnappedLatitude       float32           `protobuf:"fixed32,25,opt,name=snappedLatitude,proto3" json:"snappedLatitude,omitempty"`
+	SnappedLongitude      float32           `protobuf:"fixed32,26,opt,name=snappedLongitude,proto3" json:"snappedLongitude,omitempty"`
+	SigningKey            string            `protobuf:"bytes,27,opt,name=signingKey,proto3" json:"signingKey,omitempty"`
+	Tags                  map[string]string `protobuf:"bytes,28,rep,name=tags,proto3" json:"tags,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	VisibleTo             []string          `protobuf:"bytes,29,rep,name=visibleTo,proto3" json:"visibleTo,omitempty"`
+	Avatar                string            `protobuf:"bytes,30,opt,name=avatar,proto3" json:"avatar,omitempty"`
+	Telemetry             *Telemetry        `protobuf:"bytes,31,opt,name=telemetry,proto3" json:"telemetry,omitempty"`
+	ReportIntervalSeconds int32             `protobuf:"varint,32,opt,name=reportIntervalSeconds,proto3" json:"reportIntervalSeconds,omitempty"`
+	Coarse                bool              `protobuf:"varint,33,opt,name=coarse,proto3" json:"coarse,omitempty"`
+	PositionTime          int64             `protobuf:"varint,34,opt,name=positionTime,proto3" json:"positionTime,omitempty"`
+	ModifiedAt            int64             `protobuf:"varint,35,opt,name=modifiedAt,proto3" json:"modifiedAt,omitempty"`
+	HeartbeatOnly         bool              `protobuf:"varint,36,opt,name=heartbeatOnly,proto3" json:"heartbeatOnly,omitempty"`
+	Movement              string            `protobuf:"bytes,37,opt,name=movement,proto3" json:"movement,omitempty"`
+	AgentVersion          string            `protobuf:"bytes,38,opt,name=agentVersion,proto3" json:"agentVersion,omitempty"`
+	EmergencyClearedTime  int64             `protobuf:"varint,39,opt,name=emergencyClearedTime,proto3" json:"emergencyClearedTime,omitempty"`
+	EmergencyClearedBy    string            `protobuf:"bytes,40,opt,name=emergencyClearedBy,proto3" json:"emergencyClearedBy,omitempty"`
+	ArchivedTime          int64             `protobuf:"varint,41,opt,name=archivedTime,proto3" json:"archivedTime,omitempty"`
+	// coordinates is the position in the coordFormat of a response, never stored
+	Coordinates string `protobuf:"bytes,42,opt,name=coordinates,proto3" json:"coordinates,omitempty"`
+	// distanceMeters is the distance from the center of a near query, never stored
+	DistanceMeters float32 `protobuf:"fixed32,43,opt,name=distanceMeters,proto3" json:"distanceMeters,omitempty"`
+	// ownerId is the user who owns and manages the device, the registering user
+	// unless set. A device without it is owned by its member.
+	OwnerId string `protobuf:"bytes,44,opt,name=ownerId,proto3" json:"ownerId,omitempty"`
 }
 
 func (x *Device) Reset() {
 	*x = Device{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_family_proto_msgTypes[2]
+		mi := &file_family_proto_msgTypes[5]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -171,7 +724,7 @@ func (x *Device) String() string {
 func (*Device) ProtoMessage() {}
 
 func (x *Device) ProtoReflect() protoreflect.Message {
-	mi := &file_family_proto_msgTypes[2]
+	mi := &file_family_proto_msgTypes[5]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -184,7 +737,7 @@ func (x *Device) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use Device.ProtoReflect.Descriptor instead.
 func (*Device) Descriptor() ([]byte, []int) {
-	return file_family_proto_rawDescGZIP(), []int{2}
+	return file_family_proto_rawDescGZIP(), []int{5}
 }
 
 func (x *Device) GetId() string {
@@ -250,6 +803,251 @@ func (x *Device) GetLatitude() float32 {
 	return 0
 }
 
+func (x *Device) GetEmergency() bool {
+	if x != nil {
+		return x.Emergency
+	}
+	return false
+}
+
+func (x *Device) GetEmergencyTime() int64 {
+	if x != nil {
+		return x.EmergencyTime
+	}
+	return 0
+}
+
+func (x *Device) GetSource() LocationSource {
+	if x != nil {
+		return x.Source
+	}
+	return LocationSource_LOCATION_SOURCE_UNKNOWN
+}
+
+func (x *Device) GetAccuracy() float32 {
+	if x != nil {
+		return x.Accuracy
+	}
+	return 0
+}
+
+func (x *Device) GetPrecisionMeters() float32 {
+	if x != nil {
+		return x.PrecisionMeters
+	}
+	return 0
+}
+
+func (x *Device) GetIdempotencyKey() string {
+	if x != nil {
+		return x.IdempotencyKey
+	}
+	return ""
+}
+
+func (x *Device) GetLastSeen() int64 {
+	if x != nil {
+		return x.LastSeen
+	}
+	return 0
+}
+
+func (x *Device) GetPlace() string {
+	if x != nil {
+		return x.Place
+	}
+	return ""
+}
+
+func (x *Device) GetPublicIp() string {
+	if x != nil {
+		return x.PublicIp
+	}
+	return ""
+}
+
+func (x *Device) GetUnreliable() bool {
+	if x != nil {
+		return x.Unreliable
+	}
+	return false
+}
+
+func (x *Device) GetSpeed() float32 {
+	if x != nil {
+		return x.Speed
+	}
+	return 0
+}
+
+func (x *Device) GetHeading() float32 {
+	if x != nil {
+		return x.Heading
+	}
+	return 0
+}
+
+func (x *Device) GetAltitude() float32 {
+	if x != nil {
+		return x.Altitude
+	}
+	return 0
+}
+
+func (x *Device) GetBattery() int32 {
+	if x != nil {
+		return x.Battery
+	}
+	return 0
+}
+
+func (x *Device) GetKind() string {
+	if x != nil {
+		return x.Kind
+	}
+	return ""
+}
+
+func (x *Device) GetSnappedLatitude() float32 {
+	if x != nil {
+		return x.SnappedLatitude
+	}
+	return 0
+}
+
+func (x *Device) GetSnappedLongitude() float32 {
+	if x != nil {
+		return x.SnappedLongitude
+	}
+	return 0
+}
+
+func (x *Device) GetSigningKey() string {
+	if x != nil {
+		return x.SigningKey
+	}
+	return ""
+}
+
+func (x *Device) GetTags() map[string]string {
+	if x != nil {
+		return x.Tags
+	}
+	return nil
+}
+
+func (x *Device) GetVisibleTo() []string {
+	if x != nil {
+		return x.VisibleTo
+	}
+	return nil
+}
+
+func (x *Device) GetAvatar() string {
+	if x != nil {
+		return x.Avatar
+	}
+	return ""
+}
+
+func (x *Device) GetTelemetry() *Telemetry {
+	if x != nil {
+		return x.Telemetry
+	}
+	return nil
+}
+
+func (x *Device) GetReportIntervalSeconds() int32 {
+	if x != nil {
+		return x.ReportIntervalSeconds
+	}
+	return 0
+}
+
+func (x *Device) GetCoarse() bool {
+	if x != nil {
+		return x.Coarse
+	}
+	return false
+}
+
+func (x *Device) GetPositionTime() int64 {
+	if x != nil {
+		return x.PositionTime
+	}
+	return 0
+}
+
+func (x *Device) GetModifiedAt() int64 {
+	if x != nil {
+		return x.ModifiedAt
+	}
+	return 0
+}
+
+func (x *Device) GetHeartbeatOnly() bool {
+	if x != nil {
+		return x.HeartbeatOnly
+	}
+	return false
+}
+
+func (x *Device) GetMovement() string {
+	if x != nil {
+		return x.Movement
+	}
+	return ""
+}
+
+func (x *Device) GetAgentVersion() string {
+	if x != nil {
+		return x.AgentVersion
+	}
+	return ""
+}
+
+func (x *Device) GetEmergencyClearedTime() int64 {
+	if x != nil {
+		return x.EmergencyClearedTime
+	}
+	return 0
+}
+
+func (x *Device) GetEmergencyClearedBy() string {
+	if x != nil {
+		return x.EmergencyClearedBy
+	}
+	return ""
+}
+
+func (x *Device) GetArchivedTime() int64 {
+	if x != nil {
+		return x.ArchivedTime
+	}
+	return 0
+}
+
+func (x *Device) GetCoordinates() string {
+	if x != nil {
+		return x.Coordinates
+	}
+	return ""
+}
+
+func (x *Device) GetDistanceMeters() float32 {
+	if x != nil {
+		return x.DistanceMeters
+	}
+	return 0
+}
+
+func (x *Device) GetOwnerId() string {
+	if x != nil {
+		return x.OwnerId
+	}
+	return ""
+}
+
 type Member struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
@@ -263,7 +1061,7 @@ type Member struct {
 func (x *Member) Reset() {
 	*x = Member{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_family_proto_msgTypes[3]
+		mi := &file_family_proto_msgTypes[6]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -276,7 +1074,7 @@ func (x *Member) String() string {
 func (*Member) ProtoMessage() {}
 
 func (x *Member) ProtoReflect() protoreflect.Message {
-	mi := &file_family_proto_msgTypes[3]
+	mi := &file_family_proto_msgTypes[6]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -289,7 +1087,7 @@ func (x *Member) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use Member.ProtoReflect.Descriptor instead.
 func (*Member) Descriptor() ([]byte, []int) {
-	return file_family_proto_rawDescGZIP(), []int{3}
+	return file_family_proto_rawDescGZIP(), []int{6}
 }
 
 func (x *Member) GetId() string {
@@ -327,7 +1125,7 @@ type Activity struct {
 func (x *Activity) Reset() {
 	*x = Activity{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_family_proto_msgTypes[4]
+		mi := &file_family_proto_msgTypes[7]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -340,7 +1138,7 @@ func (x *Activity) String() string {
 func (*Activity) ProtoMessage() {}
 
 func (x *Activity) ProtoReflect() protoreflect.Message {
-	mi := &file_family_proto_msgTypes[4]
+	mi := &file_family_proto_msgTypes[7]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -353,7 +1151,7 @@ func (x *Activity) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use Activity.ProtoReflect.Descriptor instead.
 func (*Activity) Descriptor() ([]byte, []int) {
-	return file_family_proto_rawDescGZIP(), []int{4}
+	return file_family_proto_rawDescGZIP(), []int{7}
 }
 
 func (x *Activity) GetMemberId() string {
@@ -397,7 +1195,7 @@ type Family struct {
 func (x *Family) Reset() {
 	*x = Family{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_family_proto_msgTypes[5]
+		mi := &file_family_proto_msgTypes[8]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -410,7 +1208,7 @@ func (x *Family) String() string {
 func (*Family) ProtoMessage() {}
 
 func (x *Family) ProtoReflect() protoreflect.Message {
-	mi := &file_family_proto_msgTypes[5]
+	mi := &file_family_proto_msgTypes[8]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -423,7 +1221,7 @@ func (x *Family) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use Family.ProtoReflect.Descriptor instead.
 func (*Family) Descriptor() ([]byte, []int) {
-	return file_family_proto_rawDescGZIP(), []int{5}
+	return file_family_proto_rawDescGZIP(), []int{8}
 }
 
 func (x *Family) GetId() string {
@@ -452,69 +1250,249 @@ var File_family_proto protoreflect.FileDescriptor
 var file_family_proto_rawDesc = []byte{
 	0x0a, 0x0c, 0x66, 0x61, 0x6d, 0x69, 0x6c, 0x79, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12, 0x0a,
 	0x6c, 0x38, 0x6d, 0x79, 0x66, 0x61, 0x6d, 0x69, 0x6c, 0x79, 0x1a, 0x09, 0x61, 0x70, 0x69, 0x2e,
-	0x70, 0x72, 0x6f, 0x74, 0x6f, 0x22, 0x61, 0x0a, 0x08, 0x4c, 0x6f, 0x63, 0x61, 0x74, 0x69, 0x6f,
-	0x6e, 0x12, 0x1b, 0x0a, 0x09, 0x64, 0x65, 0x76, 0x69, 0x63, 0x65, 0x5f, 0x69, 0x64, 0x18, 0x01,
-	0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x64, 0x65, 0x76, 0x69, 0x63, 0x65, 0x49, 0x64, 0x12, 0x1c,
-	0x0a, 0x09, 0x6c, 0x6f, 0x6e, 0x67, 0x69, 0x74, 0x75, 0x64, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28,
+	0x70, 0x72, 0x6f, 0x74, 0x6f, 0x22, 0xa0, 0x07, 0x0a, 0x08, 0x4c, 0x6f, 0x63, 0x61, 0x74, 0x69,
+	0x6f, 0x6e, 0x12, 0x1b, 0x0a, 0x09, 0x64, 0x65, 0x76, 0x69, 0x63, 0x65, 0x5f, 0x69, 0x64, 0x18,
+	0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x64, 0x65, 0x76, 0x69, 0x63, 0x65, 0x49, 0x64, 0x12,
+	0x1c, 0x0a, 0x09, 0x6c, 0x6f, 0x6e, 0x67, 0x69, 0x74, 0x75, 0x64, 0x65, 0x18, 0x03, 0x20, 0x01,
+	0x28, 0x02, 0x52, 0x09, 0x6c, 0x6f, 0x6e, 0x67, 0x69, 0x74, 0x75, 0x64, 0x65, 0x12, 0x1a, 0x0a,
+	0x08, 0x6c, 0x61, 0x74, 0x69, 0x74, 0x75, 0x64, 0x65, 0x18, 0x04, 0x20, 0x01, 0x28, 0x02, 0x52,
+	0x08, 0x6c, 0x61, 0x74, 0x69, 0x74, 0x75, 0x64, 0x65, 0x12, 0x1c, 0x0a, 0x09, 0x65, 0x6d, 0x65,
+	0x72, 0x67, 0x65, 0x6e, 0x63, 0x79, 0x18, 0x05, 0x20, 0x01, 0x28, 0x08, 0x52, 0x09, 0x65, 0x6d,
+	0x65, 0x72, 0x67, 0x65, 0x6e, 0x63, 0x79, 0x12, 0x32, 0x0a, 0x06, 0x73, 0x6f, 0x75, 0x72, 0x63,
+	0x65, 0x18, 0x06, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x1a, 0x2e, 0x6c, 0x38, 0x6d, 0x79, 0x66, 0x61,
+	0x6d, 0x69, 0x6c, 0x79, 0x2e, 0x4c, 0x6f, 0x63, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x53, 0x6f, 0x75,
+	0x72, 0x63, 0x65, 0x52, 0x06, 0x73, 0x6f, 0x75, 0x72, 0x63, 0x65, 0x12, 0x1a, 0x0a, 0x08, 0x61,
+	0x63, 0x63, 0x75, 0x72, 0x61, 0x63, 0x79, 0x18, 0x07, 0x20, 0x01, 0x28, 0x02, 0x52, 0x08, 0x61,
+	0x63, 0x63, 0x75, 0x72, 0x61, 0x63, 0x79, 0x12, 0x1c, 0x0a, 0x09, 0x74, 0x69, 0x6d, 0x65, 0x73,
+	0x74, 0x61, 0x6d, 0x70, 0x18, 0x08, 0x20, 0x01, 0x28, 0x03, 0x52, 0x09, 0x74, 0x69, 0x6d, 0x65,
+	0x73, 0x74, 0x61, 0x6d, 0x70, 0x12, 0x27, 0x0a, 0x0f, 0x69, 0x64, 0x65, 0x6d, 0x70, 0x6f, 0x74,
+	0x65, 0x6e, 0x63, 0x79, 0x5f, 0x6b, 0x65, 0x79, 0x18, 0x09, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0e,
+	0x69, 0x64, 0x65, 0x6d, 0x70, 0x6f, 0x74, 0x65, 0x6e, 0x63, 0x79, 0x4b, 0x65, 0x79, 0x12, 0x14,
+	0x0a, 0x05, 0x70, 0x6c, 0x61, 0x63, 0x65, 0x18, 0x0a, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x70,
+	0x6c, 0x61, 0x63, 0x65, 0x12, 0x1b, 0x0a, 0x09, 0x70, 0x75, 0x62, 0x6c, 0x69, 0x63, 0x5f, 0x69,
+	0x70, 0x18, 0x0b, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x70, 0x75, 0x62, 0x6c, 0x69, 0x63, 0x49,
+	0x70, 0x12, 0x1e, 0x0a, 0x0a, 0x75, 0x6e, 0x72, 0x65, 0x6c, 0x69, 0x61, 0x62, 0x6c, 0x65, 0x18,
+	0x0c, 0x20, 0x01, 0x28, 0x08, 0x52, 0x0a, 0x75, 0x6e, 0x72, 0x65, 0x6c, 0x69, 0x61, 0x62, 0x6c,
+	0x65, 0x12, 0x14, 0x0a, 0x05, 0x73, 0x70, 0x65, 0x65, 0x64, 0x18, 0x0d, 0x20, 0x01, 0x28, 0x02,
+	0x52, 0x05, 0x73, 0x70, 0x65, 0x65, 0x64, 0x12, 0x18, 0x0a, 0x07, 0x68, 0x65, 0x61, 0x64, 0x69,
+	0x6e, 0x67, 0x18, 0x0e, 0x20, 0x01, 0x28, 0x02, 0x52, 0x07, 0x68, 0x65, 0x61, 0x64, 0x69, 0x6e,
+	0x67, 0x12, 0x1a, 0x0a, 0x08, 0x61, 0x6c, 0x74, 0x69, 0x74, 0x75, 0x64, 0x65, 0x18, 0x0f, 0x20,
+	0x01, 0x28, 0x02, 0x52, 0x08, 0x61, 0x6c, 0x74, 0x69, 0x74, 0x75, 0x64, 0x65, 0x12, 0x1c, 0x0a,
+	0x09, 0x68, 0x65, 0x61, 0x72, 0x74, 0x62, 0x65, 0x61, 0x74, 0x18, 0x10, 0x20, 0x01, 0x28, 0x08,
+	0x52, 0x09, 0x68, 0x65, 0x61, 0x72, 0x74, 0x62, 0x65, 0x61, 0x74, 0x12, 0x18, 0x0a, 0x07, 0x62,
+	0x61, 0x74, 0x74, 0x65, 0x72, 0x79, 0x18, 0x11, 0x20, 0x01, 0x28, 0x05, 0x52, 0x07, 0x62, 0x61,
+	0x74, 0x74, 0x65, 0x72, 0x79, 0x12, 0x1a, 0x0a, 0x08, 0x67, 0x65, 0x6f, 0x66, 0x65, 0x6e, 0x63,
+	0x65, 0x18, 0x12, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x67, 0x65, 0x6f, 0x66, 0x65, 0x6e, 0x63,
+	0x65, 0x12, 0x29, 0x0a, 0x10, 0x73, 0x6e, 0x61, 0x70, 0x70, 0x65, 0x64, 0x5f, 0x6c, 0x61, 0x74,
+	0x69, 0x74, 0x75, 0x64, 0x65, 0x18, 0x13, 0x20, 0x01, 0x28, 0x02, 0x52, 0x0f, 0x73, 0x6e, 0x61,
+	0x70, 0x70, 0x65, 0x64, 0x4c, 0x61, 0x74, 0x69, 0x74, 0x75, 0x64, 0x65, 0x12, 0x2b, 0x0a, 0x11,
+	0x73, 0x6e, 0x61, 0x70, 0x70, 0x65, 0x64, 0x5f, 0x6c, 0x6f, 0x6e, 0x67, 0x69, 0x74, 0x75, 0x64,
+	0x65, 0x18, 0x14, 0x20, 0x01, 0x28, 0x02, 0x52, 0x10, 0x73, 0x6e, 0x61, 0x70, 0x70, 0x65, 0x64,
+	0x4c, 0x6f, 0x6e, 0x67, 0x69, 0x74, 0x75, 0x64, 0x65, 0x12, 0x14, 0x0a, 0x05, 0x6e, 0x6f, 0x6e,
+	0x63, 0x65, 0x18, 0x15, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x6e, 0x6f, 0x6e, 0x63, 0x65, 0x12,
+	0x1c, 0x0a, 0x09, 0x73, 0x69, 0x67, 0x6e, 0x61, 0x74, 0x75, 0x72, 0x65, 0x18, 0x16, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x09, 0x73, 0x69, 0x67, 0x6e, 0x61, 0x74, 0x75, 0x72, 0x65, 0x12, 0x1f, 0x0a,
+	0x0b, 0x73, 0x69, 0x67, 0x6e, 0x65, 0x64, 0x5f, 0x74, 0x69, 0x6d, 0x65, 0x18, 0x17, 0x20, 0x01,
+	0x28, 0x03, 0x52, 0x0a, 0x73, 0x69, 0x67, 0x6e, 0x65, 0x64, 0x54, 0x69, 0x6d, 0x65, 0x12, 0x33,
+	0x0a, 0x09, 0x74, 0x65, 0x6c, 0x65, 0x6d, 0x65, 0x74, 0x72, 0x79, 0x18, 0x18, 0x20, 0x01, 0x28,
+	0x0b, 0x32, 0x15, 0x2e, 0x6c, 0x38, 0x6d, 0x79, 0x66, 0x61, 0x6d, 0x69, 0x6c, 0x79, 0x2e, 0x54,
+	0x65, 0x6c, 0x65, 0x6d, 0x65, 0x74, 0x72, 0x79, 0x52, 0x09, 0x74, 0x65, 0x6c, 0x65, 0x6d, 0x65,
+	0x74, 0x72, 0x79, 0x12, 0x36, 0x0a, 0x17, 0x72, 0x65, 0x70, 0x6f, 0x72, 0x74, 0x5f, 0x69, 0x6e,
+	0x74, 0x65, 0x72, 0x76, 0x61, 0x6c, 0x5f, 0x73, 0x65, 0x63, 0x6f, 0x6e, 0x64, 0x73, 0x18, 0x19,
+	0x20, 0x01, 0x28, 0x05, 0x52, 0x15, 0x72, 0x65, 0x70, 0x6f, 0x72, 0x74, 0x49, 0x6e, 0x74, 0x65,
+	0x72, 0x76, 0x61, 0x6c, 0x53, 0x65, 0x63, 0x6f, 0x6e, 0x64, 0x73, 0x12, 0x16, 0x0a, 0x06, 0x63,
+	0x6f, 0x61, 0x72, 0x73, 0x65, 0x18, 0x1a, 0x20, 0x01, 0x28, 0x08, 0x52, 0x06, 0x63, 0x6f, 0x61,
+	0x72, 0x73, 0x65, 0x12, 0x1a, 0x0a, 0x08, 0x6d, 0x6f, 0x76, 0x65, 0x6d, 0x65, 0x6e, 0x74, 0x18,
+	0x1b, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x6d, 0x6f, 0x76, 0x65, 0x6d, 0x65, 0x6e, 0x74, 0x12,
+	0x25, 0x0a, 0x0e, 0x73, 0x63, 0x68, 0x65, 0x6d, 0x61, 0x5f, 0x76, 0x65, 0x72, 0x73, 0x69, 0x6f,
+	0x6e, 0x18, 0x1c, 0x20, 0x01, 0x28, 0x05, 0x52, 0x0d, 0x73, 0x63, 0x68, 0x65, 0x6d, 0x61, 0x56,
+	0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x12, 0x20, 0x0a, 0x0b, 0x63, 0x6f, 0x6f, 0x72, 0x64, 0x69,
+	0x6e, 0x61, 0x74, 0x65, 0x73, 0x18, 0x1d, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0b, 0x63, 0x6f, 0x6f,
+	0x72, 0x64, 0x69, 0x6e, 0x61, 0x74, 0x65, 0x73, 0x22, 0x79, 0x0a, 0x09, 0x54, 0x65, 0x6c, 0x65,
+	0x6d, 0x65, 0x74, 0x72, 0x79, 0x12, 0x21, 0x0a, 0x0c, 0x6e, 0x65, 0x74, 0x77, 0x6f, 0x72, 0x6b,
+	0x5f, 0x74, 0x79, 0x70, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0b, 0x6e, 0x65, 0x74,
+	0x77, 0x6f, 0x72, 0x6b, 0x54, 0x79, 0x70, 0x65, 0x12, 0x1a, 0x0a, 0x08, 0x63, 0x68, 0x61, 0x72,
+	0x67, 0x69, 0x6e, 0x67, 0x18, 0x02, 0x20, 0x01, 0x28, 0x08, 0x52, 0x08, 0x63, 0x68, 0x61, 0x72,
+	0x67, 0x69, 0x6e, 0x67, 0x12, 0x0e, 0x0a, 0x02, 0x6f, 0x73, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x02, 0x6f, 0x73, 0x12, 0x1d, 0x0a, 0x0a, 0x6f, 0x73, 0x5f, 0x76, 0x65, 0x72, 0x73, 0x69,
+	0x6f, 0x6e, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x52, 0x09, 0x6f, 0x73, 0x56, 0x65, 0x72, 0x73,
+	0x69, 0x6f, 0x6e, 0x22, 0xe5, 0x01, 0x0a, 0x0d, 0x47, 0x65, 0x6f, 0x66, 0x65, 0x6e, 0x63, 0x65,
+	0x45, 0x76, 0x65, 0x6e, 0x74, 0x12, 0x1b, 0x0a, 0x09, 0x64, 0x65, 0x76, 0x69, 0x63, 0x65, 0x5f,
+	0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x64, 0x65, 0x76, 0x69, 0x63, 0x65,
+	0x49, 0x64, 0x12, 0x1a, 0x0a, 0x08, 0x67, 0x65, 0x6f, 0x66, 0x65, 0x6e, 0x63, 0x65, 0x18, 0x02,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x67, 0x65, 0x6f, 0x66, 0x65, 0x6e, 0x63, 0x65, 0x12, 0x18,
+	0x0a, 0x07, 0x65, 0x6e, 0x74, 0x65, 0x72, 0x65, 0x64, 0x18, 0x03, 0x20, 0x01, 0x28, 0x08, 0x52,
+	0x07, 0x65, 0x6e, 0x74, 0x65, 0x72, 0x65, 0x64, 0x12, 0x1c, 0x0a, 0x09, 0x74, 0x69, 0x6d, 0x65,
+	0x73, 0x74, 0x61, 0x6d, 0x70, 0x18, 0x04, 0x20, 0x01, 0x28, 0x03, 0x52, 0x09, 0x74, 0x69, 0x6d,
+	0x65, 0x73, 0x74, 0x61, 0x6d, 0x70, 0x12, 0x1a, 0x0a, 0x08, 0x6c, 0x61, 0x74, 0x69, 0x74, 0x75,
+	0x64, 0x65, 0x18, 0x05, 0x20, 0x01, 0x28, 0x02, 0x52, 0x08, 0x6c, 0x61, 0x74, 0x69, 0x74, 0x75,
+	0x64, 0x65, 0x12, 0x1c, 0x0a, 0x09, 0x6c, 0x6f, 0x6e, 0x67, 0x69, 0x74, 0x75, 0x64, 0x65, 0x18,
+	0x06, 0x20, 0x01, 0x28, 0x02, 0x52, 0x09, 0x6c, 0x6f, 0x6e, 0x67, 0x69, 0x74, 0x75, 0x64, 0x65,
+	0x12, 0x29, 0x0a, 0x10, 0x73, 0x65, 0x72, 0x76, 0x65, 0x72, 0x5f, 0x65, 0x76, 0x61, 0x6c, 0x75,
+	0x61, 0x74, 0x65, 0x64, 0x18, 0x07, 0x20, 0x01, 0x28, 0x08, 0x52, 0x0f, 0x73, 0x65, 0x72, 0x76,
+	0x65, 0x72, 0x45, 0x76, 0x61, 0x6c, 0x75, 0x61, 0x74, 0x65, 0x64, 0x22, 0x87, 0x01, 0x0a, 0x11,
+	0x47, 0x65, 0x6f, 0x66, 0x65, 0x6e, 0x63, 0x65, 0x45, 0x76, 0x65, 0x6e, 0x74, 0x4c, 0x69, 0x73,
+	0x74, 0x12, 0x2d, 0x0a, 0x04, 0x6c, 0x69, 0x73, 0x74, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32,
+	0x19, 0x2e, 0x6c, 0x38, 0x6d, 0x79, 0x66, 0x61, 0x6d, 0x69, 0x6c, 0x79, 0x2e, 0x47, 0x65, 0x6f,
+	0x66, 0x65, 0x6e, 0x63, 0x65, 0x45, 0x76, 0x65, 0x6e, 0x74, 0x52, 0x04, 0x6c, 0x69, 0x73, 0x74,
+	0x12, 0x12, 0x0a, 0x04, 0x70, 0x61, 0x67, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x05, 0x52, 0x04,
+	0x70, 0x61, 0x67, 0x65, 0x12, 0x1b, 0x0a, 0x09, 0x70, 0x61, 0x67, 0x65, 0x5f, 0x73, 0x69, 0x7a,
+	0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x05, 0x52, 0x08, 0x70, 0x61, 0x67, 0x65, 0x53, 0x69, 0x7a,
+	0x65, 0x12, 0x12, 0x0a, 0x04, 0x6d, 0x6f, 0x72, 0x65, 0x18, 0x04, 0x20, 0x01, 0x28, 0x08, 0x52,
+	0x04, 0x6d, 0x6f, 0x72, 0x65, 0x22, 0x83, 0x01, 0x0a, 0x0a, 0x44, 0x65, 0x76, 0x69, 0x63, 0x65,
+	0x4c, 0x69, 0x73, 0x74, 0x12, 0x26, 0x0a, 0x04, 0x6c, 0x69, 0x73, 0x74, 0x18, 0x01, 0x20, 0x03,
+	0x28, 0x0b, 0x32, 0x12, 0x2e, 0x6c, 0x38, 0x6d, 0x79, 0x66, 0x61, 0x6d, 0x69, 0x6c, 0x79, 0x2e,
+	0x44, 0x65, 0x76, 0x69, 0x63, 0x65, 0x52, 0x04, 0x6c, 0x69, 0x73, 0x74, 0x12, 0x2d, 0x0a, 0x08,
+	0x6d, 0x65, 0x74, 0x61, 0x64, 0x61, 0x74, 0x61, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x11,
+	0x2e, 0x6c, 0x38, 0x61, 0x70, 0x69, 0x2e, 0x4c, 0x38, 0x4d, 0x65, 0x74, 0x61, 0x44, 0x61, 0x74,
+	0x61, 0x52, 0x08, 0x6d, 0x65, 0x74, 0x61, 0x64, 0x61, 0x74, 0x61, 0x12, 0x1e, 0x0a, 0x0a, 0x73,
+	0x65, 0x72, 0x76, 0x65, 0x72, 0x54, 0x69, 0x6d, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x03, 0x52,
+	0x0a, 0x73, 0x65, 0x72, 0x76, 0x65, 0x72, 0x54, 0x69, 0x6d, 0x65, 0x22, 0xf8, 0x0b, 0x0a, 0x06,
+	0x44, 0x65, 0x76, 0x69, 0x63, 0x65, 0x12, 0x0e, 0x0a, 0x02, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x02, 0x69, 0x64, 0x12, 0x12, 0x0a, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x02,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x12, 0x1a, 0x0a, 0x08, 0x66, 0x61,
+	0x6d, 0x69, 0x6c, 0x79, 0x49, 0x64, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x66, 0x61,
+	0x6d, 0x69, 0x6c, 0x79, 0x49, 0x64, 0x12, 0x1e, 0x0a, 0x0a, 0x66, 0x61, 0x6d, 0x69, 0x6c, 0x79,
+	0x4e, 0x61, 0x6d, 0x65, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0a, 0x66, 0x61, 0x6d, 0x69,
+	0x6c, 0x79, 0x4e, 0x61, 0x6d, 0x65, 0x12, 0x1a, 0x0a, 0x08, 0x6d, 0x65, 0x6d, 0x62, 0x65, 0x72,
+	0x49, 0x64, 0x18, 0x05, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x6d, 0x65, 0x6d, 0x62, 0x65, 0x72,
+	0x49, 0x64, 0x12, 0x1e, 0x0a, 0x0a, 0x6d, 0x65, 0x6d, 0x62, 0x65, 0x72, 0x4e, 0x61, 0x6d, 0x65,
+	0x18, 0x06, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0a, 0x6d, 0x65, 0x6d, 0x62, 0x65, 0x72, 0x4e, 0x61,
+	0x6d, 0x65, 0x12, 0x1a, 0x0a, 0x08, 0x61, 0x63, 0x74, 0x69, 0x76, 0x69, 0x74, 0x79, 0x18, 0x07,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x61, 0x63, 0x74, 0x69, 0x76, 0x69, 0x74, 0x79, 0x12, 0x1c,
+	0x0a, 0x09, 0x6c, 0x6f, 0x6e, 0x67, 0x69, 0x74, 0x75, 0x64, 0x65, 0x18, 0x08, 0x20, 0x01, 0x28,
 	0x02, 0x52, 0x09, 0x6c, 0x6f, 0x6e, 0x67, 0x69, 0x74, 0x75, 0x64, 0x65, 0x12, 0x1a, 0x0a, 0x08,
-	0x6c, 0x61, 0x74, 0x69, 0x74, 0x75, 0x64, 0x65, 0x18, 0x04, 0x20, 0x01, 0x28, 0x02, 0x52, 0x08,
-	0x6c, 0x61, 0x74, 0x69, 0x74, 0x75, 0x64, 0x65, 0x22, 0x63, 0x0a, 0x0a, 0x44, 0x65, 0x76, 0x69,
-	0x63, 0x65, 0x4c, 0x69, 0x73, 0x74, 0x12, 0x26, 0x0a, 0x04, 0x6c, 0x69, 0x73, 0x74, 0x18, 0x01,
-	0x20, 0x03, 0x28, 0x0b, 0x32, 0x12, 0x2e, 0x6c, 0x38, 0x6d, 0x79, 0x66, 0x61, 0x6d, 0x69, 0x6c,
-	0x79, 0x2e, 0x44, 0x65, 0x76, 0x69, 0x63, 0x65, 0x52, 0x04, 0x6c, 0x69, 0x73, 0x74, 0x12, 0x2d,
-	0x0a, 0x08, 0x6d, 0x65, 0x74, 0x61, 0x64, 0x61, 0x74, 0x61, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b,
-	0x32, 0x11, 0x2e, 0x6c, 0x38, 0x61, 0x70, 0x69, 0x2e, 0x4c, 0x38, 0x4d, 0x65, 0x74, 0x61, 0x44,
-	0x61, 0x74, 0x61, 0x52, 0x08, 0x6d, 0x65, 0x74, 0x61, 0x64, 0x61, 0x74, 0x61, 0x22, 0xfa, 0x01,
-	0x0a, 0x06, 0x44, 0x65, 0x76, 0x69, 0x63, 0x65, 0x12, 0x0e, 0x0a, 0x02, 0x69, 0x64, 0x18, 0x01,
-	0x20, 0x01, 0x28, 0x09, 0x52, 0x02, 0x69, 0x64, 0x12, 0x12, 0x0a, 0x04, 0x6e, 0x61, 0x6d, 0x65,
-	0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x12, 0x1a, 0x0a, 0x08,
-	0x66, 0x61, 0x6d, 0x69, 0x6c, 0x79, 0x49, 0x64, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08,
-	0x66, 0x61, 0x6d, 0x69, 0x6c, 0x79, 0x49, 0x64, 0x12, 0x1e, 0x0a, 0x0a, 0x66, 0x61, 0x6d, 0x69,
-	0x6c, 0x79, 0x4e, 0x61, 0x6d, 0x65, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0a, 0x66, 0x61,
-	0x6d, 0x69, 0x6c, 0x79, 0x4e, 0x61, 0x6d, 0x65, 0x12, 0x1a, 0x0a, 0x08, 0x6d, 0x65, 0x6d, 0x62,
-	0x65, 0x72, 0x49, 0x64, 0x18, 0x05, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x6d, 0x65, 0x6d, 0x62,
-	0x65, 0x72, 0x49, 0x64, 0x12, 0x1e, 0x0a, 0x0a, 0x6d, 0x65, 0x6d, 0x62, 0x65, 0x72, 0x4e, 0x61,
-	0x6d, 0x65, 0x18, 0x06, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0a, 0x6d, 0x65, 0x6d, 0x62, 0x65, 0x72,
-	0x4e, 0x61, 0x6d, 0x65, 0x12, 0x1a, 0x0a, 0x08, 0x61, 0x63, 0x74, 0x69, 0x76, 0x69, 0x74, 0x79,
-	0x18, 0x07, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x61, 0x63, 0x74, 0x69, 0x76, 0x69, 0x74, 0x79,
-	0x12, 0x1c, 0x0a, 0x09, 0x6c, 0x6f, 0x6e, 0x67, 0x69, 0x74, 0x75, 0x64, 0x65, 0x18, 0x08, 0x20,
-	0x01, 0x28, 0x02, 0x52, 0x09, 0x6c, 0x6f, 0x6e, 0x67, 0x69, 0x74, 0x75, 0x64, 0x65, 0x12, 0x1a,
-	0x0a, 0x08, 0x6c, 0x61, 0x74, 0x69, 0x74, 0x75, 0x64, 0x65, 0x18, 0x09, 0x20, 0x01, 0x28, 0x02,
-	0x52, 0x08, 0x6c, 0x61, 0x74, 0x69, 0x74, 0x75, 0x64, 0x65, 0x22, 0xb7, 0x01, 0x0a, 0x06, 0x4d,
-	0x65, 0x6d, 0x62, 0x65, 0x72, 0x12, 0x0e, 0x0a, 0x02, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28,
+	0x6c, 0x61, 0x74, 0x69, 0x74, 0x75, 0x64, 0x65, 0x18, 0x09, 0x20, 0x01, 0x28, 0x02, 0x52, 0x08,
+	0x6c, 0x61, 0x74, 0x69, 0x74, 0x75, 0x64, 0x65, 0x12, 0x1c, 0x0a, 0x09, 0x65, 0x6d, 0x65, 0x72,
+	0x67, 0x65, 0x6e, 0x63, 0x79, 0x18, 0x0a, 0x20, 0x01, 0x28, 0x08, 0x52, 0x09, 0x65, 0x6d, 0x65,
+	0x72, 0x67, 0x65, 0x6e, 0x63, 0x79, 0x12, 0x24, 0x0a, 0x0d, 0x65, 0x6d, 0x65, 0x72, 0x67, 0x65,
+	0x6e, 0x63, 0x79, 0x54, 0x69, 0x6d, 0x65, 0x18, 0x0b, 0x20, 0x01, 0x28, 0x03, 0x52, 0x0d, 0x65,
+	0x6d, 0x65, 0x72, 0x67, 0x65, 0x6e, 0x63, 0x79, 0x54, 0x69, 0x6d, 0x65, 0x12, 0x32, 0x0a, 0x06,
+	0x73, 0x6f, 0x75, 0x72, 0x63, 0x65, 0x18, 0x0c, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x1a, 0x2e, 0x6c,
+	0x38, 0x6d, 0x79, 0x66, 0x61, 0x6d, 0x69, 0x6c, 0x79, 0x2e, 0x4c, 0x6f, 0x63, 0x61, 0x74, 0x69,
+	0x6f, 0x6e, 0x53, 0x6f, 0x75, 0x72, 0x63, 0x65, 0x52, 0x06, 0x73, 0x6f, 0x75, 0x72, 0x63, 0x65,
+	0x12, 0x1a, 0x0a, 0x08, 0x61, 0x63, 0x63, 0x75, 0x72, 0x61, 0x63, 0x79, 0x18, 0x0d, 0x20, 0x01,
+	0x28, 0x02, 0x52, 0x08, 0x61, 0x63, 0x63, 0x75, 0x72, 0x61, 0x63, 0x79, 0x12, 0x28, 0x0a, 0x0f,
+	0x70, 0x72, 0x65, 0x63, 0x69, 0x73, 0x69, 0x6f, 0x6e, 0x4d, 0x65, 0x74, 0x65, 0x72, 0x73, 0x18,
+	0x0e, 0x20, 0x01, 0x28, 0x02, 0x52, 0x0f, 0x70, 0x72, 0x65, 0x63, 0x69, 0x73, 0x69, 0x6f, 0x6e,
+	0x4d, 0x65, 0x74, 0x65, 0x72, 0x73, 0x12, 0x26, 0x0a, 0x0e, 0x69, 0x64, 0x65, 0x6d, 0x70, 0x6f,
+	0x74, 0x65, 0x6e, 0x63, 0x79, 0x4b, 0x65, 0x79, 0x18, 0x0f, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0e,
+	0x69, 0x64, 0x65, 0x6d, 0x70, 0x6f, 0x74, 0x65, 0x6e, 0x63, 0x79, 0x4b, 0x65, 0x79, 0x12, 0x1a,
+	0x0a, 0x08, 0x6c, 0x61, 0x73, 0x74, 0x53, 0x65, 0x65, 0x6e, 0x18, 0x10, 0x20, 0x01, 0x28, 0x03,
+	0x52, 0x08, 0x6c, 0x61, 0x73, 0x74, 0x53, 0x65, 0x65, 0x6e, 0x12, 0x14, 0x0a, 0x05, 0x70, 0x6c,
+	0x61, 0x63, 0x65, 0x18, 0x11, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x70, 0x6c, 0x61, 0x63, 0x65,
+	0x12, 0x1a, 0x0a, 0x08, 0x70, 0x75, 0x62, 0x6c, 0x69, 0x63, 0x49, 0x70, 0x18, 0x12, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x08, 0x70, 0x75, 0x62, 0x6c, 0x69, 0x63, 0x49, 0x70, 0x12, 0x1e, 0x0a, 0x0a,
+	0x75, 0x6e, 0x72, 0x65, 0x6c, 0x69, 0x61, 0x62, 0x6c, 0x65, 0x18, 0x13, 0x20, 0x01, 0x28, 0x08,
+	0x52, 0x0a, 0x75, 0x6e, 0x72, 0x65, 0x6c, 0x69, 0x61, 0x62, 0x6c, 0x65, 0x12, 0x14, 0x0a, 0x05,
+	0x73, 0x70, 0x65, 0x65, 0x64, 0x18, 0x14, 0x20, 0x01, 0x28, 0x02, 0x52, 0x05, 0x73, 0x70, 0x65,
+	0x65, 0x64, 0x12, 0x18, 0x0a, 0x07, 0x68, 0x65, 0x61, 0x64, 0x69, 0x6e, 0x67, 0x18, 0x15, 0x20,
+	0x01, 0x28, 0x02, 0x52, 0x07, 0x68, 0x65, 0x61, 0x64, 0x69, 0x6e, 0x67, 0x12, 0x1a, 0x0a, 0x08,
+	0x61, 0x6c, 0x74, 0x69, 0x74, 0x75, 0x64, 0x65, 0x18, 0x16, 0x20, 0x01, 0x28, 0x02, 0x52, 0x08,
+	0x61, 0x6c, 0x74, 0x69, 0x74, 0x75, 0x64, 0x65, 0x12, 0x18, 0x0a, 0x07, 0x62, 0x61, 0x74, 0x74,
+	0x65, 0x72, 0x79, 0x18, 0x17, 0x20, 0x01, 0x28, 0x05, 0x52, 0x07, 0x62, 0x61, 0x74, 0x74, 0x65,
+	0x72, 0x79, 0x12, 0x12, 0x0a, 0x04, 0x6b, 0x69, 0x6e, 0x64, 0x18, 0x18, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x04, 0x6b, 0x69, 0x6e, 0x64, 0x12, 0x28, 0x0a, 0x0f, 0x73, 0x6e, 0x61, 0x70, 0x70, 0x65,
+	0x64, 0x4c, 0x61, 0x74, 0x69, 0x74, 0x75, 0x64, 0x65, 0x18, 0x19, 0x20, 0x01, 0x28, 0x02, 0x52,
+	0x0f, 0x73, 0x6e, 0x61, 0x70, 0x70, 0x65, 0x64, 0x4c, 0x61, 0x74, 0x69, 0x74, 0x75, 0x64, 0x65,
+	0x12, 0x2a, 0x0a, 0x10, 0x73, 0x6e, 0x61, 0x70, 0x70, 0x65, 0x64, 0x4c, 0x6f, 0x6e, 0x67, 0x69,
+	0x74, 0x75, 0x64, 0x65, 0x18, 0x1a, 0x20, 0x01, 0x28, 0x02, 0x52, 0x10, 0x73, 0x6e, 0x61, 0x70,
+	0x70, 0x65, 0x64, 0x4c, 0x6f, 0x6e, 0x67, 0x69, 0x74, 0x75, 0x64, 0x65, 0x12, 0x1e, 0x0a, 0x0a,
+	0x73, 0x69, 0x67, 0x6e, 0x69, 0x6e, 0x67, 0x4b, 0x65, 0x79, 0x18, 0x1b, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x0a, 0x73, 0x69, 0x67, 0x6e, 0x69, 0x6e, 0x67, 0x4b, 0x65, 0x79, 0x12, 0x30, 0x0a, 0x04,
+	0x74, 0x61, 0x67, 0x73, 0x18, 0x1c, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x1c, 0x2e, 0x6c, 0x38, 0x6d,
+	0x79, 0x66, 0x61, 0x6d, 0x69, 0x6c, 0x79, 0x2e, 0x44, 0x65, 0x76, 0x69, 0x63, 0x65, 0x2e, 0x54,
+	0x61, 0x67, 0x73, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x52, 0x04, 0x74, 0x61, 0x67, 0x73, 0x12, 0x1c,
+	0x0a, 0x09, 0x76, 0x69, 0x73, 0x69, 0x62, 0x6c, 0x65, 0x54, 0x6f, 0x18, 0x1d, 0x20, 0x03, 0x28,
+	0x09, 0x52, 0x09, 0x76, 0x69, 0x73, 0x69, 0x62, 0x6c, 0x65, 0x54, 0x6f, 0x12, 0x16, 0x0a, 0x06,
+	0x61, 0x76, 0x61, 0x74, 0x61, 0x72, 0x18, 0x1e, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x61, 0x76,
+	0x61, 0x74, 0x61, 0x72, 0x12, 0x33, 0x0a, 0x09, 0x74, 0x65, 0x6c, 0x65, 0x6d, 0x65, 0x74, 0x72,
+	0x79, 0x18, 0x1f, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x15, 0x2e, 0x6c, 0x38, 0x6d, 0x79, 0x66, 0x61,
+	0x6d, 0x69, 0x6c, 0x79, 0x2e, 0x54, 0x65, 0x6c, 0x65, 0x6d, 0x65, 0x74, 0x72, 0x79, 0x52, 0x09,
+	0x74, 0x65, 0x6c, 0x65, 0x6d, 0x65, 0x74, 0x72, 0x79, 0x12, 0x34, 0x0a, 0x15, 0x72, 0x65, 0x70,
+	0x6f, 0x72, 0x74, 0x49, 0x6e, 0x74, 0x65, 0x72, 0x76, 0x61, 0x6c, 0x53, 0x65, 0x63, 0x6f, 0x6e,
+	0x64, 0x73, 0x18, 0x20, 0x20, 0x01, 0x28, 0x05, 0x52, 0x15, 0x72, 0x65, 0x70, 0x6f, 0x72, 0x74,
+	0x49, 0x6e, 0x74, 0x65, 0x72, 0x76, 0x61, 0x6c, 0x53, 0x65, 0x63, 0x6f, 0x6e, 0x64, 0x73, 0x12,
+	0x16, 0x0a, 0x06, 0x63, 0x6f, 0x61, 0x72, 0x73, 0x65, 0x18, 0x21, 0x20, 0x01, 0x28, 0x08, 0x52,
+	0x06, 0x63, 0x6f, 0x61, 0x72, 0x73, 0x65, 0x12, 0x22, 0x0a, 0x0c, 0x70, 0x6f, 0x73, 0x69, 0x74,
+	0x69, 0x6f, 0x6e, 0x54, 0x69, 0x6d, 0x65, 0x18, 0x22, 0x20, 0x01, 0x28, 0x03, 0x52, 0x0c, 0x70,
+	0x6f, 0x73, 0x69, 0x74, 0x69, 0x6f, 0x6e, 0x54, 0x69, 0x6d, 0x65, 0x12, 0x1e, 0x0a, 0x0a, 0x6d,
+	0x6f, 0x64, 0x69, 0x66, 0x69, 0x65, 0x64, 0x41, 0x74, 0x18, 0x23, 0x20, 0x01, 0x28, 0x03, 0x52,
+	0x0a, 0x6d, 0x6f, 0x64, 0x69, 0x66, 0x69, 0x65, 0x64, 0x41, 0x74, 0x12, 0x24, 0x0a, 0x0d, 0x68,
+	0x65, 0x61, 0x72, 0x74, 0x62, 0x65, 0x61, 0x74, 0x4f, 0x6e, 0x6c, 0x79, 0x18, 0x24, 0x20, 0x01,
+	0x28, 0x08, 0x52, 0x0d, 0x68, 0x65, 0x61, 0x72, 0x74, 0x62, 0x65, 0x61, 0x74, 0x4f, 0x6e, 0x6c,
+	0x79, 0x12, 0x1a, 0x0a, 0x08, 0x6d, 0x6f, 0x76, 0x65, 0x6d, 0x65, 0x6e, 0x74, 0x18, 0x25, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x08, 0x6d, 0x6f, 0x76, 0x65, 0x6d, 0x65, 0x6e, 0x74, 0x12, 0x22, 0x0a,
+	0x0c, 0x61, 0x67, 0x65, 0x6e, 0x74, 0x56, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x18, 0x26, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x0c, 0x61, 0x67, 0x65, 0x6e, 0x74, 0x56, 0x65, 0x72, 0x73, 0x69, 0x6f,
+	0x6e, 0x12, 0x32, 0x0a, 0x14, 0x65, 0x6d, 0x65, 0x72, 0x67, 0x65, 0x6e, 0x63, 0x79, 0x43, 0x6c,
+	0x65, 0x61, 0x72, 0x65, 0x64, 0x54, 0x69, 0x6d, 0x65, 0x18, 0x27, 0x20, 0x01, 0x28, 0x03, 0x52,
+	0x14, 0x65, 0x6d, 0x65, 0x72, 0x67, 0x65, 0x6e, 0x63, 0x79, 0x43, 0x6c, 0x65, 0x61, 0x72, 0x65,
+	0x64, 0x54, 0x69, 0x6d, 0x65, 0x12, 0x2e, 0x0a, 0x12, 0x65, 0x6d, 0x65, 0x72, 0x67, 0x65, 0x6e,
+	0x63, 0x79, 0x43, 0x6c, 0x65, 0x61, 0x72, 0x65, 0x64, 0x42, 0x79, 0x18, 0x28, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x12, 0x65, 0x6d, 0x65, 0x72, 0x67, 0x65, 0x6e, 0x63, 0x79, 0x43, 0x6c, 0x65, 0x61,
+	0x72, 0x65, 0x64, 0x42, 0x79, 0x12, 0x22, 0x0a, 0x0c, 0x61, 0x72, 0x63, 0x68, 0x69, 0x76, 0x65,
+	0x64, 0x54, 0x69, 0x6d, 0x65, 0x18, 0x29, 0x20, 0x01, 0x28, 0x03, 0x52, 0x0c, 0x61, 0x72, 0x63,
+	0x68, 0x69, 0x76, 0x65, 0x64, 0x54, 0x69, 0x6d, 0x65, 0x12, 0x20, 0x0a, 0x0b, 0x63, 0x6f, 0x6f,
+	0x72, 0x64, 0x69, 0x6e, 0x61, 0x74, 0x65, 0x73, 0x18, 0x2a, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0b,
+	0x63, 0x6f, 0x6f, 0x72, 0x64, 0x69, 0x6e, 0x61, 0x74, 0x65, 0x73, 0x12, 0x26, 0x0a, 0x0e, 0x64,
+	0x69, 0x73, 0x74, 0x61, 0x6e, 0x63, 0x65, 0x4d, 0x65, 0x74, 0x65, 0x72, 0x73, 0x18, 0x2b, 0x20,
+	0x01, 0x28, 0x02, 0x52, 0x0e, 0x64, 0x69, 0x73, 0x74, 0x61, 0x6e, 0x63, 0x65, 0x4d, 0x65, 0x74,
+	0x65, 0x72, 0x73, 0x12, 0x18, 0x0a, 0x07, 0x6f, 0x77, 0x6e, 0x65, 0x72, 0x49, 0x64, 0x18, 0x2c,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x6f, 0x77, 0x6e, 0x65, 0x72, 0x49, 0x64, 0x1a, 0x37, 0x0a,
+	0x09, 0x54, 0x61, 0x67, 0x73, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x12, 0x10, 0x0a, 0x03, 0x6b, 0x65,
+	0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x6b, 0x65, 0x79, 0x12, 0x14, 0x0a, 0x05,
+	0x76, 0x61, 0x6c, 0x75, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x76, 0x61, 0x6c,
+	0x75, 0x65, 0x3a, 0x02, 0x38, 0x01, 0x22, 0xb7, 0x01, 0x0a, 0x06, 0x4d, 0x65, 0x6d, 0x62, 0x65,
+	0x72, 0x12, 0x0e, 0x0a, 0x02, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x02, 0x69,
+	0x64, 0x12, 0x12, 0x0a, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x04, 0x6e, 0x61, 0x6d, 0x65, 0x12, 0x39, 0x0a, 0x07, 0x64, 0x65, 0x76, 0x69, 0x63, 0x65, 0x73,
+	0x18, 0x03, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x1f, 0x2e, 0x6c, 0x38, 0x6d, 0x79, 0x66, 0x61, 0x6d,
+	0x69, 0x6c, 0x79, 0x2e, 0x4d, 0x65, 0x6d, 0x62, 0x65, 0x72, 0x2e, 0x44, 0x65, 0x76, 0x69, 0x63,
+	0x65, 0x73, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x52, 0x07, 0x64, 0x65, 0x76, 0x69, 0x63, 0x65, 0x73,
+	0x1a, 0x4e, 0x0a, 0x0c, 0x44, 0x65, 0x76, 0x69, 0x63, 0x65, 0x73, 0x45, 0x6e, 0x74, 0x72, 0x79,
+	0x12, 0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x6b,
+	0x65, 0x79, 0x12, 0x28, 0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28,
+	0x0b, 0x32, 0x12, 0x2e, 0x6c, 0x38, 0x6d, 0x79, 0x66, 0x61, 0x6d, 0x69, 0x6c, 0x79, 0x2e, 0x44,
+	0x65, 0x76, 0x69, 0x63, 0x65, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x3a, 0x02, 0x38, 0x01,
+	0x22, 0x68, 0x0a, 0x08, 0x41, 0x63, 0x74, 0x69, 0x76, 0x69, 0x74, 0x79, 0x12, 0x1a, 0x0a, 0x08,
+	0x6d, 0x65, 0x6d, 0x62, 0x65, 0x72, 0x49, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08,
+	0x6d, 0x65, 0x6d, 0x62, 0x65, 0x72, 0x49, 0x64, 0x12, 0x12, 0x0a, 0x04, 0x74, 0x69, 0x6d, 0x65,
+	0x18, 0x02, 0x20, 0x01, 0x28, 0x03, 0x52, 0x04, 0x74, 0x69, 0x6d, 0x65, 0x12, 0x1a, 0x0a, 0x08,
+	0x61, 0x63, 0x74, 0x69, 0x76, 0x69, 0x74, 0x79, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08,
+	0x61, 0x63, 0x74, 0x69, 0x76, 0x69, 0x74, 0x79, 0x12, 0x10, 0x0a, 0x03, 0x6c, 0x65, 0x6e, 0x18,
+	0x04, 0x20, 0x01, 0x28, 0x03, 0x52, 0x03, 0x6c, 0x65, 0x6e, 0x22, 0xb7, 0x01, 0x0a, 0x06, 0x46,
+	0x61, 0x6d, 0x69, 0x6c, 0x79, 0x12, 0x0e, 0x0a, 0x02, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28,
 	0x09, 0x52, 0x02, 0x69, 0x64, 0x12, 0x12, 0x0a, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x02, 0x20,
-	0x01, 0x28, 0x09, 0x52, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x12, 0x39, 0x0a, 0x07, 0x64, 0x65, 0x76,
-	0x69, 0x63, 0x65, 0x73, 0x18, 0x03, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x1f, 0x2e, 0x6c, 0x38, 0x6d,
-	0x79, 0x66, 0x61, 0x6d, 0x69, 0x6c, 0x79, 0x2e, 0x4d, 0x65, 0x6d, 0x62, 0x65, 0x72, 0x2e, 0x44,
-	0x65, 0x76, 0x69, 0x63, 0x65, 0x73, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x52, 0x07, 0x64, 0x65, 0x76,
-	0x69, 0x63, 0x65, 0x73, 0x1a, 0x4e, 0x0a, 0x0c, 0x44, 0x65, 0x76, 0x69, 0x63, 0x65, 0x73, 0x45,
+	0x01, 0x28, 0x09, 0x52, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x12, 0x39, 0x0a, 0x07, 0x6d, 0x65, 0x6d,
+	0x62, 0x65, 0x72, 0x73, 0x18, 0x03, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x1f, 0x2e, 0x6c, 0x38, 0x6d,
+	0x79, 0x66, 0x61, 0x6d, 0x69, 0x6c, 0x79, 0x2e, 0x46, 0x61, 0x6d, 0x69, 0x6c, 0x79, 0x2e, 0x4d,
+	0x65, 0x6d, 0x62, 0x65, 0x72, 0x73, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x52, 0x07, 0x6d, 0x65, 0x6d,
+	0x62, 0x65, 0x72, 0x73, 0x1a, 0x4e, 0x0a, 0x0c, 0x4d, 0x65, 0x6d, 0x62, 0x65, 0x72, 0x73, 0x45,
 	0x6e, 0x74, 0x72, 0x79, 0x12, 0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18, 0x01, 0x20, 0x01, 0x28,
 	0x09, 0x52, 0x03, 0x6b, 0x65, 0x79, 0x12, 0x28, 0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x18,
 	0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x12, 0x2e, 0x6c, 0x38, 0x6d, 0x79, 0x66, 0x61, 0x6d, 0x69,
-	0x6c, 0x79, 0x2e, 0x44, 0x65, 0x76, 0x69, 0x63, 0x65, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65,
-	0x3a, 0x02, 0x38, 0x01, 0x22, 0x68, 0x0a, 0x08, 0x41, 0x63, 0x74, 0x69, 0x76, 0x69, 0x74, 0x79,
-	0x12, 0x1a, 0x0a, 0x08, 0x6d, 0x65, 0x6d, 0x62, 0x65, 0x72, 0x49, 0x64, 0x18, 0x01, 0x20, 0x01,
-	0x28, 0x09, 0x52, 0x08, 0x6d, 0x65, 0x6d, 0x62, 0x65, 0x72, 0x49, 0x64, 0x12, 0x12, 0x0a, 0x04,
-	0x74, 0x69, 0x6d, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x03, 0x52, 0x04, 0x74, 0x69, 0x6d, 0x65,
-	0x12, 0x1a, 0x0a, 0x08, 0x61, 0x63, 0x74, 0x69, 0x76, 0x69, 0x74, 0x79, 0x18, 0x03, 0x20, 0x01,
-	0x28, 0x09, 0x52, 0x08, 0x61, 0x63, 0x74, 0x69, 0x76, 0x69, 0x74, 0x79, 0x12, 0x10, 0x0a, 0x03,
-	0x6c, 0x65, 0x6e, 0x18, 0x04, 0x20, 0x01, 0x28, 0x03, 0x52, 0x03, 0x6c, 0x65, 0x6e, 0x22, 0xb7,
-	0x01, 0x0a, 0x06, 0x46, 0x61, 0x6d, 0x69, 0x6c, 0x79, 0x12, 0x0e, 0x0a, 0x02, 0x69, 0x64, 0x18,
-	0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x02, 0x69, 0x64, 0x12, 0x12, 0x0a, 0x04, 0x6e, 0x61, 0x6d,
-	0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x12, 0x39, 0x0a,
-	0x07, 0x6d, 0x65, 0x6d, 0x62, 0x65, 0x72, 0x73, 0x18, 0x03, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x1f,
-	0x2e, 0x6c, 0x38, 0x6d, 0x79, 0x66, 0x61, 0x6d, 0x69, 0x6c, 0x79, 0x2e, 0x46, 0x61, 0x6d, 0x69,
-	0x6c, 0x79, 0x2e, 0x4d, 0x65, 0x6d, 0x62, 0x65, 0x72, 0x73, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x52,
-	0x07, 0x6d, 0x65, 0x6d, 0x62, 0x65, 0x72, 0x73, 0x1a, 0x4e, 0x0a, 0x0c, 0x4d, 0x65, 0x6d, 0x62,
-	0x65, 0x72, 0x73, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x12, 0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18,
-	0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x6b, 0x65, 0x79, 0x12, 0x28, 0x0a, 0x05, 0x76, 0x61,
-	0x6c, 0x75, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x12, 0x2e, 0x6c, 0x38, 0x6d, 0x79,
-	0x66, 0x61, 0x6d, 0x69, 0x6c, 0x79, 0x2e, 0x4d, 0x65, 0x6d, 0x62, 0x65, 0x72, 0x52, 0x05, 0x76,
-	0x61, 0x6c, 0x75, 0x65, 0x3a, 0x02, 0x38, 0x01, 0x42, 0x38, 0x0a, 0x14, 0x63, 0x6f, 0x6d, 0x2e,
-	0x74, 0x79, 0x70, 0x65, 0x73, 0x2e, 0x6c, 0x38, 0x4d, 0x79, 0x46, 0x61, 0x6d, 0x69, 0x6c, 0x79,
-	0x42, 0x0a, 0x4c, 0x38, 0x4d, 0x79, 0x46, 0x61, 0x6d, 0x69, 0x6c, 0x79, 0x50, 0x01, 0x5a, 0x12,
-	0x2e, 0x2f, 0x74, 0x79, 0x70, 0x65, 0x73, 0x2f, 0x6c, 0x38, 0x6d, 0x79, 0x66, 0x61, 0x6d, 0x69,
-	0x6c, 0x79, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+	0x6c, 0x79, 0x2e, 0x4d, 0x65, 0x6d, 0x62, 0x65, 0x72, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65,
+	0x3a, 0x02, 0x38, 0x01, 0x2a, 0xf6, 0x01, 0x0a, 0x0e, 0x4c, 0x6f, 0x63, 0x61, 0x74, 0x69, 0x6f,
+	0x6e, 0x53, 0x6f, 0x75, 0x72, 0x63, 0x65, 0x12, 0x1b, 0x0a, 0x17, 0x4c, 0x4f, 0x43, 0x41, 0x54,
+	0x49, 0x4f, 0x4e, 0x5f, 0x53, 0x4f, 0x55, 0x52, 0x43, 0x45, 0x5f, 0x55, 0x4e, 0x4b, 0x4e, 0x4f,
+	0x57, 0x4e, 0x10, 0x00, 0x12, 0x1b, 0x0a, 0x17, 0x4c, 0x4f, 0x43, 0x41, 0x54, 0x49, 0x4f, 0x4e,
+	0x5f, 0x53, 0x4f, 0x55, 0x52, 0x43, 0x45, 0x5f, 0x47, 0x45, 0x4f, 0x43, 0x4c, 0x55, 0x45, 0x10,
+	0x01, 0x12, 0x20, 0x0a, 0x1c, 0x4c, 0x4f, 0x43, 0x41, 0x54, 0x49, 0x4f, 0x4e, 0x5f, 0x53, 0x4f,
+	0x55, 0x52, 0x43, 0x45, 0x5f, 0x43, 0x4f, 0x52, 0x45, 0x4c, 0x4f, 0x43, 0x41, 0x54, 0x49, 0x4f,
+	0x4e, 0x10, 0x02, 0x12, 0x1b, 0x0a, 0x17, 0x4c, 0x4f, 0x43, 0x41, 0x54, 0x49, 0x4f, 0x4e, 0x5f,
+	0x53, 0x4f, 0x55, 0x52, 0x43, 0x45, 0x5f, 0x57, 0x49, 0x4e, 0x44, 0x4f, 0x57, 0x53, 0x10, 0x03,
+	0x12, 0x19, 0x0a, 0x15, 0x4c, 0x4f, 0x43, 0x41, 0x54, 0x49, 0x4f, 0x4e, 0x5f, 0x53, 0x4f, 0x55,
+	0x52, 0x43, 0x45, 0x5f, 0x47, 0x45, 0x4f, 0x49, 0x50, 0x10, 0x04, 0x12, 0x1a, 0x0a, 0x16, 0x4c,
+	0x4f, 0x43, 0x41, 0x54, 0x49, 0x4f, 0x4e, 0x5f, 0x53, 0x4f, 0x55, 0x52, 0x43, 0x45, 0x5f, 0x4d,
+	0x41, 0x4e, 0x55, 0x41, 0x4c, 0x10, 0x05, 0x12, 0x1b, 0x0a, 0x17, 0x4c, 0x4f, 0x43, 0x41, 0x54,
+	0x49, 0x4f, 0x4e, 0x5f, 0x53, 0x4f, 0x55, 0x52, 0x43, 0x45, 0x5f, 0x41, 0x4e, 0x44, 0x52, 0x4f,
+	0x49, 0x44, 0x10, 0x06, 0x12, 0x17, 0x0a, 0x13, 0x4c, 0x4f, 0x43, 0x41, 0x54, 0x49, 0x4f, 0x4e,
+	0x5f, 0x53, 0x4f, 0x55, 0x52, 0x43, 0x45, 0x5f, 0x47, 0x50, 0x53, 0x10, 0x07, 0x42, 0x38, 0x0a,
+	0x14, 0x63, 0x6f, 0x6d, 0x2e, 0x74, 0x79, 0x70, 0x65, 0x73, 0x2e, 0x6c, 0x38, 0x4d, 0x79, 0x46,
+	0x61, 0x6d, 0x69, 0x6c, 0x79, 0x42, 0x0a, 0x4c, 0x38, 0x4d, 0x79, 0x46, 0x61, 0x6d, 0x69, 0x6c,
+	0x79, 0x50, 0x01, 0x5a, 0x12, 0x2e, 0x2f, 0x74, 0x79, 0x70, 0x65, 0x73, 0x2f, 0x6c, 0x38, 0x6d,
+	0x79, 0x66, 0x61, 0x6d, 0x69, 0x6c, 0x79, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
 }
 
 var (
@@ -529,30 +1507,42 @@ func file_family_proto_rawDescGZIP() []byte {
 	return file_family_proto_rawDescData
 }
 
-var file_family_proto_msgTypes = make([]protoimpl.MessageInfo, 8)
+var file_family_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
+var file_family_proto_msgTypes = make([]protoimpl.MessageInfo, 12)
 var file_family_proto_goTypes = []interface{}{
-	(*Location)(nil),         // 0: l8myfamily.Location
-	(*DeviceList)(nil),       // 1: l8myfamily.DeviceList
-	(*Device)(nil),           // 2: l8myfamily.Device
-	(*Member)(nil),           // 3: l8myfamily.Member
-	(*Activity)(nil),         // 4: l8myfamily.Activity
-	(*Family)(nil),           // 5: l8myfamily.Family
-	nil,                      // 6: l8myfamily.Member.DevicesEntry
-	nil,                      // 7: l8myfamily.Family.MembersEntry
-	(*l8api.L8MetaData)(nil), // 8: l8api.L8MetaData
+	(LocationSource)(0),       // 0: l8myfamily.LocationSource
+	(*Location)(nil),          // 1: l8myfamily.Location
+	(*Telemetry)(nil),         // 2: l8myfamily.Telemetry
+	(*GeofenceEvent)(nil),     // 3: l8myfamily.GeofenceEvent
+	(*GeofenceEventList)(nil), // 4: l8myfamily.GeofenceEventList
+	(*DeviceList)(nil),        // 5: l8myfamily.DeviceList
+	(*Device)(nil),            // 6: l8myfamily.Device
+	(*Member)(nil),            // 7: l8myfamily.Member
+	(*Activity)(nil),          // 8: l8myfamily.Activity
+	(*Family)(nil),            // 9: l8myfamily.Family
+	nil,                       // 10: l8myfamily.Device.TagsEntry
+	nil,                       // 11: l8myfamily.Member.DevicesEntry
+	nil,                       // 12: l8myfamily.Family.MembersEntry
+	(*l8api.L8MetaData)(nil),  // 13: l8api.L8MetaData
 }
 var file_family_proto_depIdxs = []int32{
-	2, // 0: l8myfamily.DeviceList.list:type_name -> l8myfamily.Device
-	8, // 1: l8myfamily.DeviceList.metadata:type_name -> l8api.L8MetaData
-	6, // 2: l8myfamily.Member.devices:type_name -> l8myfamily.Member.DevicesEntry
-	7, // 3: l8myfamily.Family.members:type_name -> l8myfamily.Family.MembersEntry
-	2, // 4: l8myfamily.Member.DevicesEntry.value:type_name -> l8myfamily.Device
-	3, // 5: l8myfamily.Family.MembersEntry.value:type_name -> l8myfamily.Member
-	6, // [6:6] is the sub-list for method output_type
-	6, // [6:6] is the sub-list for method input_type
-	6, // [6:6] is the sub-list for extension type_name
-	6, // [6:6] is the sub-list for extension extendee
-	0, // [0:6] is the sub-list for field type_name
+	0,  // 0: l8myfamily.Location.source:type_name -> l8myfamily.LocationSource
+	2,  // 1: l8myfamily.Location.telemetry:type_name -> l8myfamily.Telemetry
+	3,  // 2: l8myfamily.GeofenceEventList.list:type_name -> l8myfamily.GeofenceEvent
+	6,  // 3: l8myfamily.DeviceList.list:type_name -> l8myfamily.Device
+	13, // 4: l8myfamily.DeviceList.metadata:type_name -> l8api.L8MetaData
+	0,  // 5: l8myfamily.Device.source:type_name -> l8myfamily.LocationSource
+	10, // 6: l8myfamily.Device.tags:type_name -> l8myfamily.Device.TagsEntry
+	2,  // 7: l8myfamily.Device.telemetry:type_name -> l8myfamily.Telemetry
+	11, // 8: l8myfamily.Member.devices:type_name -> l8myfamily.Member.DevicesEntry
+	12, // 9: l8myfamily.Family.members:type_name -> l8myfamily.Family.MembersEntry
+	6,  // 10: l8myfamily.Member.DevicesEntry.value:type_name -> l8myfamily.Device
+	7,  // 11: l8myfamily.Family.MembersEntry.value:type_name -> l8myfamily.Member
+	12, // [12:12] is the sub-list for method output_type
+	12, // [12:12] is the sub-list for method input_type
+	12, // [12:12] is the sub-list for extension type_name
+	12, // [12:12] is the sub-list for extension extendee
+	0,  // [0:12] is the sub-list for field type_name
 }
 
 func init() { file_family_proto_init() }
@@ -574,7 +1564,7 @@ func file_family_proto_init() {
 			}
 		}
 		file_family_proto_msgTypes[1].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*DeviceList); i {
+			switch v := v.(*Telemetry); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -586,7 +1576,7 @@ func file_family_proto_init() {
 			}
 		}
 		file_family_proto_msgTypes[2].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*Device); i {
+			switch v := v.(*GeofenceEvent); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -598,7 +1588,7 @@ func file_family_proto_init() {
 			}
 		}
 		file_family_proto_msgTypes[3].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*Member); i {
+			switch v := v.(*GeofenceEventList); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -610,7 +1600,7 @@ func file_family_proto_init() {
 			}
 		}
 		file_family_proto_msgTypes[4].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*Activity); i {
+			switch v := v.(*DeviceList); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -622,6 +1612,42 @@ func file_family_proto_init() {
 			}
 		}
 		file_family_proto_msgTypes[5].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Device); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_family_proto_msgTypes[6].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Member); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_family_proto_msgTypes[7].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Activity); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_family_proto_msgTypes[8].Exporter = func(v interface{}, i int) interface{} {
 			switch v := v.(*Family); i {
 			case 0:
 				return &v.state
@@ -639,13 +1665,14 @@ func file_family_proto_init() {
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: file_family_proto_rawDesc,
-			NumEnums:      0,
-			NumMessages:   8,
+			NumEnums:      1,
+			NumMessages:   12,
 			NumExtensions: 0,
 			NumServices:   0,
 		},
 		GoTypes:           file_family_proto_goTypes,
 		DependencyIndexes: file_family_proto_depIdxs,
+		EnumInfos:         file_family_proto_enumTypes,
 		MessageInfos:      file_family_proto_msgTypes,
 	}.Build()
 	File_family_proto = out.File